@@ -46,6 +46,7 @@ github.com/coreos/etcd/raft/raftpb
 github.com/elazarl/go-bindata-assetfs
 github.com/gogo/protobuf/proto
 github.com/golang/glog
+golang.org/x/net/http2
 gopkg.in/yaml.v1
 "
 
@@ -28,12 +28,21 @@ type Call struct {
 	Method string         // The name of the database command (see api.proto)
 	Args   proto.Request  // The argument to the command
 	Reply  proto.Response // The reply from the command
+	// Idempotent is set if the command is safe to execute more than
+	// once, in which case it's sent without a client command ID.
+	Idempotent bool
 }
 
 // resetClientCmdID sets the client command ID if the call is for a
 // read-write method. The client command ID provides idempotency
-// protection in conjunction with the server.
+// protection in conjunction with the server. Idempotent calls don't
+// need this protection and are sent without a command ID, so the
+// server neither consults nor populates its response cache for them.
 func (c *Call) resetClientCmdID(clock Clock) {
+	if c.Idempotent {
+		c.Args.Header().CmdID = proto.ClientCmdID{}
+		return
+	}
 	c.Args.Header().CmdID = proto.ClientCmdID{
 		WallTime: clock.Now(),
 		Random:   rand.Int63(),
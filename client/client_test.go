@@ -308,6 +308,87 @@ func TestKVClientGetAndPutProto(t *testing.T) {
 	}
 }
 
+// TestKVClientClusterTimestamp verifies that cluster timestamps are
+// later than the timestamps of preceding writes and increase
+// monotonically.
+func TestKVClientClusterTimestamp(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	kvClient := createTestClient(s.Addr)
+	kvClient.User = storage.UserRoot
+
+	key := proto.Key("a")
+	if err := kvClient.Put(key, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	_, _, writeTS, err := kvClient.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := writeTS
+	for i := 0; i < 3; i++ {
+		ts, err := kvClient.ClusterTimestamp(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !prev.Less(ts) {
+			t.Errorf("%d: expected timestamp %s later than %s", i, ts, prev)
+		}
+		prev = ts
+	}
+}
+
+// TestKVClientConfigWatcher verifies that a config watcher delivers
+// events for existing configs and for configs as they're added and
+// removed.
+func TestKVClientConfigWatcher(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	kvClient := createTestClient(s.Addr)
+	kvClient.User = storage.UserRoot
+
+	prefix := engine.KeyConfigPermissionPrefix
+	newConfig := func() gogoproto.Message { return &proto.PermConfig{} }
+	configs, err := kvClient.GetConfigs(prefix, newConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := configs[""]; !ok || len(configs) != 1 {
+		t.Fatalf("expected only the default permission config; got %+v", configs)
+	}
+
+	w := client.NewConfigWatcher(kvClient, prefix, newConfig, 10*time.Millisecond)
+	w.Start()
+	defer w.Stop()
+	nextEvent := func() client.ConfigEvent {
+		select {
+		case e := <-w.Events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for config event")
+		}
+		return client.ConfigEvent{}
+	}
+
+	if e := nextEvent(); len(e.Key) != 0 || e.Config == nil {
+		t.Errorf("expected event for default permission config; got %+v", e)
+	}
+	permConfig := &proto.PermConfig{Read: []string{"foo"}, Write: []string{"foo"}}
+	key := engine.MakeKey(prefix, proto.Key("db1"))
+	if err := kvClient.PutProto(key, permConfig); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(); !e.Key.Equal(proto.Key("db1")) || !gogoproto.Equal(e.Config, permConfig) {
+		t.Errorf("expected event for added permission config; got %+v", e)
+	}
+	if err := kvClient.Call(proto.Delete, proto.DeleteArgs(key), &proto.DeleteResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(); !e.Key.Equal(proto.Key("db1")) || e.Config != nil {
+		t.Errorf("expected event for removed permission config; got %+v", e)
+	}
+}
+
 // TestKVClientGetAndPut verifies gets and puts of using the KV
 // client's convenience methods.
 func TestKVClientGetAndPut(t *testing.T) {
@@ -0,0 +1,178 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// A ConfigEvent describes the addition, update or removal of a system
+// configuration (e.g. a zone or permission config) under a watched
+// key prefix.
+type ConfigEvent struct {
+	// Key is the key of the config with the watched prefix removed; the
+	// default config has an empty key.
+	Key proto.Key
+	// Config is the new value of the config, or nil if it was removed.
+	Config gogoproto.Message
+}
+
+// GetConfigs reads all configs stored under the key prefix (e.g. the
+// zone config prefix), returning a map from key, with the prefix
+// removed, to config. newConfig returns an empty config message into
+// which each value is unmarshalled.
+func (kv *KV) GetConfigs(prefix proto.Key, newConfig func() gogoproto.Message) (map[string]gogoproto.Message, error) {
+	rows, err := kv.scanConfigs(prefix)
+	if err != nil {
+		return nil, err
+	}
+	configs := map[string]gogoproto.Message{}
+	for _, row := range rows {
+		config := newConfig()
+		if err := gogoproto.Unmarshal(row.Value.Bytes, config); err != nil {
+			return nil, err
+		}
+		configs[string(row.Key[len(prefix):])] = config
+	}
+	return configs, nil
+}
+
+// scanConfigs scans all key/value pairs under the key prefix.
+func (kv *KV) scanConfigs(prefix proto.Key) ([]proto.KeyValue, error) {
+	reply := &proto.ScanResponse{}
+	if err := kv.Call(proto.Scan, proto.ScanArgs(prefix, prefix.PrefixEnd(), 0), reply); err != nil {
+		return nil, err
+	}
+	return reply.Rows, nil
+}
+
+// A ConfigWatcher subscribes to changes of the configs stored under a
+// key prefix by polling at a fixed interval. On start, an event is
+// delivered for each existing config, after which events are
+// delivered as configs are added, updated or removed. This allows
+// control planes external to the cluster to reconcile desired state
+// against the cluster's configuration.
+type ConfigWatcher struct {
+	// Events delivers config events in the order they are observed.
+	Events <-chan ConfigEvent
+
+	kv        *KV
+	prefix    proto.Key
+	newConfig func() gogoproto.Message
+	interval  time.Duration
+	events    chan ConfigEvent
+	configs   map[string][]byte // Last observed config values by key
+	stop      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewConfigWatcher returns a watcher of the configs stored under the
+// key prefix, which polls for changes every interval. newConfig
+// returns an empty config message into which values are
+// unmarshalled. Call Start to begin watching.
+func NewConfigWatcher(kv *KV, prefix proto.Key, newConfig func() gogoproto.Message, interval time.Duration) *ConfigWatcher {
+	events := make(chan ConfigEvent, 10)
+	return &ConfigWatcher{
+		Events:    events,
+		kv:        kv,
+		prefix:    prefix,
+		newConfig: newConfig,
+		interval:  interval,
+		events:    events,
+		configs:   map[string][]byte{},
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start begins polling for config changes in a goroutine.
+func (w *ConfigWatcher) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if err := w.poll(); err != nil {
+				log.Warningf("unable to read configs under %q: %s", w.prefix, err)
+			}
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling. No events are delivered once Stop returns.
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.wg.Wait()
+}
+
+// poll reads the configs under the prefix and delivers an event for
+// each which has changed since the previous poll.
+func (w *ConfigWatcher) poll() error {
+	rows, err := w.kv.scanConfigs(w.prefix)
+	if err != nil {
+		return err
+	}
+	seen := map[string]struct{}{}
+	for _, row := range rows {
+		key := string(row.Key[len(w.prefix):])
+		seen[key] = struct{}{}
+		if prev, ok := w.configs[key]; ok && bytes.Equal(prev, row.Value.Bytes) {
+			continue
+		}
+		config := w.newConfig()
+		if err := gogoproto.Unmarshal(row.Value.Bytes, config); err != nil {
+			log.Errorf("unable to unmarshal config %q: %s", row.Key, err)
+			continue
+		}
+		if !w.send(ConfigEvent{Key: proto.Key(key), Config: config}) {
+			return nil
+		}
+		w.configs[key] = row.Value.Bytes
+	}
+	for key := range w.configs {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if !w.send(ConfigEvent{Key: proto.Key(key)}) {
+			return nil
+		}
+		delete(w.configs, key)
+	}
+	return nil
+}
+
+// send delivers the event, returning false if the watcher was stopped
+// first.
+func (w *ConfigWatcher) send(e ConfigEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
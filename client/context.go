@@ -53,6 +53,9 @@ type Context struct {
 	UserPriority    int32
 	TxnRetryOptions util.RetryOptions
 	Clock           Clock
+	// Timeout, if non-zero, is the time after which the server gives
+	// up on a call, setting the deadline of calls without one.
+	Timeout time.Duration
 }
 
 // NewContext creates a new context with default values.
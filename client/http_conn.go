@@ -0,0 +1,123 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// errConnExpired is returned when a request is attempted on a
+// connection which has been idle for longer than the idle timeout or
+// has exceeded its max age. The connection is discarded by the HTTP
+// transport and the request is retried immediately on a new one.
+var errConnExpired = errors.New("connection expired")
+
+// HTTPSenderOptions configures the management of the connections
+// opened by an HTTPSender. Zero values disable the corresponding
+// feature.
+type HTTPSenderOptions struct {
+	// KeepAlive is the period of TCP keepalive probes on connections.
+	KeepAlive time.Duration
+	// IdleTimeout is the duration after which a connection which has
+	// seen no traffic is discarded rather than reused. It should be
+	// less than the idle timeout of any NAT or load balancer between
+	// client and server, which may otherwise drop the connection
+	// silently.
+	IdleTimeout time.Duration
+	// MaxConnAge is the duration after which a connection is discarded
+	// rather than reused, so that load is periodically rebalanced
+	// across servers behind a load balancer.
+	MaxConnAge time.Duration
+}
+
+// DefaultHTTPSenderOptions are the connection options used by
+// NewHTTPSender.
+var DefaultHTTPSenderOptions = HTTPSenderOptions{
+	KeepAlive:   30 * time.Second,
+	IdleTimeout: 90 * time.Second,
+	MaxConnAge:  30 * time.Minute,
+}
+
+// dialFunc returns a dial function for use by an http.Transport which
+// wraps connections to enforce the options. base is used to dial the
+// underlying connection; if nil, a net.Dialer with the configured
+// keepalive period is used.
+func (o HTTPSenderOptions) dialFunc(base func(network, addr string) (net.Conn, error)) func(network, addr string) (net.Conn, error) {
+	if base == nil {
+		base = (&net.Dialer{KeepAlive: o.KeepAlive}).Dial
+	}
+	return func(network, addr string) (net.Conn, error) {
+		conn, err := base(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok && o.KeepAlive > 0 {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(o.KeepAlive)
+		}
+		now := time.Now()
+		return &managedConn{
+			Conn:       conn,
+			opts:       o,
+			created:    now,
+			lastActive: now.UnixNano(),
+		}, nil
+	}
+}
+
+// A managedConn wraps a connection, tracking its age and the time of
+// its last activity. A write, which begins a new request, fails with
+// errConnExpired if the connection has been idle too long or is too
+// old.
+type managedConn struct {
+	net.Conn
+	opts       HTTPSenderOptions
+	created    time.Time
+	lastActive int64 // Unix nanos; updated atomically
+}
+
+// Read implements net.Conn.
+func (c *managedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *managedConn) Write(b []byte) (int, error) {
+	now := time.Now()
+	idle := now.Sub(time.Unix(0, atomic.LoadInt64(&c.lastActive)))
+	if (c.opts.IdleTimeout > 0 && idle > c.opts.IdleTimeout) ||
+		(c.opts.MaxConnAge > 0 && now.Sub(c.created) > c.opts.MaxConnAge) {
+		return 0, errConnExpired
+	}
+	n, err := c.Conn.Write(b)
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+	return n, err
+}
+
+// isConnExpiredError returns whether err, as returned by an
+// http.Client, was caused by an expired connection.
+func isConnExpiredError(err error) bool {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	return err == errConnExpired
+}
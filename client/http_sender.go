@@ -19,11 +19,13 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 
 	"code.google.com/p/snappy-go/snappy"
@@ -31,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 	gogoproto "github.com/gogo/protobuf/proto"
+	"golang.org/x/net/http2"
 )
 
 const (
@@ -41,6 +44,9 @@ const (
 	// TODO(spencer): change this to CONSTANT https. We shouldn't be
 	// supporting http here at all.
 	KVDBScheme = "http"
+	// KVDBSecureScheme is the scheme for connecting to the kvdb
+	// endpoint via TLS.
+	KVDBSecureScheme = "https"
 	// StatusTooManyRequests indicates client should retry due to
 	// server having too many requests.
 	StatusTooManyRequests = 429
@@ -65,21 +71,108 @@ var HTTPRetryOptions = util.RetryOptions{
 // Key-Value database provided by a Cockroach cluster by connecting
 // via HTTP to a Cockroach node. Overly-busy nodes will redirect
 // this client to other nodes.
+//
+// An HTTPSender may be given several gateway nodes, in which case it
+// fails over to the next gateway when a request to the current one
+// can't be completed. See Send for the guarantees this provides.
 type HTTPSender struct {
-	server string       // The host:port address of the Cockroach gateway node
-	client *http.Client // The HTTP client
+	servers []string     // The host:port addresses of the Cockroach gateway nodes
+	scheme  string       // The URL scheme; https if the transport uses TLS
+	client  *http.Client // The HTTP client
+
+	mu  sync.Mutex // Protects cur
+	cur int        // Index of the current gateway in servers
 }
 
-// NewHTTPSender returns a new instance of HTTPSender.
+// NewHTTPSender returns a new instance of HTTPSender using the
+// default connection options.
 func NewHTTPSender(server string, transport *http.Transport) *HTTPSender {
+	return NewHTTPSenderWithOptions(server, transport, DefaultHTTPSenderOptions)
+}
+
+// NewHTTPSenderWithOptions returns a new instance of HTTPSender whose
+// connections are managed according to opts. The sender uses a copy
+// of the transport, whose Dial function is wrapped to enforce the
+// options; the supplied transport is left unmodified. If the transport
+// is configured for TLS, the copy is also configured to negotiate
+// HTTP/2 so that concurrent calls are multiplexed over a single
+// connection rather than queueing behind one another or opening a
+// connection apiece.
+func NewHTTPSenderWithOptions(server string, transport *http.Transport, opts HTTPSenderOptions) *HTTPSender {
+	return NewHTTPSenderWithFailover([]string{server}, transport, opts)
+}
+
+// NewHTTPSenderWithFailover returns a new instance of HTTPSender which
+// sends requests to the first of servers and fails over to the next
+// (wrapping around) whenever a request can't be completed by the
+// current gateway. Connections are managed according to opts, as for
+// NewHTTPSenderWithOptions.
+func NewHTTPSenderWithFailover(servers []string, transport *http.Transport, opts HTTPSenderOptions) *HTTPSender {
+	if len(servers) == 0 {
+		panic("HTTPSender requires at least one gateway address")
+	}
+	transport = cloneTransport(transport)
+	transport.Dial = opts.dialFunc(transport.Dial)
+	scheme := KVDBScheme
+	if transport.TLSClientConfig != nil {
+		scheme = KVDBSecureScheme
+		if err := http2.ConfigureTransport(transport); err != nil {
+			log.Warningf("unable to enable HTTP/2; falling back to HTTP/1.1: %s", err)
+		}
+	}
 	return &HTTPSender{
-		server: server,
+		servers: append([]string(nil), servers...),
+		scheme:  scheme,
 		client: &http.Client{
 			Transport: transport,
 		},
 	}
 }
 
+// cloneTransport returns a new transport with the configuration of t,
+// including a copy of its TLS configuration, so that it may be
+// modified without affecting t's other users.
+func cloneTransport(t *http.Transport) *http.Transport {
+	return &http.Transport{
+		Proxy:                 t.Proxy,
+		Dial:                  t.Dial,
+		TLSClientConfig:       cloneTLSConfig(t.TLSClientConfig),
+		TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+		DisableKeepAlives:     t.DisableKeepAlives,
+		DisableCompression:    t.DisableCompression,
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+		ResponseHeaderTimeout: t.ResponseHeaderTimeout,
+	}
+}
+
+// cloneTLSConfig returns a copy of the configuration of c, or nil if
+// c is nil.
+func cloneTLSConfig(c *tls.Config) *tls.Config {
+	if c == nil {
+		return nil
+	}
+	return &tls.Config{
+		Rand:                     c.Rand,
+		Time:                     c.Time,
+		Certificates:             c.Certificates,
+		NameToCertificate:        c.NameToCertificate,
+		RootCAs:                  c.RootCAs,
+		NextProtos:               append([]string(nil), c.NextProtos...),
+		ServerName:               c.ServerName,
+		ClientAuth:               c.ClientAuth,
+		ClientCAs:                c.ClientCAs,
+		InsecureSkipVerify:       c.InsecureSkipVerify,
+		CipherSuites:             c.CipherSuites,
+		PreferServerCipherSuites: c.PreferServerCipherSuites,
+		SessionTicketsDisabled:   c.SessionTicketsDisabled,
+		SessionTicketKey:         c.SessionTicketKey,
+		ClientSessionCache:       c.ClientSessionCache,
+		MinVersion:               c.MinVersion,
+		MaxVersion:               c.MaxVersion,
+		CurvePreferences:         c.CurvePreferences,
+	}
+}
+
 // Send sends call to Cockroach via an HTTP post. HTTP response codes
 // which are retryable are retried with backoff in a loop using the
 // default retry options. Other errors sending HTTP request are
@@ -88,12 +181,28 @@ func NewHTTPSender(server string, transport *http.Transport) *HTTPSender {
 // and been executed successfully. We retry here to eventually get
 // through with the same client command ID and be given the cached
 // response.
+//
+// If the sender was created with multiple gateways, retries following
+// a retryable response code or a failure to send the request or read
+// its response are directed to the next gateway. The call's arguments,
+// including the client command ID, are resent unchanged, so a write
+// which was executed via the failed gateway but whose response was
+// lost is usually detected as a replay by the range's response cache
+// and answered with the original response rather than being executed
+// a second time. This doesn't amount to at-most-once semantics: the
+// response cache forgets commands after
+// storage.GCResponseCacheExpiration, so a retry which follows its
+// original by longer than that, as may happen when gateways are slow
+// to fail, executes the command again. Idempotent calls carry no
+// client command ID and may be executed more than once, which by
+// definition is harmless.
 func (s *HTTPSender) Send(call *Call) {
 	retryOpts := HTTPRetryOptions
 	retryOpts.Tag = fmt.Sprintf("http %s", call.Method)
 
 	if err := util.RetryWithBackoff(retryOpts, func() (util.RetryStatus, error) {
-		resp, err := s.post(call)
+		server := s.server()
+		resp, err := s.post(server, call)
 		if err != nil {
 			if resp != nil {
 				log.Warningf("failed to send HTTP request with status code %d", resp.StatusCode)
@@ -103,12 +212,18 @@ func (s *HTTPSender) Send(call *Call) {
 					// Retry on service unavailable and request timeout.
 					// TODO(spencer): consider respecting the Retry-After header for
 					// backoff / retry duration.
+					s.failover(server)
 					return util.RetryContinue, nil
 				default:
 					// Can't recover from all other errors.
 					return util.RetryBreak, err
 				}
 			}
+			if err == errConnExpired {
+				// The request was not sent; retry immediately on a new
+				// connection.
+				return util.RetryReset, nil
+			}
 			switch t := err.(type) {
 			case *httpSendError:
 				// Assume all errors sending request are retryable. The actual
@@ -119,6 +234,7 @@ func (s *HTTPSender) Send(call *Call) {
 				// the errors we'll sweep up in this net shouldn't be retried,
 				// but we can't really know for sure which.
 				log.Warningf("failed to send HTTP request or read its response: %s", t)
+				s.failover(server)
 				return util.RetryContinue, nil
 			default:
 				// Can't retry in order to recover from this error. Propagate.
@@ -132,20 +248,40 @@ func (s *HTTPSender) Send(call *Call) {
 	}
 }
 
-// post posts the call using the HTTP client. The call's method is
+// server returns the address of the current gateway.
+func (s *HTTPSender) server() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.servers[s.cur]
+}
+
+// failover advances to the next gateway if failed is still the
+// current one. Concurrent calls which fail against the same gateway
+// thus advance past it only once.
+func (s *HTTPSender) failover(failed string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.servers) == 1 || s.servers[s.cur] != failed {
+		return
+	}
+	s.cur = (s.cur + 1) % len(s.servers)
+	log.Warningf("failing over from gateway %s to %s", failed, s.servers[s.cur])
+}
+
+// post posts the call to server using the HTTP client. The call's method is
 // appended to KVDBEndpoint and set as the URL path. The call's arguments
 // are protobuf-serialized and written as the POST body. The content
 // type is set to application/x-protobuf.
 //
 // On success, the response body is unmarshalled into call.Reply.
-func (s *HTTPSender) post(call *Call) (*http.Response, error) {
+func (s *HTTPSender) post(server string, call *Call) (*http.Response, error) {
 	// Marshal the args into a request body.
 	body, err := gogoproto.Marshal(call.Args)
 	if err != nil {
 		return nil, err
 	}
 
-	url := fmt.Sprintf("%s://%s%s%s", KVDBScheme, s.server, KVDBEndpoint, call.Method)
+	url := fmt.Sprintf("%s://%s%s%s", s.scheme, server, KVDBEndpoint, call.Method)
 	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, util.Errorf("unable to create request: %s", err)
@@ -154,6 +290,9 @@ func (s *HTTPSender) post(call *Call) (*http.Response, error) {
 	req.Header.Add("Accept", "application/x-protobuf")
 	req.Header.Add("Accept-Encoding", "snappy")
 	resp, err := s.client.Do(req)
+	if isConnExpiredError(err) {
+		return nil, errConnExpired
+	}
 	if resp == nil {
 		return nil, &httpSendError{util.Errorf("http client was closed: %s", err)}
 	}
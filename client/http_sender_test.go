@@ -18,16 +18,19 @@
 package client
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
 )
 
 var (
@@ -212,3 +215,167 @@ func TestHTTPSenderRetryHTTPSendError(t *testing.T) {
 		server.Close()
 	}
 }
+
+// TestHTTPSenderIdleConnection verifies that a connection which has
+// been idle longer than the idle timeout is discarded and the request
+// is transparently sent on a new connection.
+func TestHTTPSenderIdleConnection(t *testing.T) {
+	var remoteAddrs []string
+	server, addr := startTestHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddrs = append(remoteAddrs, r.RemoteAddr)
+		body, contentType, err := util.MarshalResponse(r, testPutResp, util.AllEncodings)
+		if err != nil {
+			t.Errorf("failed to marshal response: %s", err)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSenderWithOptions(addr, &http.Transport{
+		TLSClientConfig: rpc.LoadInsecureTLSConfig().Config(),
+	}, HTTPSenderOptions{IdleTimeout: 50 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		reply := &proto.PutResponse{}
+		sender.Send(&Call{Method: proto.Put, Args: testPutReq, Reply: reply})
+		if reply.GoError() != nil {
+			t.Fatalf("%d: expected success; got %s", i, reply.GoError())
+		}
+	}
+	if len(remoteAddrs) != 3 {
+		t.Fatalf("expected 3 requests; got %d", len(remoteAddrs))
+	}
+	// The second request reuses the first connection; the third
+	// follows an idle period and uses a new connection.
+	if remoteAddrs[0] != remoteAddrs[1] {
+		t.Errorf("expected connection reuse; got %s and %s", remoteAddrs[0], remoteAddrs[1])
+	}
+	if remoteAddrs[1] == remoteAddrs[2] {
+		t.Errorf("expected new connection after idle timeout; got %s", remoteAddrs[2])
+	}
+}
+
+// TestHTTPSenderHTTP2 verifies that a sender configured for TLS uses
+// HTTP/2 to connect to a node and multiplexes concurrent calls.
+func TestHTTPSenderHTTP2(t *testing.T) {
+	tlsConfig, err := rpc.LoadTestTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := rpc.NewServer(util.CreateTestAddr("tcp"), rpc.NewContext(hlc.NewClock(hlc.UnixNano), tlsConfig))
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "expected HTTP/2; got "+r.Proto, http.StatusBadRequest)
+			return
+		}
+		body, contentType, err := util.MarshalResponse(r, testPutResp, util.AllEncodings)
+		if err != nil {
+			t.Errorf("failed to marshal response: %s", err)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+
+	sender := NewHTTPSender(s.Addr().String(), &http.Transport{
+		TLSClientConfig: tlsConfig.Config(),
+	})
+	const count = 10
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			reply := &proto.PutResponse{}
+			sender.Send(&Call{Method: proto.Put, Args: testPutReq, Reply: reply})
+			errs <- reply.GoError()
+		}()
+	}
+	for i := 0; i < count; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestHTTPSenderFailover verifies that a call which fails against one
+// gateway is retried against the next with the same client command
+// ID, so that the range's response cache can detect the replay.
+func TestHTTPSenderFailover(t *testing.T) {
+	HTTPRetryOptions.Backoff = 1 * time.Millisecond
+
+	var cmdIDs []proto.ClientCmdID
+	recordCmdID := func(r *http.Request) {
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("unexpected error reading body: %s", err)
+		}
+		args := &proto.PutRequest{}
+		if err := util.UnmarshalRequest(r, reqBody, args, util.AllEncodings); err != nil {
+			t.Errorf("unexpected error unmarshalling request: %s", err)
+		}
+		cmdIDs = append(cmdIDs, args.CmdID)
+	}
+
+	// The first gateway receives the request but fails before sending
+	// a response.
+	var failed *httptest.Server
+	failed, failedAddr := startTestHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordCmdID(r)
+		failed.CloseClientConnections()
+	}))
+	defer failed.Close()
+	server, addr := startTestHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordCmdID(r)
+		body, contentType, err := util.MarshalResponse(r, testPutResp, util.AllEncodings)
+		if err != nil {
+			t.Errorf("failed to marshal response: %s", err)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSenderWithFailover([]string{failedAddr, addr}, &http.Transport{
+		TLSClientConfig: rpc.LoadInsecureTLSConfig().Config(),
+	}, DefaultHTTPSenderOptions)
+	args := &proto.PutRequest{RequestHeader: proto.RequestHeader{Timestamp: testTS, Key: testKey}}
+	call := &Call{Method: proto.Put, Args: args, Reply: &proto.PutResponse{}}
+	call.resetClientCmdID(systemClock{})
+	sender.Send(call)
+	if err := call.Reply.Header().GoError(); err != nil {
+		t.Fatalf("expected success; got %s", err)
+	}
+	if len(cmdIDs) != 2 {
+		t.Fatalf("expected one request to each gateway; got %d requests", len(cmdIDs))
+	}
+	if cmdIDs[0].IsEmpty() || !reflect.DeepEqual(cmdIDs[0], cmdIDs[1]) {
+		t.Errorf("expected the same non-empty client command ID on failover; got %+v and %+v", cmdIDs[0], cmdIDs[1])
+	}
+	// Subsequent calls go directly to the new gateway.
+	sender.Send(&Call{Method: proto.Put, Args: testPutReq, Reply: &proto.PutResponse{}})
+	if len(cmdIDs) != 3 {
+		t.Errorf("expected the next call to be sent to the new gateway only; got %d requests", len(cmdIDs))
+	}
+}
+
+// TestHTTPSenderTransportUnmodified verifies that creating a sender
+// leaves the supplied transport, which the caller may share, intact.
+func TestHTTPSenderTransportUnmodified(t *testing.T) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	for i := 0; i < 2; i++ {
+		NewHTTPSenderWithOptions("127.0.0.1:0", transport, DefaultHTTPSenderOptions)
+	}
+	if transport.Dial != nil {
+		t.Error("expected transport's Dial function to be left unset")
+	}
+	if transport.TLSClientConfig != tlsConfig || len(tlsConfig.NextProtos) != 0 {
+		t.Errorf("expected TLS config to be left unmodified; got protocols %v", tlsConfig.NextProtos)
+	}
+}
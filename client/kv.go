@@ -18,6 +18,8 @@
 package client
 
 import (
+	"time"
+
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -28,6 +30,11 @@ import (
 type TransactionOptions struct {
 	Name      string // Concise desc of txn for debugging
 	Isolation proto.IsolationType
+	AppName   string // Application tag by which txn stats are aggregated
+	// MaxIntents and MaxIntentBytes, if non-zero, override the
+	// coordinator's limits on the intents the transaction may write.
+	MaxIntents     int64
+	MaxIntentBytes int64
 }
 
 // KVSender is an interface for sending a request to a Key-Value
@@ -55,6 +62,10 @@ type KV struct {
 	// ignored.
 	UserPriority    int32
 	TxnRetryOptions util.RetryOptions
+	// Timeout, if non-zero, bounds the time the server spends on a
+	// call: calls made without a deadline are given one Timeout after
+	// they're sent. See proto.RequestHeader.Deadline.
+	Timeout time.Duration
 
 	sender   KVSender
 	clock    Clock
@@ -76,6 +87,7 @@ func NewKV(ctx *Context, sender KVSender) *KV {
 		User:            ctx.User,
 		UserPriority:    ctx.UserPriority,
 		TxnRetryOptions: ctx.TxnRetryOptions,
+		Timeout:         ctx.Timeout,
 		clock:           ctx.Clock,
 	}
 }
@@ -87,6 +99,7 @@ func (kv *KV) Context() *Context {
 		UserPriority:    kv.UserPriority,
 		TxnRetryOptions: kv.TxnRetryOptions,
 		Clock:           kv.clock,
+		Timeout:         kv.Timeout,
 	}
 }
 
@@ -106,20 +119,36 @@ func (kv *KV) Sender() KVSender {
 // Prepare() without a call to Flush(), this call is prepared and
 // then all prepared calls are flushed.
 func (kv *KV) Call(method string, args proto.Request, reply proto.Response) error {
+	return kv.call(&Call{Method: method, Args: args, Reply: reply})
+}
+
+// CallIdempotent is like Call, but marks the command as idempotent:
+// safe to execute more than once with the same result, such as a Put
+// of an absolute value. Idempotent commands skip the server's response
+// cache, which otherwise records the reply of every write to protect
+// against replays. Commands whose effect or reply depends on prior
+// state, such as Increment or ConditionalPut, must not be marked
+// idempotent.
+func (kv *KV) CallIdempotent(method string, args proto.Request, reply proto.Response) error {
+	return kv.call(&Call{Method: method, Args: args, Reply: reply, Idempotent: true})
+}
+
+// call sends the call synchronously, first flushing any prepared
+// calls.
+func (kv *KV) call(call *Call) error {
 	if len(kv.prepared) > 0 {
-		kv.Prepare(method, args, reply)
+		kv.prepare(call)
 		return kv.Flush()
 	}
+	args := call.Args
 	if args.Header().User == "" {
 		args.Header().User = kv.User
 	}
 	if args.Header().UserPriority == nil && kv.UserPriority != 0 {
 		args.Header().UserPriority = gogoproto.Int32(kv.UserPriority)
 	}
-	call := &Call{
-		Method: method,
-		Args:   args,
-		Reply:  reply,
+	if args.Header().Deadline == 0 && kv.Timeout != 0 {
+		args.Header().Deadline = kv.clock.Now() + kv.Timeout.Nanoseconds()
 	}
 	call.resetClientCmdID(kv.clock)
 	kv.sender.Send(call)
@@ -147,11 +176,17 @@ func (kv *KV) Call(method string, args proto.Request, reply proto.Response) erro
 // The supplied reply struct will not be valid until after a call
 // to Flush().
 func (kv *KV) Prepare(method string, args proto.Request, reply proto.Response) {
-	call := &Call{
-		Method: method,
-		Args:   args,
-		Reply:  reply,
-	}
+	kv.prepare(&Call{Method: method, Args: args, Reply: reply})
+}
+
+// PrepareIdempotent is like Prepare, but marks the command as
+// idempotent. See CallIdempotent.
+func (kv *KV) PrepareIdempotent(method string, args proto.Request, reply proto.Response) {
+	kv.prepare(&Call{Method: method, Args: args, Reply: reply, Idempotent: true})
+}
+
+// prepare buffers the call until the next flush.
+func (kv *KV) prepare(call *Call) {
 	call.resetClientCmdID(kv.clock)
 	kv.prepared = append(kv.prepared, call)
 }
@@ -168,7 +203,7 @@ func (kv *KV) Flush() (err error) {
 	} else if len(kv.prepared) == 1 {
 		call := kv.prepared[0]
 		kv.prepared = []*Call{}
-		err = kv.Call(call.Method, call.Args, call.Reply)
+		err = kv.call(call)
 		return
 	}
 	replies := make([]proto.Response, 0, len(kv.prepared))
@@ -349,3 +384,50 @@ func (kv *KV) PreparePutProto(key proto.Key, msg gogoproto.Message) error {
 	}, &proto.PutResponse{})
 	return nil
 }
+
+// AdminSplit splits the range containing splitKey at splitKey. The
+// new range starts at splitKey and extends to the end of the original
+// range. Splitting keyspaces ahead of a bulk load spreads the load
+// across ranges from the start.
+func (kv *KV) AdminSplit(splitKey proto.Key) error {
+	return kv.Call(proto.AdminSplit, &proto.AdminSplitRequest{
+		RequestHeader: proto.RequestHeader{Key: splitKey},
+		SplitKey:      splitKey,
+	}, &proto.AdminSplitResponse{})
+}
+
+// AdminMerge merges the range containing key with the range which
+// immediately follows it. Both ranges must have replicas on the same
+// stores.
+func (kv *KV) AdminMerge(key proto.Key) error {
+	return kv.Call(proto.AdminMerge, &proto.AdminMergeRequest{
+		RequestHeader: proto.RequestHeader{Key: key},
+	}, &proto.AdminMergeResponse{})
+}
+
+// AdminTransferLease transfers the leader lease of the range
+// containing key to the range's replica on the given store. Placing
+// leases near their clients reduces read latency.
+func (kv *KV) AdminTransferLease(key proto.Key, storeID proto.StoreID) error {
+	return kv.Call(proto.AdminTransferLease, &proto.AdminTransferLeaseRequest{
+		RequestHeader: proto.RequestHeader{Key: key},
+		TargetStoreID: storeID,
+	}, &proto.AdminTransferLeaseResponse{})
+}
+
+// ClusterTimestamp returns a hybrid logical clock timestamp for use by
+// applications implementing their own versioning schemes on top of
+// the KV API. The timestamp is assigned by the clock of the leader of
+// the range containing key, by way of a consistent read of key, so it
+// is greater than the timestamp of any write to key which completed
+// before the call. Within a transaction, the transaction's timestamp
+// is returned instead.
+func (kv *KV) ClusterTimestamp(key proto.Key) (proto.Timestamp, error) {
+	reply := &proto.GetResponse{}
+	if err := kv.Call(proto.Get, &proto.GetRequest{
+		RequestHeader: proto.RequestHeader{Key: key},
+	}, reply); err != nil {
+		return proto.Timestamp{}, err
+	}
+	return reply.Timestamp, nil
+}
@@ -57,6 +57,80 @@ func TestKVClientCommandID(t *testing.T) {
 	}
 }
 
+// TestKVIdempotentCommandID verifies that idempotent calls, whether
+// sent directly or prepared, are sent without a client command ID.
+func TestKVIdempotentCommandID(t *testing.T) {
+	count := 0
+	client := NewKV(nil, newTestSender(func(call *Call) {
+		count++
+		if !call.Args.Header().CmdID.IsEmpty() {
+			t.Errorf("expected empty client command ID; got %+v", call.Args.Header().CmdID)
+		}
+	}))
+	if err := client.CallIdempotent(proto.Put, testPutReq, &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	client.PrepareIdempotent(proto.Put, testPutReq, &proto.PutResponse{})
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected test sender to be invoked twice; got %d", count)
+	}
+
+	// In a batch, only the idempotent call lacks a command ID.
+	client = NewKV(nil, newTestSender(func(call *Call) {
+		reqs := call.Args.(*proto.BatchRequest).Requests
+		if len(reqs) != 2 {
+			t.Fatalf("expected 2 requests; got %d", len(reqs))
+		}
+		if cmdID := reqs[0].GetValue().(proto.Request).Header().CmdID; !cmdID.IsEmpty() {
+			t.Errorf("expected empty client command ID; got %+v", cmdID)
+		}
+		if cmdID := reqs[1].GetValue().(proto.Request).Header().CmdID; cmdID.IsEmpty() {
+			t.Error("expected client command ID to be initialized")
+		}
+	}))
+	client.PrepareIdempotent(proto.Put, &proto.PutRequest{}, &proto.PutResponse{})
+	client.Prepare(proto.Put, &proto.PutRequest{}, &proto.PutResponse{})
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestKVTimeout verifies that calls are given a deadline from the
+// client's timeout, unless they already have one.
+func TestKVTimeout(t *testing.T) {
+	var deadline int64
+	client := NewKV(nil, newTestSender(func(call *Call) {
+		deadline = call.Args.Header().Deadline
+	}))
+	if err := client.Call(proto.Put, &proto.PutRequest{}, &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if deadline != 0 {
+		t.Errorf("expected no deadline without a timeout; got %d", deadline)
+	}
+
+	client.Timeout = time.Minute
+	start := time.Now().UnixNano()
+	if err := client.Call(proto.Put, &proto.PutRequest{}, &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if deadline < start+time.Minute.Nanoseconds() || deadline > time.Now().Add(time.Minute).UnixNano() {
+		t.Errorf("expected deadline a minute from now; got %s", time.Unix(0, deadline))
+	}
+
+	args := &proto.PutRequest{}
+	args.Deadline = 1
+	if err := client.Call(proto.Put, args, &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if deadline != 1 {
+		t.Errorf("expected the call's own deadline; got %d", deadline)
+	}
+}
+
 // TestKVPrepareAndFlush verifies that Flush sends single prepared
 // call without a batch and more than one prepared calls with a batch.
 func TestKVPrepareAndFlush(t *testing.T) {
@@ -120,6 +194,73 @@ func TestKVPrepareAndCall(t *testing.T) {
 	}
 }
 
+// TestKVAdminSplit verifies that AdminSplit addresses the split
+// request to the range containing the split key.
+func TestKVAdminSplit(t *testing.T) {
+	splitKey := proto.Key("b")
+	count := 0
+	client := NewKV(nil, newTestSender(func(call *Call) {
+		count++
+		if call.Method != proto.AdminSplit {
+			t.Errorf("expected AdminSplit; got %s", call.Method)
+		}
+		args := call.Args.(*proto.AdminSplitRequest)
+		if !args.Key.Equal(splitKey) || !args.SplitKey.Equal(splitKey) {
+			t.Errorf("expected key and split key %q; got %q, %q", splitKey, args.Key, args.SplitKey)
+		}
+	}))
+	if err := client.AdminSplit(splitKey); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected test sender to be invoked once; got %d", count)
+	}
+}
+
+// TestKVAdminMerge verifies that AdminMerge addresses the merge
+// request to the range containing the key.
+func TestKVAdminMerge(t *testing.T) {
+	key := proto.Key("a")
+	count := 0
+	client := NewKV(nil, newTestSender(func(call *Call) {
+		count++
+		if call.Method != proto.AdminMerge {
+			t.Errorf("expected AdminMerge; got %s", call.Method)
+		}
+		if args := call.Args.(*proto.AdminMergeRequest); !args.Key.Equal(key) {
+			t.Errorf("expected key %q; got %q", key, args.Key)
+		}
+	}))
+	if err := client.AdminMerge(key); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected test sender to be invoked once; got %d", count)
+	}
+}
+
+// TestKVClusterTimestamp verifies that ClusterTimestamp sends a read
+// without a timestamp and returns the timestamp of the reply.
+func TestKVClusterTimestamp(t *testing.T) {
+	ts := proto.Timestamp{WallTime: 10, Logical: 1}
+	client := NewKV(nil, newTestSender(func(call *Call) {
+		if call.Method != proto.Get {
+			t.Errorf("expected Get; got %s", call.Method)
+		}
+		if args := call.Args.Header(); !args.Timestamp.Equal(proto.ZeroTimestamp) {
+			t.Errorf("expected zero timestamp; got %s", args.Timestamp)
+		}
+		call.Reply.Header().Timestamp = ts
+	}))
+	now, err := client.ClusterTimestamp(proto.Key("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !now.Equal(ts) {
+		t.Errorf("expected timestamp %s; got %s", ts, now)
+	}
+}
+
 // TestKVTransactionSender verifies the proper unwrapping and
 // re-wrapping of the client's sender when starting a transaction.
 // Also verifies that User and UserPriority are propagated to the
@@ -0,0 +1,254 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// MirrorOptions configures a MirrorSender.
+type MirrorOptions struct {
+	// ScratchPrefix, if not empty, enables the mirroring of writes. The
+	// keys of mirrored writes are prefixed with ScratchPrefix so that
+	// they don't modify the data mirrored reads are compared against.
+	ScratchPrefix proto.Key
+	// MaxInFlight is the maximum number of mirrored calls outstanding
+	// at any time. Calls beyond this limit aren't mirrored, so that a
+	// slow mirror never holds up the primary. Defaults to 100.
+	MaxInFlight int
+	// OnDivergence, if set, is invoked with each mirrored call whose
+	// response differs from the primary's. Defaults to logging a
+	// warning. It may be invoked from several goroutines at once.
+	OnDivergence func(*MirrorDivergence)
+}
+
+// A MirrorDivergence describes a mirrored call whose response
+// differs from the response of the primary.
+type MirrorDivergence struct {
+	Method      string
+	Args        proto.Request  // The arguments sent to the primary
+	Reply       proto.Response // The primary's reply
+	MirrorReply proto.Response // The mirror's reply
+}
+
+// MirrorStats counts the calls handled by a MirrorSender.
+type MirrorStats struct {
+	Mirrored  int64 // Calls sent to the mirror
+	Dropped   int64 // Calls not mirrored as MaxInFlight was reached
+	Divergent int64 // Mirrored calls whose responses differed
+}
+
+// A MirrorSender is an implementation of KVSender which sends each
+// call to a primary sender and, asynchronously, duplicates reads
+// (and optionally writes) to a mirror, typically a second cluster to
+// which data is being migrated or which runs a different version.
+// Responses from the mirror are compared with those of the primary
+// and divergences are reported. The caller only ever sees the
+// primary's response.
+//
+// Only calls outside of transactions are mirrored, as a transaction's
+// state can't be shared between clusters. Mirrored reads are sent
+// after the primary has replied and so may observe later writes;
+// concurrent writes to the data read can therefore cause spurious
+// divergences. The replies of mirrored writes are compared only as to
+// whether they succeeded, since the scratch data they modify differs
+// from the primary's.
+type MirrorSender struct {
+	primary KVSender
+	mirror  KVSender
+	opts    MirrorOptions
+	sem     chan struct{}  // Limits the number of mirrored calls in flight
+	wg      sync.WaitGroup // Tracks mirrored calls in flight
+
+	mu    sync.Mutex // Protects stats
+	stats MirrorStats
+}
+
+// NewMirrorSender returns a MirrorSender which sends calls to primary
+// and mirrors them to mirror according to opts.
+func NewMirrorSender(primary, mirror KVSender, opts MirrorOptions) *MirrorSender {
+	if opts.MaxInFlight == 0 {
+		opts.MaxInFlight = 100
+	}
+	if opts.OnDivergence == nil {
+		opts.OnDivergence = func(d *MirrorDivergence) {
+			log.Warningf("mirrored %s diverged: %s returned %s; mirror returned %s",
+				d.Method, d.Args, d.Reply, d.MirrorReply)
+		}
+	}
+	return &MirrorSender{
+		primary: primary,
+		mirror:  mirror,
+		opts:    opts,
+		sem:     make(chan struct{}, opts.MaxInFlight),
+	}
+}
+
+// Send sends call to the primary sender and, if the call is to be
+// mirrored, sends a copy of it to the mirror in the background.
+func (m *MirrorSender) Send(call *Call) {
+	mirrorCall := m.mirrorCall(call)
+	m.primary.Send(call)
+	if mirrorCall == nil {
+		return
+	}
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		m.mu.Lock()
+		m.stats.Dropped++
+		m.mu.Unlock()
+		return
+	}
+	// The caller may reuse the call once Send returns.
+	args := gogoproto.Clone(call.Args).(proto.Request)
+	reply := gogoproto.Clone(call.Reply).(proto.Response)
+	m.wg.Add(1)
+	go func() {
+		defer func() {
+			<-m.sem
+			m.wg.Done()
+		}()
+		m.mirror.Send(mirrorCall)
+		diverged := !m.repliesMatch(mirrorCall.Method, reply, mirrorCall.Reply)
+		m.mu.Lock()
+		m.stats.Mirrored++
+		if diverged {
+			m.stats.Divergent++
+		}
+		m.mu.Unlock()
+		if diverged {
+			m.opts.OnDivergence(&MirrorDivergence{
+				Method:      call.Method,
+				Args:        args,
+				Reply:       reply,
+				MirrorReply: mirrorCall.Reply,
+			})
+		}
+	}()
+}
+
+// Stats returns the counts of calls handled so far.
+func (m *MirrorSender) Stats() MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Wait blocks until all mirrored calls in flight have completed.
+func (m *MirrorSender) Wait() {
+	m.wg.Wait()
+}
+
+// mirrorCall returns the call to send to the mirror in place of the
+// supplied call, or nil if the call isn't to be mirrored.
+func (m *MirrorSender) mirrorCall(call *Call) *Call {
+	if call.Args.Header().Txn != nil || proto.IsAdmin(call.Method) {
+		return nil
+	}
+	switch {
+	case proto.IsReadOnly(call.Method):
+	case len(m.opts.ScratchPrefix) > 0 && isMirrorableWrite(call.Method):
+	default:
+		return nil
+	}
+	args, reply, err := proto.CreateArgsAndReply(call.Method)
+	if err != nil {
+		return nil
+	}
+	gogoproto.Merge(args, call.Args)
+	if proto.IsReadWrite(call.Method) {
+		m.rewriteWrite(args)
+	}
+	return &Call{
+		Method:     call.Method,
+		Args:       args,
+		Reply:      reply,
+		Idempotent: call.Idempotent,
+	}
+}
+
+// isMirrorableWrite returns true if method is a write which may be
+// mirrored to the scratch prefix. Conditional puts aren't mirrored:
+// whether they succeed depends on the existing value, which differs
+// between the primary's data and the mirror's scratch data, so their
+// outcomes would diverge spuriously.
+func isMirrorableWrite(method string) bool {
+	switch method {
+	case proto.Put, proto.Increment, proto.Delete, proto.DeleteRange:
+		return true
+	}
+	return false
+}
+
+// rewriteWrite moves the keys of the supplied write under the scratch
+// prefix. Value checksums cover the key and are recomputed.
+func (m *MirrorSender) rewriteWrite(args proto.Request) {
+	header := args.Header()
+	header.Key = m.scratchKey(header.Key)
+	if len(header.EndKey) > 0 {
+		header.EndKey = m.scratchKey(header.EndKey)
+	}
+	resetChecksum := func(v *proto.Value) {
+		if v != nil && v.Checksum != nil {
+			v.Checksum = nil
+			v.InitChecksum(header.Key)
+		}
+	}
+	if t, ok := args.(*proto.PutRequest); ok {
+		resetChecksum(&t.Value)
+	}
+}
+
+func (m *MirrorSender) scratchKey(key proto.Key) proto.Key {
+	return append(append(proto.Key(nil), m.opts.ScratchPrefix...), key...)
+}
+
+// repliesMatch returns true if the mirror's reply agrees with the
+// primary's. Headers are ignored, as are the timestamps of values,
+// which are assigned independently by each cluster. Writes are only
+// compared as to whether they succeeded.
+func (m *MirrorSender) repliesMatch(method string, reply, mirrorReply proto.Response) bool {
+	if (reply.Header().Error == nil) != (mirrorReply.Header().Error == nil) {
+		return false
+	}
+	if reply.Header().Error != nil || proto.IsReadWrite(method) {
+		return true
+	}
+	return gogoproto.Equal(normalizeReply(reply), normalizeReply(mirrorReply))
+}
+
+// normalizeReply returns a copy of reply stripped of the fields which
+// legitimately differ between clusters.
+func normalizeReply(reply proto.Response) proto.Response {
+	reply = gogoproto.Clone(reply).(proto.Response)
+	*reply.Header() = proto.ResponseHeader{}
+	switch t := reply.(type) {
+	case *proto.GetResponse:
+		if t.Value != nil {
+			t.Value.Timestamp = nil
+		}
+	case *proto.ScanResponse:
+		for i := range t.Rows {
+			t.Rows[i].Value.Timestamp = nil
+		}
+	}
+	return reply
+}
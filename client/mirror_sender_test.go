@@ -0,0 +1,126 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// newGetSender returns a sender which answers gets with value,
+// stamped with the supplied wall time.
+func newGetSender(value string, wallTime int64) *testSender {
+	return newTestSender(func(call *Call) {
+		if reply, ok := call.Reply.(*proto.GetResponse); ok {
+			ts := makeTS(wallTime, 0)
+			reply.Value = &proto.Value{Bytes: []byte(value), Timestamp: &ts}
+		}
+	})
+}
+
+// TestMirrorSenderReads verifies that reads are mirrored and that
+// divergent replies are reported while value timestamps are ignored.
+func TestMirrorSenderReads(t *testing.T) {
+	var mu sync.Mutex
+	var divergences []*MirrorDivergence
+	opts := MirrorOptions{
+		OnDivergence: func(d *MirrorDivergence) {
+			mu.Lock()
+			defer mu.Unlock()
+			divergences = append(divergences, d)
+		},
+	}
+	for i, test := range []struct {
+		mirrorValue string
+		divergent   int64
+	}{
+		{"value", 0},
+		{"other", 1},
+	} {
+		divergences = nil
+		sender := NewMirrorSender(newGetSender("value", 1), newGetSender(test.mirrorValue, 2), opts)
+		reply := &proto.GetResponse{}
+		sender.Send(&Call{Method: proto.Get, Args: proto.GetArgs(proto.Key("a")), Reply: reply})
+		if string(reply.Value.Bytes) != "value" {
+			t.Errorf("%d: expected primary's reply; got %s", i, reply)
+		}
+		sender.Wait()
+		if stats := sender.Stats(); stats.Mirrored != 1 || stats.Divergent != test.divergent {
+			t.Errorf("%d: unexpected stats %+v", i, stats)
+		}
+		if int64(len(divergences)) != test.divergent {
+			t.Errorf("%d: expected %d divergences; got %d", i, test.divergent, len(divergences))
+		}
+	}
+}
+
+// TestMirrorSenderWrites verifies that writes are only mirrored when a
+// scratch prefix is configured, in which case their keys are moved
+// under the prefix, and that transactional calls and conditional puts
+// aren't mirrored.
+func TestMirrorSenderWrites(t *testing.T) {
+	prefix := proto.Key("scratch/")
+	var mirrored []*Call
+	mirror := newTestSender(func(call *Call) {
+		mirrored = append(mirrored, call)
+	})
+	put := func(sender *MirrorSender) {
+		sender.Send(&Call{Method: proto.Put, Args: proto.PutArgs(proto.Key("a"), []byte("value")), Reply: &proto.PutResponse{}})
+		sender.Wait()
+	}
+
+	put(NewMirrorSender(newTestSender(nil), mirror, MirrorOptions{}))
+	if len(mirrored) != 0 {
+		t.Fatalf("expected write not to be mirrored without scratch prefix; got %d calls", len(mirrored))
+	}
+
+	sender := NewMirrorSender(newTestSender(nil), mirror, MirrorOptions{ScratchPrefix: prefix})
+	put(sender)
+	if len(mirrored) != 1 {
+		t.Fatalf("expected write to be mirrored; got %d calls", len(mirrored))
+	}
+	args := mirrored[0].Args.(*proto.PutRequest)
+	if expKey := append(append(proto.Key(nil), prefix...), "a"...); !bytes.Equal(args.Key, expKey) {
+		t.Errorf("expected mirrored key %q; got %q", expKey, args.Key)
+	}
+	if err := args.Value.Verify(args.Key); err != nil {
+		t.Errorf("expected checksum of mirrored value to be valid: %s", err)
+	}
+
+	// Transactional calls aren't mirrored.
+	gArgs := proto.GetArgs(proto.Key("a"))
+	gArgs.Txn = &proto.Transaction{Name: "test"}
+	sender.Send(&Call{Method: proto.Get, Args: gArgs, Reply: &proto.GetResponse{}})
+	sender.Wait()
+	if len(mirrored) != 1 {
+		t.Errorf("expected transactional call not to be mirrored; got %d calls", len(mirrored))
+	}
+
+	// Nor are conditional puts, whose outcome depends on data which
+	// differs between the primary and the scratch prefix.
+	cpArgs := &proto.ConditionalPutRequest{
+		RequestHeader: proto.RequestHeader{Key: proto.Key("a")},
+		Value:         proto.Value{Bytes: []byte("value")},
+	}
+	sender.Send(&Call{Method: proto.ConditionalPut, Args: cpArgs, Reply: &proto.ConditionalPutResponse{}})
+	sender.Wait()
+	if len(mirrored) != 1 {
+		t.Errorf("expected conditional put not to be mirrored; got %d calls", len(mirrored))
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// KVSessionEndpoint is the URL path which accepts requests to
+	// upgrade an HTTP connection to a KV session.
+	KVSessionEndpoint = "/kv/session"
+	// KVSessionProtocol is the protocol named in the Upgrade header of
+	// a request to open a KV session.
+	KVSessionProtocol = "cockroach-kv-session"
+	// MaxSessionFrameSize is the maximum size of a frame sent over a
+	// KV session.
+	MaxSessionFrameSize = 64 << 20
+)
+
+// WriteSessionFrame writes b to w as a frame of the KV session
+// protocol: a uvarint length followed by the bytes themselves.
+func WriteSessionFrame(w io.Writer, b []byte) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadSessionFrame reads a frame of the KV session protocol from r.
+func ReadSessionFrame(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxSessionFrameSize {
+		return nil, util.Errorf("session frame of %d bytes exceeds maximum of %d", size, MaxSessionFrameSize)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// A SessionSender is an implementation of KVSender which sends all
+// calls over a single persistent connection to a Cockroach gateway
+// node, avoiding the per-call overhead of HTTP. This pins a
+// transaction run over the sender to one gateway, and the gateway
+// aborts the session's transactions if the connection is lost before
+// they're ended.
+//
+// The connection is opened as an HTTP request to KVSessionEndpoint
+// which the server upgrades to the session protocol. Each call is
+// then sent as two frames, the method name followed by the
+// protobuf-serialized arguments, and answered by a single frame
+// holding the protobuf-serialized reply. The server executes calls
+// in the order they're received and replies in the same order, so
+// calls sent concurrently from multiple goroutines are pipelined.
+//
+// Unlike HTTPSender, a SessionSender doesn't retry: once the
+// connection fails, all pending and subsequent calls fail and a new
+// session must be opened.
+type SessionSender struct {
+	conn net.Conn
+
+	// writeMu protects w and orders the registration of calls as
+	// pending with the writing of their frames, so that calls are
+	// pending in the order in which they're sent. It's held while
+	// writing, which may block until replies are read; mu is not, so
+	// that the read loop can always make progress.
+	writeMu sync.Mutex
+	w       *bufio.Writer
+
+	mu      sync.Mutex // Protects pending and err
+	pending []*sessionCall
+	err     error
+}
+
+// A sessionCall is a call awaiting its reply.
+type sessionCall struct {
+	call *Call
+	done chan struct{}
+}
+
+// NewSessionSender opens a KV session to server. If tlsConfig is not
+// nil, the connection uses TLS; the config must not offer HTTP/2 via
+// NextProtos, as HTTP/2 connections can't be upgraded.
+func NewSessionSender(server string, tlsConfig *tls.Config) (*SessionSender, error) {
+	var conn net.Conn
+	var err error
+	scheme := KVDBScheme
+	if tlsConfig != nil {
+		scheme = KVDBSecureScheme
+		conn, err = tls.Dial("tcp", server, tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", scheme, server, KVSessionEndpoint), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", KVSessionProtocol)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, util.Errorf("unable to open KV session: %s", resp.Status)
+	}
+	s := &SessionSender{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+	}
+	go s.readLoop(r)
+	return s, nil
+}
+
+// Send sends call over the session and waits for its reply.
+func (s *SessionSender) Send(call *Call) {
+	body, err := gogoproto.Marshal(call.Args)
+	if err != nil {
+		call.Reply.Header().SetGoError(err)
+		return
+	}
+	sc := &sessionCall{call: call, done: make(chan struct{})}
+	s.writeMu.Lock()
+	s.mu.Lock()
+	if err := s.err; err != nil {
+		s.mu.Unlock()
+		s.writeMu.Unlock()
+		call.Reply.Header().SetGoError(err)
+		return
+	}
+	s.pending = append(s.pending, sc)
+	s.mu.Unlock()
+	if err = WriteSessionFrame(s.w, []byte(call.Method)); err == nil {
+		if err = WriteSessionFrame(s.w, body); err == nil {
+			err = s.w.Flush()
+		}
+	}
+	s.writeMu.Unlock()
+	if err != nil {
+		// Closing the connection fails the read loop, which fails all
+		// pending calls, including this one.
+		s.conn.Close()
+	}
+	<-sc.done
+}
+
+// Close closes the session. Pending calls fail.
+func (s *SessionSender) Close() {
+	s.conn.Close()
+}
+
+// readLoop reads replies from the session, matching them to pending
+// calls in the order in which the calls were sent. When reading
+// fails, all pending calls fail with the error.
+func (s *SessionSender) readLoop(r *bufio.Reader) {
+	for {
+		b, err := ReadSessionFrame(r)
+		s.mu.Lock()
+		if err != nil {
+			s.failLocked(err)
+			s.mu.Unlock()
+			return
+		}
+		if len(s.pending) == 0 {
+			s.failLocked(util.Errorf("unexpected reply received on KV session"))
+			s.mu.Unlock()
+			s.conn.Close()
+			return
+		}
+		sc := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		if err := gogoproto.Unmarshal(b, sc.call.Reply); err != nil {
+			sc.call.Reply.Header().SetGoError(util.Errorf("unable to unmarshal reply: %s", err))
+		}
+		close(sc.done)
+	}
+}
+
+// failLocked fails all pending calls and all subsequent calls with
+// err.
+func (s *SessionSender) failLocked(err error) {
+	if err == io.EOF {
+		err = util.Errorf("KV session closed")
+	}
+	s.err = util.Errorf("KV session failed: %s", err)
+	for _, sc := range s.pending {
+		sc.call.Reply.Header().SetGoError(s.err)
+		close(sc.done)
+	}
+	s.pending = nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TopologyEndpoint is the URL path which serves the cluster topology.
+// The optional "start" and "end" query parameters restrict the range
+// distribution to the ranges overlapping the span; they default to
+// the entire key space.
+const TopologyEndpoint = "/_status/topology"
+
+// A Topology describes the nodes of a cluster and the distribution
+// over them of the ranges in a key span, so that applications can
+// make data locality decisions such as connecting to the nearest
+// gateway.
+type Topology struct {
+	Nodes []NodeTopology `json:"nodes"`
+	// RangeCount is the number of ranges overlapping the span.
+	RangeCount int `json:"rangeCount"`
+}
+
+// A NodeTopology describes a node: its locality, given by its
+// attributes, its address and its health.
+type NodeTopology struct {
+	NodeID proto.NodeID `json:"nodeID"`
+	// Address is the node's address, or empty if the node isn't
+	// gossiping its descriptor.
+	Address string   `json:"address"`
+	Attrs   []string `json:"attrs"`
+	// Live is true if the node is gossiping its descriptor and its
+	// liveness record hasn't expired.
+	Live   bool            `json:"live"`
+	Stores []StoreTopology `json:"stores"`
+	// Replicas is the number of replicas on the node's stores of the
+	// ranges overlapping the span.
+	Replicas int `json:"replicas"`
+}
+
+// A StoreTopology describes a store and the number of replicas it
+// holds of the ranges overlapping the span.
+type StoreTopology struct {
+	StoreID  proto.StoreID `json:"storeID"`
+	Attrs    []string      `json:"attrs"`
+	Replicas int           `json:"replicas"`
+}
+
+// LiveNodes returns the live nodes whose attributes include all of
+// attrs, ordered by the number of replicas they hold in the span, most
+// first. An application can thereby choose the gateway nearest its
+// data in a given locality.
+func (t *Topology) LiveNodes(attrs proto.Attributes) []NodeTopology {
+	var nodes []NodeTopology
+	for _, n := range t.Nodes {
+		if n.Live && attrs.IsSubset(proto.Attributes{Attrs: n.Attrs}) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Stable(nodesByReplicas(nodes))
+	return nodes
+}
+
+type nodesByReplicas []NodeTopology
+
+func (n nodesByReplicas) Len() int           { return len(n) }
+func (n nodesByReplicas) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n nodesByReplicas) Less(i, j int) bool { return n[i].Replicas > n[j].Replicas }
+
+// Topology fetches the cluster topology from the current gateway,
+// with the distribution of the ranges overlapping the span from start
+// to end. Nil keys default to the entire key space. If a gateway
+// can't be reached, the request is tried once against each of the
+// other gateways.
+func (s *HTTPSender) Topology(start, end proto.Key) (*Topology, error) {
+	params := url.Values{}
+	if start != nil {
+		params.Set("start", string(start))
+	}
+	if end != nil {
+		params.Set("end", string(end))
+	}
+	var err error
+	for i := 0; i < len(s.servers); i++ {
+		server := s.server()
+		var t *Topology
+		if t, err = s.getTopology(server, params); err == nil {
+			return t, nil
+		}
+		s.failover(server)
+	}
+	return nil, err
+}
+
+// getTopology fetches the cluster topology from server.
+func (s *HTTPSender) getTopology(server string, params url.Values) (*Topology, error) {
+	u := fmt.Sprintf("%s://%s%s?%s", s.scheme, server, TopologyEndpoint, params.Encode())
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, util.Errorf("unable to create request: %s", err)
+	}
+	req.Header.Add("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.Errorf("unable to fetch topology from %s: %s", server, resp.Status)
+	}
+	t := &Topology{}
+	if err := json.NewDecoder(resp.Body).Decode(t); err != nil {
+		return nil, util.Errorf("unable to decode topology from %s: %s", server, err)
+	}
+	return t, nil
+}
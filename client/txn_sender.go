@@ -39,8 +39,11 @@ func newTxnSender(wrapped KVSender, opts *TransactionOptions) *txnSender {
 	return &txnSender{
 		wrapped: wrapped,
 		txn: &proto.Transaction{
-			Name:      opts.Name,
-			Isolation: opts.Isolation,
+			Name:           opts.Name,
+			Isolation:      opts.Isolation,
+			AppName:        opts.AppName,
+			MaxIntents:     opts.MaxIntents,
+			MaxIntentBytes: opts.MaxIntentBytes,
 		},
 	}
 }
@@ -60,9 +63,12 @@ func (ts *txnSender) Send(call *Call) {
 	case *proto.TransactionAbortedError:
 		// On Abort, reset the transaction so we start anew on restart.
 		ts.txn = &proto.Transaction{
-			Name:      ts.txn.Name,
-			Isolation: ts.txn.Isolation,
-			Priority:  t.Txn.Priority, // acts as a minimum priority on restart
+			Name:           ts.txn.Name,
+			Isolation:      ts.txn.Isolation,
+			AppName:        ts.txn.AppName,
+			MaxIntents:     ts.txn.MaxIntents,
+			MaxIntentBytes: ts.txn.MaxIntentBytes,
+			Priority:       t.Txn.Priority, // acts as a minimum priority on restart
 		}
 	case nil:
 		// Check for whether the transaction was ended as a direct call
@@ -0,0 +1,295 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+/*
+Package photos implements a demo photo sharing application on the KV
+client, together with a workload which exercises it. The application
+shows by example how to build on Cockroach's transactions: records
+are JSON-encoded values, a secondary index lists each user's photos
+and denormalized counters are kept consistent with the rows they
+count. The workload runs a mix of writes and scans against a skewed
+choice of photos, so that transactions contend, and Validate checks
+the invariants the transactions maintain.
+
+# Schema
+
+All keys share the prefix "photos/". IDs are encoded so that keys
+sort by ID.
+
+	photos/id                        ID allocation counter
+	photos/user/<userID>             User
+	photos/photo/<photoID>           Photo
+	photos/user-photo/<userID><photoID>
+	                                 index of photos by user (empty value)
+	photos/comment/<photoID><commentID>
+	                                 Comment
+
+A user's PhotoCount equals the number of the user's index entries and
+a photo's CommentCount equals the number of its comments.
+*/
+package photos
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/encoding"
+)
+
+var (
+	// KeyPrefix is the prefix of all of the application's keys.
+	KeyPrefix = proto.Key("photos/")
+
+	idKey            = proto.MakeKey(KeyPrefix, proto.Key("id"))
+	userPrefix       = proto.MakeKey(KeyPrefix, proto.Key("user/"))
+	photoPrefix      = proto.MakeKey(KeyPrefix, proto.Key("photo/"))
+	userPhotoPrefix  = proto.MakeKey(KeyPrefix, proto.Key("user-photo/"))
+	commentKeyPrefix = proto.MakeKey(KeyPrefix, proto.Key("comment/"))
+)
+
+// A User posts photos and comments.
+type User struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	PhotoCount int64  `json:"photoCount"`
+}
+
+// A Photo is posted by a user.
+type Photo struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"userID"`
+	Caption      string `json:"caption"`
+	CommentCount int64  `json:"commentCount"`
+}
+
+// A Comment is posted by a user on a photo.
+type Comment struct {
+	ID      int64  `json:"id"`
+	PhotoID int64  `json:"photoID"`
+	UserID  int64  `json:"userID"`
+	Text    string `json:"text"`
+}
+
+func userKey(userID int64) proto.Key {
+	return encoding.EncodeUvarint(append(proto.Key(nil), userPrefix...), uint64(userID))
+}
+
+func photoKey(photoID int64) proto.Key {
+	return encoding.EncodeUvarint(append(proto.Key(nil), photoPrefix...), uint64(photoID))
+}
+
+func userPhotosPrefix(userID int64) proto.Key {
+	return encoding.EncodeUvarint(append(proto.Key(nil), userPhotoPrefix...), uint64(userID))
+}
+
+func userPhotoKey(userID, photoID int64) proto.Key {
+	return encoding.EncodeUvarint(userPhotosPrefix(userID), uint64(photoID))
+}
+
+func commentPrefix(photoID int64) proto.Key {
+	return encoding.EncodeUvarint(append(proto.Key(nil), commentKeyPrefix...), uint64(photoID))
+}
+
+func commentKey(photoID, commentID int64) proto.Key {
+	return encoding.EncodeUvarint(commentPrefix(photoID), uint64(commentID))
+}
+
+// decodeLastID returns the ID encoded at the end of key, which begins
+// with prefix.
+func decodeLastID(prefix, key proto.Key) (int64, error) {
+	if !bytes.HasPrefix(key, prefix) {
+		return 0, util.Errorf("key %q doesn't have prefix %q", key, prefix)
+	}
+	_, id := encoding.DecodeUvarint(key[len(prefix):])
+	return int64(id), nil
+}
+
+// A DB is a photo sharing application on a KV client.
+type DB struct {
+	kv *client.KV
+}
+
+// NewDB returns a photo sharing application which stores its data
+// through kv.
+func NewDB(kv *client.KV) *DB {
+	return &DB{kv: kv}
+}
+
+// nextID allocates a new ID, unique among all users, photos and
+// comments. The counter is incremented outside of any transaction so
+// that it doesn't become a point of contention between them.
+func (db *DB) nextID() (int64, error) {
+	reply := &proto.IncrementResponse{}
+	if err := db.kv.Call(proto.Increment, proto.IncrementArgs(idKey, 1), reply); err != nil {
+		return 0, err
+	}
+	return reply.NewValue, nil
+}
+
+// getJSON reads the JSON-encoded record at key into v, returning
+// whether the record exists.
+func getJSON(kv *client.KV, key proto.Key, v interface{}) (bool, error) {
+	ok, b, _, err := kv.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, util.Errorf("unable to decode record at %q: %s", key, err)
+	}
+	return true, nil
+}
+
+// putJSON writes the JSON encoding of v to key.
+func putJSON(kv *client.KV, key proto.Key, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return kv.Put(key, b)
+}
+
+// CreateUser creates a user with the supplied name.
+func (db *DB) CreateUser(name string) (*User, error) {
+	id, err := db.nextID()
+	if err != nil {
+		return nil, err
+	}
+	user := &User{ID: id, Name: name}
+	if err := putJSON(db.kv, userKey(id), user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreatePhoto posts a photo by the user, adding it to the index of the
+// user's photos and incrementing the user's photo count in the same
+// transaction.
+func (db *DB) CreatePhoto(userID int64, caption string) (*Photo, error) {
+	id, err := db.nextID()
+	if err != nil {
+		return nil, err
+	}
+	photo := &Photo{ID: id, UserID: userID, Caption: caption}
+	opts := &client.TransactionOptions{Name: "create photo"}
+	err = db.kv.RunTransaction(opts, func(txn *client.KV) error {
+		user := &User{}
+		if ok, err := getJSON(txn, userKey(userID), user); err != nil {
+			return err
+		} else if !ok {
+			return util.Errorf("user %d not found", userID)
+		}
+		user.PhotoCount++
+		if err := putJSON(txn, userKey(userID), user); err != nil {
+			return err
+		}
+		if err := putJSON(txn, photoKey(id), photo); err != nil {
+			return err
+		}
+		return txn.Put(userPhotoKey(userID, id), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return photo, nil
+}
+
+// CreateComment posts a comment by the user on the photo, incrementing
+// the photo's comment count in the same transaction. Comments on a
+// popular photo contend on its record.
+func (db *DB) CreateComment(photoID, userID int64, text string) (*Comment, error) {
+	id, err := db.nextID()
+	if err != nil {
+		return nil, err
+	}
+	comment := &Comment{ID: id, PhotoID: photoID, UserID: userID, Text: text}
+	opts := &client.TransactionOptions{Name: "create comment"}
+	err = db.kv.RunTransaction(opts, func(txn *client.KV) error {
+		photo := &Photo{}
+		if ok, err := getJSON(txn, photoKey(photoID), photo); err != nil {
+			return err
+		} else if !ok {
+			return util.Errorf("photo %d not found", photoID)
+		}
+		photo.CommentCount++
+		if err := putJSON(txn, photoKey(photoID), photo); err != nil {
+			return err
+		}
+		return putJSON(txn, commentKey(photoID, id), comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// ListPhotos returns the user's photos in the order they were posted.
+// The user's index entries are scanned and the photos they refer to
+// fetched in a single batch, all within a transaction so that the
+// photos are consistent with the index.
+func (db *DB) ListPhotos(userID int64) ([]Photo, error) {
+	var photos []Photo
+	opts := &client.TransactionOptions{Name: "list photos"}
+	err := db.kv.RunTransaction(opts, func(txn *client.KV) error {
+		photos = nil
+		prefix := userPhotosPrefix(userID)
+		scanReply := &proto.ScanResponse{}
+		if err := txn.Call(proto.Scan, proto.ScanArgs(prefix, prefix.PrefixEnd(), 0), scanReply); err != nil {
+			return err
+		}
+		getReplies := make([]proto.GetResponse, len(scanReply.Rows))
+		for i, row := range scanReply.Rows {
+			photoID, err := decodeLastID(prefix, row.Key)
+			if err != nil {
+				return err
+			}
+			txn.Prepare(proto.Get, proto.GetArgs(photoKey(photoID)), &getReplies[i])
+		}
+		if err := txn.Flush(); err != nil {
+			return err
+		}
+		for i, reply := range getReplies {
+			if reply.Value == nil {
+				return util.Errorf("photo at index entry %q not found", scanReply.Rows[i].Key)
+			}
+			var photo Photo
+			if err := json.Unmarshal(reply.Value.Bytes, &photo); err != nil {
+				return err
+			}
+			photos = append(photos, photo)
+		}
+		return nil
+	})
+	return photos, err
+}
+
+// ListComments returns the comments on the photo in the order they
+// were posted.
+func (db *DB) ListComments(photoID int64) ([]Comment, error) {
+	prefix := commentPrefix(photoID)
+	reply := &proto.ScanResponse{}
+	if err := db.kv.Call(proto.Scan, proto.ScanArgs(prefix, prefix.PrefixEnd(), 0), reply); err != nil {
+		return nil, err
+	}
+	comments := make([]Comment, len(reply.Rows))
+	for i, row := range reply.Rows {
+		if err := json.Unmarshal(row.Value.Bytes, &comments[i]); err != nil {
+			return nil, util.Errorf("unable to decode comment at %q: %s", row.Key, err)
+		}
+	}
+	return comments, nil
+}
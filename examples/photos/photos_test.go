@@ -0,0 +1,102 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package photos_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/examples/photos"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func startTestDB(t *testing.T) (*photos.DB, *server.TestServer) {
+	s := &server.TestServer{}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Could not start server: %v", err)
+	}
+	kv := client.NewKV(nil, client.NewHTTPSender(s.Addr, &http.Transport{
+		TLSClientConfig: rpc.LoadInsecureTLSConfig().Config(),
+	}))
+	kv.User = storage.UserRoot
+	return photos.NewDB(kv), s
+}
+
+// TestPhotos verifies that photos are listed through the index of
+// their users' photos and that comments are listed with their photos.
+func TestPhotos(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	db, s := startTestDB(t)
+	defer s.Stop()
+
+	user, err := db.CreateUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var photoIDs []int64
+	for _, caption := range []string{"beach", "mountain"} {
+		photo, err := db.CreatePhoto(user.ID, caption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		photoIDs = append(photoIDs, photo.ID)
+	}
+	if _, err := db.CreatePhoto(user.ID+1000, "orphan"); err == nil {
+		t.Error("expected photo of unknown user to fail")
+	}
+	if _, err := db.CreateComment(photoIDs[1], user.ID, "nice"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := db.ListPhotos(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Caption != "beach" || list[1].Caption != "mountain" || list[1].CommentCount != 1 {
+		t.Errorf("unexpected photos: %+v", list)
+	}
+	comments, err := db.ListComments(photoIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].Text != "nice" {
+		t.Errorf("unexpected comments: %+v", comments)
+	}
+	if err := photos.Validate(db); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestPhotosWorkload runs the workload with concurrent clients and
+// verifies that the application's invariants hold despite contention.
+func TestPhotosWorkload(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	db, s := startTestDB(t)
+	defer s.Stop()
+
+	stats, err := photos.Run(db, photos.Config{Users: 5, Concurrency: 4, Ops: 200}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("workload stats:\n%s", stats)
+	if err := photos.Validate(db); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package photos
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// The operations run by the workload.
+const (
+	opCreateUser = iota
+	opCreatePhoto
+	opCreateComment
+	opListPhotos
+	opListComments
+	numOps
+)
+
+var opNames = [numOps]string{"create user", "create photo", "create comment", "list photos", "list comments"}
+
+// opWeights are the relative frequencies of the operations once the
+// initial users have been created.
+var opWeights = [numOps]int{opCreateUser: 5, opCreatePhoto: 25, opCreateComment: 40, opListPhotos: 15, opListComments: 15}
+
+// Config configures the workload.
+type Config struct {
+	Users       int           // Users created before the mix of operations begins
+	Concurrency int           // Number of concurrent clients
+	Ops         int           // Number of operations after which to stop; zero for no limit
+	Duration    time.Duration // Time after which to stop; zero for no limit
+	Seed        int64         // Seed of the random operation choices
+}
+
+// Stats counts the operations run by the workload.
+type Stats struct {
+	Ops      [numOps]int64
+	Errors   int64
+	Duration time.Duration
+}
+
+// String formats the stats with the throughput of each operation.
+func (s *Stats) String() string {
+	var total int64
+	str := ""
+	for op, n := range s.Ops {
+		total += n
+		str += fmt.Sprintf("%-15s %8d\n", opNames[op], n)
+	}
+	secs := s.Duration.Seconds()
+	if secs == 0 {
+		secs = 1
+	}
+	return str + fmt.Sprintf("%-15s %8d (%.1f ops/sec, %d errors)\n", "total", total, float64(total)/secs, s.Errors)
+}
+
+// A workload tracks the users and photos created so far, from which
+// operations choose their targets.
+type workload struct {
+	db     *DB
+	config Config
+	stats  Stats
+	ops    int64 // Operations started; updated atomically
+
+	mu       sync.Mutex
+	userIDs  []int64
+	photoIDs []int64
+}
+
+// Run runs the workload against db until the configured number of
+// operations have run, the configured duration has elapsed or stopper
+// is closed, and returns the operation counts. Operations which fail
+// are counted and logged, but don't stop the workload; run Validate
+// afterwards to verify the application's invariants.
+func Run(db *DB, config Config, stopper <-chan struct{}) (*Stats, error) {
+	if config.Users <= 0 {
+		config.Users = 1
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	w := &workload{db: db, config: config}
+	start := time.Now()
+	for i := 0; i < config.Users; i++ {
+		if _, err := w.createUser(); err != nil {
+			return nil, err
+		}
+		w.stats.Ops[opCreateUser]++
+	}
+
+	var deadline <-chan time.Time
+	if config.Duration > 0 {
+		deadline = time.After(config.Duration)
+	}
+	done, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		select {
+		case <-deadline:
+		case <-stopper:
+		case <-finished:
+			return
+		}
+		close(done)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			w.runClient(rng, done)
+		}(rand.New(rand.NewSource(config.Seed + int64(i))))
+	}
+	wg.Wait()
+	close(finished)
+	w.stats.Duration = time.Now().Sub(start)
+	return &w.stats, nil
+}
+
+// runClient runs randomly chosen operations until done is closed or
+// the operation limit is reached.
+func (w *workload) runClient(rng *rand.Rand, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if n := atomic.AddInt64(&w.ops, 1); w.config.Ops > 0 && n > int64(w.config.Ops) {
+			return
+		}
+		op, err := w.runOp(rng, chooseOp(rng))
+		if err != nil {
+			log.Warningf("%s failed: %s", opNames[op], err)
+			atomic.AddInt64(&w.stats.Errors, 1)
+			continue
+		}
+		atomic.AddInt64(&w.stats.Ops[op], 1)
+	}
+}
+
+// chooseOp chooses an operation according to opWeights.
+func chooseOp(rng *rand.Rand) int {
+	var total int
+	for _, weight := range opWeights {
+		total += weight
+	}
+	n := rng.Intn(total)
+	for op, weight := range opWeights {
+		if n < weight {
+			return op
+		}
+		n -= weight
+	}
+	panic("unreachable")
+}
+
+// runOp runs the operation and returns the operation run. Operations
+// on photos fall back to posting a photo until there are photos to
+// operate on.
+func (w *workload) runOp(rng *rand.Rand, op int) (int, error) {
+	userID := w.chooseUser(rng)
+	photoID, ok := w.choosePhoto(rng)
+	if !ok && (op == opCreateComment || op == opListComments) {
+		op = opCreatePhoto
+	}
+	var err error
+	switch op {
+	case opCreateUser:
+		_, err = w.createUser()
+	case opCreatePhoto:
+		var photo *Photo
+		if photo, err = w.db.CreatePhoto(userID, fmt.Sprintf("photo by user %d", userID)); err == nil {
+			w.mu.Lock()
+			w.photoIDs = append(w.photoIDs, photo.ID)
+			w.mu.Unlock()
+		}
+	case opCreateComment:
+		_, err = w.db.CreateComment(photoID, userID, fmt.Sprintf("comment by user %d", userID))
+	case opListPhotos:
+		_, err = w.db.ListPhotos(userID)
+	case opListComments:
+		_, err = w.db.ListComments(photoID)
+	default:
+		err = util.Errorf("unknown operation %d", op)
+	}
+	return op, err
+}
+
+func (w *workload) createUser() (*User, error) {
+	w.mu.Lock()
+	n := len(w.userIDs)
+	w.mu.Unlock()
+	user, err := w.db.CreateUser(fmt.Sprintf("user %d", n))
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.userIDs = append(w.userIDs, user.ID)
+	w.mu.Unlock()
+	return user, nil
+}
+
+// chooseUser chooses a user uniformly.
+func (w *workload) chooseUser(rng *rand.Rand) int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.userIDs[rng.Intn(len(w.userIDs))]
+}
+
+// choosePhoto chooses a photo, skewed towards the earliest posted so
+// that a few popular photos attract most comments and their
+// transactions contend. Returns false if there are no photos.
+func (w *workload) choosePhoto(rng *rand.Rand) (int64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.photoIDs) == 0 {
+		return 0, false
+	}
+	return w.photoIDs[rng.Intn(rng.Intn(len(w.photoIDs))+1)], true
+}
+
+// Validate verifies the application's invariants over all of its
+// data: that each user's photo count equals the number of entries in
+// the index of the user's photos, that each index entry refers to a
+// photo posted by the user, that each photo is indexed and that each
+// photo's comment count equals the number of its comments. The data
+// is read without a transaction, so the workload must be stopped.
+func Validate(db *DB) error {
+	var users []*User
+	if err := db.scanJSON(userPrefix, func() interface{} {
+		users = append(users, &User{})
+		return users[len(users)-1]
+	}); err != nil {
+		return err
+	}
+	var photos []*Photo
+	if err := db.scanJSON(photoPrefix, func() interface{} {
+		photos = append(photos, &Photo{})
+		return photos[len(photos)-1]
+	}); err != nil {
+		return err
+	}
+	photosByID := map[int64]*Photo{}
+	for _, p := range photos {
+		photosByID[p.ID] = p
+	}
+
+	indexed := map[int64]bool{}
+	for _, u := range users {
+		prefix := userPhotosPrefix(u.ID)
+		reply := &proto.ScanResponse{}
+		if err := db.kv.Call(proto.Scan, proto.ScanArgs(prefix, prefix.PrefixEnd(), 0), reply); err != nil {
+			return err
+		}
+		if int64(len(reply.Rows)) != u.PhotoCount {
+			return util.Errorf("user %d has photo count %d but %d indexed photos", u.ID, u.PhotoCount, len(reply.Rows))
+		}
+		for _, row := range reply.Rows {
+			photoID, err := decodeLastID(prefix, row.Key)
+			if err != nil {
+				return err
+			}
+			if p, ok := photosByID[photoID]; !ok || p.UserID != u.ID {
+				return util.Errorf("index entry %q of user %d refers to photo %+v", row.Key, u.ID, p)
+			}
+			indexed[photoID] = true
+		}
+	}
+	for _, p := range photos {
+		if !indexed[p.ID] {
+			return util.Errorf("photo %d isn't indexed", p.ID)
+		}
+		comments, err := db.ListComments(p.ID)
+		if err != nil {
+			return err
+		}
+		if int64(len(comments)) != p.CommentCount {
+			return util.Errorf("photo %d has comment count %d but %d comments", p.ID, p.CommentCount, len(comments))
+		}
+	}
+	return nil
+}
+
+// scanJSON decodes each JSON-encoded record under prefix into the
+// value returned by newRecord.
+func (db *DB) scanJSON(prefix proto.Key, newRecord func() interface{}) error {
+	reply := &proto.ScanResponse{}
+	if err := db.kv.Call(proto.Scan, proto.ScanArgs(prefix, prefix.PrefixEnd(), 0), reply); err != nil {
+		return err
+	}
+	for _, row := range reply.Rows {
+		if err := json.Unmarshal(row.Value.Bytes, newRecord()); err != nil {
+			return util.Errorf("unable to decode record at %q: %s", row.Key, err)
+		}
+	}
+	return nil
+}
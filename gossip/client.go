@@ -33,6 +33,20 @@ const (
 	// maxWaitForNewGossip is maximum wait for new gossip before a
 	// peer is considered a poor source of good gossip and is GC'd.
 	maxWaitForNewGossip = 1 * time.Minute
+	// fullGossipFactor is the multiple of the gossip interval after
+	// which a client exchanges its entire infostore with its peer
+	// rather than a delta. The periodic full exchange repairs any
+	// divergence the incremental exchange has missed, such as infos
+	// lost to a restart or dropped from a group at its limit, while
+	// keeping gossip bandwidth proportional to the rate of change
+	// rather than to the size of the infostore.
+	fullGossipFactor = 50
+	// maxInfosPerGossip is the maximum number of infos sent in each
+	// direction by a single gossip exchange. Larger deltas, such as
+	// those of a full exchange, are sent in batches over successive
+	// exchanges, so that each exchange's bandwidth stays bounded as the
+	// infostore grows.
+	maxInfosPerGossip = 100
 )
 
 // init pre-registers net.UnixAddr and net.TCPAddr concrete types with
@@ -95,11 +109,13 @@ func (c *client) close() {
 }
 
 // gossip loops, sending deltas of the infostore and receiving deltas
-// in turn. If an alternate is proposed on response, the client addr
-// is modified and method returns for forwarding by caller.
+// in turn, with a periodic full exchange for anti-entropy. If an
+// alternate is proposed on response, the client addr is modified and
+// method returns for forwarding by caller.
 func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 	localMaxSeq := int64(0)
 	remoteMaxSeq := int64(-1)
+	lastFull := time.Now()
 	for {
 		// Do a periodic check to determine whether this outgoing client
 		// is duplicating work already being done by an incoming client.
@@ -109,9 +125,18 @@ func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 			return util.Errorf("stopping outgoing client %s; already have incoming", c.addr)
 		}
 
+		// Periodically send and request all infos rather than deltas.
+		if now := time.Now(); now.Sub(lastFull) > g.interval*fullGossipFactor {
+			log.V(1).Infof("exchanging full infostore with %s", c.addr)
+			localMaxSeq, lastFull = 0, now
+			if remoteMaxSeq > 0 {
+				remoteMaxSeq = 0
+			}
+		}
+
 		// Compute the delta of local node's infostore to send with request.
 		g.mu.Lock()
-		delta := g.is.delta(c.addr, localMaxSeq)
+		delta := g.is.deltaBatch(c.addr, localMaxSeq, maxInfosPerGossip)
 		var deltaBytes []byte
 		if delta != nil {
 			localMaxSeq = delta.MaxSeq
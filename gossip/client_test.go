@@ -134,3 +134,34 @@ func TestClientDisconnectRedundant(t *testing.T) {
 		t.Fatalf("timeout reached before redundant client connection was closed")
 	}
 }
+
+// TestClientGossipAntiEntropy verifies that an info lost by the
+// client after it was gossiped is restored by the periodic full
+// exchange, though the incremental exchange doesn't resend it.
+func TestClientGossipAntiEntropy(t *testing.T) {
+	local, remote, stopper := startGossip(t)
+	defer stopper.Stop()
+	remote.AddInfo("remote-key", "remote value", time.Minute)
+	disconnected := make(chan *client, 1)
+
+	client := newClient(remote.is.NodeAddr)
+	client.start(local, disconnected, stopper)
+
+	if err := util.IsTrueWithin(func() bool {
+		_, err := local.GetInfo("remote-key")
+		return err == nil
+	}, 500*time.Millisecond); err != nil {
+		t.Fatalf("gossip exchange failed or taking too long")
+	}
+
+	// Lose the info, as if dropped from its group.
+	local.mu.Lock()
+	delete(local.is.Infos, "remote-key")
+	local.mu.Unlock()
+	if err := util.IsTrueWithin(func() bool {
+		_, err := local.GetInfo("remote-key")
+		return err == nil
+	}, 2*gossipInterval*fullGossipFactor); err != nil {
+		t.Errorf("lost info not restored by full gossip exchange")
+	}
+}
@@ -30,7 +30,12 @@ the system with minimal total hops. The algorithm is as follows:
    will be 0. Otherwise, will be value of MaxSeq from last response to
    gossip request. Requesting node times out at gossipInterval*2. On
    timeout, client is closed and GC'd. If node has no outgoing
-   connections, goto #1.
+   connections, goto #1. Every fullGossipFactor gossip intervals,
+   MaxSeq is reset to 0 and the node sends its entire infostore, so
+   that peers exchange all infos and repair any divergence. Each
+   exchange carries at most maxInfosPerGossip infos in either
+   direction; larger deltas are sent in batches over successive
+   exchanges, continuing from the MaxSeq of the last batch.
 
    a. When gossip is received, infostore is augmented. If new info was
       received, the client in question is credited. If nothing new was
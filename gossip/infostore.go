@@ -24,6 +24,7 @@ import (
 	"net"
 	"reflect"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
@@ -372,6 +373,14 @@ func (is *infoStore) combine(delta *infoStore) int {
 //
 // Returns nil if there are no deltas.
 func (is *infoStore) delta(addr net.Addr, seq int64) *infoStore {
+	return is.deltaBatch(addr, seq, 0)
+}
+
+// deltaBatch is like delta, but if maxInfos is positive, it returns at
+// most maxInfos of the infos with the lowest sequence numbers. The
+// MaxSeq of a truncated delta is that of its last info, so that the
+// remaining infos are included in the delta since it.
+func (is *infoStore) deltaBatch(addr net.Addr, seq int64, maxInfos int) *infoStore {
 	if seq >= is.MaxSeq {
 		return nil
 	}
@@ -379,21 +388,38 @@ func (is *infoStore) delta(addr net.Addr, seq int64) *infoStore {
 	delta := newInfoStore(is.NodeAddr)
 
 	// Compute delta of groups and infos.
+	var fresh infosBySeq
 	is.visitInfos(func(g *group) error {
 		gDelta := newGroup(g.Prefix, g.Limit, g.TypeOf)
 		delta.registerGroup(gDelta)
 		return nil
 	}, func(i *info) error {
 		if i.isFresh(addr, seq) {
-			delta.addInfo(i)
+			fresh = append(fresh, i)
 		}
 		return nil
 	})
 
-	delta.MaxSeq = is.MaxSeq
+	maxSeq := is.MaxSeq
+	if maxInfos > 0 && len(fresh) > maxInfos {
+		sort.Sort(fresh)
+		fresh = fresh[:maxInfos]
+		maxSeq = fresh[maxInfos-1].seq
+	}
+	for _, i := range fresh {
+		delta.addInfo(i)
+	}
+	delta.MaxSeq = maxSeq
 	return delta
 }
 
+// infosBySeq implements sort.Interface.
+type infosBySeq []*info
+
+func (is infosBySeq) Len() int           { return len(is) }
+func (is infosBySeq) Swap(i, j int)      { is[i], is[j] = is[j], is[i] }
+func (is infosBySeq) Less(i, j int) bool { return is[i].seq < is[j].seq }
+
 // distant returns an addrSet of node addresses for gossip peers which
 // originated infos with info.Hops > maxHops.
 func (is *infoStore) distant(maxHops uint32) *addrSet {
@@ -412,6 +412,43 @@ func TestInfoStoreDelta(t *testing.T) {
 	}
 }
 
+// TestInfoStoreDeltaBatch verifies that a delta limited to a batch of
+// infos returns those with the lowest sequence numbers, and that
+// successive batches return every info exactly once.
+func TestInfoStoreDeltaBatch(t *testing.T) {
+	is := createTestInfoStore(t)
+	total := int(is.infoCount())
+
+	seen := map[string]struct{}{}
+	seq := int64(0)
+	for batches := 0; ; batches++ {
+		delta := is.deltaBatch(testAddr("<client-addr>"), seq, 7)
+		if delta == nil {
+			if exp := (total + 6) / 7; batches != exp {
+				t.Errorf("expected %d batches; got %d", exp, batches)
+			}
+			break
+		}
+		if n := int(delta.infoCount()); n > 7 {
+			t.Fatalf("expected at most 7 infos in batch; got %d", n)
+		}
+		delta.visitInfos(nil, func(i *info) error {
+			if i.seq <= seq || i.seq > delta.MaxSeq {
+				t.Errorf("info %s with seq %d outside batch (%d, %d]", i.Key, i.seq, seq, delta.MaxSeq)
+			}
+			if _, ok := seen[i.Key]; ok {
+				t.Errorf("info %s returned twice", i.Key)
+			}
+			seen[i.Key] = struct{}{}
+			return nil
+		})
+		seq = delta.MaxSeq
+	}
+	if len(seen) != total {
+		t.Errorf("expected %d infos over all batches; got %d", total, len(seen))
+	}
+}
+
 // TestInfoStoreDistant verifies selection of infos from store with
 // Hops > maxHops.
 func TestInfoStoreDistant(t *testing.T) {
@@ -62,6 +62,12 @@ const (
 	// string address of the node. E.g. node:1 => 127.0.0.1:24001
 	KeyNodeIDPrefix = "node"
 
+	// KeyNodeLivenessPrefix is the key prefix for gossiping node
+	// liveness records. The actual key is suffixed with the decimal
+	// representation of the node id and the value is a
+	// storage.LivenessRecord struct.
+	KeyNodeLivenessPrefix = "node-liveness"
+
 	// KeySentinel is a key for gossip which must not expire or else the
 	// node considers itself partitioned and will retry with bootstrap hosts.
 	KeySentinel = KeyClusterID
@@ -97,3 +103,9 @@ func MakeNodeIDKey(nodeID proto.NodeID) string {
 func MakeMaxAvailCapacityKey(nodeID proto.NodeID, storeID proto.StoreID) string {
 	return MakeKey(KeyMaxAvailCapacityPrefix, nodeID.String(), storeID.String())
 }
+
+// MakeNodeLivenessKey returns the gossip key for the given node's
+// liveness record.
+func MakeNodeLivenessKey(nodeID proto.NodeID) string {
+	return MakeKey(KeyNodeLivenessPrefix, nodeID.String())
+}
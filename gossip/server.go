@@ -103,7 +103,7 @@ func (s *server) Gossip(args *proto.GossipRequest, reply *proto.GossipResponse)
 		return util.Errorf("gossip server shutdown")
 	}
 	// Return reciprocal delta.
-	delta := s.is.delta(addr, args.MaxSeq)
+	delta := s.is.deltaBatch(addr, args.MaxSeq, maxInfosPerGossip)
 	if delta != nil {
 		var buf bytes.Buffer
 		if err := gob.NewEncoder(&buf).Encode(delta); err != nil {
@@ -113,6 +113,12 @@ type DistSender struct {
 	// outside of tests.
 	rpcSend         rpcSendFn
 	rpcRetryOptions util.RetryOptions
+	// hedging, if set, enables hedging of read-only requests based on
+	// the latencies recorded in readLatencies.
+	hedging       *HedgingPolicy
+	readLatencies *latencyTracker
+	// authorizer decides whether requests may be sent.
+	authorizer storage.Authorizer
 }
 
 // rpcSendFn is the function type used to dispatch RPC calls.
@@ -130,6 +136,13 @@ type DistSenderContext struct {
 	RangeLookupMaxRanges int32
 	LeaderCacheSize      int32
 	RPCRetryOptions      *util.RetryOptions
+	// HedgingPolicy, if provided, enables hedging of read-only
+	// requests to a second replica.
+	HedgingPolicy *HedgingPolicy
+	// Authorizer, if provided, decides whether each request may be
+	// sent. Defaults to an Authorizer which consults the permission
+	// configs available via gossip.
+	Authorizer storage.Authorizer
 	// nodeDescriptor, if provided, is used to describe which node the DistSender
 	// lives on, for instance when deciding where to send RPCs.
 	// Usually it is filled in from the Gossip network on demand.
@@ -171,7 +184,8 @@ func NewDistSender(ctx *DistSenderContext, gossip *gossip.Gossip) *DistSender {
 		lcSize = defaultLeaderCacheSize
 	}
 	ds.leaderCache = newLeaderCache(int(lcSize))
-	if ctx.RangeLookupMaxRanges <= 0 {
+	ds.rangeLookupMaxRanges = ctx.RangeLookupMaxRanges
+	if ds.rangeLookupMaxRanges <= 0 {
 		ds.rangeLookupMaxRanges = defaultRangeLookupMaxRanges
 	}
 	ds.rpcSend = rpc.Send
@@ -182,75 +196,24 @@ func NewDistSender(ctx *DistSenderContext, gossip *gossip.Gossip) *DistSender {
 	if ctx.RPCRetryOptions != nil {
 		ds.rpcRetryOptions = *ctx.RPCRetryOptions
 	}
+	ds.hedging = ctx.HedgingPolicy
+	ds.authorizer = ctx.Authorizer
+	if ds.authorizer == nil {
+		ds.authorizer = storage.NewPermConfigAuthorizer(gossip)
+	}
+	ds.readLatencies = newLatencyTracker(hedgingLatencySamples)
 	return ds
 }
 
 // verifyPermissions verifies that the requesting user (header.User)
-// has permission to read/write (capabilities depend on method
-// name). In the event that multiple permission configs apply to the
-// key range implicated by the command, the lowest common denominator
-// for permission. For example, if a scan crosses two permission
-// configs, both configs must allow read permissions or the entire
-// scan will fail.
+// has permission to invoke method on the key range implicated by the
+// header. See storage.Authorizer.
 func (ds *DistSender) verifyPermissions(method string, header *proto.RequestHeader) error {
-	// The root user can always proceed.
-	if header.User == storage.UserRoot {
-		return nil
-	}
-	// Check for admin methods.
-	if proto.NeedAdminPerm(method) {
-		if header.User != storage.UserRoot {
-			return util.Errorf("user %q cannot invoke admin command %s", header.User, method)
-		}
-		return nil
-	}
-	// Get permissions map from gossip.
-	configMap, err := ds.gossip.GetInfo(gossip.KeyConfigPermission)
-	if err != nil {
-		return util.Errorf("permissions not available via gossip")
-	}
-	if configMap == nil {
-		return util.Errorf("perm configs not available; cannot execute %s", method)
-	}
-	permMap := configMap.(storage.PrefixConfigMap)
-	headerEnd := header.EndKey
-	if headerEnd == nil {
-		headerEnd = header.Key
-	}
-	// Visit PermConfig(s) which apply to the method's key range.
-	//   - For each perm config which the range covers, verify read or writes
-	//     are allowed as method requires.
-	//   - Verify the permissions hierarchically; that is, if permissions aren't
-	//     granted at the longest prefix, try next longest, then next, etc., up
-	//     to and including the default prefix.
-	//
-	// TODO(spencer): it might make sense to visit prefixes from the
-	//   shortest to longest instead for performance. Keep an eye on profiling
-	//   for this code path as permission sets grow large.
-	return permMap.VisitPrefixes(header.Key, headerEnd,
-		func(start, end proto.Key, config interface{}) (bool, error) {
-			hasPerm := false
-			permMap.VisitPrefixesHierarchically(start, func(start, end proto.Key, config interface{}) (bool, error) {
-				perm := config.(*proto.PermConfig)
-				if proto.NeedReadPerm(method) && !perm.CanRead(header.User) {
-					return false, nil
-				}
-				if proto.NeedWritePerm(method) && !perm.CanWrite(header.User) {
-					return false, nil
-				}
-				// Return done = true, as permissions have been granted by this config.
-				hasPerm = true
-				return true, nil
-			})
-			if !hasPerm {
-				return false, util.Errorf("user %q cannot invoke %s at %q-%q", header.User, method, start, end)
-			}
-			return false, nil
-		})
+	return ds.authorizer.Authorize(method, header)
 }
 
 // internalRangeLookup dispatches an InternalRangeLookup request for the given
-// metadata key to the replicas of the given range. Note that we allow
+// lookup key to the replicas of the given range. Note that we allow
 // inconsistent reads when doing range lookups for efficiency. Getting stale
 // data is not a correctness problem but instead may infrequently result in
 // additional latency as additional range lookups may be required. Note also
@@ -295,13 +258,21 @@ func (ds *DistSender) getFirstRangeDescriptor() (*proto.RangeDescriptor, error)
 // RangeDescriptors are returned with the intent of pre-caching
 // subsequent ranges which are likely to be requested soon by the
 // current workload.
+//
+// The RangeDescriptor containing key is stored at the first metadata
+// key following metadataKey, which may lie in a subsequent range when
+// the meta2 records are split across several ranges. The lookup then
+// continues at the start of each following range until a descriptor
+// is found.
 func (ds *DistSender) getRangeDescriptor(key proto.Key) ([]proto.RangeDescriptor, error) {
 	var (
-		// metadataKey is sent to InternalRangeLookup to find the
-		// RangeDescriptor which contains key.
+		// metadataKey is the Range Metadata Key of key.
 		metadataKey = engine.RangeMetaKey(key)
+		// lookupKey is sent to InternalRangeLookup to find the
+		// RangeDescriptor which contains key.
+		lookupKey = metadataKey.Next()
 		// desc is the RangeDescriptor for the range which contains
-		// metadataKey.
+		// lookupKey.
 		desc *proto.RangeDescriptor
 		err  error
 	)
@@ -323,13 +294,27 @@ func (ds *DistSender) getRangeDescriptor(key proto.Key) ([]proto.RangeDescriptor
 	} else {
 		// Look up desc from the cache, which will recursively call into
 		// ds.getRangeDescriptor if it is not cached.
-		desc, err = ds.rangeCache.LookupRangeDescriptor(metadataKey)
+		desc, err = ds.rangeCache.LookupRangeDescriptor(lookupKey)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return ds.internalRangeLookup(metadataKey, desc)
+	for {
+		rds, err := ds.internalRangeLookup(lookupKey, desc)
+		if err != nil {
+			return nil, err
+		}
+		if len(rds) > 0 {
+			return rds, nil
+		}
+		// No metadata record follows lookupKey within desc; continue the
+		// lookup in the next range.
+		lookupKey = desc.EndKey
+		if desc, err = ds.rangeCache.LookupRangeDescriptor(lookupKey); err != nil {
+			return nil, err
+		}
+	}
 }
 
 func (ds *DistSender) optimizeReplicaOrder(replicas proto.ReplicaSlice) rpc.OrderingPolicy {
@@ -433,6 +418,13 @@ func (ds *DistSender) sendRPC(desc *proto.RangeDescriptor, method string,
 		SendNextTimeout: defaultSendNextTimeout,
 		Timeout:         defaultRPCTimeout,
 	}
+	// If hedging, send a second attempt after the policy's percentile
+	// of recent read latencies. Only INCONSISTENT reads are hedged, as
+	// hedged attempts may go to replicas without the leader lease.
+	hedge := ds.hedging.shouldHedge(method, args.Header())
+	if hedge {
+		rpcOpts.SendNextTimeout = ds.hedging.hedgeDelay(ds.readLatencies)
+	}
 	// getArgs clones the arguments on demand for all but the first replica.
 	firstArgs := true
 	getArgs := func(addr net.Addr) interface{} {
@@ -448,7 +440,10 @@ func (ds *DistSender) sendRPC(desc *proto.RangeDescriptor, method string,
 		a.Header().Replica = *replicaMap[addr.String()]
 		return a
 	}
-	firstReply := true
+	// When hedging, every attempt gets its own reply, since the losing
+	// attempt, which rpc.Send abandons, may still complete after the
+	// winner is returned.
+	firstReply := !hedge
 	getReply := func() interface{} {
 		if firstReply {
 			firstReply = false
@@ -456,7 +451,14 @@ func (ds *DistSender) sendRPC(desc *proto.RangeDescriptor, method string,
 		}
 		return gogoproto.Clone(reply)
 	}
-	_, err := ds.rpcSend(rpcOpts, "Node."+method, addrs, getArgs, getReply, ds.gossip.RPCContext)
+	start := time.Now()
+	replies, err := ds.rpcSend(rpcOpts, "Node."+method, addrs, getArgs, getReply, ds.gossip.RPCContext)
+	if err == nil && proto.IsReadOnly(method) {
+		ds.readLatencies.record(time.Now().Sub(start))
+	}
+	if err == nil && hedge {
+		gogoproto.Merge(reply, replies[0].(proto.Response))
+	}
 	return err
 }
 
@@ -581,6 +583,25 @@ func (ds *DistSender) Send(call *client.Call) {
 					}
 				}
 
+				// A scan which stopped at a limit in this range reports the
+				// key at which to resume it; the remaining ranges aren't
+				// scanned. Otherwise, the rows got in this round count
+				// against the scan's byte budget.
+				if reply, ok := reply.(*proto.ScanResponse); ok {
+					args := args.(*proto.ScanRequest)
+					if len(reply.ResumeKey) > 0 {
+						descNext = nil
+					} else if args.MaxBytes > 0 && descNext != nil {
+						for _, kv := range reply.Rows {
+							args.MaxBytes -= int64(kv.Size())
+						}
+						if args.MaxBytes <= 0 {
+							reply.ResumeKey = descNext.StartKey
+							descNext = nil
+						}
+					}
+				}
+
 				// descNext can be nil in two cases:
 				// 1. Got enough rows in the middle of the request.
 				// 2. It is the last range of the request.
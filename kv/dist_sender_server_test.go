@@ -176,3 +176,86 @@ func TestMultiRangeScanInconsistent(t *testing.T) {
 		t.Errorf("expected key %q; got %q", keys[0], key)
 	}
 }
+
+// TestRangeLookupAcrossMetaRanges splits the meta2 records across
+// several ranges and verifies that range lookups which find no
+// metadata record in the range they are sent to continue in the
+// following range, including when a meta2 range begins exactly at the
+// record being looked up.
+func TestRangeLookupAcrossMetaRanges(t *testing.T) {
+	s := &server.TestServer{}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	sender := client.NewHTTPSender(s.Addr, &http.Transport{
+		TLSClientConfig: rpc.LoadInsecureTLSConfig().Config(),
+	})
+	db := client.NewKV(nil, sender)
+	db.User = storage.UserRoot
+
+	split := func(key proto.Key) {
+		if err := db.Call(proto.AdminSplit, &proto.AdminSplitRequest{
+			RequestHeader: proto.RequestHeader{Key: key},
+			SplitKey:      key,
+		}, &proto.AdminSplitResponse{}); err != nil {
+			t.Fatalf("failed to split at %q: %s", key, err)
+		}
+	}
+	var keys []proto.Key
+	for c := 'a'; c <= 'z'; c++ {
+		key := proto.Key(string(c))
+		split(key)
+		keys = append(keys, key, key.Next())
+	}
+	for _, key := range keys {
+		if err := db.Call(proto.Put, proto.PutArgs(key, []byte(key)), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Split the meta2 records both at a record and between records.
+	for _, key := range []proto.Key{
+		engine.RangeMetaKey(proto.Key("e")),
+		engine.RangeMetaKey(proto.Key("h")),
+		engine.RangeMetaKey(proto.Key("p")).Next(),
+	} {
+		split(key)
+	}
+
+	// Read each key through a new DistSender, whose range descriptor cache
+	// is empty and which prefetches no descriptors, so that every key
+	// requires range lookups.
+	ds := kv.NewDistSender(&kv.DistSenderContext{RangeLookupMaxRanges: 1}, s.Gossip())
+	for _, key := range keys {
+		ga := proto.GetArgs(key)
+		ga.User = storage.UserRoot
+		gr := &proto.GetResponse{}
+		ds.Send(&client.Call{Method: proto.Get, Args: ga, Reply: gr})
+		if err := gr.GoError(); err != nil {
+			t.Fatalf("failed to read %q: %s", key, err)
+		}
+		if gr.Value == nil || !proto.Key(gr.Value.Bytes).Equal(key) {
+			t.Errorf("expected value %q for key %q; got %+v", key, key, gr.Value)
+		}
+	}
+
+	// The descriptor of range "d"-"e" is stored at meta2(e), at the start
+	// of the second meta2 range, so a lookup of "d\x00" sent to the first
+	// meta2 range returns no descriptors.
+	la := &proto.InternalRangeLookupRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:             engine.RangeMetaKey(proto.Key("d").Next()).Next(),
+			User:            storage.UserRoot,
+			ReadConsistency: proto.INCONSISTENT,
+		},
+		MaxRanges: 1,
+	}
+	lr := &proto.InternalRangeLookupResponse{}
+	ds.Send(&client.Call{Method: proto.InternalRangeLookup, Args: la, Reply: lr})
+	if err := lr.GoError(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lr.Ranges) != 0 {
+		t.Errorf("expected no descriptors from first meta2 range; got %+v", lr.Ranges)
+	}
+}
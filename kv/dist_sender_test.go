@@ -484,3 +484,90 @@ func TestVerifyPermissions(t *testing.T) {
 	}
 	n.Stop()
 }
+
+// TestHedgedReads verifies that INCONSISTENT read-only requests are
+// hedged to a second replica after the policy's delay, that the
+// winning reply is returned and that consistent reads aren't hedged.
+func TestHedgedReads(t *testing.T) {
+	g := makeTestGossip(t)
+	g.AddInfo(gossip.MakeNodeIDKey(2), &storage.NodeDescriptor{
+		NodeID:  2,
+		Address: util.MakeRawAddr("tcp", "node2:8080"),
+	}, time.Hour)
+	desc := testRangeDescriptor
+	desc.Replicas = append(append([]proto.Replica(nil), desc.Replicas...),
+		proto.Replica{NodeID: 2, StoreID: 2})
+
+	policy := &HedgingPolicy{Percentile: 0.9, MaxDelay: 10 * time.Millisecond}
+	var testFn rpcSendFn = func(opts rpc.Options, method string, addrs []net.Addr, getArgs func(addr net.Addr) interface{}, getReply func() interface{}, _ *rpc.Context) ([]interface{}, error) {
+		first := getArgs(addrs[0]).(proto.Request)
+		if first.Header().ReadConsistency != proto.INCONSISTENT {
+			if opts.SendNextTimeout == policy.MaxDelay {
+				t.Errorf("expected consistent read not to be hedged")
+			}
+			return []interface{}{getReply()}, nil
+		}
+		if opts.SendNextTimeout != policy.MaxDelay {
+			t.Errorf("expected send next timeout %s; got %s", policy.MaxDelay, opts.SendNextTimeout)
+		}
+		if len(addrs) != 2 {
+			t.Fatalf("expected 2 addresses; got %d", len(addrs))
+		}
+		hedged := getArgs(addrs[1]).(proto.Request)
+		if hedged.Header().ReadConsistency != proto.INCONSISTENT {
+			t.Errorf("expected hedged attempt to be inconsistent")
+		}
+		getReply()
+		reply := getReply().(*proto.GetResponse)
+		reply.Value = &proto.Value{Bytes: []byte("hedged")}
+		return []interface{}{reply}, nil
+	}
+
+	ctx := &DistSenderContext{
+		rpcSend:       testFn,
+		HedgingPolicy: policy,
+		rangeDescriptorDB: mockRangeDescriptorDB(func(_ proto.Key) ([]proto.RangeDescriptor, error) {
+			return []proto.RangeDescriptor{desc}, nil
+		}),
+	}
+	ds := NewDistSender(ctx, g)
+	args := proto.GetArgs(proto.Key("a"))
+	reply := &proto.GetResponse{}
+	ds.Send(&client.Call{Method: proto.Get, Args: args, Reply: reply})
+	if err := reply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+
+	args = proto.GetArgs(proto.Key("a"))
+	args.ReadConsistency = proto.INCONSISTENT
+	reply = &proto.GetResponse{}
+	ds.Send(&client.Call{Method: proto.Get, Args: args, Reply: reply})
+	if err := reply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value == nil || !bytes.Equal(reply.Value.Bytes, []byte("hedged")) {
+		t.Errorf("expected hedged reply; got %+v", reply)
+	}
+}
+
+// TestHedgeDelay verifies that the hedging delay tracks the policy's
+// percentile of recorded latencies within its bounds.
+func TestHedgeDelay(t *testing.T) {
+	policy := &HedgingPolicy{Percentile: 0.9, MinDelay: 5 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+	lt := newLatencyTracker(100)
+	// Too few samples; use the max delay.
+	lt.record(time.Millisecond)
+	if d := policy.hedgeDelay(lt); d != policy.MaxDelay {
+		t.Errorf("expected max delay %s; got %s", policy.MaxDelay, d)
+	}
+	for i := 1; i <= 100; i++ {
+		lt.record(time.Duration(i) * time.Millisecond)
+	}
+	if d := policy.hedgeDelay(lt); d != 90*time.Millisecond {
+		t.Errorf("expected 90th percentile of 90ms; got %s", d)
+	}
+	policy.MinDelay = 100 * time.Millisecond
+	if d := policy.hedgeDelay(lt); d != policy.MinDelay {
+		t.Errorf("expected min delay %s; got %s", policy.MinDelay, d)
+	}
+}
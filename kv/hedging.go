@@ -0,0 +1,126 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+const (
+	// hedgingLatencySamples is the number of recent read latencies
+	// from which the hedging threshold is computed.
+	hedgingLatencySamples = 1000
+	// hedgingMinSamples is the number of read latencies which must be
+	// observed before the percentile is trusted; until then, MaxDelay
+	// is used.
+	hedgingMinSamples = 20
+)
+
+// A HedgingPolicy configures hedging of read-only requests. If a read
+// hasn't returned within the policy's latency percentile of recent
+// reads, a second attempt is sent to another replica and the first
+// response is used; the losing attempt is abandoned. Hedged attempts
+// go to replicas which may not hold the leader lease, so only reads
+// which the caller has marked INCONSISTENT, and which may therefore
+// return stale data, are hedged. Hedging is opt-in since it increases
+// load on the cluster.
+type HedgingPolicy struct {
+	// Percentile of recent read latencies after which a hedged attempt
+	// is sent, in (0, 1]. For example, 0.95 hedges the slowest 5% of
+	// reads.
+	Percentile float64
+	// MinDelay and MaxDelay bound the delay before a hedged attempt.
+	// MaxDelay defaults to the DistSender's send-next timeout.
+	MinDelay, MaxDelay time.Duration
+}
+
+// shouldHedge returns whether the request may be hedged.
+func (hp *HedgingPolicy) shouldHedge(method string, header *proto.RequestHeader) bool {
+	return hp != nil && proto.IsReadOnly(method) && header.Txn == nil &&
+		header.ReadConsistency == proto.INCONSISTENT
+}
+
+// A latencyTracker records the latencies of recent requests in a ring
+// buffer for computing percentiles.
+type latencyTracker struct {
+	sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// newLatencyTracker returns a tracker retaining up to size samples.
+func newLatencyTracker(size int) *latencyTracker {
+	return &latencyTracker{samples: make([]time.Duration, 0, size)}
+}
+
+// record adds a latency sample, replacing the oldest if full.
+func (lt *latencyTracker) record(d time.Duration) {
+	lt.Lock()
+	defer lt.Unlock()
+	if len(lt.samples) < cap(lt.samples) {
+		lt.samples = append(lt.samples, d)
+		return
+	}
+	lt.samples[lt.next] = d
+	lt.next = (lt.next + 1) % len(lt.samples)
+}
+
+// percentile returns the latency at percentile p of the recorded
+// samples and the number of samples.
+func (lt *latencyTracker) percentile(p float64) (time.Duration, int) {
+	lt.Lock()
+	sorted := append([]time.Duration(nil), lt.samples...)
+	lt.Unlock()
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	sort.Sort(durations(sorted))
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx], len(sorted)
+}
+
+// hedgeDelay returns the delay after which a hedged attempt is sent,
+// based on the recorded latencies.
+func (hp *HedgingPolicy) hedgeDelay(lt *latencyTracker) time.Duration {
+	maxDelay := hp.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultSendNextTimeout
+	}
+	delay, n := lt.percentile(hp.Percentile)
+	if n < hedgingMinSamples || delay > maxDelay {
+		return maxDelay
+	}
+	if delay < hp.MinDelay {
+		delay = hp.MinDelay
+	}
+	return delay
+}
+
+// durations implements sort.Interface.
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
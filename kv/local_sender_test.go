@@ -130,6 +130,7 @@ func TestLocalSenderLookupReplica(t *testing.T) {
 	stopper := util.NewStopper()
 	defer stopper.Stop()
 	db := client.NewKV(nil, NewTxnCoordSender(ls, clock, false, stopper))
+	db.User = storage.UserRoot
 	transport := multiraft.NewLocalRPCTransport()
 	defer transport.Close()
 	store := storage.NewStore(clock, eng, db, nil, transport, storage.TestStoreConfig)
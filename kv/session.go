@@ -0,0 +1,257 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// SessionPrefix is the endpoint which upgrades HTTP connections to
+	// KV sessions.
+	SessionPrefix = client.KVSessionEndpoint
+)
+
+var (
+	// sessionIdleTimeout is the time a session may wait for the next
+	// call before it's closed and its transactions are aborted.
+	sessionIdleTimeout = 10 * time.Minute
+	// sessionWriteTimeout is the time allowed to write each reply to a
+	// session before it's closed.
+	sessionWriteTimeout = 30 * time.Second
+)
+
+// A SessionServer provides an HTTP endpoint which upgrades connections
+// to KV sessions, over which clients send a stream of calls to the
+// public key-value API. See client.SessionSender for the protocol.
+type SessionServer struct {
+	sender  client.KVSender
+	stopper *util.Stopper
+}
+
+// NewSessionServer allocates and returns a new SessionServer. Open
+// sessions are closed when the stopper stops.
+func NewSessionServer(sender client.KVSender, stopper *util.Stopper) *SessionServer {
+	return &SessionServer{sender: sender, stopper: stopper}
+}
+
+// ServeHTTP upgrades the connection to a KV session and serves calls
+// on it until the client closes the connection, sends a malformed
+// call or is idle for longer than sessionIdleTimeout, or the server
+// stops.
+func (s *SessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), client.KVSessionProtocol) {
+		w.Header().Set("Upgrade", client.KVSessionProtocol)
+		http.Error(w, "the KV session protocol requires an upgrade", http.StatusUpgradeRequired)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can't be upgraded", http.StatusInternalServerError)
+		return
+	}
+	// The session runs as a worker of the stopper. Starting a task
+	// first ensures the stopper isn't already stopping.
+	if !s.stopper.StartTask() {
+		http.Error(w, "server is stopping", http.StatusServiceUnavailable)
+		return
+	}
+	s.stopper.AddWorker()
+	s.stopper.FinishTask()
+	defer s.stopper.SetStopped()
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	// Hijacked connections aren't closed by the HTTP server, so close
+	// the connection when stopping to end the session.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.stopper.ShouldStop():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: " + client.KVSessionProtocol + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+	sess := &session{
+		sender: s.sender,
+		conn:   conn,
+		r:      rw.Reader,
+		w:      rw.Writer,
+		txns:   map[string]*sessionTxn{},
+	}
+	err = sess.serve()
+	select {
+	case <-s.stopper.ShouldStop():
+		// Commands can't complete once the stores are stopping; the
+		// transactions are aborted when their coordinators time out.
+		return
+	default:
+	}
+	if err != nil && err != io.EOF {
+		log.Warningf("KV session from %s failed: %s", conn.RemoteAddr(), err)
+	}
+	sess.abortTxns()
+}
+
+// A session serves the calls received over a single KV session
+// connection.
+type session struct {
+	sender client.KVSender
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	// txns holds the most recent state of each of the session's
+	// transactions which hasn't yet been ended, keyed by transaction ID.
+	txns map[string]*sessionTxn
+}
+
+// A sessionTxn is an open transaction of a session and the user
+// running it.
+type sessionTxn struct {
+	txn  *proto.Transaction
+	user string
+}
+
+// serve reads calls from the session and executes them one at a time,
+// writing each reply before executing the next call. Replies are
+// flushed only once no further calls are buffered, so that the
+// replies to pipelined calls are written together.
+func (s *session) serve() error {
+	for {
+		if err := s.conn.SetReadDeadline(time.Now().Add(sessionIdleTimeout)); err != nil {
+			return err
+		}
+		method, err := client.ReadSessionFrame(s.r)
+		if err != nil {
+			return err
+		}
+		body, err := client.ReadSessionFrame(s.r)
+		if err != nil {
+			return err
+		}
+		reply, err := s.execute(string(method), body)
+		if err != nil {
+			return err
+		}
+		b, err := gogoproto.Marshal(reply)
+		if err != nil {
+			return err
+		}
+		if err := s.conn.SetWriteDeadline(time.Now().Add(sessionWriteTimeout)); err != nil {
+			return err
+		}
+		if err := client.WriteSessionFrame(s.w, b); err != nil {
+			return err
+		}
+		if s.r.Buffered() == 0 {
+			if err := s.w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// execute unmarshals and executes a call, returning its reply. An
+// error is returned only if the method isn't part of the public API,
+// as no reply can be created for it; other failures are returned in
+// the reply header.
+func (s *session) execute(method string, body []byte) (proto.Response, error) {
+	if !proto.IsPublic(method) {
+		return nil, util.Errorf("method %q is not available via the KV API", method)
+	}
+	args, reply, err := proto.CreateArgsAndReply(method)
+	if err != nil {
+		return nil, err
+	}
+	if err := gogoproto.Unmarshal(body, args); err != nil {
+		reply.Header().SetGoError(util.Errorf("unable to unmarshal arguments: %s", err))
+		return reply, nil
+	}
+	if err := verifyRequest(args); err != nil {
+		reply.Header().SetGoError(err)
+		return reply, nil
+	}
+	s.sender.Send(&client.Call{Method: method, Args: args, Reply: reply})
+	s.trackTxn(method, args, reply)
+	return reply, nil
+}
+
+// trackTxn records the state of the transaction, if any, returned in
+// reply. The transaction is no longer tracked once it's been committed
+// or aborted; a failed EndTransaction leaves it open, to be aborted
+// with the session's other transactions.
+func (s *session) trackTxn(method string, args proto.Request, reply proto.Response) {
+	txn := reply.Header().Txn
+	if txn == nil || len(txn.ID) == 0 {
+		return
+	}
+	if txn.Status != proto.PENDING || (method == proto.EndTransaction && reply.Header().GoError() == nil) {
+		delete(s.txns, string(txn.ID))
+		return
+	}
+	s.txns[string(txn.ID)] = &sessionTxn{txn: txn, user: args.Header().User}
+}
+
+// abortTxns aborts the session's transactions which the session ended
+// before. Otherwise their intents would block conflicting writers
+// until their coordinators timed out.
+func (s *session) abortTxns() {
+	for id, st := range s.txns {
+		delete(s.txns, id)
+		txn := st.txn
+		log.Infof("aborting transaction %s abandoned by closed KV session", txn)
+		reply := &proto.EndTransactionResponse{}
+		s.sender.Send(&client.Call{
+			Method: proto.EndTransaction,
+			Args: &proto.EndTransactionRequest{
+				RequestHeader: proto.RequestHeader{
+					Key:       txn.Key,
+					User:      st.user,
+					Timestamp: txn.Timestamp,
+					Txn:       txn,
+				},
+				Commit: false,
+			},
+			Reply: reply,
+		})
+		if err := reply.GoError(); err != nil {
+			log.Warningf("failed to abort transaction %s: %s", txn, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestKVSession verifies that calls sent concurrently over a KV
+// session are pipelined and each receives its own reply.
+func TestKVSession(t *testing.T) {
+	addr, _, stopper := startServer(t)
+	defer stopper.Stop()
+
+	sender, err := client.NewSessionSender(addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+
+	const count = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kvClient := client.NewKV(nil, sender)
+			kvClient.User = storage.UserRoot
+			key := proto.Key(fmt.Sprintf("key-%02d", i))
+			value := []byte(fmt.Sprintf("value-%02d", i))
+			if err := kvClient.Put(key, value); err != nil {
+				errs <- err
+				return
+			}
+			ok, b, _, err := kvClient.Get(key)
+			if err != nil {
+				errs <- err
+			} else if !ok || !bytes.Equal(b, value) {
+				errs <- util.Errorf("expected %q for %q; got %q", value, key, b)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// A call to a method outside the public API ends the session.
+	gcReply := &proto.InternalGCResponse{}
+	sender.Send(&client.Call{
+		Method: proto.InternalGC,
+		Args:   &proto.InternalGCRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}},
+		Reply:  gcReply,
+	})
+	if gcReply.GoError() == nil {
+		t.Error("expected error calling internal method over KV session")
+	}
+	kvClient := client.NewKV(nil, sender)
+	if err := kvClient.Put(proto.Key("a"), []byte("value")); err == nil {
+		t.Error("expected error calling closed KV session")
+	}
+}
+
+// TestKVSessionAbortsTxnsOnClose verifies that the gateway aborts each
+// of a session's pending transactions when the session is closed.
+func TestKVSessionAbortsTxnsOnClose(t *testing.T) {
+	addr, db, stopper := startServer(t)
+	defer stopper.Stop()
+
+	sender, err := client.NewSessionSender(addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var txns []*proto.Transaction
+	for _, key := range []string{"a", "b"} {
+		putReq := &proto.PutRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:  proto.Key(key),
+				User: storage.UserRoot,
+				Txn:  &proto.Transaction{Name: "test-" + key},
+			},
+			Value: proto.Value{Bytes: []byte("value")},
+		}
+		putResp := &proto.PutResponse{}
+		sender.Send(&client.Call{Method: proto.Put, Args: putReq, Reply: putResp})
+		if err := putResp.GoError(); err != nil {
+			t.Fatal(err)
+		}
+		txn := putResp.Txn
+		if txn == nil || len(txn.ID) == 0 {
+			t.Fatalf("expected transaction in reply; got %+v", putResp)
+		}
+		txns = append(txns, txn)
+	}
+	sender.Close()
+
+	for _, txn := range txns {
+		if err := util.IsTrueWithin(func() bool {
+			var record proto.Transaction
+			ok, _, err := db.GetProto(engine.TransactionKey(txn.Key, txn.ID), &record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ok && record.Status == proto.ABORTED
+		}, 5*time.Second); err != nil {
+			t.Errorf("transaction %s wasn't aborted on session close: %s", txn.Name, err)
+		}
+	}
+}
+
+// TestKVSessionClosedOnStop verifies that open sessions are closed
+// when the server stops, rather than preventing it from stopping.
+func TestKVSessionClosedOnStop(t *testing.T) {
+	addr, _, stopper := startServer(t)
+
+	sender, err := client.NewSessionSender(addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	kvClient := client.NewKV(nil, sender)
+	kvClient.User = storage.UserRoot
+	if err := kvClient.Put(proto.Key("a"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		stopper.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stopper didn't stop with a KV session open")
+	}
+	if err := kvClient.Put(proto.Key("a"), []byte("value")); err == nil {
+		t.Error("expected error calling KV session of stopped server")
+	}
+}
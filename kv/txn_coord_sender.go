@@ -19,6 +19,8 @@
 package kv
 
 import (
+	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -32,6 +34,23 @@ import (
 	gogoproto "github.com/gogo/protobuf/proto"
 )
 
+// resolveIntentBatchSize is the maximum number of individually written
+// keys listed by a single resolve intent command.
+const resolveIntentBatchSize = 500
+
+// An intentSpan is a key, or key range if end isn't start.Next(),
+// holding write intents of a transaction.
+type intentSpan struct {
+	start, end proto.Key
+}
+
+// intentSpans sorts spans by start key.
+type intentSpans []intentSpan
+
+func (s intentSpans) Len() int           { return len(s) }
+func (s intentSpans) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s intentSpans) Less(i, j int) bool { return s[i].start.Less(s[j].start) }
+
 // txnMetadata holds information about an ongoing transaction, as
 // seen from the perspective of this coordinator. It records all
 // keys (and key ranges) mutated as part of the transaction for
@@ -64,11 +83,59 @@ type txnMetadata struct {
 	// operations to this coordinator.
 	lastUpdateTS proto.Timestamp
 
+	// firstUpdateNanos is the wall time in nanoseconds when the client
+	// first sent transaction operations to this coordinator. It's used
+	// to measure commit latency.
+	firstUpdateNanos int64
+
 	// timeoutDuration is the time after which the transaction should be
 	// considered abandoned by the client. That is, when
 	// current_timestamp > lastUpdateTS + timeoutDuration If this value
 	// is set to 0, a default timeout will be used.
 	timeoutDuration time.Duration
+
+	// intents holds the size in bytes of the key and value of each key
+	// written by the transaction through this coordinator, so that a
+	// rewritten key counts as a single intent. rangeIntents counts the
+	// intents laid down by range writes, whose keys aren't known.
+	intents      map[string]int64
+	rangeIntents int64
+
+	// intentBytes is the total size of the keys and values written
+	// by the transaction through this coordinator.
+	intentBytes int64
+
+	// pendingIntents and pendingBytes are reserved by writes which have
+	// passed the intent limits but not yet completed.
+	pendingIntents int64
+	pendingBytes   int64
+
+	// heartbeating is set once the transaction's first write succeeds
+	// and its heartbeat is started.
+	heartbeating bool
+}
+
+// intentCount returns the number of intents written by the
+// transaction through this coordinator.
+func (tm *txnMetadata) intentCount() int64 {
+	return int64(len(tm.intents)) + tm.rangeIntents
+}
+
+// recordIntents records the intents written by the successful write
+// command args, which returned reply.
+func (tm *txnMetadata) recordIntents(args proto.Request, reply proto.Response) {
+	header := args.Header()
+	tm.addKeyRange(header.Key, header.EndKey)
+	if len(header.EndKey) > 0 {
+		if dr, ok := reply.(*proto.DeleteRangeResponse); ok {
+			tm.rangeIntents += dr.NumDeleted
+		}
+		tm.intentBytes += intentBytes(args)
+		return
+	}
+	bytes := intentBytes(args)
+	tm.intentBytes += bytes - tm.intents[string(header.Key)]
+	tm.intents[string(header.Key)] = bytes
 }
 
 // addKeyRange adds the specified key range to the interval cache,
@@ -97,60 +164,114 @@ func (tm *txnMetadata) addKeyRange(start, end proto.Key) {
 	tm.keys.Add(key, nil)
 }
 
+// resolveSpans returns the spans for which resolve intent commands
+// are sent when the transaction is closed. Key ranges which overlap or
+// abut are coalesced, so that runs of consecutive keys are resolved
+// as a single range, each with one pass of an engine iterator. Spans
+// separated by keys the transaction didn't write are never coalesced,
+// as resolving the gap would scan data unrelated to the transaction.
+func (tm *txnMetadata) resolveSpans() []intentSpan {
+	overlaps := tm.keys.GetOverlaps(engine.KeyMin, engine.KeyMax)
+	spans := make(intentSpans, len(overlaps))
+	for i, o := range overlaps {
+		spans[i] = intentSpan{start: o.Key.Start().(proto.Key), end: o.Key.End().(proto.Key)}
+	}
+	if len(spans) <= 1 {
+		return spans
+	}
+	sort.Sort(spans)
+	coalesced := []intentSpan{spans[0]}
+	for _, s := range spans[1:] {
+		last := &coalesced[len(coalesced)-1]
+		if last.end.Less(s.start) {
+			coalesced = append(coalesced, s)
+			continue
+		}
+		if last.end.Less(s.end) {
+			last.end = s.end
+		}
+	}
+	return coalesced
+}
+
 // close sends resolve intent commands for all key ranges this
 // transaction has covered, clears the keys cache and closes the
 // metadata heartbeat. Any keys listed in the resolved slice have
 // already been resolved and do not receive resolve intent commands.
+//
+// Individually written keys are resolved in batches of up to
+// resolveIntentBatchSize keys. Each batch is sent as a single command
+// listing its keys, which the sender splits by range, so that each
+// range resolves all of its keys in the batch with one command.
 func (tm *txnMetadata) close(txn *proto.Transaction, resolved []proto.Key, sender client.KVSender, stopper *util.Stopper) {
 	if tm.keys.Len() > 0 {
 		log.V(1).Infof("cleaning up %d intent(s) for transaction %s", tm.keys.Len(), txn)
 	}
-	for _, o := range tm.keys.GetOverlaps(engine.KeyMin, engine.KeyMax) {
-		call := &client.Call{
-			Method: proto.InternalResolveIntent,
-			Args: &proto.InternalResolveIntentRequest{
-				RequestHeader: proto.RequestHeader{
-					Timestamp: txn.Timestamp,
-					Key:       o.Key.Start().(proto.Key),
-					User:      storage.UserRoot,
-					Txn:       txn,
-				},
-			},
-			Reply: &proto.InternalResolveIntentResponse{},
+	var keys []proto.Key
+	for _, span := range tm.resolveSpans() {
+		if !span.start.Next().Equal(span.end) {
+			resolveIntents(txn, span.start, span.end, nil, sender, stopper)
+			continue
 		}
-		// Set the end key only if it's not equal to Key.Next(). This
-		// saves us from unnecessarily clearing intents as a range.
-		endKey := o.Key.End().(proto.Key)
-		if !call.Args.Header().Key.Next().Equal(endKey) {
-			call.Args.Header().EndKey = endKey
-		} else {
-			// Check if the key has already been resolved; skip if yes.
-			found := false
-			for _, k := range resolved {
-				if call.Args.Header().Key.Equal(k) {
-					found = true
-				}
-			}
-			if found {
-				continue
+		// Check if the key has already been resolved; skip if yes.
+		found := false
+		for _, k := range resolved {
+			if span.start.Equal(k) {
+				found = true
 			}
 		}
-		// We don't care about the reply channel; these are best
-		// effort. We simply fire and forget, each in its own goroutine.
-		if stopper.StartTask() {
-			go func() {
-				log.V(1).Infof("cleaning up intent %q for txn %s", call.Args.Header().Key, txn)
-				sender.Send(call)
-				if call.Reply.Header().Error != nil {
-					log.Warningf("failed to cleanup %q intent: %s", call.Args.Header().Key, call.Reply.Header().GoError())
-				}
-				stopper.FinishTask()
-			}()
+		if !found {
+			keys = append(keys, span.start)
 		}
 	}
+	// The resolve spans are sorted, so each batch of keys is too.
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > resolveIntentBatchSize {
+			n = resolveIntentBatchSize
+		}
+		if n == 1 {
+			resolveIntents(txn, keys[0], nil, nil, sender, stopper)
+		} else {
+			resolveIntents(txn, keys[0], keys[n-1].Next(), keys[:n], sender, stopper)
+		}
+		keys = keys[n:]
+	}
 	tm.keys.Clear()
 }
 
+// resolveIntents sends a resolve intent command for the transaction's
+// intents on key, on the key range [key, endKey) or, if keys is not
+// empty, on the listed keys within that range. We don't care about the
+// reply; these are best effort. We simply fire and forget, each in its
+// own goroutine.
+func resolveIntents(txn *proto.Transaction, key, endKey proto.Key, keys []proto.Key, sender client.KVSender, stopper *util.Stopper) {
+	call := &client.Call{
+		Method: proto.InternalResolveIntent,
+		Args: &proto.InternalResolveIntentRequest{
+			RequestHeader: proto.RequestHeader{
+				Timestamp: txn.Timestamp,
+				Key:       key,
+				EndKey:    endKey,
+				User:      storage.UserRoot,
+				Txn:       txn,
+			},
+			Keys: keys,
+		},
+		Reply: &proto.InternalResolveIntentResponse{},
+	}
+	if stopper.StartTask() {
+		go func() {
+			log.V(1).Infof("cleaning up intents on %q-%q (%d listed keys) for txn %s", key, endKey, len(keys), txn)
+			sender.Send(call)
+			if call.Reply.Header().Error != nil {
+				log.Warningf("failed to cleanup intents on %q-%q: %s", key, endKey, call.Reply.Header().GoError())
+			}
+			stopper.FinishTask()
+		}()
+	}
+}
+
 // A TxnCoordSender is an implementation of client.KVSender which
 // wraps a lower-level KVSender (either a LocalSender or a DistSender)
 // to which it sends commands. It acts as a man-in-the-middle,
@@ -159,7 +280,9 @@ func (tm *txnMetadata) close(txn *proto.Transaction, resolved []proto.Key, sende
 // messages to that transaction's txn record, to keep it live. It also
 // keeps track of each written key or key range over the course of the
 // transaction. When the transaction is committed or aborted, it
-// clears accumulated write intents for the transaction.
+// clears accumulated write intents for the transaction. Commit, abort
+// and restart statistics are aggregated by transaction application
+// name; see TxnStats.
 type TxnCoordSender struct {
 	wrapped           client.KVSender
 	clock             *hlc.Clock
@@ -169,6 +292,10 @@ type TxnCoordSender struct {
 	txns              map[string]*txnMetadata // txn key to metadata
 	linearizable      bool                    // Enables linearizable behaviour.
 	stopper           *util.Stopper
+	stats             txnStatsMap // Txn stats by application name
+	maxIntents        int64       // Default max intents per txn; 0 for no limit
+	maxIntentBytes    int64       // Default max intent bytes per txn; 0 for no limit
+	traceSampleRate   float64     // Fraction of untraced requests assigned a trace ID
 }
 
 // NewTxnCoordSender creates a new TxnCoordSender for use from a KV
@@ -188,12 +315,46 @@ func NewTxnCoordSender(wrapped client.KVSender, clock *hlc.Clock, linearizable b
 	return tc
 }
 
+// SetIntentLimits sets the default maximum number of intents and
+// total intent bytes which a single transaction may write through
+// the coordinator. A transaction's MaxIntents and MaxIntentBytes, if
+// non-zero, override these defaults. Zero imposes no limit. The
+// limits are cluster settings held by the default zone config, which
+// the node's stores apply as it changes.
+func (tc *TxnCoordSender) SetIntentLimits(maxIntents, maxIntentBytes int64) {
+	tc.Lock()
+	defer tc.Unlock()
+	tc.maxIntents = maxIntents
+	tc.maxIntentBytes = maxIntentBytes
+}
+
+// SetTraceSampleRate sets the fraction of requests without a trace ID
+// to which the coordinator assigns one, so that the stores executing
+// them log the spans of their execution. Zero traces only requests
+// which arrive with a trace ID. It must be called before the
+// coordinator is used.
+func (tc *TxnCoordSender) SetTraceSampleRate(rate float64) {
+	tc.traceSampleRate = rate
+}
+
+// maybeTrace assigns a random trace ID to a sample of the requests
+// which don't already belong to a trace.
+func (tc *TxnCoordSender) maybeTrace(header *proto.RequestHeader) {
+	if header.TraceID != 0 || tc.traceSampleRate <= 0 || rand.Float64() >= tc.traceSampleRate {
+		return
+	}
+	for header.TraceID == 0 {
+		header.TraceID = rand.Int63()
+	}
+}
+
 // Send implements the client.KVSender interface. If the call is part
 // of a transaction, the coordinator will initialize the transaction
 // if it's not nil but has an empty ID.
 func (tc *TxnCoordSender) Send(call *client.Call) {
 	header := call.Args.Header()
 	tc.maybeBeginTxn(header)
+	tc.maybeTrace(header)
 
 	// Process batch specially; otherwise, send via wrapped sender.
 	if call.Method == proto.Batch {
@@ -205,7 +366,8 @@ func (tc *TxnCoordSender) Send(call *client.Call) {
 
 // maybeBeginTxn begins a new transaction if a txn has been specified
 // in the request but has a nil ID. The new transaction is initialized
-// using the name and isolation in the otherwise uninitialized txn.
+// using the name, isolation and application name in the otherwise
+// uninitialized txn.
 // The Priority, if non-zero is used as a minimum.
 func (tc *TxnCoordSender) maybeBeginTxn(header *proto.RequestHeader) {
 	if header.Txn != nil {
@@ -217,6 +379,9 @@ func (tc *TxnCoordSender) maybeBeginTxn(header *proto.RequestHeader) {
 			if newTxn.Priority < header.Txn.Priority {
 				newTxn.Priority = header.Txn.Priority
 			}
+			newTxn.AppName = header.Txn.AppName
+			newTxn.MaxIntents = header.Txn.MaxIntents
+			newTxn.MaxIntentBytes = header.Txn.MaxIntentBytes
 			header.Txn = newTxn
 		}
 	}
@@ -237,6 +402,7 @@ func (tc *TxnCoordSender) maybeBeginTxn(header *proto.RequestHeader) {
 // of intents.
 func (tc *TxnCoordSender) sendOne(call *client.Call) {
 	var startNS int64
+	var reservation *intentReservation
 	header := call.Args.Header()
 	// If this call is part of a transaction...
 	if header.Txn != nil {
@@ -255,6 +421,15 @@ func (tc *TxnCoordSender) sendOne(call *client.Call) {
 			// be linearizable.
 			startNS = tc.clock.PhysicalNow()
 		}
+		// Fail writes which would take the transaction past its intent
+		// limits before they lay down any intents.
+		if proto.IsTransactional(call.Method) {
+			var err error
+			if reservation, err = tc.reserveIntents(header.Txn, call.Args); err != nil {
+				call.Reply.Header().SetGoError(err)
+				return
+			}
+		}
 	}
 
 	// Send the command through wrapped sender.
@@ -268,32 +443,18 @@ func (tc *TxnCoordSender) sendOne(call *client.Call) {
 		tc.updateResponseTxn(header, call.Reply.Header())
 	}
 
-	// If successful, we're in a transaction, and the command leaves
-	// transactional intents, add the key or key range to the intents map.
-	// If the transaction metadata doesn't yet exist, create it.
-	if call.Reply.Header().GoError() == nil && header.Txn != nil && proto.IsTransactional(call.Method) {
-		tc.Lock()
-		var ok bool
-		var txnMeta *txnMetadata
-		if txnMeta, ok = tc.txns[string(header.Txn.ID)]; !ok {
-			txnMeta = &txnMetadata{
-				txn:             *header.Txn,
-				keys:            util.NewIntervalCache(util.CacheConfig{Policy: util.CacheNone}),
-				lastUpdateTS:    tc.clock.Now(),
-				timeoutDuration: tc.clientTimeout,
-			}
-			tc.txns[string(header.Txn.ID)] = txnMeta
-			tc.heartbeat(header.Txn)
-		}
-		txnMeta.lastUpdateTS = tc.clock.Now()
-		txnMeta.addKeyRange(header.Key, header.EndKey)
-		tc.Unlock()
+	// If we're in a transaction and the command leaves transactional
+	// intents, release its reservation and, if successful, add the key
+	// or key range to the intents map.
+	if reservation != nil {
+		tc.recordIntents(header.Txn, reservation, call.Args, call.Reply)
 	}
 
 	// Cleanup intents and transaction map if end of transaction.
 	switch t := call.Reply.Header().GoError().(type) {
 	case *proto.TransactionAbortedError:
 		// If already aborted, cleanup the txn on this TxnCoordSender.
+		tc.recordTxnEnd(header.Txn, proto.ABORTED)
 		tc.cleanupTxn(&t.Txn, nil)
 	case *proto.OpRequiresTxnError:
 		// Run a one-off transaction with that single command.
@@ -337,11 +498,130 @@ func (tc *TxnCoordSender) sendOne(call *client.Call) {
 			resolved = call.Reply.(*proto.EndTransactionResponse).Resolved
 		}
 		if txn != nil && txn.Status != proto.PENDING {
+			tc.recordTxnEnd(txn, txn.Status)
 			tc.cleanupTxn(txn, resolved)
 		}
 	}
 }
 
+// txnMetadataLocked returns the metadata of txn, creating it if it
+// doesn't yet exist. The caller must hold the lock.
+func (tc *TxnCoordSender) txnMetadataLocked(txn *proto.Transaction) *txnMetadata {
+	txnMeta, ok := tc.txns[string(txn.ID)]
+	if !ok {
+		txnMeta = &txnMetadata{
+			txn:              *txn,
+			keys:             util.NewIntervalCache(util.CacheConfig{Policy: util.CacheNone}),
+			intents:          map[string]int64{},
+			lastUpdateTS:     tc.clock.Now(),
+			firstUpdateNanos: tc.clock.PhysicalNow(),
+			timeoutDuration:  tc.clientTimeout,
+		}
+		tc.txns[string(txn.ID)] = txnMeta
+	}
+	return txnMeta
+}
+
+// An intentReservation holds the intents and bytes reserved for an
+// in-flight write in the metadata of its transaction.
+type intentReservation struct {
+	txnMeta        *txnMetadata
+	intents, bytes int64
+}
+
+// reserveIntents returns an error if writing args would take the
+// transaction past the maximum number of intents or intent bytes
+// allowed it, counting the writes already in flight. Otherwise, the
+// intents and bytes of args are reserved until recordIntents is
+// called. A rewrite of a key written before reserves no new intent,
+// while a range write reserves a single intent, as the number of keys
+// it deletes isn't known until it completes. A transaction's own
+// limits, if non-zero, take precedence over the coordinator's
+// defaults.
+func (tc *TxnCoordSender) reserveIntents(txn *proto.Transaction, args proto.Request) (*intentReservation, error) {
+	tc.Lock()
+	defer tc.Unlock()
+	maxIntents, maxIntentBytes := tc.maxIntents, tc.maxIntentBytes
+	if txn.MaxIntents != 0 {
+		maxIntents = txn.MaxIntents
+	}
+	if txn.MaxIntentBytes != 0 {
+		maxIntentBytes = txn.MaxIntentBytes
+	}
+	txnMeta := tc.txnMetadataLocked(txn)
+	r := &intentReservation{txnMeta: txnMeta, intents: 1, bytes: intentBytes(args)}
+	if key := args.Header().Key; len(args.Header().EndKey) == 0 {
+		if prev, ok := txnMeta.intents[string(key)]; ok {
+			r.intents, r.bytes = 0, r.bytes-prev
+		}
+	}
+	count := txnMeta.intentCount() + txnMeta.pendingIntents
+	if maxIntents > 0 && r.intents > 0 && count+r.intents > maxIntents {
+		tc.releaseTxnMetadataLocked(txn, txnMeta)
+		return nil, util.Errorf("transaction %q exceeded its limit of %d intents", txn.Name, maxIntents)
+	}
+	bytes := txnMeta.intentBytes + txnMeta.pendingBytes
+	if maxIntentBytes > 0 && r.bytes > 0 && bytes+r.bytes > maxIntentBytes {
+		tc.releaseTxnMetadataLocked(txn, txnMeta)
+		return nil, util.Errorf("transaction %q exceeded its limit of %d intent bytes", txn.Name, maxIntentBytes)
+	}
+	txnMeta.pendingIntents += r.intents
+	txnMeta.pendingBytes += r.bytes
+	return r, nil
+}
+
+// recordIntents releases the reservation of the write command args
+// and, if the command succeeded, records the intents it wrote. The
+// transaction's heartbeat is started by its first successful write.
+func (tc *TxnCoordSender) recordIntents(txn *proto.Transaction, r *intentReservation,
+	args proto.Request, reply proto.Response) {
+	tc.Lock()
+	defer tc.Unlock()
+	txnMeta, ok := tc.txns[string(txn.ID)]
+	if ok && txnMeta == r.txnMeta {
+		txnMeta.pendingIntents -= r.intents
+		txnMeta.pendingBytes -= r.bytes
+	}
+	if reply.Header().GoError() != nil {
+		if ok {
+			tc.releaseTxnMetadataLocked(txn, txnMeta)
+		}
+		return
+	}
+	txnMeta = tc.txnMetadataLocked(txn)
+	txnMeta.lastUpdateTS = tc.clock.Now()
+	txnMeta.recordIntents(args, reply)
+	if !txnMeta.heartbeating {
+		txnMeta.heartbeating = true
+		tc.heartbeat(txn)
+	}
+}
+
+// releaseTxnMetadataLocked removes the metadata of a transaction which
+// has neither written intents nor writes in flight, and so isn't
+// heartbeated. The caller must hold the lock.
+func (tc *TxnCoordSender) releaseTxnMetadataLocked(txn *proto.Transaction, txnMeta *txnMetadata) {
+	if !txnMeta.heartbeating && txnMeta.pendingIntents == 0 {
+		delete(tc.txns, string(txn.ID))
+	}
+}
+
+// intentBytes returns the number of bytes of keys and values written
+// by args.
+func intentBytes(args proto.Request) int64 {
+	header := args.Header()
+	n := len(header.Key) + len(header.EndKey)
+	switch t := args.(type) {
+	case *proto.PutRequest:
+		n += t.Value.Size()
+	case *proto.ConditionalPutRequest:
+		n += t.Value.Size()
+	case *proto.EnqueueMessageRequest:
+		n += t.Msg.Size()
+	}
+	return int64(n)
+}
+
 // sendBatch unrolls a batched command and sends each constituent
 // command in parallel.
 func (tc *TxnCoordSender) sendBatch(batchArgs *proto.BatchRequest, batchReply *proto.BatchResponse) {
@@ -366,6 +646,12 @@ func (tc *TxnCoordSender) sendBatch(batchArgs *proto.BatchRequest, batchReply *p
 			args.Header().UserPriority = batchArgs.UserPriority
 		}
 		args.Header().Txn = batchArgs.Txn
+		if args.Header().Deadline == 0 {
+			args.Header().Deadline = batchArgs.Deadline
+		}
+		if args.Header().TraceID == 0 {
+			args.Header().TraceID = batchArgs.TraceID
+		}
 
 		// Create a reply from the method type and add to batch response.
 		if i >= len(batchReply.Responses) {
@@ -421,6 +707,7 @@ func (tc *TxnCoordSender) updateResponseTxn(argsHeader *proto.RequestHeader, rep
 			replyHeader.Txn.Timestamp = candidateTS
 		}
 		replyHeader.Txn.Restart(argsHeader.GetUserPriority(), replyHeader.Txn.Priority, replyHeader.Txn.Timestamp)
+		tc.stats.recordRestart(replyHeader.Txn.AppName)
 	case *proto.TransactionAbortedError:
 		// Increase timestamp if applicable.
 		if replyHeader.Txn.Timestamp.Less(t.Txn.Timestamp) {
@@ -434,13 +721,41 @@ func (tc *TxnCoordSender) updateResponseTxn(argsHeader *proto.RequestHeader, rep
 			replyHeader.Txn.Timestamp.Logical++ // ensure this txn's timestamp > other txn
 		}
 		replyHeader.Txn.Restart(argsHeader.GetUserPriority(), t.PusheeTxn.Priority-1, replyHeader.Txn.Timestamp)
+		tc.stats.recordRestart(replyHeader.Txn.AppName)
 	case *proto.TransactionRetryError:
 		// Increase timestamp if applicable.
 		if replyHeader.Txn.Timestamp.Less(t.Txn.Timestamp) {
 			replyHeader.Txn.Timestamp = t.Txn.Timestamp
 		}
 		replyHeader.Txn.Restart(argsHeader.GetUserPriority(), t.Txn.Priority, replyHeader.Txn.Timestamp)
+		tc.stats.recordRestart(replyHeader.Txn.AppName)
+	}
+}
+
+// recordTxnEnd records the commit or abort of txn in the stats for
+// its application. Commit latency is measured from the first write
+// sent through this coordinator, if any.
+func (tc *TxnCoordSender) recordTxnEnd(txn *proto.Transaction, status proto.TransactionStatus) {
+	if status == proto.ABORTED {
+		tc.stats.recordAbort(txn.AppName)
+		return
+	}
+	latency := time.Duration(-1)
+	tc.Lock()
+	if txnMeta, ok := tc.txns[string(txn.ID)]; ok {
+		latency = time.Duration(tc.clock.PhysicalNow() - txnMeta.firstUpdateNanos)
 	}
+	tc.Unlock()
+	tc.stats.recordCommit(txn.AppName, latency)
+}
+
+// TxnStats returns a snapshot of the statistics for the transactions
+// coordinated by this TxnCoordSender, keyed by application name.
+// Transactions without an application name are keyed by the empty
+// string, and those of applications beyond the first hundred seen by
+// TxnStatsOtherApps.
+func (tc *TxnCoordSender) TxnStats() map[string]TxnStats {
+	return tc.stats.snapshot()
 }
 
 // cleanupTxn is called to resolve write intents which were set down over
@@ -482,7 +797,7 @@ func (tc *TxnCoordSender) hasClientAbandonedCoord(txnID []byte) bool {
 // aborted or committed or if the TxnCoordSender is closed.
 func (tc *TxnCoordSender) heartbeat(txn *proto.Transaction) {
 	tc.stopper.RunWorker(func() {
-		ticker := time.NewTicker(tc.heartbeatInterval)
+		ticker := util.NewTicker(tc.heartbeatInterval)
 		request := &proto.InternalHeartbeatTxnRequest{
 			RequestHeader: proto.RequestHeader{
 				Key:  txn.Key,
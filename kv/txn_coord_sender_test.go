@@ -21,6 +21,9 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -311,6 +314,117 @@ func TestTxnCoordSenderKeyRanges(t *testing.T) {
 	}
 }
 
+// TestTxnCoordSenderIntentLimits verifies that writes which would take
+// a transaction past the coordinator's intent limits fail, and that a
+// transaction may override the limits.
+func TestTxnCoordSenderIntentLimits(t *testing.T) {
+	db, _, clock, _, _, stopper, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	coord := getCoord(db)
+	defer stopper.Stop()
+	coord.SetIntentLimits(2, 0)
+
+	txn := newTxn(db, clock, proto.Key("a"))
+	for _, key := range []string{"a", "b"} {
+		if err := db.Call(proto.Put, createPutRequest(proto.Key(key), []byte("value"), txn), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Writing a new key exceeds the limit, but rewriting a key lays
+	// down no new intent.
+	err = db.Call(proto.Put, createPutRequest(proto.Key("c"), []byte("value"), txn), &proto.PutResponse{})
+	if err == nil || !strings.Contains(err.Error(), "exceeded its limit of 2 intents") {
+		t.Errorf("expected intent limit error writing \"c\"; got %v", err)
+	}
+	if err := db.Call(proto.Put, createPutRequest(proto.Key("a"), []byte("value"), txn), &proto.PutResponse{}); err != nil {
+		t.Errorf("expected rewrite to succeed; got %s", err)
+	}
+	if txnMeta := coord.txns[string(txn.ID)]; txnMeta.intentCount() != 2 {
+		t.Errorf("expected 2 intents; got %d", txnMeta.intentCount())
+	}
+
+	// The transaction's own limit takes precedence.
+	txn = newTxn(db, clock, proto.Key("d"))
+	txn.MaxIntents = 3
+	for _, key := range []string{"d", "e", "f"} {
+		if err := db.Call(proto.Put, createPutRequest(proto.Key(key), []byte("value"), txn), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Limit intent bytes instead.
+	coord.SetIntentLimits(0, 64)
+	txn = newTxn(db, clock, proto.Key("g"))
+	if err := db.Call(proto.Put, createPutRequest(proto.Key("g"), []byte("value"), txn), &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Call(proto.Put, createPutRequest(proto.Key("h"), make([]byte, 64), txn), &proto.PutResponse{})
+	if err == nil || !strings.Contains(err.Error(), "exceeded its limit of 64 intent bytes") {
+		t.Errorf("expected intent bytes limit error; got %v", err)
+	}
+}
+
+// TestTxnCoordSenderIntentReservations verifies that writes in flight
+// count against a transaction's intent limits, and that range writes
+// count the intents they laid down.
+func TestTxnCoordSenderIntentReservations(t *testing.T) {
+	db, _, clock, _, _, stopper, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	coord := getCoord(db)
+	defer stopper.Stop()
+	coord.SetIntentLimits(2, 0)
+
+	// Two concurrent writes reserve the transaction's intents, so a
+	// third fails until one of them completes unsuccessfully.
+	txn := newTxn(db, clock, proto.Key("a"))
+	var reservations []*intentReservation
+	for _, key := range []string{"a", "b"} {
+		r, err := coord.reserveIntents(txn, createPutRequest(proto.Key(key), []byte("value"), txn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reservations = append(reservations, r)
+	}
+	args := createPutRequest(proto.Key("c"), []byte("value"), txn)
+	if _, err := coord.reserveIntents(txn, args); err == nil {
+		t.Error("expected in-flight writes to count against the intent limit")
+	}
+	reply := &proto.PutResponse{}
+	reply.SetGoError(util.Errorf("write failed"))
+	coord.recordIntents(txn, reservations[1], createPutRequest(proto.Key("b"), []byte("value"), txn), reply)
+	if _, err := coord.reserveIntents(txn, args); err != nil {
+		t.Errorf("expected failed write to release its reservation; got %s", err)
+	}
+
+	// A range write counts each key it deleted.
+	coord.SetIntentLimits(0, 0)
+	txn = newTxn(db, clock, proto.Key("d"))
+	for _, key := range []string{"d", "e", "f"} {
+		if err := db.Call(proto.Put, proto.PutArgs(proto.Key(key), []byte("value")), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drArgs := &proto.DeleteRangeRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       proto.Key("d"),
+			EndKey:    proto.Key("g"),
+			User:      storage.UserRoot,
+			Timestamp: txn.Timestamp,
+			Txn:       txn,
+		},
+	}
+	if err := db.Call(proto.DeleteRange, drArgs, &proto.DeleteRangeResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if txnMeta := coord.txns[string(txn.ID)]; txnMeta.intentCount() != 3 {
+		t.Errorf("expected 3 intents; got %d", txnMeta.intentCount())
+	}
+}
+
 // TestTxnCoordSenderMultipleTxns verifies correct operation with
 // multiple outstanding transactions.
 func TestTxnCoordSenderMultipleTxns(t *testing.T) {
@@ -434,6 +548,7 @@ func TestTxnCoordSenderEndTxn(t *testing.T) {
 		Args: &proto.EndTransactionRequest{
 			RequestHeader: proto.RequestHeader{
 				Key:       txn.Key,
+				User:      storage.UserRoot,
 				Timestamp: txn.Timestamp,
 				Txn:       txn,
 			},
@@ -447,6 +562,141 @@ func TestTxnCoordSenderEndTxn(t *testing.T) {
 	verifyCleanup(key, db, eng, t)
 }
 
+// TestTxnCoordSenderBatchedResolve verifies that the intents of a
+// transaction on runs of consecutive keys, and on overlapping or
+// abutting key ranges, are coalesced into single spans for
+// resolution, that spans separated by a gap are not, and that all of
+// the transaction's intents are resolved on commit.
+func TestTxnCoordSenderBatchedResolve(t *testing.T) {
+	db, eng, clock, _, _, stopper, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopper.Stop()
+
+	a := proto.Key("a")
+	keys := []proto.Key{a, a.Next(), a.Next().Next(), proto.Key("c"), proto.Key("e")}
+	txn := newTxn(db, clock, keys[0])
+	for _, key := range keys {
+		if err := db.Call(proto.Put, createPutRequest(key, []byte("value"), txn), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, span := range []intentSpan{{proto.Key("d"), proto.Key("f")}, {proto.Key("f"), proto.Key("g")}} {
+		if err := db.Call(proto.DeleteRange, &proto.DeleteRangeRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       span.start,
+				EndKey:    span.end,
+				User:      storage.UserRoot,
+				Timestamp: txn.Timestamp,
+				Txn:       txn,
+			},
+		}, &proto.DeleteRangeResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	coord := getCoord(db)
+	coord.Lock()
+	spans := coord.txns[string(txn.ID)].resolveSpans()
+	coord.Unlock()
+	expSpans := []intentSpan{
+		{start: a, end: a.Next().Next().Next()},
+		{start: proto.Key("c"), end: proto.Key("c").Next()},
+		{start: proto.Key("d"), end: proto.Key("g")},
+	}
+	if !reflect.DeepEqual(spans, expSpans) {
+		t.Errorf("expected resolve spans %q; got %q", expSpans, spans)
+	}
+
+	if err := db.Call(proto.EndTransaction, &proto.EndTransactionRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       txn.Key,
+			User:      storage.UserRoot,
+			Timestamp: txn.Timestamp,
+			Txn:       txn,
+		},
+		Commit: true,
+	}, &proto.EndTransactionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		verifyCleanup(key, db, eng, t)
+	}
+}
+
+// TestTxnCoordSenderResolveKeysTogether verifies that the scattered
+// keys written by a transaction are resolved by a single command
+// listing them, that key ranges are resolved by a command each and
+// that keys which were already resolved are left out.
+func TestTxnCoordSenderResolveKeysTogether(t *testing.T) {
+	stopper := util.NewStopper()
+	var mu sync.Mutex
+	var calls []*proto.InternalResolveIntentRequest
+	sender := newTestSender(func(call *client.Call) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call.Args.(*proto.InternalResolveIntentRequest))
+	})
+	tm := &txnMetadata{
+		keys: util.NewIntervalCache(util.CacheConfig{Policy: util.CacheNone}),
+	}
+	for _, key := range []string{"a", "c", "e", "g"} {
+		tm.addKeyRange(proto.Key(key), nil)
+	}
+	tm.addKeyRange(proto.Key("x"), proto.Key("z"))
+	txn := &proto.Transaction{ID: []byte("txn"), Status: proto.COMMITTED}
+	tm.close(txn, []proto.Key{proto.Key("g")}, sender, stopper)
+	stopper.Stop()
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 resolve commands; got %d", len(calls))
+	}
+	sort.Sort(resolveRequests(calls))
+	expKeys := []proto.Key{proto.Key("a"), proto.Key("c"), proto.Key("e")}
+	if args := calls[0]; !args.Key.Equal(proto.Key("a")) || !args.EndKey.Equal(proto.Key("e").Next()) ||
+		!reflect.DeepEqual(args.Keys, expKeys) {
+		t.Errorf("expected keys %q in [a, e.Next()); got %q in [%q, %q)", expKeys, args.Keys, args.Key, args.EndKey)
+	}
+	if args := calls[1]; !args.Key.Equal(proto.Key("x")) || !args.EndKey.Equal(proto.Key("z")) || len(args.Keys) != 0 {
+		t.Errorf("expected the key range [x, z); got %q in [%q, %q)", args.Keys, args.Key, args.EndKey)
+	}
+
+	// Resolving scattered keys together clears all of their intents.
+	db, eng, clock, _, _, stopper, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopper.Stop()
+	txn = newTxn(db, clock, proto.Key("a"))
+	for _, key := range expKeys {
+		if err := db.Call(proto.Put, createPutRequest(key, []byte("value"), txn), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Call(proto.EndTransaction, &proto.EndTransactionRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       txn.Key,
+			User:      storage.UserRoot,
+			Timestamp: txn.Timestamp,
+			Txn:       txn,
+		},
+		Commit: true,
+	}, &proto.EndTransactionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range expKeys {
+		verifyCleanup(key, db, eng, t)
+	}
+}
+
+// resolveRequests sorts resolve intent requests by key.
+type resolveRequests []*proto.InternalResolveIntentRequest
+
+func (r resolveRequests) Len() int           { return len(r) }
+func (r resolveRequests) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r resolveRequests) Less(i, j int) bool { return r[i].Key.Less(r[j].Key) }
+
 // TestTxnCoordSenderCleanupOnAborted verifies that if a txn receives a
 // TransactionAbortedError, the coordinator cleans up the transaction.
 func TestTxnCoordSenderCleanupOnAborted(t *testing.T) {
@@ -631,3 +881,119 @@ func TestTxnCoordSenderTxnUpdatedOnError(t *testing.T) {
 		}
 	}
 }
+
+// TestTxnCoordSenderStats verifies that commits, aborts and restarts
+// are aggregated by transaction application name.
+func TestTxnCoordSenderStats(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+
+	var putErr error
+	ts := NewTxnCoordSender(newTestSender(func(call *client.Call) {
+		switch call.Method {
+		case proto.Put:
+			call.Reply.Header().SetGoError(putErr)
+		case proto.EndTransaction:
+			txn := gogoproto.Clone(call.Args.Header().Txn).(*proto.Transaction)
+			txn.Status = proto.COMMITTED
+			call.Reply.Header().Txn = txn
+		}
+	}), clock, false, stopper)
+
+	send := func(method, appName string, args proto.Request, reply proto.Response) {
+		args.Header().Key = proto.Key("a")
+		args.Header().User = storage.UserRoot
+		args.Header().Txn = &proto.Transaction{Name: "test", AppName: appName}
+		ts.Send(&client.Call{Method: method, Args: args, Reply: reply})
+	}
+	// Application "a" writes and then commits 10ns later.
+	pArgs := &proto.PutRequest{}
+	pReply := &proto.PutResponse{}
+	send(proto.Put, "a", pArgs, pReply)
+	manual.Set(10)
+	ts.Send(&client.Call{
+		Method: proto.EndTransaction,
+		Args: &proto.EndTransactionRequest{
+			RequestHeader: proto.RequestHeader{Key: proto.Key("a"), User: storage.UserRoot, Txn: pReply.Txn},
+			Commit:        true,
+		},
+		Reply: &proto.EndTransactionResponse{},
+	})
+	// Application "b" restarts and is then aborted.
+	putErr = &proto.TransactionRetryError{}
+	send(proto.Put, "b", &proto.PutRequest{}, &proto.PutResponse{})
+	putErr = &proto.TransactionAbortedError{}
+	send(proto.Put, "b", &proto.PutRequest{}, &proto.PutResponse{})
+	// An untagged read-only transaction commits.
+	send(proto.EndTransaction, "", &proto.EndTransactionRequest{Commit: true}, &proto.EndTransactionResponse{})
+
+	expStats := map[string]TxnStats{
+		"a": {Commits: 1, WriteCommits: 1, CommitLatencyNanos: 10, MaxCommitLatencyNanos: 10},
+		"b": {Aborts: 1, Restarts: 1},
+		"":  {Commits: 1},
+	}
+	if stats := ts.TxnStats(); !reflect.DeepEqual(stats, expStats) {
+		t.Errorf("expected stats %+v; got %+v", expStats, stats)
+	}
+}
+
+// TestTxnStatsMapBounded verifies that the stats of applications
+// beyond the first maxTxnStatsApps are aggregated under
+// TxnStatsOtherApps.
+func TestTxnStatsMapBounded(t *testing.T) {
+	var m txnStatsMap
+	for i := 0; i < maxTxnStatsApps+10; i++ {
+		m.recordAbort(fmt.Sprintf("app%d", i))
+	}
+	// Applications already tracked continue to be.
+	m.recordAbort("app0")
+
+	stats := m.snapshot()
+	if len(stats) != maxTxnStatsApps+1 {
+		t.Errorf("expected stats for %d applications; got %d", maxTxnStatsApps+1, len(stats))
+	}
+	if s := stats["app0"]; s.Aborts != 2 {
+		t.Errorf("expected 2 aborts for app0; got %d", s.Aborts)
+	}
+	if s := stats[TxnStatsOtherApps]; s.Aborts != 10 {
+		t.Errorf("expected 10 aborts for other applications; got %d", s.Aborts)
+	}
+}
+
+// TestTxnCoordSenderTraceSampling verifies that the coordinator assigns
+// trace IDs to sampled requests, leaves those of traced requests
+// intact and propagates the trace ID of a batch to its requests.
+func TestTxnCoordSenderTraceSampling(t *testing.T) {
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	var traceIDs []int64
+	ts := NewTxnCoordSender(newTestSender(func(call *client.Call) {
+		traceIDs = append(traceIDs, call.Args.Header().TraceID)
+	}), hlc.NewClock(hlc.UnixNano), false, stopper)
+
+	// Without sampling, requests are sent untraced.
+	ts.Send(&client.Call{Method: proto.Get, Args: &proto.GetRequest{}, Reply: &proto.GetResponse{}})
+	// A request's own trace ID is kept.
+	ts.SetTraceSampleRate(1)
+	args := &proto.GetRequest{}
+	args.TraceID = 7
+	ts.Send(&client.Call{Method: proto.Get, Args: args, Reply: &proto.GetResponse{}})
+	// Sampled requests are assigned a trace ID.
+	ts.Send(&client.Call{Method: proto.Get, Args: &proto.GetRequest{}, Reply: &proto.GetResponse{}})
+	// The requests of a batch belong to the batch's trace.
+	bArgs := &proto.BatchRequest{}
+	bArgs.Add(&proto.GetRequest{})
+	ts.Send(&client.Call{Method: proto.Batch, Args: bArgs, Reply: &proto.BatchResponse{}})
+
+	if len(traceIDs) != 4 {
+		t.Fatalf("expected 4 requests; got %d", len(traceIDs))
+	}
+	if traceIDs[0] != 0 || traceIDs[1] != 7 || traceIDs[2] == 0 {
+		t.Errorf("unexpected trace IDs %v", traceIDs)
+	}
+	if traceIDs[3] == 0 || traceIDs[3] != bArgs.TraceID {
+		t.Errorf("expected batched request to have the batch's trace ID %d; got %d", bArgs.TraceID, traceIDs[3])
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// maxTxnStatsApps bounds the number of application names for which
+	// stats are kept. Application names are supplied by clients, so
+	// without a bound the stats could grow without limit.
+	maxTxnStatsApps = 100
+	// TxnStatsOtherApps is the name under which the stats of
+	// applications beyond the first maxTxnStatsApps are aggregated.
+	TxnStatsOtherApps = "(other)"
+)
+
+// TxnStats holds statistics for the transactions of a single
+// application, as identified by the AppName in the transaction
+// proto, which were coordinated by a TxnCoordSender.
+type TxnStats struct {
+	Commits  int64 `json:"commits"`  // Committed transactions
+	Aborts   int64 `json:"aborts"`   // Aborted transactions
+	Restarts int64 `json:"restarts"` // Transaction restarts (epoch increments)
+	// Latencies are only known for transactions which wrote through
+	// the coordinator, and are measured from the first such write to
+	// the commit. WriteCommits counts the commits of these
+	// transactions.
+	WriteCommits          int64 `json:"write_commits"`
+	CommitLatencyNanos    int64 `json:"commit_latency_nanos"`
+	MaxCommitLatencyNanos int64 `json:"max_commit_latency_nanos"`
+}
+
+// txnStatsMap aggregates TxnStats by application name. It is safe for
+// concurrent access.
+type txnStatsMap struct {
+	sync.Mutex
+	stats map[string]*TxnStats
+}
+
+// get returns the stats for appName, creating them if necessary. Once
+// maxTxnStatsApps applications are tracked, the stats of new ones are
+// aggregated under TxnStatsOtherApps. The caller must hold the lock.
+func (m *txnStatsMap) get(appName string) *TxnStats {
+	if m.stats == nil {
+		m.stats = map[string]*TxnStats{}
+	}
+	s, ok := m.stats[appName]
+	if !ok && len(m.stats) >= maxTxnStatsApps {
+		appName = TxnStatsOtherApps
+		s, ok = m.stats[appName]
+	}
+	if !ok {
+		s = &TxnStats{}
+		m.stats[appName] = s
+	}
+	return s
+}
+
+// recordCommit records a commit. A negative latency indicates the
+// latency is unknown.
+func (m *txnStatsMap) recordCommit(appName string, latency time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	s := m.get(appName)
+	s.Commits++
+	if latency >= 0 {
+		s.WriteCommits++
+		s.CommitLatencyNanos += latency.Nanoseconds()
+		if latency.Nanoseconds() > s.MaxCommitLatencyNanos {
+			s.MaxCommitLatencyNanos = latency.Nanoseconds()
+		}
+	}
+}
+
+// recordAbort records an abort.
+func (m *txnStatsMap) recordAbort(appName string) {
+	m.Lock()
+	defer m.Unlock()
+	m.get(appName).Aborts++
+}
+
+// recordRestart records a restart.
+func (m *txnStatsMap) recordRestart(appName string) {
+	m.Lock()
+	defer m.Unlock()
+	m.get(appName).Restarts++
+}
+
+// snapshot returns a copy of the stats, keyed by application name.
+func (m *txnStatsMap) snapshot() map[string]TxnStats {
+	m.Lock()
+	defer m.Unlock()
+	stats := make(map[string]TxnStats, len(m.stats))
+	for appName, s := range m.stats {
+		stats[appName] = *s
+	}
+	return stats
+}
@@ -17,7 +17,11 @@
 
 package multiraft
 
-import "time"
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+)
 
 // Ticker encapsulates the timing-related parts of the raft protocol.
 type Ticker interface {
@@ -28,11 +32,11 @@ type Ticker interface {
 }
 
 type realTicker struct {
-	*time.Ticker
+	*util.Ticker
 }
 
 func newTicker(interval time.Duration) Ticker {
-	return &realTicker{time.NewTicker(interval)}
+	return &realTicker{util.NewTicker(interval)}
 }
 
 func (t *realTicker) Chan() <-chan time.Time {
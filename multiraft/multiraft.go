@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
@@ -64,6 +65,25 @@ type Config struct {
 	HeartbeatIntervalTicks int
 	TickInterval           time.Duration
 
+	// QuiesceTicks is the number of ticks a group may go without
+	// proposals or messages other than heartbeats before it is
+	// quiesced: it is removed from the raft node so that it is neither
+	// ticked nor heartbeated, and is recreated from storage when the
+	// next proposal or message for it arrives. Leaders wait an
+	// additional election timeout so that their followers quiesce
+	// first and don't call an election. Zero disables quiescing.
+	QuiesceTicks int
+
+	// ReproposalTicks is the number of ticks a proposed command may go
+	// uncommitted before it is proposed again. Proposals may be dropped
+	// without notice, for instance when there is no leader or the leader
+	// loses its leadership before appending them, and are otherwise only
+	// proposed again when the group's committed term advances. Since a
+	// command may then be committed more than once, the state machine
+	// must deduplicate commands by their IDs. Membership changes aren't
+	// reproposed. Zero disables reproposal.
+	ReproposalTicks int
+
 	// If Strict is true, some warnings become fatal panics and additional (possibly expensive)
 	// sanity checks will be done.
 	Strict bool
@@ -102,6 +122,14 @@ type MultiRaft struct {
 	proposalChan    chan *proposal
 	// callbackChan is a generic hook to run a callback in the raft thread.
 	callbackChan chan func()
+
+	// matchMu protects matchIndexes.
+	matchMu sync.Mutex
+	// matchIndexes maps from group ID to the highest log index
+	// acknowledged by each follower, as observed by this node while
+	// leading the group. They're cleared on changes of leadership and
+	// of the followers' membership.
+	matchIndexes map[uint64]map[NodeID]uint64
 }
 
 // multiraftServer is a type alias to separate RPC methods
@@ -145,6 +173,7 @@ func NewMultiRaft(nodeID NodeID, config *Config) (*MultiRaft, error) {
 		removeGroupChan: make(chan *removeGroupOp, 100),
 		proposalChan:    make(chan *proposal, 100),
 		callbackChan:    make(chan func(), 100),
+		matchIndexes:    map[uint64]map[NodeID]uint64{},
 	}
 
 	err = m.Transport.Listen(nodeID, (*multiraftServer)(m))
@@ -289,6 +318,21 @@ func (m *MultiRaft) RemoveGroup(groupID uint64) error {
 	return <-op.ch
 }
 
+// FollowerMatchIndexes returns the highest log index acknowledged by
+// each follower of the given group. Followers are only reported once
+// they have acknowledged an append from this node since it became the
+// group's leader and since their latest addition to the group, so the
+// result is empty unless this node leads the group.
+func (m *MultiRaft) FollowerMatchIndexes(groupID uint64) map[NodeID]uint64 {
+	m.matchMu.Lock()
+	defer m.matchMu.Unlock()
+	indexes := map[NodeID]uint64{}
+	for nodeID, index := range m.matchIndexes[groupID] {
+		indexes[nodeID] = index
+	}
+	return indexes
+}
+
 // SubmitCommand sends a command (a binary blob) to the cluster. This method returns
 // when the command has been successfully sent, not when it has been committed.
 // An error or nil will be written to the returned channel when the command has
@@ -333,16 +377,19 @@ func (m *MultiRaft) ChangeGroupMembership(groupID uint64, commandID string,
 			}
 
 		},
-		ch: ch,
+		ch:         ch,
+		confChange: true,
 	}
 	return ch
 }
 
 type proposal struct {
-	groupID   uint64
-	commandID string
-	fn        func()
-	ch        chan<- error
+	groupID    uint64
+	commandID  string
+	fn         func()
+	ch         chan<- error
+	confChange bool // True for membership changes, which aren't reproposed
+	ticks      int  // Ticks since the proposal was last proposed
 }
 
 // group represents the state of a consensus group.
@@ -363,6 +410,16 @@ type group struct {
 	// is written to proposal.ch and it is removed from this
 	// map.
 	pending map[string]*proposal
+
+	// idleTicks counts the ticks since the group last saw a proposal
+	// or a message other than a heartbeat.
+	idleTicks int
+
+	// waking is true while a group woken from quiescence hasn't yet
+	// learned of a leader, and wakeTicks counts the ticks since it was
+	// woken.
+	waking    bool
+	wakeTicks int
 }
 
 type createGroupOp struct {
@@ -397,6 +454,7 @@ type state struct {
 	*MultiRaft
 	groups        map[uint64]*group
 	nodes         map[NodeID]*node
+	quiesced      map[uint64]struct{} // Groups quiesced for lack of activity
 	electionTimer *time.Timer
 	writeTask     *writeTask
 	stopper       *util.Stopper
@@ -411,6 +469,7 @@ func newState(m *MultiRaft) *state {
 		MultiRaft: m,
 		groups:    make(map[uint64]*group),
 		nodes:     make(map[NodeID]*node),
+		quiesced:  make(map[uint64]struct{}),
 		writeTask: newWriteTask(m.Storage),
 		clock: func() int64 {
 			return time.Now().UnixNano()
@@ -470,6 +529,10 @@ func (s *state) start(stopper *util.Stopper) {
 					s.fanoutHeartbeatResponse(req)
 				default:
 					if _, ok := s.groups[req.GroupID]; !ok {
+						if a, ok := s.Storage.(GroupAdmitter); ok && !a.AdmitGroup(req.GroupID, req.Message) {
+							log.V(1).Infof("node %v: dropping %s for removed group %d", s.nodeID, req.Message.Type, req.GroupID)
+							break
+						}
 						log.Infof("node %v: got message for unknown group %d; creating it", s.nodeID, req.GroupID)
 						if err := s.createGroup(req.GroupID); err != nil {
 							log.Warningf("Error creating group %d: %s", req.GroupID, err)
@@ -489,6 +552,12 @@ func (s *state) start(stopper *util.Stopper) {
 						continue
 					}
 
+					s.groups[req.GroupID].idleTicks = 0
+
+					if req.Message.Type == raftpb.MsgAppResp && !req.Message.Reject {
+						s.recordMatchIndex(req.GroupID, NodeID(req.Message.From), req.Message.Index)
+					}
+
 					if err := s.multiNode.Step(context.Background(), req.GroupID, req.Message); err != nil {
 						log.V(4).Infof("node %v: multinode step failed for message %s", s.nodeID, req.GroupID,
 							raft.DescribeMessage(req.Message, s.EntryFormatter))
@@ -526,6 +595,13 @@ func (s *state) start(stopper *util.Stopper) {
 					ticks = 0
 					s.coalescedHeartbeat()
 				}
+				if s.ReproposalTicks > 0 {
+					s.reproposeStalled()
+				}
+				if s.QuiesceTicks > 0 {
+					s.quiesceIdleGroups(readyGroups, writingGroups)
+					s.campaignWokenGroups()
+				}
 
 			case cb := <-s.callbackChan:
 				cb()
@@ -614,6 +690,9 @@ func (s *state) createGroup(groupID uint64) error {
 	log.V(6).Infof("node %v creating group %v", s.nodeID, groupID)
 
 	gs := s.Storage.GroupStorage(groupID)
+	if gs == nil {
+		return util.Errorf("group %d has been removed", groupID)
+	}
 	_, cs, err := gs.InitialState()
 	if err != nil {
 		return err
@@ -642,6 +721,7 @@ func (s *state) createGroup(groupID uint64) error {
 	s.groups[groupID] = &group{
 		pending: map[string]*proposal{},
 	}
+	delete(s.quiesced, groupID)
 
 	for _, nodeID := range cs.Nodes {
 		if err := s.addNode(NodeID(nodeID), groupID); err != nil {
@@ -655,6 +735,7 @@ func (s *state) createGroup(groupID uint64) error {
 func (s *state) removeGroup(op *removeGroupOp) {
 	// Group creation is lazy and idempotent; so is removal.
 	if _, ok := s.groups[op.groupID]; !ok {
+		delete(s.quiesced, op.groupID)
 		op.ch <- nil
 		return
 	}
@@ -671,19 +752,147 @@ func (s *state) removeGroup(op *removeGroupOp) {
 		s.nodes[NodeID(nodeID)].unregisterGroup(op.groupID)
 	}
 	delete(s.groups, op.groupID)
+	s.clearMatchIndexes(op.groupID)
 	op.ch <- nil
 }
 
+// recordMatchIndex records a follower's acknowledgement of log
+// entries through index.
+func (s *state) recordMatchIndex(groupID uint64, nodeID NodeID, index uint64) {
+	s.matchMu.Lock()
+	defer s.matchMu.Unlock()
+	indexes, ok := s.matchIndexes[groupID]
+	if !ok {
+		indexes = map[NodeID]uint64{}
+		s.matchIndexes[groupID] = indexes
+	}
+	if index > indexes[nodeID] {
+		indexes[nodeID] = index
+	}
+}
+
+// clearMatchIndexes forgets the acknowledgements of all followers of
+// the group.
+func (s *state) clearMatchIndexes(groupID uint64) {
+	s.matchMu.Lock()
+	defer s.matchMu.Unlock()
+	delete(s.matchIndexes, groupID)
+}
+
+// clearMatchIndex forgets the acknowledgements of a follower of the
+// group.
+func (s *state) clearMatchIndex(groupID uint64, nodeID NodeID) {
+	s.matchMu.Lock()
+	defer s.matchMu.Unlock()
+	delete(s.matchIndexes[groupID], nodeID)
+}
+
+// quiesceIdleGroups ticks the idle counters of all groups and quiesces
+// those which have been idle for long enough. Groups with outstanding
+// proposals, pending Ready structs or a leader lease granted by this
+// node are left alone.
+func (s *state) quiesceIdleGroups(readyGroups, writingGroups map[uint64]raft.Ready) {
+	now := s.clock()
+	for groupID, g := range s.groups {
+		g.idleTicks++
+		threshold := s.QuiesceTicks
+		if g.leader == s.nodeID {
+			threshold += s.ElectionTimeoutTicks
+		}
+		if g.idleTicks < threshold || len(g.pending) > 0 || g.leaseGrantedUntil > now {
+			continue
+		}
+		if _, ok := readyGroups[groupID]; ok {
+			continue
+		}
+		if _, ok := writingGroups[groupID]; ok {
+			continue
+		}
+		if err := s.multiNode.RemoveGroup(groupID); err != nil {
+			log.Warningf("node %v: error quiescing group %v: %s", s.nodeID, groupID, err)
+			continue
+		}
+		for _, n := range s.nodes {
+			n.unregisterGroup(groupID)
+		}
+		delete(s.groups, groupID)
+		s.quiesced[groupID] = struct{}{}
+		log.V(4).Infof("node %v: quiesced idle group %v", s.nodeID, groupID)
+	}
+}
+
+// wakeGroup recreates a quiesced group. The group doesn't campaign
+// right away, which would depose a leader whose group is still
+// active; see campaignWokenGroups.
+func (s *state) wakeGroup(groupID uint64) error {
+	log.V(4).Infof("node %v: waking quiesced group %v", s.nodeID, groupID)
+	if err := s.createGroup(groupID); err != nil {
+		return err
+	}
+	s.groups[groupID].waking = true
+	return nil
+}
+
+// campaignWokenGroups campaigns for the leadership of groups which
+// have gone an election timeout since they were woken without learning
+// of a leader, as the other members have likely quiesced the group as
+// well.
+func (s *state) campaignWokenGroups() {
+	for groupID, g := range s.groups {
+		if !g.waking {
+			continue
+		}
+		if g.leader != 0 {
+			g.waking = false
+			continue
+		}
+		if g.wakeTicks++; g.wakeTicks < s.ElectionTimeoutTicks {
+			continue
+		}
+		g.waking = false
+		log.V(4).Infof("node %v: campaigning for woken group %v without a leader", s.nodeID, groupID)
+		if err := s.multiNode.Campaign(context.Background(), groupID); err != nil {
+			log.Warningf("node %v: error campaigning for group %v: %s", s.nodeID, groupID, err)
+		}
+	}
+}
+
 func (s *state) propose(p *proposal) {
+	if _, ok := s.quiesced[p.groupID]; ok {
+		if err := s.wakeGroup(p.groupID); err != nil {
+			p.ch <- err
+			return
+		}
+	}
 	g, ok := s.groups[p.groupID]
 	if !ok {
 		p.ch <- util.Errorf("group %d not found", p.groupID)
 		return
 	}
+	g.idleTicks = 0
 	g.pending[p.commandID] = p
+	p.ticks = 0
 	p.fn()
 }
 
+// reproposeStalled proposes again the pending commands which have gone
+// uncommitted for ReproposalTicks, in case their proposals were
+// dropped. See Config.ReproposalTicks.
+func (s *state) reproposeStalled() {
+	for groupID, g := range s.groups {
+		for _, p := range g.pending {
+			if p.confChange {
+				continue
+			}
+			if p.ticks++; p.ticks >= s.ReproposalTicks {
+				log.V(4).Infof("node %v: reproposing command %x to group %v", s.nodeID, p.commandID, groupID)
+				p.ticks = 0
+				p.fn()
+			}
+		}
+	}
+}
+
 func (s *state) handleRaftReady(readyGroups map[uint64]raft.Ready) {
 	// Soft state is updated immediately; everything else waits for handleWriteReady.
 	for groupID, ready := range readyGroups {
@@ -717,8 +926,14 @@ func (s *state) handleRaftReady(readyGroups map[uint64]raft.Ready) {
 		}
 		term := g.committedTerm
 		if ready.SoftState != nil {
-			// Always save the leader whenever we get a SoftState.
-			g.leader = NodeID(ready.SoftState.Lead)
+			// Always save the leader whenever we get a SoftState. The
+			// acknowledgements observed while previously leading the group
+			// may since have been lost, e.g. by a replica removed and added
+			// again, so they're forgotten on a change of leadership.
+			if leader := NodeID(ready.SoftState.Lead); leader != g.leader {
+				s.clearMatchIndexes(groupID)
+				g.leader = leader
+			}
 		}
 		if len(ready.CommittedEntries) > 0 {
 			term = ready.CommittedEntries[len(ready.CommittedEntries)-1].Term
@@ -813,8 +1028,12 @@ func (s *state) handleWriteResponse(response *writeResponse, readyGroups map[uin
 								// TODO(bdarnell): dedupe by keeping a record of recently-applied commandIDs
 								switch cc.Type {
 								case raftpb.ConfChangeAddNode:
+									// A replica added again starts without the entries it
+									// acknowledged before its removal.
+									s.clearMatchIndex(groupID, NodeID(cc.NodeID))
 									err = s.addNode(NodeID(cc.NodeID), groupID)
 								case raftpb.ConfChangeRemoveNode:
+									s.clearMatchIndex(groupID, NodeID(cc.NodeID))
 									// TODO(bdarnell): support removing nodes; fix double-application of initial entries
 								case raftpb.ConfChangeUpdateNode:
 									// Updates don't concern multiraft, they are simply passed through.
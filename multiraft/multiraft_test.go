@@ -263,6 +263,86 @@ func TestCommand(t *testing.T) {
 	}
 }
 
+// TestQuiesceIdleGroup verifies that a group is quiesced after it has
+// been idle for QuiesceTicks (plus an election timeout, for leaders)
+// and that a proposal wakes it.
+func TestQuiesceIdleGroup(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stopper := util.NewStopper()
+	cluster := newTestCluster(nil, 1, stopper, t)
+	defer stopper.Stop()
+	node := cluster.nodes[0]
+	groupID := uint64(1)
+	cluster.createGroup(groupID, 0, 1)
+	cluster.triggerElection(0, groupID)
+	cluster.waitForElection(0)
+
+	// Configure and inspect the state on the raft goroutine.
+	done := make(chan struct{})
+	node.callbackChan <- func() {
+		node.QuiesceTicks = 3
+		close(done)
+	}
+	<-done
+	isQuiesced := func() bool {
+		ch := make(chan bool)
+		node.callbackChan <- func() {
+			_, ok := node.quiesced[groupID]
+			ch <- ok
+		}
+		return <-ch
+	}
+
+	for i := 0; i < node.QuiesceTicks; i++ {
+		cluster.tickers[0].Tick()
+	}
+	if isQuiesced() {
+		t.Fatal("expected leader to wait an election timeout before quiescing")
+	}
+	for i := 0; i < node.ElectionTimeoutTicks+2; i++ {
+		cluster.tickers[0].Tick()
+	}
+	if !isQuiesced() {
+		t.Fatal("expected idle group to be quiesced")
+	}
+
+	// A proposal wakes the group, which doesn't campaign until it has
+	// gone an election timeout without learning of a leader. The
+	// proposal is then committed.
+	errCh := node.SubmitCommand(groupID, makeCommandID(), []byte("command"))
+	if err := util.IsTrueWithin(func() bool { return !isQuiesced() }, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	leaderCh := make(chan NodeID)
+	node.callbackChan <- func() {
+		leaderCh <- node.groups[groupID].leader
+	}
+	if leader := <-leaderCh; leader != 0 {
+		t.Fatalf("expected woken group not to campaign right away; got leader %v", leader)
+	}
+	for i := 0; ; i++ {
+		if i > 10*node.ElectionTimeoutTicks {
+			t.Fatal("expected woken group to elect a leader and commit the proposal")
+		}
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatal(err)
+			}
+		default:
+			cluster.tickers[0].Tick()
+			continue
+		}
+		break
+	}
+	if commit := <-cluster.events[0].CommandCommitted; string(commit.Command) != "command" {
+		t.Errorf("unexpected value in committed command: %v", commit.Command)
+	}
+	if isQuiesced() {
+		t.Error("expected group to be awake after proposal")
+	}
+}
+
 func makeClock(i int) func() int64 {
 	return func() int64 {
 		return int64(i)
@@ -453,3 +533,26 @@ func TestMembershipChange(t *testing.T) {
 			}
 		}*/
 }
+
+// TestClearMatchIndexes verifies that the acknowledgements of
+// followers may be forgotten individually or for a whole group.
+func TestClearMatchIndexes(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	s := &state{MultiRaft: &MultiRaft{matchIndexes: map[uint64]map[NodeID]uint64{}}}
+	s.recordMatchIndex(1, 2, 10)
+	s.recordMatchIndex(1, 3, 5)
+	s.recordMatchIndex(1, 3, 4)
+	if indexes := s.FollowerMatchIndexes(1); !reflect.DeepEqual(indexes, map[NodeID]uint64{2: 10, 3: 5}) {
+		t.Errorf("unexpected match indexes %v", indexes)
+	}
+	s.clearMatchIndex(1, 3)
+	if indexes := s.FollowerMatchIndexes(1); !reflect.DeepEqual(indexes, map[NodeID]uint64{2: 10}) {
+		t.Errorf("expected node 3 to be forgotten; got %v", indexes)
+	}
+	s.clearMatchIndexes(1)
+	if indexes := s.FollowerMatchIndexes(1); len(indexes) != 0 {
+		t.Errorf("expected no match indexes; got %v", indexes)
+	}
+	// Forgetting the followers of unknown groups is harmless.
+	s.clearMatchIndex(2, 3)
+}
@@ -40,9 +40,20 @@ var _ WriteableGroupStorage = (*raft.MemoryStorage)(nil)
 // The Storage interface is supplied by the application to manage persistent storage
 // of raft data.
 type Storage interface {
+	// GroupStorage returns the storage for the given group, or nil if
+	// the group has been removed and must not be recreated.
 	GroupStorage(groupID uint64) WriteableGroupStorage
 }
 
+// A GroupAdmitter is a Storage which decides whether an incoming
+// message for a group unknown to the node may create the group. This
+// allows a removed group to be recreated by messages addressed to a
+// newer member on this node, while messages addressed to the removed
+// member are dropped.
+type GroupAdmitter interface {
+	AdmitGroup(groupID uint64, msg raftpb.Message) bool
+}
+
 // The StateMachine interface is supplied by the application to manage a persistent
 // state machine (in Cockroach the StateMachine and the Storage are the same thing
 // but they are logically distinct and systems like etcd keep them separate).
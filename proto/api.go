@@ -72,6 +72,9 @@ const (
 	AdminSplit = "AdminSplit"
 	// AdminMerge is called to coordinate a merge of two adjacent ranges.
 	AdminMerge = "AdminMerge"
+	// AdminTransferLease is called to transfer the leader lease of a
+	// range to another of its replicas.
+	AdminTransferLease = "AdminTransferLease"
 )
 
 type stringSet map[string]struct{}
@@ -92,58 +95,69 @@ type RaftID int64
 
 // AllMethods specifies the complete set of methods.
 var AllMethods = stringSet{
-	Contains:              {},
-	Get:                   {},
-	Put:                   {},
-	ConditionalPut:        {},
-	Increment:             {},
-	Delete:                {},
-	DeleteRange:           {},
-	Scan:                  {},
-	EndTransaction:        {},
-	ReapQueue:             {},
-	EnqueueUpdate:         {},
-	EnqueueMessage:        {},
-	AdminSplit:            {},
-	AdminMerge:            {},
-	Batch:                 {},
-	InternalHeartbeatTxn:  {},
-	InternalGC:            {},
-	InternalPushTxn:       {},
-	InternalResolveIntent: {},
-	InternalMerge:         {},
-	InternalTruncateLog:   {},
+	Contains:               {},
+	Get:                    {},
+	Put:                    {},
+	ConditionalPut:         {},
+	Increment:              {},
+	Delete:                 {},
+	DeleteRange:            {},
+	Scan:                   {},
+	EndTransaction:         {},
+	ReapQueue:              {},
+	EnqueueUpdate:          {},
+	EnqueueMessage:         {},
+	AdminSplit:             {},
+	AdminMerge:             {},
+	AdminTransferLease:     {},
+	Batch:                  {},
+	InternalHeartbeatTxn:   {},
+	InternalGC:             {},
+	InternalPushTxn:        {},
+	InternalResolveIntent:  {},
+	InternalMerge:          {},
+	InternalTruncateLog:    {},
+	InternalLeaderLease:    {},
+	InternalChangeReplicas: {},
+	InternalRecomputeStats: {},
+	InternalExport:         {},
+	InternalImport:         {},
 }
 
 // PublicMethods specifies the set of methods accessible via the
 // public key-value API.
 var PublicMethods = stringSet{
-	Contains:       {},
-	Get:            {},
-	Put:            {},
-	ConditionalPut: {},
-	Increment:      {},
-	Delete:         {},
-	DeleteRange:    {},
-	Scan:           {},
-	EndTransaction: {},
-	ReapQueue:      {},
-	EnqueueUpdate:  {},
-	EnqueueMessage: {},
-	Batch:          {},
-	AdminSplit:     {},
-	AdminMerge:     {},
+	Contains:           {},
+	Get:                {},
+	Put:                {},
+	ConditionalPut:     {},
+	Increment:          {},
+	Delete:             {},
+	DeleteRange:        {},
+	Scan:               {},
+	EndTransaction:     {},
+	ReapQueue:          {},
+	EnqueueUpdate:      {},
+	EnqueueMessage:     {},
+	Batch:              {},
+	AdminSplit:         {},
+	AdminMerge:         {},
+	AdminTransferLease: {},
 }
 
 // InternalMethods specifies the set of methods accessible only
 // via the internal node RPC API.
 var InternalMethods = stringSet{
-	InternalHeartbeatTxn:  {},
-	InternalGC:            {},
-	InternalPushTxn:       {},
-	InternalResolveIntent: {},
-	InternalMerge:         {},
-	InternalTruncateLog:   {},
+	InternalHeartbeatTxn:   {},
+	InternalGC:             {},
+	InternalPushTxn:        {},
+	InternalResolveIntent:  {},
+	InternalMerge:          {},
+	InternalTruncateLog:    {},
+	InternalChangeReplicas: {},
+	InternalRecomputeStats: {},
+	InternalExport:         {},
+	InternalImport:         {},
 }
 
 // ReadMethods specifies the set of methods which read and return data.
@@ -155,26 +169,30 @@ var ReadMethods = stringSet{
 	Scan:                {},
 	ReapQueue:           {},
 	InternalRangeLookup: {},
+	InternalExport:      {},
 }
 
 // WriteMethods specifies the set of methods which write data.
 var WriteMethods = stringSet{
-	Put:                   {},
-	ConditionalPut:        {},
-	Increment:             {},
-	Delete:                {},
-	DeleteRange:           {},
-	EndTransaction:        {},
-	ReapQueue:             {},
-	EnqueueUpdate:         {},
-	EnqueueMessage:        {},
-	Batch:                 {},
-	InternalHeartbeatTxn:  {},
-	InternalGC:            {},
-	InternalPushTxn:       {},
-	InternalResolveIntent: {},
-	InternalMerge:         {},
-	InternalTruncateLog:   {},
+	Put:                    {},
+	ConditionalPut:         {},
+	Increment:              {},
+	Delete:                 {},
+	DeleteRange:            {},
+	EndTransaction:         {},
+	ReapQueue:              {},
+	EnqueueUpdate:          {},
+	EnqueueMessage:         {},
+	Batch:                  {},
+	InternalHeartbeatTxn:   {},
+	InternalGC:             {},
+	InternalPushTxn:        {},
+	InternalResolveIntent:  {},
+	InternalMerge:          {},
+	InternalTruncateLog:    {},
+	InternalLeaderLease:    {},
+	InternalRecomputeStats: {},
+	InternalImport:         {},
 }
 
 // TxnMethods specifies the set of methods which leave key intents
@@ -194,8 +212,10 @@ var TxnMethods = stringSet{
 // read-only nor read-write commands but instead execute directly on
 // the Raft leader.
 var adminMethods = stringSet{
-	AdminSplit: {},
-	AdminMerge: {},
+	AdminSplit:             {},
+	AdminMerge:             {},
+	AdminTransferLease:     {},
+	InternalChangeReplicas: {},
 }
 
 // NeedReadPerm returns true if the specified method requires read permissions.
@@ -356,6 +376,8 @@ func MethodForRequest(req Request) (string, error) {
 		return AdminSplit, nil
 	case *AdminMergeRequest:
 		return AdminMerge, nil
+	case *AdminTransferLeaseRequest:
+		return AdminTransferLease, nil
 	case *InternalHeartbeatTxnRequest:
 		return InternalHeartbeatTxn, nil
 	case *InternalGCRequest:
@@ -370,6 +392,14 @@ func MethodForRequest(req Request) (string, error) {
 		return InternalTruncateLog, nil
 	case *InternalLeaderLeaseRequest:
 		return InternalLeaderLease, nil
+	case *InternalChangeReplicasRequest:
+		return InternalChangeReplicas, nil
+	case *InternalRecomputeStatsRequest:
+		return InternalRecomputeStats, nil
+	case *InternalExportRequest:
+		return InternalExport, nil
+	case *InternalImportRequest:
+		return InternalImport, nil
 	}
 	return "", util.Errorf("unhandled request %T", req)
 }
@@ -417,6 +447,8 @@ func CreateArgs(method string) (Request, error) {
 		return &AdminSplitRequest{}, nil
 	case AdminMerge:
 		return &AdminMergeRequest{}, nil
+	case AdminTransferLease:
+		return &AdminTransferLeaseRequest{}, nil
 	case InternalHeartbeatTxn:
 		return &InternalHeartbeatTxnRequest{}, nil
 	case InternalGC:
@@ -431,6 +463,14 @@ func CreateArgs(method string) (Request, error) {
 		return &InternalTruncateLogRequest{}, nil
 	case InternalLeaderLease:
 		return &InternalLeaderLeaseRequest{}, nil
+	case InternalChangeReplicas:
+		return &InternalChangeReplicasRequest{}, nil
+	case InternalRecomputeStats:
+		return &InternalRecomputeStatsRequest{}, nil
+	case InternalExport:
+		return &InternalExportRequest{}, nil
+	case InternalImport:
+		return &InternalImportRequest{}, nil
 	}
 	return nil, util.Errorf("unhandled method %s", method)
 }
@@ -468,6 +508,8 @@ func CreateReply(method string) (Response, error) {
 		return &AdminSplitResponse{}, nil
 	case AdminMerge:
 		return &AdminMergeResponse{}, nil
+	case AdminTransferLease:
+		return &AdminTransferLeaseResponse{}, nil
 	case InternalHeartbeatTxn:
 		return &InternalHeartbeatTxnResponse{}, nil
 	case InternalGC:
@@ -482,6 +524,14 @@ func CreateReply(method string) (Response, error) {
 		return &InternalTruncateLogResponse{}, nil
 	case InternalLeaderLease:
 		return &InternalLeaderLeaseResponse{}, nil
+	case InternalChangeReplicas:
+		return &InternalChangeReplicasResponse{}, nil
+	case InternalRecomputeStats:
+		return &InternalRecomputeStatsResponse{}, nil
+	case InternalExport:
+		return &InternalExportResponse{}, nil
+	case InternalImport:
+		return &InternalImportResponse{}, nil
 	}
 	return nil, util.Errorf("unhandled method %s", method)
 }
@@ -535,6 +585,9 @@ func (sr *ScanResponse) Combine(c Response) {
 	otherSR := c.(*ScanResponse)
 	if sr != nil {
 		sr.Rows = append(sr.Rows, otherSR.GetRows()...)
+		if len(otherSR.ResumeKey) > 0 {
+			sr.ResumeKey = otherSR.ResumeKey
+		}
 		sr.Header().Combine(otherSR.Header())
 	}
 }
@@ -653,7 +706,8 @@ func (sr *ScanResponse) Verify(req Request) error {
 // the key range of the first request added to it.
 //
 // TODO(spencer): batches should include a list of key ranges
-//   representing the constituent requests.
+//
+//	representing the constituent requests.
 func (br *BatchRequest) Add(args Request) {
 	union := RequestUnion{}
 	union.SetValue(args)
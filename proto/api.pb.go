@@ -3,54 +3,58 @@
 // DO NOT EDIT!
 
 /*
-	Package proto is a generated protocol buffer package.
-
-	It is generated from these files:
-		cockroach/proto/api.proto
-		cockroach/proto/config.proto
-		cockroach/proto/data.proto
-		cockroach/proto/errors.proto
-		cockroach/proto/gossip.proto
-		cockroach/proto/heartbeat.proto
-		cockroach/proto/internal.proto
-		cockroach/proto/status.proto
-
-	It has these top-level messages:
-		ClientCmdID
-		RequestHeader
-		ResponseHeader
-		ContainsRequest
-		ContainsResponse
-		GetRequest
-		GetResponse
-		PutRequest
-		PutResponse
-		ConditionalPutRequest
-		ConditionalPutResponse
-		IncrementRequest
-		IncrementResponse
-		DeleteRequest
-		DeleteResponse
-		DeleteRangeRequest
-		DeleteRangeResponse
-		ScanRequest
-		ScanResponse
-		EndTransactionRequest
-		EndTransactionResponse
-		ReapQueueRequest
-		ReapQueueResponse
-		EnqueueUpdateRequest
-		EnqueueUpdateResponse
-		EnqueueMessageRequest
-		EnqueueMessageResponse
-		RequestUnion
-		ResponseUnion
-		BatchRequest
-		BatchResponse
-		AdminSplitRequest
-		AdminSplitResponse
-		AdminMergeRequest
-		AdminMergeResponse
+Package proto is a generated protocol buffer package.
+
+It is generated from these files:
+
+	cockroach/proto/api.proto
+	cockroach/proto/config.proto
+	cockroach/proto/data.proto
+	cockroach/proto/errors.proto
+	cockroach/proto/gossip.proto
+	cockroach/proto/heartbeat.proto
+	cockroach/proto/internal.proto
+	cockroach/proto/status.proto
+
+It has these top-level messages:
+
+	ClientCmdID
+	RequestHeader
+	ResponseHeader
+	ContainsRequest
+	ContainsResponse
+	GetRequest
+	GetResponse
+	PutRequest
+	PutResponse
+	ConditionalPutRequest
+	ConditionalPutResponse
+	IncrementRequest
+	IncrementResponse
+	DeleteRequest
+	DeleteResponse
+	DeleteRangeRequest
+	DeleteRangeResponse
+	ScanRequest
+	ScanResponse
+	EndTransactionRequest
+	EndTransactionResponse
+	ReapQueueRequest
+	ReapQueueResponse
+	EnqueueUpdateRequest
+	EnqueueUpdateResponse
+	EnqueueMessageRequest
+	EnqueueMessageResponse
+	RequestUnion
+	ResponseUnion
+	BatchRequest
+	BatchResponse
+	AdminSplitRequest
+	AdminSplitResponse
+	AdminMergeRequest
+	AdminMergeResponse
+	AdminTransferLeaseRequest
+	AdminTransferLeaseResponse
 */
 package proto
 
@@ -197,8 +201,19 @@ type RequestHeader struct {
 	// ReadConsistency specifies the consistency for read
 	// operations. The default is CONSISTENT. This value is ignored for
 	// write operations.
-	ReadConsistency  ReadConsistencyType `protobuf:"varint,10,opt,name=read_consistency,enum=cockroach.proto.ReadConsistencyType" json:"read_consistency"`
-	XXX_unrecognized []byte              `json:"-"`
+	ReadConsistency ReadConsistencyType `protobuf:"varint,10,opt,name=read_consistency,enum=cockroach.proto.ReadConsistencyType" json:"read_consistency"`
+	// Deadline is the wall time in nanoseconds since the epoch after
+	// which the client no longer awaits the response. Commands whose
+	// deadline has passed aren't executed, and the store stops waiting
+	// for a command's application once its deadline passes. Clients
+	// set it from their timeout (see client.KV.Timeout). Zero specifies
+	// no deadline.
+	Deadline int64 `protobuf:"varint,11,opt,name=deadline" json:"deadline"`
+	// TraceID identifies the trace to which the request belongs. If
+	// non-zero, stores log the spans of the request's execution under
+	// the trace ID. Zero specifies an untraced request.
+	TraceID          int64  `protobuf:"varint,12,opt,name=trace_id" json:"trace_id"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *RequestHeader) Reset()         { *m = RequestHeader{} }
@@ -263,6 +278,20 @@ func (m *RequestHeader) GetReadConsistency() ReadConsistencyType {
 	return CONSISTENT
 }
 
+func (m *RequestHeader) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
+}
+
+func (m *RequestHeader) GetTraceID() int64 {
+	if m != nil {
+		return m.TraceID
+	}
+	return 0
+}
+
 // ResponseHeader is returned with every storage node response.
 type ResponseHeader struct {
 	// Error is non-nil if an error occurred.
@@ -399,7 +428,10 @@ func (*PutResponse) ProtoMessage()    {}
 // - Returns true and sets value if ExpValue equals existing value.
 // - If key doesn't exist and ExpValue is nil, sets value.
 // - If key exists, but value is empty and ExpValue is not nil but empty, sets value.
-// - Otherwise, returns error and the actual value of the key in the response.
+// - If key doesn't exist and AllowIfMissing is set, sets value.
+// - Otherwise, returns error and the actual value of the key, if any.
+//
+// On success, the replaced value is returned in the response.
 type ConditionalPutRequest struct {
 	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
 	// The value to put.
@@ -407,7 +439,10 @@ type ConditionalPutRequest struct {
 	// ExpValue.Bytes empty to test for non-existence. Specify as nil
 	// to indicate there should be no existing entry. This is different
 	// from the expectation that the value exists but is empty.
-	ExpValue         *Value `protobuf:"bytes,3,opt,name=exp_value" json:"exp_value,omitempty"`
+	ExpValue *Value `protobuf:"bytes,3,opt,name=exp_value" json:"exp_value,omitempty"`
+	// If allow_if_missing is set, the value is also put if the key
+	// doesn't exist, rather than only if its value matches exp_value.
+	AllowIfMissing   bool   `protobuf:"varint,4,opt,name=allow_if_missing" json:"allow_if_missing"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -429,10 +464,19 @@ func (m *ConditionalPutRequest) GetExpValue() *Value {
 	return nil
 }
 
+func (m *ConditionalPutRequest) GetAllowIfMissing() bool {
+	if m != nil {
+		return m.AllowIfMissing
+	}
+	return false
+}
+
 // A ConditionalPutResponse is the return value from the
 // ConditionalPut() method.
 type ConditionalPutResponse struct {
-	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	ResponseHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	// The value replaced by the put; nil if the key didn't exist.
+	PrevValue        *Value `protobuf:"bytes,2,opt,name=prev_value" json:"prev_value,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -440,6 +484,13 @@ func (m *ConditionalPutResponse) Reset()         { *m = ConditionalPutResponse{}
 func (m *ConditionalPutResponse) String() string { return proto1.CompactTextString(m) }
 func (*ConditionalPutResponse) ProtoMessage()    {}
 
+func (m *ConditionalPutResponse) GetPrevValue() *Value {
+	if m != nil {
+		return m.PrevValue
+	}
+	return nil
+}
+
 // An IncrementRequest is arguments to the Increment() method. It
 // increments the value for key, and returns the new value. If no
 // value exists for a key, incrementing by 0 is not a noop, but will
@@ -447,8 +498,12 @@ func (*ConditionalPutResponse) ProtoMessage()    {}
 // by Put() or ConditionalPut(). Similarly, Put() and ConditionalPut()
 // cannot be invoked on an incremented key.
 type IncrementRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
-	Increment        int64  `protobuf:"varint,2,opt,name=increment" json:"increment"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Increment     int64 `protobuf:"varint,2,opt,name=increment" json:"increment"`
+	// If saturate is set, an increment which would overflow or
+	// underflow stores the maximum or minimum int64 instead of failing
+	// with an IncrementOverflowError.
+	Saturate         bool   `protobuf:"varint,3,opt,name=saturate" json:"saturate"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -463,6 +518,13 @@ func (m *IncrementRequest) GetIncrement() int64 {
 	return 0
 }
 
+func (m *IncrementRequest) GetSaturate() bool {
+	if m != nil {
+		return m.Saturate
+	}
+	return false
+}
+
 // An IncrementResponse is the return value from the Increment
 // method. The new value after increment is specified in NewValue. If
 // the value could not be decoded as specified, Error will be set.
@@ -549,7 +611,10 @@ func (m *DeleteRangeResponse) GetNumDeleted() int64 {
 type ScanRequest struct {
 	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
 	// Must be > 0.
-	MaxResults       int64  `protobuf:"varint,2,opt,name=max_results" json:"max_results"`
+	MaxResults int64 `protobuf:"varint,2,opt,name=max_results" json:"max_results"`
+	// If > 0, the scan stops before the encoded sizes of the results
+	// would exceed max_bytes. At least one result is always returned.
+	MaxBytes         int64  `protobuf:"varint,3,opt,name=max_bytes" json:"max_bytes"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -564,12 +629,22 @@ func (m *ScanRequest) GetMaxResults() int64 {
 	return 0
 }
 
+func (m *ScanRequest) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
 // A ScanResponse is the return value from the Scan() method.
 type ScanResponse struct {
 	ResponseHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
 	// Empty if no rows were scanned.
-	Rows             []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
-	XXX_unrecognized []byte     `json:"-"`
+	Rows []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
+	// If the scan stopped at max_results or max_bytes, the key at which
+	// to resume it. Empty if the scan covered the whole key range.
+	ResumeKey        Key    `protobuf:"bytes,3,opt,name=resume_key,customtype=Key" json:"resume_key"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *ScanResponse) Reset()         { *m = ScanResponse{} }
@@ -1012,8 +1087,11 @@ func (m *BatchResponse) GetResponses() []ResponseUnion {
 // metadata (e.g. response cache and range stats must be copied or
 // recomputed).
 type AdminSplitRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
-	SplitKey         Key    `protobuf:"bytes,2,opt,name=split_key,customtype=Key" json:"split_key"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	SplitKey      Key `protobuf:"bytes,2,opt,name=split_key,customtype=Key" json:"split_key"`
+	// Reason describes why the split was requested. It's recorded in
+	// the range event log.
+	Reason           string `protobuf:"bytes,3,opt,name=reason" json:"reason"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -1021,6 +1099,13 @@ func (m *AdminSplitRequest) Reset()         { *m = AdminSplitRequest{} }
 func (m *AdminSplitRequest) String() string { return proto1.CompactTextString(m) }
 func (*AdminSplitRequest) ProtoMessage()    {}
 
+func (m *AdminSplitRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 // An AdminSplitResponse is the return value from the AdminSplit()
 // method.
 type AdminSplitResponse struct {
@@ -1061,6 +1146,33 @@ func (m *AdminMergeResponse) Reset()         { *m = AdminMergeResponse{} }
 func (m *AdminMergeResponse) String() string { return proto1.CompactTextString(m) }
 func (*AdminMergeResponse) ProtoMessage()    {}
 
+// An AdminTransferLeaseRequest is arguments to the AdminTransferLease()
+// method. The leader lease of the range which contains
+// RequestHeader.Key is transferred to the range's replica on the store
+// target_store_id. The request must be served by the current holder of
+// the lease. Transferring the lease to the replica which already holds
+// it is a noop.
+type AdminTransferLeaseRequest struct {
+	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	TargetStoreID    StoreID `protobuf:"varint,2,opt,name=target_store_id,customtype=StoreID" json:"target_store_id"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AdminTransferLeaseRequest) Reset()         { *m = AdminTransferLeaseRequest{} }
+func (m *AdminTransferLeaseRequest) String() string { return proto1.CompactTextString(m) }
+func (*AdminTransferLeaseRequest) ProtoMessage()    {}
+
+// An AdminTransferLeaseResponse is the return value from the
+// AdminTransferLease() method.
+type AdminTransferLeaseResponse struct {
+	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *AdminTransferLeaseResponse) Reset()         { *m = AdminTransferLeaseResponse{} }
+func (m *AdminTransferLeaseResponse) String() string { return proto1.CompactTextString(m) }
+func (*AdminTransferLeaseResponse) ProtoMessage()    {}
+
 func init() {
 	proto1.RegisterEnum("cockroach.proto.ReadConsistencyType", ReadConsistencyType_name, ReadConsistencyType_value)
 }
@@ -1371,6 +1483,36 @@ func (m *RequestHeader) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deadline", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Deadline |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TraceID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.TraceID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2072,6 +2214,23 @@ func (m *ConditionalPutRequest) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AllowIfMissing", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AllowIfMissing = bool(v != 0)
 		default:
 			var sizeOfWire int
 			for {
@@ -2138,6 +2297,33 @@ func (m *ConditionalPutResponse) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevValue", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PrevValue == nil {
+				m.PrevValue = &Value{}
+			}
+			if err := m.PrevValue.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -2219,6 +2405,23 @@ func (m *IncrementRequest) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Saturate", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Saturate = bool(v != 0)
 		default:
 			var sizeOfWire int
 			for {
@@ -2675,6 +2878,21 @@ func (m *ScanRequest) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2764,6 +2982,30 @@ func (m *ScanResponse) Unmarshal(data []byte) error {
 			m.Rows = append(m.Rows, KeyValue{})
 			m.Rows[len(m.Rows)-1].Unmarshal(data[index:postIndex])
 			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResumeKey", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResumeKey.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -4436,6 +4678,28 @@ func (m *AdminSplitRequest) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Reason = string(data[index:postIndex])
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -4657,6 +4921,153 @@ func (m *AdminMergeResponse) Unmarshal(data []byte) error {
 	}
 	return nil
 }
+func (m *AdminTransferLeaseRequest) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TargetStoreID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.TargetStoreID |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *AdminTransferLeaseResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
 func (this *RequestUnion) GetValue() interface{} {
 	if this.Contains != nil {
 		return this.Contains
@@ -4834,6 +5245,8 @@ func (m *RequestHeader) Size() (n int) {
 		n += 1 + l + sovApi(uint64(l))
 	}
 	n += 1 + sovApi(uint64(m.ReadConsistency))
+	n += 1 + sovApi(uint64(m.Deadline))
+	n += 1 + sovApi(uint64(m.TraceID))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4943,6 +5356,7 @@ func (m *ConditionalPutRequest) Size() (n int) {
 		l = m.ExpValue.Size()
 		n += 1 + l + sovApi(uint64(l))
 	}
+	n += 2
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4954,6 +5368,10 @@ func (m *ConditionalPutResponse) Size() (n int) {
 	_ = l
 	l = m.ResponseHeader.Size()
 	n += 1 + l + sovApi(uint64(l))
+	if m.PrevValue != nil {
+		l = m.PrevValue.Size()
+		n += 1 + l + sovApi(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4966,6 +5384,7 @@ func (m *IncrementRequest) Size() (n int) {
 	l = m.RequestHeader.Size()
 	n += 1 + l + sovApi(uint64(l))
 	n += 1 + sovApi(uint64(m.Increment))
+	n += 2
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5036,6 +5455,7 @@ func (m *ScanRequest) Size() (n int) {
 	l = m.RequestHeader.Size()
 	n += 1 + l + sovApi(uint64(l))
 	n += 1 + sovApi(uint64(m.MaxResults))
+	n += 1 + sovApi(uint64(m.MaxBytes))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5053,6 +5473,8 @@ func (m *ScanResponse) Size() (n int) {
 			n += 1 + l + sovApi(uint64(l))
 		}
 	}
+	l = m.ResumeKey.Size()
+	n += 1 + l + sovApi(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5323,6 +5745,8 @@ func (m *AdminSplitRequest) Size() (n int) {
 	n += 1 + l + sovApi(uint64(l))
 	l = m.SplitKey.Size()
 	n += 1 + l + sovApi(uint64(l))
+	l = len(m.Reason)
+	n += 1 + l + sovApi(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5362,6 +5786,29 @@ func (m *AdminMergeResponse) Size() (n int) {
 	return n
 }
 
+func (m *AdminTransferLeaseRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovApi(uint64(l))
+	n += 1 + sovApi(uint64(m.TargetStoreID))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *AdminTransferLeaseResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovApi(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func sovApi(x uint64) (n int) {
 	for {
 		n++
@@ -5482,6 +5929,12 @@ func (m *RequestHeader) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x50
 	i++
 	i = encodeVarintApi(data, i, uint64(m.ReadConsistency))
+	data[i] = 0x58
+	i++
+	i = encodeVarintApi(data, i, uint64(m.Deadline))
+	data[i] = 0x60
+	i++
+	i = encodeVarintApi(data, i, uint64(m.TraceID))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5778,6 +6231,14 @@ func (m *ConditionalPutRequest) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n20
 	}
+	data[i] = 0x20
+	i++
+	if m.AllowIfMissing {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5807,6 +6268,16 @@ func (m *ConditionalPutResponse) MarshalTo(data []byte) (n int, err error) {
 		return 0, err
 	}
 	i += n21
+	if m.PrevValue != nil {
+		data[i] = 0x12
+		i++
+		i = encodeVarintApi(data, i, uint64(m.PrevValue.Size()))
+		n73, err := m.PrevValue.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n73
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5839,6 +6310,14 @@ func (m *IncrementRequest) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x10
 	i++
 	i = encodeVarintApi(data, i, uint64(m.Increment))
+	data[i] = 0x18
+	i++
+	if m.Saturate {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -6025,6 +6504,9 @@ func (m *ScanRequest) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x10
 	i++
 	i = encodeVarintApi(data, i, uint64(m.MaxResults))
+	data[i] = 0x18
+	i++
+	i = encodeVarintApi(data, i, uint64(m.MaxBytes))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -6066,6 +6548,14 @@ func (m *ScanResponse) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	data[i] = 0x1a
+	i++
+	i = encodeVarintApi(data, i, uint64(m.ResumeKey.Size()))
+	n74, err := m.ResumeKey.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n74
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -6755,6 +7245,10 @@ func (m *AdminSplitRequest) MarshalTo(data []byte) (n int, err error) {
 		return 0, err
 	}
 	i += n67
+	data[i] = 0x1a
+	i++
+	i = encodeVarintApi(data, i, uint64(len(m.Reason)))
+	i += copy(data[i:], m.Reason)
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -6848,6 +7342,67 @@ func (m *AdminMergeResponse) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *AdminTransferLeaseRequest) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *AdminTransferLeaseRequest) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintApi(data, i, uint64(m.RequestHeader.Size()))
+	n71, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n71
+	data[i] = 0x10
+	i++
+	i = encodeVarintApi(data, i, uint64(m.TargetStoreID))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *AdminTransferLeaseResponse) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *AdminTransferLeaseResponse) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintApi(data, i, uint64(m.ResponseHeader.Size()))
+	n72, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n72
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func encodeFixed64Api(data []byte, offset int, v uint64) int {
 	data[offset] = uint8(v)
 	data[offset+1] = uint8(v >> 8)
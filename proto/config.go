@@ -76,6 +76,38 @@ func (n *StoreID) Unmarshal(bytes []byte) error {
 	return nil
 }
 
+// MinRangeMaxBytes is the minimum value for a zone's range max bytes.
+const MinRangeMaxBytes = 1 << 20
+
+// Validate returns an error if the zone config is invalid. A zone
+// must specify attributes for at least one replica and sensible range
+// size targets. A GC policy is optional; zones without one inherit the
+// policy of the enclosing zone.
+func (z *ZoneConfig) Validate() error {
+	if len(z.ReplicaAttrs) == 0 {
+		return util.Errorf("attributes for at least one replica must be specified in zone config")
+	}
+	if z.RangeMaxBytes < MinRangeMaxBytes {
+		return util.Errorf("RangeMaxBytes %d less than minimum allowed %d", z.RangeMaxBytes, MinRangeMaxBytes)
+	}
+	if z.RangeMinBytes >= z.RangeMaxBytes {
+		return util.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
+			z.RangeMinBytes, z.RangeMaxBytes)
+	}
+	if z.RecoverySnapshotRate < 0 || z.RebalanceSnapshotRate < 0 {
+		return util.Errorf("snapshot rates must not be negative")
+	}
+	return nil
+}
+
+// RequiredAttrs returns the attributes required of the zone's i-th
+// replica: those listed in ReplicaAttrs together with the
+// constraints required of every replica in the zone.
+func (z *ZoneConfig) RequiredAttrs(i int) Attributes {
+	attrs := append([]string(nil), z.Constraints.Attrs...)
+	return Attributes{Attrs: append(attrs, z.ReplicaAttrs[i].Attrs...)}
+}
+
 // IsSubset returns whether attributes list a is a subset of
 // attributes list b.
 func (a Attributes) IsSubset(b Attributes) bool {
@@ -43,8 +43,11 @@ type Replica struct {
 	NodeID  NodeID  `protobuf:"varint,1,opt,name=node_id,customtype=NodeID" json:"node_id"`
 	StoreID StoreID `protobuf:"varint,2,opt,name=store_id,customtype=StoreID" json:"store_id"`
 	// Combination of node & store attributes.
-	Attrs            Attributes `protobuf:"bytes,3,opt,name=attrs" json:"attrs"`
-	XXX_unrecognized []byte     `json:"-"`
+	Attrs Attributes `protobuf:"bytes,3,opt,name=attrs" json:"attrs"`
+	// ReplicaID identifies the replica within its range. A replica which
+	// is removed from a store and later added back is assigned a new ID.
+	ReplicaID        int32  `protobuf:"varint,4,opt,name=replica_id" json:"replica_id"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *Replica) Reset()         { *m = Replica{} }
@@ -58,6 +61,13 @@ func (m *Replica) GetAttrs() Attributes {
 	return Attributes{}
 }
 
+func (m *Replica) GetReplicaID() int32 {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
 // RangeDescriptor is the value stored in a range metadata key.
 // A range is described using an inclusive start key, a non-inclusive end key,
 // and a list of replicas where the range is stored.
@@ -71,8 +81,11 @@ type RangeDescriptor struct {
 	EndKey Key `protobuf:"bytes,3,opt,name=end_key,customtype=Key" json:"end_key"`
 	// Replicas is the set of replicas on which this range is stored, the
 	// ordering being arbitrary and subject to permutation.
-	Replicas         []Replica `protobuf:"bytes,4,rep,name=replicas" json:"replicas"`
-	XXX_unrecognized []byte    `json:"-"`
+	Replicas []Replica `protobuf:"bytes,4,rep,name=replicas" json:"replicas"`
+	// NextReplicaID is the ID assigned to the next replica added to the
+	// range.
+	NextReplicaID    int32  `protobuf:"varint,5,opt,name=next_replica_id" json:"next_replica_id"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *RangeDescriptor) Reset()         { *m = RangeDescriptor{} }
@@ -93,6 +106,13 @@ func (m *RangeDescriptor) GetReplicas() []Replica {
 	return nil
 }
 
+func (m *RangeDescriptor) GetNextReplicaID() int32 {
+	if m != nil {
+		return m.NextReplicaID
+	}
+	return 0
+}
+
 // GCPolicy defines garbage collection policies which apply to MVCC
 // values within a zone.
 //
@@ -172,8 +192,37 @@ type ZoneConfig struct {
 	RangeMaxBytes int64        `protobuf:"varint,3,opt,name=range_max_bytes" json:"range_max_bytes" yaml:"range_max_bytes,omitempty"`
 	// If GC policy is not set, uses the next highest, non-null policy
 	// in the zone config hierarchy, up to the default policy if necessary.
-	GC               *GCPolicy `protobuf:"bytes,4,opt,name=gc" json:"gc,omitempty" yaml:"gc,omitempty"`
-	XXX_unrecognized []byte    `json:"-"`
+	GC *GCPolicy `protobuf:"bytes,4,opt,name=gc" json:"gc,omitempty" yaml:"gc,omitempty"`
+	// Constraints are attributes required of every replica in the zone,
+	// in addition to those given in ReplicaAttrs. They pin the zone's
+	// data to a locality, e.g. "region=eu".
+	Constraints Attributes `protobuf:"bytes,5,opt,name=constraints" json:"constraints" yaml:"constraints,omitempty"`
+	// RecoverySnapshotRate and RebalanceSnapshotRate limit the bandwidth
+	// in bytes per second which each store uses to send snapshots to
+	// replicas which have fallen behind or are being restored, and to
+	// replicas added by rebalancing, respectively. They're cluster
+	// settings: only those of the default zone config apply, to every
+	// store. Zero imposes no limit.
+	RecoverySnapshotRate  int64 `protobuf:"varint,6,opt,name=recovery_snapshot_rate" json:"recovery_snapshot_rate" yaml:"recovery_snapshot_rate,omitempty"`
+	RebalanceSnapshotRate int64 `protobuf:"varint,7,opt,name=rebalance_snapshot_rate" json:"rebalance_snapshot_rate" yaml:"rebalance_snapshot_rate,omitempty"`
+	// SplitIntervalNanos and RebalanceIntervalNanos are the minimum
+	// intervals between background range splits and between background
+	// replica changes, respectively, on each store. Zero imposes no
+	// limit. Cluster settings, like the snapshot rates.
+	SplitIntervalNanos     int64 `protobuf:"varint,8,opt,name=split_interval_nanos" json:"split_interval_nanos" yaml:"split_interval_nanos,omitempty"`
+	RebalanceIntervalNanos int64 `protobuf:"varint,9,opt,name=rebalance_interval_nanos" json:"rebalance_interval_nanos" yaml:"rebalance_interval_nanos,omitempty"`
+	// PauseWindows is a comma-separated list of daily windows, each
+	// specified as HH:MM-HH:MM in UTC, during which background data
+	// movement (splits and replica changes) is paused. A cluster
+	// setting, like the snapshot rates.
+	PauseWindows string `protobuf:"bytes,10,opt,name=pause_windows" json:"pause_windows" yaml:"pause_windows,omitempty"`
+	// MaxTxnIntents and MaxTxnIntentBytes limit the number of intents
+	// and the total size in bytes of the writes which a transaction may
+	// make. Transactions may override either limit. Zero imposes no
+	// limit. Cluster settings, like the snapshot rates.
+	MaxTxnIntents     int64  `protobuf:"varint,11,opt,name=max_txn_intents" json:"max_txn_intents" yaml:"max_txn_intents,omitempty"`
+	MaxTxnIntentBytes int64  `protobuf:"varint,12,opt,name=max_txn_intent_bytes" json:"max_txn_intent_bytes" yaml:"max_txn_intent_bytes,omitempty"`
+	XXX_unrecognized  []byte `json:"-"`
 }
 
 func (m *ZoneConfig) Reset()         { *m = ZoneConfig{} }
@@ -208,6 +257,62 @@ func (m *ZoneConfig) GetGC() *GCPolicy {
 	return nil
 }
 
+func (m *ZoneConfig) GetConstraints() Attributes {
+	if m != nil {
+		return m.Constraints
+	}
+	return Attributes{}
+}
+
+func (m *ZoneConfig) GetRecoverySnapshotRate() int64 {
+	if m != nil {
+		return m.RecoverySnapshotRate
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetRebalanceSnapshotRate() int64 {
+	if m != nil {
+		return m.RebalanceSnapshotRate
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetSplitIntervalNanos() int64 {
+	if m != nil {
+		return m.SplitIntervalNanos
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetRebalanceIntervalNanos() int64 {
+	if m != nil {
+		return m.RebalanceIntervalNanos
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetPauseWindows() string {
+	if m != nil {
+		return m.PauseWindows
+	}
+	return ""
+}
+
+func (m *ZoneConfig) GetMaxTxnIntents() int64 {
+	if m != nil {
+		return m.MaxTxnIntents
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetMaxTxnIntentBytes() int64 {
+	if m != nil {
+		return m.MaxTxnIntentBytes
+	}
+	return 0
+}
+
 // RangeTree holds the root node and size of the range tree.
 type RangeTree struct {
 	RootKey          Key    `protobuf:"bytes,1,opt,name=root_key,customtype=Key" json:"root_key"`
@@ -383,6 +488,21 @@ func (m *Replica) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ReplicaID |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -511,6 +631,21 @@ func (m *RangeDescriptor) Unmarshal(data []byte) error {
 			m.Replicas = append(m.Replicas, Replica{})
 			m.Replicas[len(m.Replicas)-1].Unmarshal(data[index:postIndex])
 			index = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NextReplicaID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.NextReplicaID |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -840,6 +975,142 @@ func (m *ZoneConfig) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Constraints", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Constraints.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RecoverySnapshotRate", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.RecoverySnapshotRate |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RebalanceSnapshotRate", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.RebalanceSnapshotRate |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SplitIntervalNanos", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.SplitIntervalNanos |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RebalanceIntervalNanos", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.RebalanceIntervalNanos |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PauseWindows", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PauseWindows = string(data[index:postIndex])
+			index = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnIntents", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxTxnIntents |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnIntentBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxTxnIntentBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -1108,6 +1379,7 @@ func (m *Replica) Size() (n int) {
 	n += 1 + sovConfig(uint64(m.StoreID))
 	l = m.Attrs.Size()
 	n += 1 + l + sovConfig(uint64(l))
+	n += 1 + sovConfig(uint64(m.ReplicaID))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1128,6 +1400,7 @@ func (m *RangeDescriptor) Size() (n int) {
 			n += 1 + l + sovConfig(uint64(l))
 		}
 	}
+	n += 1 + sovConfig(uint64(m.NextReplicaID))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1191,6 +1464,16 @@ func (m *ZoneConfig) Size() (n int) {
 		l = m.GC.Size()
 		n += 1 + l + sovConfig(uint64(l))
 	}
+	l = m.Constraints.Size()
+	n += 1 + l + sovConfig(uint64(l))
+	n += 1 + sovConfig(uint64(m.RecoverySnapshotRate))
+	n += 1 + sovConfig(uint64(m.RebalanceSnapshotRate))
+	n += 1 + sovConfig(uint64(m.SplitIntervalNanos))
+	n += 1 + sovConfig(uint64(m.RebalanceIntervalNanos))
+	l = len(m.PauseWindows)
+	n += 1 + l + sovConfig(uint64(l))
+	n += 1 + sovConfig(uint64(m.MaxTxnIntents))
+	n += 1 + sovConfig(uint64(m.MaxTxnIntentBytes))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1308,6 +1591,9 @@ func (m *Replica) MarshalTo(data []byte) (n int, err error) {
 		return 0, err
 	}
 	i += n1
+	data[i] = 0x20
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.ReplicaID))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -1360,6 +1646,9 @@ func (m *RangeDescriptor) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	data[i] = 0x28
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.NextReplicaID))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -1509,6 +1798,36 @@ func (m *ZoneConfig) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n4
 	}
+	data[i] = 0x2a
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.Constraints.Size()))
+	n10, err := m.Constraints.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n10
+	data[i] = 0x30
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.RecoverySnapshotRate))
+	data[i] = 0x38
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.RebalanceSnapshotRate))
+	data[i] = 0x40
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.SplitIntervalNanos))
+	data[i] = 0x48
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.RebalanceIntervalNanos))
+	data[i] = 0x52
+	i++
+	i = encodeVarintConfig(data, i, uint64(len(m.PauseWindows)))
+	i += copy(data[i:], m.PauseWindows)
+	data[i] = 0x58
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.MaxTxnIntents))
+	data[i] = 0x60
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.MaxTxnIntentBytes))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -333,15 +333,15 @@ func (v *Value) InitChecksum(key []byte) {
 }
 
 // Verify verifies the value's Checksum matches a newly-computed
-// checksum of the value's contents. If the value's Checksum is not
-// set the verification is a noop. It also ensures that both Bytes
-// and Integer are not both set.
+// checksum of the value's contents, returning a ValueCorruptionError
+// if it doesn't. If the value's Checksum is not set the verification
+// is a noop. It also ensures that both Bytes and Integer are not both
+// set.
 func (v *Value) Verify(key []byte) error {
 	if v.Checksum != nil {
 		cksum := v.computeChecksum(key)
 		if v.GetChecksum() != cksum {
-			return util.Errorf("invalid checksum (%d) for key %s, value [% x]",
-				cksum, Key(key), v)
+			return &ValueCorruptionError{Key: Key(key), Checksum: v.GetChecksum(), ComputedChecksum: cksum}
 		}
 	}
 	if v.Bytes != nil && v.Integer != nil {
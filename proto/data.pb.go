@@ -516,8 +516,18 @@ type Transaction struct {
 	// Bits of this mechanism are found in the local sender, the range and the
 	// txn_coord_sender, with brief comments referring here.
 	// See https://github.com/cockroachdb/cockroach/pull/221.
-	CertainNodes     NodeList `protobuf:"bytes,12,opt,name=certain_nodes" json:"certain_nodes"`
-	XXX_unrecognized []byte   `json:"-"`
+	CertainNodes NodeList `protobuf:"bytes,12,opt,name=certain_nodes" json:"certain_nodes"`
+	// AppName is an optional tag identifying the application which
+	// issued the transaction. Transaction statistics are aggregated by
+	// application name to attribute contention to specific services.
+	AppName string `protobuf:"bytes,13,opt,name=app_name" json:"app_name"`
+	// MaxIntents, if non-zero, overrides the coordinator's limit on the
+	// number of intents the transaction may write.
+	MaxIntents int64 `protobuf:"varint,14,opt,name=max_intents" json:"max_intents"`
+	// MaxIntentBytes, if non-zero, overrides the coordinator's limit on
+	// the total size in bytes of the writes the transaction may make.
+	MaxIntentBytes   int64  `protobuf:"varint,15,opt,name=max_intent_bytes" json:"max_intent_bytes"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *Transaction) Reset()      { *m = Transaction{} }
@@ -600,6 +610,27 @@ func (m *Transaction) GetCertainNodes() NodeList {
 	return NodeList{}
 }
 
+func (m *Transaction) GetAppName() string {
+	if m != nil {
+		return m.AppName
+	}
+	return ""
+}
+
+func (m *Transaction) GetMaxIntents() int64 {
+	if m != nil {
+		return m.MaxIntents
+	}
+	return 0
+}
+
+func (m *Transaction) GetMaxIntentBytes() int64 {
+	if m != nil {
+		return m.MaxIntentBytes
+	}
+	return 0
+}
+
 // Lease contains information about leader leases including the
 // expiration and lease holder.
 type Lease struct {
@@ -616,7 +647,21 @@ type Lease struct {
 	// The leadership term for this lease.
 	Term uint64 `protobuf:"varint,3,opt,name=term" json:"term"`
 	// The Raft NodeID on which the would-be lease holder lives.
-	RaftNodeID       uint64 `protobuf:"varint,4,opt,name=raft_node_id" json:"raft_node_id"`
+	RaftNodeID uint64 `protobuf:"varint,4,opt,name=raft_node_id" json:"raft_node_id"`
+	// The closed timestamp at or below which the range accepts no more
+	// writes. Every replica which has applied the lease has applied all
+	// writes at or below it and may serve reads at or below it without
+	// holding the lease.
+	ClosedTimestamp Timestamp `protobuf:"bytes,5,opt,name=closed_timestamp" json:"closed_timestamp"`
+	// The liveness epoch of the holder's node, for an epoch-based lease.
+	// Such a lease is valid, regardless of its expiration, for as long as
+	// the holder's node liveness record carries this epoch and hasn't
+	// expired. Zero for a lease which is valid until its expiration.
+	Epoch int64 `protobuf:"varint,6,opt,name=epoch" json:"epoch"`
+	// The unix nanos wall time at which the holder's tenure began, i.e.
+	// the start of the first of its consecutive leases. Set when the
+	// lease is applied; commands proposed before it are not applied.
+	TenureStart      int64  `protobuf:"varint,7,opt,name=tenure_start" json:"tenure_start"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -652,6 +697,27 @@ func (m *Lease) GetRaftNodeID() uint64 {
 	return 0
 }
 
+func (m *Lease) GetClosedTimestamp() Timestamp {
+	if m != nil {
+		return m.ClosedTimestamp
+	}
+	return Timestamp{}
+}
+
+func (m *Lease) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Lease) GetTenureStart() int64 {
+	if m != nil {
+		return m.TenureStart
+	}
+	return 0
+}
+
 // MVCCMetadata holds MVCC metadata for a key. Used by storage/engine/mvcc.go.
 type MVCCMetadata struct {
 	Txn *Transaction `protobuf:"bytes,1,opt,name=txn" json:"txn,omitempty"`
@@ -2198,6 +2264,58 @@ func (m *Transaction) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AppName = string(data[index:postIndex])
+			index = postIndex
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxIntents", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxIntents |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxIntentBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxIntentBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2300,6 +2418,60 @@ func (m *Lease) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClosedTimestamp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ClosedTimestamp.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Epoch |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TenureStart", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.TenureStart |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2969,6 +3141,10 @@ func (m *Transaction) Size() (n int) {
 	n += 1 + l + sovData(uint64(l))
 	l = m.CertainNodes.Size()
 	n += 1 + l + sovData(uint64(l))
+	l = len(m.AppName)
+	n += 1 + l + sovData(uint64(l))
+	n += 1 + sovData(uint64(m.MaxIntents))
+	n += 1 + sovData(uint64(m.MaxIntentBytes))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2982,6 +3158,10 @@ func (m *Lease) Size() (n int) {
 	n += 1 + sovData(uint64(m.Duration))
 	n += 1 + sovData(uint64(m.Term))
 	n += 1 + sovData(uint64(m.RaftNodeID))
+	l = m.ClosedTimestamp.Size()
+	n += 1 + l + sovData(uint64(l))
+	n += 1 + sovData(uint64(m.Epoch))
+	n += 1 + sovData(uint64(m.TenureStart))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -3593,6 +3773,16 @@ func (m *Transaction) MarshalTo(data []byte) (n int, err error) {
 		return 0, err
 	}
 	i += n19
+	data[i] = 0x6a
+	i++
+	i = encodeVarintData(data, i, uint64(len(m.AppName)))
+	i += copy(data[i:], m.AppName)
+	data[i] = 0x70
+	i++
+	i = encodeVarintData(data, i, uint64(m.MaxIntents))
+	data[i] = 0x78
+	i++
+	i = encodeVarintData(data, i, uint64(m.MaxIntentBytes))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -3626,6 +3816,20 @@ func (m *Lease) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x20
 	i++
 	i = encodeVarintData(data, i, uint64(m.RaftNodeID))
+	data[i] = 0x2a
+	i++
+	i = encodeVarintData(data, i, uint64(m.ClosedTimestamp.Size()))
+	n23, err := m.ClosedTimestamp.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n23
+	data[i] = 0x30
+	i++
+	i = encodeVarintData(data, i, uint64(m.Epoch))
+	data[i] = 0x38
+	i++
+	i = encodeVarintData(data, i, uint64(m.TenureStart))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -324,6 +324,10 @@ func TestValueChecksumWithBytes(t *testing.T) {
 	v.Bytes = []byte("abcd")
 	if err := v.Verify(k); err == nil {
 		t.Error("expected checksum verification failure on different value")
+	} else if cErr, ok := err.(*ValueCorruptionError); !ok {
+		t.Errorf("expected ValueCorruptionError; got %T: %s", err, err)
+	} else if !cErr.Key.Equal(k) || cErr.Checksum != v.GetChecksum() {
+		t.Errorf("unexpected corruption error %+v", cErr)
 	}
 }
 
@@ -177,3 +177,49 @@ func (e *OpRequiresTxnError) Error() string {
 func (e *ConditionFailedError) Error() string {
 	return fmt.Sprintf("unexpected value: %s", e.ActualValue)
 }
+
+// Error formats error.
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("user %q lacks permission for %s on key range %q-%q", e.User, e.Method, e.Key, e.EndKey)
+}
+
+// Error formats error.
+func (e *RangeTooLargeError) Error() string {
+	return fmt.Sprintf("range %d is %d bytes, far beyond its max size of %d bytes; writes are refused until it splits",
+		e.RaftID, e.Bytes, e.MaxBytes)
+}
+
+// Error formats error.
+func (e *ValueCorruptionError) Error() string {
+	return fmt.Sprintf("value of key %s is corrupt: checksum %d doesn't match computed checksum %d",
+		e.Key, e.Checksum, e.ComputedChecksum)
+}
+
+// Error formats error.
+func (e *IncrementOverflowError) Error() string {
+	return fmt.Sprintf("key %s with value %d incremented by %d results in overflow", e.Key, e.Value, e.Increment)
+}
+
+// Error formats error.
+func (e *StoreUnhealthyError) Error() string {
+	return fmt.Sprintf("store %d is unhealthy: %s", e.StoreID, e.Reason)
+}
+
+// CanRetry implements the util.Retryable interface. The error is
+// retryable so that clients back off while the store's leases move
+// to healthy replicas.
+func (e *StoreUnhealthyError) CanRetry() bool {
+	return true
+}
+
+// Error formats error.
+func (e *StoreOverloadedError) Error() string {
+	return fmt.Sprintf("store %d is overloaded: %s", e.StoreID, e.Reason)
+}
+
+// CanRetry implements the util.Retryable interface. The error is
+// retryable so that clients back off, giving the store a chance to
+// work through its backlog.
+func (e *StoreOverloadedError) CanRetry() bool {
+	return true
+}
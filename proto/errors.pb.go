@@ -332,6 +332,162 @@ func (m *ConditionFailedError) GetActualValue() *Value {
 	return nil
 }
 
+// A PermissionError indicates that the user lacks the read or write
+// permission required by a command for some part of its key range.
+type PermissionError struct {
+	User             string `protobuf:"bytes,1,opt,name=user" json:"user"`
+	Method           string `protobuf:"bytes,2,opt,name=method" json:"method"`
+	Key              Key    `protobuf:"bytes,3,opt,name=key,customtype=Key" json:"key"`
+	EndKey           Key    `protobuf:"bytes,4,opt,name=end_key,customtype=Key" json:"end_key"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *PermissionError) Reset()         { *m = PermissionError{} }
+func (m *PermissionError) String() string { return proto1.CompactTextString(m) }
+func (*PermissionError) ProtoMessage()    {}
+
+func (m *PermissionError) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *PermissionError) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+// A RangeTooLargeError indicates that a write was refused because the
+// range has grown far beyond its max size, typically because it is
+// failing to split.
+type RangeTooLargeError struct {
+	RaftID           int64  `protobuf:"varint,1,opt,name=raft_id" json:"raft_id"`
+	Bytes            int64  `protobuf:"varint,2,opt,name=bytes" json:"bytes"`
+	MaxBytes         int64  `protobuf:"varint,3,opt,name=max_bytes" json:"max_bytes"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *RangeTooLargeError) Reset()         { *m = RangeTooLargeError{} }
+func (m *RangeTooLargeError) String() string { return proto1.CompactTextString(m) }
+func (*RangeTooLargeError) ProtoMessage()    {}
+
+func (m *RangeTooLargeError) GetRaftID() int64 {
+	if m != nil {
+		return m.RaftID
+	}
+	return 0
+}
+
+func (m *RangeTooLargeError) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *RangeTooLargeError) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+// A ValueCorruptionError indicates that a value read from storage
+// doesn't match its checksum, and so was corrupted.
+type ValueCorruptionError struct {
+	Key              Key    `protobuf:"bytes,1,opt,name=key,customtype=Key" json:"key"`
+	Checksum         uint32 `protobuf:"varint,2,opt,name=checksum" json:"checksum"`
+	ComputedChecksum uint32 `protobuf:"varint,3,opt,name=computed_checksum" json:"computed_checksum"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *ValueCorruptionError) Reset()         { *m = ValueCorruptionError{} }
+func (m *ValueCorruptionError) String() string { return proto1.CompactTextString(m) }
+func (*ValueCorruptionError) ProtoMessage()    {}
+
+func (m *ValueCorruptionError) GetChecksum() uint32 {
+	if m != nil {
+		return m.Checksum
+	}
+	return 0
+}
+
+func (m *ValueCorruptionError) GetComputedChecksum() uint32 {
+	if m != nil {
+		return m.ComputedChecksum
+	}
+	return 0
+}
+
+// An IncrementOverflowError indicates that incrementing the integer
+// value of a key would overflow or underflow an int64.
+type IncrementOverflowError struct {
+	Key              Key    `protobuf:"bytes,1,opt,name=key,customtype=Key" json:"key"`
+	Value            int64  `protobuf:"varint,2,opt,name=value" json:"value"`
+	Increment        int64  `protobuf:"varint,3,opt,name=increment" json:"increment"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *IncrementOverflowError) Reset()         { *m = IncrementOverflowError{} }
+func (m *IncrementOverflowError) String() string { return proto1.CompactTextString(m) }
+func (*IncrementOverflowError) ProtoMessage()    {}
+
+func (m *IncrementOverflowError) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *IncrementOverflowError) GetIncrement() int64 {
+	if m != nil {
+		return m.Increment
+	}
+	return 0
+}
+
+// A StoreUnhealthyError indicates that a store failed a write because
+// its engine is unhealthy: its disk is full, its writes have stalled
+// or they fail with I/O errors.
+type StoreUnhealthyError struct {
+	StoreID          StoreID `protobuf:"varint,1,opt,name=store_id,customtype=StoreID" json:"store_id"`
+	Reason           string  `protobuf:"bytes,2,opt,name=reason" json:"reason"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *StoreUnhealthyError) Reset()         { *m = StoreUnhealthyError{} }
+func (m *StoreUnhealthyError) String() string { return proto1.CompactTextString(m) }
+func (*StoreUnhealthyError) ProtoMessage()    {}
+
+func (m *StoreUnhealthyError) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// A StoreOverloadedError indicates that a store shed a command because
+// it is overloaded.
+type StoreOverloadedError struct {
+	StoreID          StoreID `protobuf:"varint,1,opt,name=store_id,customtype=StoreID" json:"store_id"`
+	Reason           string  `protobuf:"bytes,2,opt,name=reason" json:"reason"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *StoreOverloadedError) Reset()         { *m = StoreOverloadedError{} }
+func (m *StoreOverloadedError) String() string { return proto1.CompactTextString(m) }
+func (*StoreOverloadedError) ProtoMessage()    {}
+
+func (m *StoreOverloadedError) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 // ErrorDetail is a union type containing all available errors.
 type ErrorDetail struct {
 	NotLeader                     *NotLeaderError                     `protobuf:"bytes,1,opt,name=not_leader" json:"not_leader,omitempty"`
@@ -346,6 +502,12 @@ type ErrorDetail struct {
 	WriteTooOld                   *WriteTooOldError                   `protobuf:"bytes,10,opt,name=write_too_old" json:"write_too_old,omitempty"`
 	OpRequiresTxn                 *OpRequiresTxnError                 `protobuf:"bytes,11,opt,name=op_requires_txn" json:"op_requires_txn,omitempty"`
 	ConditionFailed               *ConditionFailedError               `protobuf:"bytes,12,opt,name=condition_failed" json:"condition_failed,omitempty"`
+	Permission                    *PermissionError                    `protobuf:"bytes,13,opt,name=permission" json:"permission,omitempty"`
+	RangeTooLarge                 *RangeTooLargeError                 `protobuf:"bytes,14,opt,name=range_too_large" json:"range_too_large,omitempty"`
+	ValueCorruption               *ValueCorruptionError               `protobuf:"bytes,15,opt,name=value_corruption" json:"value_corruption,omitempty"`
+	IncrementOverflow             *IncrementOverflowError             `protobuf:"bytes,16,opt,name=increment_overflow" json:"increment_overflow,omitempty"`
+	StoreUnhealthy                *StoreUnhealthyError                `protobuf:"bytes,17,opt,name=store_unhealthy" json:"store_unhealthy,omitempty"`
+	StoreOverloaded               *StoreOverloadedError               `protobuf:"bytes,18,opt,name=store_overloaded" json:"store_overloaded,omitempty"`
 	XXX_unrecognized              []byte                              `json:"-"`
 }
 
@@ -437,6 +599,48 @@ func (m *ErrorDetail) GetConditionFailed() *ConditionFailedError {
 	return nil
 }
 
+func (m *ErrorDetail) GetPermission() *PermissionError {
+	if m != nil {
+		return m.Permission
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetRangeTooLarge() *RangeTooLargeError {
+	if m != nil {
+		return m.RangeTooLarge
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetValueCorruption() *ValueCorruptionError {
+	if m != nil {
+		return m.ValueCorruption
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetIncrementOverflow() *IncrementOverflowError {
+	if m != nil {
+		return m.IncrementOverflow
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetStoreUnhealthy() *StoreUnhealthyError {
+	if m != nil {
+		return m.StoreUnhealthy
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetStoreOverloaded() *StoreOverloadedError {
+	if m != nil {
+		return m.StoreOverloaded
+	}
+	return nil
+}
+
 // Error is a generic represesentation including a string message
 // and information about retryability.
 type Error struct {
@@ -1439,7 +1643,7 @@ func (m *ConditionFailedError) Unmarshal(data []byte) error {
 	}
 	return nil
 }
-func (m *ErrorDetail) Unmarshal(data []byte) error {
+func (m *PermissionError) Unmarshal(data []byte) error {
 	l := len(data)
 	index := 0
 	for index < l {
@@ -1460,190 +1664,761 @@ func (m *ErrorDetail) Unmarshal(data []byte) error {
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NotLeader", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
+			postIndex := index + int(stringLen)
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.NotLeader == nil {
-				m.NotLeader = &NotLeaderError{}
-			}
-			if err := m.NotLeader.Unmarshal(data[index:postIndex]); err != nil {
-				return err
-			}
+			m.User = string(data[index:postIndex])
 			index = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RangeNotFound", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
+			postIndex := index + int(stringLen)
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.RangeNotFound == nil {
-				m.RangeNotFound = &RangeNotFoundError{}
-			}
-			if err := m.RangeNotFound.Unmarshal(data[index:postIndex]); err != nil {
-				return err
-			}
+			m.Method = string(data[index:postIndex])
 			index = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RangeKeyMismatch", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
+			postIndex := index + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.RangeKeyMismatch == nil {
-				m.RangeKeyMismatch = &RangeKeyMismatchError{}
-			}
-			if err := m.RangeKeyMismatch.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.Key.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
 		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadWithinUncertaintyInterval", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field EndKey", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
+			postIndex := index + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.ReadWithinUncertaintyInterval == nil {
-				m.ReadWithinUncertaintyInterval = &ReadWithinUncertaintyIntervalError{}
-			}
-			if err := m.ReadWithinUncertaintyInterval.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.EndKey.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TransactionAborted", wireType)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *RangeTooLargeError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RaftID", wireType)
 			}
-			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				m.RaftID |= (int64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.TransactionAborted == nil {
-				m.TransactionAborted = &TransactionAbortedError{}
-			}
-			if err := m.TransactionAborted.Unmarshal(data[index:postIndex]); err != nil {
-				return err
-			}
-			index = postIndex
-		case 6:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TransactionPush", wireType)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bytes", wireType)
 			}
-			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Bytes |= (int64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.TransactionPush == nil {
-				m.TransactionPush = &TransactionPushError{}
-			}
-			if err := m.TransactionPush.Unmarshal(data[index:postIndex]); err != nil {
-				return err
-			}
-			index = postIndex
-		case 7:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TransactionRetry", wireType)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytes", wireType)
 			}
-			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				msglen |= (int(b) & 0x7F) << shift
+				m.MaxBytes |= (int64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.TransactionRetry == nil {
-				m.TransactionRetry = &TransactionRetryError{}
-			}
-			if err := m.TransactionRetry.Unmarshal(data[index:postIndex]); err != nil {
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *ValueCorruptionError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Key.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Checksum |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ComputedChecksum", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ComputedChecksum |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *IncrementOverflowError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Key.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Value |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Increment", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Increment |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *StoreUnhealthyError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.StoreID |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Reason = string(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *StoreOverloadedError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.StoreID |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Reason = string(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *ErrorDetail) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NotLeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.NotLeader == nil {
+				m.NotLeader = &NotLeaderError{}
+			}
+			if err := m.NotLeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeNotFound", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.RangeNotFound == nil {
+				m.RangeNotFound = &RangeNotFoundError{}
+			}
+			if err := m.RangeNotFound.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeKeyMismatch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.RangeKeyMismatch == nil {
+				m.RangeKeyMismatch = &RangeKeyMismatchError{}
+			}
+			if err := m.RangeKeyMismatch.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadWithinUncertaintyInterval", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ReadWithinUncertaintyInterval == nil {
+				m.ReadWithinUncertaintyInterval = &ReadWithinUncertaintyIntervalError{}
+			}
+			if err := m.ReadWithinUncertaintyInterval.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TransactionAborted", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TransactionAborted == nil {
+				m.TransactionAborted = &TransactionAbortedError{}
+			}
+			if err := m.TransactionAborted.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TransactionPush", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TransactionPush == nil {
+				m.TransactionPush = &TransactionPushError{}
+			}
+			if err := m.TransactionPush.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TransactionRetry", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TransactionRetry == nil {
+				m.TransactionRetry = &TransactionRetryError{}
+			}
+			if err := m.TransactionRetry.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
@@ -1782,6 +2557,168 @@ func (m *ErrorDetail) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Permission", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Permission == nil {
+				m.Permission = &PermissionError{}
+			}
+			if err := m.Permission.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeTooLarge", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.RangeTooLarge == nil {
+				m.RangeTooLarge = &RangeTooLargeError{}
+			}
+			if err := m.RangeTooLarge.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueCorruption", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ValueCorruption == nil {
+				m.ValueCorruption = &ValueCorruptionError{}
+			}
+			if err := m.ValueCorruption.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IncrementOverflow", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.IncrementOverflow == nil {
+				m.IncrementOverflow = &IncrementOverflowError{}
+			}
+			if err := m.IncrementOverflow.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreUnhealthy", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StoreUnhealthy == nil {
+				m.StoreUnhealthy = &StoreUnhealthyError{}
+			}
+			if err := m.StoreUnhealthy.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreOverloaded", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StoreOverloaded == nil {
+				m.StoreOverloaded = &StoreOverloadedError{}
+			}
+			if err := m.StoreOverloaded.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -1965,6 +2902,24 @@ func (this *ErrorDetail) GetValue() interface{} {
 	if this.ConditionFailed != nil {
 		return this.ConditionFailed
 	}
+	if this.Permission != nil {
+		return this.Permission
+	}
+	if this.RangeTooLarge != nil {
+		return this.RangeTooLarge
+	}
+	if this.ValueCorruption != nil {
+		return this.ValueCorruption
+	}
+	if this.IncrementOverflow != nil {
+		return this.IncrementOverflow
+	}
+	if this.StoreUnhealthy != nil {
+		return this.StoreUnhealthy
+	}
+	if this.StoreOverloaded != nil {
+		return this.StoreOverloaded
+	}
 	return nil
 }
 
@@ -1994,6 +2949,18 @@ func (this *ErrorDetail) SetValue(value interface{}) bool {
 		this.OpRequiresTxn = vt
 	case *ConditionFailedError:
 		this.ConditionFailed = vt
+	case *PermissionError:
+		this.Permission = vt
+	case *RangeTooLargeError:
+		this.RangeTooLarge = vt
+	case *ValueCorruptionError:
+		this.ValueCorruption = vt
+	case *IncrementOverflowError:
+		this.IncrementOverflow = vt
+	case *StoreUnhealthyError:
+		this.StoreUnhealthy = vt
+	case *StoreOverloadedError:
+		this.StoreOverloaded = vt
 	default:
 		return false
 	}
@@ -2025,19 +2992,96 @@ func (m *RangeKeyMismatchError) Size() (n int) {
 	_ = l
 	l = m.RequestStartKey.Size()
 	n += 1 + l + sovErrors(uint64(l))
-	l = m.RequestEndKey.Size()
+	l = m.RequestEndKey.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	if m.Range != nil {
+		l = m.Range.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ReadWithinUncertaintyIntervalError) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Timestamp.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	l = m.ExistingTimestamp.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TransactionAbortedError) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Txn.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TransactionPushError) Size() (n int) {
+	var l int
+	_ = l
+	if m.Txn != nil {
+		l = m.Txn.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	l = m.PusheeTxn.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TransactionRetryError) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Txn.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TransactionStatusError) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Txn.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	l = len(m.Msg)
+	n += 1 + l + sovErrors(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *WriteIntentError) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Key.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	l = m.Txn.Size()
 	n += 1 + l + sovErrors(uint64(l))
-	if m.Range != nil {
-		l = m.Range.Size()
-		n += 1 + l + sovErrors(uint64(l))
-	}
+	n += 2
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *ReadWithinUncertaintyIntervalError) Size() (n int) {
+func (m *WriteTooOldError) Size() (n int) {
 	var l int
 	_ = l
 	l = m.Timestamp.Size()
@@ -2050,36 +3094,38 @@ func (m *ReadWithinUncertaintyIntervalError) Size() (n int) {
 	return n
 }
 
-func (m *TransactionAbortedError) Size() (n int) {
+func (m *OpRequiresTxnError) Size() (n int) {
 	var l int
 	_ = l
-	l = m.Txn.Size()
-	n += 1 + l + sovErrors(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *TransactionPushError) Size() (n int) {
+func (m *ConditionFailedError) Size() (n int) {
 	var l int
 	_ = l
-	if m.Txn != nil {
-		l = m.Txn.Size()
+	if m.ActualValue != nil {
+		l = m.ActualValue.Size()
 		n += 1 + l + sovErrors(uint64(l))
 	}
-	l = m.PusheeTxn.Size()
-	n += 1 + l + sovErrors(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *TransactionRetryError) Size() (n int) {
+func (m *PermissionError) Size() (n int) {
 	var l int
 	_ = l
-	l = m.Txn.Size()
+	l = len(m.User)
+	n += 1 + l + sovErrors(uint64(l))
+	l = len(m.Method)
+	n += 1 + l + sovErrors(uint64(l))
+	l = m.Key.Size()
+	n += 1 + l + sovErrors(uint64(l))
+	l = m.EndKey.Size()
 	n += 1 + l + sovErrors(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
@@ -2087,62 +3133,62 @@ func (m *TransactionRetryError) Size() (n int) {
 	return n
 }
 
-func (m *TransactionStatusError) Size() (n int) {
+func (m *RangeTooLargeError) Size() (n int) {
 	var l int
 	_ = l
-	l = m.Txn.Size()
-	n += 1 + l + sovErrors(uint64(l))
-	l = len(m.Msg)
-	n += 1 + l + sovErrors(uint64(l))
+	n += 1 + sovErrors(uint64(m.RaftID))
+	n += 1 + sovErrors(uint64(m.Bytes))
+	n += 1 + sovErrors(uint64(m.MaxBytes))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *WriteIntentError) Size() (n int) {
+func (m *ValueCorruptionError) Size() (n int) {
 	var l int
 	_ = l
 	l = m.Key.Size()
 	n += 1 + l + sovErrors(uint64(l))
-	l = m.Txn.Size()
-	n += 1 + l + sovErrors(uint64(l))
-	n += 2
+	n += 1 + sovErrors(uint64(m.Checksum))
+	n += 1 + sovErrors(uint64(m.ComputedChecksum))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *WriteTooOldError) Size() (n int) {
+func (m *IncrementOverflowError) Size() (n int) {
 	var l int
 	_ = l
-	l = m.Timestamp.Size()
-	n += 1 + l + sovErrors(uint64(l))
-	l = m.ExistingTimestamp.Size()
+	l = m.Key.Size()
 	n += 1 + l + sovErrors(uint64(l))
+	n += 1 + sovErrors(uint64(m.Value))
+	n += 1 + sovErrors(uint64(m.Increment))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *OpRequiresTxnError) Size() (n int) {
+func (m *StoreUnhealthyError) Size() (n int) {
 	var l int
 	_ = l
+	n += 1 + sovErrors(uint64(m.StoreID))
+	l = len(m.Reason)
+	n += 1 + l + sovErrors(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *ConditionFailedError) Size() (n int) {
+func (m *StoreOverloadedError) Size() (n int) {
 	var l int
 	_ = l
-	if m.ActualValue != nil {
-		l = m.ActualValue.Size()
-		n += 1 + l + sovErrors(uint64(l))
-	}
+	n += 1 + sovErrors(uint64(m.StoreID))
+	l = len(m.Reason)
+	n += 1 + l + sovErrors(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2200,6 +3246,30 @@ func (m *ErrorDetail) Size() (n int) {
 		l = m.ConditionFailed.Size()
 		n += 1 + l + sovErrors(uint64(l))
 	}
+	if m.Permission != nil {
+		l = m.Permission.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	if m.RangeTooLarge != nil {
+		l = m.RangeTooLarge.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	if m.ValueCorruption != nil {
+		l = m.ValueCorruption.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	if m.IncrementOverflow != nil {
+		l = m.IncrementOverflow.Size()
+		n += 2 + l + sovErrors(uint64(l))
+	}
+	if m.StoreUnhealthy != nil {
+		l = m.StoreUnhealthy.Size()
+		n += 2 + l + sovErrors(uint64(l))
+	}
+	if m.StoreOverloaded != nil {
+		l = m.StoreOverloaded.Size()
+		n += 2 + l + sovErrors(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2637,6 +3707,207 @@ func (m *ConditionFailedError) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *PermissionError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *PermissionError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintErrors(data, i, uint64(len(m.User)))
+	i += copy(data[i:], m.User)
+	data[i] = 0x12
+	i++
+	i = encodeVarintErrors(data, i, uint64(len(m.Method)))
+	i += copy(data[i:], m.Method)
+	data[i] = 0x1a
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Key.Size()))
+	n17, err := m.Key.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n17
+	data[i] = 0x22
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.EndKey.Size()))
+	n18, err := m.EndKey.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n18
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *RangeTooLargeError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RangeTooLargeError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.RaftID))
+	data[i] = 0x10
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Bytes))
+	data[i] = 0x18
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.MaxBytes))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *ValueCorruptionError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *ValueCorruptionError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Key.Size()))
+	n31, err := m.Key.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n31
+	data[i] = 0x10
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Checksum))
+	data[i] = 0x18
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.ComputedChecksum))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *IncrementOverflowError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *IncrementOverflowError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Key.Size()))
+	n33, err := m.Key.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n33
+	data[i] = 0x10
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Value))
+	data[i] = 0x18
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Increment))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *StoreUnhealthyError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *StoreUnhealthyError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.StoreID))
+	data[i] = 0x12
+	i++
+	i = encodeVarintErrors(data, i, uint64(len(m.Reason)))
+	i += copy(data[i:], m.Reason)
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *StoreOverloadedError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *StoreOverloadedError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.StoreID))
+	data[i] = 0x12
+	i++
+	i = encodeVarintErrors(data, i, uint64(len(m.Reason)))
+	i += copy(data[i:], m.Reason)
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func (m *ErrorDetail) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
@@ -2772,6 +4043,72 @@ func (m *ErrorDetail) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n28
 	}
+	if m.Permission != nil {
+		data[i] = 0x6a
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.Permission.Size()))
+		n29, err := m.Permission.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n29
+	}
+	if m.RangeTooLarge != nil {
+		data[i] = 0x72
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.RangeTooLarge.Size()))
+		n30, err := m.RangeTooLarge.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n30
+	}
+	if m.ValueCorruption != nil {
+		data[i] = 0x7a
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.ValueCorruption.Size()))
+		n32, err := m.ValueCorruption.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n32
+	}
+	if m.IncrementOverflow != nil {
+		data[i] = 0x82
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.IncrementOverflow.Size()))
+		n34, err := m.IncrementOverflow.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n34
+	}
+	if m.StoreUnhealthy != nil {
+		data[i] = 0x8a
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.StoreUnhealthy.Size()))
+		n35, err := m.StoreUnhealthy.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n35
+	}
+	if m.StoreOverloaded != nil {
+		data[i] = 0x92
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.StoreOverloaded.Size()))
+		n36, err := m.StoreOverloaded.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n36
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -61,6 +61,22 @@ const (
 	InternalTruncateLog = "InternalTruncateLog"
 	// InternalLeaderLease requests a leader lease for a replica.
 	InternalLeaderLease = "InternalLeaderLease"
+	// InternalChangeReplicas adds or removes a replica of a range. The
+	// range descriptor and addressing records are updated in a
+	// distributed transaction whose commit trigger proposes the
+	// corresponding Raft membership change.
+	InternalChangeReplicas = "InternalChangeReplicas"
+	// InternalRecomputeStats recomputes a range's MVCC stats from its
+	// data and corrects drift accumulated by incremental updates.
+	InternalRecomputeStats = "InternalRecomputeStats"
+	// InternalExport writes a consistent snapshot of a key span as of
+	// a timestamp to a file in the export sink of the store serving
+	// the range, forming the storage half of backups.
+	InternalExport = "InternalExport"
+	// InternalImport writes key/value pairs at the timestamps of their
+	// values, optionally clearing the span they're written to first,
+	// forming the storage half of restores.
+	InternalImport = "InternalImport"
 )
 
 // ToValue generates a Value message which contains an encoded copy of this
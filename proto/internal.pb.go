@@ -131,7 +131,10 @@ func (*InternalHeartbeatTxnResponse) ProtoMessage()    {}
 // sent by range leaders after scanning range data to find expired
 // MVCC values.
 type InternalGCRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	// GCMeta is the range's GC metadata, stored by the request unless
+	// zero. A range's GC is split into many requests; only the last
+	// carries the metadata, once the scan is complete.
 	GCMeta           GCMetadata                `protobuf:"bytes,2,opt,name=gc_meta" json:"gc_meta"`
 	Keys             []InternalGCRequest_GCKey `protobuf:"bytes,3,rep,name=keys" json:"keys"`
 	XXX_unrecognized []byte                    `json:"-"`
@@ -255,7 +258,12 @@ func (m *InternalPushTxnResponse) GetPusheeTxn() *Transaction {
 // coordinators and after success calling InternalPushTxn to clean up
 // write intents: either to remove them or commit them.
 type InternalResolveIntentRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	// Keys, if not empty, lists the individual keys whose intents are
+	// resolved, instead of the key range of the header. Only the listed
+	// keys which lie within the header's key range are resolved, so that
+	// the request may be split by range like any range request.
+	Keys             []Key  `protobuf:"bytes,2,rep,name=keys,customtype=Key" json:"keys,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -341,8 +349,18 @@ func (*InternalTruncateLogResponse) ProtoMessage()    {}
 // method. It is sent by the store on behalf of one of its ranges upon receipt
 // of a leader election event for that range.
 type InternalLeaderLeaseRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
-	Lease            Lease  `protobuf:"bytes,2,opt,name=lease" json:"lease"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Lease         Lease `protobuf:"bytes,2,opt,name=lease" json:"lease"`
+	// PrevLease is set when the holder of a lease transfers it to
+	// another replica. The new lease is granted, though it begins
+	// before the previous lease expires, only if PrevLease is still
+	// the range's lease.
+	PrevLease *Lease `protobuf:"bytes,3,opt,name=prev_lease" json:"prev_lease,omitempty"`
+	// PrevHolderEpoch is set when taking over an epoch-based lease from
+	// another node. It is the liveness epoch that node was moved to, and
+	// the new lease is granted only if it exceeds the previous lease's
+	// epoch.
+	PrevHolderEpoch  int64  `protobuf:"varint,4,opt,name=prev_holder_epoch" json:"prev_holder_epoch"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -357,6 +375,20 @@ func (m *InternalLeaderLeaseRequest) GetLease() Lease {
 	return Lease{}
 }
 
+func (m *InternalLeaderLeaseRequest) GetPrevLease() *Lease {
+	if m != nil {
+		return m.PrevLease
+	}
+	return nil
+}
+
+func (m *InternalLeaderLeaseRequest) GetPrevHolderEpoch() int64 {
+	if m != nil {
+		return m.PrevHolderEpoch
+	}
+	return 0
+}
+
 // An InternalLeaderLeaseResponse is the response to an InternalLeaderLease()
 // operation.
 type InternalLeaderLeaseResponse struct {
@@ -517,16 +549,18 @@ type InternalRaftCommandUnion struct {
 	EnqueueMessage *EnqueueMessageRequest `protobuf:"bytes,12,opt,name=enqueue_message" json:"enqueue_message,omitempty"`
 	// Other requests. Allow a gap in tag numbers so the previous list can
 	// be copy/pasted from RequestUnion.
-	Batch                 *BatchRequest                 `protobuf:"bytes,30,opt,name=batch" json:"batch,omitempty"`
-	InternalRangeLookup   *InternalRangeLookupRequest   `protobuf:"bytes,31,opt,name=internal_range_lookup" json:"internal_range_lookup,omitempty"`
-	InternalHeartbeatTxn  *InternalHeartbeatTxnRequest  `protobuf:"bytes,32,opt,name=internal_heartbeat_txn" json:"internal_heartbeat_txn,omitempty"`
-	InternalPushTxn       *InternalPushTxnRequest       `protobuf:"bytes,33,opt,name=internal_push_txn" json:"internal_push_txn,omitempty"`
-	InternalResolveIntent *InternalResolveIntentRequest `protobuf:"bytes,34,opt,name=internal_resolve_intent" json:"internal_resolve_intent,omitempty"`
-	InternalMergeResponse *InternalMergeRequest         `protobuf:"bytes,35,opt,name=internal_merge_response" json:"internal_merge_response,omitempty"`
-	InternalTruncateLog   *InternalTruncateLogRequest   `protobuf:"bytes,36,opt,name=internal_truncate_log" json:"internal_truncate_log,omitempty"`
-	InternalGC            *InternalGCRequest            `protobuf:"bytes,37,opt,name=internal_gc" json:"internal_gc,omitempty"`
-	InternalLease         *InternalLeaderLeaseRequest   `protobuf:"bytes,38,opt,name=internal_lease" json:"internal_lease,omitempty"`
-	XXX_unrecognized      []byte                        `json:"-"`
+	Batch                  *BatchRequest                  `protobuf:"bytes,30,opt,name=batch" json:"batch,omitempty"`
+	InternalRangeLookup    *InternalRangeLookupRequest    `protobuf:"bytes,31,opt,name=internal_range_lookup" json:"internal_range_lookup,omitempty"`
+	InternalHeartbeatTxn   *InternalHeartbeatTxnRequest   `protobuf:"bytes,32,opt,name=internal_heartbeat_txn" json:"internal_heartbeat_txn,omitempty"`
+	InternalPushTxn        *InternalPushTxnRequest        `protobuf:"bytes,33,opt,name=internal_push_txn" json:"internal_push_txn,omitempty"`
+	InternalResolveIntent  *InternalResolveIntentRequest  `protobuf:"bytes,34,opt,name=internal_resolve_intent" json:"internal_resolve_intent,omitempty"`
+	InternalMergeResponse  *InternalMergeRequest          `protobuf:"bytes,35,opt,name=internal_merge_response" json:"internal_merge_response,omitempty"`
+	InternalTruncateLog    *InternalTruncateLogRequest    `protobuf:"bytes,36,opt,name=internal_truncate_log" json:"internal_truncate_log,omitempty"`
+	InternalGC             *InternalGCRequest             `protobuf:"bytes,37,opt,name=internal_gc" json:"internal_gc,omitempty"`
+	InternalLease          *InternalLeaderLeaseRequest    `protobuf:"bytes,38,opt,name=internal_lease" json:"internal_lease,omitempty"`
+	InternalRecomputeStats *InternalRecomputeStatsRequest `protobuf:"bytes,39,opt,name=internal_recompute_stats" json:"internal_recompute_stats,omitempty"`
+	InternalImport         *InternalImportRequest         `protobuf:"bytes,40,opt,name=internal_import" json:"internal_import,omitempty"`
+	XXX_unrecognized       []byte                         `json:"-"`
 }
 
 func (m *InternalRaftCommandUnion) Reset()         { *m = InternalRaftCommandUnion{} }
@@ -680,6 +714,20 @@ func (m *InternalRaftCommandUnion) GetInternalLease() *InternalLeaderLeaseReques
 	return nil
 }
 
+func (m *InternalRaftCommandUnion) GetInternalRecomputeStats() *InternalRecomputeStatsRequest {
+	if m != nil {
+		return m.InternalRecomputeStats
+	}
+	return nil
+}
+
+func (m *InternalRaftCommandUnion) GetInternalImport() *InternalImportRequest {
+	if m != nil {
+		return m.InternalImport
+	}
+	return nil
+}
+
 // An InternalRaftCommand is a command which can be serialized and
 // sent via raft.
 type InternalRaftCommand struct {
@@ -717,7 +765,10 @@ type RaftMessageRequest struct {
 	// The raft payload, an encoded raftpb.Message. We transmit the message as
 	// an opaque blob to avoid the complexity of importing proto files across
 	// packages.
-	Msg              []byte `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
+	Msg []byte `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
+	// The ID of the sender's cluster. Messages from other clusters are
+	// rejected.
+	ClusterID        string `protobuf:"bytes,3,opt,name=cluster_id" json:"cluster_id"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -739,6 +790,13 @@ func (m *RaftMessageRequest) GetMsg() []byte {
 	return nil
 }
 
+func (m *RaftMessageRequest) GetClusterID() string {
+	if m != nil {
+		return m.ClusterID
+	}
+	return ""
+}
+
 // RaftMessageResponse is an empty message returned by raft RPCs.
 type RaftMessageResponse struct {
 	XXX_unrecognized []byte `json:"-"`
@@ -953,8 +1011,15 @@ func (m *RaftTruncatedState) GetTerm() uint64 {
 // RaftSnapshotData is the payload of a raftpb.Snapshot. It contains a raw copy of
 // all of the range's data and metadata, including the raft log, response cache, etc.
 type RaftSnapshotData struct {
-	KV               []*RaftSnapshotData_KeyValue `protobuf:"bytes,1,rep" json:"KV,omitempty"`
-	XXX_unrecognized []byte                       `json:"-"`
+	KV []*RaftSnapshotData_KeyValue `protobuf:"bytes,1,rep" json:"KV,omitempty"`
+	// The range descriptor as of the snapshot. A replica which is new
+	// to the range uses it to initialize itself.
+	RangeDescriptor RangeDescriptor `protobuf:"bytes,2,opt,name=range_descriptor" json:"range_descriptor"`
+	// The ID assigned to the receiving replica by a preemptive snapshot,
+	// which precedes the addition of the replica to the range descriptor.
+	// Zero for other snapshots.
+	ReplicaID        int32  `protobuf:"varint,3,opt,name=replica_id" json:"replica_id"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *RaftSnapshotData) Reset()         { *m = RaftSnapshotData{} }
@@ -968,6 +1033,20 @@ func (m *RaftSnapshotData) GetKV() []*RaftSnapshotData_KeyValue {
 	return nil
 }
 
+func (m *RaftSnapshotData) GetRangeDescriptor() RangeDescriptor {
+	if m != nil {
+		return m.RangeDescriptor
+	}
+	return RangeDescriptor{}
+}
+
+func (m *RaftSnapshotData) GetReplicaID() int32 {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
 type RaftSnapshotData_KeyValue struct {
 	Key              []byte `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Value            []byte `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
@@ -992,6 +1071,260 @@ func (m *RaftSnapshotData_KeyValue) GetValue() []byte {
 	return nil
 }
 
+// An InternalChangeReplicasRequest is arguments to the
+// InternalChangeReplicas() method. It adds or removes a replica of the
+// range containing header.key. When removing a replica, only the
+// node_id and store_id fields of the replica are used.
+type InternalChangeReplicasRequest struct {
+	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	ChangeType       ReplicaChangeType `protobuf:"varint,2,opt,name=change_type,enum=cockroach.proto.ReplicaChangeType" json:"change_type"`
+	Replica          Replica           `protobuf:"bytes,3,opt,name=replica" json:"replica"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *InternalChangeReplicasRequest) Reset()         { *m = InternalChangeReplicasRequest{} }
+func (m *InternalChangeReplicasRequest) String() string { return proto1.CompactTextString(m) }
+func (*InternalChangeReplicasRequest) ProtoMessage()    {}
+
+func (m *InternalChangeReplicasRequest) GetChangeType() ReplicaChangeType {
+	if m != nil {
+		return m.ChangeType
+	}
+	return ADD_REPLICA
+}
+
+func (m *InternalChangeReplicasRequest) GetReplica() Replica {
+	if m != nil {
+		return m.Replica
+	}
+	return Replica{}
+}
+
+// An InternalChangeReplicasResponse is the response to an
+// InternalChangeReplicas() operation.
+type InternalChangeReplicasResponse struct {
+	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalChangeReplicasResponse) Reset()         { *m = InternalChangeReplicasResponse{} }
+func (m *InternalChangeReplicasResponse) String() string { return proto1.CompactTextString(m) }
+func (*InternalChangeReplicasResponse) ProtoMessage()    {}
+
+// An InternalRecomputeStatsRequest is arguments to the
+// InternalRecomputeStats() method. It recomputes the MVCC stats of the
+// range containing header.key from a scan of its data and corrects any
+// drift in the persisted stats.
+type InternalRecomputeStatsRequest struct {
+	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalRecomputeStatsRequest) Reset()         { *m = InternalRecomputeStatsRequest{} }
+func (m *InternalRecomputeStatsRequest) String() string { return proto1.CompactTextString(m) }
+func (*InternalRecomputeStatsRequest) ProtoMessage()    {}
+
+// An InternalRecomputeStatsResponse is the response to an
+// InternalRecomputeStats() operation. It reports the correction
+// applied to each of the range's stats, i.e. the recomputed value
+// minus the persisted value.
+type InternalRecomputeStatsResponse struct {
+	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	LiveBytes        int64  `protobuf:"varint,2,opt,name=live_bytes" json:"live_bytes"`
+	KeyBytes         int64  `protobuf:"varint,3,opt,name=key_bytes" json:"key_bytes"`
+	ValBytes         int64  `protobuf:"varint,4,opt,name=val_bytes" json:"val_bytes"`
+	IntentBytes      int64  `protobuf:"varint,5,opt,name=intent_bytes" json:"intent_bytes"`
+	LiveCount        int64  `protobuf:"varint,6,opt,name=live_count" json:"live_count"`
+	KeyCount         int64  `protobuf:"varint,7,opt,name=key_count" json:"key_count"`
+	ValCount         int64  `protobuf:"varint,8,opt,name=val_count" json:"val_count"`
+	IntentCount      int64  `protobuf:"varint,9,opt,name=intent_count" json:"intent_count"`
+	IntentAge        int64  `protobuf:"varint,10,opt,name=intent_age" json:"intent_age"`
+	GCBytesAge       int64  `protobuf:"varint,11,opt,name=gc_bytes_age" json:"gc_bytes_age"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalRecomputeStatsResponse) Reset()         { *m = InternalRecomputeStatsResponse{} }
+func (m *InternalRecomputeStatsResponse) String() string { return proto1.CompactTextString(m) }
+func (*InternalRecomputeStatsResponse) ProtoMessage()    {}
+
+func (m *InternalRecomputeStatsResponse) GetLiveBytes() int64 {
+	if m != nil {
+		return m.LiveBytes
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetKeyBytes() int64 {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetValBytes() int64 {
+	if m != nil {
+		return m.ValBytes
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetIntentBytes() int64 {
+	if m != nil {
+		return m.IntentBytes
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetLiveCount() int64 {
+	if m != nil {
+		return m.LiveCount
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetKeyCount() int64 {
+	if m != nil {
+		return m.KeyCount
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetValCount() int64 {
+	if m != nil {
+		return m.ValCount
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetIntentCount() int64 {
+	if m != nil {
+		return m.IntentCount
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetIntentAge() int64 {
+	if m != nil {
+		return m.IntentAge
+	}
+	return 0
+}
+
+func (m *InternalRecomputeStatsResponse) GetGCBytesAge() int64 {
+	if m != nil {
+		return m.GCBytesAge
+	}
+	return 0
+}
+
+// An InternalExportRequest is arguments to the InternalExport()
+// method. It writes the key/value pairs in the span [header.key,
+// header.end_key) as of header.timestamp to the file with the given
+// name in the store's export sink.
+type InternalExportRequest struct {
+	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Name             string `protobuf:"bytes,2,opt,name=name" json:"name"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalExportRequest) Reset()         { *m = InternalExportRequest{} }
+func (m *InternalExportRequest) String() string { return proto1.CompactTextString(m) }
+func (*InternalExportRequest) ProtoMessage()    {}
+
+func (m *InternalExportRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// An InternalExportResponse is the response to an InternalExport()
+// operation. It reports the number of key/value pairs exported, the
+// size in bytes of the file written and its SHA-256 checksum.
+type InternalExportResponse struct {
+	ResponseHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	KeyCount       int64 `protobuf:"varint,2,opt,name=key_count" json:"key_count"`
+	DataSize       int64 `protobuf:"varint,3,opt,name=data_size" json:"data_size"`
+	// Tag 4 held a CRC-32 checksum in earlier versions and must not be
+	// reused.
+	Sha256           []byte `protobuf:"bytes,5,opt,name=sha256" json:"sha256,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalExportResponse) Reset()         { *m = InternalExportResponse{} }
+func (m *InternalExportResponse) String() string { return proto1.CompactTextString(m) }
+func (*InternalExportResponse) ProtoMessage()    {}
+
+func (m *InternalExportResponse) GetKeyCount() int64 {
+	if m != nil {
+		return m.KeyCount
+	}
+	return 0
+}
+
+func (m *InternalExportResponse) GetDataSize() int64 {
+	if m != nil {
+		return m.DataSize
+	}
+	return 0
+}
+
+func (m *InternalExportResponse) GetSha256() []byte {
+	if m != nil {
+		return m.Sha256
+	}
+	return nil
+}
+
+// An InternalImportRequest is arguments to the InternalImport()
+// method. It writes the given key/value pairs, which must lie within
+// the span [header.key, header.end_key), at the timestamps of their
+// values. If clear is set, all versions of all keys in the span are
+// first deleted.
+type InternalImportRequest struct {
+	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Rows             []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
+	Clear            bool       `protobuf:"varint,3,opt,name=clear" json:"clear"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *InternalImportRequest) Reset()         { *m = InternalImportRequest{} }
+func (m *InternalImportRequest) String() string { return proto1.CompactTextString(m) }
+func (*InternalImportRequest) ProtoMessage()    {}
+
+func (m *InternalImportRequest) GetRows() []KeyValue {
+	if m != nil {
+		return m.Rows
+	}
+	return nil
+}
+
+func (m *InternalImportRequest) GetClear() bool {
+	if m != nil {
+		return m.Clear
+	}
+	return false
+}
+
+// An InternalImportResponse is the response to an InternalImport()
+// operation. It reports the number of key/value pairs written.
+type InternalImportResponse struct {
+	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	KeyCount         int64  `protobuf:"varint,2,opt,name=key_count" json:"key_count"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalImportResponse) Reset()         { *m = InternalImportResponse{} }
+func (m *InternalImportResponse) String() string { return proto1.CompactTextString(m) }
+func (*InternalImportResponse) ProtoMessage()    {}
+
+func (m *InternalImportResponse) GetKeyCount() int64 {
+	if m != nil {
+		return m.KeyCount
+	}
+	return 0
+}
+
 func init() {
 	proto1.RegisterEnum("cockroach.proto.InternalValueType", InternalValueType_name, InternalValueType_value)
 }
@@ -1809,6 +2142,29 @@ func (m *InternalResolveIntentRequest) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Keys", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Keys = append(m.Keys, Key{})
+			m.Keys[len(m.Keys)-1].Unmarshal(data[index:postIndex])
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -2268,6 +2624,48 @@ func (m *InternalLeaderLeaseRequest) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevLease", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PrevLease == nil {
+				m.PrevLease = &Lease{}
+			}
+			if err := m.PrevLease.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevHolderEpoch", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.PrevHolderEpoch |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -3390,25 +3788,79 @@ func (m *InternalRaftCommandUnion) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
-		default:
-			var sizeOfWire int
-			for {
-				sizeOfWire++
-				wire >>= 7
-				if wire == 0 {
+		case 39:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InternalRecomputeStats", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
 					break
 				}
 			}
-			index -= sizeOfWire
-			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
-			if err != nil {
-				return err
-			}
-			if (index + skippy) > l {
+			postIndex := index + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
-			index += skippy
+			if m.InternalRecomputeStats == nil {
+				m.InternalRecomputeStats = &InternalRecomputeStatsRequest{}
+			}
+			if err := m.InternalRecomputeStats.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 40:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InternalImport", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.InternalImport == nil {
+				m.InternalImport = &InternalImportRequest{}
+			}
+			if err := m.InternalImport.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
 		}
 	}
 	return nil
@@ -3550,6 +4002,28 @@ func (m *RaftMessageRequest) Unmarshal(data []byte) error {
 			}
 			m.Msg = append([]byte{}, data[index:postIndex]...)
 			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClusterID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ClusterID = string(data[index:postIndex])
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -4009,6 +4483,45 @@ func (m *RaftSnapshotData) Unmarshal(data []byte) error {
 			m.KV = append(m.KV, &RaftSnapshotData_KeyValue{})
 			m.KV[len(m.KV)-1].Unmarshal(data[index:postIndex])
 			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeDescriptor", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RangeDescriptor.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ReplicaID |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -4268,6 +4781,12 @@ func (this *InternalRaftCommandUnion) GetValue() interface{} {
 	if this.InternalLease != nil {
 		return this.InternalLease
 	}
+	if this.InternalRecomputeStats != nil {
+		return this.InternalRecomputeStats
+	}
+	if this.InternalImport != nil {
+		return this.InternalImport
+	}
 	return nil
 }
 
@@ -4315,76 +4834,926 @@ func (this *InternalRaftCommandUnion) SetValue(value interface{}) bool {
 		this.InternalGC = vt
 	case *InternalLeaderLeaseRequest:
 		this.InternalLease = vt
+	case *InternalRecomputeStatsRequest:
+		this.InternalRecomputeStats = vt
+	case *InternalImportRequest:
+		this.InternalImport = vt
 	default:
 		return false
 	}
 	return true
 }
-func (m *InternalRangeLookupRequest) Size() (n int) {
-	var l int
-	_ = l
-	l = m.RequestHeader.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	n += 1 + sovInternal(uint64(m.MaxRanges))
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *InternalRangeLookupResponse) Size() (n int) {
-	var l int
-	_ = l
-	l = m.ResponseHeader.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	if len(m.Ranges) > 0 {
-		for _, e := range m.Ranges {
-			l = e.Size()
-			n += 1 + l + sovInternal(uint64(l))
+func (m *InternalChangeReplicasRequest) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
 		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *InternalHeartbeatTxnRequest) Size() (n int) {
-	var l int
-	_ = l
-	l = m.RequestHeader.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *InternalHeartbeatTxnResponse) Size() (n int) {
-	var l int
-	_ = l
-	l = m.ResponseHeader.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *InternalGCRequest) Size() (n int) {
-	var l int
-	_ = l
-	l = m.RequestHeader.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	l = m.GCMeta.Size()
-	n += 1 + l + sovInternal(uint64(l))
-	if len(m.Keys) > 0 {
-		for _, e := range m.Keys {
-			l = e.Size()
-			n += 1 + l + sovInternal(uint64(l))
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChangeType", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ChangeType |= (ReplicaChangeType(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replica", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Replica.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
 		}
 	}
-	if m.XXX_unrecognized != nil {
+	return nil
+}
+func (m *InternalChangeReplicasResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalRecomputeStatsRequest) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalRecomputeStatsResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LiveBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.LiveBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ValBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LiveCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.LiveCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ValCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentAge", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentAge |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GCBytesAge", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.GCBytesAge |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalExportRequest) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalExportResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DataSize", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.DataSize |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sha256", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Sha256 = append([]byte{}, data[index:postIndex]...)
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalImportRequest) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Rows", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Rows = append(m.Rows, KeyValue{})
+			m.Rows[len(m.Rows)-1].Unmarshal(data[index:postIndex])
+			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Clear", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Clear = bool(v != 0)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalImportResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalRangeLookupRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.MaxRanges))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalRangeLookupResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Ranges) > 0 {
+		for _, e := range m.Ranges {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalHeartbeatTxnRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalHeartbeatTxnResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalGCRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	l = m.GCMeta.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Keys) > 0 {
+		for _, e := range m.Keys {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
@@ -4448,6 +5817,12 @@ func (m *InternalResolveIntentRequest) Size() (n int) {
 	_ = l
 	l = m.RequestHeader.Size()
 	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Keys) > 0 {
+		for _, e := range m.Keys {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4519,6 +5894,11 @@ func (m *InternalLeaderLeaseRequest) Size() (n int) {
 	n += 1 + l + sovInternal(uint64(l))
 	l = m.Lease.Size()
 	n += 1 + l + sovInternal(uint64(l))
+	if m.PrevLease != nil {
+		l = m.PrevLease.Size()
+		n += 1 + l + sovInternal(uint64(l))
+	}
+	n += 1 + sovInternal(uint64(m.PrevHolderEpoch))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4692,6 +6072,14 @@ func (m *InternalRaftCommandUnion) Size() (n int) {
 		l = m.InternalLease.Size()
 		n += 2 + l + sovInternal(uint64(l))
 	}
+	if m.InternalRecomputeStats != nil {
+		l = m.InternalRecomputeStats.Size()
+		n += 2 + l + sovInternal(uint64(l))
+	}
+	if m.InternalImport != nil {
+		l = m.InternalImport.Size()
+		n += 2 + l + sovInternal(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4718,6 +6106,8 @@ func (m *RaftMessageRequest) Size() (n int) {
 		l = len(m.Msg)
 		n += 1 + l + sovInternal(uint64(l))
 	}
+	l = len(m.ClusterID)
+	n += 1 + l + sovInternal(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4780,43 +6170,163 @@ func (m *InternalTimeSeriesSample) Size() (n int) {
 	return n
 }
 
-func (m *RaftTruncatedState) Size() (n int) {
+func (m *RaftTruncatedState) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovInternal(uint64(m.Index))
+	n += 1 + sovInternal(uint64(m.Term))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RaftSnapshotData) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.KV) > 0 {
+		for _, e := range m.KV {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
+	l = m.RangeDescriptor.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.ReplicaID))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RaftSnapshotData_KeyValue) Size() (n int) {
+	var l int
+	_ = l
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovInternal(uint64(l))
+	}
+	if m.Value != nil {
+		l = len(m.Value)
+		n += 1 + l + sovInternal(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalChangeReplicasRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.ChangeType))
+	l = m.Replica.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalChangeReplicasResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalRecomputeStatsRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalRecomputeStatsResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.LiveBytes))
+	n += 1 + sovInternal(uint64(m.KeyBytes))
+	n += 1 + sovInternal(uint64(m.ValBytes))
+	n += 1 + sovInternal(uint64(m.IntentBytes))
+	n += 1 + sovInternal(uint64(m.LiveCount))
+	n += 1 + sovInternal(uint64(m.KeyCount))
+	n += 1 + sovInternal(uint64(m.ValCount))
+	n += 1 + sovInternal(uint64(m.IntentCount))
+	n += 1 + sovInternal(uint64(m.IntentAge))
+	n += 1 + sovInternal(uint64(m.GCBytesAge))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalExportRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	l = len(m.Name)
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalExportResponse) Size() (n int) {
 	var l int
 	_ = l
-	n += 1 + sovInternal(uint64(m.Index))
-	n += 1 + sovInternal(uint64(m.Term))
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.KeyCount))
+	n += 1 + sovInternal(uint64(m.DataSize))
+	if m.Sha256 != nil {
+		l = len(m.Sha256)
+		n += 1 + l + sovInternal(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *RaftSnapshotData) Size() (n int) {
+func (m *InternalImportRequest) Size() (n int) {
 	var l int
 	_ = l
-	if len(m.KV) > 0 {
-		for _, e := range m.KV {
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Rows) > 0 {
+		for _, e := range m.Rows {
 			l = e.Size()
 			n += 1 + l + sovInternal(uint64(l))
 		}
 	}
+	n += 2
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *RaftSnapshotData_KeyValue) Size() (n int) {
+func (m *InternalImportResponse) Size() (n int) {
 	var l int
 	_ = l
-	if m.Key != nil {
-		l = len(m.Key)
-		n += 1 + l + sovInternal(uint64(l))
-	}
-	if m.Value != nil {
-		l = len(m.Value)
-		n += 1 + l + sovInternal(uint64(l))
-	}
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.KeyCount))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -5038,22 +6548,242 @@ func (m *InternalGCRequest_GCKey) MarshalTo(data []byte) (n int, err error) {
 	if err != nil {
 		return 0, err
 	}
-	i += n7
+	i += n7
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Timestamp.Size()))
+	n8, err := m.Timestamp.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n8
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalGCResponse) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalGCResponse) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n9, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n9
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalPushTxnRequest) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalPushTxnRequest) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n10, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n10
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.PusheeTxn.Size()))
+	n11, err := m.PusheeTxn.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n11
+	data[i] = 0x18
+	i++
+	if m.Abort {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalPushTxnResponse) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalPushTxnResponse) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n12, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n12
+	if m.PusheeTxn != nil {
+		data[i] = 0x12
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.PusheeTxn.Size()))
+		n13, err := m.PusheeTxn.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n13
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalResolveIntentRequest) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalResolveIntentRequest) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n14, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n14
+	if len(m.Keys) > 0 {
+		for _, msg := range m.Keys {
+			data[i] = 0x12
+			i++
+			i = encodeVarintInternal(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalResolveIntentResponse) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalResolveIntentResponse) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n15, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n15
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalMergeRequest) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalMergeRequest) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n16, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n16
 	data[i] = 0x12
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.Timestamp.Size()))
-	n8, err := m.Timestamp.MarshalTo(data[i:])
+	i = encodeVarintInternal(data, i, uint64(m.Value.Size()))
+	n17, err := m.Value.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n8
+	i += n17
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalGCResponse) Marshal() (data []byte, err error) {
+func (m *InternalMergeResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5063,7 +6793,7 @@ func (m *InternalGCResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalGCResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalMergeResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -5071,18 +6801,18 @@ func (m *InternalGCResponse) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0xa
 	i++
 	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n9, err := m.ResponseHeader.MarshalTo(data[i:])
+	n18, err := m.ResponseHeader.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n9
+	i += n18
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalPushTxnRequest) Marshal() (data []byte, err error) {
+func (m *InternalTruncateLogRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5092,7 +6822,7 @@ func (m *InternalPushTxnRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalPushTxnRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalTruncateLogRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -5100,34 +6830,21 @@ func (m *InternalPushTxnRequest) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0xa
 	i++
 	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
-	n10, err := m.RequestHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
-	}
-	i += n10
-	data[i] = 0x12
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.PusheeTxn.Size()))
-	n11, err := m.PusheeTxn.MarshalTo(data[i:])
+	n19, err := m.RequestHeader.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n11
-	data[i] = 0x18
-	i++
-	if m.Abort {
-		data[i] = 1
-	} else {
-		data[i] = 0
-	}
+	i += n19
+	data[i] = 0x10
 	i++
+	i = encodeVarintInternal(data, i, uint64(m.Index))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalPushTxnResponse) Marshal() (data []byte, err error) {
+func (m *InternalTruncateLogResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5137,7 +6854,7 @@ func (m *InternalPushTxnResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalPushTxnResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalTruncateLogResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -5145,28 +6862,18 @@ func (m *InternalPushTxnResponse) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0xa
 	i++
 	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n12, err := m.ResponseHeader.MarshalTo(data[i:])
+	n20, err := m.ResponseHeader.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n12
-	if m.PusheeTxn != nil {
-		data[i] = 0x12
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.PusheeTxn.Size()))
-		n13, err := m.PusheeTxn.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n13
-	}
+	i += n20
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalResolveIntentRequest) Marshal() (data []byte, err error) {
+func (m *InternalLeaderLeaseRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5176,7 +6883,7 @@ func (m *InternalResolveIntentRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalResolveIntentRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalLeaderLeaseRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -5184,18 +6891,39 @@ func (m *InternalResolveIntentRequest) MarshalTo(data []byte) (n int, err error)
 	data[i] = 0xa
 	i++
 	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
-	n14, err := m.RequestHeader.MarshalTo(data[i:])
+	n21, err := m.RequestHeader.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n14
+	i += n21
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Lease.Size()))
+	n22, err := m.Lease.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n22
+	if m.PrevLease != nil {
+		data[i] = 0x1a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.PrevLease.Size()))
+		n61, err := m.PrevLease.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n61
+	}
+	data[i] = 0x20
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.PrevHolderEpoch))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalResolveIntentResponse) Marshal() (data []byte, err error) {
+func (m *InternalLeaderLeaseResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5205,7 +6933,7 @@ func (m *InternalResolveIntentResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalResolveIntentResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalLeaderLeaseResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -5213,18 +6941,18 @@ func (m *InternalResolveIntentResponse) MarshalTo(data []byte) (n int, err error
 	data[i] = 0xa
 	i++
 	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n15, err := m.ResponseHeader.MarshalTo(data[i:])
+	n23, err := m.ResponseHeader.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n15
+	i += n23
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalMergeRequest) Marshal() (data []byte, err error) {
+func (m *ReadWriteCmdResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5234,34 +6962,168 @@ func (m *InternalMergeRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalMergeRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *ReadWriteCmdResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
-	n16, err := m.RequestHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
+	if m.Put != nil {
+		data[i] = 0xa
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Put.Size()))
+		n24, err := m.Put.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n24
 	}
-	i += n16
-	data[i] = 0x12
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.Value.Size()))
-	n17, err := m.Value.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
+	if m.ConditionalPut != nil {
+		data[i] = 0x12
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.ConditionalPut.Size()))
+		n25, err := m.ConditionalPut.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n25
+	}
+	if m.Increment != nil {
+		data[i] = 0x1a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Increment.Size()))
+		n26, err := m.Increment.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n26
+	}
+	if m.Delete != nil {
+		data[i] = 0x22
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Delete.Size()))
+		n27, err := m.Delete.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n27
+	}
+	if m.DeleteRange != nil {
+		data[i] = 0x2a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.DeleteRange.Size()))
+		n28, err := m.DeleteRange.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n28
+	}
+	if m.EndTransaction != nil {
+		data[i] = 0x32
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EndTransaction.Size()))
+		n29, err := m.EndTransaction.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n29
+	}
+	if m.ReapQueue != nil {
+		data[i] = 0x3a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.ReapQueue.Size()))
+		n30, err := m.ReapQueue.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n30
+	}
+	if m.EnqueueUpdate != nil {
+		data[i] = 0x42
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EnqueueUpdate.Size()))
+		n31, err := m.EnqueueUpdate.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n31
+	}
+	if m.EnqueueMessage != nil {
+		data[i] = 0x4a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EnqueueMessage.Size()))
+		n32, err := m.EnqueueMessage.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n32
+	}
+	if m.InternalHeartbeatTxn != nil {
+		data[i] = 0x52
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalHeartbeatTxn.Size()))
+		n33, err := m.InternalHeartbeatTxn.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n33
+	}
+	if m.InternalPushTxn != nil {
+		data[i] = 0x5a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalPushTxn.Size()))
+		n34, err := m.InternalPushTxn.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n34
+	}
+	if m.InternalResolveIntent != nil {
+		data[i] = 0x62
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalResolveIntent.Size()))
+		n35, err := m.InternalResolveIntent.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n35
+	}
+	if m.InternalMerge != nil {
+		data[i] = 0x6a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalMerge.Size()))
+		n36, err := m.InternalMerge.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n36
+	}
+	if m.InternalTruncateLog != nil {
+		data[i] = 0x72
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalTruncateLog.Size()))
+		n37, err := m.InternalTruncateLog.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n37
+	}
+	if m.InternalGc != nil {
+		data[i] = 0x7a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalGc.Size()))
+		n38, err := m.InternalGc.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n38
 	}
-	i += n17
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalMergeResponse) Marshal() (data []byte, err error) {
+func (m *InternalRaftCommandUnion) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5271,26 +7133,270 @@ func (m *InternalMergeResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalMergeResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalRaftCommandUnion) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n18, err := m.ResponseHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
+	if m.Contains != nil {
+		data[i] = 0xa
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Contains.Size()))
+		n39, err := m.Contains.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n39
+	}
+	if m.Get != nil {
+		data[i] = 0x12
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Get.Size()))
+		n40, err := m.Get.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n40
+	}
+	if m.Put != nil {
+		data[i] = 0x1a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Put.Size()))
+		n41, err := m.Put.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n41
+	}
+	if m.ConditionalPut != nil {
+		data[i] = 0x22
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.ConditionalPut.Size()))
+		n42, err := m.ConditionalPut.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n42
+	}
+	if m.Increment != nil {
+		data[i] = 0x2a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Increment.Size()))
+		n43, err := m.Increment.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n43
+	}
+	if m.Delete != nil {
+		data[i] = 0x32
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Delete.Size()))
+		n44, err := m.Delete.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n44
+	}
+	if m.DeleteRange != nil {
+		data[i] = 0x3a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.DeleteRange.Size()))
+		n45, err := m.DeleteRange.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n45
+	}
+	if m.Scan != nil {
+		data[i] = 0x42
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Scan.Size()))
+		n46, err := m.Scan.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n46
+	}
+	if m.EndTransaction != nil {
+		data[i] = 0x4a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EndTransaction.Size()))
+		n47, err := m.EndTransaction.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n47
+	}
+	if m.ReapQueue != nil {
+		data[i] = 0x52
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.ReapQueue.Size()))
+		n48, err := m.ReapQueue.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n48
+	}
+	if m.EnqueueUpdate != nil {
+		data[i] = 0x5a
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EnqueueUpdate.Size()))
+		n49, err := m.EnqueueUpdate.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n49
+	}
+	if m.EnqueueMessage != nil {
+		data[i] = 0x62
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.EnqueueMessage.Size()))
+		n50, err := m.EnqueueMessage.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n50
+	}
+	if m.Batch != nil {
+		data[i] = 0xf2
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.Batch.Size()))
+		n51, err := m.Batch.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n51
+	}
+	if m.InternalRangeLookup != nil {
+		data[i] = 0xfa
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalRangeLookup.Size()))
+		n52, err := m.InternalRangeLookup.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n52
+	}
+	if m.InternalHeartbeatTxn != nil {
+		data[i] = 0x82
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalHeartbeatTxn.Size()))
+		n53, err := m.InternalHeartbeatTxn.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n53
+	}
+	if m.InternalPushTxn != nil {
+		data[i] = 0x8a
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalPushTxn.Size()))
+		n54, err := m.InternalPushTxn.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n54
+	}
+	if m.InternalResolveIntent != nil {
+		data[i] = 0x92
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalResolveIntent.Size()))
+		n55, err := m.InternalResolveIntent.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n55
+	}
+	if m.InternalMergeResponse != nil {
+		data[i] = 0x9a
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalMergeResponse.Size()))
+		n56, err := m.InternalMergeResponse.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n56
+	}
+	if m.InternalTruncateLog != nil {
+		data[i] = 0xa2
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalTruncateLog.Size()))
+		n57, err := m.InternalTruncateLog.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n57
+	}
+	if m.InternalGC != nil {
+		data[i] = 0xaa
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalGC.Size()))
+		n58, err := m.InternalGC.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n58
+	}
+	if m.InternalLease != nil {
+		data[i] = 0xb2
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalLease.Size()))
+		n59, err := m.InternalLease.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n59
+	}
+	if m.InternalRecomputeStats != nil {
+		data[i] = 0xba
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalRecomputeStats.Size()))
+		n60, err := m.InternalRecomputeStats.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n60
+	}
+	if m.InternalImport != nil {
+		data[i] = 0xc2
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalImport.Size()))
+		n64, err := m.InternalImport.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n64
 	}
-	i += n18
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalTruncateLogRequest) Marshal() (data []byte, err error) {
+func (m *InternalRaftCommand) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5300,29 +7406,29 @@ func (m *InternalTruncateLogRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalTruncateLogRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalRaftCommand) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
+	data[i] = 0x10
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
-	n19, err := m.RequestHeader.MarshalTo(data[i:])
+	i = encodeVarintInternal(data, i, uint64(m.RaftID))
+	data[i] = 0x1a
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Cmd.Size()))
+	n60, err := m.Cmd.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n19
-	data[i] = 0x10
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.Index))
+	i += n60
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalTruncateLogResponse) Marshal() (data []byte, err error) {
+func (m *RaftMessageRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5332,26 +7438,31 @@ func (m *InternalTruncateLogResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalTruncateLogResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *RaftMessageRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
+	data[i] = 0x8
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n20, err := m.ResponseHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
+	i = encodeVarintInternal(data, i, uint64(m.GroupID))
+	if m.Msg != nil {
+		data[i] = 0x12
+		i++
+		i = encodeVarintInternal(data, i, uint64(len(m.Msg)))
+		i += copy(data[i:], m.Msg)
 	}
-	i += n20
+	data[i] = 0x1a
+	i++
+	i = encodeVarintInternal(data, i, uint64(len(m.ClusterID)))
+	i += copy(data[i:], m.ClusterID)
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalLeaderLeaseRequest) Marshal() (data []byte, err error) {
+func (m *RaftMessageResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5361,34 +7472,18 @@ func (m *InternalLeaderLeaseRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalLeaderLeaseRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *RaftMessageResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
-	n21, err := m.RequestHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
-	}
-	i += n21
-	data[i] = 0x12
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.Lease.Size()))
-	n22, err := m.Lease.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
-	}
-	i += n22
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalLeaderLeaseResponse) Marshal() (data []byte, err error) {
+func (m *InternalTimeSeriesData) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5398,26 +7493,36 @@ func (m *InternalLeaderLeaseResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalLeaderLeaseResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalTimeSeriesData) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0xa
+	data[i] = 0x8
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
-	n23, err := m.ResponseHeader.MarshalTo(data[i:])
-	if err != nil {
-		return 0, err
+	i = encodeVarintInternal(data, i, uint64(m.StartTimestampNanos))
+	data[i] = 0x10
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.SampleDurationNanos))
+	if len(m.Samples) > 0 {
+		for _, msg := range m.Samples {
+			data[i] = 0x1a
+			i++
+			i = encodeVarintInternal(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
 	}
-	i += n23
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *ReadWriteCmdResponse) Marshal() (data []byte, err error) {
+func (m *InternalTimeSeriesSample) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5427,168 +7532,84 @@ func (m *ReadWriteCmdResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *ReadWriteCmdResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalTimeSeriesSample) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.Put != nil {
-		data[i] = 0xa
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Put.Size()))
-		n24, err := m.Put.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n24
-	}
-	if m.ConditionalPut != nil {
-		data[i] = 0x12
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.ConditionalPut.Size()))
-		n25, err := m.ConditionalPut.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n25
-	}
-	if m.Increment != nil {
-		data[i] = 0x1a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Increment.Size()))
-		n26, err := m.Increment.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n26
-	}
-	if m.Delete != nil {
-		data[i] = 0x22
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Delete.Size()))
-		n27, err := m.Delete.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n27
-	}
-	if m.DeleteRange != nil {
-		data[i] = 0x2a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.DeleteRange.Size()))
-		n28, err := m.DeleteRange.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n28
-	}
-	if m.EndTransaction != nil {
-		data[i] = 0x32
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.EndTransaction.Size()))
-		n29, err := m.EndTransaction.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n29
-	}
-	if m.ReapQueue != nil {
-		data[i] = 0x3a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.ReapQueue.Size()))
-		n30, err := m.ReapQueue.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n30
-	}
-	if m.EnqueueUpdate != nil {
-		data[i] = 0x42
+	data[i] = 0x8
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Offset))
+	data[i] = 0x10
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.IntCount))
+	if m.IntSum != nil {
+		data[i] = 0x18
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.EnqueueUpdate.Size()))
-		n31, err := m.EnqueueUpdate.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n31
+		i = encodeVarintInternal(data, i, uint64(*m.IntSum))
 	}
-	if m.EnqueueMessage != nil {
-		data[i] = 0x4a
+	if m.IntMax != nil {
+		data[i] = 0x20
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.EnqueueMessage.Size()))
-		n32, err := m.EnqueueMessage.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n32
+		i = encodeVarintInternal(data, i, uint64(*m.IntMax))
 	}
-	if m.InternalHeartbeatTxn != nil {
-		data[i] = 0x52
+	if m.IntMin != nil {
+		data[i] = 0x28
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalHeartbeatTxn.Size()))
-		n33, err := m.InternalHeartbeatTxn.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n33
+		i = encodeVarintInternal(data, i, uint64(*m.IntMin))
 	}
-	if m.InternalPushTxn != nil {
-		data[i] = 0x5a
+	data[i] = 0x30
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.FloatCount))
+	if m.FloatSum != nil {
+		data[i] = 0x3d
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalPushTxn.Size()))
-		n34, err := m.InternalPushTxn.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n34
+		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatSum)))
 	}
-	if m.InternalResolveIntent != nil {
-		data[i] = 0x62
+	if m.FloatMax != nil {
+		data[i] = 0x45
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalResolveIntent.Size()))
-		n35, err := m.InternalResolveIntent.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n35
+		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatMax)))
 	}
-	if m.InternalMerge != nil {
-		data[i] = 0x6a
+	if m.FloatMin != nil {
+		data[i] = 0x4d
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalMerge.Size()))
-		n36, err := m.InternalMerge.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n36
+		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatMin)))
 	}
-	if m.InternalTruncateLog != nil {
-		data[i] = 0x72
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalTruncateLog.Size()))
-		n37, err := m.InternalTruncateLog.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n37
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
 	}
-	if m.InternalGc != nil {
-		data[i] = 0x7a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalGc.Size()))
-		n38, err := m.InternalGc.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n38
+	return i, nil
+}
+
+func (m *RaftTruncatedState) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
 	}
+	return data[:n], nil
+}
+
+func (m *RaftTruncatedState) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Index))
+	data[i] = 0x10
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Term))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalRaftCommandUnion) Marshal() (data []byte, err error) {
+func (m *RaftSnapshotData) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5598,238 +7619,66 @@ func (m *InternalRaftCommandUnion) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalRaftCommandUnion) MarshalTo(data []byte) (n int, err error) {
+func (m *RaftSnapshotData) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.Contains != nil {
-		data[i] = 0xa
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Contains.Size()))
-		n39, err := m.Contains.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n39
-	}
-	if m.Get != nil {
-		data[i] = 0x12
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Get.Size()))
-		n40, err := m.Get.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n40
-	}
-	if m.Put != nil {
-		data[i] = 0x1a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Put.Size()))
-		n41, err := m.Put.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n41
-	}
-	if m.ConditionalPut != nil {
-		data[i] = 0x22
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.ConditionalPut.Size()))
-		n42, err := m.ConditionalPut.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n42
-	}
-	if m.Increment != nil {
-		data[i] = 0x2a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Increment.Size()))
-		n43, err := m.Increment.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n43
-	}
-	if m.Delete != nil {
-		data[i] = 0x32
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Delete.Size()))
-		n44, err := m.Delete.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n44
-	}
-	if m.DeleteRange != nil {
-		data[i] = 0x3a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.DeleteRange.Size()))
-		n45, err := m.DeleteRange.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n45
-	}
-	if m.Scan != nil {
-		data[i] = 0x42
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Scan.Size()))
-		n46, err := m.Scan.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n46
-	}
-	if m.EndTransaction != nil {
-		data[i] = 0x4a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.EndTransaction.Size()))
-		n47, err := m.EndTransaction.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n47
-	}
-	if m.ReapQueue != nil {
-		data[i] = 0x52
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.ReapQueue.Size()))
-		n48, err := m.ReapQueue.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n48
-	}
-	if m.EnqueueUpdate != nil {
-		data[i] = 0x5a
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.EnqueueUpdate.Size()))
-		n49, err := m.EnqueueUpdate.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n49
-	}
-	if m.EnqueueMessage != nil {
-		data[i] = 0x62
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.EnqueueMessage.Size()))
-		n50, err := m.EnqueueMessage.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n50
-	}
-	if m.Batch != nil {
-		data[i] = 0xf2
-		i++
-		data[i] = 0x1
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.Batch.Size()))
-		n51, err := m.Batch.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n51
-	}
-	if m.InternalRangeLookup != nil {
-		data[i] = 0xfa
-		i++
-		data[i] = 0x1
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalRangeLookup.Size()))
-		n52, err := m.InternalRangeLookup.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n52
-	}
-	if m.InternalHeartbeatTxn != nil {
-		data[i] = 0x82
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalHeartbeatTxn.Size()))
-		n53, err := m.InternalHeartbeatTxn.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n53
-	}
-	if m.InternalPushTxn != nil {
-		data[i] = 0x8a
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalPushTxn.Size()))
-		n54, err := m.InternalPushTxn.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n54
-	}
-	if m.InternalResolveIntent != nil {
-		data[i] = 0x92
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalResolveIntent.Size()))
-		n55, err := m.InternalResolveIntent.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
+	if len(m.KV) > 0 {
+		for _, msg := range m.KV {
+			data[i] = 0xa
+			i++
+			i = encodeVarintInternal(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
-		i += n55
 	}
-	if m.InternalMergeResponse != nil {
-		data[i] = 0x9a
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalMergeResponse.Size()))
-		n56, err := m.InternalMergeResponse.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n56
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RangeDescriptor.Size()))
+	n1, err := m.RangeDescriptor.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
 	}
-	if m.InternalTruncateLog != nil {
-		data[i] = 0xa2
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalTruncateLog.Size()))
-		n57, err := m.InternalTruncateLog.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n57
+	i += n1
+	data[i] = 0x18
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ReplicaID))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
 	}
-	if m.InternalGC != nil {
-		data[i] = 0xaa
-		i++
-		data[i] = 0x2
-		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalGC.Size()))
-		n58, err := m.InternalGC.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n58
+	return i, nil
+}
+
+func (m *RaftSnapshotData_KeyValue) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
 	}
-	if m.InternalLease != nil {
-		data[i] = 0xb2
+	return data[:n], nil
+}
+
+func (m *RaftSnapshotData_KeyValue) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Key != nil {
+		data[i] = 0xa
 		i++
-		data[i] = 0x2
+		i = encodeVarintInternal(data, i, uint64(len(m.Key)))
+		i += copy(data[i:], m.Key)
+	}
+	if m.Value != nil {
+		data[i] = 0x12
 		i++
-		i = encodeVarintInternal(data, i, uint64(m.InternalLease.Size()))
-		n59, err := m.InternalLease.MarshalTo(data[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n59
+		i = encodeVarintInternal(data, i, uint64(len(m.Value)))
+		i += copy(data[i:], m.Value)
 	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
@@ -5837,7 +7686,7 @@ func (m *InternalRaftCommandUnion) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
-func (m *InternalRaftCommand) Marshal() (data []byte, err error) {
+func (m *InternalChangeReplicasRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5847,29 +7696,37 @@ func (m *InternalRaftCommand) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalRaftCommand) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalChangeReplicasRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n2, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n2
 	data[i] = 0x10
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.RaftID))
+	i = encodeVarintInternal(data, i, uint64(m.ChangeType))
 	data[i] = 0x1a
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.Cmd.Size()))
-	n60, err := m.Cmd.MarshalTo(data[i:])
+	i = encodeVarintInternal(data, i, uint64(m.Replica.Size()))
+	n3, err := m.Replica.MarshalTo(data[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n60
+	i += n3
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *RaftMessageRequest) Marshal() (data []byte, err error) {
+func (m *InternalChangeReplicasResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5879,27 +7736,54 @@ func (m *RaftMessageRequest) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *RaftMessageRequest) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalChangeReplicasResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0x8
+	data[i] = 0xa
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.GroupID))
-	if m.Msg != nil {
-		data[i] = 0x12
-		i++
-		i = encodeVarintInternal(data, i, uint64(len(m.Msg)))
-		i += copy(data[i:], m.Msg)
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n2, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
 	}
+	i += n2
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *RaftMessageResponse) Marshal() (data []byte, err error) {
+func encodeFixed64Internal(data []byte, offset int, v uint64) int {
+	data[offset] = uint8(v)
+	data[offset+1] = uint8(v >> 8)
+	data[offset+2] = uint8(v >> 16)
+	data[offset+3] = uint8(v >> 24)
+	data[offset+4] = uint8(v >> 32)
+	data[offset+5] = uint8(v >> 40)
+	data[offset+6] = uint8(v >> 48)
+	data[offset+7] = uint8(v >> 56)
+	return offset + 8
+}
+func encodeFixed32Internal(data []byte, offset int, v uint32) int {
+	data[offset] = uint8(v)
+	data[offset+1] = uint8(v >> 8)
+	data[offset+2] = uint8(v >> 16)
+	data[offset+3] = uint8(v >> 24)
+	return offset + 4
+}
+func encodeVarintInternal(data []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		data[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	data[offset] = uint8(v)
+	return offset + 1
+}
+
+func (m *InternalRecomputeStatsRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5909,18 +7793,26 @@ func (m *RaftMessageResponse) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *RaftMessageResponse) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalRecomputeStatsRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n1, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n1
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalTimeSeriesData) Marshal() (data []byte, err error) {
+func (m *InternalRecomputeStatsResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5930,36 +7822,56 @@ func (m *InternalTimeSeriesData) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalTimeSeriesData) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalRecomputeStatsResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0x8
+	data[i] = 0xa
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.StartTimestampNanos))
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n2, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n2
 	data[i] = 0x10
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.SampleDurationNanos))
-	if len(m.Samples) > 0 {
-		for _, msg := range m.Samples {
-			data[i] = 0x1a
-			i++
-			i = encodeVarintInternal(data, i, uint64(msg.Size()))
-			n, err := msg.MarshalTo(data[i:])
-			if err != nil {
-				return 0, err
-			}
-			i += n
-		}
-	}
+	i = encodeVarintInternal(data, i, uint64(m.LiveBytes))
+	data[i] = 0x18
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.KeyBytes))
+	data[i] = 0x20
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ValBytes))
+	data[i] = 0x28
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.IntentBytes))
+	data[i] = 0x30
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.LiveCount))
+	data[i] = 0x38
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.KeyCount))
+	data[i] = 0x40
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ValCount))
+	data[i] = 0x48
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.IntentCount))
+	data[i] = 0x50
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.IntentAge))
+	data[i] = 0x58
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.GCBytesAge))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *InternalTimeSeriesSample) Marshal() (data []byte, err error) {
+func (m *InternalExportRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -5969,57 +7881,30 @@ func (m *InternalTimeSeriesSample) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *InternalTimeSeriesSample) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalExportRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0x8
-	i++
-	i = encodeVarintInternal(data, i, uint64(m.Offset))
-	data[i] = 0x10
+	data[i] = 0xa
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.IntCount))
-	if m.IntSum != nil {
-		data[i] = 0x18
-		i++
-		i = encodeVarintInternal(data, i, uint64(*m.IntSum))
-	}
-	if m.IntMax != nil {
-		data[i] = 0x20
-		i++
-		i = encodeVarintInternal(data, i, uint64(*m.IntMax))
-	}
-	if m.IntMin != nil {
-		data[i] = 0x28
-		i++
-		i = encodeVarintInternal(data, i, uint64(*m.IntMin))
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n62, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
 	}
-	data[i] = 0x30
+	i += n62
+	data[i] = 0x12
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.FloatCount))
-	if m.FloatSum != nil {
-		data[i] = 0x3d
-		i++
-		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatSum)))
-	}
-	if m.FloatMax != nil {
-		data[i] = 0x45
-		i++
-		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatMax)))
-	}
-	if m.FloatMin != nil {
-		data[i] = 0x4d
-		i++
-		i = encodeFixed32Internal(data, i, uint32(math.Float32bits(*m.FloatMin)))
-	}
+	i = encodeVarintInternal(data, i, uint64(len(m.Name)))
+	i += copy(data[i:], m.Name)
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *RaftTruncatedState) Marshal() (data []byte, err error) {
+func (m *InternalExportResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -6029,24 +7914,38 @@ func (m *RaftTruncatedState) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *RaftTruncatedState) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalExportResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	data[i] = 0x8
+	data[i] = 0xa
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.Index))
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n63, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n63
 	data[i] = 0x10
 	i++
-	i = encodeVarintInternal(data, i, uint64(m.Term))
+	i = encodeVarintInternal(data, i, uint64(m.KeyCount))
+	data[i] = 0x18
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.DataSize))
+	if m.Sha256 != nil {
+		data[i] = 0x2a
+		i++
+		i = encodeVarintInternal(data, i, uint64(len(m.Sha256)))
+		i += copy(data[i:], m.Sha256)
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *RaftSnapshotData) Marshal() (data []byte, err error) {
+func (m *InternalImportRequest) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -6056,14 +7955,22 @@ func (m *RaftSnapshotData) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *RaftSnapshotData) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalImportRequest) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if len(m.KV) > 0 {
-		for _, msg := range m.KV {
-			data[i] = 0xa
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n65, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n65
+	if len(m.Rows) > 0 {
+		for _, msg := range m.Rows {
+			data[i] = 0x12
 			i++
 			i = encodeVarintInternal(data, i, uint64(msg.Size()))
 			n, err := msg.MarshalTo(data[i:])
@@ -6073,13 +7980,21 @@ func (m *RaftSnapshotData) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	data[i] = 0x18
+	i++
+	if m.Clear {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
 
-func (m *RaftSnapshotData_KeyValue) Marshal() (data []byte, err error) {
+func (m *InternalImportResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -6089,53 +8004,24 @@ func (m *RaftSnapshotData_KeyValue) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *RaftSnapshotData_KeyValue) MarshalTo(data []byte) (n int, err error) {
+func (m *InternalImportResponse) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.Key != nil {
-		data[i] = 0xa
-		i++
-		i = encodeVarintInternal(data, i, uint64(len(m.Key)))
-		i += copy(data[i:], m.Key)
-	}
-	if m.Value != nil {
-		data[i] = 0x12
-		i++
-		i = encodeVarintInternal(data, i, uint64(len(m.Value)))
-		i += copy(data[i:], m.Value)
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n66, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
 	}
+	i += n66
+	data[i] = 0x10
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.KeyCount))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
 	return i, nil
 }
-
-func encodeFixed64Internal(data []byte, offset int, v uint64) int {
-	data[offset] = uint8(v)
-	data[offset+1] = uint8(v >> 8)
-	data[offset+2] = uint8(v >> 16)
-	data[offset+3] = uint8(v >> 24)
-	data[offset+4] = uint8(v >> 32)
-	data[offset+5] = uint8(v >> 40)
-	data[offset+6] = uint8(v >> 48)
-	data[offset+7] = uint8(v >> 56)
-	return offset + 8
-}
-func encodeFixed32Internal(data []byte, offset int, v uint32) int {
-	data[offset] = uint8(v)
-	data[offset+1] = uint8(v >> 8)
-	data[offset+2] = uint8(v >> 16)
-	data[offset+3] = uint8(v >> 24)
-	return offset + 4
-}
-func encodeVarintInternal(data []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		data[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
-	}
-	data[offset] = uint8(v)
-	return offset + 1
-}
@@ -17,6 +17,54 @@ import github_com_gogo_protobuf_proto "github.com/gogo/protobuf/proto"
 var _ = proto1.Marshal
 var _ = math.Inf
 
+// RangeEventType is the type of a RangeEvent.
+type RangeEventType int32
+
+const (
+	// A range was split in two.
+	RANGE_SPLIT RangeEventType = 0
+	// A range subsumed the range following it.
+	RANGE_MERGE RangeEventType = 1
+	// A replica was added to a range.
+	REPLICA_ADDED RangeEventType = 2
+	// A replica was removed from a range.
+	REPLICA_REMOVED RangeEventType = 3
+	// A range's leader lease was transferred to another replica.
+	LEASE_TRANSFERRED RangeEventType = 4
+)
+
+var RangeEventType_name = map[int32]string{
+	0: "RANGE_SPLIT",
+	1: "RANGE_MERGE",
+	2: "REPLICA_ADDED",
+	3: "REPLICA_REMOVED",
+	4: "LEASE_TRANSFERRED",
+}
+var RangeEventType_value = map[string]int32{
+	"RANGE_SPLIT":       0,
+	"RANGE_MERGE":       1,
+	"REPLICA_ADDED":     2,
+	"REPLICA_REMOVED":   3,
+	"LEASE_TRANSFERRED": 4,
+}
+
+func (x RangeEventType) Enum() *RangeEventType {
+	p := new(RangeEventType)
+	*p = x
+	return p
+}
+func (x RangeEventType) String() string {
+	return proto1.EnumName(RangeEventType_name, int32(x))
+}
+func (x *RangeEventType) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(RangeEventType_value, data, "RangeEventType")
+	if err != nil {
+		return err
+	}
+	*x = RangeEventType(value)
+	return nil
+}
+
 // StoreStatus contains the stats needed to calculate the current status of a
 // store.
 // TODO(bram): add significantly more statistics.
@@ -80,7 +128,167 @@ func (m *StoreStatus) GetMaxBytes() int64 {
 	return 0
 }
 
+// AcctUsage is a rollup of the usage of the ranges attributed to an
+// accounting config prefix by a single store.
+type AcctUsage struct {
+	StoreID          StoreID `protobuf:"varint,1,opt,name=store_id,customtype=StoreID" json:"store_id"`
+	Prefix           Key     `protobuf:"bytes,2,opt,name=prefix,customtype=Key" json:"prefix"`
+	UpdatedAt        int64   `protobuf:"varint,3,opt,name=updated_at" json:"updated_at"`
+	RangeCount       int64   `protobuf:"varint,4,opt,name=range_count" json:"range_count"`
+	LiveBytes        int64   `protobuf:"varint,5,opt,name=live_bytes" json:"live_bytes"`
+	KeyBytes         int64   `protobuf:"varint,6,opt,name=key_bytes" json:"key_bytes"`
+	ValBytes         int64   `protobuf:"varint,7,opt,name=val_bytes" json:"val_bytes"`
+	IntentBytes      int64   `protobuf:"varint,8,opt,name=intent_bytes" json:"intent_bytes"`
+	LiveCount        int64   `protobuf:"varint,9,opt,name=live_count" json:"live_count"`
+	KeyCount         int64   `protobuf:"varint,10,opt,name=key_count" json:"key_count"`
+	ValCount         int64   `protobuf:"varint,11,opt,name=val_count" json:"val_count"`
+	IntentCount      int64   `protobuf:"varint,12,opt,name=intent_count" json:"intent_count"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AcctUsage) Reset()         { *m = AcctUsage{} }
+func (m *AcctUsage) String() string { return proto1.CompactTextString(m) }
+func (*AcctUsage) ProtoMessage()    {}
+
+func (m *AcctUsage) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetRangeCount() int64 {
+	if m != nil {
+		return m.RangeCount
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetLiveBytes() int64 {
+	if m != nil {
+		return m.LiveBytes
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetKeyBytes() int64 {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetValBytes() int64 {
+	if m != nil {
+		return m.ValBytes
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetIntentBytes() int64 {
+	if m != nil {
+		return m.IntentBytes
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetLiveCount() int64 {
+	if m != nil {
+		return m.LiveCount
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetKeyCount() int64 {
+	if m != nil {
+		return m.KeyCount
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetValCount() int64 {
+	if m != nil {
+		return m.ValCount
+	}
+	return 0
+}
+
+func (m *AcctUsage) GetIntentCount() int64 {
+	if m != nil {
+		return m.IntentCount
+	}
+	return 0
+}
+
+// A RangeEvent is an entry of the range event log, recording a change
+// to a range's boundaries or replicas.
+type RangeEvent struct {
+	// The wall time in nanoseconds at which the event occurred.
+	Timestamp int64 `protobuf:"varint,1,opt,name=timestamp" json:"timestamp"`
+	// The range the event occurred on.
+	RaftID int64 `protobuf:"varint,2,opt,name=raft_id" json:"raft_id"`
+	// The store which initiated the event.
+	StoreID   StoreID        `protobuf:"varint,3,opt,name=store_id,customtype=StoreID" json:"store_id"`
+	EventType RangeEventType `protobuf:"varint,4,opt,name=event_type,enum=cockroach.proto.RangeEventType" json:"event_type"`
+	// For splits, the range created by the split; for merges, the
+	// range subsumed.
+	OtherRaftID int64 `protobuf:"varint,5,opt,name=other_raft_id" json:"other_raft_id"`
+	// For replica changes, the replica added or removed; for lease
+	// transfers, the replica receiving the lease.
+	Replica Replica `protobuf:"bytes,6,opt,name=replica" json:"replica"`
+	// Why the event occurred, if known.
+	Reason           string `protobuf:"bytes,7,opt,name=reason" json:"reason"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *RangeEvent) Reset()         { *m = RangeEvent{} }
+func (m *RangeEvent) String() string { return proto1.CompactTextString(m) }
+func (*RangeEvent) ProtoMessage()    {}
+
+func (m *RangeEvent) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *RangeEvent) GetRaftID() int64 {
+	if m != nil {
+		return m.RaftID
+	}
+	return 0
+}
+
+func (m *RangeEvent) GetEventType() RangeEventType {
+	if m != nil {
+		return m.EventType
+	}
+	return RANGE_SPLIT
+}
+
+func (m *RangeEvent) GetOtherRaftID() int64 {
+	if m != nil {
+		return m.OtherRaftID
+	}
+	return 0
+}
+
+func (m *RangeEvent) GetReplica() Replica {
+	if m != nil {
+		return m.Replica
+	}
+	return Replica{}
+}
+
+func (m *RangeEvent) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 func init() {
+	proto1.RegisterEnum("cockroach.proto.RangeEventType", RangeEventType_name, RangeEventType_value)
 }
 func (m *StoreStatus) Unmarshal(data []byte) error {
 	l := len(data)
@@ -231,79 +439,623 @@ func (m *StoreStatus) Unmarshal(data []byte) error {
 	}
 	return nil
 }
-func (m *StoreStatus) Size() (n int) {
-	var l int
-	_ = l
-	n += 1 + sovStatus(uint64(m.StoreID))
-	n += 1 + sovStatus(uint64(m.NodeID))
-	if len(m.RaftIDs) > 0 {
-		for _, e := range m.RaftIDs {
-			n += 1 + sovStatus(uint64(e))
-		}
-	}
-	n += 1 + sovStatus(uint64(m.UpdatedAt))
-	n += 1 + sovStatus(uint64(m.StartedAt))
-	n += 1 + sovStatus(uint64(m.UsedBytes))
-	n += 1 + sovStatus(uint64(m.MaxBytes))
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func sovStatus(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
-	}
-	return n
-}
-func sozStatus(x uint64) (n int) {
-	return sovStatus(uint64((x << 1) ^ uint64((int64(x) >> 63))))
-}
-func (m *StoreStatus) Marshal() (data []byte, err error) {
-	size := m.Size()
-	data = make([]byte, size)
-	n, err := m.MarshalTo(data)
-	if err != nil {
-		return nil, err
-	}
-	return data[:n], nil
-}
-
-func (m *StoreStatus) MarshalTo(data []byte) (n int, err error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	data[i] = 0x8
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.StoreID))
-	data[i] = 0x10
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.NodeID))
-	if len(m.RaftIDs) > 0 {
-		for _, num := range m.RaftIDs {
-			data[i] = 0x18
-			i++
-			i = encodeVarintStatus(data, i, uint64(num))
+func (m *AcctUsage) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
 		}
-	}
-	data[i] = 0x20
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.UpdatedAt))
-	data[i] = 0x28
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.StartedAt))
-	data[i] = 0x30
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.UsedBytes))
-	data[i] = 0x38
-	i++
-	i = encodeVarintStatus(data, i, uint64(m.MaxBytes))
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.StoreID |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Prefix.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedAt", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.UpdatedAt |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.RangeCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LiveBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.LiveBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ValBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LiveCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.LiveCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.KeyCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ValCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentCount", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentCount |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *RangeEvent) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Timestamp |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RaftID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.RaftID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.StoreID |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EventType", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.EventType |= (RangeEventType(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OtherRaftID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.OtherRaftID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replica", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Replica.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Reason = string(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *StoreStatus) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovStatus(uint64(m.StoreID))
+	n += 1 + sovStatus(uint64(m.NodeID))
+	if len(m.RaftIDs) > 0 {
+		for _, e := range m.RaftIDs {
+			n += 1 + sovStatus(uint64(e))
+		}
+	}
+	n += 1 + sovStatus(uint64(m.UpdatedAt))
+	n += 1 + sovStatus(uint64(m.StartedAt))
+	n += 1 + sovStatus(uint64(m.UsedBytes))
+	n += 1 + sovStatus(uint64(m.MaxBytes))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *AcctUsage) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovStatus(uint64(m.StoreID))
+	l = m.Prefix.Size()
+	n += 1 + l + sovStatus(uint64(l))
+	n += 1 + sovStatus(uint64(m.UpdatedAt))
+	n += 1 + sovStatus(uint64(m.RangeCount))
+	n += 1 + sovStatus(uint64(m.LiveBytes))
+	n += 1 + sovStatus(uint64(m.KeyBytes))
+	n += 1 + sovStatus(uint64(m.ValBytes))
+	n += 1 + sovStatus(uint64(m.IntentBytes))
+	n += 1 + sovStatus(uint64(m.LiveCount))
+	n += 1 + sovStatus(uint64(m.KeyCount))
+	n += 1 + sovStatus(uint64(m.ValCount))
+	n += 1 + sovStatus(uint64(m.IntentCount))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RangeEvent) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovStatus(uint64(m.Timestamp))
+	n += 1 + sovStatus(uint64(m.RaftID))
+	n += 1 + sovStatus(uint64(m.StoreID))
+	n += 1 + sovStatus(uint64(m.EventType))
+	n += 1 + sovStatus(uint64(m.OtherRaftID))
+	l = m.Replica.Size()
+	n += 1 + l + sovStatus(uint64(l))
+	l = len(m.Reason)
+	n += 1 + l + sovStatus(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovStatus(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozStatus(x uint64) (n int) {
+	return sovStatus(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *StoreStatus) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *StoreStatus) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.StoreID))
+	data[i] = 0x10
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.NodeID))
+	if len(m.RaftIDs) > 0 {
+		for _, num := range m.RaftIDs {
+			data[i] = 0x18
+			i++
+			i = encodeVarintStatus(data, i, uint64(num))
+		}
+	}
+	data[i] = 0x20
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.UpdatedAt))
+	data[i] = 0x28
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.StartedAt))
+	data[i] = 0x30
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.UsedBytes))
+	data[i] = 0x38
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.MaxBytes))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *AcctUsage) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *AcctUsage) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.StoreID))
+	data[i] = 0x12
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.Prefix.Size()))
+	n2, err := m.Prefix.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n2
+	data[i] = 0x18
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.UpdatedAt))
+	data[i] = 0x20
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.RangeCount))
+	data[i] = 0x28
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.LiveBytes))
+	data[i] = 0x30
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.KeyBytes))
+	data[i] = 0x38
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.ValBytes))
+	data[i] = 0x40
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.IntentBytes))
+	data[i] = 0x48
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.LiveCount))
+	data[i] = 0x50
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.KeyCount))
+	data[i] = 0x58
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.ValCount))
+	data[i] = 0x60
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.IntentCount))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *RangeEvent) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RangeEvent) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.Timestamp))
+	data[i] = 0x10
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.RaftID))
+	data[i] = 0x18
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.StoreID))
+	data[i] = 0x20
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.EventType))
+	data[i] = 0x28
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.OtherRaftID))
+	data[i] = 0x32
+	i++
+	i = encodeVarintStatus(data, i, uint64(m.Replica.Size()))
+	n3, err := m.Replica.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n3
+	data[i] = 0x3a
+	i++
+	i = encodeVarintStatus(data, i, uint64(len(m.Reason)))
+	i += copy(data[i:], m.Reason)
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -96,7 +96,8 @@ func (s SendError) CanRetry() bool { return s.canRetry }
 // function. On success, Send returns a slice of replies of length
 // opts.N. Otherwise, Send returns an error if and as soon as the
 // number of failed RPCs exceeds the available endpoints less the
-// number of required replies.
+// number of required replies. RPCs still outstanding when Send returns
+// are abandoned: their replies, if any, are discarded.
 func Send(opts Options, method string, addrs []net.Addr, getArgs func(addr net.Addr) interface{},
 	getReply func() interface{}, context *Context) ([]interface{}, error) {
 
@@ -145,6 +146,8 @@ func Send(opts Options, method string, addrs []net.Addr, getArgs func(addr net.A
 
 	replies := []interface{}(nil)
 	helperChan := make(chan interface{}, len(clients))
+	done := make(chan struct{})
+	defer close(done)
 	N := opts.N
 	errors := 0
 	retryableErrors := 0
@@ -162,7 +165,7 @@ func Send(opts Options, method string, addrs []net.Addr, getArgs func(addr net.A
 			}
 			reply := getReply()
 			log.V(1).Infof("%s: sending request to %s: %+v", method, clients[index].Addr(), args)
-			go sendOneFn(clients[index], opts.Timeout, method, args, reply, helperChan)
+			go sendOneFn(clients[index], opts.Timeout, method, args, reply, helperChan, done)
 		}
 		// Wait for completions.
 		select {
@@ -206,11 +209,19 @@ func Send(opts Options, method string, addrs []net.Addr, getArgs func(addr net.A
 
 // sendOne invokes the specified RPC on the supplied client when the
 // client is ready. On success, the reply is sent on the channel;
-// otherwise an error is sent.
-func sendOne(client *Client, timeout time.Duration, method string, args, reply interface{}, c chan interface{}) {
-	<-client.Ready
+// otherwise an error is sent. If done is closed first, the RPC is
+// abandoned and nothing is sent.
+func sendOne(client *Client, timeout time.Duration, method string, args, reply interface{},
+	c chan interface{}, done <-chan struct{}) {
+	select {
+	case <-client.Ready:
+	case <-done:
+		return
+	}
 	call := client.Go(method, args, reply, nil)
 	select {
+	case <-done:
+		return
 	case <-call.Done:
 		if call.Error != nil {
 			// Handle cases which are retryable.
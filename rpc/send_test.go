@@ -220,7 +220,7 @@ func TestComplexScenarios(t *testing.T) {
 
 		// Mock sendOne.
 		sendOneFn = func(client *Client, timeout time.Duration, method string, args, reply interface{},
-			c chan interface{}) {
+			c chan interface{}, _ <-chan struct{}) {
 			addr := args.(net.Addr)
 			addrID := -1
 			for serverAddrID, serverAddr := range serverAddrs {
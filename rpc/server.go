@@ -18,6 +18,8 @@
 package rpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
@@ -28,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc/codec"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
+	"golang.org/x/net/http2"
 )
 
 // Server is a Cockroach-specific RPC server with an embedded go RPC
@@ -48,6 +51,38 @@ type Server struct {
 	closeCallbacks []func(conn net.Conn) // Slice of callbacks to invoke on conn close
 }
 
+// A PeerRequest is an RPC request which is told the identity of the
+// peer which sent it, for services which authenticate their callers.
+type PeerRequest interface {
+	// SetPeer is invoked after the request is decoded with the remote
+	// address of the connection and, if TLS is in use, the peer's
+	// certificate chain, which has already been verified against the
+	// cluster CA.
+	SetPeer(addr net.Addr, certs []*x509.Certificate)
+}
+
+// peerCodec wraps a server codec to inform each PeerRequest of the
+// peer on the other end of the connection.
+type peerCodec struct {
+	rpc.ServerCodec
+	conn net.Conn
+}
+
+// ReadRequestBody implements rpc.ServerCodec.
+func (c *peerCodec) ReadRequestBody(x interface{}) error {
+	if err := c.ServerCodec.ReadRequestBody(x); err != nil {
+		return err
+	}
+	if req, ok := x.(PeerRequest); ok {
+		var certs []*x509.Certificate
+		if tlsConn, ok := c.conn.(*tls.Conn); ok {
+			certs = tlsConn.ConnectionState().PeerCertificates
+		}
+		req.SetPeer(c.conn.RemoteAddr(), certs)
+	}
+	return nil
+}
+
 // NewServer creates a new instance of Server.
 func NewServer(addr net.Addr, context *Context) *Server {
 	s := &Server{
@@ -93,7 +128,14 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		io.WriteString(w, "405 must CONNECT\n")
 		return
 	}
-	conn, _, err := w.(http.Hijacker).Hijack()
+	// RPC connections are hijacked, which is only possible over
+	// HTTP/1.x; RPC clients never negotiate HTTP/2.
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "505 must CONNECT via HTTP/1.x", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	conn, _, err := hj.Hijack()
 	if err != nil {
 		log.Infof("rpc hijacking %s: %s", r.RemoteAddr, err)
 		return
@@ -124,10 +166,15 @@ func (s *Server) Listen() error {
 }
 
 // Serve accepts and services connections on the already started
-// listener.
+// listener. Clients connecting via TLS may negotiate HTTP/2, in which
+// case concurrent requests are multiplexed over the connection.
 func (s *Server) Serve(handler http.Handler) {
 	s.handler = handler
-	go http.Serve(s.listener, s)
+	srv := &http.Server{Handler: s}
+	if err := http2.ConfigureServer(srv, nil); err != nil {
+		log.Errorf("unable to enable HTTP/2: %s", err)
+	}
+	go srv.Serve(s.listener)
 }
 
 // Start runs the RPC server. After this method returns, the socket
@@ -201,7 +248,7 @@ func (s *Server) Close() {
 // serveConn synchronously serves a single connection. When the
 // connection is closed, close callbacks are invoked.
 func (s *Server) serveConn(conn net.Conn) {
-	s.ServeCodec(codec.NewServerCodec(conn))
+	s.ServeCodec(&peerCodec{ServerCodec: codec.NewServerCodec(conn), conn: conn})
 	s.mu.Lock()
 	if s.closeCallbacks != nil {
 		for _, cb := range s.closeCallbacks {
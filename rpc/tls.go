@@ -35,6 +35,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc/rpctest"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
+	"golang.org/x/net/http2"
 )
 
 // TLSConfig contains the TLS settings for a Cockroach node. Currently it's
@@ -134,7 +135,7 @@ func LoadTestTLSConfig() (*TLSConfig, error) {
 }
 
 // tlsListen wraps either net.Listen or crypto/tls.Listen, depending on the contents of
-// the passed TLSConfig.
+// the passed TLSConfig. TLS listeners offer HTTP/2 to clients which support it.
 func tlsListen(network, address string, config *TLSConfig) (net.Listener, error) {
 	cfg := config.Config()
 	if cfg == nil {
@@ -143,6 +144,7 @@ func tlsListen(network, address string, config *TLSConfig) (net.Listener, error)
 		}
 		return net.Listen(network, address)
 	}
+	cfg.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
 	return tls.Listen(network, address, cfg)
 }
 
@@ -23,6 +23,8 @@ import (
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
 // An acctHandler implements the adminHandler interface.
@@ -55,3 +57,35 @@ func (ah *acctHandler) Get(path string, r *http.Request) (body []byte, contentTy
 func (ah *acctHandler) Delete(path string, r *http.Request) error {
 	return deleteConfig(ah.db, engine.KeyConfigAccountingPrefix, path, r)
 }
+
+// GetAcctUsage returns the usage of the accounting config prefix,
+// summed over the most recent rollups of all stores. UpdatedAt is set
+// to the time of the oldest rollup included. Rollups are computed
+// periodically by each store, so usage lags writes.
+func GetAcctUsage(db *client.KV, prefix proto.Key) (*proto.AcctUsage, error) {
+	start := engine.AcctUsagePrefix(prefix)
+	reply := &proto.ScanResponse{}
+	if err := db.Call(proto.Scan, proto.ScanArgs(start, start.PrefixEnd(), 0), reply); err != nil {
+		return nil, err
+	}
+	total := &proto.AcctUsage{Prefix: prefix}
+	for _, row := range reply.Rows {
+		u := &proto.AcctUsage{}
+		if err := gogoproto.Unmarshal(row.Value.Bytes, u); err != nil {
+			return nil, util.Errorf("unable to unmarshal accounting usage at %q: %s", row.Key, err)
+		}
+		if total.UpdatedAt == 0 || u.UpdatedAt < total.UpdatedAt {
+			total.UpdatedAt = u.UpdatedAt
+		}
+		total.RangeCount += u.RangeCount
+		total.LiveBytes += u.LiveBytes
+		total.KeyBytes += u.KeyBytes
+		total.ValBytes += u.ValBytes
+		total.IntentBytes += u.IntentBytes
+		total.LiveCount += u.LiveCount
+		total.KeyCount += u.KeyCount
+		total.ValCount += u.ValCount
+		total.IntentCount += u.IntentCount
+	}
+	return total, nil
+}
@@ -76,6 +76,9 @@ type adminServer struct {
 	acct    *acctHandler
 	perm    *permHandler
 	zone    *zoneHandler
+	backup  *backupHandler // Set if the server backs up key spans
+	// checkpoint is set if the server checkpoints the node's stores.
+	checkpoint *checkpointHandler
 }
 
 // newAdminServer allocates and returns a new REST server for
@@ -104,6 +107,12 @@ func (s *adminServer) registerHandlers(mux *http.ServeMux) {
 	mux.HandleFunc(permPathPrefix+"/", s.handlePermAction)
 	mux.HandleFunc(zonePathPrefix, s.handleZoneAction)
 	mux.HandleFunc(zonePathPrefix+"/", s.handleZoneAction)
+	if s.backup != nil {
+		mux.Handle(backupPath, s.backup)
+	}
+	if s.checkpoint != nil {
+		mux.Handle(checkpointPath, s.checkpoint)
+	}
 }
 
 // handleHealth responds to health requests from monitoring services.
@@ -38,7 +38,7 @@ import (
 // Cockroach KV client address is set to the address of the test server.
 func startAdminServer() (string, *util.Stopper) {
 	stopper := util.NewStopper()
-	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), stopper)
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,87 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// backupPath is the endpoint which backs up a key span. POSTing to it
+// with the "name" query parameter writes a backup of the span given
+// by the optional "start" and "end" query parameters, which default
+// to the entire user key space, to the directory of that name in the
+// export directory. The backup's manifest is returned.
+const backupPath = adminEndpoint + "backup"
+
+// A backupHandler serves requests to back up key spans.
+type backupHandler struct {
+	db     *client.KV
+	clock  *hlc.Clock
+	gossip *gossip.Gossip
+	sink   storage.ExportSink // Nil if no export directory is configured
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *backupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "backups must be requested with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.sink == nil {
+		http.Error(w, "no export directory is configured", http.StatusBadRequest)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "the name of the backup must be specified", http.StatusBadRequest)
+		return
+	}
+	start, end := engine.KeySystemMax, engine.KeyMax
+	if v := r.URL.Query().Get("start"); v != "" {
+		start = proto.Key(v)
+	}
+	if v := r.URL.Query().Get("end"); v != "" {
+		end = proto.Key(v)
+	}
+	var clusterID string
+	if info, err := h.gossip.GetInfo(gossip.KeyClusterID); err == nil {
+		clusterID, _ = info.(string)
+	}
+	manifest, err := storage.Backup(h.db, h.sink, name, start, end, h.clock.Now(), clusterID)
+	if err != nil {
+		log.Errorf("backup %s failed: %s", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("backed up %q-%q to %s in %d files", start, end, name, len(manifest.Files))
+	b, contentType, err := util.MarshalResponse(r, manifest, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+)
+
+// attrRE matches a valid node or store attribute. Attributes are
+// separated by colons and stores by commas, so neither may appear
+// within an attribute.
+var attrRE = regexp.MustCompile(`^[^\s,:]+$`)
+
+// Validate checks the configuration for errors which can be detected
+// locally, without initializing engines or contacting peers: store
+// paths and their permissions, in-memory store capacities, the cache
+// size against system memory, attribute syntax, the maximum clock
+// offset and gossip bootstrap addresses. All errors found are
+// returned so they can be fixed at once.
+func (ctx *Context) Validate() []error {
+	var errs []error
+	storeSpecs, err := ctx.parseStoreSpecs()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	var memStoreBytes int64
+	for _, store := range storeSpecs {
+		errs = append(errs, validateAttrs(store[1], "store "+store[0])...)
+		if size, err := strconv.ParseInt(store[2], 10, 64); err == nil {
+			if size <= 0 {
+				errs = append(errs, util.Errorf("in-memory store %q must have a positive capacity", store[0]))
+			}
+			memStoreBytes += size
+			continue
+		}
+		if err := validateStorePath(store[2]); err != nil {
+			errs = append(errs, util.Errorf("store %q: %s", store[0], err))
+		}
+	}
+
+	if ctx.CacheSize < 0 {
+		errs = append(errs, util.Errorf("cache size %d must not be negative; set -cache-size", ctx.CacheSize))
+	}
+	if mem, err := totalSystemMemory(); err == nil {
+		if ctx.CacheSize > mem {
+			errs = append(errs, util.Errorf("cache size %d exceeds system memory %d; lower -cache-size",
+				ctx.CacheSize, mem))
+		} else if ctx.CacheSize+memStoreBytes > mem {
+			errs = append(errs, util.Errorf("cache size %d plus in-memory store capacity %d exceeds "+
+				"system memory %d; lower -cache-size or the in-memory store capacities",
+				ctx.CacheSize, memStoreBytes, mem))
+		}
+	}
+	if ctx.MemoryBudget < 0 {
+		errs = append(errs, util.Errorf("memory budget %d must not be negative; set -memory-budget", ctx.MemoryBudget))
+	}
+
+	errs = append(errs, validateAttrs(ctx.Attrs, "node")...)
+
+	if ctx.MaxOffset <= 0 {
+		errs = append(errs, util.Errorf("max clock offset %s must be positive; set -max-offset", ctx.MaxOffset))
+	}
+
+	if resolvers, err := ctx.parseGossipBootstrapResolvers(); err != nil {
+		errs = append(errs, util.Errorf("invalid gossip bootstrap addresses: %s", err))
+	} else if len(resolvers) == 0 {
+		errs = append(errs, util.Errorf("no gossip addresses found, did you specify -gossip?"))
+	}
+	return errs
+}
+
+// CheckClockOffsets connects to each gossip bootstrap host and
+// measures the offset of its clock from the local clock. An error is
+// returned for each host which can't be reached within timeout or
+// whose clock offset may exceed MaxOffset, in which case the node
+// would commit suicide shortly after joining the cluster.
+func (ctx *Context) CheckClockOffsets(timeout time.Duration) []error {
+	resolvers, err := ctx.parseGossipBootstrapResolvers()
+	if err != nil {
+		return []error{err}
+	}
+	tlsConfig, err := ctx.loadTLSConfig()
+	if err != nil {
+		return []error{err}
+	}
+	clock := hlc.NewClock(hlc.UnixNano)
+	clock.SetMaxOffset(ctx.MaxOffset)
+	rpcContext := rpc.NewContext(clock, tlsConfig)
+	self := util.EnsureHost(ctx.Addr)
+
+	var errs []error
+	for _, resolver := range resolvers {
+		addr, err := resolver.GetAddress()
+		if err != nil {
+			errs = append(errs, util.Errorf("unable to resolve gossip address %q: %s", resolver.Address, err))
+			continue
+		}
+		if addr.String() == self {
+			continue
+		}
+		client := rpc.NewClient(addr, &util.RetryOptions{MaxAttempts: 1}, rpcContext)
+		select {
+		case <-client.Ready:
+		case <-client.Closed:
+			errs = append(errs, util.Errorf("unable to connect to gossip host %s", addr))
+			continue
+		case <-time.After(timeout):
+			errs = append(errs, util.Errorf("timed out connecting to gossip host %s", addr))
+			continue
+		}
+		offset := client.RemoteOffset()
+		client.Close()
+		abs := offset.Offset
+		if abs < 0 {
+			abs = -abs
+		}
+		if offset.Offset == proto.InfiniteOffset.Offset || abs+offset.Error > ctx.MaxOffset.Nanoseconds() {
+			errs = append(errs, util.Errorf("clock offset from gossip host %s of %s (+/- %s) may exceed "+
+				"max offset %s; synchronize clocks with NTP or raise -max-offset", addr,
+				time.Duration(offset.Offset), time.Duration(offset.Error), ctx.MaxOffset))
+		}
+	}
+	return errs
+}
+
+// loadTLSConfig loads the TLS configuration from Certs, or an
+// insecure configuration if no certificate directory is specified.
+func (ctx *Context) loadTLSConfig() (*rpc.TLSConfig, error) {
+	if ctx.Certs == "" {
+		return rpc.LoadInsecureTLSConfig(), nil
+	}
+	tlsConfig, err := rpc.LoadTLSConfigFromDir(ctx.Certs)
+	if err != nil {
+		return nil, util.Errorf("unable to load TLS config: %v", err)
+	}
+	return tlsConfig, nil
+}
+
+// validateAttrs verifies that each of the colon-separated attributes
+// in attrsStr is well-formed. what describes the attributes' owner
+// for error messages.
+func validateAttrs(attrsStr, what string) []error {
+	var errs []error
+	seen := map[string]struct{}{}
+	for _, attr := range parseAttributes(attrsStr).Attrs {
+		if !attrRE.MatchString(attr) {
+			errs = append(errs, util.Errorf("%s attribute %q must not contain whitespace or commas", what, attr))
+		}
+		if _, ok := seen[attr]; ok {
+			errs = append(errs, util.Errorf("%s attribute %q is specified more than once", what, attr))
+		}
+		seen[attr] = struct{}{}
+	}
+	return errs
+}
+
+// validateStorePath verifies that path is a writable directory, or
+// that it doesn't exist and can be created in a writable parent
+// directory.
+func validateStorePath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		parent := filepath.Dir(path)
+		if info, err = os.Stat(parent); err != nil {
+			return util.Errorf("path %q does not exist and neither does its parent directory; "+
+				"create it before starting", path)
+		}
+		if !info.IsDir() {
+			return util.Errorf("parent of path %q is not a directory", path)
+		}
+		path = parent
+	} else if err != nil {
+		return util.Errorf("unable to stat path %q: %s", path, err)
+	} else if !info.IsDir() {
+		return util.Errorf("path %q is not a directory", path)
+	}
+	f, err := ioutil.TempFile(path, ".check-config")
+	if err != nil {
+		return util.Errorf("directory %q is not writable; check its permissions: %s", path, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// totalSystemMemory returns the total system memory in bytes. An
+// error is returned on systems where it can't be determined.
+func totalSystemMemory() (int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb << 10, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, util.Errorf("total memory not found in /proc/meminfo")
+}
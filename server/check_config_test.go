@@ -0,0 +1,74 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContextValidate verifies that Validate reports each kind of
+// configuration error and accepts a valid configuration.
+func TestContextValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check_config_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		stores  string
+		attrs   string
+		gossip  string
+		expErrs int
+	}{
+		// Valid persistent and in-memory stores.
+		{fmt.Sprintf("ssd=%s,mem=1", dir), "us-west-1a:gpu", "self://", 0},
+		// A store path which doesn't exist yet in a writable directory.
+		{fmt.Sprintf("ssd=%s", filepath.Join(dir, "new")), "", "self://", 0},
+		// A store path which is a file.
+		{fmt.Sprintf("ssd=%s", file), "", "self://", 1},
+		// A store path whose parent doesn't exist.
+		{fmt.Sprintf("ssd=%s", filepath.Join(dir, "a", "b")), "", "self://", 1},
+		// An in-memory store with no capacity.
+		{"mem=0", "", "self://", 1},
+		// Duplicate store attributes.
+		{"ssd:ssd=1", "", "self://", 1},
+		// Invalid and duplicate node attributes.
+		{"mem=1", "us west:gpu:gpu", "self://", 2},
+		// Missing gossip addresses.
+		{"mem=1", "", "", 1},
+		// Missing stores.
+		{"", "", "self://", 1},
+	}
+	for i, test := range testCases {
+		ctx := NewContext()
+		ctx.CacheSize = 1 << 20
+		ctx.Stores = test.stores
+		ctx.Attrs = test.attrs
+		ctx.GossipBootstrap = test.gossip
+		if errs := ctx.Validate(); len(errs) != test.expErrs {
+			t.Errorf("%d: expected %d errors; got %v", i, test.expErrs, errs)
+		}
+	}
+}
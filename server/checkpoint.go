@@ -0,0 +1,74 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// checkpointPath is the endpoint which checkpoints the stores of the
+// running node. POSTing to it with the "dir" query parameter, an
+// absolute path on the node, creates a consistent on-disk copy of
+// each store in the subdirectory of dir named by its store ID. See
+// the checkpoint command for checkpointing the stores of a stopped
+// node.
+const checkpointPath = adminEndpoint + "checkpoint"
+
+// A checkpointHandler serves requests to checkpoint the node's stores.
+type checkpointHandler struct {
+	stores *kv.LocalSender
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *checkpointHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "checkpoints must be requested with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	dir := r.URL.Query().Get("dir")
+	if !filepath.IsAbs(dir) {
+		http.Error(w, "the absolute path of the checkpoint directory must be specified", http.StatusBadRequest)
+		return
+	}
+	// Checkpoint the stores without holding the sender's lock, which
+	// would block commands for the duration.
+	var stores []*storage.Store
+	h.stores.VisitStores(func(s *storage.Store) error {
+		stores = append(stores, s)
+		return nil
+	})
+	var buf bytes.Buffer
+	for _, s := range stores {
+		storeDir := filepath.Join(dir, strconv.FormatInt(int64(s.StoreID()), 10))
+		if err := s.Engine().Checkpoint(storeDir); err != nil {
+			log.Errorf("checkpoint of store %s failed: %s", s, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("store %s: checkpointed to %s", s, storeDir)
+		fmt.Fprintf(&buf, "store %d: checkpointed to %s\n", s.StoreID(), storeDir)
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestCheckpointHandler verifies that the stores of a running node are
+// checkpointed to subdirectories named by their store IDs, and that
+// the checkpoints may be opened as engines.
+func TestCheckpointHandler(t *testing.T) {
+	loc := util.CreateTempDirectory()
+	defer func() {
+		if err := os.RemoveAll(loc); err != nil {
+			t.Errorf("could not remove %s: %v", loc, err)
+		}
+	}()
+
+	stopper := util.NewStopper()
+	e := engine.NewRocksDB(proto.Attributes{}, filepath.Join(loc, "db"), 1<<20)
+	if _, err := BootstrapCluster("cluster-1", e, nil, stopper); err != nil {
+		t.Fatal(err)
+	}
+	stopper.Stop()
+	_, node, stopper := createAndStartTestNode(util.CreateTestAddr("tcp"), []engine.Engine{e}, nil, t)
+	defer stopper.Stop()
+	h := &checkpointHandler{stores: node.lSender}
+
+	for _, test := range []struct {
+		method, dir string
+		expCode     int
+	}{
+		{"GET", filepath.Join(loc, "checkpoint"), http.StatusMethodNotAllowed},
+		{"POST", "checkpoint", http.StatusBadRequest},
+		{"POST", filepath.Join(loc, "checkpoint"), http.StatusOK},
+		// The checkpoint directories of the stores must not exist.
+		{"POST", filepath.Join(loc, "checkpoint"), http.StatusInternalServerError},
+	} {
+		req, err := http.NewRequest(test.method, checkpointPath+"?dir="+test.dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != test.expCode {
+			t.Errorf("%s %s: expected status %d; got %d: %s", test.method, test.dir, test.expCode, w.Code, w.Body)
+		}
+	}
+
+	checkpoint := engine.NewRocksDB(proto.Attributes{}, filepath.Join(loc, "checkpoint", "1"), 1<<20)
+	if err := checkpoint.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+	var ident proto.StoreIdent
+	ok, err := engine.MVCCGetProto(checkpoint, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &ident)
+	if err != nil || !ok {
+		t.Fatalf("expected store ident in checkpoint; got %t, %v", ok, err)
+	}
+	if ident.ClusterID != "cluster-1" || ident.StoreID != 1 {
+		t.Errorf("unexpected store ident in checkpoint: %+v", ident)
+	}
+}
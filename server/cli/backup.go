@@ -0,0 +1,125 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/storage"
+)
+
+// A backupCmd command operates on backups.
+var backupCmd = &commander.Command{
+	UsageLine: "backup [options] (verify|restore) <dir>",
+	Short:     "verifies or restores a backup",
+	Long: `
+Verify checks the integrity of the backup in <dir> without restoring
+it. Each file listed in the backup's manifest is checked against its
+size and SHA-256 checksum, its keys are checked to lie in order within
+its span, and the spans of the files are checked to cover the span of
+the backup without gaps or overlaps.
+
+Restore verifies the backup in <dir> and writes its data to the
+cluster at --addr, which needn't be the cluster that was backed up.
+Values are written as of the time of the restore.
+`,
+	Run:  runBackup,
+	Flag: *flag.CommandLine,
+}
+
+func runBackup(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	sink := storage.NewLocalExportSink(args[1])
+	var manifest *storage.BackupManifest
+	var err error
+	switch args[0] {
+	case "verify":
+		manifest, err = storage.VerifyBackup(sink, "")
+	case "restore":
+		manifest, err = storage.Restore(makeKVClient(), sink, "")
+	default:
+		cmd.Usage()
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(osStderr, "backup %s failed: %s\n", args[0], err)
+		osExit(1)
+		return
+	}
+	var keys, size int64
+	for _, file := range manifest.Files {
+		keys += file.KeyCount
+		size += file.Size
+	}
+	verb := "verified"
+	if args[0] == "restore" {
+		verb = "restored"
+	}
+	fmt.Printf("backup of %q-%q at %s %s: %d files, %d keys, %d bytes\n",
+		manifest.StartKey, manifest.EndKey, manifest.Timestamp, verb, len(manifest.Files), keys, size)
+}
+
+// A checkpointCmd command checkpoints the stores of a stopped node.
+var checkpointCmd = &commander.Command{
+	UsageLine: "checkpoint [options] <dir>",
+	Short:     "checkpoints the stores of a stopped node\n",
+	Long: `
+Creates a consistent on-disk copy of each store specified by the
+-stores flag in a numbered subdirectory of <dir>, in the order the
+stores are specified. The files of the stores are hard-linked where
+possible, so checkpoints are cheap but must be copied elsewhere to
+survive the loss of the disk. The node must be stopped; the stores
+of a running node are checkpointed by POSTing to its
+/_admin/checkpoint endpoint.
+`,
+	Run:  runCheckpoint,
+	Flag: *flag.CommandLine,
+}
+
+func runCheckpoint(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	if err := Context.Init(); err != nil {
+		fmt.Fprintf(osStderr, "failed to initialize context: %s\n", err)
+		osExit(1)
+		return
+	}
+	for i, e := range Context.Engines {
+		if err := e.Open(); err != nil {
+			fmt.Fprintf(osStderr, "unable to open store %s: %s\n", e, err)
+			osExit(1)
+			return
+		}
+		dir := filepath.Join(args[0], strconv.Itoa(i))
+		err := e.Checkpoint(dir)
+		e.Close()
+		if err != nil {
+			fmt.Fprintf(osStderr, "checkpoint of store %s failed: %s\n", e, err)
+			osExit(1)
+			return
+		}
+		fmt.Printf("store %s: checkpointed to %s\n", e, dir)
+	}
+}
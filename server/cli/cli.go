@@ -78,11 +78,18 @@ var allCmds = &commander.Commander{
 		incCmd,
 		delCmd,
 		scanCmd,
+		kvShellCmd,
 
 		// Range commands.
 		lsRangesCmd,
 		splitRangeCmd,
 		mergeRangeCmd,
+		transferLeaseCmd,
+		unsafeRecoverRangeCmd,
+
+		// Backup commands.
+		backupCmd,
+		checkpointCmd,
 
 		// Accounting commands.
 		getAcctCmd,
@@ -102,6 +109,9 @@ var allCmds = &commander.Commander{
 		rmZoneCmd,
 		setZoneCmd,
 
+		// Demo commands.
+		photosCmd,
+
 		// Miscellaneous commands.
 		// TODO(pmattis): stats
 		listParamsCmd,
@@ -96,6 +96,70 @@ func ExampleBasic() {
 	// node drained and shutdown: ok
 }
 
+func ExampleKVShell() {
+	c := newCLITest()
+	osStdin = strings.NewReader(`timing off
+put a 1
+put "b c" "x\ty"
+inc d 5
+get "b c"
+begin
+put a 2
+get a
+rollback
+get a
+begin snapshot
+del a
+inc d
+commit
+scan
+bogus
+get
+!4
+history
+`)
+	defer func() { osStdin = os.Stdin }()
+
+	c.Run("kv shell")
+	c.Run("quit")
+
+	// Output:
+	// kv shell
+	// 5
+	// "x\ty"
+	// "2"
+	// "1"
+	// 6
+	// "b c"	"x\ty"
+	// "d"	6
+	// 2 rows
+	// unknown command "bogus"; type help for a list of commands
+	// usage: get <key>
+	// inc d 5
+	// 11
+	//     1  timing off
+	//     2  put a 1
+	//     3  put "b c" "x\ty"
+	//     4  inc d 5
+	//     5  get "b c"
+	//     6  begin
+	//     7  put a 2
+	//     8  get a
+	//     9  rollback
+	//    10  get a
+	//    11  begin snapshot
+	//    12  del a
+	//    13  inc d
+	//    14  commit
+	//    15  scan
+	//    16  bogus
+	//    17  get
+	//    18  inc d 5
+	//    19  history
+	// quit
+	// node drained and shutdown: ok
+}
+
 func ExampleSplitMergeRanges() {
 	c := newCLITest()
 
@@ -23,6 +23,10 @@ import (
 	"github.com/cockroachdb/cockroach/server"
 )
 
+// checkConfig is set to validate the configuration and exit rather
+// than starting the node.
+var checkConfig bool
+
 // initFlags sets the server.Context values to flag values.
 // Keep in sync with "server/context.go". Values in Context should be
 // settable here.
@@ -31,15 +35,24 @@ func initFlags(ctx *server.Context) {
 	flag.StringVar(&ctx.Addr, "addr", ctx.Addr, "when run as the server the host:port to bind for "+
 		"HTTP/RPC traffic; when run as the client the address for connection to the cockroach cluster.")
 
+	flag.BoolVar(&checkConfig, "check-config", false, "when starting a node, validate the store "+
+		"paths and permissions, cache size, node and store attributes, and clock offset from "+
+		"the gossip bootstrap hosts, print any errors and exit without joining the cluster.")
+
 	flag.StringVar(&ctx.Certs, "certs", ctx.Certs, "directory containing RSA key and x509 certs.")
 
 	flag.StringVar(&ctx.Stores, "stores", ctx.Stores, "specify a comma-separated list of stores, "+
 		"specified by a colon-separated list of device attributes followed by '=' and "+
 		"either a filepath for a persistent store or an integer size in bytes for an "+
-		"in-memory store. Device attributes typically include whether the store is "+
+		"in-memory store. A filepath may be suffixed with :<max bytes> to limit the "+
+		"capacity of the store to part of the disk, and then with semicolon-separated "+
+		"options overriding the -write-buffer-size, -compression and -bloom-filter-bits "+
+		"flags for the store, e.g. ;compression=lz4;bloom-filter-bits=10. "+
+		"Device attributes typically include whether the store is "+
 		"flash (ssd), spinny disk (hdd), fusion-io (fio), in-memory (mem); device "+
 		"attributes might also include speeds and other specs (7200rpm, 200kiops, etc.). "+
-		"For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01,ssd=/mnt/ssd02,mem=1073741824.")
+		"For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01:107374182400;compression=lz4,"+
+		"ssd=/mnt/ssd02,mem=1073741824.")
 
 	flag.StringVar(&ctx.Attrs, "attrs", ctx.Attrs, "specify an ordered, colon-separated list of node "+
 		"attributes. Attributes are arbitrary strings specifying topography or "+
@@ -74,10 +87,102 @@ func initFlags(ctx *server.Context) {
 		"of operations on this node by making sure that no commit timestamp is reported "+
 		"back to the client until all other node clocks have necessarily passed it.")
 
+	flag.Float64Var(&ctx.TraceSampleRate, "trace-sample-rate", ctx.TraceSampleRate, "fraction "+
+		"of the requests coordinated by this node which are traced, with stores logging the time "+
+		"each spends in the command queue, Raft and the engine. Zero traces only requests which "+
+		"arrive with a trace ID.")
+
 	// Engine flags.
 
 	flag.Int64Var(&ctx.CacheSize, "cache-size", ctx.CacheSize, "total size in bytes for "+
 		"caches, shared evenly if there are multiple storage devices.")
+
+	flag.BoolVar(&ctx.SyncWrites, "sync-writes", ctx.SyncWrites, "sync the write-ahead log "+
+		"of each store to disk before acknowledging writes. Otherwise, writes acknowledged "+
+		"shortly before a machine failure may be lost; such losses are reported on restart.")
+
+	flag.Int64Var(&ctx.WriteBufferSize, "write-buffer-size", ctx.WriteBufferSize, "size in "+
+		"bytes of each store's in-memory write buffer before it's flushed to disk. Zero "+
+		"selects the default of 64 MB.")
+
+	flag.IntVar(&ctx.MaxBackgroundCompactions, "max-background-compactions",
+		ctx.MaxBackgroundCompactions, "number of threads running the compactions of the "+
+			"node's stores. The threads are shared by all stores, any of which may run as many "+
+			"compactions concurrently. Zero selects the default of 1.")
+
+	flag.StringVar(&ctx.Compression, "compression", ctx.Compression, "algorithm with which "+
+		"stores compress data on disk: snappy, none, zlib or lz4. Defaults to snappy.")
+
+	flag.IntVar(&ctx.BloomFilterBits, "bloom-filter-bits", ctx.BloomFilterBits, "bits per "+
+		"key of the bloom filters which save stores reads of files not containing a key. "+
+		"Zero disables the bloom filters.")
+
+	flag.StringVar(&ctx.EncryptionKeyFile, "encryption-keys", ctx.EncryptionKeyFile, "path of "+
+		"a file of keys with which stores encrypt data at rest, one per line as an ID and a "+
+		"hex-encoded AES key. The key with the highest ID encrypts new data. Only values are "+
+		"encrypted; keys are stored in the clear. Encryption must be enabled when a store is "+
+		"created.")
+
+	flag.Int64Var(&ctx.MemoryBudget, "memory-budget", ctx.MemoryBudget, "heap size in bytes "+
+		"beyond which in-flight scans and snapshots are shed, largest first, and must be "+
+		"retried by the client. Zero disables the memory watchdog.")
+
+	// Data movement flags.
+
+	flag.Float64Var(&ctx.SplitQPS, "split-qps", ctx.SplitQPS, "request rate (queries "+
+		"per second) above which a range is split at a key dividing its load, so that a hot "+
+		"key span isn't bottlenecked on a single range. Zero disables load-based splits.")
+
+	flag.DurationVar(&ctx.RangeCreationInterval, "range-creation-interval", ctx.RangeCreationInterval,
+		"minimum interval (time.Duration) between the creation of ranges on each store, by "+
+			"splits or by adding replicas, so that bulk imports don't starve foreground traffic. "+
+			"Zero imposes no limit.")
+
+	flag.IntVar(&ctx.MaxConcurrentSnapshots, "max-concurrent-snapshots", ctx.MaxConcurrentSnapshots,
+		"number of snapshots each store generates and sends at once. Snapshots to replicas which "+
+			"have fallen behind or restore a range's replication are sent before those for "+
+			"rebalancing. Zero selects the default; a negative value imposes no limit.")
+
+	flag.BoolVar(&ctx.SplitSystemRanges, "split-system-ranges", ctx.SplitSystemRanges,
+		"split ranges at the boundaries of system data, so that range addressing records, "+
+			"config maps and other system data don't share ranges with user data. Applies "+
+			"both to the init command and to the stores of started nodes.")
+
+	flag.Int64Var(&ctx.MaxPendingProposals, "max-pending-proposals", ctx.MaxPendingProposals,
+		"number of write commands awaiting Raft commit at which a store is overloaded and sheds "+
+			"client commands with a retryable error, rather than accepting work until latency "+
+			"collapses. Zero selects the default; a negative value imposes no limit.")
+
+	flag.DurationVar(&ctx.SlowCmdThreshold, "slow-cmd-threshold", ctx.SlowCmdThreshold,
+		"execution time (time.Duration) beyond which stores log a command, along with its trace "+
+			"if its request is traced (see --trace-sample-rate). Zero selects the default; a "+
+			"negative value logs only the commands of traced requests.")
+
+	flag.DurationVar(&ctx.TimeUntilNodeDead, "time-until-node-dead", ctx.TimeUntilNodeDead,
+		"duration (time.Duration) for which a node must have missed its liveness heartbeats "+
+			"before its replicas are replaced on other nodes. Much longer than the liveness "+
+			"threshold, so that brief pauses and restarts don't trigger up-replication. Zero "+
+			"selects the default.")
+
+	// Backup flags.
+
+	flag.StringVar(&ctx.ExportDir, "export-dir", ctx.ExportDir, "directory to which "+
+		"exported key spans, such as the files of backups, are written. It should be shared "+
+		"storage mounted at the same path on every node. Exports fail if it's unset.")
+
+	// Demo flags.
+
+	flag.IntVar(&photosConfig.Users, "photos-users", photosConfig.Users, "number of users "+
+		"the photos demo creates before running its mix of operations.")
+
+	flag.IntVar(&photosConfig.Concurrency, "photos-concurrency", photosConfig.Concurrency,
+		"number of concurrent clients running the photos demo.")
+
+	flag.IntVar(&photosConfig.Ops, "photos-ops", photosConfig.Ops, "number of operations "+
+		"after which the photos demo stops. Zero imposes no limit.")
+
+	flag.DurationVar(&photosConfig.Duration, "photos-duration", photosConfig.Duration,
+		"time (time.Duration) after which the photos demo stops. Zero imposes no limit.")
 }
 
 func init() {
@@ -27,6 +27,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
@@ -40,6 +41,10 @@ import (
 var osExit = os.Exit
 var osStderr = os.Stderr
 
+// kvTimeout is the time after which servers give up on the calls of
+// the key-value commands.
+const kvTimeout = 30 * time.Second
+
 func makeKVClient() *client.KV {
 	transport := &http.Transport{
 		TLSClientConfig: rpc.LoadInsecureTLSConfig().Config(),
@@ -48,6 +53,7 @@ func makeKVClient() *client.KV {
 		util.EnsureHost(Context.Addr), transport))
 	// TODO(pmattis): Initialize this to something more reasonable
 	kv.User = "root"
+	kv.Timeout = kvTimeout
 	return kv
 }
 
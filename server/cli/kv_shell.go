@@ -0,0 +1,487 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+
+	commander "code.google.com/p/go-commander"
+)
+
+var osStdin io.Reader = os.Stdin
+
+const (
+	// kvShellHistoryFile is the file, relative to the home directory,
+	// in which interactive shells keep their history.
+	kvShellHistoryFile = ".cockroach_kv_history"
+	// kvShellHistoryMax is the maximum number of commands kept in the
+	// shell's history.
+	kvShellHistoryMax = 1000
+	// kvShellScanMax is the maximum number of rows returned by scan.
+	kvShellScanMax = 1000
+)
+
+var (
+	errKVShellRollback = errors.New("transaction rolled back")
+	errKVShellRestart  = errors.New("transaction must restart; enter its commands again")
+)
+
+// A kvShellCmd command runs an interactive key/value shell.
+var kvShellCmd = &commander.Command{
+	UsageLine: "kv [options] shell",
+	Short:     "runs an interactive key/value shell",
+	Long: `
+Runs an interactive shell which reads key/value commands, one per
+line, and executes them against the cluster at --addr. Type "help"
+in the shell for a list of commands.
+
+Keys and values may be given as double-quoted Go strings in order to
+include white space or arbitrary bytes, and are displayed the same
+way. Between "begin" and "commit" or "rollback", commands run within
+a transaction as they are entered. An error aborts the transaction,
+as does a conflict which requires the transaction to restart, in
+which case its commands must be entered again.
+
+When reading from a terminal, the shell keeps the history of its
+commands in ~/.cockroach_kv_history. The time taken by each command
+is displayed unless turned off with "timing off".
+`,
+	Run:  runKVShell,
+	Flag: *flag.CommandLine,
+}
+
+// A kvShellCommand describes a command of the shell.
+type kvShellCommand struct {
+	args     string
+	min, max int // Bounds on the number of arguments; max < 0 for no bound
+	help     string
+}
+
+var kvShellCommands = map[string]kvShellCommand{
+	"get":      {"<key>", 1, 1, "fetches the value for a key"},
+	"put":      {"<key> <value>", 2, 2, "sets the value for a key"},
+	"inc":      {"<key> [<amount>]", 1, 2, "increments the value for a key"},
+	"del":      {"<key> [<key2>...]", 1, -1, "deletes the values for keys"},
+	"scan":     {"[<start-key> [<end-key>]]", 0, 2, "scans a range of keys"},
+	"begin":    {"[snapshot]", 0, 1, "begins a serializable or snapshot transaction"},
+	"commit":   {"", 0, 0, "commits the transaction"},
+	"rollback": {"", 0, 0, "aborts the transaction"},
+	"history":  {"", 0, 0, "lists previous commands; !<n> runs command n again"},
+	"timing":   {"[on|off]", 0, 1, "turns display of command timings on or off"},
+	"help":     {"", 0, 0, "lists the commands"},
+	"quit":     {"", 0, 0, "exits the shell; exit does the same"},
+}
+
+// kvShellCommandOrder is the order in which help lists the commands.
+var kvShellCommandOrder = []string{"get", "put", "inc", "del", "scan", "begin", "commit",
+	"rollback", "history", "timing", "help", "quit"}
+
+// A kvShell executes commands read from its input, keeping a history
+// of them and the state of the open transaction, if any.
+type kvShell struct {
+	kv      *client.KV
+	out     io.Writer
+	timing  bool
+	history []string
+	txn     *kvShellTxn
+}
+
+// A kvShellTxn runs the commands of an open transaction within
+// client.KV.RunTransaction, which runs in its own goroutine so that
+// commands can be executed as they are entered.
+type kvShellTxn struct {
+	cmds    chan []string // Commands to execute, ending with commit or rollback
+	results chan error    // Results of each command other than commit or rollback
+	done    chan error    // Result of the transaction
+}
+
+func runKVShell(cmd *commander.Command, args []string) {
+	if len(args) != 1 || args[0] != "shell" {
+		cmd.Usage()
+		return
+	}
+	s := &kvShell{kv: makeKVClient(), out: os.Stdout, timing: true}
+	interactive := isTerminal(osStdin)
+	historyPath := ""
+	if home := os.Getenv("HOME"); interactive && home != "" {
+		historyPath = filepath.Join(home, kvShellHistoryFile)
+		s.loadHistory(historyPath)
+	}
+
+	in := bufio.NewScanner(osStdin)
+	for {
+		if interactive {
+			fmt.Fprint(s.out, s.prompt())
+		}
+		if !in.Scan() {
+			break
+		}
+		line := strings.TrimSpace(in.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !s.handle(line) {
+			break
+		}
+	}
+	if s.txn != nil {
+		fmt.Fprintf(osStderr, "aborting open transaction\n")
+		s.endTxn("rollback")
+	}
+	if historyPath != "" {
+		s.saveHistory(historyPath)
+	}
+}
+
+// isTerminal returns true if r is a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (s *kvShell) prompt() string {
+	if s.txn != nil {
+		return "kv(txn)> "
+	}
+	return "kv> "
+}
+
+// handle executes a line of input and returns false if the shell
+// should exit.
+func (s *kvShell) handle(line string) bool {
+	if strings.HasPrefix(line, "!") {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(s.history) {
+			fmt.Fprintf(osStderr, "no command %s in history\n", line[1:])
+			return true
+		}
+		line = s.history[n-1]
+		fmt.Fprintf(s.out, "%s\n", line)
+	}
+	s.addHistory(line)
+
+	args, err := splitKVShellLine(line)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		return true
+	}
+	name := args[0]
+	c, ok := kvShellCommands[name]
+	if name == "exit" {
+		name, c, ok = "quit", kvShellCommands["quit"], true
+	}
+	if !ok {
+		fmt.Fprintf(osStderr, "unknown command %q; type help for a list of commands\n", name)
+		return true
+	}
+	if n := len(args) - 1; n < c.min || (c.max >= 0 && n > c.max) {
+		fmt.Fprintf(osStderr, "usage: %s %s\n", name, c.args)
+		return true
+	}
+
+	start := time.Now()
+	timed := true
+	switch name {
+	case "quit":
+		return false
+	case "help":
+		s.printHelp()
+		timed = false
+	case "history":
+		for i, h := range s.history {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, h)
+		}
+		timed = false
+	case "timing":
+		err = s.setTiming(args[1:])
+		timed = false
+	case "begin":
+		err = s.beginTxn(args[1:])
+	case "commit", "rollback":
+		err = s.endTxn(name)
+	default:
+		err = s.run(args)
+	}
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s failed: %s\n", name, err)
+	}
+	if timed && s.timing {
+		fmt.Fprintf(s.out, "(%s)\n", time.Now().Sub(start))
+	}
+	return true
+}
+
+func (s *kvShell) printHelp() {
+	for _, name := range kvShellCommandOrder {
+		c := kvShellCommands[name]
+		fmt.Fprintf(s.out, "  %-36s %s\n", strings.TrimSpace(name+" "+c.args), c.help)
+	}
+}
+
+func (s *kvShell) setTiming(args []string) error {
+	if len(args) == 0 {
+		s.timing = !s.timing
+	} else if args[0] == "on" || args[0] == "off" {
+		s.timing = args[0] == "on"
+	} else {
+		return util.Errorf("expected on or off; got %q", args[0])
+	}
+	return nil
+}
+
+// run executes a key/value command, within the open transaction if
+// there is one. An error aborts the transaction.
+func (s *kvShell) run(args []string) error {
+	if s.txn == nil {
+		return s.exec(s.kv, args)
+	}
+	s.txn.cmds <- args
+	if err := <-s.txn.results; err != nil {
+		txnErr := <-s.txn.done
+		s.txn = nil
+		if txnErr == errKVShellRestart {
+			return util.Errorf("%s; %s", err, txnErr)
+		}
+		return util.Errorf("%s; transaction aborted", err)
+	}
+	return nil
+}
+
+// beginTxn opens a transaction, in which subsequent commands run until
+// it is committed or rolled back.
+func (s *kvShell) beginTxn(args []string) error {
+	if s.txn != nil {
+		return util.Errorf("transaction already in progress")
+	}
+	isolation := proto.SERIALIZABLE
+	if len(args) > 0 {
+		if args[0] != "snapshot" {
+			return util.Errorf("unknown isolation %q", args[0])
+		}
+		isolation = proto.SNAPSHOT
+	}
+	t := &kvShellTxn{
+		cmds:    make(chan []string),
+		results: make(chan error),
+		done:    make(chan error, 1),
+	}
+	s.txn = t
+	go func() {
+		var attempts int
+		opts := &client.TransactionOptions{Name: "kv shell", Isolation: isolation}
+		t.done <- s.kv.RunTransaction(opts, func(txn *client.KV) error {
+			// The commands of a restarted transaction can't be replayed, as
+			// their results have already been displayed.
+			if attempts++; attempts > 1 {
+				return errKVShellRestart
+			}
+			for args := range t.cmds {
+				switch args[0] {
+				case "commit":
+					return nil
+				case "rollback":
+					return errKVShellRollback
+				}
+				err := s.exec(txn, args)
+				t.results <- err
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// endTxn commits or rolls back the open transaction.
+func (s *kvShell) endTxn(name string) error {
+	if s.txn == nil {
+		return util.Errorf("no transaction in progress")
+	}
+	s.txn.cmds <- []string{name}
+	err := <-s.txn.done
+	s.txn = nil
+	if err == errKVShellRollback {
+		err = nil
+	}
+	return err
+}
+
+// exec executes a key/value command through kv and displays its
+// result.
+func (s *kvShell) exec(kv *client.KV, args []string) error {
+	name, args := args[0], args[1:]
+	// Do not allow system keys to be modified.
+	var keys []string
+	switch name {
+	case "put", "inc":
+		keys = args[:1]
+	case "del":
+		keys = args
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "\x00") {
+			return util.Errorf("unable to modify system key: %s", proto.Key(key))
+		}
+	}
+
+	switch name {
+	case "get":
+		reply := &proto.GetResponse{}
+		if err := kv.Call(proto.Get, proto.GetArgs(proto.Key(args[0])), reply); err != nil {
+			return err
+		}
+		if reply.Value == nil {
+			fmt.Fprintf(s.out, "%s not found\n", proto.Key(args[0]))
+		} else {
+			fmt.Fprintf(s.out, "%s\n", formatKVShellValue(reply.Value))
+		}
+	case "put":
+		return kv.Call(proto.Put, proto.PutArgs(proto.Key(args[0]), []byte(args[1])), &proto.PutResponse{})
+	case "inc":
+		amount := int64(1)
+		if len(args) > 1 {
+			var err error
+			if amount, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return util.Errorf("invalid increment %s: %s", args[1], err)
+			}
+		}
+		reply := &proto.IncrementResponse{}
+		if err := kv.Call(proto.Increment, proto.IncrementArgs(proto.Key(args[0]), amount), reply); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "%d\n", reply.NewValue)
+	case "del":
+		for _, arg := range args {
+			kv.Prepare(proto.Delete, proto.DeleteArgs(proto.Key(arg)), &proto.DeleteResponse{})
+		}
+		return kv.Flush()
+	case "scan":
+		startKey, endKey := engine.KeySystemMax, proto.KeyMax
+		if len(args) > 0 {
+			startKey = proto.Key(args[0])
+		}
+		if len(args) > 1 {
+			endKey = proto.Key(args[1])
+		}
+		reply := &proto.ScanResponse{}
+		if err := kv.Call(proto.Scan, proto.ScanArgs(startKey, endKey, kvShellScanMax), reply); err != nil {
+			return err
+		}
+		for _, row := range reply.Rows {
+			fmt.Fprintf(s.out, "%s\t%s\n", row.Key, formatKVShellValue(&row.Value))
+		}
+		fmt.Fprintf(s.out, "%d rows\n", len(reply.Rows))
+	default:
+		return util.Errorf("unknown command %q", name)
+	}
+	return nil
+}
+
+// formatKVShellValue formats integer values as decimal numbers and
+// others as quoted strings, like keys.
+func formatKVShellValue(value *proto.Value) string {
+	if value.Integer != nil {
+		return strconv.FormatInt(*value.Integer, 10)
+	}
+	return strconv.Quote(string(value.Bytes))
+}
+
+// splitKVShellLine splits a line into fields separated by white space.
+// A field which begins with a double quote extends to the matching
+// quote and is unquoted as a Go string.
+func splitKVShellLine(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		if line == "" {
+			return fields, nil
+		}
+		if line[0] != '"' {
+			end := strings.IndexFunc(line, unicode.IsSpace)
+			if end == -1 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+			continue
+		}
+		end := 1
+		for ; end < len(line) && line[end] != '"'; end++ {
+			if line[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(line) {
+			return nil, util.Errorf("unterminated quoted string: %s", line)
+		}
+		field, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, util.Errorf("invalid quoted string %s: %s", line[:end+1], err)
+		}
+		fields = append(fields, field)
+		line = line[end+1:]
+	}
+}
+
+// addHistory appends line to the history, dropping the oldest
+// commands beyond kvShellHistoryMax.
+func (s *kvShell) addHistory(line string) {
+	s.history = append(s.history, line)
+	if n := len(s.history); n > kvShellHistoryMax {
+		s.history = append([]string(nil), s.history[n-kvShellHistoryMax:]...)
+	}
+}
+
+func (s *kvShell) loadHistory(path string) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" {
+			s.addHistory(line)
+		}
+	}
+}
+
+func (s *kvShell) saveHistory(path string) {
+	data := strings.Join(s.history, "\n") + "\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		fmt.Fprintf(osStderr, "unable to save history to %s: %s\n", path, err)
+	}
+}
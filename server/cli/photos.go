@@ -0,0 +1,86 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cockroachdb/cockroach/examples/photos"
+
+	commander "code.google.com/p/go-commander"
+)
+
+// photosConfig configures the photos demo.
+var photosConfig = photos.Config{
+	Users:       100,
+	Concurrency: 10,
+	Duration:    time.Minute,
+	Seed:        time.Now().UnixNano(),
+}
+
+// A photosCmd command runs the photos demo workload.
+var photosCmd = &commander.Command{
+	UsageLine: "photos [options]",
+	Short:     "runs the photos demo workload",
+	Long: `
+Runs a demo photo sharing application against the cluster at --addr.
+Users post photos and comment on them, with popular photos attracting
+most comments so that transactions contend, while others list photos
+and comments. Each user's photos are listed through a secondary index
+which is maintained transactionally, together with counts of each
+user's photos and each photo's comments.
+
+The demo runs for --photos-duration or --photos-ops operations, or
+until interrupted, prints the throughput of each operation and then
+validates the consistency of the counts and index with the data.
+See the examples/photos package for the schema.
+`,
+	Run:  runPhotos,
+	Flag: *flag.CommandLine,
+}
+
+func runPhotos(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	db := photos.NewDB(makeKVClient())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	stopper := make(chan struct{})
+	go func() {
+		<-signals
+		close(stopper)
+	}()
+
+	stats, err := photos.Run(db, photosConfig, stopper)
+	if err != nil {
+		fmt.Fprintf(osStderr, "photos demo failed: %s\n", err)
+		osExit(1)
+		return
+	}
+	fmt.Print(stats)
+	if err := photos.Validate(db); err != nil {
+		fmt.Fprintf(osStderr, "photos demo validation failed: %s\n", err)
+		osExit(1)
+		return
+	}
+	fmt.Println("validation succeeded")
+}
@@ -21,10 +21,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	commander "code.google.com/p/go-commander"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	gogoproto "github.com/gogo/protobuf/proto"
 )
 
@@ -143,3 +147,99 @@ func runMergeRange(cmd *commander.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+// A transferLeaseCmd command transfers the leader lease of a range.
+var transferLeaseCmd = &commander.Command{
+	UsageLine: "transfer-lease [options] <key> <store-id>",
+	Short:     "transfers the leader lease of a range",
+	Long: `
+Transfers the leader lease of the range containing <key> to the range's
+replica on store <store-id>.
+`,
+	Run:  runTransferLease,
+	Flag: *flag.CommandLine,
+}
+
+func runTransferLease(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	storeID, err := strconv.ParseInt(args[1], 10, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid store id %q: %s\n", args[1], err)
+		os.Exit(1)
+	}
+
+	kv := makeKVClient()
+	req := &proto.AdminTransferLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key: proto.Key(args[0]),
+		},
+		TargetStoreID: proto.StoreID(storeID),
+	}
+	resp := &proto.AdminTransferLeaseResponse{}
+	if err := kv.Call(proto.AdminTransferLease, req, resp); err != nil {
+		fmt.Fprintf(os.Stderr, "lease transfer failed: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// An unsafeRecoverRangeCmd command rewrites the replica set of a range
+// which has permanently lost its quorum.
+var unsafeRecoverRangeCmd = &commander.Command{
+	UsageLine: "unsafe-recover-range [options] <raft-id> <store-id>[,<store-id>...]",
+	Short:     "forcibly rewrites the replicas of a range (UNSAFE)\n",
+	Long: `
+Rewrites the descriptor of range <raft-id> held in each store specified
+by the -stores flag so that the range's replicas are only those on the
+listed surviving stores, which then form the range's quorum.
+
+THIS IS UNSAFE and is a last resort for a range which has permanently
+lost its quorum: writes to the range may be lost. The node must be
+stopped, and the command must be run with the same surviving stores on
+the node of each of them before any is restarted.
+`,
+	Run:  runUnsafeRecoverRange,
+	Flag: *flag.CommandLine,
+}
+
+func runUnsafeRecoverRange(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	raftID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid raft id %q: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	var survivors []proto.StoreID
+	for _, arg := range strings.Split(args[1], ",") {
+		storeID, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid store id %q: %s\n", arg, err)
+			os.Exit(1)
+		}
+		survivors = append(survivors, proto.StoreID(storeID))
+	}
+
+	if err := Context.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize context: %s\n", err)
+		os.Exit(1)
+	}
+	clock := hlc.NewClock(hlc.UnixNano)
+	for _, e := range Context.Engines {
+		if err := e.Open(); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to open store %s: %s\n", e, err)
+			os.Exit(1)
+		}
+		desc, err := storage.UnsafeRecoverRange(e, raftID, survivors, clock.Now())
+		e.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "recovery of range %d on store %s failed: %s\n", raftID, e, err)
+			os.Exit(1)
+		}
+		fmt.Printf("store %s: %s-%s [%d] replicas=%v\n", e, desc.StartKey, desc.EndKey, desc.RaftID, desc.Replicas)
+	}
+}
@@ -20,6 +20,7 @@ package cli
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -30,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/kv"
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/structured"
 	"github.com/cockroachdb/cockroach/util"
@@ -39,6 +41,10 @@ import (
 // Context is the CLI Context used for the server.
 var Context = server.NewContext()
 
+// checkConfigTimeout is the time allowed to connect to each gossip
+// bootstrap host when checking clock offsets with -check-config.
+const checkConfigTimeout = 10 * time.Second
+
 // An initCmd command initializes a new Cockroach cluster.
 var initCmd = &commander.Command{
 	UsageLine: "init <storage-location>",
@@ -74,7 +80,11 @@ func runInit(cmd *commander.Command, args []string) {
 	clusterID := uuid.New()
 	e := engine.NewRocksDB(proto.Attributes{}, args[0], 1<<20)
 	stopper := util.NewStopper()
-	if _, err := server.BootstrapCluster(clusterID, e, stopper); err != nil {
+	var splitKeys []proto.Key
+	if Context.SplitSystemRanges {
+		splitKeys = storage.SystemSplitKeys
+	}
+	if _, err := server.BootstrapCluster(clusterID, e, splitKeys, stopper); err != nil {
 		log.Errorf("unable to bootstrap cluster: %s", err)
 		return
 	}
@@ -108,6 +118,10 @@ For example:
 
   cockroach start -gossip=host1:port1,host2:port2 -stores=ssd=/mnt/ssd1,ssd=/mnt/ssd2
 
+The configuration is validated before the node joins the cluster. To
+validate the configuration, including the clock offset from the gossip
+bootstrap hosts, without starting the node, specify -check-config.
+
 A node exports an HTTP API with the following endpoints:
 
   Health check:           /healthz
@@ -128,6 +142,24 @@ func runStart(cmd *commander.Command, args []string) {
 	log.Infof("build Time: %s", info.Time)
 	log.Infof("build Deps: %s", info.Deps)
 
+	// Validate the configuration before initializing engines or
+	// joining the cluster.
+	errs := Context.Validate()
+	if checkConfig {
+		errs = append(errs, Context.CheckClockOffsets(checkConfigTimeout)...)
+	}
+	for _, err := range errs {
+		fmt.Fprintf(osStderr, "invalid configuration: %s\n", err)
+	}
+	if len(errs) > 0 {
+		osExit(1)
+		return
+	}
+	if checkConfig {
+		fmt.Println("configuration OK")
+		return
+	}
+
 	// First initialize the Context as it is used in other places.
 	err := Context.Init()
 	if err != nil {
@@ -210,6 +242,12 @@ func runExterminate(cmd *commander.Command, args []string) {
 
 	// Exterminate all data held in specified stores.
 	for _, e := range Context.Engines {
+		switch t := e.(type) {
+		case *engine.EncryptedEngine:
+			e = t.Engine
+		case *engine.UnencryptedEngine:
+			e = t.Engine
+		}
 		if rocksdb, ok := e.(*engine.RocksDB); ok {
 			log.Infof("exterminating data from store %s", e)
 			if err := rocksdb.Destroy(); err != nil {
@@ -120,9 +120,14 @@ The zone config format has the following YAML schema:
   replicas:
     - [comma-separated attribute list]
     - ...
+  constraints: [comma-separated attribute list]
   range_min_bytes: <size-in-bytes>
   range_max_bytes: <size-in-bytes>
 
+The optional constraints are attributes required of every replica,
+pinning the zone's data to stores in a locality. Replicas on stores
+which don't satisfy the constraints are moved.
+
 For example:
 
   replicas:
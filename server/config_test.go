@@ -23,6 +23,10 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
 // TestSetZoneInvalid sets invalid zone configs and verifies error
@@ -68,3 +72,76 @@ range_max_bytes: 67108864
 		}
 	}
 }
+
+// TestZoneConfigAPI verifies getting, putting and deleting zone
+// configs via the Go API.
+func TestZoneConfigAPI(t *testing.T) {
+	s := startTestServer(t)
+	defer s.Stop()
+
+	// The default zone config is written on bootstrap.
+	if zone, err := GetZoneConfig(s.kv, nil); err != nil || zone == nil {
+		t.Fatalf("expected default zone config; got %+v, %v", zone, err)
+	}
+
+	prefix := proto.Key("db1")
+	if zone, err := GetZoneConfig(s.kv, prefix); err != nil || zone != nil {
+		t.Fatalf("expected no zone config; got %+v, %v", zone, err)
+	}
+	if err := PutZoneConfig(s.kv, prefix, &proto.ZoneConfig{}); err == nil {
+		t.Error("expected invalid zone config to be rejected")
+	}
+	zone := &proto.ZoneConfig{
+		ReplicaAttrs:  []proto.Attributes{{Attrs: []string{"dc1", "ssd"}}},
+		RangeMinBytes: 1 << 20,
+		RangeMaxBytes: 64 << 20,
+		GC:            &proto.GCPolicy{TTLSeconds: 3600},
+	}
+	if err := PutZoneConfig(s.kv, prefix, zone); err != nil {
+		t.Fatal(err)
+	}
+	readZone, err := GetZoneConfig(s.kv, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gogoproto.Equal(zone, readZone) {
+		t.Errorf("expected zone config %+v; got %+v", zone, readZone)
+	}
+
+	if err := DeleteZoneConfig(s.kv, nil); err == nil {
+		t.Error("expected deletion of default zone config to fail")
+	}
+	if err := DeleteZoneConfig(s.kv, prefix); err != nil {
+		t.Fatal(err)
+	}
+	if zone, err := GetZoneConfig(s.kv, prefix); err != nil || zone != nil {
+		t.Errorf("expected zone config to be deleted; got %+v, %v", zone, err)
+	}
+}
+
+// TestGetAcctUsage verifies that the usage rollups of all stores for
+// an accounting prefix are summed.
+func TestGetAcctUsage(t *testing.T) {
+	s := startTestServer(t)
+	defer s.Stop()
+
+	prefix := proto.Key("db1")
+	rollups := []*proto.AcctUsage{
+		{StoreID: 1, Prefix: prefix, UpdatedAt: 20, RangeCount: 1, LiveBytes: 100, KeyCount: 2},
+		{StoreID: 2, Prefix: prefix, UpdatedAt: 10, RangeCount: 2, LiveBytes: 50, KeyCount: 3},
+		// A rollup for a different prefix isn't included.
+		{StoreID: 1, Prefix: proto.Key("db10"), UpdatedAt: 5, RangeCount: 4, LiveBytes: 10, KeyCount: 1},
+	}
+	for _, u := range rollups {
+		if err := s.kv.PutProto(engine.AcctUsageKey(u.Prefix, int32(u.StoreID)), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usage, err := GetAcctUsage(s.kv, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UpdatedAt != 10 || usage.RangeCount != 3 || usage.LiveBytes != 150 || usage.KeyCount != 5 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
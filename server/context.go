@@ -20,6 +20,7 @@ package server
 import (
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -60,7 +62,11 @@ type Context struct {
 	// in-memory store. Device attributes typically include whether the store is
 	// flash (ssd), spinny disk (hdd), fusion-io (fio), in-memory (mem); device
 	// attributes might also include speeds and other specs (7200rpm, 200kiops, etc.).
-	// For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01,ssd=/mnt/ssd02,mem=1073741824
+	// A filepath may be suffixed with :<max bytes> to limit the capacity of the
+	// store to part of the disk, and then with semicolon-separated RocksDB
+	// options overriding those of the flags for the store: write-buffer-size,
+	// compression and bloom-filter-bits.
+	// For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01:107374182400;compression=lz4,ssd=/mnt/ssd02,mem=1073741824
 	Stores string
 
 	// Attrs specifies a colon-separated list of node topography or machine
@@ -84,10 +90,107 @@ type Context struct {
 	// node clocks have necessarily passed it.
 	Linearizable bool
 
+	// TraceSampleRate is the fraction of the requests coordinated by
+	// this node which are assigned a trace ID, so that the stores
+	// executing them log the spans of their execution. Zero traces
+	// only requests which arrive with a trace ID.
+	TraceSampleRate float64
+
 	// CacheSize is the amount of memory in bytes to use for caching data.
 	// The value is split evenly between the stores if there are more than one.
 	CacheSize int64
 
+	// SyncWrites, if true, syncs each RocksDB store's write-ahead log to
+	// disk before acknowledging writes. Otherwise, writes acknowledged
+	// shortly before a machine failure may be lost.
+	SyncWrites bool
+
+	// WriteBufferSize is the size in bytes of each RocksDB store's
+	// memtable before it's flushed to disk. Zero selects the default.
+	WriteBufferSize int64
+
+	// MaxBackgroundCompactions is the number of threads which run the
+	// compactions of the node's RocksDB stores. The threads are shared
+	// by all the node's stores, any of which may use all of them. Zero
+	// selects the default.
+	MaxBackgroundCompactions int
+
+	// Compression is the name of the algorithm with which RocksDB
+	// stores compress their blocks: snappy, none, zlib or lz4. Empty
+	// selects snappy.
+	Compression string
+
+	// BloomFilterBits is the number of bits per key of the bloom filter
+	// of each RocksDB table. Zero disables the bloom filters.
+	BloomFilterBits int
+
+	// EncryptionKeyFile, if set, is the path of a file holding the keys
+	// with which the values of each store are encrypted at rest, one
+	// per line as the key's ID followed by the hex-encoded AES key. The
+	// key with the highest ID encrypts new values; keys are rotated by
+	// appending a key with a higher ID. A key may be removed once the
+	// values it encrypted have been rewritten in the background. Keys
+	// of the stores are not encrypted. Encryption must be enabled when
+	// a store is created.
+	EncryptionKeyFile string
+
+	// MemoryBudget is the Go heap size in bytes beyond which the memory
+	// watchdog sheds in-flight scans and snapshots. Zero disables the
+	// watchdog.
+	MemoryBudget int64
+
+	// SplitQPS is the request rate above which a range is split to
+	// spread its load. Zero disables load-based splits.
+	SplitQPS float64
+
+	// RangeCreationInterval is the minimum interval between the
+	// creation of ranges on each store, by splits or by adding
+	// replicas. It keeps bulk imports from starving foreground
+	// traffic. Zero imposes no limit.
+	RangeCreationInterval time.Duration
+
+	// MaxConcurrentSnapshots is the number of snapshots each store
+	// generates and sends at once, with recovery snapshots admitted
+	// before rebalance snapshots. Zero selects the default; a negative
+	// value imposes no limit.
+	MaxConcurrentSnapshots int
+
+	// SplitSystemRanges specifies whether ranges are split at the
+	// boundaries of system data (see storage.SystemSplitKeys), so that
+	// range addressing records, config maps and other system data don't
+	// share ranges with user data. Applies both when bootstrapping a
+	// cluster and to the split queues of the node's stores.
+	SplitSystemRanges bool
+
+	// MaxPendingProposals is the number of write commands awaiting
+	// Raft commit at which a store is overloaded and sheds client
+	// commands with a retryable error. Zero selects the default; a
+	// negative value imposes no limit.
+	MaxPendingProposals int64
+
+	// SlowCmdThreshold is the execution time beyond which stores log
+	// a command, along with its trace if its request is traced. Zero
+	// selects the default; a negative value logs only the commands of
+	// traced requests.
+	SlowCmdThreshold time.Duration
+
+	// TimeUntilNodeDead is the duration for which a node's liveness
+	// record must have been expired before its replicas are replaced
+	// on other nodes. Zero selects the default.
+	TimeUntilNodeDead time.Duration
+
+	// ExportDir, if set, is the directory, typically a mount of shared
+	// storage, to which the stores of the node write exported key spans,
+	// such as the files of backups.
+	ExportDir string
+
+	// Authorizer, if set, is consulted to authorize every command, both
+	// at the gateway and at the store executing it. It is not settable
+	// via flags; it allows external policy systems to be registered
+	// when constructing a server. Defaults to authorizing commands
+	// according to the permission configs.
+	Authorizer storage.Authorizer
+
 	// Parsed values.
 
 	// Engines is the storage instances specified by Stores.
@@ -108,6 +211,8 @@ func NewContext() *Context {
 		MaxOffset:      defaultMaxOffset,
 		GossipInterval: defaultGossipInterval,
 		CacheSize:      defaultCacheSize,
+
+		SplitSystemRanges: true,
 	}
 }
 
@@ -115,27 +220,38 @@ func NewContext() *Context {
 // engine.Engine objects, parses node attributes, and initializes
 // the gossip bootstrap resolvers.
 func (ctx *Context) Init() error {
-	var err error
-	storesRE := regexp.MustCompile(`([^=]+)=([^,]+)(,|$)`)
-	// Error if regexp doesn't match.
-	storeSpecs := storesRE.FindAllStringSubmatch(ctx.Stores, -1)
-	if storeSpecs == nil || len(storeSpecs) == 0 {
-		return fmt.Errorf("invalid or empty engines specification %q, "+
-			"did you specify -stores?", ctx.Stores)
+	storeSpecs, err := ctx.parseStoreSpecs()
+	if err != nil {
+		return err
+	}
+
+	var keys *engine.EncryptionKeys
+	if ctx.EncryptionKeyFile != "" {
+		if keys, err = readEncryptionKeys(ctx.EncryptionKeyFile); err != nil {
+			return err
+		}
+	}
+
+	// The compaction threads are shared by all stores, and are sized
+	// once for the process.
+	if ctx.MaxBackgroundCompactions > 0 {
+		engine.SetCompactionThreads(ctx.MaxBackgroundCompactions)
 	}
 
 	ctx.Engines = nil
 	for _, store := range storeSpecs {
-		if len(store) != 4 {
-			return util.Errorf("unable to parse attributes and path from store %q", store[0])
-		}
 		// There are two matches for each store specification: the colon-separated
 		// list of attributes and the path.
-		engine, err := ctx.initEngine(store[1], store[2])
+		e, err := ctx.initEngine(store[1], store[2])
 		if err != nil {
 			return util.Errorf("unable to init engine for store %q: %s", store[0], err)
 		}
-		ctx.Engines = append(ctx.Engines, engine)
+		if keys != nil {
+			e = engine.NewEncryptedEngine(e, keys)
+		} else {
+			e = engine.NewUnencryptedEngine(e)
+		}
+		ctx.Engines = append(ctx.Engines, e)
 	}
 	log.Infof("initialized %d storage engine(s)", len(ctx.Engines))
 
@@ -153,13 +269,46 @@ func (ctx *Context) Init() error {
 	return nil
 }
 
+// storesRE matches each store specification in Stores.
+var storesRE = regexp.MustCompile(`([^=]+)=([^,]+)(,|$)`)
+
+// parseStoreSpecs splits Stores into store specifications. Each
+// specification holds the full match, the colon-separated list of
+// attributes and the path or in-memory capacity.
+func (ctx *Context) parseStoreSpecs() ([][]string, error) {
+	// Error if regexp doesn't match.
+	storeSpecs := storesRE.FindAllStringSubmatch(ctx.Stores, -1)
+	if storeSpecs == nil || len(storeSpecs) == 0 {
+		return nil, fmt.Errorf("invalid or empty engines specification %q, "+
+			"did you specify -stores?", ctx.Stores)
+	}
+	for _, store := range storeSpecs {
+		if len(store) != 4 {
+			return nil, util.Errorf("unable to parse attributes and path from store %q", store[0])
+		}
+	}
+	return storeSpecs, nil
+}
+
 // initEngine parses the store attributes as a colon-separated list
 // and instantiates an engine based on the dir parameter. If dir parses
 // to an integer, it's taken to mean an in-memory engine; otherwise,
-// dir is treated as a path and a RocksDB engine is created.
+// dir is treated as a path and a RocksDB engine is created. The path
+// may be suffixed with ":<max bytes>" to limit the capacity of the
+// store to part of the disk. RocksDB engines are tuned according to
+// the context's RocksDB options, which may be overridden for the store
+// by semicolon-separated options following the path; see
+// parseStoreOptions.
 func (ctx *Context) initEngine(attrsStr, path string) (engine.Engine, error) {
 	attrs := parseAttributes(attrsStr)
+	var storeOpts []string
+	if i := strings.Index(path, ";"); i != -1 {
+		path, storeOpts = path[:i], strings.Split(path[i+1:], ";")
+	}
 	if size, err := strconv.ParseUint(path, 10, 64); err == nil {
+		if len(storeOpts) > 0 {
+			return nil, util.Errorf("RocksDB options can't be specified for in-memory stores")
+		}
 		if size == 0 {
 			return nil, util.Errorf("unable to initialize an in-memory store with capacity 0")
 		}
@@ -167,7 +316,81 @@ func (ctx *Context) initEngine(attrsStr, path string) (engine.Engine, error) {
 		// TODO(spencer): should be using rocksdb for in-memory stores and
 		// relegate the InMem engine to usage only from unittests.
 	}
-	return engine.NewRocksDB(attrs, path, ctx.CacheSize), nil
+	var maxSize uint64
+	if i := strings.LastIndex(path, ":"); i != -1 {
+		if size, err := strconv.ParseUint(path[i+1:], 10, 64); err == nil {
+			if size == 0 {
+				return nil, util.Errorf("unable to limit store at %q to capacity 0", path[:i])
+			}
+			path, maxSize = path[:i], size
+		}
+	}
+	opts := engine.RocksDBOptions{
+		WriteBufferSize:          ctx.WriteBufferSize,
+		MaxBackgroundCompactions: ctx.MaxBackgroundCompactions,
+		BloomFilterBitsPerKey:    ctx.BloomFilterBits,
+	}
+	if ctx.Compression != "" {
+		var err error
+		if opts.Compression, err = engine.ParseCompression(ctx.Compression); err != nil {
+			return nil, err
+		}
+	}
+	if err := parseStoreOptions(&opts, storeOpts); err != nil {
+		return nil, util.Errorf("invalid options for store at %q: %s", path, err)
+	}
+	eng := engine.NewRocksDB(attrs, path, ctx.CacheSize)
+	eng.SetOptions(opts)
+	eng.SetMaxSize(int64(maxSize))
+	if ctx.SyncWrites {
+		eng.SetDurability(engine.DurabilitySync)
+	}
+	return eng, nil
+}
+
+// parseStoreOptions overrides opts with the options of a store
+// specification, each formatted as <name>=<value>. The options are
+// named after the flags setting them for every store:
+// write-buffer-size, compression and bloom-filter-bits. The compaction
+// threads are shared by all stores, so max-background-compactions may
+// only be set for the node.
+func parseStoreOptions(opts *engine.RocksDBOptions, storeOpts []string) error {
+	for _, opt := range storeOpts {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return util.Errorf("option %q isn't of the form <name>=<value>", opt)
+		}
+		var err error
+		switch kv[0] {
+		case "write-buffer-size":
+			opts.WriteBufferSize, err = strconv.ParseInt(kv[1], 10, 64)
+		case "compression":
+			opts.Compression, err = engine.ParseCompression(kv[1])
+		case "bloom-filter-bits":
+			opts.BloomFilterBitsPerKey, err = strconv.Atoi(kv[1])
+		default:
+			return util.Errorf("unknown store option %q", kv[0])
+		}
+		if err != nil {
+			return util.Errorf("invalid value for store option %q: %s", kv[0], err)
+		}
+	}
+	return nil
+}
+
+// readEncryptionKeys reads the encryption keys of the stores from the
+// named file.
+func readEncryptionKeys(path string) (*engine.EncryptionKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, util.Errorf("unable to read encryption keys: %s", err)
+	}
+	defer f.Close()
+	keys, err := engine.ParseEncryptionKeys(f)
+	if err != nil {
+		return nil, util.Errorf("invalid encryption keys in %s: %s", path, err)
+	}
+	return keys, nil
 }
 
 // parseGossipBootstrapResolvers parses a comma-separated list of
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/storage/engine"
 )
 
 func TestParseNodeAttributes(t *testing.T) {
@@ -55,3 +56,41 @@ func TestParseGossipBootstrapAddrs(t *testing.T) {
 		t.Fatalf("Unexpected bootstrap addresses: %v, expected: %v", ctx.GossipBootstrapResolvers, expected)
 	}
 }
+
+// TestParseStoreOptions verifies that the RocksDB options of a store
+// specification override those of the flags, and that invalid options
+// are rejected.
+func TestParseStoreOptions(t *testing.T) {
+	defaults := engine.RocksDBOptions{WriteBufferSize: 1 << 20, Compression: engine.CompressionSnappy}
+	opts := defaults
+	if err := parseStoreOptions(&opts, []string{"compression=lz4", "bloom-filter-bits=10"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := engine.RocksDBOptions{
+		WriteBufferSize:       1 << 20,
+		Compression:           engine.CompressionLZ4,
+		BloomFilterBitsPerKey: 10,
+	}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected options %+v; got %+v", expected, opts)
+	}
+
+	for _, storeOpts := range [][]string{
+		{"compression"},
+		{"compression=gzip"},
+		{"write-buffer-size=big"},
+		{"max-background-compactions=4"},
+	} {
+		opts := defaults
+		if err := parseStoreOptions(&opts, storeOpts); err == nil {
+			t.Errorf("expected options %q to be rejected", storeOpts)
+		}
+	}
+
+	ctx := NewContext()
+	ctx.Stores = "mem=1;compression=lz4"
+	ctx.GossipBootstrap = "self://"
+	if err := ctx.Init(); err == nil {
+		t.Error("expected options of an in-memory store to be rejected")
+	}
+}
@@ -19,8 +19,12 @@ package server
 
 import (
 	"container/list"
+	"fmt"
+	"math"
+	"math/rand"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/cockroach/client"
@@ -34,6 +38,7 @@ import (
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
 const (
@@ -44,8 +49,31 @@ const (
 	gossipInterval = 1 * time.Minute
 	// ttlNodeIDGossip is time-to-live for node ID -> address.
 	ttlNodeIDGossip = 0 * time.Second
+	// throttleCheckInterval is the interval at which the node checks
+	// whether its stores' throttled state has changed, in which case
+	// store capacities are gossiped immediately.
+	throttleCheckInterval = 5 * time.Second
 )
 
+// A NodeThrottledError indicates that a node pushed back on a request
+// because all of its stores are throttled. The error is retryable so
+// that clients back off and give healthy nodes a chance to absorb
+// the load.
+type NodeThrottledError struct {
+	NodeID   proto.NodeID
+	Severity float64
+}
+
+// Error formats error.
+func (e *NodeThrottledError) Error() string {
+	return fmt.Sprintf("node %d is throttled with severity %.2f", e.NodeID, e.Severity)
+}
+
+// CanRetry implements the util.Retryable interface.
+func (e *NodeThrottledError) CanRetry() bool {
+	return true
+}
+
 // A Node manages a map of stores (by store ID) for which it serves
 // traffic. A node is the top-level data structure. There is one node
 // instance per process. A node accepts incoming RPCs and services
@@ -63,6 +91,7 @@ type Node struct {
 	db            *client.KV             // KV DB client; used to access global id generators
 	raftTransport multiraft.Transport
 	lSender       *kv.LocalSender // Local KV sender for access to node-local stores
+	stopper       *util.Stopper
 }
 
 // allocateNodeID increments the node id generator key to allocate
@@ -100,11 +129,13 @@ func allocateStoreIDs(nodeID proto.NodeID, inc int64, db *client.KV) (proto.Stor
 
 // BootstrapCluster bootstraps a store using the provided engine and
 // cluster ID. The bootstrapped store contains a single range spanning
-// all keys. Initial range lookup metadata is populated for the range.
+// all keys, which is then split at each of splitKeys, such as
+// storage.SystemSplitKeys. Initial range lookup metadata is populated
+// for the range.
 //
 // Returns a KV client for unittest purposes. Caller should close
 // the returned client.
-func BootstrapCluster(clusterID string, eng engine.Engine, stopper *util.Stopper) (*client.KV, error) {
+func BootstrapCluster(clusterID string, eng engine.Engine, splitKeys []proto.Key, stopper *util.Stopper) (*client.KV, error) {
 	sIdent := proto.StoreIdent{
 		ClusterID: clusterID,
 		NodeID:    1,
@@ -148,6 +179,17 @@ func BootstrapCluster(clusterID string, eng engine.Engine, stopper *util.Stopper
 			sIdent.StoreID, storeID, err)
 	}
 
+	for _, splitKey := range splitKeys {
+		req := &proto.AdminSplitRequest{
+			RequestHeader: proto.RequestHeader{Key: splitKey, User: storage.UserRoot},
+			SplitKey:      splitKey,
+			Reason:        "bootstrap split key",
+		}
+		if err := localDB.Call(proto.AdminSplit, req, &proto.AdminSplitResponse{}); err != nil {
+			return nil, util.Errorf("unable to split bootstrap range at key %q: %s", splitKey, err)
+		}
+	}
+
 	return localDB, nil
 }
 
@@ -217,6 +259,7 @@ func (n *Node) initNodeID(id proto.NodeID) {
 // engine. Launches periodic store gossiping in a goroutine.
 func (n *Node) start(rpcServer *rpc.Server, clock *hlc.Clock,
 	engines []engine.Engine, attrs proto.Attributes, stopper *util.Stopper) error {
+	n.stopper = stopper
 	n.initDescriptor(rpcServer.Addr(), attrs)
 	if err := rpcServer.RegisterName("Node", n); err != nil {
 		log.Fatalf("unable to register node service with RPC server: %s", err)
@@ -227,6 +270,13 @@ func (n *Node) start(rpcServer *rpc.Server, clock *hlc.Clock,
 		return err
 	}
 	n.startGossip(stopper)
+	// The node's stores are drained before the stopper refuses the
+	// node's commands and stops Raft, and so before the Raft transport
+	// and the engines are closed.
+	stopper.AddDrainer(n)
+	if n.storeConfig.NodeLiveness != nil {
+		n.storeConfig.NodeLiveness.Start(n.Descriptor.NodeID, stopper)
+	}
 	log.Infof("Started node with %v engine(s) and attributes %v", engines, attrs.Attrs)
 	return nil
 }
@@ -377,7 +427,13 @@ func (n *Node) connectGossip() {
 // information. Starts a goroutine to loop until the node is closed.
 func (n *Node) startGossip(stopper *util.Stopper) {
 	stopper.RunWorker(func() {
-		ticker := time.NewTicker(gossipInterval)
+		ticker := util.NewTicker(gossipInterval)
+		throttleTicker := util.NewTicker(throttleCheckInterval)
+		defer throttleTicker.Stop()
+		// Gossip the store descriptors right away, as other nodes accept
+		// raft messages from our stores only once they've received them.
+		n.gossipCapacities()
+		var throttled bool
 		for {
 			select {
 			case <-ticker.C:
@@ -385,6 +441,14 @@ func (n *Node) startGossip(stopper *util.Stopper) {
 					n.gossipCapacities()
 					stopper.FinishTask()
 				}
+			case <-throttleTicker.C:
+				// Gossip promptly when the node becomes throttled or
+				// recovers, rather than waiting for the next interval.
+				if t := n.throttleSeverity() > 0; t != throttled && stopper.StartTask() {
+					throttled = t
+					n.gossipCapacities()
+					stopper.FinishTask()
+				}
 			case <-stopper.ShouldStop():
 				return
 			}
@@ -401,14 +465,101 @@ func (n *Node) gossipCapacities() {
 	})
 }
 
-// executeCmd creates a client.Call struct and sends if via our local sender.
+// throttleSeverity returns the least severity with which the node's
+// stores are throttled. The result is non-zero only if all local
+// stores are throttled.
+func (n *Node) throttleSeverity() float64 {
+	severity := math.Inf(1)
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		severity = math.Min(severity, s.ThrottleSeverity())
+		return nil
+	})
+	if math.IsInf(severity, 1) {
+		return 0
+	}
+	return severity
+}
+
+// checkThrottle returns a NodeThrottledError with probability equal
+// to the given throttle severity, so that the fraction of requests
+// pushed back is proportional to how badly the node is throttled.
+func (n *Node) checkThrottle(severity float64) error {
+	if severity > 0 && rand.Float64() < severity {
+		return &NodeThrottledError{NodeID: n.Descriptor.NodeID, Severity: severity}
+	}
+	return nil
+}
+
+// throttleExempt returns true if the command is never pushed back by
+// throttling: internal commands, such as intent resolution, GC and
+// leader lease requests, which throttled stores need in order to
+// recover; admin commands; and commands addressing system keys, such
+// as range lookups and config updates, which the rest of the cluster
+// depends on.
+func throttleExempt(method string, args proto.Request) bool {
+	return proto.IsInternal(method) || proto.IsAdmin(method) ||
+		args.Header().Key.Less(engine.KeySystemMax)
+}
+
+// Drain drains the node's stores in parallel while the node is still
+// running, moving their leader leases to other nodes before the node
+// stops. See storage.Store.Drain. Drain implements util.Drainer.
+func (n *Node) Drain() {
+	var wg sync.WaitGroup
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Drain()
+		}()
+		return nil
+	})
+	wg.Wait()
+}
+
+// executeCmd creates a client.Call struct and sends if via our local
+// sender. If all local stores are throttled, the command may instead
+// be pushed back with a retryable error unless it's exempt; see
+// throttleExempt.
+//
+// The command runs under a stopper task, but the wait for it is cut
+// short once the stopper drains: a command awaiting Raft, which may
+// never commit it if its range has lost quorum, must not keep the node
+// from stopping. The command is sent with a copy of the reply, as it
+// may still complete after it's been given up on.
 func (n *Node) executeCmd(method string, args proto.Request, reply proto.Response) error {
+	// Refuse commands once the node is stopping, so that none is
+	// executed while Raft stops and the engines close. The node's
+	// leader leases have been transferred by then, so that commands are
+	// normally sent to other replicas.
+	if !n.stopper.StartTask() {
+		reply.Header().SetGoError(util.Errorf("node %d is stopping", n.Descriptor.NodeID))
+		return nil
+	}
+	defer n.stopper.FinishTask()
+	if !throttleExempt(method, args) {
+		if err := n.checkThrottle(n.throttleSeverity()); err != nil {
+			reply.Header().SetGoError(err)
+			return nil
+		}
+	}
 	call := &client.Call{
 		Method: method,
 		Args:   args,
-		Reply:  reply,
+		Reply:  gogoproto.Clone(reply).(proto.Response),
+	}
+	done := make(chan struct{})
+	go func() {
+		n.lSender.Send(call)
+		close(done)
+	}()
+	select {
+	case <-done:
+		reply.Reset()
+		gogoproto.Merge(reply, call.Reply)
+	case <-n.stopper.ShouldDrain():
+		reply.Header().SetGoError(util.Errorf("node %d is stopping", n.Descriptor.NodeID))
 	}
-	n.lSender.Send(call)
 	return nil
 }
 
@@ -484,6 +635,11 @@ func (n *Node) AdminMerge(args *proto.AdminMergeRequest, reply *proto.AdminMerge
 	return n.executeCmd(proto.AdminMerge, args, reply)
 }
 
+// AdminTransferLease .
+func (n *Node) AdminTransferLease(args *proto.AdminTransferLeaseRequest, reply *proto.AdminTransferLeaseResponse) error {
+	return n.executeCmd(proto.AdminTransferLease, args, reply)
+}
+
 // InternalRangeLookup .
 func (n *Node) InternalRangeLookup(args *proto.InternalRangeLookupRequest, reply *proto.InternalRangeLookupResponse) error {
 	return n.executeCmd(proto.InternalRangeLookup, args, reply)
@@ -514,7 +670,27 @@ func (n *Node) InternalMerge(args *proto.InternalMergeRequest, reply *proto.Inte
 	return n.executeCmd(proto.InternalMerge, args, reply)
 }
 
+// InternalChangeReplicas .
+func (n *Node) InternalChangeReplicas(args *proto.InternalChangeReplicasRequest, reply *proto.InternalChangeReplicasResponse) error {
+	return n.executeCmd(proto.InternalChangeReplicas, args, reply)
+}
+
 // InternalTruncateLog .
 func (n *Node) InternalTruncateLog(args *proto.InternalTruncateLogRequest, reply *proto.InternalTruncateLogResponse) error {
 	return n.executeCmd(proto.InternalTruncateLog, args, reply)
 }
+
+// InternalRecomputeStats .
+func (n *Node) InternalRecomputeStats(args *proto.InternalRecomputeStatsRequest, reply *proto.InternalRecomputeStatsResponse) error {
+	return n.executeCmd(proto.InternalRecomputeStats, args, reply)
+}
+
+// InternalExport .
+func (n *Node) InternalExport(args *proto.InternalExportRequest, reply *proto.InternalExportResponse) error {
+	return n.executeCmd(proto.InternalExport, args, reply)
+}
+
+// InternalImport .
+func (n *Node) InternalImport(args *proto.InternalImportRequest, reply *proto.InternalImportResponse) error {
+	return n.executeCmd(proto.InternalImport, args, reply)
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
 // createTestNode creates an rpc server using the specified address,
@@ -94,7 +95,7 @@ func formatKeys(keys []proto.Key) string {
 func TestBootstrapCluster(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	localDB, err := BootstrapCluster("cluster-1", e, stopper)
+	localDB, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -135,12 +136,51 @@ func TestBootstrapCluster(t *testing.T) {
 	// TODO(spencer): check values.
 }
 
+// TestBootstrapClusterSystemSplitKeys verifies that bootstrapping a
+// cluster with the system split keys leaves a range beginning at each
+// of them.
+func TestBootstrapClusterSystemSplitKeys(t *testing.T) {
+	stopper := util.NewStopper()
+	e := engine.NewInMem(proto.Attributes{}, 1<<20)
+	localDB, err := BootstrapCluster("cluster-1", e, storage.SystemSplitKeys, stopper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopper.Stop()
+
+	// Scan the range addressing records, which all lie in the first range.
+	sr := &proto.ScanResponse{}
+	if err := localDB.Call(proto.Scan, &proto.ScanRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:    engine.KeyMeta2Prefix,
+			EndKey: engine.KeyMetaMax,
+			User:   storage.UserRoot,
+		},
+		MaxResults: math.MaxInt64,
+	}, sr); err != nil {
+		t.Fatal(err)
+	}
+	startKeys := map[string]struct{}{}
+	for _, kv := range sr.Rows {
+		desc := &proto.RangeDescriptor{}
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, desc); err != nil {
+			t.Fatal(err)
+		}
+		startKeys[string(desc.StartKey)] = struct{}{}
+	}
+	for _, key := range storage.SystemSplitKeys {
+		if _, ok := startKeys[string(key)]; !ok {
+			t.Errorf("expected a range beginning at split key %q", key)
+		}
+	}
+}
+
 // TestBootstrapNewStore starts a cluster with two unbootstrapped
 // stores and verifies both stores are added and started.
 func TestBootstrapNewStore(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -179,7 +219,7 @@ func TestBootstrapNewStore(t *testing.T) {
 func TestNodeJoin(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -228,7 +268,7 @@ func TestNodeJoin(t *testing.T) {
 func TestCorruptedClusterID(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -251,3 +291,52 @@ func TestCorruptedClusterID(t *testing.T) {
 	}
 	stopper.Stop()
 }
+
+// TestNodeCheckThrottle verifies that a node pushes back on requests
+// with a retryable error when throttled at full severity and never
+// when healthy.
+func TestNodeCheckThrottle(t *testing.T) {
+	n := &Node{Descriptor: storage.NodeDescriptor{NodeID: 1}}
+	for i := 0; i < 100; i++ {
+		if err := n.checkThrottle(0); err != nil {
+			t.Fatalf("unexpected pushback from healthy node: %s", err)
+		}
+	}
+	err := n.checkThrottle(1)
+	if err == nil {
+		t.Fatal("expected pushback from fully throttled node")
+	}
+	reply := &proto.GetResponse{}
+	reply.Header().SetGoError(err)
+	if !reply.Header().Error.Retryable {
+		t.Errorf("expected throttling error to be retryable: %+v", reply.Header().Error)
+	}
+}
+
+// TestNodeThrottleExempt verifies that internal and admin commands
+// and commands addressing system keys are exempt from throttling.
+func TestNodeThrottleExempt(t *testing.T) {
+	testCases := []struct {
+		method string
+		key    proto.Key
+		exempt bool
+	}{
+		{proto.Put, proto.Key("a"), false},
+		{proto.Scan, proto.Key("a"), false},
+		{proto.InternalResolveIntent, proto.Key("a"), true},
+		{proto.InternalGC, proto.Key("a"), true},
+		{proto.AdminSplit, proto.Key("a"), true},
+		{proto.InternalRangeLookup, engine.RangeMetaKey(proto.Key("a")), true},
+		{proto.Put, engine.MakeKey(engine.KeyConfigZonePrefix, proto.Key("db")), true},
+	}
+	for i, test := range testCases {
+		args, err := proto.CreateArgs(test.method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args.Header().Key = test.key
+		if exempt := throttleExempt(test.method, args); exempt != test.exempt {
+			t.Errorf("%d: expected %s of %q exempt=%t; got %t", i, test.method, test.key, test.exempt, exempt)
+		}
+	}
+}
@@ -19,7 +19,10 @@
 package server
 
 import (
+	"crypto/x509"
+	"net"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/multiraft"
@@ -33,6 +36,10 @@ import (
 const (
 	raftServiceName = "MultiRaft"
 	raftMessageName = raftServiceName + ".RaftMessage"
+
+	// resolvedHostTTL is the time for which the addresses a host name
+	// resolves to are cached when authenticating raft messages.
+	resolvedHostTTL = time.Minute
 )
 
 // rpcTransport handles the rpc messages for multiraft.
@@ -40,6 +47,7 @@ type rpcTransport struct {
 	gossip     *gossip.Gossip
 	rpcServer  *rpc.Server
 	rpcContext *rpc.Context
+	hosts      *hostCache
 	mu         sync.Mutex
 	servers    map[multiraft.NodeID]multiraft.ServerInterface
 }
@@ -51,6 +59,7 @@ func newRPCTransport(gossip *gossip.Gossip, rpcServer *rpc.Server, rpcContext *r
 		gossip:     gossip,
 		rpcServer:  rpcServer,
 		rpcContext: rpcContext,
+		hosts:      newHostCache(resolvedHostTTL, net.LookupIP),
 		servers:    make(map[multiraft.NodeID]multiraft.ServerInterface),
 	}
 
@@ -66,14 +75,35 @@ func newRPCTransport(gossip *gossip.Gossip, rpcServer *rpc.Server, rpcContext *r
 // (which net/rpc finds via reflection) from others.
 type transportRPCServer rpcTransport
 
-// RaftMessage proxies the incoming request to the listening server interface.
-func (t *transportRPCServer) RaftMessage(protoReq *proto.RaftMessageRequest,
+// A PeerRaftMessageRequest is a RaftMessageRequest which is told the
+// address of the peer which sent it by the RPC server.
+type PeerRaftMessageRequest struct {
+	proto.RaftMessageRequest
+	peer net.Addr
+}
+
+// SetPeer implements the rpc.PeerRequest interface. The peer's
+// certificates have already been verified against the cluster CA
+// during the TLS handshake.
+func (r *PeerRaftMessageRequest) SetPeer(addr net.Addr, _ []*x509.Certificate) {
+	r.peer = addr
+}
+
+// RaftMessage proxies the incoming request to the listening server
+// interface. Messages (including snapshots) are rejected unless they
+// carry this node's cluster ID and were sent by the node to which the
+// sending store belongs.
+func (t *transportRPCServer) RaftMessage(protoReq *PeerRaftMessageRequest,
 	resp *proto.RaftMessageResponse) error {
 	// Convert from proto to internal formats.
 	req := &multiraft.RaftMessageRequest{GroupID: protoReq.GroupID}
 	if err := req.Message.Unmarshal(protoReq.Msg); err != nil {
 		return err
 	}
+	if err := (*rpcTransport)(t).authenticate(protoReq, req); err != nil {
+		log.Warningf("rejected raft message from %s: %s", protoReq.peer, err)
+		return err
+	}
 
 	t.mu.Lock()
 	server, ok := t.servers[multiraft.NodeID(req.Message.To)]
@@ -86,6 +116,125 @@ func (t *transportRPCServer) RaftMessage(protoReq *proto.RaftMessageRequest,
 	return util.Errorf("Unable to proxy message to node: %d", req.Message.To)
 }
 
+// authenticate verifies that the message was sent by a member of this
+// node's cluster and that the sending store belongs to the peer which
+// sent it. Node certificates don't identify individual nodes, so the
+// peer is matched against the gossiped address of the store's node,
+// and the store against its gossiped descriptor, which names the node
+// it belongs to. This prevents a host from injecting messages on
+// behalf of the stores of other nodes.
+func (t *rpcTransport) authenticate(protoReq *PeerRaftMessageRequest, req *multiraft.RaftMessageRequest) error {
+	clusterID, err := t.clusterID()
+	if err != nil {
+		return err
+	}
+	if protoReq.ClusterID != clusterID {
+		return util.Errorf("message from cluster %q does not match cluster %q", protoReq.ClusterID, clusterID)
+	}
+	if protoReq.peer == nil || protoReq.peer.Network() == "unix" {
+		return nil
+	}
+	nodeID, storeID := storage.DecodeRaftNodeID(multiraft.NodeID(req.Message.From))
+	addr, err := storage.NodeIDToAddress(t.gossip, nodeID)
+	if err != nil {
+		return err
+	}
+	if !t.hosts.sameHost(addr, protoReq.peer) {
+		return util.Errorf("store %d of node %d at %s cannot be sent from %s",
+			storeID, nodeID, addr, protoReq.peer)
+	}
+	info, err := t.gossip.GetInfo(gossip.MakeMaxAvailCapacityKey(nodeID, storeID))
+	if err != nil || info == nil {
+		return util.Errorf("unable to look up descriptor of store %d of node %d: %v", storeID, nodeID, err)
+	}
+	if desc, ok := info.(storage.StoreDescriptor); !ok || desc.StoreID != storeID || desc.Node.NodeID != nodeID {
+		return util.Errorf("store %d does not belong to node %d", storeID, nodeID)
+	}
+	return nil
+}
+
+// clusterID returns the cluster ID from gossip.
+func (t *rpcTransport) clusterID() (string, error) {
+	val, err := t.gossip.GetInfo(gossip.KeyClusterID)
+	if err != nil || val == nil {
+		return "", util.Errorf("unable to ascertain cluster ID from gossip network: %v", err)
+	}
+	return val.(string), nil
+}
+
+// A hostCache caches the addresses host names resolve to, so that
+// raft messages, which are authenticated against the gossiped
+// addresses of their senders, don't each incur a lookup.
+type hostCache struct {
+	ttl    time.Duration
+	lookup func(host string) ([]net.IP, error)
+
+	mu      sync.Mutex
+	entries map[string]hostCacheEntry
+}
+
+type hostCacheEntry struct {
+	ips      []net.IP
+	resolved time.Time
+}
+
+// newHostCache returns a hostCache which resolves host names using
+// lookup and caches the results for ttl.
+func newHostCache(ttl time.Duration, lookup func(string) ([]net.IP, error)) *hostCache {
+	return &hostCache{
+		ttl:     ttl,
+		lookup:  lookup,
+		entries: map[string]hostCacheEntry{},
+	}
+}
+
+// resolve returns the addresses host resolves to, looking them up if
+// they aren't cached or have been cached for longer than the TTL.
+// Failed lookups aren't cached.
+func (hc *hostCache) resolve(host string) ([]net.IP, error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if e, ok := hc.entries[host]; ok && time.Since(e.resolved) < hc.ttl {
+		return e.ips, nil
+	}
+	ips, err := hc.lookup(host)
+	if err != nil {
+		return nil, err
+	}
+	hc.entries[host] = hostCacheEntry{ips: ips, resolved: time.Now()}
+	return ips, nil
+}
+
+// sameHost returns whether the host of the advertised address resolves
+// to the host of the peer address.
+func (hc *hostCache) sameHost(advertised, peer net.Addr) bool {
+	advertisedHost, _, err := net.SplitHostPort(advertised.String())
+	if err != nil {
+		return false
+	}
+	peerHost, _, err := net.SplitHostPort(peer.String())
+	if err != nil {
+		return false
+	}
+	if advertisedHost == peerHost {
+		return true
+	}
+	peerIP := net.ParseIP(peerHost)
+	if peerIP == nil {
+		return false
+	}
+	ips, err := hc.resolve(advertisedHost)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip.Equal(peerIP) {
+			return true
+		}
+	}
+	return false
+}
+
 // Listen implements the multiraft.Transport interface by registering a ServerInterface
 // to receive proxied messages.
 func (t *rpcTransport) Listen(id multiraft.NodeID, server multiraft.ServerInterface) error {
@@ -110,6 +259,9 @@ func (t *rpcTransport) Send(id multiraft.NodeID, req *multiraft.RaftMessageReque
 	if protoReq.Msg, err = req.Message.Marshal(); err != nil {
 		return err
 	}
+	if protoReq.ClusterID, err = t.clusterID(); err != nil {
+		return err
+	}
 
 	nodeID, _ := storage.DecodeRaftNodeID(id)
 	addr, err := storage.NodeIDToAddress(t.gossip, nodeID)
@@ -18,6 +18,7 @@
 package server
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -42,6 +43,9 @@ func (s ChannelServer) RaftMessage(req *multiraft.RaftMessageRequest,
 func TestSendAndReceive(t *testing.T) {
 	rpcContext := rpc.NewContext(hlc.NewClock(hlc.UnixNano), rpc.LoadInsecureTLSConfig())
 	g := gossip.New(rpcContext, gossip.TestInterval, gossip.TestBootstrap)
+	if err := g.AddInfo(gossip.KeyClusterID, "test-cluster", time.Hour); err != nil {
+		t.Fatal(err)
+	}
 
 	// Create several servers, each of which has two stores (A multiraft node ID addresses
 	// a store).
@@ -78,9 +82,12 @@ func TestSendAndReceive(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if err := g.AddInfo(gossip.MakeNodeIDKey(protoNodeID),
-				&storage.NodeDescriptor{Address: server.Addr()},
-				time.Hour); err != nil {
+			nodeDesc := storage.NodeDescriptor{NodeID: protoNodeID, Address: server.Addr()}
+			if err := g.AddInfo(gossip.MakeNodeIDKey(protoNodeID), &nodeDesc, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.AddInfo(gossip.MakeMaxAvailCapacityKey(protoNodeID, 1),
+				storage.StoreDescriptor{StoreID: 1, Node: nodeDesc}, time.Hour); err != nil {
 				t.Fatal(err)
 			}
 
@@ -133,3 +140,123 @@ func TestSendAndReceive(t *testing.T) {
 		}
 	}
 }
+
+// TestRejectUnauthenticatedMessages verifies that raft messages from
+// another cluster, sent on behalf of another node or from a store
+// which doesn't belong to the sending node are rejected.
+func TestRejectUnauthenticatedMessages(t *testing.T) {
+	rpcContext := rpc.NewContext(hlc.NewClock(hlc.UnixNano), rpc.LoadInsecureTLSConfig())
+	g := gossip.New(rpcContext, gossip.TestInterval, gossip.TestBootstrap)
+	if err := g.AddInfo(gossip.KeyClusterID, "test-cluster", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	server := rpc.NewServer(util.CreateTestAddr("tcp"), rpcContext)
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	transport, err := newRPCTransport(g, server, rpcContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+	channel := make(ChannelServer, 10)
+	if err := transport.Listen(storage.MakeRaftNodeID(1, 1), channel); err != nil {
+		t.Fatal(err)
+	}
+
+	// Node 2 is local; node 3 is on another host. Store 1 belongs to
+	// node 2 and store 2 to node 3.
+	node2 := storage.NodeDescriptor{NodeID: 2, Address: server.Addr()}
+	node3 := storage.NodeDescriptor{NodeID: 3, Address: util.MakeRawAddr("tcp", "10.0.0.3:8080")}
+	if err := g.AddInfo(gossip.MakeNodeIDKey(2), &node2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddInfo(gossip.MakeNodeIDKey(3), &node3, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddInfo(gossip.MakeMaxAvailCapacityKey(2, 1),
+		storage.StoreDescriptor{StoreID: 1, Node: node2}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddInfo(gossip.MakeMaxAvailCapacityKey(3, 2),
+		storage.StoreDescriptor{StoreID: 2, Node: node3}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	client := rpc.NewClient(server.Addr(), nil, rpcContext)
+	<-client.Ready
+	defer client.Close()
+
+	testCases := []struct {
+		from      proto.NodeID
+		store     proto.StoreID
+		clusterID string
+		expOK     bool
+	}{
+		{2, 1, "test-cluster", true},
+		{2, 1, "other-cluster", false},
+		{2, 1, "", false},
+		{2, 2, "test-cluster", false},
+		{2, 3, "test-cluster", false},
+		{3, 2, "test-cluster", false},
+		{4, 1, "test-cluster", false},
+	}
+	for i, test := range testCases {
+		msg := raftpb.Message{
+			From: uint64(storage.MakeRaftNodeID(test.from, test.store)),
+			To:   uint64(storage.MakeRaftNodeID(1, 1)),
+			Type: raftpb.MsgHeartbeat,
+		}
+		data, err := msg.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &proto.RaftMessageRequest{GroupID: 1, Msg: data, ClusterID: test.clusterID}
+		err = client.Call(raftMessageName, req, &proto.RaftMessageResponse{})
+		if ok := err == nil; ok != test.expOK {
+			t.Errorf("%d: expected ok=%t; got %v", i, test.expOK, err)
+		}
+	}
+	if len(channel) != 1 {
+		t.Errorf("expected 1 message delivered; got %d", len(channel))
+	}
+}
+
+// TestHostCache verifies that host names are resolved once per TTL
+// when authenticating senders and that failed lookups are retried.
+func TestHostCache(t *testing.T) {
+	var lookups int
+	fail := false
+	hc := newHostCache(time.Hour, func(host string) ([]net.IP, error) {
+		lookups++
+		if fail {
+			return nil, util.Errorf("lookup of %s failed", host)
+		}
+		return []net.IP{net.ParseIP("10.0.0.1")}, nil
+	})
+	advertised := util.MakeRawAddr("tcp", "node1:8080")
+	for i := 0; i < 3; i++ {
+		if !hc.sameHost(advertised, util.MakeRawAddr("tcp", "10.0.0.1:1234")) {
+			t.Fatal("expected peer to match the resolved host")
+		}
+		if hc.sameHost(advertised, util.MakeRawAddr("tcp", "10.0.0.2:1234")) {
+			t.Fatal("expected peer not to match the resolved host")
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected 1 lookup; got %d", lookups)
+	}
+
+	fail = true
+	other := util.MakeRawAddr("tcp", "node2:8080")
+	for i := 0; i < 2; i++ {
+		if hc.sameHost(other, util.MakeRawAddr("tcp", "10.0.0.1:1234")) {
+			t.Fatal("expected failed lookup not to match")
+		}
+	}
+	if lookups != 3 {
+		t.Errorf("expected failed lookups to be retried; got %d lookups", lookups)
+	}
+}
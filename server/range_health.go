@@ -0,0 +1,213 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// Range health classifications. A range is assigned the first
+// classification which applies, in the order listed.
+const (
+	// rangeUnavailable ranges have too few live replicas for a quorum.
+	rangeUnavailable = "unavailable"
+	// rangeUnderReplicated ranges have fewer live replicas than their
+	// zone config requires.
+	rangeUnderReplicated = "under-replicated"
+	// rangeOverReplicated ranges have more replicas than their zone
+	// config requires.
+	rangeOverReplicated = "over-replicated"
+	// rangeViolatingConstraints ranges have replicas on stores which
+	// don't match the attributes required by their zone config.
+	rangeViolatingConstraints = "violating-constraints"
+	// rangeHealthy ranges are none of the above.
+	rangeHealthy = "healthy"
+
+	// maxRangeHealthExamples is the maximum number of example ranges
+	// reported for each classification.
+	maxRangeHealthExamples = 5
+)
+
+// A RangeHealth is a rollup of the health of the ranges in a key
+// span, with the count of ranges and example range descriptors for
+// each classification.
+type RangeHealth struct {
+	Counts   map[string]int                     `json:"counts"`
+	Examples map[string][]proto.RangeDescriptor `json:"examples"`
+}
+
+// handleRangeHealth handles GET requests for the health of the ranges
+// overlapping the span given by the optional "start" and "end" query
+// parameters, which default to the entire key space.
+func (s *statusServer) handleRangeHealth(w http.ResponseWriter, r *http.Request) {
+	start, end := engine.KeyMin, engine.KeyMax
+	if v := r.URL.Query().Get("start"); v != "" {
+		start = proto.Key(v)
+	}
+	if v := r.URL.Query().Get("end"); v != "" {
+		end = proto.Key(v)
+	}
+	health, err := s.rangeHealth(start, end)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, health, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// rangeHealth classifies each range overlapping the span from start
+// to end. Range descriptors are read from the meta2 addressing
+// records. A store is considered live if its descriptor is being
+// gossiped.
+func (s *statusServer) rangeHealth(start, end proto.Key) (*RangeHealth, error) {
+	info, err := s.gossip.GetInfo(gossip.KeyConfigZone)
+	if err != nil {
+		return nil, util.Errorf("unable to fetch zone config from gossip: %s", err)
+	}
+	zoneMap, ok := info.(storage.PrefixConfigMap)
+	if !ok {
+		return nil, util.Errorf("gossiped info is not a prefix configuration map: %+v", info)
+	}
+
+	descs, err := s.rangeDescriptors(start, end)
+	if err != nil {
+		return nil, err
+	}
+	health := &RangeHealth{
+		Counts:   map[string]int{},
+		Examples: map[string][]proto.RangeDescriptor{},
+	}
+	for _, desc := range descs {
+		zone := zoneMap.MatchByPrefix(desc.StartKey).Config.(*proto.ZoneConfig)
+		class := classifyRange(&desc, zone, s.lookupStore)
+		health.Counts[class]++
+		if len(health.Examples[class]) < maxRangeHealthExamples {
+			health.Examples[class] = append(health.Examples[class], desc)
+		}
+	}
+	return health, nil
+}
+
+// rangeDescriptors returns the descriptors of the ranges overlapping
+// the span from start to end, read from the meta2 addressing records.
+func (s *statusServer) rangeDescriptors(start, end proto.Key) ([]proto.RangeDescriptor, error) {
+	reply := &proto.ScanResponse{}
+	if err := s.db.Call(proto.Scan, proto.ScanArgs(engine.KeyMeta2Prefix, engine.KeyMeta2Prefix.PrefixEnd(), 0), reply); err != nil {
+		return nil, err
+	}
+	var descs []proto.RangeDescriptor
+	for _, row := range reply.Rows {
+		desc := proto.RangeDescriptor{}
+		if err := gogoproto.Unmarshal(row.Value.Bytes, &desc); err != nil {
+			return nil, util.Errorf("unable to unmarshal range descriptor at %q: %s", row.Key, err)
+		}
+		if desc.StartKey.Less(end) && start.Less(desc.EndKey) {
+			descs = append(descs, desc)
+		}
+	}
+	return descs, nil
+}
+
+// lookupStore returns the gossiped descriptor of the replica's store,
+// or nil if the store isn't live.
+func (s *statusServer) lookupStore(replica proto.Replica) *storage.StoreDescriptor {
+	info, err := s.gossip.GetInfo(gossip.MakeMaxAvailCapacityKey(replica.NodeID, replica.StoreID))
+	if err != nil {
+		return nil
+	}
+	storeDesc, ok := info.(storage.StoreDescriptor)
+	if !ok {
+		return nil
+	}
+	return &storeDesc
+}
+
+// classifyRange returns the health classification of the range
+// described by desc, given its zone config. lookupStore returns the
+// descriptor of a replica's store, or nil if the store isn't live.
+func classifyRange(desc *proto.RangeDescriptor, zone *proto.ZoneConfig,
+	lookupStore func(proto.Replica) *storage.StoreDescriptor) string {
+	var live []*storage.StoreDescriptor
+	for _, replica := range desc.Replicas {
+		if storeDesc := lookupStore(replica); storeDesc != nil {
+			live = append(live, storeDesc)
+		}
+	}
+	required := len(zone.ReplicaAttrs)
+	requiredAttrs := make([]proto.Attributes, required)
+	for i := range requiredAttrs {
+		requiredAttrs[i] = zone.RequiredAttrs(i)
+	}
+	switch {
+	case len(live) < len(desc.Replicas)/2+1:
+		return rangeUnavailable
+	case len(live) < required:
+		return rangeUnderReplicated
+	case len(desc.Replicas) > required:
+		return rangeOverReplicated
+	case !satisfiesConstraints(requiredAttrs, live):
+		return rangeViolatingConstraints
+	}
+	return rangeHealthy
+}
+
+// satisfiesConstraints returns whether each of the required attribute
+// sets is matched by a distinct store. A store may match several of
+// the sets, so the sets are assigned stores by bipartite matching,
+// reassigning earlier sets along augmenting paths where needed.
+func satisfiesConstraints(required []proto.Attributes, stores []*storage.StoreDescriptor) bool {
+	// matchedBy[i] is the index of the set matched by stores[i], or -1.
+	matchedBy := make([]int, len(stores))
+	for i := range matchedBy {
+		matchedBy[i] = -1
+	}
+	var match func(set int, visited []bool) bool
+	match = func(set int, visited []bool) bool {
+		for i, storeDesc := range stores {
+			if visited[i] || !required[set].IsSubset(*storeDesc.CombinedAttrs()) {
+				continue
+			}
+			visited[i] = true
+			if matchedBy[i] == -1 || match(matchedBy[i], visited) {
+				matchedBy[i] = set
+				return true
+			}
+		}
+		return false
+	}
+	for set := range required {
+		if !match(set, make([]bool, len(stores))) {
+			return false
+		}
+	}
+	return true
+}
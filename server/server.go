@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"code.google.com/p/snappy-go/snappy"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/cockroachdb/cockroach/resource"
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/structured"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
@@ -42,6 +44,10 @@ import (
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 )
 
+// memoryCheckInterval is the interval at which the memory watchdog
+// compares the heap size against the configured budget.
+const memoryCheckInterval = time.Second
+
 var (
 	// Allocation pool for gzip writers.
 	gzipWriterPool sync.Pool
@@ -60,6 +66,8 @@ type Server struct {
 	kv             *client.KV
 	kvDB           *kv.DBServer
 	kvREST         *kv.RESTServer
+	kvSession      *kv.SessionServer
+	liveness       *storage.NodeLiveness
 	node           *Node
 	admin          *adminServer
 	status         *statusServer
@@ -81,13 +89,9 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 		return nil, util.Errorf("unable to resolve RPC address %q: %v", addr, err)
 	}
 
-	var tlsConfig *rpc.TLSConfig
-	if ctx.Certs == "" {
-		tlsConfig = rpc.LoadInsecureTLSConfig()
-	} else {
-		if tlsConfig, err = rpc.LoadTLSConfigFromDir(ctx.Certs); err != nil {
-			return nil, util.Errorf("unable to load TLS config: %v", err)
-		}
+	tlsConfig, err := ctx.loadTLSConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	s := &Server{
@@ -105,8 +109,12 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 	s.stopper.AddCloser(s.rpc)
 	s.gossip = gossip.New(rpcContext, s.ctx.GossipInterval, s.ctx.GossipBootstrapResolvers)
 
-	ds := kv.NewDistSender(&kv.DistSenderContext{Clock: s.clock}, s.gossip)
+	ds := kv.NewDistSender(&kv.DistSenderContext{
+		Clock:      s.clock,
+		Authorizer: ctx.Authorizer,
+	}, s.gossip)
 	sender := kv.NewTxnCoordSender(ds, s.clock, ctx.Linearizable, s.stopper)
+	sender.SetTraceSampleRate(ctx.TraceSampleRate)
 	s.kv = client.NewKV(nil, sender)
 	s.kv.User = storage.UserRoot
 
@@ -118,10 +126,36 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 
 	s.kvDB = kv.NewDBServer(sender)
 	s.kvREST = kv.NewRESTServer(s.kv)
-	// TODO(bdarnell): make StoreConfig configurable.
-	s.node = NewNode(s.kv, s.gossip, storage.StoreConfig{}, s.raftTransport)
+	s.kvSession = kv.NewSessionServer(sender, s.stopper)
+	s.liveness = storage.NewNodeLiveness(s.kv, s.gossip, s.clock, storage.DefaultNodeLivenessThreshold)
+	// TODO(bdarnell): make the Raft parameters of StoreConfig configurable.
+	storeConfig := storage.StoreConfig{
+		SplitQPS:               ctx.SplitQPS,
+		RangeCreationInterval:  ctx.RangeCreationInterval,
+		MaxConcurrentSnapshots: ctx.MaxConcurrentSnapshots,
+		MaxPendingProposals:    ctx.MaxPendingProposals,
+		SlowCmdThreshold:       ctx.SlowCmdThreshold,
+		TimeUntilNodeDead:      ctx.TimeUntilNodeDead,
+		Authorizer:             ctx.Authorizer,
+		NodeLiveness:           s.liveness,
+		IntentLimiter:          sender,
+	}
+	if ctx.SplitSystemRanges {
+		storeConfig.StaticSplitKeys = storage.SystemSplitKeys
+	}
+	if ctx.ExportDir != "" {
+		storeConfig.ExportSink = storage.NewLocalExportSink(ctx.ExportDir)
+	}
+	s.node = NewNode(s.kv, s.gossip, storeConfig, s.raftTransport)
 	s.admin = newAdminServer(s.kv, s.stopper)
-	s.status = newStatusServer(s.kv, s.gossip)
+	s.admin.backup = &backupHandler{
+		db:     s.kv,
+		clock:  s.clock,
+		gossip: s.gossip,
+		sink:   storeConfig.ExportSink,
+	}
+	s.admin.checkpoint = &checkpointHandler{stores: s.node.lSender}
+	s.status = newStatusServer(s.kv, s.gossip, sender, s.liveness, s.node.lSender)
 	s.structuredDB = structured.NewDB(s.kv)
 	s.structuredREST = structured.NewRESTServer(s.structuredDB)
 
@@ -146,9 +180,15 @@ func (s *Server) Start(selfBootstrap bool) error {
 	}
 	s.gossip.Start(s.rpc, s.stopper)
 
+	if s.ctx.MemoryBudget > 0 {
+		util.DefaultMemoryWatchdog.SetBudget(s.ctx.MemoryBudget)
+		util.DefaultMemoryWatchdog.Start(memoryCheckInterval, s.stopper)
+	}
+
 	if err := s.node.start(s.rpc, s.clock, s.ctx.Engines, s.ctx.NodeAttributes, s.stopper); err != nil {
 		return err
 	}
+	s.startReencryption()
 
 	log.Infof("starting http server at %s", s.rpc.Addr())
 	// TODO(spencer): go1.5 is supposed to allow shutdown of running http server.
@@ -157,6 +197,23 @@ func (s *Server) Start(selfBootstrap bool) error {
 	return nil
 }
 
+// startReencryption rewrites the values of encrypted stores which were
+// encrypted with keys other than the active key in the background, so
+// that the replaced keys may be retired.
+func (s *Server) startReencryption() {
+	for _, e := range s.ctx.Engines {
+		ee, ok := e.(*engine.EncryptedEngine)
+		if !ok {
+			continue
+		}
+		s.stopper.RunWorker(func() {
+			if err := ee.Reencrypt(s.stopper.ShouldStop()); err != nil {
+				log.Errorf("unable to reencrypt store %s: %s", ee.Engine, err)
+			}
+		})
+	}
+}
+
 func (s *Server) initHTTP() {
 	s.mux.Handle("/", http.FileServer(
 		&assetfs.AssetFS{Asset: resource.Asset, AssetDir: resource.AssetDir, Prefix: "./ui/"}))
@@ -169,6 +226,7 @@ func (s *Server) initHTTP() {
 
 	s.mux.Handle(kv.RESTPrefix, s.kvREST)
 	s.mux.Handle(kv.DBPrefix, s.kvDB)
+	s.mux.Handle(kv.SessionPrefix, s.kvSession)
 	s.mux.Handle(structured.StructuredKeyPrefix, s.structuredREST)
 }
 
@@ -175,6 +175,44 @@ func TestInitEngines(t *testing.T) {
 	}
 }
 
+// TestInitEnginesMaxSize verifies that a persistent store's path may
+// be suffixed with a maximum size which limits its capacity.
+func TestInitEnginesMaxSize(t *testing.T) {
+	tmp := createTempDirs(2, t)
+	defer resetTestData(tmp)
+
+	ctx := NewContext()
+	ctx.Stores = fmt.Sprintf("ssd=%s:1000000,hdd=%s", tmp[0], tmp[1])
+	ctx.GossipBootstrap = "self://"
+	if err := ctx.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Engines) != 2 {
+		t.Fatalf("expected 2 engines; got %d", len(ctx.Engines))
+	}
+	for i, e := range ctx.Engines {
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+		capacity, err := e.Capacity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limited := capacity.Capacity == 1000000; limited != (i == 0) {
+			t.Errorf("%d: expected capacity limited? %t; got %+v", i, i == 0, capacity)
+		}
+		if capacity.Available > capacity.Capacity {
+			t.Errorf("%d: available space exceeds capacity: %+v", i, capacity)
+		}
+	}
+
+	ctx.Stores = fmt.Sprintf("ssd=%s:0", tmp[0])
+	if err := ctx.Init(); err == nil {
+		t.Error("expected error limiting store to capacity 0")
+	}
+}
+
 // TestSelfBootstrap verifies operation when no bootstrap hosts have
 // been specified.
 func TestSelfBootstrap(t *testing.T) {
@@ -20,10 +20,14 @@ package server
 import (
 	"net/http"
 	"runtime"
+	"strconv"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/server/status"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -48,6 +52,11 @@ const (
 	// statusLocalStacksKey exposes stack traces of running goroutines.
 	statusLocalStacksKey = statusLocalKeyPrefix + "stacks"
 
+	// statusLocalRangesKey exposes the in-memory state of the ranges of
+	// the node's stores, for diagnosing stuck ranges. The optional
+	// "raft_id" query parameter restricts it to a single range.
+	statusLocalRangesKey = statusLocalKeyPrefix + "ranges"
+
 	// statusNodesKeyPrefix exposes status for each of the nodes the cluster.
 	// GETing statusNodesKeyPrefix will list all nodes.
 	// Individual node status can be queried at statusNodesKeyPrefix/NodeID.
@@ -56,24 +65,57 @@ const (
 	// statusStoresKeyPrefix exposes status for each store.
 	statusStoresKeyPrefix = statusKeyPrefix + "stores/"
 
-	// statusTransactionsKeyPrefix exposes transaction statistics.
+	// statusTransactionsKeyPrefix exposes statistics for the
+	// transactions coordinated by the node serving the request, keyed
+	// by application name.
 	statusTransactionsKeyPrefix = statusKeyPrefix + "txns/"
+
+	// statusRangeHealthKey exposes a rollup of the health of the ranges
+	// in the span given by the "start" and "end" query parameters.
+	statusRangeHealthKey = statusKeyPrefix + "ranges/health"
+
+	// statusLivenessKey exposes the liveness record of each node
+	// which has ever heartbeated, and whether it's currently live.
+	statusLivenessKey = statusKeyPrefix + "liveness"
+
+	// statusRangeLogKey exposes the range event log. The optional
+	// "since" query parameter restricts the events to those at or after
+	// the given wall time in nanoseconds and "limit" to the number
+	// returned.
+	statusRangeLogKey = statusKeyPrefix + "rangelog"
+
+	// statusTopologyKey exposes the cluster's nodes and the distribution
+	// of the ranges in a span over them; see client.Topology.
+	statusTopologyKey = client.TopologyEndpoint
 )
 
 // A statusServer provides a RESTful status API.
 type statusServer struct {
-	db     *client.KV
-	gossip *gossip.Gossip
+	db       *client.KV
+	gossip   *gossip.Gossip
+	coord    *kv.TxnCoordSender
+	liveness *storage.NodeLiveness
+	stores   *kv.LocalSender // The node's stores
 }
 
 // newStatusServer allocates and returns a statusServer.
-func newStatusServer(db *client.KV, gossip *gossip.Gossip) *statusServer {
+func newStatusServer(db *client.KV, gossip *gossip.Gossip, coord *kv.TxnCoordSender,
+	liveness *storage.NodeLiveness, stores *kv.LocalSender) *statusServer {
 	return &statusServer{
-		db:     db,
-		gossip: gossip,
+		db:       db,
+		gossip:   gossip,
+		coord:    coord,
+		liveness: liveness,
+		stores:   stores,
 	}
 }
 
+// A TxnStatus holds statistics for the transactions coordinated by a
+// node, keyed by application name.
+type TxnStatus struct {
+	Transactions map[string]kv.TxnStats `json:"transactions"`
+}
+
 // registerHandlers registers admin handlers with the supplied
 // serve mux.
 func (s *statusServer) registerHandlers(mux *http.ServeMux) {
@@ -81,9 +123,14 @@ func (s *statusServer) registerHandlers(mux *http.ServeMux) {
 	mux.HandleFunc(statusGossipKeyPrefix, s.handleGossipStatus)
 	mux.HandleFunc(statusLocalKeyPrefix, s.handleLocalStatus)
 	mux.HandleFunc(statusLocalStacksKey, s.handleLocalStacks)
+	mux.HandleFunc(statusLocalRangesKey, s.handleLocalRanges)
 	mux.HandleFunc(statusNodesKeyPrefix, s.handleNodeStatus)
 	mux.HandleFunc(statusStoresKeyPrefix, s.handleStoresStatus)
 	mux.HandleFunc(statusTransactionsKeyPrefix, s.handleTransactionStatus)
+	mux.HandleFunc(statusRangeHealthKey, s.handleRangeHealth)
+	mux.HandleFunc(statusLivenessKey, s.handleLivenessStatus)
+	mux.HandleFunc(statusRangeLogKey, s.handleRangeLog)
+	mux.HandleFunc(statusTopologyKey, s.handleTopology)
 }
 
 // handleStatus handles GET requests for cluster status.
@@ -145,6 +192,43 @@ func (s *statusServer) handleLocalStacks(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleLocalRanges handles GET requests for the in-memory state of
+// the ranges of the node's stores.
+func (s *statusServer) handleLocalRanges(w http.ResponseWriter, r *http.Request) {
+	var raftID int64
+	if str := r.URL.Query().Get("raft_id"); str != "" {
+		var err error
+		if raftID, err = strconv.ParseInt(str, 10, 64); err != nil {
+			http.Error(w, util.Errorf("invalid raft_id parameter %q: %s", str, err).Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ranges := []*storage.RangeDebugInfo{}
+	err := s.stores.VisitStores(func(store *storage.Store) error {
+		infos, err := store.RangeDebugInfo(raftID)
+		if _, ok := err.(*proto.RangeNotFoundError); ok {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		ranges = append(ranges, infos...)
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, ranges, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
 // handleNodeStatus handles GET requests for node status.
 func (s *statusServer) handleNodeStatus(w http.ResponseWriter, r *http.Request) {
 	// TODO(shawn) parse node-id in path
@@ -166,7 +250,88 @@ func (s *statusServer) handleStoresStatus(w http.ResponseWriter, r *http.Request
 }
 
 // handleTransactionStatus handles GET requests for transaction status.
+// If the "app" query parameter is given, only the statistics for that
+// application are returned.
 func (s *statusServer) handleTransactionStatus(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"transactions": []}`))
+	txnStatus := &TxnStatus{Transactions: s.coord.TxnStats()}
+	if app, ok := r.URL.Query()["app"]; ok {
+		for appName := range txnStatus.Transactions {
+			if appName != app[0] {
+				delete(txnStatus.Transactions, appName)
+			}
+		}
+	}
+	b, contentType, err := util.MarshalResponse(r, txnStatus, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// A NodeLivenessStatus describes the liveness of a node.
+type NodeLivenessStatus struct {
+	NodeID     proto.NodeID `json:"nodeID"`
+	Expiration int64        `json:"expiration"`
+	Live       bool         `json:"live"`
+}
+
+// handleLivenessStatus handles GET requests for the liveness of the
+// cluster's nodes, as persisted by their most recent heartbeats.
+func (s *statusServer) handleLivenessStatus(w http.ResponseWriter, r *http.Request) {
+	records, err := s.liveness.ScanRecords()
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	nodes := []NodeLivenessStatus{}
+	for _, lr := range records {
+		nodes = append(nodes, NodeLivenessStatus{
+			NodeID:     lr.NodeID,
+			Expiration: lr.Expiration,
+			Live:       !s.liveness.IsDead(lr.NodeID),
+		})
+	}
+	b, contentType, err := util.MarshalResponse(r, nodes, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// handleRangeLog handles GET requests for the range event log, which
+// records the splits, merges, replica changes and lease transfers of
+// the cluster's ranges.
+func (s *statusServer) handleRangeLog(w http.ResponseWriter, r *http.Request) {
+	var since, limit int64
+	for name, v := range map[string]*int64{"since": &since, "limit": &limit} {
+		if str := r.URL.Query().Get(name); str != "" {
+			i, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				http.Error(w, util.Errorf("invalid %s parameter %q: %s", name, str, err).Error(), http.StatusBadRequest)
+				return
+			}
+			*v = i
+		}
+	}
+	events, err := storage.ScanRangeEvents(s.db, since, limit)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, events, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
 }
@@ -20,13 +20,17 @@ package server
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
@@ -40,11 +44,11 @@ import (
 // Cockroach KV client address is set to the address of the test server.
 func startStatusServer() (*httptest.Server, *util.Stopper) {
 	stopper := util.NewStopper()
-	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), stopper)
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
 	if err != nil {
 		log.Fatal(err)
 	}
-	status := newStatusServer(db, nil)
+	status := newStatusServer(db, nil, nil, nil, nil)
 	mux := http.NewServeMux()
 	status.registerHandlers(mux)
 	httpServer := httptest.NewServer(mux)
@@ -211,3 +215,162 @@ func TestStatusGossipJson(t *testing.T) {
 		}
 	}
 }
+
+// TestClassifyRange verifies the health classification of ranges given
+// the liveness and attributes of their replicas' stores.
+func TestClassifyRange(t *testing.T) {
+	stores := map[proto.StoreID]*storage.StoreDescriptor{
+		1: {StoreID: 1, Attrs: proto.Attributes{Attrs: []string{"ssd"}}},
+		2: {StoreID: 2, Attrs: proto.Attributes{Attrs: []string{"ssd"}}},
+		3: {StoreID: 3, Attrs: proto.Attributes{Attrs: []string{"hdd"}}},
+		6: {StoreID: 6, Attrs: proto.Attributes{Attrs: []string{"hdd", "ssd"}}},
+	}
+	lookupStore := func(r proto.Replica) *storage.StoreDescriptor {
+		return stores[r.StoreID]
+	}
+	ssd := proto.Attributes{Attrs: []string{"ssd"}}
+	hdd := proto.Attributes{Attrs: []string{"hdd"}}
+
+	testCases := []struct {
+		storeIDs []proto.StoreID // Stores 4 and 5 aren't live
+		required []proto.Attributes
+		expClass string
+	}{
+		{[]proto.StoreID{1, 2, 3}, []proto.Attributes{ssd, ssd, hdd}, rangeHealthy},
+		{[]proto.StoreID{1, 2, 4}, []proto.Attributes{ssd, ssd, hdd}, rangeUnderReplicated},
+		{[]proto.StoreID{1, 4, 5}, []proto.Attributes{ssd, ssd, hdd}, rangeUnavailable},
+		{[]proto.StoreID{1, 2, 3}, []proto.Attributes{ssd}, rangeOverReplicated},
+		{[]proto.StoreID{1, 2, 3}, []proto.Attributes{ssd, hdd, hdd}, rangeViolatingConstraints},
+		{[]proto.StoreID{3}, []proto.Attributes{ssd}, rangeViolatingConstraints},
+		// Store 6 matches either set, but only store 3 matches hdd.
+		{[]proto.StoreID{6, 3}, []proto.Attributes{hdd, ssd}, rangeHealthy},
+		{[]proto.StoreID{6, 1}, []proto.Attributes{hdd, hdd}, rangeViolatingConstraints},
+	}
+	for i, test := range testCases {
+		desc := &proto.RangeDescriptor{}
+		for _, storeID := range test.storeIDs {
+			desc.Replicas = append(desc.Replicas, proto.Replica{NodeID: proto.NodeID(storeID), StoreID: storeID})
+		}
+		zone := &proto.ZoneConfig{ReplicaAttrs: test.required}
+		if class := classifyRange(desc, zone, lookupStore); class != test.expClass {
+			t.Errorf("%d: expected %s; got %s", i, test.expClass, class)
+		}
+	}
+}
+
+// TestStatusRangeHealth verifies that the range health endpoint
+// classifies the ranges of a test server.
+func TestStatusRangeHealth(t *testing.T) {
+	s := startTestServer(t)
+	defer s.Stop()
+
+	body, err := getText("http://" + s.Addr + statusRangeHealthKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	health := &RangeHealth{}
+	if err := json.Unmarshal(body, health); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, count := range health.Counts {
+		total += count
+	}
+	if total == 0 {
+		t.Errorf("expected at least one range to be classified: %s", body)
+	}
+}
+
+// TestStatusLocalRanges verifies that the in-memory state of the
+// ranges of a test server is exposed, optionally for a single range.
+func TestStatusLocalRanges(t *testing.T) {
+	s := startTestServer(t)
+	defer s.Stop()
+
+	for _, test := range []struct {
+		query string
+		empty bool
+	}{
+		{"", false},
+		{"?raft_id=1", false},
+		{"?raft_id=1000", true},
+	} {
+		body, err := getText("http://" + s.Addr + statusLocalRangesKey + test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ranges []*storage.RangeDebugInfo
+		if err := json.Unmarshal(body, &ranges); err != nil {
+			t.Fatal(err)
+		}
+		if (len(ranges) == 0) != test.empty {
+			t.Errorf("%q: unexpected ranges %s", test.query, body)
+		}
+	}
+}
+
+// TestBuildTopology verifies that the topology lists each node with
+// its locality and health, and counts the replicas of the span's
+// ranges per node and store.
+func TestBuildTopology(t *testing.T) {
+	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:26257")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeDescs := map[proto.NodeID]*storage.NodeDescriptor{
+		1: {NodeID: 1, Address: addr, Attrs: proto.Attributes{Attrs: []string{"us"}}},
+		2: {NodeID: 2, Attrs: proto.Attributes{Attrs: []string{"eu"}}},
+	}
+	lookupNode := func(nodeID proto.NodeID) *storage.NodeDescriptor { return nodeDescs[nodeID] }
+	lookupStore := func(r proto.Replica) *storage.StoreDescriptor {
+		return &storage.StoreDescriptor{StoreID: r.StoreID, Attrs: proto.Attributes{Attrs: []string{"ssd"}}}
+	}
+	isLive := func(nodeID proto.NodeID) bool { return nodeID != 2 }
+	descs := []proto.RangeDescriptor{
+		{Replicas: []proto.Replica{{NodeID: 1, StoreID: 2}, {NodeID: 3, StoreID: 3}}},
+		{Replicas: []proto.Replica{{NodeID: 1, StoreID: 1}, {NodeID: 1, StoreID: 2}}},
+	}
+
+	topology := buildTopology([]proto.NodeID{2, 1}, descs, lookupNode, lookupStore, isLive)
+	expected := &client.Topology{
+		RangeCount: 2,
+		Nodes: []client.NodeTopology{
+			{NodeID: 1, Address: addr.String(), Attrs: []string{"us"}, Live: true, Replicas: 3,
+				Stores: []client.StoreTopology{
+					{StoreID: 1, Attrs: []string{"ssd"}, Replicas: 1},
+					{StoreID: 2, Attrs: []string{"ssd"}, Replicas: 2},
+				}},
+			{NodeID: 2, Attrs: []string{"eu"}, Stores: []client.StoreTopology{}},
+			{NodeID: 3, Attrs: []string{}, Replicas: 1,
+				Stores: []client.StoreTopology{{StoreID: 3, Attrs: []string{"ssd"}, Replicas: 1}}},
+		},
+	}
+	if !reflect.DeepEqual(expected, topology) {
+		t.Errorf("expected topology %+v; got %+v", expected, topology)
+	}
+	if nodes := topology.LiveNodes(proto.Attributes{Attrs: []string{"us"}}); len(nodes) != 1 || nodes[0].NodeID != 1 {
+		t.Errorf("expected node 1 to be the only live node in \"us\"; got %+v", nodes)
+	}
+}
+
+// TestStatusTopology verifies that a client can fetch the topology of
+// a test server.
+func TestStatusTopology(t *testing.T) {
+	s := startTestServer(t)
+	defer s.Stop()
+
+	sender := client.NewHTTPSender(s.Addr, &http.Transport{})
+	var topology *client.Topology
+	if err := util.IsTrueWithin(func() bool {
+		var err error
+		if topology, err = sender.Topology(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		return len(topology.LiveNodes(proto.Attributes{})) == 1
+	}, 5*time.Second); err != nil {
+		t.Fatalf("expected one live node; got %+v", topology)
+	}
+	if topology.RangeCount == 0 || topology.Nodes[0].Replicas != topology.RangeCount {
+		t.Errorf("expected the node to hold a replica of every range; got %+v", topology)
+	}
+}
@@ -88,6 +88,8 @@ func (ts *TestServer) Start() error {
 	ctx.Addr = ts.Addr
 	ctx.Certs = ts.CertDir
 	ctx.MaxOffset = ts.MaxOffset
+	// Tests expect a single range unless they split it themselves.
+	ctx.SplitSystemRanges = false
 
 	var err error
 	ts.Server, err = NewServer(ctx, util.NewStopper())
@@ -101,7 +103,7 @@ func (ts *TestServer) Start() error {
 	ctx.Engines = []engine.Engine{ts.Engine}
 	if !ts.SkipBootstrap {
 		stopper := util.NewStopper()
-		_, err := BootstrapCluster("cluster-1", ts.Engine, stopper)
+		_, err := BootstrapCluster("cluster-1", ts.Engine, nil, stopper)
 		if err != nil {
 			return util.Errorf("could not bootstrap cluster: %s", err)
 		}
@@ -0,0 +1,155 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// handleTopology handles GET requests for the cluster topology, with
+// the distribution of the ranges overlapping the span given by the
+// optional "start" and "end" query parameters, which default to the
+// entire key space. See client.HTTPSender.Topology.
+func (s *statusServer) handleTopology(w http.ResponseWriter, r *http.Request) {
+	start, end := engine.KeyMin, engine.KeyMax
+	if v := r.URL.Query().Get("start"); v != "" {
+		start = proto.Key(v)
+	}
+	if v := r.URL.Query().Get("end"); v != "" {
+		end = proto.Key(v)
+	}
+	topology, err := s.topology(start, end)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, topology, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// topology returns the cluster topology with the distribution of the
+// ranges overlapping the span from start to end. The cluster's nodes
+// are those which have a liveness record or hold a replica in the
+// span.
+func (s *statusServer) topology(start, end proto.Key) (*client.Topology, error) {
+	records, err := s.liveness.ScanRecords()
+	if err != nil {
+		return nil, err
+	}
+	descs, err := s.rangeDescriptors(start, end)
+	if err != nil {
+		return nil, err
+	}
+	var nodeIDs []proto.NodeID
+	for _, lr := range records {
+		nodeIDs = append(nodeIDs, lr.NodeID)
+	}
+	isLive := func(nodeID proto.NodeID) bool {
+		return !s.liveness.IsDead(nodeID)
+	}
+	return buildTopology(nodeIDs, descs, s.lookupNode, s.lookupStore, isLive), nil
+}
+
+// lookupNode returns the gossiped descriptor of the node, or nil if
+// the node isn't gossiping its descriptor.
+func (s *statusServer) lookupNode(nodeID proto.NodeID) *storage.NodeDescriptor {
+	info, err := s.gossip.GetInfo(gossip.MakeNodeIDKey(nodeID))
+	if err != nil {
+		return nil
+	}
+	nodeDesc, ok := info.(*storage.NodeDescriptor)
+	if !ok {
+		return nil
+	}
+	return nodeDesc
+}
+
+// buildTopology assembles the topology of the nodes listed in nodeIDs
+// and those holding replicas of the ranges described by descs.
+// lookupNode and lookupStore return the descriptors of nodes and of
+// replicas' stores, or nil if they aren't gossiped; isLive returns
+// whether a node's liveness record is current. Nodes and stores are
+// listed in ascending order of ID.
+func buildTopology(nodeIDs []proto.NodeID, descs []proto.RangeDescriptor,
+	lookupNode func(proto.NodeID) *storage.NodeDescriptor,
+	lookupStore func(proto.Replica) *storage.StoreDescriptor,
+	isLive func(proto.NodeID) bool) *client.Topology {
+	nodes := map[proto.NodeID]*client.NodeTopology{}
+	getNode := func(nodeID proto.NodeID) *client.NodeTopology {
+		n, ok := nodes[nodeID]
+		if !ok {
+			n = &client.NodeTopology{NodeID: nodeID, Attrs: []string{}, Stores: []client.StoreTopology{}}
+			if nodeDesc := lookupNode(nodeID); nodeDesc != nil {
+				if nodeDesc.Address != nil {
+					n.Address = nodeDesc.Address.String()
+				}
+				n.Attrs = append(n.Attrs, nodeDesc.Attrs.Attrs...)
+				n.Live = isLive(nodeID)
+			}
+			nodes[nodeID] = n
+		}
+		return n
+	}
+	for _, nodeID := range nodeIDs {
+		getNode(nodeID)
+	}
+	for _, desc := range descs {
+		for _, replica := range desc.Replicas {
+			n := getNode(replica.NodeID)
+			n.Replicas++
+			i := sort.Search(len(n.Stores), func(i int) bool { return n.Stores[i].StoreID >= replica.StoreID })
+			if i == len(n.Stores) || n.Stores[i].StoreID != replica.StoreID {
+				store := client.StoreTopology{StoreID: replica.StoreID, Attrs: []string{}}
+				if storeDesc := lookupStore(replica); storeDesc != nil {
+					store.Attrs = append(store.Attrs, storeDesc.Attrs.Attrs...)
+				}
+				n.Stores = append(n.Stores, client.StoreTopology{})
+				copy(n.Stores[i+1:], n.Stores[i:])
+				n.Stores[i] = store
+			}
+			n.Stores[i].Replicas++
+		}
+	}
+
+	t := &client.Topology{Nodes: []client.NodeTopology{}, RangeCount: len(descs)}
+	for _, n := range nodes {
+		t.Nodes = append(t.Nodes, *n)
+	}
+	sort.Sort(nodesByID(t.Nodes))
+	return t
+}
+
+type nodesByID []client.NodeTopology
+
+func (n nodesByID) Len() int           { return len(n) }
+func (n nodesByID) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n nodesByID) Less(i, j int) bool { return n[i].NodeID < n[j].NodeID }
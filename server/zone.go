@@ -22,16 +22,12 @@ import (
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	gogoproto "github.com/gogo/protobuf/proto"
 )
 
-const (
-	// minRangeMaxBytes is the minimum value for range max bytes.
-	minRangeMaxBytes = 1 << 20
-)
-
 // A zoneHandler implements the adminHandler interface.
 type zoneHandler struct {
 	db *client.KV // Key-value database client
@@ -39,18 +35,45 @@ type zoneHandler struct {
 
 // validateZoneConfig returns an error if a given zone config is invalid.
 func validateZoneConfig(config gogoproto.Message) error {
-	zConfig := config.(*proto.ZoneConfig)
-	if len(zConfig.ReplicaAttrs) == 0 {
-		return util.Errorf("attributes for at least one replica must be specified in zone config")
+	zone := config.(*proto.ZoneConfig)
+	if err := zone.Validate(); err != nil {
+		return err
 	}
-	if zConfig.RangeMaxBytes < minRangeMaxBytes {
-		return util.Errorf("RangeMaxBytes %d less than minimum allowed %d", zConfig.RangeMaxBytes, minRangeMaxBytes)
+	_, err := storage.ParseTimeWindows(zone.PauseWindows)
+	return err
+}
+
+// GetZoneConfig returns the zone config for the key prefix, or nil if
+// there is none. The default zone config has an empty prefix.
+func GetZoneConfig(db *client.KV, prefix proto.Key) (*proto.ZoneConfig, error) {
+	zone := &proto.ZoneConfig{}
+	ok, _, err := db.GetProto(engine.MakeKey(engine.KeyConfigZonePrefix, prefix), zone)
+	if err != nil || !ok {
+		return nil, err
 	}
-	if zConfig.RangeMinBytes >= zConfig.RangeMaxBytes {
-		return util.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
-			zConfig.RangeMinBytes, zConfig.RangeMaxBytes)
+	return zone, nil
+}
+
+// PutZoneConfig validates and writes the zone config for the key
+// prefix. Once gossiped, the zone config applies to all ranges whose
+// keys have the prefix and which aren't covered by a zone config with
+// a longer prefix. Ranges spanning the prefix are split along it.
+func PutZoneConfig(db *client.KV, prefix proto.Key, zone *proto.ZoneConfig) error {
+	if err := validateZoneConfig(zone); err != nil {
+		return err
+	}
+	return db.PutProto(engine.MakeKey(engine.KeyConfigZonePrefix, prefix), zone)
+}
+
+// DeleteZoneConfig removes the zone config for the key prefix. The
+// default zone config cannot be deleted.
+func DeleteZoneConfig(db *client.KV, prefix proto.Key) error {
+	if len(prefix) == 0 {
+		return util.Errorf("the default zone config cannot be deleted")
 	}
-	return nil
+	return db.Call(proto.Delete, &proto.DeleteRequest{
+		RequestHeader: proto.RequestHeader{Key: engine.MakeKey(engine.KeyConfigZonePrefix, prefix)},
+	}, &proto.DeleteResponse{})
 }
 
 // Put writes a zone config for the specified key prefix (which is
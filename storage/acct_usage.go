@@ -0,0 +1,125 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// defaultAcctRollupInterval is the interval at which stores roll up
+// the usage of their ranges by accounting config prefix.
+const defaultAcctRollupInterval = 1 * time.Minute
+
+// startAcctRollups periodically rolls up the usage of the store's
+// ranges by accounting config prefix until the store is stopped.
+func (s *Store) startAcctRollups() {
+	s.stopper.RunWorker(func() {
+		ticker := util.NewTicker(defaultAcctRollupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.rollupAcctUsage(); err != nil {
+					log.Warningf("%s: failed to roll up accounting usage: %s", s, err)
+				}
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// rollupAcctUsage sums the stats of the ranges for which this store
+// is responsible by accounting config prefix and writes the sums to
+// the store's usage rollup key for each prefix. A range is attributed
+// to the longest accounting prefix matching its start key; since
+// ranges are split along accounting prefixes, this is the only
+// prefix the range's keys fall under once splits have completed.
+// A rollup is written for every prefix, including those with no
+// ranges, so that stale rollups are overwritten.
+func (s *Store) rollupAcctUsage() error {
+	if s.gossip == nil {
+		return nil
+	}
+	info, err := s.gossip.GetInfo(gossip.KeyConfigAccounting)
+	if err != nil {
+		return util.Errorf("unable to fetch accounting config from gossip: %s", err)
+	}
+	acctMap, ok := info.(PrefixConfigMap)
+	if !ok {
+		return util.Errorf("gossiped info is not a prefix configuration map: %+v", info)
+	}
+
+	now := s.clock.PhysicalNow()
+	usage := map[string]*proto.AcctUsage{}
+	for _, pc := range acctMap {
+		if pc.Canonical == nil {
+			usage[string(pc.Prefix)] = &proto.AcctUsage{
+				StoreID:   s.StoreID(),
+				Prefix:    pc.Prefix,
+				UpdatedAt: now,
+			}
+		}
+	}
+	s.mu.RLock()
+	for _, rng := range s.ranges {
+		if !s.isAcctRollupReplica(rng, now) {
+			continue
+		}
+		u := usage[string(acctMap.MatchByPrefix(rng.Desc().StartKey).Prefix)]
+		ms := rng.stats.GetMVCC()
+		u.RangeCount++
+		u.LiveBytes += ms.LiveBytes
+		u.KeyBytes += ms.KeyBytes
+		u.ValBytes += ms.ValBytes
+		u.IntentBytes += ms.IntentBytes
+		u.LiveCount += ms.LiveCount
+		u.KeyCount += ms.KeyCount
+		u.ValCount += ms.ValCount
+		u.IntentCount += ms.IntentCount
+	}
+	s.mu.RUnlock()
+
+	for _, u := range usage {
+		if err := s.db.PreparePutProto(engine.AcctUsageKey(u.Prefix, int32(u.StoreID)), u); err != nil {
+			return err
+		}
+	}
+	return s.db.Flush()
+}
+
+// isAcctRollupReplica returns whether this store's replica of the
+// range is responsible for including the range in the store's usage
+// rollups, so that each range is counted once across the cluster.
+// The replica holding the leader lease is responsible; if no replica
+// holds an unexpired lease (e.g. because the range is idle), the
+// first replica listed in the range descriptor is.
+func (s *Store) isAcctRollupReplica(rng *Range, now int64) bool {
+	if rng.HasLeaderLease() {
+		return true
+	}
+	if l := rng.getLease(); l != nil && now < rng.leaseExpiration(l) {
+		return false
+	}
+	replicas := rng.Desc().Replicas
+	return len(replicas) > 0 && replicas[0].StoreID == s.StoreID()
+}
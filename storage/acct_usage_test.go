@@ -0,0 +1,80 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestStoreRollupAcctUsage verifies that a store writes a usage
+// rollup for each accounting prefix, attributing each range to the
+// longest prefix matching its start key.
+func TestStoreRollupAcctUsage(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	// Add an accounting config for prefix "/db1"; writing the config
+	// causes it to be re-gossiped.
+	data, err := gogoproto.Marshal(&proto.AcctConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db1 := proto.Key("/db1")
+	pArgs, pReply := putArgs(engine.MakeKey(engine.KeyConfigAccountingPrefix, db1), data, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	pArgs, pReply = putArgs([]byte("a"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read the stats before the rollup, which itself writes to the range.
+	ms := rng.stats.GetMVCC()
+	if err := store.rollupAcctUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The single range starts at KeyMin and is attributed to the
+	// default prefix; "/db1" has a rollup without any ranges.
+	testCases := []struct {
+		prefix               proto.Key
+		rangeCount, keyCount int64
+	}{
+		{engine.KeyMin, 1, ms.KeyCount},
+		{db1, 0, 0},
+	}
+	for i, test := range testCases {
+		u := &proto.AcctUsage{}
+		ok, _, err := store.DB().GetProto(engine.AcctUsageKey(test.prefix, int32(store.StoreID())), u)
+		if err != nil || !ok {
+			t.Fatalf("%d: expected usage rollup; got %t, %v", i, ok, err)
+		}
+		if u.StoreID != store.StoreID() || u.RangeCount != test.rangeCount || u.KeyCount != test.keyCount {
+			t.Errorf("%d: unexpected usage rollup %+v", i, u)
+		}
+	}
+}
@@ -20,6 +20,8 @@ package storage
 
 import (
 	"math/rand"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
@@ -32,7 +34,24 @@ import (
 // availability of servers is gleaned from the gossip network.
 type allocator struct {
 	storeFinder FindStoreFunc
+	liveness    *NodeLiveness // Stores on dead nodes aren't allocated; may be nil
 	rand        rand.Rand
+
+	mu        sync.Mutex      // Protects transfers
+	transfers []leaseTransfer // Recent lease transfers, oldest first
+}
+
+// leaseTransferTTL is the time for which the allocator counts a lease
+// it has transferred towards the lease counts of the stores involved.
+// Stores gossip their descriptors, including their lease counts, once
+// a minute, so until then the gossiped counts don't reflect the
+// transfer.
+const leaseTransferTTL = time.Minute
+
+// A leaseTransfer records a lease transferred by the allocator's store.
+type leaseTransfer struct {
+	from, to proto.StoreID
+	at       time.Time
 }
 
 // newAllocator creates a new allocator.
@@ -49,6 +68,8 @@ func newAllocator(f FindStoreFunc) *allocator {
 // error. It uses the allocator's StoreFinder to select the set of
 // available stores matching attributes for missing replicas and picks
 // using randomly weighted selection based on available capacities.
+// Stores on nodes known to be dead and unhealthy stores are never
+// selected.
 func (a *allocator) allocate(required proto.Attributes, existingReplicas []proto.Replica) (
 	*StoreDescriptor, error) {
 	// Get a set of current nodes -- we never want to allocate on an existing node.
@@ -66,6 +87,9 @@ func (a *allocator) allocate(required proto.Attributes, existingReplicas []proto
 	var candidates []*StoreDescriptor
 	var capacityTotal float64
 	for _, s := range stores {
+		if a.liveness.IsDead(s.Node.NodeID) || s.Stats.Unhealthy != "" {
+			continue
+		}
 		if _, ok := usedNodes[s.Node.NodeID]; !ok {
 			candidates = append(candidates, s)
 			capacityTotal += s.Capacity.PercentAvail()
@@ -85,3 +109,146 @@ func (a *allocator) allocate(required proto.Attributes, existingReplicas []proto
 	}
 	return nil, util.Errorf("unable to find an appropriate store for requested replica attributes")
 }
+
+// leaseRebalanceThreshold is the fraction by which the number of
+// leases held by a store must exceed the mean over the stores of a
+// range's replicas before the allocator moves the range's lease.
+const leaseRebalanceThreshold = 0.1
+
+// leaseTarget returns the replica to which the leader lease of a range
+// should be transferred from the replica on store leaseStoreID, or nil
+// if the lease is well placed. Leases move to replicas on stores with
+// the preferred attributes, typically those of the zone's first
+// replica, so that reads are served close to their clients. Among
+// those, leases move from stores holding more than their share to the
+// store holding the fewest; a store must hold at least two more leases
+// than the target so that the lease doesn't move back. Leases move off
+// unhealthy stores regardless. Replicas on dead nodes or unhealthy
+// stores never receive the lease. Lease counts include the transfers
+// recorded with leaseTransferred which the gossiped counts may not yet
+// reflect, so that the ranges of an overloaded store don't all move
+// their leases to the same store before its count is gossiped again.
+func (a *allocator) leaseTarget(preferred proto.Attributes, replicas []proto.Replica,
+	leaseStoreID proto.StoreID) *proto.Replica {
+	stores, err := a.storeFinder(proto.Attributes{})
+	if err != nil {
+		return nil
+	}
+	descs := make(map[proto.StoreID]*StoreDescriptor, len(stores))
+	for _, s := range stores {
+		descs[s.StoreID] = s
+	}
+	source, ok := descs[leaseStoreID]
+	if !ok {
+		return nil
+	}
+	sourcePreferred := preferred.IsSubset(*source.CombinedAttrs())
+	pending := a.pendingTransfers()
+	leaseCount := func(s *StoreDescriptor) int {
+		return s.Stats.LeaseCount + pending[s.StoreID]
+	}
+
+	// Collect the candidates, restricted to preferred stores if any of
+	// the replicas are on one.
+	var candidates, preferredCandidates []*StoreDescriptor
+	for _, replica := range replicas {
+		s, ok := descs[replica.StoreID]
+		if !ok || a.liveness.IsDead(replica.NodeID) || s.Stats.Unhealthy != "" {
+			continue
+		}
+		candidates = append(candidates, s)
+		if preferred.IsSubset(*s.CombinedAttrs()) {
+			preferredCandidates = append(preferredCandidates, s)
+		}
+	}
+	if len(preferredCandidates) > 0 {
+		candidates = preferredCandidates
+	}
+
+	var target *StoreDescriptor
+	var total int
+	for _, s := range candidates {
+		total += leaseCount(s)
+		if s.StoreID != leaseStoreID && (target == nil || leaseCount(s) < leaseCount(target)) {
+			target = s
+		}
+	}
+	if target == nil {
+		return nil
+	}
+	// Move the lease off an unhealthy store, or to a preferred store,
+	// regardless of load.
+	if source.Stats.Unhealthy != "" || (!sourcePreferred && len(preferredCandidates) > 0) {
+		return a.replicaOn(target.StoreID, replicas)
+	}
+	mean := float64(total) / float64(len(candidates))
+	if float64(leaseCount(source)) <= mean*(1+leaseRebalanceThreshold) ||
+		leaseCount(source)-leaseCount(target) < 2 {
+		return nil
+	}
+	return a.replicaOn(target.StoreID, replicas)
+}
+
+// leaseTransferred records the transfer of a lease from store from to
+// store to, to be counted by leaseTarget for leaseTransferTTL.
+func (a *allocator) leaseTransferred(from, to proto.StoreID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.transfers = append(a.transfers, leaseTransfer{from: from, to: to, at: util.Now()})
+}
+
+// pendingTransfers returns the net number of leases transferred to
+// each store within the last leaseTransferTTL, discarding the records
+// of earlier transfers.
+func (a *allocator) pendingTransfers() map[proto.StoreID]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cutoff := util.Now().Add(-leaseTransferTTL)
+	i := 0
+	for i < len(a.transfers) && a.transfers[i].at.Before(cutoff) {
+		i++
+	}
+	a.transfers = a.transfers[i:]
+	pending := make(map[proto.StoreID]int)
+	for _, t := range a.transfers {
+		pending[t.from]--
+		pending[t.to]++
+	}
+	return pending
+}
+
+// misplacedReplicas returns the replicas on stores whose attributes
+// don't include all of the constraints, and which must therefore be
+// moved to comply with the zone config. Replicas on stores which
+// aren't gossiping their descriptors are presumed to be in place.
+func (a *allocator) misplacedReplicas(constraints proto.Attributes, replicas []proto.Replica) []proto.Replica {
+	if len(constraints.Attrs) == 0 {
+		return nil
+	}
+	stores, err := a.storeFinder(proto.Attributes{})
+	if err != nil {
+		return nil
+	}
+	descs := make(map[proto.StoreID]*StoreDescriptor, len(stores))
+	for _, s := range stores {
+		descs[s.StoreID] = s
+	}
+	var misplaced []proto.Replica
+	for _, replica := range replicas {
+		if s, ok := descs[replica.StoreID]; ok && !constraints.IsSubset(*s.CombinedAttrs()) {
+			misplaced = append(misplaced, replica)
+		}
+	}
+	return misplaced
+}
+
+// replicaOn returns the replica on the given store, or nil if none of
+// the replicas are.
+func (a *allocator) replicaOn(storeID proto.StoreID, replicas []proto.Replica) *proto.Replica {
+	for i := range replicas {
+		if replicas[i].StoreID == storeID {
+			return &replicas[i]
+		}
+	}
+	return nil
+}
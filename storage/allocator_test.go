@@ -20,9 +20,11 @@ package storage
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 )
 
@@ -294,3 +296,206 @@ func TestExistingReplica(t *testing.T) {
 		t.Errorf("expected result to have node 3 and store 4: %+v", result)
 	}
 }
+
+func TestAllocatorSkipsDeadNodes(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	manual := hlc.NewManualClock(10)
+	var a = allocator{
+		storeFinder: multiDCStores,
+		rand:        *rand.New(rand.NewSource(0)),
+		liveness: &NodeLiveness{
+			clock: hlc.NewClock(manual.UnixNano),
+			records: map[proto.NodeID]LivenessRecord{
+				1: {NodeID: 1, Expiration: 5},
+				2: {NodeID: 2, Expiration: 20},
+			},
+		},
+	}
+	if result, err := a.allocate(multiDCConfig.ReplicaAttrs[0], []proto.Replica{}); err == nil {
+		t.Errorf("expected no allocation on dead node 1; got %+v", result)
+	}
+	result, err := a.allocate(multiDCConfig.ReplicaAttrs[1], []proto.Replica{})
+	if err != nil {
+		t.Fatalf("Unable to perform allocation: %v", err)
+	}
+	if result.Node.NodeID != 2 {
+		t.Errorf("expected live node 2; got %+v", result.Node)
+	}
+}
+
+// TestAllocatorLeaseTarget verifies that leases move to preferred
+// stores and from stores holding more than their share of leases to
+// the store holding the fewest.
+func TestAllocatorLeaseTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stores := []*StoreDescriptor{
+		{StoreID: 1, Node: NodeDescriptor{NodeID: 1, Attrs: proto.Attributes{Attrs: []string{"a"}}}},
+		{StoreID: 2, Node: NodeDescriptor{NodeID: 2, Attrs: proto.Attributes{Attrs: []string{"a"}}}},
+		{StoreID: 3, Node: NodeDescriptor{NodeID: 3, Attrs: proto.Attributes{Attrs: []string{"b"}}}},
+	}
+	a := allocator{
+		storeFinder: func(attrs proto.Attributes) ([]*StoreDescriptor, error) {
+			return filterStores(attrs, stores)
+		},
+		rand: *rand.New(rand.NewSource(0)),
+	}
+	replicas := []proto.Replica{
+		{NodeID: 1, StoreID: 1},
+		{NodeID: 2, StoreID: 2},
+		{NodeID: 3, StoreID: 3},
+	}
+	testCases := []struct {
+		leaseCounts []int
+		preferred   []string
+		source      proto.StoreID
+		expTarget   proto.StoreID // Zero if the lease shouldn't move
+	}{
+		// Balanced leases stay put.
+		{[]int{10, 10, 10}, nil, 1, 0},
+		{[]int{11, 10, 10}, nil, 1, 0},
+		// Leases move from overloaded stores to the least loaded.
+		{[]int{20, 10, 5}, nil, 1, 3},
+		{[]int{2, 0, 1}, nil, 1, 2},
+		// Leases move to preferred stores regardless of load.
+		{[]int{0, 0, 20}, []string{"a"}, 3, 1},
+		// Among preferred stores, leases are balanced.
+		{[]int{20, 10, 0}, []string{"a"}, 1, 2},
+		{[]int{10, 10, 0}, []string{"a"}, 1, 0},
+	}
+	for i, test := range testCases {
+		for j, count := range test.leaseCounts {
+			stores[j].Stats.LeaseCount = count
+		}
+		target := a.leaseTarget(proto.Attributes{Attrs: test.preferred}, replicas, test.source)
+		if test.expTarget == 0 {
+			if target != nil {
+				t.Errorf("%d: expected lease to stay on store %d; got %+v", i, test.source, target)
+			}
+		} else if target == nil || target.StoreID != test.expTarget {
+			t.Errorf("%d: expected lease to move to store %d; got %+v", i, test.expTarget, target)
+		}
+	}
+}
+
+// TestAllocatorLeaseTargetCountsTransfers verifies that recent lease
+// transfers count towards the lease counts of the stores involved
+// until they'd be reflected in gossip, so that successive transfers
+// don't all go to the same store.
+func TestAllocatorLeaseTargetCountsTransfers(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stores := []*StoreDescriptor{
+		{StoreID: 1, Node: NodeDescriptor{NodeID: 1}, Stats: StoreStats{LeaseCount: 20}},
+		{StoreID: 2, Node: NodeDescriptor{NodeID: 2}, Stats: StoreStats{LeaseCount: 10}},
+		{StoreID: 3, Node: NodeDescriptor{NodeID: 3}, Stats: StoreStats{LeaseCount: 5}},
+	}
+	a := allocator{
+		storeFinder: func(attrs proto.Attributes) ([]*StoreDescriptor, error) {
+			return filterStores(attrs, stores)
+		},
+		rand: *rand.New(rand.NewSource(0)),
+	}
+	replicas := []proto.Replica{
+		{NodeID: 1, StoreID: 1},
+		{NodeID: 2, StoreID: 2},
+		{NodeID: 3, StoreID: 3},
+	}
+	for i, expTarget := range []proto.StoreID{3, 3, 3, 3, 3, 2} {
+		target := a.leaseTarget(proto.Attributes{}, replicas, 1)
+		if target == nil || target.StoreID != expTarget {
+			t.Fatalf("%d: expected lease to move to store %d; got %+v", i, expTarget, target)
+		}
+		a.leaseTransferred(1, target.StoreID)
+	}
+
+	// Once the transfers would have been gossiped, they're forgotten.
+	for i := range a.transfers {
+		a.transfers[i].at = a.transfers[i].at.Add(-leaseTransferTTL - time.Second)
+	}
+	if target := a.leaseTarget(proto.Attributes{}, replicas, 1); target == nil || target.StoreID != 3 {
+		t.Errorf("expected lease to move to store 3; got %+v", target)
+	}
+	if len(a.transfers) != 0 {
+		t.Errorf("expected expired transfers to be discarded; got %+v", a.transfers)
+	}
+}
+
+// TestAllocatorSkipsUnhealthyStores verifies that unhealthy stores
+// receive neither replicas nor leases, and that leases move off them
+// regardless of load.
+func TestAllocatorSkipsUnhealthyStores(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	capacity := engine.StoreCapacity{Capacity: 100, Available: 100}
+	stores := []*StoreDescriptor{
+		{StoreID: 1, Node: NodeDescriptor{NodeID: 1, Attrs: proto.Attributes{Attrs: []string{"a"}}}, Capacity: capacity},
+		{StoreID: 2, Node: NodeDescriptor{NodeID: 2, Attrs: proto.Attributes{Attrs: []string{"a"}}}, Capacity: capacity},
+		{StoreID: 3, Node: NodeDescriptor{NodeID: 3, Attrs: proto.Attributes{Attrs: []string{"b"}}}, Capacity: capacity},
+	}
+	stores[1].Stats.Unhealthy = "disk is full"
+	a := allocator{
+		storeFinder: func(attrs proto.Attributes) ([]*StoreDescriptor, error) {
+			return filterStores(attrs, stores)
+		},
+		rand: *rand.New(rand.NewSource(0)),
+	}
+	for i := 0; i < 10; i++ {
+		result, err := a.allocate(proto.Attributes{Attrs: []string{"a"}}, []proto.Replica{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.StoreID != 1 {
+			t.Fatalf("expected healthy store 1; got store %d", result.StoreID)
+		}
+	}
+
+	replicas := []proto.Replica{
+		{NodeID: 1, StoreID: 1},
+		{NodeID: 2, StoreID: 2},
+		{NodeID: 3, StoreID: 3},
+	}
+	if target := a.leaseTarget(proto.Attributes{}, replicas, 2); target == nil || target.StoreID != 1 {
+		t.Errorf("expected lease to move off unhealthy store 2 to store 1; got %+v", target)
+	}
+	stores[0].Stats.LeaseCount = 20
+	stores[2].Stats.LeaseCount = 10
+	if target := a.leaseTarget(proto.Attributes{}, replicas, 1); target == nil || target.StoreID != 3 {
+		t.Errorf("expected lease to move to healthy store 3; got %+v", target)
+	}
+}
+
+// TestAllocatorMisplacedReplicas verifies that replicas on stores
+// lacking the zone's constraints are identified for replacement and
+// that replacements are allocated on conforming stores.
+func TestAllocatorMisplacedReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var a = allocator{
+		storeFinder: multiDCStores,
+		rand:        *rand.New(rand.NewSource(0)),
+	}
+	replicas := []proto.Replica{
+		{NodeID: 1, StoreID: 1},
+		{NodeID: 2, StoreID: 2},
+		{NodeID: 9, StoreID: 9}, // Not gossiped
+	}
+	if misplaced := a.misplacedReplicas(proto.Attributes{}, replicas); len(misplaced) != 0 {
+		t.Errorf("expected no misplaced replicas without constraints; got %+v", misplaced)
+	}
+	misplaced := a.misplacedReplicas(proto.Attributes{Attrs: []string{"b"}}, replicas)
+	if len(misplaced) != 1 || misplaced[0].StoreID != 1 {
+		t.Errorf("expected replica on store 1 to be misplaced; got %+v", misplaced)
+	}
+
+	zone := proto.ZoneConfig{
+		ReplicaAttrs: []proto.Attributes{{Attrs: []string{"ssd"}}},
+		Constraints:  proto.Attributes{Attrs: []string{"b"}},
+	}
+	result, err := a.allocate(zone.RequiredAttrs(0), []proto.Replica{})
+	if err != nil {
+		t.Fatalf("Unable to perform allocation: %v", err)
+	}
+	if result.StoreID != 2 {
+		t.Errorf("expected allocation on store 2 satisfying constraints; got %+v", result)
+	}
+	if result, err := a.allocate(zone.RequiredAttrs(0), replicas[1:2]); err == nil {
+		t.Errorf("expected no allocation satisfying constraints; got %+v", result)
+	}
+}
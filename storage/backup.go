@@ -0,0 +1,381 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// BackupManifestName is the name of the manifest within the
+// directory of a backup.
+const BackupManifestName = "MANIFEST"
+
+// A BackupManifest describes a backup: the span it covers, the time
+// as of which it's consistent and the files holding its data. It's
+// written as JSON once all of the files have been exported, so a
+// backup without a manifest is incomplete. Keys are stored as byte
+// slices, which are base64 encoded in JSON.
+type BackupManifest struct {
+	ClusterID string          `json:"clusterID"`
+	Build     util.BuildInfo  `json:"build"`
+	StartKey  []byte          `json:"startKey"`
+	EndKey    []byte          `json:"endKey"`
+	Timestamp proto.Timestamp `json:"timestamp"`
+	// Files are in key order and their spans partition the span of the
+	// backup.
+	Files []BackupFile `json:"files"`
+}
+
+// A BackupFile describes a file of a backup, which holds the data of
+// the span [StartKey, EndKey).
+type BackupFile struct {
+	Name     string `json:"name"` // Relative to the backup's directory
+	StartKey []byte `json:"startKey"`
+	EndKey   []byte `json:"endKey"`
+	KeyCount int64  `json:"keyCount"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"` // Hex encoded
+}
+
+// maxBackupRetries is the number of times in a row the export of a
+// range's share of a backup is retried because the range boundaries
+// changed while it was being exported.
+const maxBackupRetries = 5
+
+// Backup exports the span [start, end) as of timestamp into the
+// directory dir of sink, one file per range, and writes the backup's
+// manifest once all exports have succeeded. Each range is exported by
+// its leader to the leader's store's export sink, so sink and the
+// stores' sinks must refer to the same shared storage. If a range
+// splits or merges while the backup is in progress, its export is
+// retried along the new range boundaries.
+func Backup(db *client.KV, sink ExportSink, dir string, start, end proto.Key,
+	timestamp proto.Timestamp, clusterID string) (*BackupManifest, error) {
+	if !start.Less(end) {
+		return nil, util.Errorf("invalid backup span %q-%q", start, end)
+	}
+	spans, err := rangeSpans(db, start, end)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &BackupManifest{
+		ClusterID: clusterID,
+		Build:     util.GetBuildInfo(),
+		StartKey:  start,
+		EndKey:    end,
+		Timestamp: timestamp,
+	}
+	var exports, retries int
+	for len(spans) > 0 {
+		span := spans[0]
+		// Each export is written to a file of its own, so that a failed
+		// export can't leave its partial output in the place of another.
+		file := BackupFile{
+			Name:     fmt.Sprintf("%06d.kv", exports),
+			StartKey: span.start,
+			EndKey:   span.end,
+		}
+		exports++
+		args := &proto.InternalExportRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       span.start,
+				EndKey:    span.end,
+				Timestamp: timestamp,
+			},
+			Name: path.Join(dir, file.Name),
+		}
+		exportReply := &proto.InternalExportResponse{}
+		if err := db.Call(proto.InternalExport, args, exportReply); err != nil {
+			// The export fails if it spans more than one range, which
+			// happens if the range split after its descriptor was read.
+			// Reread the descriptors of the remaining span and, if the
+			// range's boundaries have changed, retry along the new ones.
+			newSpans, lookupErr := rangeSpans(db, span.start, end)
+			if lookupErr != nil || len(newSpans) == 0 || newSpans[0].end.Equal(span.end) ||
+				retries == maxBackupRetries {
+				return nil, util.Errorf("export of %q-%q failed: %s", span.start, span.end, err)
+			}
+			spans = newSpans
+			retries++
+			continue
+		}
+		file.KeyCount = exportReply.KeyCount
+		file.Size = exportReply.DataSize
+		file.SHA256 = hex.EncodeToString(exportReply.Sha256)
+		manifest.Files = append(manifest.Files, file)
+		spans = spans[1:]
+		retries = 0
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	w, err := sink.Create(path.Join(dir, BackupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// A keySpan is the span of keys [start, end).
+type keySpan struct {
+	start, end proto.Key
+}
+
+// rangeSpans returns the intersections of the span [start, end) with
+// the spans of the ranges it overlaps, in key order, as read from the
+// range addressing records through db. Commands which must not cross
+// range boundaries are issued once per span.
+func rangeSpans(db *client.KV, start, end proto.Key) ([]keySpan, error) {
+	reply := &proto.ScanResponse{}
+	if err := db.Call(proto.Scan, proto.ScanArgs(engine.KeyMeta2Prefix, engine.KeyMeta2Prefix.PrefixEnd(), 0), reply); err != nil {
+		return nil, err
+	}
+	var spans []keySpan
+	for _, row := range reply.Rows {
+		var desc proto.RangeDescriptor
+		if err := gogoproto.Unmarshal(row.Value.Bytes, &desc); err != nil {
+			return nil, util.Errorf("unable to unmarshal range descriptor at %q: %s", row.Key, err)
+		}
+		if !desc.StartKey.Less(end) || !start.Less(desc.EndKey) {
+			continue
+		}
+		span := keySpan{start: desc.StartKey, end: desc.EndKey}
+		if span.start.Less(start) {
+			span.start = start
+		}
+		if end.Less(span.end) {
+			span.end = end
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// ReadBackupManifest reads the manifest of the backup in directory
+// dir of sink.
+func ReadBackupManifest(sink ExportSink, dir string) (*BackupManifest, error) {
+	r, err := sink.Open(path.Join(dir, BackupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &BackupManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, util.Errorf("unable to parse backup manifest: %s", err)
+	}
+	return manifest, nil
+}
+
+// VerifyBackup checks the integrity of the backup in directory dir of
+// sink without restoring it: the files listed in the manifest must
+// cover the span of the backup without gaps or overlaps, and each
+// file must match its size and checksum and hold keys in order within
+// its span. The manifest is returned along with the first problem
+// found, if any.
+func VerifyBackup(sink ExportSink, dir string) (*BackupManifest, error) {
+	manifest, err := ReadBackupManifest(sink, dir)
+	if err != nil {
+		return nil, err
+	}
+	next := proto.Key(manifest.StartKey)
+	for _, file := range manifest.Files {
+		start, end := proto.Key(file.StartKey), proto.Key(file.EndKey)
+		if !start.Equal(next) {
+			if next.Less(start) {
+				return manifest, util.Errorf("span %q-%q is not covered by the backup", next, start)
+			}
+			return manifest, util.Errorf("file %s overlaps the span of the preceding file at %q", file.Name, start)
+		}
+		if !start.Less(end) {
+			return manifest, util.Errorf("file %s has invalid span %q-%q", file.Name, start, end)
+		}
+		if err := verifyBackupFile(sink, dir, file); err != nil {
+			return manifest, err
+		}
+		next = end
+	}
+	if !next.Equal(manifest.EndKey) {
+		return manifest, util.Errorf("span %q-%q is not covered by the backup", next, manifest.EndKey)
+	}
+	return manifest, nil
+}
+
+// verifyBackupFile checks that the backup file matches the size and
+// checksum recorded in the manifest and holds keys in order within
+// its span.
+func verifyBackupFile(sink ExportSink, dir string, file BackupFile) error {
+	r, err := sink.Open(path.Join(dir, file.Name))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	hash := sha256.New()
+	counter := &countingWriter{}
+	var keyCount int64
+	var lastKey proto.Key
+	err = ReadExport(io.TeeReader(r, io.MultiWriter(hash, counter)), func(kv proto.KeyValue) error {
+		if kv.Key.Less(file.StartKey) || !kv.Key.Less(file.EndKey) {
+			return util.Errorf("key %q is outside of span %q-%q", kv.Key, file.StartKey, file.EndKey)
+		}
+		if lastKey != nil && !lastKey.Less(kv.Key) {
+			return util.Errorf("key %q is out of order", kv.Key)
+		}
+		lastKey = kv.Key
+		keyCount++
+		return nil
+	})
+	if err != nil {
+		return util.Errorf("file %s is corrupt: %s", file.Name, err)
+	}
+	if counter.n != file.Size {
+		return util.Errorf("file %s has size %d; expected %d", file.Name, counter.n, file.Size)
+	}
+	if sum := hash.Sum(nil); file.SHA256 != hex.EncodeToString(sum) {
+		return util.Errorf("file %s has checksum %x; expected %s", file.Name, sum, file.SHA256)
+	}
+	if keyCount != file.KeyCount {
+		return util.Errorf("file %s has %d keys; expected %d", file.Name, keyCount, file.KeyCount)
+	}
+	return nil
+}
+
+// restoreBatchSize is the number of key/value pairs written by each
+// import of a restore.
+const restoreBatchSize = 100
+
+// Restore writes the data of the backup in directory dir of sink
+// through db, which may be a client of a different cluster than the
+// one backed up. The backup is verified before any of its data is
+// written. Values are written at their original timestamps, so the
+// restored span reads as the backed up span did as of the backup's
+// timestamp; the history preceding the backup's timestamp isn't part
+// of the backup and isn't restored. Before a range's share of the span
+// of the backup is written, all existing data in it is removed, so
+// the span must not be in use during the restore. Writes aren't
+// transactional, so a restore which fails may leave part of the
+// backup restored; it may be retried.
+func Restore(db *client.KV, sink ExportSink, dir string) (*BackupManifest, error) {
+	manifest, err := VerifyBackup(sink, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		if err := restoreBackupFile(db, sink, dir, file); err != nil {
+			return nil, util.Errorf("restore of %s failed: %s", file.Name, err)
+		}
+	}
+	return manifest, nil
+}
+
+// restoreBackupFile imports the key/value pairs of the backup file
+// through db. The span of the file is divided along the boundaries of
+// the ranges it overlaps; the first import into each range clears its
+// share of the span and each import writes at most restoreBatchSize
+// key/value pairs.
+func restoreBackupFile(db *client.KV, sink ExportSink, dir string, file BackupFile) error {
+	spans, err := rangeSpans(db, file.StartKey, file.EndKey)
+	if err != nil {
+		return err
+	}
+	if len(spans) == 0 {
+		return util.Errorf("no ranges found for span %q-%q", file.StartKey, file.EndKey)
+	}
+	r, err := sink.Open(path.Join(dir, file.Name))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var cleared bool
+	rows := make([]proto.KeyValue, 0, restoreBatchSize)
+	flush := func() error {
+		span := spans[0]
+		args := &proto.InternalImportRequest{
+			RequestHeader: proto.RequestHeader{Key: span.start, EndKey: span.end},
+			Rows:          rows,
+			Clear:         !cleared,
+		}
+		// Imports write absolute values at fixed timestamps, so they may
+		// be retried without the response cache.
+		if err := db.CallIdempotent(proto.InternalImport, args, &proto.InternalImportResponse{}); err != nil {
+			return util.Errorf("import into %q-%q failed: %s", span.start, span.end, err)
+		}
+		cleared = true
+		rows = rows[:0]
+		return nil
+	}
+	// next flushes the rows of the current span, clearing it if that
+	// hasn't been done yet, and moves on to the following one.
+	next := func() error {
+		if !cleared || len(rows) > 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		spans = spans[1:]
+		cleared = false
+		return nil
+	}
+	err = ReadExport(r, func(kv proto.KeyValue) error {
+		if err := kv.Value.Verify(kv.Key); err != nil {
+			return err
+		}
+		for !kv.Key.Less(spans[0].end) {
+			if err := next(); err != nil {
+				return err
+			}
+		}
+		rows = append(rows, kv)
+		if len(rows) == restoreBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Flush the last rows and clear the spans following them.
+	for len(spans) > 0 {
+		if err := next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
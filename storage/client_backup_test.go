@@ -0,0 +1,169 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestStoreBackupAndVerify backs up a span covering two ranges and
+// verifies that the backup passes verification until one of its files
+// is corrupted or dropped from the manifest.
+func TestStoreBackupAndVerify(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := storage.NewLocalExportSink(dir)
+	store.ExportSink = sink
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := store.DB().Call(proto.Put, proto.PutArgs(proto.Key(key), []byte(key)), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args, reply := adminSplitArgs(engine.KeyMin, []byte("c"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.AdminSplit, args, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := storage.Backup(store.DB(), sink, "backup", proto.Key("b"), proto.Key("z"),
+		store.Clock().Now(), "test-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 || manifest.Files[0].KeyCount != 1 || manifest.Files[1].KeyCount != 2 {
+		t.Fatalf("unexpected backup files %+v", manifest.Files)
+	}
+	if _, err := storage.VerifyBackup(sink, "backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt a file without changing its size.
+	path := filepath.Join(dir, "backup", manifest.Files[1].Name)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.VerifyBackup(sink, "backup"); err == nil {
+		t.Error("expected corruption to be detected")
+	}
+
+	// Drop the first file from the manifest.
+	manifest.Files = manifest.Files[1:]
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "backup", storage.BackupManifestName), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.VerifyBackup(sink, "backup"); err == nil || !strings.Contains(err.Error(), "not covered") {
+		t.Errorf("expected coverage gap; got %v", err)
+	}
+}
+
+// TestStoreBackupAndRestore backs up a span of one store and restores
+// it to another, split along different keys, verifying that the values
+// within the span are copied at their original timestamps and that
+// existing values within the span are removed.
+func TestStoreBackupAndRestore(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+	dest, destStopper := createTestStore(t)
+	defer destStopper.Stop()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := storage.NewLocalExportSink(dir)
+	store.ExportSink = sink
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := store.DB().Call(proto.Put, proto.PutArgs(proto.Key(key), []byte(key)), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"a", "e"} {
+		if err := dest.DB().Call(proto.Put, proto.PutArgs(proto.Key(key), []byte("dest")), &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args, reply := adminSplitArgs(engine.KeyMin, []byte("c"), 1, dest.StoreID())
+	if err := dest.ExecuteCmd(proto.AdminSplit, args, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := storage.Backup(store.DB(), sink, "backup", proto.Key("b"), proto.Key("z"),
+		store.Clock().Now(), "test-cluster"); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := storage.Restore(dest.DB(), sink, "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].KeyCount != 3 {
+		t.Fatalf("unexpected backup files %+v", manifest.Files)
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		ok, value, ts, err := dest.DB().Get(proto.Key(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch key {
+		case "a":
+			if !ok || string(value) != "dest" {
+				t.Errorf("expected key %q outside of the backup to be untouched; got %q", key, value)
+			}
+		case "e":
+			if ok {
+				t.Errorf("expected key %q within the backup's span to be removed; got %q", key, value)
+			}
+		default:
+			_, _, origTS, err := store.DB().Get(proto.Key(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || string(value) != key {
+				t.Errorf("expected key %q to be restored; got %q", key, value)
+			} else if !ts.Equal(origTS) {
+				t.Errorf("expected key %q to be restored at %s; got %s", key, origTS, ts)
+			}
+		}
+	}
+}
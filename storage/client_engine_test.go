@@ -0,0 +1,266 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// createFaultyTestStore creates a test store on a FaultyEngine which
+// wraps an in-memory engine. The caller is responsible for stopping
+// the store on exit.
+func createFaultyTestStore(t *testing.T) (*storage.Store, *engine.FaultyEngine, *util.Stopper) {
+	fe := engine.NewFaultyEngine(engine.NewInMem(proto.Attributes{}, 10<<20))
+	store, stopper := createTestStoreWithEngine(t, fe, hlc.NewClock(hlc.NewManualClock(0).UnixNano), true)
+	return store, fe, stopper
+}
+
+// keySpan returns the span of encoded keys holding all versions of
+// key.
+func keySpan(key proto.Key) (proto.EncodedKey, proto.EncodedKey) {
+	return engine.MVCCEncodeKey(key), engine.MVCCEncodeKey(key.Next())
+}
+
+// isInjected returns whether err was injected by a FaultyEngine.
+// Errors lose their type on their way back through the store.
+func isInjected(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "injected engine failure")
+}
+
+// TestStoreEngineWriteFailure verifies that a command whose writes
+// fail to commit returns the error to the client without applying
+// its effects, and that the range continues to accept commands.
+func TestStoreEngineWriteFailure(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, fe, stopper := createFaultyTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	fe.FailNthWriteIn(1, keySpan(key))
+	pArgs, pReply := putArgs(key, []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); !isInjected(err) {
+		t.Fatalf("expected injected write failure; got %v", err)
+	}
+	gArgs, gReply := getArgs(key, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if gReply.Value != nil {
+		t.Fatalf("expected failed put not to be applied; got %+v", gReply.Value)
+	}
+
+	pArgs, pReply = putArgs(key, []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	gArgs, gReply = getArgs(key, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if gReply.Value == nil || string(gReply.Value.Bytes) != "value" {
+		t.Fatalf("expected value after retried put; got %+v", gReply.Value)
+	}
+}
+
+// TestStoreEngineCorruption verifies that reads of corrupt data
+// return an error to the client, that reads of other keys are
+// unaffected and that slow engine writes delay but don't fail
+// commands.
+func TestStoreEngineCorruption(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, fe, stopper := createFaultyTestStore(t)
+	defer stopper.Stop()
+
+	fe.DelayWrites(10 * time.Millisecond)
+	for _, key := range []string{"a", "b"} {
+		pArgs, pReply := putArgs(proto.Key(key), []byte(key), 1, store.StoreID())
+		if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fe.DelayWrites(0)
+
+	fe.CorruptSpan(keySpan(proto.Key("a")))
+	gArgs, gReply := getArgs(proto.Key("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); !isInjected(err) {
+		t.Fatalf("expected read of corrupt key to fail; got %v", err)
+	}
+	gArgs, gReply = getArgs(proto.Key("b"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	sArgs := &proto.ScanRequest{
+		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
+			Key:     proto.Key("a"),
+			EndKey:  proto.Key("c"),
+			RaftID:  1,
+			Replica: proto.Replica{StoreID: store.StoreID()},
+		},
+	}
+	if err := store.ExecuteCmd(proto.Scan, sArgs, &proto.ScanResponse{}); !isInjected(err) {
+		t.Fatalf("expected scan of corrupt key to fail; got %v", err)
+	}
+
+	fe.CorruptSpan(nil, nil)
+	gArgs, gReply = getArgs(proto.Key("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStoreDeadlineExceeded verifies that commands whose deadline has
+// passed aren't executed, and that the store stops waiting for a slow
+// command at its deadline while the command is still applied exactly
+// once.
+func TestStoreDeadlineExceeded(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, fe, stopper := createFaultyTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	iArgs, iReply := incrementArgs(key, 1, 1, store.StoreID())
+	iArgs.Deadline = util.Now().Add(-time.Second).UnixNano()
+	if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); err == nil {
+		t.Fatal("expected command past its deadline to fail")
+	} else if _, ok := err.(*storage.DeadlineExceededError); !ok {
+		t.Fatalf("expected DeadlineExceededError; got %s", err)
+	}
+
+	fe.DelayWrites(100 * time.Millisecond)
+	iArgs, iReply = incrementArgs(key, 1, 1, store.StoreID())
+	iArgs.CmdID = proto.ClientCmdID{WallTime: 1, Random: 1}
+	iArgs.Deadline = util.Now().Add(10 * time.Millisecond).UnixNano()
+	if _, ok := store.ExecuteCmd(proto.Increment, iArgs, iReply).(*storage.DeadlineExceededError); !ok {
+		t.Fatalf("expected slow command to exceed its deadline; got %+v", iReply)
+	}
+	fe.DelayWrites(0)
+
+	// Retrying the command returns the result of its first execution.
+	iArgs.Deadline = 0
+	iReply = &proto.IncrementResponse{}
+	if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); err != nil {
+		t.Fatal(err)
+	}
+	if iReply.NewValue != 1 {
+		t.Errorf("expected increment to be applied once; got %d", iReply.NewValue)
+	}
+}
+
+// TestStoreReproposalAppliedOnce verifies that a command whose Raft
+// log entry is written slowly enough for it to be proposed again is
+// applied exactly once, though it's committed more than once.
+func TestStoreReproposalAppliedOnce(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, fe, stopper := createFaultyTestStore(t)
+	defer stopper.Stop()
+
+	// TestStoreConfig ticks every millisecond and reproposes commands
+	// which go uncommitted for ten ticks, so each command is proposed
+	// several times while its log entry is written.
+	fe.DelayWrites(50 * time.Millisecond)
+	key := proto.Key("a")
+	for i := int64(1); i <= 2; i++ {
+		iArgs, iReply := incrementArgs(key, 1, 1, store.StoreID())
+		if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); err != nil {
+			t.Fatal(err)
+		}
+		if iReply.NewValue != i {
+			t.Errorf("expected increment %d to yield %d; got %d", i, i, iReply.NewValue)
+		}
+	}
+	fe.DelayWrites(0)
+
+	gArgs, gReply := getArgs(key, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if gReply.Value == nil || gReply.Value.GetInteger() != 2 {
+		t.Fatalf("expected each increment to be applied once; got %+v", gReply.Value)
+	}
+}
+
+// TestStoreCrashRecovery verifies that a store restarted after its
+// engine crashes while applying a command recovers the data synced
+// before the crash, that the command, whose Raft log entry was synced,
+// is applied exactly once, and that the range serves commands again.
+func TestStoreCrashRecovery(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, fe, stopper := createFaultyTestStore(t)
+
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+
+	// Crash on committing the application of the increment. Its Raft
+	// log entry doesn't write the key, so it's synced before the crash.
+	key := proto.Key("b")
+	fe.FailNthSyncIn(1, keySpan(key))
+	iArgs, iReply := incrementArgs(key, 1, 1, store.StoreID())
+	iArgs.CmdID = proto.ClientCmdID{WallTime: 1, Random: 1}
+	if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); !isInjected(err) {
+		t.Fatalf("expected injected sync failure; got %v", err)
+	}
+	if !fe.Crashed() {
+		t.Fatal("expected engine to have crashed")
+	}
+
+	// Restart the store on the wrapped engine, holding an extra
+	// reference to it so that it isn't closed when the store stops.
+	e := fe.Engine
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	stopper.Stop()
+	store, stopper = createTestStoreWithEngine(t, e, hlc.NewClock(hlc.NewManualClock(1).UnixNano), false)
+	defer stopper.Stop()
+	e.Close()
+
+	gArgs, gReply := getArgs(proto.Key("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if gReply.Value == nil || string(gReply.Value.Bytes) != "value" {
+		t.Fatalf("expected value synced before the crash; got %+v", gReply.Value)
+	}
+
+	// Whether or not the increment was applied on replaying the Raft
+	// log, retrying it returns the result of a single application.
+	iReply = &proto.IncrementResponse{}
+	if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); err != nil {
+		t.Fatal(err)
+	}
+	if iReply.NewValue != 1 {
+		t.Errorf("expected increment to be applied once; got %d", iReply.NewValue)
+	}
+	iArgs, iReply = incrementArgs(key, 1, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Increment, iArgs, iReply); err != nil {
+		t.Fatal(err)
+	}
+	if iReply.NewValue != 2 {
+		t.Errorf("expected increment after recovery to yield 2; got %d", iReply.NewValue)
+	}
+}
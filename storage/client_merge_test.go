@@ -32,6 +32,7 @@ import (
 func adminMergeArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.AdminMergeRequest, *proto.AdminMergeResponse) {
 	args := &proto.AdminMergeRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -182,6 +183,48 @@ func TestStoreRangeMergeWithData(t *testing.T) {
 	}
 }
 
+// TestStoreRangeMergeClearsSubsumedMetadata verifies that a merge
+// removes the range-ID-local metadata of the subsumed range.
+func TestStoreRangeMergeClearsSubsumedMetadata(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	_, bDesc, err := createSplitRanges(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write to the subsumed range so that it has response cache entries
+	// and a Raft log in addition to its stats.
+	pArgs, pReply := putArgs([]byte("ccc"), []byte("value"), bDesc.RaftID, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	prefix := engine.RangeIDPrefix(bDesc.RaftID)
+	countKeys := func() int {
+		var count int
+		if err := engine.IteratePrefix(store.Engine(), prefix, func(_ proto.RawKeyValue) (bool, error) {
+			count++
+			return false, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	if count := countKeys(); count == 0 {
+		t.Fatal("expected range-ID-local keys for the range to be merged")
+	}
+
+	args, reply := adminMergeArgs(engine.KeyMin, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.AdminMerge, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if count := countKeys(); count != 0 {
+		t.Errorf("expected the subsumed range's metadata to be cleared; found %d keys", count)
+	}
+}
+
 // TestStoreRangeMergeLastRange verifies that merging the last range is a noop.
 func TestStoreRangeMergeLastRange(t *testing.T) {
 	defer leaktest.AfterTest(t)
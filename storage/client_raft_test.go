@@ -199,7 +199,7 @@ func TestReplicateRange(t *testing.T) {
 			NodeID:  mtc.stores[1].Ident.NodeID,
 			StoreID: mtc.stores[1].Ident.StoreID,
 			Attrs:   proto.Attributes{},
-		}); err != nil {
+		}, ""); err != nil {
 		t.Fatal(err)
 	}
 	// Verify no intent remain on range descriptor key.
@@ -208,13 +208,12 @@ func TestReplicateRange(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Verify that the same data is available on the replica.
-	// TODO(bdarnell): relies on the fact that we allow reads from followers.
-	// When we enforce reads from leader/quorum leases, we'll need to introduce a
-	// non-transactional local read for tests like this.
-	// Also applies to other tests in this file.
+	// Verify that the same data is available on the replica. The follower
+	// does not hold the leader lease, so use an inconsistent read. This also
+	// applies to other tests in this file.
 	if err := util.IsTrueWithin(func() bool {
 		getArgs, getResp := getArgs([]byte("a"), 1, mtc.stores[1].StoreID())
+		getArgs.ReadConsistency = proto.INCONSISTENT
 		if err := mtc.stores[1].ExecuteCmd(proto.Get, getArgs, getResp); err != nil {
 			return false
 		}
@@ -224,6 +223,62 @@ func TestReplicateRange(t *testing.T) {
 	}
 }
 
+// TestInternalChangeReplicas verifies that a replica can be added to a
+// range other than the first via the InternalChangeReplicas command,
+// that the new replica initializes itself from a snapshot and that the
+// range addressing records reflect the change.
+func TestInternalChangeReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	mtc := multiTestContext{}
+	mtc.Start(t, 2)
+	defer mtc.Stop()
+
+	store := mtc.stores[0]
+	splitArgs, splitResp := adminSplitArgs(engine.KeyMin, []byte("m"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.AdminSplit, splitArgs, splitResp); err != nil {
+		t.Fatal(err)
+	}
+	rng := store.LookupRange(proto.Key("m"), nil)
+	if rng == nil {
+		t.Fatal("range for key \"m\" not found after split")
+	}
+
+	args := &proto.InternalChangeReplicasRequest{
+		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
+			Key:     proto.Key("m"),
+			RaftID:  rng.Desc().RaftID,
+			Replica: proto.Replica{StoreID: store.StoreID()},
+		},
+		ChangeType: proto.ADD_REPLICA,
+		Replica: proto.Replica{
+			NodeID:  mtc.stores[1].Ident.NodeID,
+			StoreID: mtc.stores[1].Ident.StoreID,
+		},
+	}
+	if err := store.ExecuteCmd(proto.InternalChangeReplicas, args, &proto.InternalChangeReplicasResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new replica learns its key span from the snapshot.
+	if err := util.IsTrueWithin(func() bool {
+		newRng := mtc.stores[1].LookupRange(proto.Key("m"), nil)
+		return newRng != nil && newRng.Desc().RaftID == rng.Desc().RaftID
+	}, 1*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The meta2 addressing record lists both replicas.
+	if err := util.IsTrueWithin(func() bool {
+		var desc proto.RangeDescriptor
+		ok, err := engine.MVCCGetProto(store.Engine(), engine.MakeKey(engine.KeyMeta2Prefix, engine.KeyMax),
+			store.Clock().Now(), true, nil, &desc)
+		return err == nil && ok && len(desc.Replicas) == 2
+	}, 1*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestRestoreReplicas ensures that consensus group membership is properly
 // persisted to disk and restored when a node is stopped and restarted.
 func TestRestoreReplicas(t *testing.T) {
@@ -249,7 +304,7 @@ func TestRestoreReplicas(t *testing.T) {
 			NodeID:  mtc.stores[1].Ident.NodeID,
 			StoreID: mtc.stores[1].Ident.StoreID,
 			Attrs:   proto.Attributes{},
-		}); err != nil {
+		}, ""); err != nil {
 		t.Fatal(err)
 	}
 
@@ -261,31 +316,33 @@ func TestRestoreReplicas(t *testing.T) {
 
 	// TODO(bdarnell): initial creation and replication needs to be atomic;
 	// cutting off the process too soon currently results in a corrupted range.
-	time.Sleep(500 * time.Millisecond)
+	mtc.waitForFullReplication(t, 1)
+	mtc.waitForValues(t, proto.Key("a"), []int64{23, 23})
 
 	mtc.Restart(t)
 
-	// Send a command on each store. The follower will forward to the leader and both
-	// commands will eventually commit.
+	// Send a command on each store. The follower does not hold the leader
+	// lease and must redirect to the leader, where both commands will
+	// eventually commit.
 	incArgs, incResp = incrementArgs([]byte("a"), 5, 1, mtc.stores[0].StoreID())
 	if err := mtc.stores[0].ExecuteCmd(proto.Increment, incArgs, incResp); err != nil {
 		t.Fatal(err)
 	}
 	incArgs, incResp = incrementArgs([]byte("a"), 11, 1, mtc.stores[1].StoreID())
-	if err := mtc.stores[1].ExecuteCmd(proto.Increment, incArgs, incResp); err != nil {
-		t.Fatal(err)
+	if err := mtc.stores[1].ExecuteCmd(proto.Increment, incArgs, incResp); err == nil {
+		t.Fatal("expected follower to reject increment")
+	} else if nlErr, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	} else if nlErr.Leader.StoreID != mtc.stores[0].StoreID() {
+		t.Fatalf("expected redirect to store %d; got %+v", mtc.stores[0].StoreID(), nlErr.Leader)
 	}
-
-	if err := util.IsTrueWithin(func() bool {
-		getArgs, getResp := getArgs([]byte("a"), 1, mtc.stores[1].StoreID())
-		if err := mtc.stores[1].ExecuteCmd(proto.Get, getArgs, getResp); err != nil {
-			return false
-		}
-		return getResp.Value.GetInteger() == 39
-	}, 1*time.Second); err != nil {
+	incArgs, incResp = incrementArgs([]byte("a"), 11, 1, mtc.stores[0].StoreID())
+	if err := mtc.stores[0].ExecuteCmd(proto.Increment, incArgs, incResp); err != nil {
 		t.Fatal(err)
 	}
 
+	mtc.waitForValues(t, proto.Key("a"), []int64{39, 39})
+
 	// Both replicas have a complete list in Desc.Replicas
 	for i, store := range mtc.stores {
 		rng, err := store.GetRange(1)
@@ -304,6 +361,65 @@ func TestRestoreReplicas(t *testing.T) {
 	}
 }
 
+// TestLeaderLeaseAndLivenessExpiry verifies that with independent
+// clocks, a follower acquires the leader lease once the lease has
+// expired by its own clock, and that the liveness of a single node
+// can be expired as seen by all others.
+func TestLeaderLeaseAndLivenessExpiry(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	mtc := multiTestContext{independentClocks: true}
+	mtc.Start(t, 2)
+	defer mtc.Stop()
+
+	rng, err := mtc.stores[0].GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incArgs, incResp := incrementArgs([]byte("a"), 5, 1, mtc.stores[0].StoreID())
+	if err := mtc.stores[0].ExecuteCmd(proto.Increment, incArgs, incResp); err != nil {
+		t.Fatal(err)
+	}
+	if err := rng.ChangeReplicas(proto.ADD_REPLICA,
+		proto.Replica{
+			NodeID:  mtc.stores[1].Ident.NodeID,
+			StoreID: mtc.stores[1].Ident.StoreID,
+		}, ""); err != nil {
+		t.Fatal(err)
+	}
+	mtc.waitForFullReplication(t, 1)
+	mtc.waitForValues(t, proto.Key("a"), []int64{5, 5})
+
+	// Advancing only the leader's clock doesn't free the lease for the
+	// follower, whose clock still falls within it.
+	mtc.advanceClock(0, 10*time.Second)
+	incArgs, incResp = incrementArgs([]byte("a"), 7, 1, mtc.stores[1].StoreID())
+	if err := mtc.stores[1].ExecuteCmd(proto.Increment, incArgs, incResp); err == nil {
+		t.Fatal("expected follower to reject increment")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	}
+
+	// Once the lease has expired on all clocks, the follower takes it.
+	mtc.expireLeaderLease(t, 1)
+	if err := mtc.stores[1].ExecuteCmd(proto.Increment, incArgs, incResp); err != nil {
+		t.Fatal(err)
+	}
+	mtc.waitForValues(t, proto.Key("a"), []int64{12, 12})
+
+	// Expire the liveness of the second node only.
+	mtc.heartbeatLiveness(t, 0)
+	mtc.heartbeatLiveness(t, 1)
+	mtc.expireLiveness(t, 1)
+	for i, nl := range mtc.livenesses {
+		if nl.IsDead(mtc.stores[0].Ident.NodeID) {
+			t.Errorf("store %d: expected node %d to be live", i, mtc.stores[0].Ident.NodeID)
+		}
+		if !nl.IsDead(mtc.stores[1].Ident.NodeID) {
+			t.Errorf("store %d: expected node %d to be dead", i, mtc.stores[1].Ident.NodeID)
+		}
+	}
+}
+
 func TestFailedReplicaChange(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	mtc := multiTestContext{}
@@ -331,7 +447,7 @@ func TestFailedReplicaChange(t *testing.T) {
 			NodeID:  mtc.stores[1].Ident.NodeID,
 			StoreID: mtc.stores[1].Ident.StoreID,
 			Attrs:   proto.Attributes{},
-		})
+		}, "")
 	if err == nil || !strings.Contains(err.Error(), "boom") {
 		t.Fatalf("did not get expected error: %s", err)
 	}
@@ -351,7 +467,7 @@ func TestFailedReplicaChange(t *testing.T) {
 			NodeID:  mtc.stores[1].Ident.NodeID,
 			StoreID: mtc.stores[1].Ident.StoreID,
 			Attrs:   proto.Attributes{},
-		})
+		}, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -417,13 +533,14 @@ func TestReplicateAfterTruncation(t *testing.T) {
 			NodeID:  mtc.stores[1].Ident.NodeID,
 			StoreID: mtc.stores[1].Ident.StoreID,
 			Attrs:   proto.Attributes{},
-		}); err != nil {
+		}, ""); err != nil {
 		t.Fatal(err)
 	}
 
 	// Once it catches up, the effects of both commands can be seen.
 	if err := util.IsTrueWithin(func() bool {
 		getArgs, getResp := getArgs([]byte("a"), 1, mtc.stores[1].StoreID())
+		getArgs.ReadConsistency = proto.INCONSISTENT
 		if err := mtc.stores[1].ExecuteCmd(proto.Get, getArgs, getResp); err != nil {
 			return false
 		}
@@ -442,6 +559,7 @@ func TestReplicateAfterTruncation(t *testing.T) {
 
 	if err := util.IsTrueWithin(func() bool {
 		getArgs, getResp := getArgs([]byte("a"), 1, mtc.stores[1].StoreID())
+		getArgs.ReadConsistency = proto.INCONSISTENT
 		if err := mtc.stores[1].ExecuteCmd(proto.Get, getArgs, getResp); err != nil {
 			return false
 		}
@@ -38,6 +38,7 @@ import (
 func adminSplitArgs(key, splitKey []byte, raftID int64, storeID proto.StoreID) (*proto.AdminSplitRequest, *proto.AdminSplitResponse) {
 	args := &proto.AdminSplitRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -138,6 +139,32 @@ func TestStoreRangeSplitConcurrent(t *testing.T) {
 	}
 }
 
+// TestStoreRangeSplitEventLog verifies that a split is recorded in the
+// range event log along with its reason.
+func TestStoreRangeSplitEventLog(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	args, reply := adminSplitArgs(engine.KeyMin, []byte("a"), 1, store.StoreID())
+	args.Reason = "test"
+	if err := store.ExecuteCmd(proto.AdminSplit, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	newRng := store.LookupRange([]byte("a"), nil)
+	events, err := storage.ScanRangeEvents(store.DB(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event; got %+v", events)
+	}
+	if e := events[0]; e.EventType != proto.RANGE_SPLIT || e.RaftID != 1 ||
+		e.OtherRaftID != newRng.Desc().RaftID || e.StoreID != store.StoreID() || e.Reason != "test" {
+		t.Errorf("unexpected split event %+v", e)
+	}
+}
+
 // TestStoreRangeSplit executes a split of a range and verifies that the
 // resulting ranges respond to the right key ranges and that their stats
 // and response caches have been properly accounted for.
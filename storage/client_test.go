@@ -27,9 +27,12 @@ package storage_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
@@ -96,11 +99,34 @@ type multiTestContext struct {
 	engines     []engine.Engine
 	stores      []*storage.Store
 	stopper     *util.Stopper
+
+	// If independentClocks is set before Start, each store is given its
+	// own manual clock, starting at the time of manualClock, which can
+	// be advanced separately to expire leases and liveness records as
+	// seen by some stores but not others. Otherwise all stores share
+	// manualClock and clock.
+	independentClocks bool
+	manualClocks      []*hlc.ManualClock
+	clocks            []*hlc.Clock
+	livenesses        []*storage.NodeLiveness
+}
+
+// newManualClock returns a manual clock set to nanos. In builds with
+// the virtualclock tag, the clock also drives all tickers and timers
+// via a newly installed util.VirtualClock, so advancing it fires lease
+// expirations, heartbeats and scanner intervals deterministically.
+func newManualClock(nanos int64) *hlc.ManualClock {
+	if !util.VirtualClockEnabled {
+		return hlc.NewManualClock(nanos)
+	}
+	vc := util.NewVirtualClock(nanos)
+	util.SetVirtualClock(vc)
+	return hlc.NewVirtualManualClock(vc)
 }
 
 func (m *multiTestContext) Start(t *testing.T, numStores int) {
 	if m.manualClock == nil {
-		m.manualClock = hlc.NewManualClock(0)
+		m.manualClock = newManualClock(0)
 	}
 	if m.clock == nil {
 		m.clock = hlc.NewClock(m.manualClock.UnixNano)
@@ -132,6 +158,9 @@ func (m *multiTestContext) Start(t *testing.T, numStores int) {
 
 func (m *multiTestContext) Stop() {
 	m.stopper.Stop()
+	if util.VirtualClockEnabled {
+		util.SetVirtualClock(nil)
+	}
 }
 
 // AddStore creates a new store on the same Transport but doesn't create any ranges.
@@ -147,7 +176,21 @@ func (m *multiTestContext) addStore(t *testing.T) {
 		needBootstrap = true
 	}
 
-	store := storage.NewStore(m.clock, eng, m.db, m.gossip, m.transport, storage.TestStoreConfig)
+	manual, clock := m.manualClock, m.clock
+	if m.independentClocks {
+		manual = hlc.NewManualClock(m.manualClock.UnixNano())
+		clock = hlc.NewClock(manual.UnixNano)
+	}
+	m.manualClocks = append(m.manualClocks, manual)
+	m.clocks = append(m.clocks, clock)
+	// Liveness records are only written by heartbeatLiveness, so that
+	// tests control exactly when nodes are live or dead.
+	liveness := storage.NewNodeLiveness(m.db, m.gossip, clock, storage.DefaultNodeLivenessThreshold)
+	m.livenesses = append(m.livenesses, liveness)
+
+	config := storage.TestStoreConfig
+	config.NodeLiveness = liveness
+	store := storage.NewStore(clock, eng, m.db, m.gossip, m.transport, config)
 	if needBootstrap {
 		err := store.Bootstrap(proto.StoreIdent{
 			NodeID:  proto.NodeID(idx + 1),
@@ -173,6 +216,8 @@ func (m *multiTestContext) addStore(t *testing.T) {
 
 // Restart stops and restarts all stores but leaves the engines intact,
 // so the stores should contain the same persistent storage as before.
+// In-memory engines are saved to files and reloaded, so that the
+// restarted stores hold only what their engines persist.
 func (m *multiTestContext) Restart(t *testing.T) {
 	// Add extra ref counts to engines so the underlying rocksdb instances
 	// aren't closed when stopping and restarting the stores.
@@ -181,12 +226,24 @@ func (m *multiTestContext) Restart(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
-	nanos := m.manualClock.UnixNano() + 1
+	nanos := m.manualClock.UnixNano()
+	for _, manual := range m.manualClocks {
+		if n := manual.UnixNano(); n > nanos {
+			nanos = n
+		}
+	}
 	engines := m.engines
+	independentClocks := m.independentClocks
 	m.Stop()
+	for i, e := range engines {
+		if inMem, ok := e.(*engine.InMem); ok {
+			engines[i] = reloadInMem(t, inMem)
+		}
+	}
 	*m = multiTestContext{
-		manualClock: hlc.NewManualClock(nanos),
-		engines:     engines,
+		manualClock:       newManualClock(nanos + 1),
+		engines:           engines,
+		independentClocks: independentClocks,
 	}
 	m.Start(t, len(engines))
 	// Remove extra ref counts.
@@ -195,11 +252,140 @@ func (m *multiTestContext) Restart(t *testing.T) {
 	}
 }
 
+// reloadInMem saves the contents of e to a file, closes it and returns
+// a new engine loaded from the file.
+func reloadInMem(t *testing.T, e *engine.InMem) *engine.InMem {
+	f, err := ioutil.TempFile("", "inmem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := e.SaveFile(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+	reloaded, err := engine.NewInMemFromFile(e.Attrs(), 1<<20, f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloaded
+}
+
+// advanceClock advances the clock of the store at index idx by d.
+// Unless the context has independent clocks, this advances the clocks
+// of all stores.
+func (m *multiTestContext) advanceClock(idx int, d time.Duration) {
+	m.manualClocks[idx].Increment(d.Nanoseconds())
+}
+
+// forwardClocks moves the clocks of all stores, and the context's own
+// clock, forward to nanos. Clocks already at or beyond nanos are left
+// untouched.
+func (m *multiTestContext) forwardClocks(nanos int64) {
+	for _, manual := range append([]*hlc.ManualClock{m.manualClock}, m.manualClocks...) {
+		if manual.UnixNano() < nanos {
+			manual.Set(nanos)
+		}
+	}
+}
+
+// expireLeaderLease moves the clocks of all stores past the expiration
+// of the leader lease of the range raftID, as last applied by any of
+// its replicas, so that any replica may acquire a new lease.
+func (m *multiTestContext) expireLeaderLease(t *testing.T, raftID int64) {
+	var expiration int64
+	for _, store := range m.stores {
+		rng, err := store.GetRange(raftID)
+		if err != nil {
+			continue
+		}
+		if l := rng.LeaderLease(); l != nil && l.Expiration > expiration {
+			expiration = l.Expiration
+		}
+	}
+	if expiration == 0 {
+		t.Fatalf("no replica of range %d has applied a leader lease", raftID)
+	}
+	m.forwardClocks(expiration + m.clock.MaxOffset().Nanoseconds() + 1)
+}
+
+// heartbeatLiveness writes a liveness record for the node of the store
+// at index idx, keeping the node live for the liveness threshold as
+// measured by that store's clock.
+func (m *multiTestContext) heartbeatLiveness(t *testing.T, idx int) {
+	if err := m.livenesses[idx].Heartbeat(m.stores[idx].Ident.NodeID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expireLiveness moves the clocks of all stores past the expiration of
+// the liveness record of the node of the store at index idx and then
+// renews the records of all other nodes which had one, so that only
+// that node is considered dead.
+func (m *multiTestContext) expireLiveness(t *testing.T, idx int) {
+	lr, ok := m.livenesses[idx].GetLiveness(m.stores[idx].Ident.NodeID)
+	if !ok {
+		t.Fatalf("node %d has no liveness record", m.stores[idx].Ident.NodeID)
+	}
+	m.forwardClocks(lr.Expiration)
+	for i, store := range m.stores {
+		if i == idx {
+			continue
+		}
+		if _, ok := m.livenesses[i].GetLiveness(store.Ident.NodeID); ok {
+			m.heartbeatLiveness(t, i)
+		}
+	}
+}
+
+// waitForFullReplication waits until every store holds a replica of
+// the range raftID whose descriptor lists a replica on every store.
+func (m *multiTestContext) waitForFullReplication(t *testing.T, raftID int64) {
+	if err := util.IsTrueWithin(func() bool {
+		for _, store := range m.stores {
+			rng, err := store.GetRange(raftID)
+			if err != nil {
+				return false
+			}
+			for _, other := range m.stores {
+				if _, replica := rng.Desc().FindReplica(other.StoreID()); replica == nil {
+					return false
+				}
+			}
+		}
+		return true
+	}, 1*time.Second); err != nil {
+		t.Fatalf("range %d not fully replicated: %s", raftID, err)
+	}
+}
+
+// waitForValues waits until the integer value of key read directly
+// from the engine of each store equals the corresponding expected
+// value. A missing key reads as zero.
+func (m *multiTestContext) waitForValues(t *testing.T, key proto.Key, expected []int64) {
+	var actual []int64
+	if err := util.IsTrueWithin(func() bool {
+		actual = make([]int64, len(m.engines))
+		for i, eng := range m.engines {
+			val, err := engine.MVCCGet(eng, key, m.clock.Now(), true, nil)
+			if err != nil {
+				return false
+			}
+			actual[i] = val.GetInteger()
+		}
+		return reflect.DeepEqual(expected, actual)
+	}, 1*time.Second); err != nil {
+		t.Fatalf("expected values %v for key %q; got %v", expected, key, actual)
+	}
+}
+
 // getArgs returns a GetRequest and GetResponse pair addressed to
 // the default replica for the specified key.
 func getArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.GetRequest, *proto.GetResponse) {
 	args := &proto.GetRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -214,6 +400,7 @@ func getArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.GetRequest
 func putArgs(key, value []byte, raftID int64, storeID proto.StoreID) (*proto.PutRequest, *proto.PutResponse) {
 	args := &proto.PutRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -231,6 +418,7 @@ func putArgs(key, value []byte, raftID int64, storeID proto.StoreID) (*proto.Put
 func incrementArgs(key []byte, inc int64, raftID int64, storeID proto.StoreID) (*proto.IncrementRequest, *proto.IncrementResponse) {
 	args := &proto.IncrementRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -245,6 +433,7 @@ func internalTruncateLogArgs(index uint64, raftID int64, storeID proto.StoreID)
 	*proto.InternalTruncateLogRequest, *proto.InternalTruncateLogResponse) {
 	args := &proto.InternalTruncateLogRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    storage.UserRoot,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
 		},
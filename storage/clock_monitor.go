@@ -0,0 +1,159 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// defaultClockJumpThreshold is the default divergence between the
+	// wall clock and elapsed monotonic time considered a clock jump.
+	defaultClockJumpThreshold = 500 * time.Millisecond
+	// clockJumpEventBufferSize is the number of clock jump events
+	// buffered for consumers; further events are dropped.
+	clockJumpEventBufferSize = 16
+)
+
+// A ClockJumpError indicates that the store has suspended
+// lease-dependent commands following a jump of its wall clock.
+type ClockJumpError struct {
+	Until time.Time // Monotonic time at which the suspension ends
+}
+
+// Error formats error.
+func (e *ClockJumpError) Error() string {
+	return fmt.Sprintf("leader leases suspended after clock jump; retry in %s", e.Until.Sub(util.Now()))
+}
+
+// CanRetry implements the util.Retryable interface.
+func (e *ClockJumpError) CanRetry() bool {
+	return true
+}
+
+// A ClockJumpEvent describes a jump of the wall clock detected by a
+// store's clock monitor.
+type ClockJumpEvent struct {
+	// Jump is the amount by which the wall clock diverged from elapsed
+	// monotonic time; negative for backward jumps.
+	Jump time.Duration
+	// SuspendedUntil is the monotonic time at which lease-dependent
+	// commands resume.
+	SuspendedUntil time.Time
+}
+
+// A clockMonitor periodically compares the advance of the hybrid
+// logical clock's physical clock with elapsed monotonic time. If the
+// two diverge by more than the threshold (e.g. a VM was paused or
+// the clock was set manually), lease-dependent commands are suspended
+// for the maximum lease duration plus the clock's max offset, so that
+// every lease granted under the old clock readings has expired by the
+// time commands resume. The HLC itself never moves backwards, so no
+// adjustment is made to it; leases held by this store are invalidated
+// via onJump and must be reacquired under the new clock readings.
+type clockMonitor struct {
+	clock     *hlc.Clock
+	threshold time.Duration
+	onJump    func()
+	events    chan *ClockJumpEvent
+
+	mu             sync.Mutex
+	lastMono       time.Time // Monotonic time of last observation
+	lastWall       int64     // Physical clock at last observation
+	suspendedUntil time.Time // Lease-dependent commands are suspended until
+}
+
+// newClockMonitor returns a clock monitor for the supplied clock. A
+// negative threshold disables jump detection.
+func newClockMonitor(clock *hlc.Clock, threshold time.Duration, onJump func()) *clockMonitor {
+	return &clockMonitor{
+		clock:     clock,
+		threshold: threshold,
+		onJump:    onJump,
+		events:    make(chan *ClockJumpEvent, clockJumpEventBufferSize),
+	}
+}
+
+// start begins monitoring the clock at half the jump threshold.
+func (cm *clockMonitor) start(stopper *util.Stopper) {
+	if cm.threshold < 0 {
+		return
+	}
+	cm.observe(util.Now(), cm.clock.PhysicalNow())
+	stopper.RunWorker(func() {
+		ticker := util.NewTicker(cm.threshold / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cm.observe(util.Now(), cm.clock.PhysicalNow())
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// observe records a pair of monotonic and wall clock readings and
+// reacts to a jump if the wall clock advanced by more or less than
+// the monotonic time elapsed since the previous observation.
+func (cm *clockMonitor) observe(mono time.Time, wall int64) {
+	cm.mu.Lock()
+	lastMono, lastWall := cm.lastMono, cm.lastWall
+	cm.lastMono, cm.lastWall = mono, wall
+	cm.mu.Unlock()
+	if lastMono.IsZero() {
+		return
+	}
+	jump := time.Duration(wall-lastWall) - mono.Sub(lastMono)
+	if jump > -cm.threshold && jump < cm.threshold {
+		return
+	}
+
+	until := mono.Add(defaultLeaderLeaseDuration + cm.clock.MaxOffset())
+	cm.mu.Lock()
+	if until.After(cm.suspendedUntil) {
+		cm.suspendedUntil = until
+	}
+	cm.mu.Unlock()
+	log.Warningf("wall clock jumped by %s; suspending leader leases for %s",
+		jump, until.Sub(mono))
+	if cm.onJump != nil {
+		cm.onJump()
+	}
+	select {
+	case cm.events <- &ClockJumpEvent{Jump: jump, SuspendedUntil: until}:
+	default:
+		// Drop the event if nobody is listening.
+	}
+}
+
+// checkLeases returns a ClockJumpError if lease-dependent commands are
+// currently suspended following a clock jump.
+func (cm *clockMonitor) checkLeases() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if util.Now().Before(cm.suspendedUntil) {
+		return &ClockJumpError{Until: cm.suspendedUntil}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestClockMonitorJumps verifies that forward and backward jumps of
+// the wall clock relative to elapsed time suspend leader leases and
+// emit events, while small drift is ignored.
+func TestClockMonitorJumps(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	jumps := 0
+	cm := newClockMonitor(clock, 100*time.Millisecond, func() { jumps++ })
+
+	mono := time.Now()
+	cm.observe(mono, manual.UnixNano())
+
+	// Drift below the threshold is ignored.
+	mono = mono.Add(time.Second)
+	manual.Increment((time.Second + 50*time.Millisecond).Nanoseconds())
+	cm.observe(mono, manual.UnixNano())
+	if err := cm.checkLeases(); err != nil {
+		t.Fatalf("unexpected suspension: %s", err)
+	}
+
+	testCases := []struct {
+		elapsed, wall time.Duration
+	}{
+		{time.Second, time.Hour},        // forward jump
+		{time.Second, -time.Hour},       // backward jump
+		{time.Millisecond, time.Second}, // VM pause observed as forward jump
+	}
+	for i, test := range testCases {
+		mono = mono.Add(test.elapsed)
+		manual.Increment(test.wall.Nanoseconds())
+		cm.observe(mono, manual.UnixNano())
+		if jumps != i+1 {
+			t.Errorf("%d: expected %d jumps; got %d", i, i+1, jumps)
+		}
+		err := cm.checkLeases()
+		if _, ok := err.(*ClockJumpError); !ok {
+			t.Errorf("%d: expected clock jump error; got %v", i, err)
+		} else if !err.(util.Retryable).CanRetry() {
+			t.Errorf("%d: expected clock jump error to be retryable", i)
+		}
+		select {
+		case e := <-cm.events:
+			if exp := test.wall - test.elapsed; e.Jump != exp {
+				t.Errorf("%d: expected jump of %s; got %s", i, exp, e.Jump)
+			}
+		default:
+			t.Errorf("%d: expected clock jump event", i)
+		}
+	}
+}
+
+// TestClockMonitorDisabled verifies that a negative threshold
+// disables clock jump detection.
+func TestClockMonitorDisabled(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	cm := newClockMonitor(hlc.NewClock(hlc.NewManualClock(0).UnixNano), -1, nil)
+	cm.start(stopper)
+	if !cm.lastMono.IsZero() {
+		t.Errorf("expected disabled clock monitor not to observe the clock")
+	}
+}
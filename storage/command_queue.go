@@ -132,3 +132,8 @@ func (cq *CommandQueue) Remove(key interface{}) {
 func (cq *CommandQueue) Clear() {
 	cq.cache.Clear()
 }
+
+// Len returns the number of commands in the queue.
+func (cq *CommandQueue) Len() int {
+	return cq.cache.Len()
+}
@@ -0,0 +1,177 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// defaultCompactionInterval is the interval at which the compactor
+	// checks whether vacated spans should be compacted.
+	defaultCompactionInterval = 1 * time.Minute
+	// defaultCompactionThreshold is the estimated number of reclaimable
+	// bytes which triggers a compaction of vacated spans.
+	defaultCompactionThreshold = 32 << 20 // 32M
+)
+
+// A compactionSpan is a span of encoded engine keys vacated by the
+// removal of data.
+type compactionSpan struct {
+	start, end proto.EncodedKey
+}
+
+// compactionSpans sorts spans by start key.
+type compactionSpans []compactionSpan
+
+func (cs compactionSpans) Len() int           { return len(cs) }
+func (cs compactionSpans) Swap(i, j int)      { cs[i], cs[j] = cs[j], cs[i] }
+func (cs compactionSpans) Less(i, j int) bool { return bytes.Compare(cs[i].start, cs[j].start) < 0 }
+
+// A compactor schedules engine compactions of key spans vacated by
+// range merges, log truncations, replica removal and garbage
+// collection. Deleted entries hold on to disk
+// space until the engine compacts the files containing them, which
+// for spans that see no further writes may take arbitrarily long. The
+// compactor accumulates the vacated spans along with an estimate of
+// the bytes they occupied and compacts them once the estimate exceeds
+// a threshold.
+type compactor struct {
+	eng       engine.Engine
+	interval  time.Duration
+	threshold int64
+
+	mu          sync.Mutex
+	spans       compactionSpans
+	reclaimable int64 // Estimated reclaimable bytes in spans
+}
+
+// newCompactor returns a compactor for the supplied engine.
+func newCompactor(eng engine.Engine, interval time.Duration, threshold int64) *compactor {
+	return &compactor{
+		eng:       eng,
+		interval:  interval,
+		threshold: threshold,
+	}
+}
+
+// suggest records a span which is about to be vacated. The bytes the
+// span currently occupies are added to the reclaimable estimate, so
+// suggest must be called before the span's data is removed.
+func (c *compactor) suggest(start, end proto.EncodedKey) {
+	c.suggestBytes(start, end, c.estimate(start, end))
+}
+
+// estimate returns the approximate number of bytes the span occupies.
+func (c *compactor) estimate(start, end proto.EncodedKey) int64 {
+	size, err := c.eng.ApproximateSize(start, end)
+	if err != nil {
+		log.Warningf("unable to estimate size of vacated span %q-%q: %s", start, end, err)
+	}
+	return int64(size)
+}
+
+// suggestBytes records a vacated span for which the caller has
+// estimated the reclaimable bytes, as when only part of the span's
+// data is removed.
+func (c *compactor) suggestBytes(start, end proto.EncodedKey, bytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, compactionSpan{start: start, end: end})
+	c.reclaimable += bytes
+}
+
+// suggestOnCommit records a span which the supplied batch vacates
+// once the batch commits, so that no compaction is suggested for
+// mutations which are abandoned. The span's size is estimated
+// immediately, before its data is removed. Writes made directly to an
+// engine rather than a batch are suggested immediately.
+func (c *compactor) suggestOnCommit(batch engine.Engine, start, end proto.EncodedKey) {
+	c.suggestBytesOnCommit(batch, start, end, c.estimate(start, end))
+}
+
+// suggestBytesOnCommit is like suggestOnCommit for a span whose
+// reclaimable bytes the caller has estimated.
+func (c *compactor) suggestBytesOnCommit(batch engine.Engine, start, end proto.EncodedKey, bytes int64) {
+	onCommit(batch, func() { c.suggestBytes(start, end, bytes) })
+}
+
+// ReclaimableBytes returns the estimated number of bytes which will
+// be reclaimed by compacting the vacated spans.
+func (c *compactor) ReclaimableBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reclaimable
+}
+
+// start runs a worker which compacts vacated spans whenever the
+// reclaimable bytes exceed the threshold.
+func (c *compactor) start(stopper *util.Stopper) {
+	stopper.RunWorker(func() {
+		ticker := util.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if c.ReclaimableBytes() >= c.threshold {
+					c.compact()
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// compact compacts all vacated spans, coalescing overlapping and
+// adjacent spans, and resets the reclaimable bytes estimate.
+func (c *compactor) compact() {
+	c.mu.Lock()
+	spans, reclaimable := c.spans, c.reclaimable
+	c.spans, c.reclaimable = nil, 0
+	c.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	sort.Sort(spans)
+	merged := compactionSpans{spans[0]}
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if bytes.Compare(s.start, last.end) <= 0 {
+			if bytes.Compare(s.end, last.end) > 0 {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	start := time.Now()
+	for _, s := range merged {
+		c.eng.CompactRange(s.start, s.end)
+	}
+	log.Infof("compacted %d vacated spans with an estimated %d reclaimable bytes in %s",
+		len(merged), reclaimable, time.Now().Sub(start))
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// compactionRecorder records compacted spans in place of compacting.
+type compactionRecorder struct {
+	engine.Engine
+	compacted compactionSpans
+}
+
+func (cr *compactionRecorder) CompactRange(start, end proto.EncodedKey) {
+	cr.compacted = append(cr.compacted, compactionSpan{start: start, end: end})
+}
+
+// TestCompactorReclaimableBytes verifies that suggested spans
+// accumulate an estimate of reclaimable bytes and that compaction
+// coalesces overlapping spans and resets the estimate.
+func TestCompactorReclaimableBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	for i := 0; i < 1000; i++ {
+		key := proto.EncodedKey(fmt.Sprintf("key%04d", i))
+		if err := eng.Put(key, []byte(fmt.Sprintf("value%04d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := eng.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &compactionRecorder{Engine: eng}
+	c := newCompactor(cr, defaultCompactionInterval, defaultCompactionThreshold)
+	c.suggest(proto.EncodedKey("key0500"), proto.EncodedKey("key0900"))
+	c.suggest(proto.EncodedKey("key0000"), proto.EncodedKey("key0200"))
+	c.suggest(proto.EncodedKey("key0100"), proto.EncodedKey("key0300"))
+	if c.ReclaimableBytes() <= 0 {
+		t.Fatalf("expected positive reclaimable bytes; got %d", c.ReclaimableBytes())
+	}
+
+	c.compact()
+	expected := compactionSpans{
+		{start: proto.EncodedKey("key0000"), end: proto.EncodedKey("key0300")},
+		{start: proto.EncodedKey("key0500"), end: proto.EncodedKey("key0900")},
+	}
+	if !reflect.DeepEqual(cr.compacted, expected) {
+		t.Errorf("expected compacted spans %q; got %q", expected, cr.compacted)
+	}
+	if c.ReclaimableBytes() != 0 {
+		t.Errorf("expected reclaimable bytes to reset; got %d", c.ReclaimableBytes())
+	}
+}
+
+// TestCompactorSuggestOnCommit verifies that spans vacated by a batch
+// are suggested only once the batch commits.
+func TestCompactorSuggestOnCommit(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	c := newCompactor(eng, defaultCompactionInterval, defaultCompactionThreshold)
+
+	// An abandoned batch suggests nothing.
+	abandoned := eng.NewBatch()
+	c.suggestBytesOnCommit(abandoned, proto.EncodedKey("a"), proto.EncodedKey("b"), 10)
+	if n := c.ReclaimableBytes(); n != 0 {
+		t.Errorf("expected no reclaimable bytes before commit; got %d", n)
+	}
+
+	batch := eng.NewBatch()
+	c.suggestBytesOnCommit(batch, proto.EncodedKey("c"), proto.EncodedKey("d"), 10)
+	if n := c.ReclaimableBytes(); n != 0 {
+		t.Errorf("expected no reclaimable bytes before commit; got %d", n)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.ReclaimableBytes(); n != 10 {
+		t.Errorf("expected 10 reclaimable bytes after commit; got %d", n)
+	}
+	expected := compactionSpans{{start: proto.EncodedKey("c"), end: proto.EncodedKey("d")}}
+	if !reflect.DeepEqual(c.spans, expected) {
+		t.Errorf("expected suggested spans %q; got %q", expected, c.spans)
+	}
+}
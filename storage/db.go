@@ -64,6 +64,13 @@ func MergeRangeAddressing(db *client.KV, left, merged *proto.RangeDescriptor) er
 	return nil
 }
 
+// UpdateRangeAddressing overwrites the meta1 and meta2 range
+// addressing records for the range specified by desc, as is required
+// when its replicas change.
+func UpdateRangeAddressing(db *client.KV, desc *proto.RangeDescriptor) error {
+	return updateRangeAddressing(db, desc, putMeta)
+}
+
 // updateRangeAddressing updates or deletes the range addressing
 // metadata for the range specified by desc. The action to take is
 // specified by the supplied metaAction function.
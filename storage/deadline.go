@@ -0,0 +1,55 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// A DeadlineExceededError indicates that the deadline of a command
+// passed before it completed. The command wasn't executed if it was
+// read-only; a write may yet be applied, so that a retry must reuse
+// the command's ClientCmdID to learn its outcome.
+type DeadlineExceededError struct {
+	Method   string
+	Deadline int64 // Nanoseconds since the epoch
+}
+
+// Error formats error.
+func (e *DeadlineExceededError) Error() string {
+	return fmt.Sprintf("deadline %s of %s exceeded", time.Unix(0, e.Deadline).UTC(), e.Method)
+}
+
+// checkDeadline returns a DeadlineExceededError if the deadline of the
+// request header has passed. Deadlines are compared to the wall time
+// of the local clock, so they are enforced within the clock offset
+// between client and server.
+func checkDeadline(method string, header *proto.RequestHeader) error {
+	if header.Deadline != 0 && util.Now().UnixNano() >= header.Deadline {
+		return &DeadlineExceededError{Method: method, Deadline: header.Deadline}
+	}
+	return nil
+}
+
+// untilDeadline returns the time remaining until the deadline of the
+// request header, which must be set.
+func untilDeadline(header *proto.RequestHeader) time.Duration {
+	return time.Duration(header.Deadline - util.Now().UnixNano())
+}
@@ -20,7 +20,6 @@
 package engine
 
 import (
-	"bytes"
 	"runtime/debug"
 
 	"github.com/biogo/store/llrb"
@@ -42,6 +41,7 @@ type Batch struct {
 	engine    Engine
 	updates   llrb.Tree
 	committed bool
+	defers    []func()
 }
 
 // NewBatch returns a new instance of Batch which wraps engine.
@@ -108,32 +108,11 @@ func (b *Batch) GetProto(key proto.EncodedKey, msg gogoproto.Message) (
 	return
 }
 
-// Iterate invokes f on key/value pairs merged from the underlying
-// engine and pending batch updates. If f returns done or an error,
-// the iteration ends and propagates the error.
-func (b *Batch) Iterate(start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error)) error {
-	it := b.NewIterator()
-	defer it.Close()
-
-	it.Seek(start)
-	for ; it.Valid(); it.Next() {
-		k := it.Key()
-		if bytes.Compare(it.Key(), end) >= 0 {
-			break
-		}
-		if done, err := f(proto.RawKeyValue{Key: k, Value: it.Value()}); done || err != nil {
-			return err
-		}
-	}
-	// Check for any errors during iteration.
-	return it.Error()
-}
-
 // Scan scans from both the updates tree and the underlying engine
 // and combines the results, up to max.
 func (b *Batch) Scan(start, end proto.EncodedKey, max int64) ([]proto.RawKeyValue, error) {
 	var kvs []proto.RawKeyValue
-	err := b.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+	err := Iterate(b, start, end, func(kv proto.RawKeyValue) (bool, error) {
 		if max != 0 && int64(len(kvs)) >= max {
 			return true, nil
 		}
@@ -209,7 +188,21 @@ func (b *Batch) Commit() error {
 		return false
 	}, proto.RawKeyValue{Key: proto.EncodedKey(KeyMin)}, proto.RawKeyValue{Key: proto.EncodedKey(KeyMax)})
 	b.committed = true
-	return b.engine.WriteBatch(batch)
+	if err := b.engine.WriteBatch(batch); err != nil {
+		return err
+	}
+	for _, fn := range b.defers {
+		fn()
+	}
+	return nil
+}
+
+// Defer adds a function to be called once the batch has been
+// committed successfully, for side effects which must not happen if
+// the batch's mutations are abandoned. Functions are called in the
+// order in which they were deferred.
+func (b *Batch) Defer(fn func()) {
+	b.defers = append(b.defers, fn)
 }
 
 // Open returns an error if called on a Batch.
@@ -245,15 +238,37 @@ func (b *Batch) ApproximateSize(start, end proto.EncodedKey) (uint64, error) {
 	return 0, util.Errorf("cannot get approximate size from a Batch")
 }
 
+// CacheStats returns the block cache statistics of the underlying
+// engine.
+func (b *Batch) CacheStats() CacheStats {
+	return b.engine.CacheStats()
+}
+
+// CompactRange is a noop for Batch.
+func (b *Batch) CompactRange(start, end proto.EncodedKey) {
+}
+
 // Flush returns an error if called on a Batch.
 func (b *Batch) Flush() error {
 	return util.Errorf("cannot flush a Batch")
 }
 
+// Checkpoint returns an error if called on a Batch.
+func (b *Batch) Checkpoint(dir string) error {
+	return util.Errorf("cannot checkpoint a Batch")
+}
+
 // NewIterator returns an iterator over Batch. Batch iterators are
 // not thread safe.
 func (b *Batch) NewIterator() Iterator {
-	return newBatchIterator(b.engine, &b.updates)
+	return newBatchIterator(b.engine.NewIterator(), &b.updates)
+}
+
+// NewScanIterator returns an iterator over Batch whose underlying
+// engine iterator is tuned according to the supplied scan hint.
+// Batch iterators are not thread safe.
+func (b *Batch) NewScanIterator(hint ScanHint) Iterator {
+	return newBatchIterator(b.engine.NewScanIterator(hint), &b.updates)
 }
 
 // NewSnapshot returns nil if called on a Batch.
@@ -273,10 +288,11 @@ type batchIterator struct {
 	err     error
 }
 
-// newBatchIterator returns a new iterator over the supplied Batch instance.
-func newBatchIterator(engine Engine, updates *llrb.Tree) *batchIterator {
+// newBatchIterator returns a new iterator which merges the batch
+// updates with the supplied engine iterator.
+func newBatchIterator(iter Iterator, updates *llrb.Tree) *batchIterator {
 	return &batchIterator{
-		iter:    engine.NewIterator(),
+		iter:    iter,
 		updates: updates,
 	}
 }
@@ -293,6 +309,10 @@ func (bi *batchIterator) Seek(key []byte) {
 	bi.mergeUpdates(key)
 }
 
+func (bi *batchIterator) SeekToLast() {
+	bi.seekBefore(nil)
+}
+
 func (bi *batchIterator) Valid() bool {
 	return bi.err == nil && len(bi.pending) > 0
 }
@@ -311,6 +331,14 @@ func (bi *batchIterator) Next() {
 	}
 }
 
+func (bi *batchIterator) Prev() {
+	if !bi.Valid() {
+		bi.err = util.Errorf("prev called with invalid iterator")
+		return
+	}
+	bi.seekBefore(bi.pending[0].Key)
+}
+
 func (bi *batchIterator) Key() proto.EncodedKey {
 	if !bi.Valid() {
 		debug.PrintStack()
@@ -336,6 +364,64 @@ func (bi *batchIterator) Error() error {
 	return bi.err
 }
 
+// seekBefore positions the iterator at the last key which precedes
+// key, or at the last key if key is nil, and which hasn't been deleted
+// by a batch update. The preceding
+// key is the later of the engine iterator's preceding key and the
+// batch's preceding update; keys deleted by the batch are skipped by
+// searching again before them. The iterator is then seeked to the key
+// found, so that iteration may continue forward from it.
+func (bi *batchIterator) seekBefore(key proto.EncodedKey) {
+	bi.pending = []proto.RawKeyValue{}
+	bi.err = nil
+	for {
+		var engKey proto.EncodedKey
+		if key == nil {
+			bi.iter.SeekToLast()
+		} else {
+			bi.iter.Seek(key)
+			if !bi.iter.Valid() {
+				bi.iter.SeekToLast()
+			}
+			for bi.iter.Valid() && !bi.iter.Key().Less(key) {
+				bi.iter.Prev()
+			}
+		}
+		if bi.err = bi.iter.Error(); bi.err != nil {
+			return
+		}
+		if bi.iter.Valid() {
+			engKey = bi.iter.Key()
+		}
+
+		var update llrb.Comparable
+		if key == nil {
+			update = bi.updates.Max()
+		} else {
+			bi.updates.DoRangeReverse(func(n llrb.Comparable) bool {
+				if n.Compare(proto.RawKeyValue{Key: key}) >= 0 {
+					return false
+				}
+				update = n
+				return true
+			}, proto.RawKeyValue{Key: key}, proto.RawKeyValue{Key: proto.EncodedKey(KeyMin)})
+		}
+
+		if update != nil && (engKey == nil || update.Compare(proto.RawKeyValue{Key: engKey}) >= 0) {
+			if del, ok := update.(BatchDelete); ok {
+				key = del.Key
+				continue
+			}
+			bi.Seek(update.(proto.KeyGetter).KeyGet())
+			return
+		}
+		if engKey != nil {
+			bi.Seek(engKey)
+		}
+		return
+	}
+}
+
 // mergeUpdates combines the next key/value from the engine iterator
 // with all batch updates which preceed it. The final batch update
 // which might overlap the next key/value is merged. The start
@@ -378,6 +378,62 @@ func TestBatchScanMaxWithDeleted(t *testing.T) {
 	}
 }
 
+// TestBatchIteratorPrev verifies that a batch iterator moves back
+// over the keys merged from the engine and the batch updates, skipping
+// keys deleted in the batch.
+func TestBatchIteratorPrev(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+
+	for _, key := range []string{"a", "c", "d", "f"} {
+		if err := e.Put(proto.EncodedKey(key), []byte("engine")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := e.NewBatch()
+	for _, key := range []string{"b", "c", "g"} {
+		if err := b.Put(proto.EncodedKey(key), []byte("batch")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"d", "e", "f"} {
+		if err := b.Clear(proto.EncodedKey(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []proto.RawKeyValue{
+		{Key: proto.EncodedKey("a"), Value: []byte("engine")},
+		{Key: proto.EncodedKey("b"), Value: []byte("batch")},
+		{Key: proto.EncodedKey("c"), Value: []byte("batch")},
+		{Key: proto.EncodedKey("g"), Value: []byte("batch")},
+	}
+	iter := b.NewIterator()
+	defer iter.Close()
+	for _, seek := range []func(){
+		iter.SeekToLast,
+		func() { iter.Seek(proto.EncodedKey("g")) },
+	} {
+		var kvs []proto.RawKeyValue
+		for seek(); iter.Valid(); iter.Prev() {
+			kvs = append([]proto.RawKeyValue{{Key: iter.Key(), Value: iter.Value()}}, kvs...)
+		}
+		if err := iter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(kvs, expected) {
+			t.Errorf("expected %v; got %v", expected, kvs)
+		}
+	}
+	// The iterator continues forward from a key reached in reverse.
+	iter.Seek(proto.EncodedKey("g"))
+	iter.Prev()
+	if iter.Next(); !iter.Valid() || !iter.Key().Equal(proto.EncodedKey("g")) {
+		t.Errorf("expected iterator to return to key \"g\"")
+	}
+}
+
 // TestBatchConcurrency verifies operation of batch when the
 // underlying engine has concurrent modifications to overlapping
 // keys. This should never happen with the way Cockroach uses
@@ -23,9 +23,12 @@
 #include "rocksdb/compaction_filter.h"
 #include "rocksdb/db.h"
 #include "rocksdb/env.h"
+#include "rocksdb/filter_policy.h"
 #include "rocksdb/merge_operator.h"
 #include "rocksdb/options.h"
+#include "rocksdb/statistics.h"
 #include "rocksdb/table.h"
+#include "rocksdb/utilities/checkpoint.h"
 #include "cockroach/proto/api.pb.h"
 #include "cockroach/proto/data.pb.h"
 #include "cockroach/proto/internal.pb.h"
@@ -45,10 +48,15 @@ struct DBBatch {
 struct DBEngine {
   rocksdb::DB* rep;
   rocksdb::Env* memenv;
+  rocksdb::WriteOptions write_options;
 };
 
 struct DBIterator {
   rocksdb::Iterator* rep;
+  // The storage for the iterator's upper bound, which must outlive
+  // the iterator.
+  std::string upper_bound;
+  rocksdb::Slice upper_bound_slice;
 };
 
 struct DBSnapshot {
@@ -69,6 +77,10 @@ const rocksdb::Slice kKeyLocalRangeKeyPrefix("\x00\xff\x00\xff\x00\xffk", 7);
 const rocksdb::Slice kKeyLocalResponseCacheSuffix("res-", 4);
 const rocksdb::Slice kKeyLocalTransactionSuffix("\x00\x01txn-", 6);
 
+// NOTE: this constant must be kept in sync with encryptedValueVersion
+// in storage/engine/encrypted.go.
+const char kEncryptedValueVersion = 1;
+
 const DBStatus kSuccess = { NULL, 0 };
 
 std::string ToString(DBSlice s) {
@@ -238,6 +250,12 @@ class DBCompactionFilter : public rocksdb::CompactionFilter {
     if (!is_rcache && !is_txn) {
       return false;
     }
+    // Skip values encrypted at rest, which can't be inspected here
+    // (see encryptedValueVersion in encrypted.go). They're garbage
+    // collected by the GC queue instead.
+    if (existing_value.size() > 0 && existing_value[0] == kEncryptedValueVersion) {
+      return false;
+    }
     // Parse MVCC metadata for inlined value.
     cockroach::proto::MVCCMetadata meta;
     if (!meta.ParseFromArray(existing_value.data(), existing_value.size())) {
@@ -745,31 +763,57 @@ class DBLogger : public rocksdb::Logger {
   const bool enabled_;
 };
 
+// ToCompressionType returns the RocksDB compression type specified
+// by DBOptions.compression.
+rocksdb::CompressionType ToCompressionType(int compression) {
+  switch (compression) {
+    case 1:
+      return rocksdb::kNoCompression;
+    case 2:
+      return rocksdb::kZlibCompression;
+    case 3:
+      return rocksdb::kLZ4Compression;
+  }
+  return rocksdb::kSnappyCompression;
+}
+
 }  // namespace
 
+void DBSetCompactionThreads(int threads) {
+  // Compactions run in the low priority thread pool of the default
+  // env, which the in-memory envs wrap, and which is shared by every
+  // database in the process.
+  rocksdb::Env::Default()->SetBackgroundThreads(threads, rocksdb::Env::LOW);
+}
+
 DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   rocksdb::BlockBasedTableOptions table_options;
   table_options.block_cache = rocksdb::NewLRUCache(
       db_opts.cache_size, 4 /* num-shard-bits */);
+  if (db_opts.bloom_bits_per_key > 0) {
+    table_options.filter_policy.reset(
+        rocksdb::NewBloomFilterPolicy(db_opts.bloom_bits_per_key));
+  }
 
   rocksdb::Options options;
   options.allow_os_buffer = db_opts.allow_os_buffer;
-  options.compression = rocksdb::kSnappyCompression;
+  options.compression = ToCompressionType(db_opts.compression);
   options.compaction_filter_factory.reset(new DBCompactionFilterFactory());
   options.create_if_missing = true;
   options.info_log.reset(new DBLogger(db_opts.logging_enabled));
   options.merge_operator.reset(new DBMergeOperator);
   options.table_factory.reset(rocksdb::NewBlockBasedTableFactory(table_options));
-  options.write_buffer_size = 64 << 20;           // 64 MB
+  options.write_buffer_size = db_opts.write_buffer_size;
   options.target_file_size_base = 64 << 20;       // 64 MB
   options.max_bytes_for_level_base = 512 << 20;   // 512 MB
+  options.max_background_compactions = db_opts.max_background_compactions;
+  options.statistics = rocksdb::CreateDBStatistics();
 
   rocksdb::Env* memenv = NULL;
   if (dir.len == 0) {
     memenv = rocksdb::NewMemEnv(rocksdb::Env::Default());
     options.env = memenv;
   }
-
   rocksdb::DB *db_ptr;
   rocksdb::Status status = rocksdb::DB::Open(options, ToString(dir), &db_ptr);
   if (!status.ok()) {
@@ -778,6 +822,7 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   *db = new DBEngine;
   (*db)->rep = db_ptr;
   (*db)->memenv = memenv;
+  (*db)->write_options.sync = db_opts.sync_wal;
   return kSuccess;
 }
 
@@ -798,6 +843,17 @@ DBStatus DBFlush(DBEngine* db) {
   return ToDBStatus(db->rep->Flush(options));
 }
 
+DBStatus DBCheckpoint(DBEngine* db, DBSlice dir) {
+  rocksdb::Checkpoint* checkpoint;
+  rocksdb::Status status = rocksdb::Checkpoint::Create(db->rep, &checkpoint);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  status = checkpoint->CreateCheckpoint(ToString(dir));
+  delete checkpoint;
+  return ToDBStatus(status);
+}
+
 void DBSetGCTimeouts(DBEngine * db, int64_t min_txn_ts, int64_t min_rcache_ts) {
   DBCompactionFilterFactory *db_cff =
       (DBCompactionFilterFactory*)db->rep->GetOptions().compaction_filter_factory.get();
@@ -827,14 +883,18 @@ uint64_t DBApproximateSize(DBEngine* db, DBSlice start, DBSlice end) {
   return result;
 }
 
+void DBGetCacheStats(DBEngine* db, DBCacheStats* stats) {
+  const std::shared_ptr<rocksdb::Statistics>& s = db->rep->GetOptions().statistics;
+  stats->hits = s->getTickerCount(rocksdb::BLOCK_CACHE_HIT);
+  stats->misses = s->getTickerCount(rocksdb::BLOCK_CACHE_MISS);
+}
+
 DBStatus DBPut(DBEngine* db, DBSlice key, DBSlice value) {
-  rocksdb::WriteOptions options;
-  return ToDBStatus(db->rep->Put(options, ToSlice(key), ToSlice(value)));
+  return ToDBStatus(db->rep->Put(db->write_options, ToSlice(key), ToSlice(value)));
 }
 
 DBStatus DBMerge(DBEngine* db, DBSlice key, DBSlice value) {
-  rocksdb::WriteOptions options;
-  return ToDBStatus(db->rep->Merge(options, ToSlice(key), ToSlice(value)));
+  return ToDBStatus(db->rep->Merge(db->write_options, ToSlice(key), ToSlice(value)));
 }
 
 DBStatus DBGet(DBEngine* db, DBSnapshot* snap, DBSlice key, DBString* value) {
@@ -855,13 +915,11 @@ DBStatus DBGet(DBEngine* db, DBSnapshot* snap, DBSlice key, DBString* value) {
 }
 
 DBStatus DBDelete(DBEngine* db, DBSlice key) {
-  rocksdb::WriteOptions options;
-  return ToDBStatus(db->rep->Delete(options, ToSlice(key)));
+  return ToDBStatus(db->rep->Delete(db->write_options, ToSlice(key)));
 }
 
 DBStatus DBWrite(DBEngine* db, DBBatch *batch) {
-  rocksdb::WriteOptions options;
-  return ToDBStatus(db->rep->Write(options, &batch->rep));
+  return ToDBStatus(db->rep->Write(db->write_options, &batch->rep));
 }
 
 DBSnapshot* DBNewSnapshot(DBEngine* db)  {
@@ -876,9 +934,16 @@ void DBSnapshotRelease(DBSnapshot* snap) {
   delete snap;
 }
 
-DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snap) {
+DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snap, DBIterOptions iter_opts) {
   DBIterator* iter = new DBIterator;
-  iter->rep = db->rep->NewIterator(MakeReadOptions(snap));
+  rocksdb::ReadOptions options = MakeReadOptions(snap);
+  options.fill_cache = iter_opts.fill_cache;
+  if (iter_opts.upper_bound.len > 0) {
+    iter->upper_bound = ToString(iter_opts.upper_bound);
+    iter->upper_bound_slice = iter->upper_bound;
+    options.iterate_upper_bound = &iter->upper_bound_slice;
+  }
+  iter->rep = db->rep->NewIterator(options);
   return iter;
 }
 
@@ -907,6 +972,10 @@ void DBIterNext(DBIterator* iter) {
   iter->rep->Next();
 }
 
+void DBIterPrev(DBIterator* iter) {
+  iter->rep->Prev();
+}
+
 DBSlice DBIterKey(DBIterator* iter) {
   return ToDBSlice(iter->rep->key());
 }
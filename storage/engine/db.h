@@ -53,12 +53,49 @@ typedef struct {
   int64_t cache_size;
   bool allow_os_buffer;
   bool logging_enabled;
+  // If true, the write-ahead log is synced to disk before each write
+  // is acknowledged.
+  bool sync_wal;
+  // The size in bytes of a memtable before it's flushed to disk.
+  int64_t write_buffer_size;
+  // The maximum number of concurrent background compactions.
+  int max_background_compactions;
+  // The compression algorithm for blocks: 0 = snappy, 1 = none,
+  // 2 = zlib, 3 = lz4.
+  int compression;
+  // The bits per key of the bloom filter of each table, or 0 for no
+  // bloom filter.
+  int bloom_bits_per_key;
 } DBOptions;
 
+// DBIterOptions contains hints used to tune a database iterator.
+typedef struct {
+  // If false, blocks read by the iterator are not added to the block
+  // cache. Large scans disable this to avoid evicting the working set
+  // of other readers.
+  bool fill_cache;
+  // If non-empty, an exclusive upper bound for the iterator. The
+  // iterator becomes invalid upon reaching it rather than reading
+  // further data.
+  DBSlice upper_bound;
+} DBIterOptions;
+
+// DBCacheStats contains block cache statistics.
+typedef struct {
+  int64_t hits;
+  int64_t misses;
+} DBCacheStats;
+
 // Opens the database located in "dir", creating it if it doesn't
 // exist.
 DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions options);
 
+// Sets the number of threads in the pool which runs the compactions
+// of every database in the process. The pool is process-wide, so it
+// should be sized once, before the databases are opened, rather than
+// per database.
+void DBSetCompactionThreads(int threads);
+
 // Destroys the database located in "dir". As the name implies, this
 // operation is destructive. Use with caution.
 DBStatus DBDestroy(DBSlice dir);
@@ -70,6 +107,11 @@ void DBClose(DBEngine* db);
 // complete.
 DBStatus DBFlush(DBEngine* db);
 
+// Creates a checkpoint of the database in "dir", which must not
+// exist: a consistent copy of its on-disk state in which the table
+// files are hard-linked where possible.
+DBStatus DBCheckpoint(DBEngine* db, DBSlice dir);
+
 // Sets GC timeouts.
 void DBSetGCTimeouts(DBEngine * db, int64_t min_txn_ts, int64_t min_rcache_ts);
 
@@ -84,6 +126,10 @@ DBStatus DBCompactRange(DBEngine* db, DBSlice* start, DBSlice* end);
 // range [start,end].
 uint64_t DBApproximateSize(DBEngine* db, DBSlice start, DBSlice end);
 
+// Retrieves the block cache hit and miss counts accumulated since the
+// database was opened.
+void DBGetCacheStats(DBEngine* db, DBCacheStats* stats);
+
 // Sets the database entry for "key" to "value".
 DBStatus DBPut(DBEngine* db, DBSlice key, DBSlice value);
 
@@ -114,7 +160,7 @@ void DBSnapshotRelease(DBSnapshot* snapshot);
 // Creates a new database iterator. If snapshot==NULL the iterator
 // will iterate over the current state of the database. It is the
 // callers responsibility to call DBIterDestroy().
-DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snapshot);
+DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snapshot, DBIterOptions options);
 
 // Destroys an iterator, freeing up any associated memory.
 void DBIterDestroy(DBIterator* iter);
@@ -137,6 +183,11 @@ int  DBIterValid(DBIterator* iter);
 // last key.
 void DBIterNext(DBIterator* iter);
 
+// Moves the iterator back to the previous key. After this call,
+// DBIterValid() returns 1 iff the iterator was not positioned at the
+// first key.
+void DBIterPrev(DBIterator* iter);
+
 // Returns the key at the current iterator position. Note that a slice
 // is returned and the memory does not have to be freed.
 DBSlice DBIterKey(DBIterator* iter);
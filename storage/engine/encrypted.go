@@ -0,0 +1,579 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// encryptedValueVersion is the first byte of each encrypted value,
+// identifying the format of its envelope. It is followed by the
+// big-endian ID of the key with which the value was encrypted, the
+// nonce and the value sealed with AES-GCM, which authenticates the
+// engine key under which the value is stored. No marshaled proto
+// begins with this byte; the compaction filter in db.cc relies on it
+// to skip encrypted values.
+const encryptedValueVersion = 1
+
+// reencryptBatchSize is the number of values Reencrypt examines per
+// batch, with writes blocked. Variable for testing.
+var reencryptBatchSize = 100
+
+const (
+	keyIDSize                 = 4
+	envelopeHeaderSize        = 1 + keyIDSize
+	keyFingerprintSize        = sha256.Size
+	encryptionRecordEntrySize = keyIDSize + keyFingerprintSize
+)
+
+// encryptionRecordKey is the engine key of the record of encryption
+// keys. The record itself is stored unencrypted.
+var encryptionRecordKey = MVCCEncodeKey(StoreEncryptionKey())
+
+// EncryptionKeys holds the AES keys of an encrypted engine, each
+// identified by a positive ID. Values are encrypted with the active
+// key, which is the key with the highest ID. Keys are rotated by
+// adding a key with a higher ID; earlier keys must be retained to
+// decrypt the values written before the rotation.
+type EncryptionKeys struct {
+	ciphers      map[uint32]cipher.AEAD
+	fingerprints map[uint32][]byte
+	active       uint32
+}
+
+// NewEncryptionKeys returns the EncryptionKeys holding the given AES
+// keys, which must be 16, 24 or 32 bytes long, by ID.
+func NewEncryptionKeys(keys map[uint32][]byte) (*EncryptionKeys, error) {
+	if len(keys) == 0 {
+		return nil, util.Errorf("no encryption keys specified")
+	}
+	ek := &EncryptionKeys{
+		ciphers:      map[uint32]cipher.AEAD{},
+		fingerprints: map[uint32][]byte{},
+	}
+	for id, key := range keys {
+		if id == 0 {
+			return nil, util.Errorf("encryption key IDs must be positive")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, util.Errorf("invalid encryption key %d: %s", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, util.Errorf("invalid encryption key %d: %s", id, err)
+		}
+		fingerprint := sha256.Sum256(key)
+		ek.ciphers[id] = aead
+		ek.fingerprints[id] = fingerprint[:]
+		if id > ek.active {
+			ek.active = id
+		}
+	}
+	return ek, nil
+}
+
+// ParseEncryptionKeys reads encryption keys from r, one per line as
+// the key's ID followed by the hex-encoded key. Blank lines and lines
+// beginning with '#' are ignored.
+func ParseEncryptionKeys(r io.Reader) (*EncryptionKeys, error) {
+	keys := map[uint32][]byte{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, util.Errorf("line %d: expected key ID and hex-encoded key", line)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, util.Errorf("line %d: invalid key ID %q: %s", line, fields[0], err)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, util.Errorf("line %d: invalid key: %s", line, err)
+		}
+		if _, ok := keys[uint32(id)]; ok {
+			return nil, util.Errorf("line %d: duplicate key ID %d", line, id)
+		}
+		keys[uint32(id)] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewEncryptionKeys(keys)
+}
+
+// encrypt seals value with the active key, authenticating the engine
+// key under which it's stored.
+func (ek *EncryptionKeys) encrypt(key proto.EncodedKey, value []byte) ([]byte, error) {
+	aead := ek.ciphers[ek.active]
+	headerSize := envelopeHeaderSize + aead.NonceSize()
+	buf := make([]byte, headerSize, headerSize+len(value)+aead.Overhead())
+	buf[0] = encryptedValueVersion
+	binary.BigEndian.PutUint32(buf[1:], ek.active)
+	nonce := buf[envelopeHeaderSize:headerSize]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, util.Errorf("unable to generate nonce: %s", err)
+	}
+	return aead.Seal(buf, nonce, value, key), nil
+}
+
+// decrypt opens the envelope of the value stored under key with the
+// key which encrypted it.
+func (ek *EncryptionKeys) decrypt(key proto.EncodedKey, value []byte) ([]byte, error) {
+	if len(value) < envelopeHeaderSize || value[0] != encryptedValueVersion {
+		return nil, util.Errorf("value of key %q isn't encrypted", key)
+	}
+	id := binary.BigEndian.Uint32(value[1:])
+	aead, ok := ek.ciphers[id]
+	if !ok {
+		return nil, util.Errorf("value of key %q is encrypted with missing key %d", key, id)
+	}
+	headerSize := envelopeHeaderSize + aead.NonceSize()
+	if len(value) < headerSize {
+		return nil, util.Errorf("encrypted value of key %q is truncated", key)
+	}
+	plaintext, err := aead.Open(nil, value[envelopeHeaderSize:headerSize], value[headerSize:], key)
+	if err != nil {
+		return nil, util.Errorf("unable to decrypt value of key %q with key %d: %s", key, id, err)
+	}
+	if plaintext == nil {
+		// Distinguish an empty value from a missing one.
+		plaintext = []byte{}
+	}
+	return plaintext, nil
+}
+
+// An EncryptedEngine wraps an Engine and encrypts the values written
+// to it with AES-GCM, so that they are encrypted at rest. Only values
+// are encrypted: keys, which embed the keys of user data, are stored
+// in the clear, as they determine the order of the engine.
+//
+// Encryption must be enabled on a new store: an EncryptedEngine
+// refuses to open an engine holding unencrypted data. The engine
+// records the IDs and fingerprints of the keys with which its values
+// have been encrypted, and refuses to open if any of them is missing
+// or doesn't match. Once Reencrypt has rewritten the values under the
+// active key, the keys it replaced are dropped from the record and may
+// be retired.
+//
+// RocksDB can't merge encrypted values, so merges are applied by
+// reading, merging and rewriting the value as a put. Writes are
+// serialized so that no concurrent write is lost in between. Nor can
+// RocksDB's compaction filter inspect encrypted values, so response
+// cache entries and transaction records are garbage collected only by
+// the GC queue.
+type EncryptedEngine struct {
+	Engine
+	keys *EncryptionKeys
+	mu   sync.Mutex // Serializes writes
+}
+
+// NewEncryptedEngine returns an EncryptedEngine wrapping e which
+// encrypts values with keys.
+func NewEncryptedEngine(e Engine, keys *EncryptionKeys) *EncryptedEngine {
+	return &EncryptedEngine{Engine: e, keys: keys}
+}
+
+// An UnencryptedEngine wraps an engine used without encryption. It
+// refuses to open an engine holding a record of encryption keys, whose
+// values would otherwise be served, and overwritten, as ciphertext.
+type UnencryptedEngine struct {
+	Engine
+}
+
+// NewUnencryptedEngine returns an UnencryptedEngine wrapping e.
+func NewUnencryptedEngine(e Engine) *UnencryptedEngine {
+	return &UnencryptedEngine{Engine: e}
+}
+
+// Open implements the Engine interface. Once the wrapped engine is
+// open, it's checked for a record of encryption keys.
+func (ue *UnencryptedEngine) Open() error {
+	if err := ue.Engine.Open(); err != nil {
+		return err
+	}
+	record, err := ue.Engine.Get(encryptionRecordKey)
+	if err == nil && record != nil {
+		err = util.Errorf("store %s holds encrypted data; its encryption keys must be supplied", ue.Engine)
+	}
+	if err != nil {
+		ue.Engine.Close()
+		return err
+	}
+	return nil
+}
+
+// String names the wrapped engine, so that the store is identified
+// in messages as it is without encryption.
+func (ee *EncryptedEngine) String() string {
+	return fmt.Sprint(ee.Engine)
+}
+
+// String names the wrapped engine.
+func (ue *UnencryptedEngine) String() string {
+	return fmt.Sprint(ue.Engine)
+}
+
+// isEncrypted returns whether the value of key is encrypted.
+func isEncrypted(key proto.EncodedKey) bool {
+	return !bytes.Equal(key, encryptionRecordKey)
+}
+
+// Open implements the Engine interface. Once the wrapped engine is
+// open, the record of its encryption keys is checked against the
+// keys of the EncryptedEngine, and the active key is recorded.
+func (ee *EncryptedEngine) Open() error {
+	if err := ee.Engine.Open(); err != nil {
+		return err
+	}
+	if err := ee.checkKeys(); err != nil {
+		ee.Engine.Close()
+		return err
+	}
+	return nil
+}
+
+// checkKeys verifies that the keys with which the engine's values may
+// have been encrypted are held and match their recorded fingerprints,
+// and adds the active key to the record.
+func (ee *EncryptedEngine) checkKeys() error {
+	record, err := ee.Engine.Get(encryptionRecordKey)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		iter := ee.Engine.NewIterator()
+		iter.Seek(nil)
+		hasData := iter.Valid()
+		iter.Close()
+		if hasData {
+			return util.Errorf("store %s holds unencrypted data; encryption must be enabled on a new store", ee.Engine)
+		}
+	}
+	if len(record)%encryptionRecordEntrySize != 0 {
+		return util.Errorf("record of encryption keys of store %s is corrupt", ee.Engine)
+	}
+	recorded := false
+	for i := 0; i < len(record); i += encryptionRecordEntrySize {
+		id := binary.BigEndian.Uint32(record[i:])
+		fingerprint, ok := ee.keys.fingerprints[id]
+		if !ok {
+			return util.Errorf("store %s holds values encrypted with missing key %d", ee.Engine, id)
+		}
+		if !bytes.Equal(fingerprint, record[i+keyIDSize:i+encryptionRecordEntrySize]) {
+			return util.Errorf("key %d doesn't match the key with which store %s was encrypted", id, ee.Engine)
+		}
+		recorded = recorded || id == ee.keys.active
+	}
+	if recorded {
+		return nil
+	}
+	entry := make([]byte, encryptionRecordEntrySize)
+	binary.BigEndian.PutUint32(entry, ee.keys.active)
+	copy(entry[keyIDSize:], ee.keys.fingerprints[ee.keys.active])
+	return ee.Engine.Put(encryptionRecordKey, append(append([]byte(nil), record...), entry...))
+}
+
+// encrypt returns the value to be stored under key.
+func (ee *EncryptedEngine) encrypt(key proto.EncodedKey, value []byte) ([]byte, error) {
+	if !isEncrypted(key) {
+		return value, nil
+	}
+	return ee.keys.encrypt(key, value)
+}
+
+// decrypt returns the value stored under key in the clear.
+func (ee *EncryptedEngine) decrypt(key proto.EncodedKey, value []byte) ([]byte, error) {
+	if value == nil || !isEncrypted(key) {
+		return value, nil
+	}
+	return ee.keys.decrypt(key, value)
+}
+
+// Put implements the Engine interface.
+func (ee *EncryptedEngine) Put(key proto.EncodedKey, value []byte) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	value, err := ee.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	ee.mu.Lock()
+	defer ee.mu.Unlock()
+	return ee.Engine.Put(key, value)
+}
+
+// Clear implements the Engine interface.
+func (ee *EncryptedEngine) Clear(key proto.EncodedKey) error {
+	ee.mu.Lock()
+	defer ee.mu.Unlock()
+	return ee.Engine.Clear(key)
+}
+
+// Get implements the Engine interface.
+func (ee *EncryptedEngine) Get(key proto.EncodedKey) ([]byte, error) {
+	value, err := ee.Engine.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return ee.decrypt(key, value)
+}
+
+// GetProto implements the Engine interface. The size of the value
+// returned is that of the decrypted value.
+func (ee *EncryptedEngine) GetProto(key proto.EncodedKey, msg gogoproto.Message) (
+	ok bool, keyBytes, valBytes int64, err error) {
+	var data []byte
+	if data, err = ee.Get(key); err != nil || data == nil {
+		return
+	}
+	ok = true
+	if msg != nil {
+		if err = gogoproto.Unmarshal(data, msg); err != nil {
+			return
+		}
+	}
+	keyBytes = int64(len(key))
+	valBytes = int64(len(data))
+	return
+}
+
+// WriteBatch implements the Engine interface. Merges are combined
+// with the existing value, including the values written earlier in
+// the batch, and written as puts.
+func (ee *EncryptedEngine) WriteBatch(cmds []interface{}) error {
+	ee.mu.Lock()
+	defer ee.mu.Unlock()
+	// The values written by the batch so far, by key. Deleted keys map
+	// to nil.
+	written := map[string][]byte{}
+	encrypted := make([]interface{}, 0, len(cmds))
+	for i, cmd := range cmds {
+		var key proto.EncodedKey
+		switch t := cmd.(type) {
+		case BatchDelete:
+			written[string(t.Key)] = nil
+			encrypted = append(encrypted, t)
+			continue
+		case BatchPut:
+			key = t.Key
+			written[string(key)] = t.Value
+		case BatchMerge:
+			key = t.Key
+			existing, ok := written[string(key)]
+			if !ok {
+				var err error
+				if existing, err = ee.Get(key); err != nil {
+					return err
+				}
+			}
+			merged, err := goMerge(existing, t.Value)
+			if err != nil {
+				return err
+			}
+			written[string(key)] = merged
+		default:
+			return util.Errorf("illegal operation #%d passed to WriteBatch: %T", i, t)
+		}
+		value, err := ee.encrypt(key, written[string(key)])
+		if err != nil {
+			return err
+		}
+		encrypted = append(encrypted, BatchPut{proto.RawKeyValue{Key: key, Value: value}})
+	}
+	return ee.Engine.WriteBatch(encrypted)
+}
+
+// Merge implements the Engine interface. The merged value is written
+// in place of the existing value.
+func (ee *EncryptedEngine) Merge(key proto.EncodedKey, value []byte) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	return ee.WriteBatch([]interface{}{BatchMerge{proto.RawKeyValue{Key: key, Value: value}}})
+}
+
+// NewIterator implements the Engine interface.
+func (ee *EncryptedEngine) NewIterator() Iterator {
+	return &encryptedIterator{Iterator: ee.Engine.NewIterator(), ee: ee}
+}
+
+// NewScanIterator implements the Engine interface.
+func (ee *EncryptedEngine) NewScanIterator(hint ScanHint) Iterator {
+	return &encryptedIterator{Iterator: ee.Engine.NewScanIterator(hint), ee: ee}
+}
+
+// NewSnapshot implements the Engine interface.
+func (ee *EncryptedEngine) NewSnapshot() Engine {
+	return &EncryptedEngine{Engine: ee.Engine.NewSnapshot(), keys: ee.keys}
+}
+
+// NewBatch implements the Engine interface. The batch is committed
+// through the EncryptedEngine, which encrypts its values.
+func (ee *EncryptedEngine) NewBatch() Engine {
+	return NewBatch(ee)
+}
+
+// Checkpoint implements the Engine interface. The values of the
+// checkpoint remain encrypted, and it holds the record of the keys
+// with which they were encrypted: it must be opened as an
+// EncryptedEngine holding those keys.
+func (ee *EncryptedEngine) Checkpoint(dir string) error {
+	return ee.Engine.Checkpoint(dir)
+}
+
+// Reencrypt rewrites the values encrypted with keys other than the
+// active key under the active key, a batch at a time, and then drops
+// the replaced keys from the record of encryption keys, so that they
+// may be retired. Returns early without error if stop is closed.
+func (ee *EncryptedEngine) Reencrypt(stop <-chan struct{}) error {
+	if ee.reencrypted() {
+		return nil
+	}
+	var start proto.EncodedKey
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		var err error
+		if start, err = ee.reencryptBatch(start); err != nil {
+			return err
+		}
+		if start == nil {
+			return ee.retireKeys()
+		}
+	}
+}
+
+// reencrypted returns whether the record of encryption keys holds
+// only the active key, in which case there's nothing to rewrite.
+func (ee *EncryptedEngine) reencrypted() bool {
+	record, err := ee.Engine.Get(encryptionRecordKey)
+	return err == nil && len(record) == encryptionRecordEntrySize &&
+		binary.BigEndian.Uint32(record) == ee.keys.active
+}
+
+// reencryptBatch examines up to reencryptBatchSize values at or after
+// start, rewriting those encrypted with keys other than the active key.
+// Writes are blocked meanwhile, so that none is overwritten. Returns
+// the key at which to resume, or nil once all values are examined.
+func (ee *EncryptedEngine) reencryptBatch(start proto.EncodedKey) (proto.EncodedKey, error) {
+	ee.mu.Lock()
+	defer ee.mu.Unlock()
+	iter := ee.Engine.NewIterator()
+	defer iter.Close()
+	var puts []interface{}
+	n := 0
+	for iter.Seek(start); iter.Valid(); iter.Next() {
+		if n++; n > reencryptBatchSize {
+			start = append(proto.EncodedKey(nil), iter.Key()...)
+			return start, ee.Engine.WriteBatch(puts)
+		}
+		key, value := iter.Key(), iter.Value()
+		if !isEncrypted(key) || len(value) < envelopeHeaderSize ||
+			binary.BigEndian.Uint32(value[1:]) == ee.keys.active {
+			continue
+		}
+		key = append(proto.EncodedKey(nil), key...)
+		plaintext, err := ee.keys.decrypt(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = ee.keys.encrypt(key, plaintext); err != nil {
+			return nil, err
+		}
+		puts = append(puts, BatchPut{proto.RawKeyValue{Key: key, Value: value}})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return nil, ee.Engine.WriteBatch(puts)
+}
+
+// retireKeys drops all but the active key from the record of
+// encryption keys, once every value is encrypted with the active key.
+func (ee *EncryptedEngine) retireKeys() error {
+	ee.mu.Lock()
+	defer ee.mu.Unlock()
+	entry := make([]byte, encryptionRecordEntrySize)
+	binary.BigEndian.PutUint32(entry, ee.keys.active)
+	copy(entry[keyIDSize:], ee.keys.fingerprints[ee.keys.active])
+	return ee.Engine.Put(encryptionRecordKey, entry)
+}
+
+// An encryptedIterator decrypts the values of the iterator it wraps.
+// It becomes invalid if a value can't be decrypted.
+type encryptedIterator struct {
+	Iterator
+	ee  *EncryptedEngine
+	err error
+}
+
+// Valid implements the Iterator interface.
+func (ei *encryptedIterator) Valid() bool {
+	return ei.err == nil && ei.Iterator.Valid()
+}
+
+// Value implements the Iterator interface.
+func (ei *encryptedIterator) Value() []byte {
+	value, err := ei.ee.decrypt(ei.Iterator.Key(), ei.Iterator.Value())
+	if err != nil {
+		ei.err = err
+		return nil
+	}
+	return value
+}
+
+// ValueProto implements the Iterator interface.
+func (ei *encryptedIterator) ValueProto(msg gogoproto.Message) error {
+	value := ei.Value()
+	if ei.err != nil {
+		return ei.err
+	}
+	return gogoproto.Unmarshal(value, msg)
+}
+
+// Error implements the Iterator interface.
+func (ei *encryptedIterator) Error() error {
+	if ei.err != nil {
+		return ei.err
+	}
+	return ei.Iterator.Error()
+}
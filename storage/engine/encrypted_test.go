@@ -0,0 +1,246 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// testEncryptionKeys returns EncryptionKeys holding a distinct 16 byte
+// key for each ID.
+func testEncryptionKeys(t *testing.T, ids ...uint32) *EncryptionKeys {
+	keys := map[uint32][]byte{}
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(id)}, 16)
+	}
+	ek, err := NewEncryptionKeys(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ek
+}
+
+// TestEncryptedEngine verifies that values written through an
+// EncryptedEngine, including merges and batches, are read back in the
+// clear but stored encrypted in the wrapped engine.
+func TestEncryptedEngine(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	ee := NewEncryptedEngine(e, testEncryptionKeys(t, 1))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer ee.Close()
+
+	if err := MVCCPut(ee, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	batch := ee.NewBatch()
+	for i := 0; i < 2; i++ {
+		if err := MVCCMerge(batch, nil, testKey2, proto.Value{Integer: gogoproto.Int64(2)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCMerge(ee, nil, testKey2, proto.Value{Integer: gogoproto.Int64(3)}); err != nil {
+		t.Fatal(err)
+	}
+
+	kvs, err := MVCCScan(ee, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || !bytes.Equal(kvs[0].Value.Bytes, value1.Bytes) || kvs[1].Value.GetInteger() != 7 {
+		t.Errorf("expected %q and merged integer 7; got %+v", value1.Bytes, kvs)
+	}
+	iter := e.NewIterator()
+	defer iter.Close()
+	for iter.Seek(nil); iter.Valid(); iter.Next() {
+		if bytes.Contains(iter.Value(), value1.Bytes) {
+			t.Errorf("value of key %q is stored in the clear", iter.Key())
+		}
+	}
+}
+
+// TestEncryptedEngineKeyRotation verifies that values remain readable
+// after a key rotation, and that the engine refuses to open without
+// the keys with which its values were encrypted or when it holds
+// unencrypted data, and that an encrypted engine can't be opened
+// without encryption.
+func TestEncryptedEngineKeyRotation(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	ee := NewEncryptedEngine(e, testEncryptionKeys(t, 1))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ee, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	ee.Close()
+
+	ee = NewEncryptedEngine(e, testEncryptionKeys(t, 1, 2))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ee, nil, testKey2, makeTS(1, 0), value2, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []proto.Key{testKey1, testKey2} {
+		if val, err := MVCCGet(ee, key, makeTS(2, 0), true, nil); err != nil || val == nil {
+			t.Errorf("expected to read %q after rotation; got %v, %v", key, val, err)
+		}
+	}
+	ee.Close()
+
+	wrongKey, err := NewEncryptionKeys(map[uint32][]byte{
+		1: bytes.Repeat([]byte{9}, 16),
+		2: bytes.Repeat([]byte{2}, 16),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, keys := range []*EncryptionKeys{testEncryptionKeys(t, 2), wrongKey} {
+		if err := NewEncryptedEngine(e, keys).Open(); err == nil {
+			t.Errorf("%d: expected open to fail", i)
+		}
+	}
+
+	plain := NewInMem(proto.Attributes{}, 1<<20)
+	defer plain.Close()
+	if err := MVCCPut(plain, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewEncryptedEngine(plain, testEncryptionKeys(t, 1)).Open(); err == nil {
+		t.Error("expected open of engine holding unencrypted data to fail")
+	}
+	if err := NewUnencryptedEngine(e).Open(); err == nil {
+		t.Error("expected open of engine holding encrypted data without keys to fail")
+	}
+	if err := NewUnencryptedEngine(plain).Open(); err != nil {
+		t.Errorf("expected open of engine holding unencrypted data without keys to succeed: %s", err)
+	}
+	plain.Close()
+}
+
+// TestEncryptedEngineConcurrentMerges verifies that concurrent merges
+// of a key, which are rewritten as puts, aren't lost.
+func TestEncryptedEngineConcurrentMerges(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	ee := NewEncryptedEngine(e, testEncryptionKeys(t, 1))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer ee.Close()
+
+	const count = 10
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		go func() {
+			defer wg.Done()
+			if err := MVCCMerge(ee, nil, testKey1, proto.Value{Integer: gogoproto.Int64(1)}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if val, err := MVCCGet(ee, testKey1, makeTS(1, 0), true, nil); err != nil || val.GetInteger() != count {
+		t.Errorf("expected merged integer %d; got %v, %v", count, val, err)
+	}
+}
+
+// TestEncryptedEngineReencrypt verifies that Reencrypt rewrites values
+// under the active key, after which the keys it replaced may be
+// retired.
+func TestEncryptedEngineReencrypt(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	defer func(size int) { reencryptBatchSize = size }(reencryptBatchSize)
+	reencryptBatchSize = 1
+
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	ee := NewEncryptedEngine(e, testEncryptionKeys(t, 1))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []proto.Key{testKey1, testKey2, testKey3} {
+		if err := MVCCPut(ee, nil, key, makeTS(1, 0), value1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ee = NewEncryptedEngine(e, testEncryptionKeys(t, 1, 2))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ee.Reencrypt(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ee = NewEncryptedEngine(e, testEncryptionKeys(t, 2))
+	if err := ee.Open(); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := MVCCScan(ee, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 3 {
+		t.Errorf("expected 3 values after reencryption; got %+v", kvs)
+	}
+}
+
+// TestParseEncryptionKeys verifies the parsing of encryption keys and
+// that the key with the highest ID is active.
+func TestParseEncryptionKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ek, err := ParseEncryptionKeys(strings.NewReader(`
+# Rotated on 2015-06-01.
+1 000102030405060708090a0b0c0d0e0f
+2 101112131415161718191a1b1c1d1e1f101112131415161718191a1b1c1d1e1f
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ek.active != 2 || len(ek.ciphers) != 2 {
+		t.Errorf("expected 2 keys with key 2 active; got %d keys with key %d active", len(ek.ciphers), ek.active)
+	}
+	for _, text := range []string{
+		"",
+		"1 0001",
+		"0 000102030405060708090a0b0c0d0e0f",
+		"1 xyz",
+		"1 000102030405060708090a0b0c0d0e0f\n1 000102030405060708090a0b0c0d0e0f",
+	} {
+		if _, err := ParseEncryptionKeys(strings.NewReader(text)); err == nil {
+			t.Errorf("expected %q to fail to parse", text)
+		}
+	}
+}
@@ -20,6 +20,7 @@
 package engine
 
 import (
+	"bytes"
 	"sync"
 
 	"github.com/cockroachdb/cockroach/proto"
@@ -39,6 +40,20 @@ func (sc StoreCapacity) PercentAvail() float64 {
 	return float64(sc.Available) / float64(sc.Capacity)
 }
 
+// CacheStats contains block cache statistics for an engine.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate computes the fraction of block reads served from the cache.
+func (cs CacheStats) HitRate() float64 {
+	if total := cs.Hits + cs.Misses; total > 0 {
+		return float64(cs.Hits) / float64(total)
+	}
+	return 0
+}
+
 // Iterator is an interface for iterating over key/value pairs in an
 // engine. Iterator implementation are thread safe unless otherwise
 // noted.
@@ -48,6 +63,8 @@ type Iterator interface {
 	// Seek advances the iterator to the first key in the engine which
 	// is >= the provided key.
 	Seek(key []byte)
+	// SeekToLast positions the iterator at the last key in the engine.
+	SeekToLast()
 	// Valid returns true if the iterator is currently valid. An
 	// iterator which hasn't been seeked or has gone past the end of the
 	// key range is invalid.
@@ -56,6 +73,10 @@ type Iterator interface {
 	// iteration. After this call, the Valid() will be true if the
 	// iterator was not positioned at the last key.
 	Next()
+	// Prev moves the iterator back to the previous key/value in the
+	// iteration. After this call, Valid() will be true if the iterator
+	// was not positioned at the first key.
+	Prev()
 	// Key returns the current key as a byte slice.
 	Key() proto.EncodedKey
 	// Value returns the current value as a byte slice.
@@ -85,12 +106,6 @@ type Engine interface {
 	// key was not found. On success, returns the length in bytes of the
 	// key and the value.
 	GetProto(key proto.EncodedKey, msg gogoproto.Message) (ok bool, keyBytes, valBytes int64, err error)
-	// Iterate scans from start to end keys, visiting at most max
-	// key/value pairs. On each key value pair, the function f is
-	// invoked. If f returns an error or if the scan itself encounters
-	// an error, the iteration will stop and return the error.
-	// If the first result of f is true, the iteration stops.
-	Iterate(start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error)) error
 	// Clear removes the item from the db with the given key.
 	// Note that clear actually removes entries from the storage
 	// engine, rather than inserting tombstones.
@@ -115,6 +130,9 @@ type Engine interface {
 	Merge(key proto.EncodedKey, value []byte) error
 	// Capacity returns capacity details for the engine's available storage.
 	Capacity() (StoreCapacity, error)
+	// CacheStats returns the block cache statistics accumulated since
+	// the engine was opened.
+	CacheStats() CacheStats
 	// SetGCTimeouts sets a function which yields timeout values for GC
 	// compaction of transaction and response cache entries. The return
 	// values are in unix nanoseconds for the minimum transaction row
@@ -125,13 +143,25 @@ type Engine interface {
 	// ApproximateSize returns the approximate number of bytes the engine is
 	// using to store data for the given range of keys.
 	ApproximateSize(start, end proto.EncodedKey) (uint64, error)
+	// CompactRange compacts the specified key range, reclaiming the
+	// space held by deleted entries. Specifying nil for start or end
+	// extends the compaction to the first or last key respectively.
+	CompactRange(start, end proto.EncodedKey)
 	// Flush causes the engine to write all in-memory data to disk
 	// immediately.
 	Flush() error
+	// Checkpoint creates a consistent copy of the engine's on-disk state
+	// in dir, which must not exist. Files are hard-linked where possible,
+	// so checkpoints are cheap.
+	Checkpoint(dir string) error
 	// NewIterator returns a new instance of an Iterator over this
 	// engine. The caller must invoke Iterator.Close() when finished with
 	// the iterator to free resources.
 	NewIterator() Iterator
+	// NewScanIterator returns a new instance of an Iterator over this
+	// engine, tuned for a scan described by the supplied hint. The
+	// caller must invoke Iterator.Close() when finished.
+	NewScanIterator(hint ScanHint) Iterator
 	// NewSnapshot returns a new instance of a read-only snapshot
 	// engine. Snapshots are instantaneous and, as long as they're
 	// released relatively quickly, inexpensive. Snapshots are released
@@ -147,6 +177,28 @@ type Engine interface {
 	Commit() error
 }
 
+// LargeScanThreshold is the number of keys above which a scan is
+// considered large. Large scans bypass the block cache so as not to
+// evict the working set of point reads and smaller scans.
+var LargeScanThreshold int64 = 1000
+
+// A ScanHint describes an upcoming scan so that the engine can tune
+// how its iterator reads data.
+type ScanHint struct {
+	// MaxKeys is the maximum number of keys the scan will read. Zero
+	// indicates an unbounded scan.
+	MaxKeys int64
+	// EndKey, if not empty, is the exclusive upper bound of the scan.
+	// The iterator will not read data at or beyond it.
+	EndKey proto.EncodedKey
+}
+
+// FillCache returns whether data read by a scan with this hint
+// should be added to the engine's block cache.
+func (h ScanHint) FillCache() bool {
+	return h.MaxKeys != 0 && h.MaxKeys <= LargeScanThreshold
+}
+
 // A BatchDelete is a delete operation executed as part of an atomic batch.
 type BatchDelete struct {
 	proto.RawKeyValue
@@ -233,12 +285,51 @@ func Increment(engine Engine, key proto.EncodedKey, inc int64) (int64, error) {
 	return r, nil
 }
 
+// Iterate scans the engine from start (inclusive) to end
+// (exclusive) keys with an iterator, invoking f on each key/value
+// pair. If f returns an error or if the scan itself encounters an
+// error, the iteration will stop and return the error. If the first
+// result of f is true, the iteration stops.
+func Iterate(engine Engine, start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error)) error {
+	if bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+	it := engine.NewIterator()
+	defer it.Close()
+
+	for it.Seek(start); it.Valid(); it.Next() {
+		k := it.Key()
+		if !k.Less(end) {
+			break
+		}
+		if done, err := f(proto.RawKeyValue{Key: k, Value: it.Value()}); done || err != nil {
+			return err
+		}
+	}
+	// Check for any errors during iteration.
+	return it.Error()
+}
+
+// PrefixSpan returns the span of encoded engine keys, from start
+// (inclusive) to end (exclusive), holding all MVCC keys and versions
+// with the specified prefix.
+func PrefixSpan(prefix proto.Key) (start, end proto.EncodedKey) {
+	return MVCCEncodeKey(prefix), MVCCEncodeKey(prefix.PrefixEnd())
+}
+
+// IteratePrefix invokes f on each raw key/value pair of all MVCC keys
+// and versions with the specified prefix, as Iterate does.
+func IteratePrefix(engine Engine, prefix proto.Key, f func(proto.RawKeyValue) (bool, error)) error {
+	start, end := PrefixSpan(prefix)
+	return Iterate(engine, start, end, f)
+}
+
 // Scan returns up to max key/value objects starting from
 // start (inclusive) and ending at end (non-inclusive).
 // Specify max=0 for unbounded scans.
 func Scan(engine Engine, start, end proto.EncodedKey, max int64) ([]proto.RawKeyValue, error) {
 	var kvs []proto.RawKeyValue
-	err := engine.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+	err := Iterate(engine, start, end, func(kv proto.RawKeyValue) (bool, error) {
 		if max != 0 && int64(len(kvs)) >= max {
 			return true, nil
 		}
@@ -256,7 +347,7 @@ func Scan(engine Engine, start, end proto.EncodedKey, max int64) ([]proto.RawKey
 // inserting tombstones, as with deletion through the MVCC.
 func ClearRange(engine Engine, start, end proto.EncodedKey) (int, error) {
 	var deletes []interface{}
-	if err := engine.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+	if err := Iterate(engine, start, end, func(kv proto.RawKeyValue) (bool, error) {
 		deletes = append(deletes, BatchDelete{proto.RawKeyValue{Key: kv.Key}})
 		return false, nil
 	}); err != nil {
@@ -264,3 +355,10 @@ func ClearRange(engine Engine, start, end proto.EncodedKey) (int, error) {
 	}
 	return len(deletes), engine.WriteBatch(deletes)
 }
+
+// ClearPrefix removes all MVCC keys and versions with the specified
+// prefix, as ClearRange does, returning the number of entries removed.
+func ClearPrefix(engine Engine, prefix proto.Key) (int, error) {
+	start, end := PrefixSpan(prefix)
+	return ClearRange(engine, start, end)
+}
@@ -460,6 +460,41 @@ func TestEngineScan2(t *testing.T) {
 	}, t)
 }
 
+// TestEngineIteratorPrev verifies that an iterator moves back over
+// the keys of the engine from a seeked key and from the last key.
+func TestEngineIteratorPrev(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	runWithAllEngines(func(engine Engine, t *testing.T) {
+		keys := []proto.EncodedKey{
+			proto.EncodedKey("a"),
+			proto.EncodedKey("b"),
+			proto.EncodedKey("c"),
+		}
+		insertKeys(keys, engine, t)
+
+		iter := engine.NewIterator()
+		defer iter.Close()
+		verifyPrev := func(expKeys []proto.EncodedKey) {
+			for i := len(expKeys) - 1; i >= 0; i-- {
+				if !iter.Valid() {
+					t.Fatalf("expected key %q; iterator invalid: %v", expKeys[i], iter.Error())
+				}
+				if key := iter.Key(); !key.Equal(expKeys[i]) {
+					t.Errorf("expected key %q; got %q", expKeys[i], key)
+				}
+				iter.Prev()
+			}
+			if iter.Valid() {
+				t.Errorf("expected iterator to be invalid before first key; got %q", iter.Key())
+			}
+		}
+		iter.Seek(proto.EncodedKey("b"))
+		verifyPrev(keys[:2])
+		iter.SeekToLast()
+		verifyPrev(keys)
+	}, t)
+}
+
 func TestEngineDeleteRange(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	runWithAllEngines(func(engine Engine, t *testing.T) {
@@ -492,6 +527,47 @@ func TestEngineDeleteRange(t *testing.T) {
 	}, t)
 }
 
+// TestEngineClearPrefix verifies that ClearPrefix removes all
+// versions of MVCC keys with the prefix and nothing else.
+func TestEngineClearPrefix(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	runWithAllEngines(func(engine Engine, t *testing.T) {
+		value := proto.Value{Bytes: []byte("value")}
+		for _, key := range []proto.Key{proto.Key("a"), proto.Key("b"), proto.Key("b\x00"), proto.Key("bb"), proto.Key("c")} {
+			for _, ts := range []proto.Timestamp{makeTS(1, 0), makeTS(2, 0)} {
+				if err := MVCCPut(engine, nil, key, ts, value, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		count := 0
+		if err := IteratePrefix(engine, proto.Key("b"), func(kv proto.RawKeyValue) (bool, error) {
+			count++
+			return false, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// Each key has a metadata entry and two versions.
+		if count != 9 {
+			t.Errorf("expected 9 entries with prefix; got %d", count)
+		}
+		cleared, err := ClearPrefix(engine, proto.Key("b"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cleared != count {
+			t.Errorf("expected %d entries cleared; got %d", count, cleared)
+		}
+		kvs, err := MVCCScan(engine, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != 2 || !kvs[0].Key.Equal(proto.Key("a")) || !kvs[1].Key.Equal(proto.Key("c")) {
+			t.Errorf("expected only keys a and c to remain; got %v", kvs)
+		}
+	}, t)
+}
+
 func TestSnapshot(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	runWithAllEngines(func(engine Engine, t *testing.T) {
@@ -590,7 +666,7 @@ func TestSnapshotMethods(t *testing.T) {
 
 		// Verify Iterate.
 		index := 0
-		if err := snap.Iterate(proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax), func(kv proto.RawKeyValue) (bool, error) {
+		if err := Iterate(snap, proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax), func(kv proto.RawKeyValue) (bool, error) {
 			if !bytes.Equal(kv.Key, keys[index]) || !bytes.Equal(kv.Value, vals[index]) {
 				t.Errorf("%d: key/value not equal between expected and snapshot: %s/%s, %s/%s",
 					index, keys[index], vals[index], kv.Key, kv.Value)
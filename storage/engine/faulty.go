@@ -0,0 +1,314 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// An InjectedError is returned by a FaultyEngine for an operation
+// which it has been configured to fail.
+type InjectedError struct {
+	Msg string
+}
+
+// Error formats error string.
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("injected engine failure: %s", e.Msg)
+}
+
+// faults holds the failures configured for a FaultyEngine, shared
+// with the batches and snapshots created from it.
+type faults struct {
+	sync.Mutex
+	failWrite    int // Writes until the one which fails; 0 for none
+	failStart    proto.EncodedKey
+	failEnd      proto.EncodedKey
+	failSync     int // Syncs until the one which crashes; 0 for none
+	syncStart    proto.EncodedKey
+	syncEnd      proto.EncodedKey
+	crashed      bool
+	corruptStart proto.EncodedKey
+	corruptEnd   proto.EncodedKey
+	writeDelay   time.Duration
+}
+
+// A FaultyEngine wraps an Engine and injects failures for use in
+// tests: it can fail a chosen write, crash at a chosen sync, report
+// corruption on reads of a span of keys and delay writes. Writes are
+// Put, Clear, Merge and WriteBatch, which includes the commit of a
+// batch created by NewBatch. Syncs, which make data durable, are
+// WriteBatch and Flush. Batches and snapshots created from a
+// FaultyEngine share its configuration. All methods are thread safe.
+type FaultyEngine struct {
+	Engine
+	f *faults
+}
+
+// NewFaultyEngine returns a FaultyEngine wrapping e which initially
+// injects no failures.
+func NewFaultyEngine(e Engine) *FaultyEngine {
+	return &FaultyEngine{Engine: e, f: &faults{}}
+}
+
+// FailNthWrite causes the nth write from now to fail with an
+// InjectedError, without being applied. The writes which precede it
+// and those which follow it succeed. Specify 0 to cancel a pending
+// failure.
+func (fe *FaultyEngine) FailNthWrite(n int) {
+	fe.FailNthWriteIn(n, nil, nil)
+}
+
+// FailNthWriteIn is like FailNthWrite, but counts only writes of keys
+// in [start, end). This allows a test to fail the application of a
+// command without failing the writes of the Raft log, which are
+// fatal.
+func (fe *FaultyEngine) FailNthWriteIn(n int, start, end proto.EncodedKey) {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	fe.f.failWrite, fe.f.failStart, fe.f.failEnd = n, start, end
+}
+
+// FailNthSync causes the nth sync from now to fail with an
+// InjectedError, after which the engine behaves as though its process
+// had crashed: neither the failed sync nor any later write is
+// applied, though later writes report success so that the store
+// doesn't stop on them. Reads continue to see the data synced before
+// the crash. A test simulates recovery by stopping the store and
+// starting another on the wrapped engine. Specify 0 to cancel a
+// pending crash.
+func (fe *FaultyEngine) FailNthSync(n int) {
+	fe.FailNthSyncIn(n, nil, nil)
+}
+
+// FailNthSyncIn is like FailNthSync, but counts only syncs which
+// write keys in [start, end). Flushes count only if start is nil.
+func (fe *FaultyEngine) FailNthSyncIn(n int, start, end proto.EncodedKey) {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	fe.f.failSync, fe.f.syncStart, fe.f.syncEnd = n, start, end
+}
+
+// Crashed returns whether a sync configured with FailNthSync has
+// failed.
+func (fe *FaultyEngine) Crashed() bool {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	return fe.f.crashed
+}
+
+// CorruptSpan causes reads of keys in [start, end) to fail with an
+// InjectedError, as though the data had been corrupted on disk.
+// Specify a nil start to clear the corruption.
+func (fe *FaultyEngine) CorruptSpan(start, end proto.EncodedKey) {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	fe.f.corruptStart, fe.f.corruptEnd = start, end
+}
+
+// DelayWrites causes each subsequent write to block for d before it
+// is applied. Specify 0 to stop delaying writes.
+func (fe *FaultyEngine) DelayWrites(d time.Duration) {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	fe.f.writeDelay = d
+}
+
+// inSpan returns whether key lies within [start, end). A nil start
+// denotes the entire key space.
+func inSpan(key, start, end proto.EncodedKey) bool {
+	return start == nil || (bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0)
+}
+
+// countDown decrements *n if it's positive and one of keys lies
+// within [start, end), or if no keys are supplied and the span is
+// unrestricted. It returns whether *n reached zero.
+func countDown(n *int, start, end proto.EncodedKey, keys []proto.EncodedKey) bool {
+	if *n <= 0 {
+		return false
+	}
+	match := len(keys) == 0 && start == nil
+	for _, key := range keys {
+		if inSpan(key, start, end) {
+			match = true
+			break
+		}
+	}
+	if match {
+		*n--
+	}
+	return match && *n == 0
+}
+
+// beforeWrite applies the configured delay and returns whether the
+// write of the supplied keys should be applied, along with an error
+// if it's the write configured to fail or the sync configured to
+// crash the engine. Writes made once the engine has crashed aren't
+// applied, but don't fail. Flushes aren't writes, but are syncs.
+func (fe *FaultyEngine) beforeWrite(op string, write, sync bool, keys ...proto.EncodedKey) (bool, error) {
+	fe.f.Lock()
+	delay := fe.f.writeDelay
+	crashed := fe.f.crashed
+	fail := false
+	if !crashed {
+		fail = write && countDown(&fe.f.failWrite, fe.f.failStart, fe.f.failEnd, keys)
+		if sync && countDown(&fe.f.failSync, fe.f.syncStart, fe.f.syncEnd, keys) {
+			fe.f.crashed = true
+			fail = true
+		}
+	}
+	fe.f.Unlock()
+
+	if delay > 0 && write {
+		time.Sleep(delay)
+	}
+	if fail {
+		return false, &InjectedError{Msg: fmt.Sprintf("failed %s", op)}
+	}
+	return !crashed, nil
+}
+
+// checkCorrupt returns an error if key lies within the corrupt span.
+func (fe *FaultyEngine) checkCorrupt(key proto.EncodedKey) error {
+	fe.f.Lock()
+	defer fe.f.Unlock()
+	if fe.f.corruptStart == nil || !inSpan(key, fe.f.corruptStart, fe.f.corruptEnd) {
+		return nil
+	}
+	return &InjectedError{Msg: fmt.Sprintf("corruption at key %q", key)}
+}
+
+// Put implements the Engine interface.
+func (fe *FaultyEngine) Put(key proto.EncodedKey, value []byte) error {
+	if apply, err := fe.beforeWrite("put", true, false, key); !apply {
+		return err
+	}
+	return fe.Engine.Put(key, value)
+}
+
+// Get implements the Engine interface.
+func (fe *FaultyEngine) Get(key proto.EncodedKey) ([]byte, error) {
+	if err := fe.checkCorrupt(key); err != nil {
+		return nil, err
+	}
+	return fe.Engine.Get(key)
+}
+
+// GetProto implements the Engine interface.
+func (fe *FaultyEngine) GetProto(key proto.EncodedKey, msg gogoproto.Message) (bool, int64, int64, error) {
+	if err := fe.checkCorrupt(key); err != nil {
+		return false, 0, 0, err
+	}
+	return fe.Engine.GetProto(key, msg)
+}
+
+// Clear implements the Engine interface.
+func (fe *FaultyEngine) Clear(key proto.EncodedKey) error {
+	if apply, err := fe.beforeWrite("clear", true, false, key); !apply {
+		return err
+	}
+	return fe.Engine.Clear(key)
+}
+
+// WriteBatch implements the Engine interface.
+func (fe *FaultyEngine) WriteBatch(batch []interface{}) error {
+	keys := make([]proto.EncodedKey, 0, len(batch))
+	for _, op := range batch {
+		switch t := op.(type) {
+		case BatchPut:
+			keys = append(keys, t.Key)
+		case BatchDelete:
+			keys = append(keys, t.Key)
+		case BatchMerge:
+			keys = append(keys, t.Key)
+		}
+	}
+	if apply, err := fe.beforeWrite("batch write", true, true, keys...); !apply {
+		return err
+	}
+	return fe.Engine.WriteBatch(batch)
+}
+
+// Flush implements the Engine interface.
+func (fe *FaultyEngine) Flush() error {
+	if apply, err := fe.beforeWrite("flush", false, true); !apply {
+		return err
+	}
+	return fe.Engine.Flush()
+}
+
+// Merge implements the Engine interface.
+func (fe *FaultyEngine) Merge(key proto.EncodedKey, value []byte) error {
+	if apply, err := fe.beforeWrite("merge", true, false, key); !apply {
+		return err
+	}
+	return fe.Engine.Merge(key, value)
+}
+
+// NewIterator implements the Engine interface.
+func (fe *FaultyEngine) NewIterator() Iterator {
+	return &faultyIterator{Iterator: fe.Engine.NewIterator(), fe: fe}
+}
+
+// NewScanIterator implements the Engine interface.
+func (fe *FaultyEngine) NewScanIterator(hint ScanHint) Iterator {
+	return &faultyIterator{Iterator: fe.Engine.NewScanIterator(hint), fe: fe}
+}
+
+// NewSnapshot implements the Engine interface. The snapshot reports
+// the same corruption as the engine.
+func (fe *FaultyEngine) NewSnapshot() Engine {
+	return &FaultyEngine{Engine: fe.Engine.NewSnapshot(), f: fe.f}
+}
+
+// NewBatch implements the Engine interface. The batch is committed
+// through the FaultyEngine, so that its commit counts as a write.
+func (fe *FaultyEngine) NewBatch() Engine {
+	return NewBatch(fe)
+}
+
+// A faultyIterator becomes invalid with an InjectedError on reaching
+// a key within the corrupt span of its FaultyEngine.
+type faultyIterator struct {
+	Iterator
+	fe  *FaultyEngine
+	err error
+}
+
+// Valid implements the Iterator interface.
+func (fi *faultyIterator) Valid() bool {
+	if fi.err != nil || !fi.Iterator.Valid() {
+		return false
+	}
+	if fi.err = fi.fe.checkCorrupt(fi.Iterator.Key()); fi.err != nil {
+		return false
+	}
+	return true
+}
+
+// Error implements the Iterator interface.
+func (fi *faultyIterator) Error() error {
+	if fi.err != nil {
+		return fi.err
+	}
+	return fi.Iterator.Error()
+}
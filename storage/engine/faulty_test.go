@@ -0,0 +1,164 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestFaultyEngineFailNthWrite verifies that exactly the chosen write
+// fails, including the commit of a batch, and that it isn't applied.
+func TestFaultyEngineFailNthWrite(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	fe := NewFaultyEngine(e)
+
+	fe.FailNthWrite(2)
+	if err := fe.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	b := fe.NewBatch()
+	if err := b.Put(proto.EncodedKey("b"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(); err == nil {
+		t.Fatal("expected batch commit to fail")
+	} else if _, ok := err.(*InjectedError); !ok {
+		t.Fatalf("expected InjectedError; got %s", err)
+	}
+	if err := fe.Put(proto.EncodedKey("c"), []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, exp := range map[string]string{"a": "1", "b": "", "c": "3"} {
+		val, err := e.Get(proto.EncodedKey(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(val) != exp {
+			t.Errorf("key %q: expected %q; got %q", key, exp, val)
+		}
+	}
+}
+
+// TestFaultyEngineFailNthSync verifies that the chosen sync fails and
+// crashes the engine: neither it nor any later write is applied,
+// while the data synced before it remains readable.
+func TestFaultyEngineFailNthSync(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	fe := NewFaultyEngine(e)
+
+	// Only writes of keys in [b, c) count.
+	fe.FailNthSyncIn(2, proto.EncodedKey("b"), proto.EncodedKey("c"))
+	for i, key := range []string{"a", "b", "a2"} {
+		b := fe.NewBatch()
+		if err := b.Put(proto.EncodedKey(key), []byte{byte('1' + i)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fe.Crashed() {
+		t.Fatal("expected engine not to have crashed before the chosen sync")
+	}
+	b := fe.NewBatch()
+	if err := b.Put(proto.EncodedKey("b2"), []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(); err == nil {
+		t.Fatal("expected batch commit to fail")
+	} else if _, ok := err.(*InjectedError); !ok {
+		t.Fatalf("expected InjectedError; got %s", err)
+	}
+	if !fe.Crashed() {
+		t.Fatal("expected engine to have crashed")
+	}
+	// Later writes report success but aren't applied.
+	if err := fe.Put(proto.EncodedKey("c"), []byte("5")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, exp := range map[string]string{"a": "1", "b": "2", "a2": "3", "b2": "", "c": ""} {
+		val, err := fe.Get(proto.EncodedKey(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(val) != exp {
+			t.Errorf("key %q: expected %q; got %q", key, exp, val)
+		}
+	}
+}
+
+// TestFaultyEngineCorruptSpan verifies that reads of keys within the
+// corrupt span fail through every read path, and that reads outside
+// it succeed.
+func TestFaultyEngineCorruptSpan(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	fe := NewFaultyEngine(e)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := fe.Put(proto.EncodedKey(key), []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fe.CorruptSpan(proto.EncodedKey("b"), proto.EncodedKey("c"))
+
+	if _, err := fe.Get(proto.EncodedKey("a")); err != nil {
+		t.Errorf("expected read outside corrupt span to succeed: %s", err)
+	}
+	if _, err := fe.Get(proto.EncodedKey("b")); err == nil {
+		t.Error("expected read of corrupt key to fail")
+	}
+	snap := fe.NewSnapshot()
+	defer snap.Close()
+	if _, err := snap.Get(proto.EncodedKey("b")); err == nil {
+		t.Error("expected snapshot read of corrupt key to fail")
+	}
+	var keys []string
+	err := Iterate(fe, proto.EncodedKey("a"), proto.EncodedKey("d"), func(kv proto.RawKeyValue) (bool, error) {
+		keys = append(keys, string(kv.Key))
+		return false, nil
+	})
+	if err == nil || len(keys) != 1 {
+		t.Errorf("expected iteration to fail after one key; got %v, %v", keys, err)
+	}
+	iter := fe.NewIterator()
+	defer iter.Close()
+	iter.Seek([]byte("a"))
+	if !iter.Valid() {
+		t.Fatal("expected iterator to be valid at key \"a\"")
+	}
+	iter.Next()
+	if iter.Valid() || iter.Error() == nil {
+		t.Error("expected iterator to fail on reaching corrupt key")
+	}
+
+	fe.CorruptSpan(nil, nil)
+	if _, err := fe.Get(proto.EncodedKey("b")); err != nil {
+		t.Errorf("expected read to succeed once corruption is cleared: %s", err)
+	}
+}
@@ -17,7 +17,15 @@
 
 package engine
 
-import "github.com/cockroachdb/cockroach/proto"
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
 
 // InMem wraps RocksDB and configures it for in-memory only storage.
 type InMem struct {
@@ -34,3 +42,89 @@ func NewInMem(attrs proto.Attributes, cacheSize int64) *InMem {
 	}
 	return db
 }
+
+// inMemFileHeader begins each file to which an InMem engine is saved,
+// identifying the format of the file.
+const inMemFileHeader = "cockroach-inmem-v1\n"
+
+// SaveFile writes a consistent copy of the contents of the engine to
+// the file at path, from which they may be reloaded with
+// NewInMemFromFile. The file is a sequence of uvarint
+// length-prefixed keys and values, in order.
+func (m *InMem) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(inMemFileHeader); err != nil {
+		return err
+	}
+	snap := m.NewSnapshot()
+	defer snap.Close()
+	iter := snap.NewIterator()
+	defer iter.Close()
+	var lenBuf [binary.MaxVarintLen64]byte
+	for iter.Seek(nil); iter.Valid(); iter.Next() {
+		for _, b := range [][]byte{iter.Key(), iter.Value()} {
+			n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+			if _, err := w.Write(lenBuf[:n]); err != nil {
+				return err
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// NewInMemFromFile allocates and returns a new, opened InMem engine
+// holding the contents saved to the file at path by InMem.SaveFile.
+func NewInMemFromFile(attrs proto.Attributes, cacheSize int64, path string) (*InMem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	header := make([]byte, len(inMemFileHeader))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != inMemFileHeader {
+		return nil, util.Errorf("%s isn't a saved in-memory engine", path)
+	}
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+
+	m := NewInMem(attrs, cacheSize)
+	for {
+		key, err := readBytes()
+		if err == io.EOF {
+			return m, nil
+		}
+		var value []byte
+		if err == nil {
+			value, err = readBytes()
+		}
+		if err == nil {
+			err = m.Put(key, value)
+		}
+		if err != nil {
+			m.Close()
+			return nil, util.Errorf("unable to load in-memory engine from %s: %s", path, err)
+		}
+	}
+}
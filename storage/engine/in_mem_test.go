@@ -0,0 +1,82 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestInMemSaveFile verifies that an engine loaded from the file to
+// which an InMem engine was saved holds the same contents, and that
+// other files are refused.
+func TestInMemSaveFile(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	loc := util.CreateTempDirectory()
+	defer func() {
+		if err := os.RemoveAll(loc); err != nil {
+			t.Errorf("could not remove %s: %v", loc, err)
+		}
+	}()
+
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	for _, key := range []proto.Key{testKey1, testKey2, KeyMax} {
+		if err := MVCCPut(e, nil, key, makeTS(1, 0), value1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Put(proto.EncodedKey("empty"), []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(loc, "engine")
+	if err := e.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewInMemFromFile(proto.Attributes{}, 1<<20, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	scanAll := func(e Engine) []proto.RawKeyValue {
+		var kvs []proto.RawKeyValue
+		iter := e.NewIterator()
+		defer iter.Close()
+		for iter.Seek(nil); iter.Valid(); iter.Next() {
+			kvs = append(kvs, proto.RawKeyValue{Key: iter.Key(), Value: iter.Value()})
+		}
+		return kvs
+	}
+	if expKVs, kvs := scanAll(e), scanAll(loaded); !reflect.DeepEqual(expKVs, kvs) {
+		t.Errorf("expected loaded engine to hold %+v; got %+v", expKVs, kvs)
+	}
+
+	bogus := filepath.Join(loc, "bogus")
+	if err := ioutil.WriteFile(bogus, []byte("not an engine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewInMemFromFile(proto.Attributes{}, 1<<20, bogus); err == nil {
+		t.Error("expected load of bogus file to fail")
+	}
+}
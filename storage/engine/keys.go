@@ -24,6 +24,7 @@ import (
 	"strconv"
 
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -45,6 +46,19 @@ func StoreIdentKey() proto.Key {
 	return MakeStoreKey(KeyLocalStoreIdentSuffix, proto.Key{})
 }
 
+// StoreHealthProbeKey returns a store-local key written periodically
+// to measure engine write latency.
+func StoreHealthProbeKey() proto.Key {
+	return MakeStoreKey(KeyLocalStoreHealthProbeSuffix, proto.Key{})
+}
+
+// StoreEncryptionKey returns a store-local key for the record of the
+// encryption keys with which the values of an encrypted engine have
+// been encrypted.
+func StoreEncryptionKey() proto.Key {
+	return MakeStoreKey(KeyLocalStoreEncryptionSuffix, proto.Key{})
+}
+
 // StoreStatKey returns the key for accessing the named stat.
 func StoreStatKey(stat proto.Key) proto.Key {
 	return MakeStoreKey(KeyLocalStoreStatSuffix, stat)
@@ -56,6 +70,43 @@ func StoreStatusKey(storeID int32) proto.Key {
 	return MakeKey(KeyStatusStorePrefix, proto.Key(strconv.FormatInt(int64(storeID), 10)))
 }
 
+// AcctUsagePrefix returns the key prefix under which the usage
+// rollups of all stores for the specified accounting config prefix
+// are stored.
+func AcctUsagePrefix(prefix proto.Key) proto.Key {
+	return MakeKey(KeyStatusAcctUsagePrefix, encoding.EncodeBytes(nil, prefix))
+}
+
+// AcctUsageKey returns the key for accessing the usage rollup of the
+// specified accounting config prefix computed by the specified store.
+func AcctUsageKey(prefix proto.Key, storeID int32) proto.Key {
+	return MakeKey(AcctUsagePrefix(prefix), encoding.EncodeUvarint(nil, uint64(storeID)))
+}
+
+// NodeLivenessKey returns the key for the liveness record of the
+// specified node.
+func NodeLivenessKey(nodeID proto.NodeID) proto.Key {
+	return MakeKey(KeyNodeLivenessPrefix, encoding.EncodeUvarint(nil, uint64(nodeID)))
+}
+
+// DecodeNodeLivenessKey returns the node ID of a key returned by
+// NodeLivenessKey.
+func DecodeNodeLivenessKey(key proto.Key) (proto.NodeID, error) {
+	if !bytes.HasPrefix(key, KeyNodeLivenessPrefix) {
+		return 0, util.Errorf("key %q is not a node liveness key", key)
+	}
+	_, nodeID := encoding.DecodeUvarint(key[len(KeyNodeLivenessPrefix):])
+	return proto.NodeID(nodeID), nil
+}
+
+// RangeEventLogKey returns the key for the range event log entry of
+// an event which occurred on the specified range at the specified wall
+// time. Entries sort by time.
+func RangeEventLogKey(timestamp, raftID int64) proto.Key {
+	key := encoding.EncodeUvarint(nil, uint64(timestamp))
+	return MakeKey(KeyRangeEventLogPrefix, encoding.EncodeUvarint(key, uint64(raftID)))
+}
+
 // MakeRangeIDKey creates a range-local key based on the range's
 // Raft ID, metadata key suffix, and optional detail (e.g. the
 // encoded command ID for a response cache entry, etc.).
@@ -66,6 +117,13 @@ func MakeRangeIDKey(raftID int64, suffix, detail proto.Key) proto.Key {
 	return MakeKey(KeyLocalRangeIDPrefix, encoding.EncodeUvarint(nil, uint64(raftID)), suffix, detail)
 }
 
+// RangeIDPrefix returns the prefix shared by all range-local keys
+// keyed by the range's Raft ID, such as its Raft state, response
+// cache and stats.
+func RangeIDPrefix(raftID int64) proto.Key {
+	return MakeKey(KeyLocalRangeIDPrefix, encoding.EncodeUvarint(nil, uint64(raftID)))
+}
+
 // RaftLogKey returns a system-local key for a Raft log entry.
 func RaftLogKey(raftID int64, logIndex uint64) proto.Key {
 	// The log is stored "backwards" so we can easily find the highest index stored.
@@ -103,12 +161,33 @@ func RaftAppliedIndexKey(raftID int64) proto.Key {
 	return MakeRangeIDKey(raftID, KeyLocalRaftAppliedIndexSuffix, proto.Key{})
 }
 
+// RaftAppliedProposalKey returns a range-local key by Raft ID for the
+// record of an applied Raft proposal, with detail specified by
+// encoding the supplied proposal ID.
+func RaftAppliedProposalKey(raftID int64, proposalID *proto.ClientCmdID) proto.Key {
+	detail := encoding.EncodeUvarint(nil, uint64(proposalID.WallTime))
+	detail = encoding.EncodeUint64(detail, uint64(proposalID.Random))
+	return MakeRangeIDKey(raftID, KeyLocalRaftAppliedProposalSuffix, detail)
+}
+
 // RangeStatKey returns the key for accessing the named stat
 // for the specified Raft ID.
 func RangeStatKey(raftID int64, stat proto.Key) proto.Key {
 	return MakeRangeIDKey(raftID, KeyLocalRangeStatSuffix, stat)
 }
 
+// RangeStatPrefix returns the range-local prefix shared by all stats
+// of the range with the specified Raft ID.
+func RangeStatPrefix(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalRangeStatSuffix, proto.Key{})
+}
+
+// ResponseCachePrefix returns the range-local prefix shared by all
+// response cache entries of the range with the specified Raft ID.
+func ResponseCachePrefix(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalResponseCacheSuffix, proto.Key{})
+}
+
 // ResponseCacheKey returns a range-local key by Raft ID for a
 // response cache entry, with detail specified by encoding the
 // supplied client command ID.
@@ -131,6 +210,14 @@ func MakeRangeKey(key, suffix, detail proto.Key) proto.Key {
 	return MakeKey(KeyLocalRangeKeyPrefix, encoding.EncodeBytes(nil, key), suffix, detail)
 }
 
+// RangeKeyPrefix returns the prefix shared by all range-local keys
+// based on the specified key. The range-local keys of the range
+// spanning [start, end) sort from RangeKeyPrefix(start) (inclusive)
+// to RangeKeyPrefix(end) (exclusive).
+func RangeKeyPrefix(key proto.Key) proto.Key {
+	return MakeKey(KeyLocalRangeKeyPrefix, encoding.EncodeBytes(nil, key))
+}
+
 // DecodeRangeKey decodes the range key into range start key,
 // suffix and optional detail (may be nil).
 func DecodeRangeKey(key proto.Key) (startKey, suffix, detail proto.Key) {
@@ -161,6 +248,18 @@ func RangeLastVerificationTimestampKey(raftID int64) proto.Key {
 	return MakeRangeIDKey(raftID, KeyLocalRangeLastVerificationTimestampSuffix, proto.Key{})
 }
 
+// RangeLeaderLeaseKey returns a range-local key for the range's
+// leader lease.
+func RangeLeaderLeaseKey(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalRangeLeaderLeaseSuffix, proto.Key{})
+}
+
+// RangeTombstoneKey returns a range-local key for the tombstone
+// written when the range's replica is removed from a store.
+func RangeTombstoneKey(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalRangeTombstoneSuffix, proto.Key{})
+}
+
 // RangeTreeNodeKey returns a range-local key for the the range's
 // node in the range tree.
 func RangeTreeNodeKey(key proto.Key) proto.Key {
@@ -353,6 +452,12 @@ var (
 	KeyLocalStoreIdentSuffix = proto.Key("iden")
 	// KeyLocalStoreStatSuffix is the suffix for store statistics.
 	KeyLocalStoreStatSuffix = proto.Key("sst-")
+	// KeyLocalStoreHealthProbeSuffix is the suffix for the key written
+	// by the store health monitor to probe engine write latency.
+	KeyLocalStoreHealthProbeSuffix = proto.Key("hlth")
+	// KeyLocalStoreEncryptionSuffix is the suffix for the record of
+	// the encryption keys of an encrypted engine.
+	KeyLocalStoreEncryptionSuffix = proto.Key("encr")
 
 	// KeyLocalRangeIDPrefix is the prefix identifying per-range data
 	// indexed by Raft ID. The Raft ID is appended to this prefix,
@@ -372,11 +477,19 @@ var (
 	KeyLocalRaftTruncatedStateSuffix = proto.Key("rftt")
 	// KeyLocalRaftAppliedIndexSuffix is the suffix for the raft applied index.
 	KeyLocalRaftAppliedIndexSuffix = proto.Key("rfta")
+	// KeyLocalRaftAppliedProposalSuffix is the suffix for the records
+	// of applied Raft proposals, used to apply each proposal only once.
+	KeyLocalRaftAppliedProposalSuffix = proto.Key("rftp")
 	// KeyLocalRangeGCMetadataSuffix is the suffix for a range's GC metadata.
 	KeyLocalRangeGCMetadataSuffix = proto.Key("rgcm")
 	// KeyLocalRangeLastVerificationTimestampSuffix is the suffix for a range's
 	// last verification timestamp (for checking integrity of on-disk data).
 	KeyLocalRangeLastVerificationTimestampSuffix = proto.Key("rlvt")
+	// KeyLocalRangeLeaderLeaseSuffix is the suffix for a range's leader lease.
+	KeyLocalRangeLeaderLeaseSuffix = proto.Key("rll-")
+	// KeyLocalRangeTombstoneSuffix is the suffix for the tombstone of a
+	// replica removed from the store.
+	KeyLocalRangeTombstoneSuffix = proto.Key("rtmb")
 	// KeyLocalRangeStatSuffix is the suffix for range statistics.
 	KeyLocalRangeStatSuffix = proto.Key("rst-")
 	// KeyLocalResponseCacheSuffix is the suffix for keys storing
@@ -442,8 +555,16 @@ var (
 	KeyConfigZonePrefix = MakeKey(KeySystemPrefix, proto.Key("zone"))
 	// KeyNodeIDGenerator is the global node ID generator sequence.
 	KeyNodeIDGenerator = MakeKey(KeySystemPrefix, proto.Key("node-idgen"))
+	// KeyNodeLivenessPrefix specifies the key prefix for node liveness
+	// records. The suffix is the encoded node ID and the value is the
+	// wall time in nanoseconds until which the node is considered live.
+	KeyNodeLivenessPrefix = MakeKey(KeySystemPrefix, proto.Key("node-liveness-"))
 	// KeyRaftIDGenerator is the global Raft consensus group ID generator sequence.
 	KeyRaftIDGenerator = MakeKey(KeySystemPrefix, proto.Key("raft-idgen"))
+	// KeyRangeEventLogPrefix specifies the key prefix for the range
+	// event log. The suffix is the encoded wall time of the event
+	// followed by the encoded Raft ID of the range.
+	KeyRangeEventLogPrefix = MakeKey(KeySystemPrefix, proto.Key("range-log-"))
 	// KeySchemaPrefix specifies key prefixes for schema definitions.
 	KeySchemaPrefix = MakeKey(KeySystemPrefix, proto.Key("schema"))
 	// KeyStoreIDGeneratorPrefix specifies key prefixes for sequence
@@ -456,4 +577,7 @@ var (
 	KeyStatusPrefix = MakeKey(KeySystemPrefix, proto.Key("status-"))
 	// KeyStatusStorePrefix stores all status info for stores.
 	KeyStatusStorePrefix = MakeKey(KeyStatusPrefix, proto.Key("store-"))
+	// KeyStatusAcctUsagePrefix stores per-store usage rollups for
+	// accounting config prefixes.
+	KeyStatusAcctUsagePrefix = MakeKey(KeyStatusPrefix, proto.Key("acct-usage-"))
 )
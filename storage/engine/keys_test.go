@@ -72,6 +72,77 @@ func TestKeyAddress(t *testing.T) {
 	}
 }
 
+// TestRangeLocalPrefixes verifies that range-local keys sort within
+// the spans of the prefixes constructed for them, and that the keys
+// of other ranges don't.
+func TestRangeLocalPrefixes(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	within := func(key proto.Key, start, end proto.EncodedKey) bool {
+		encKey := MVCCEncodeKey(key)
+		return !encKey.Less(start) && encKey.Less(end)
+	}
+	cmdID := &proto.ClientCmdID{WallTime: 1, Random: 2}
+	testCases := []struct {
+		prefix proto.Key
+		keys   []proto.Key
+		others []proto.Key
+	}{
+		{
+			RangeIDPrefix(10),
+			[]proto.Key{RaftLogKey(10, 5), RaftHardStateKey(10), RangeStatKey(10, KeyLocalRangeStatSuffix), ResponseCacheKey(10, cmdID)},
+			[]proto.Key{RaftHardStateKey(1), RaftHardStateKey(11), RaftHardStateKey(100)},
+		},
+		{
+			ResponseCachePrefix(10),
+			[]proto.Key{ResponseCacheKey(10, cmdID), ResponseCacheKey(10, nil)},
+			[]proto.Key{ResponseCacheKey(11, cmdID), RaftHardStateKey(10)},
+		},
+		{
+			RangeStatPrefix(10),
+			[]proto.Key{RangeStatKey(10, proto.Key("live"))},
+			[]proto.Key{RangeStatKey(11, proto.Key("live")), RangeGCMetadataKey(10)},
+		},
+		{
+			RaftLogPrefix(10),
+			[]proto.Key{RaftLogKey(10, 0), RaftLogKey(10, 1<<40)},
+			[]proto.Key{RaftLogKey(11, 1), RaftTruncatedStateKey(10)},
+		},
+	}
+	for i, test := range testCases {
+		start, end := PrefixSpan(test.prefix)
+		for _, key := range test.keys {
+			if !within(key, start, end) {
+				t.Errorf("%d: expected key %q within prefix %q", i, key, test.prefix)
+			}
+		}
+		for _, key := range test.others {
+			if within(key, start, end) {
+				t.Errorf("%d: expected key %q outside prefix %q", i, key, test.prefix)
+			}
+		}
+	}
+
+	// The range-local keys of the range [b, d) sort between the range
+	// key prefixes of its bounds.
+	start, end := MVCCEncodeKey(RangeKeyPrefix(proto.Key("b"))), MVCCEncodeKey(RangeKeyPrefix(proto.Key("d")))
+	for _, key := range []proto.Key{
+		RangeDescriptorKey(proto.Key("b")),
+		TransactionKey(proto.Key("c"), proto.Key(uuid.New())),
+	} {
+		if !within(key, start, end) {
+			t.Errorf("expected key %q within range-local span of [b, d)", key)
+		}
+	}
+	for _, key := range []proto.Key{
+		RangeDescriptorKey(proto.Key("a")),
+		TransactionKey(proto.Key("d"), proto.Key(uuid.New())),
+	} {
+		if within(key, start, end) {
+			t.Errorf("expected key %q outside range-local span of [b, d)", key)
+		}
+	}
+}
+
 func TestRangeMetaKey(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	testCases := []struct {
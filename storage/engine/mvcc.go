@@ -98,6 +98,21 @@ func (ms *MVCCStats) Accumulate(oms MVCCStats) {
 	ms.LastUpdateNanos += oms.LastUpdateNanos
 }
 
+// Subtract subtracts the values of oms from ms.
+func (ms *MVCCStats) Subtract(oms MVCCStats) {
+	ms.LiveBytes -= oms.LiveBytes
+	ms.KeyBytes -= oms.KeyBytes
+	ms.ValBytes -= oms.ValBytes
+	ms.IntentBytes -= oms.IntentBytes
+	ms.LiveCount -= oms.LiveCount
+	ms.KeyCount -= oms.KeyCount
+	ms.ValCount -= oms.ValCount
+	ms.IntentCount -= oms.IntentCount
+	ms.IntentAge -= oms.IntentAge
+	ms.GCBytesAge -= oms.GCBytesAge
+	ms.LastUpdateNanos -= oms.LastUpdateNanos
+}
+
 // updateStatsForKey returns whether or not the bytes and counts for
 // the specified key should be tracked. Local keys are excluded.
 func (ms *MVCCStats) updateStatsForKey(key proto.Key) bool {
@@ -529,6 +544,12 @@ func mvccGetInternal(engine Engine, key proto.Key, metaKey proto.EncodedKey, tim
 			// intent; the reader will have to act on this.
 			return nil, &proto.WriteIntentError{Key: key, Txn: *meta.Txn}
 		}
+		// If the latest write is a committed deletion, the metadata
+		// suffices and the tombstone needn't be read. This keeps scans
+		// over spans of deleted keys from reading a version per key.
+		if meta.Deleted && meta.Txn == nil {
+			return nil, nil
+		}
 		latestKey := mvccEncodeTimestamp(metaKey, meta.Timestamp)
 
 		// Check for case where we're reading our own txn's intent
@@ -693,6 +714,11 @@ func mvccPutInternal(engine Engine, ms *MVCCStats, key proto.Key, timestamp prot
 	if value.Value != nil && value.Value.Bytes != nil && value.Value.Integer != nil {
 		return util.Errorf("key %q value contains both a byte slice and an integer value: %+v", key, value)
 	}
+	// Values are checksummed as they're written, so that reads can
+	// detect their corruption.
+	if value.Value != nil {
+		value.Value.InitChecksum(key)
+	}
 
 	meta := &buf.meta
 	metaKey := mvccEncodeKey(buf.key[0:0], key)
@@ -797,8 +823,12 @@ func mvccPutInternal(engine Engine, ms *MVCCStats, key proto.Key, timestamp prot
 
 // MVCCIncrement fetches the value for key, and assuming the value is
 // an "integer" type, increments it by inc and stores the new
-// value. The newly incremented value is returned.
-func MVCCIncrement(engine Engine, ms *MVCCStats, key proto.Key, timestamp proto.Timestamp, txn *proto.Transaction, inc int64) (int64, error) {
+// value. The newly incremented value is returned. An increment which
+// would overflow or underflow returns an IncrementOverflowError,
+// unless saturate is true, in which case the maximum or minimum int64
+// is stored instead.
+func MVCCIncrement(engine Engine, ms *MVCCStats, key proto.Key, timestamp proto.Timestamp, txn *proto.Transaction,
+	inc int64, saturate bool) (int64, error) {
 	// Handle check for non-existence of key. In order to detect
 	// the potential write intent by another concurrent transaction
 	// with a newer timestamp, we need to use the max timestamp
@@ -818,55 +848,72 @@ func MVCCIncrement(engine Engine, ms *MVCCStats, key proto.Key, timestamp proto.
 	}
 
 	// Check for overflow and underflow.
+	var r int64
 	if encoding.WillOverflow(int64Val, inc) {
-		return 0, util.Errorf("key %s with value %d incremented by %d results in overflow", key, int64Val, inc)
+		if !saturate {
+			return 0, &proto.IncrementOverflowError{Key: key, Value: int64Val, Increment: inc}
+		}
+		if inc > 0 {
+			r = math.MaxInt64
+		} else {
+			r = math.MinInt64
+		}
+	} else {
+		r = int64Val + inc
 	}
 
-	// Skip writing the value in the event the value already exists.
-	if inc == 0 && value != nil {
+	// Skip writing the value in the event the value is unchanged.
+	if r == int64Val && value != nil {
 		return int64Val, nil
 	}
 
-	r := int64Val + inc
 	newValue := proto.Value{Integer: gogoproto.Int64(r)}
 	newValue.InitChecksum(key)
 	return r, MVCCPut(engine, ms, key, timestamp, newValue, txn)
 }
 
 // MVCCConditionalPut sets the value for a specified key only if the
-// expected value matches. If not, the return a ConditionFailedError
-// containing the actual value.
+// expected value matches, returning the value it replaced, if any. A
+// nil expValue expects the key not to exist. If allowIfMissing is
+// true, the value is also put if the key doesn't exist. If the
+// condition fails, a ConditionFailedError is returned containing the
+// actual value, so that callers may retry the swap against it.
 func MVCCConditionalPut(engine Engine, ms *MVCCStats, key proto.Key, timestamp proto.Timestamp, value proto.Value,
-	expValue *proto.Value, txn *proto.Transaction) error {
+	expValue *proto.Value, allowIfMissing bool, txn *proto.Transaction) (*proto.Value, error) {
 	// Handle check for non-existence of key. In order to detect
 	// the potential write intent by another concurrent transaction
 	// with a newer timestamp, we need to use the max timestamp
 	// while reading.
 	existVal, err := MVCCGet(engine, key, proto.MaxTimestamp, true, txn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if expValue == nil && existVal != nil {
-		return &proto.ConditionFailedError{
+		return nil, &proto.ConditionFailedError{
 			ActualValue: existVal,
 		}
 	} else if expValue != nil {
 		// Handle check for existence when there is no key.
 		if existVal == nil {
-			return &proto.ConditionFailedError{}
+			if !allowIfMissing {
+				return nil, &proto.ConditionFailedError{}
+			}
 		} else if expValue.Bytes != nil && !bytes.Equal(expValue.Bytes, existVal.Bytes) {
-			return &proto.ConditionFailedError{
+			return nil, &proto.ConditionFailedError{
 				ActualValue: existVal,
 			}
 		} else if expValue.Integer != nil && (existVal.Integer == nil || expValue.GetInteger() != existVal.GetInteger()) {
-			return &proto.ConditionFailedError{
+			return nil, &proto.ConditionFailedError{
 				ActualValue: existVal,
 			}
 		}
 	}
 
-	return MVCCPut(engine, ms, key, timestamp, value, txn)
+	if err := MVCCPut(engine, ms, key, timestamp, value, txn); err != nil {
+		return nil, err
+	}
+	return existVal, nil
 }
 
 // MVCCMerge implements a merge operation. Merge adds integer values,
@@ -886,31 +933,32 @@ func MVCCMerge(engine Engine, ms *MVCCStats, key proto.Key, value proto.Value) e
 	if err != nil {
 		return err
 	}
-	engine.Merge(metaKey, data)
+	if err := engine.Merge(metaKey, data); err != nil {
+		return err
+	}
 	ms.updateStatsOnMerge(key, int64(len(value.Bytes)))
 	return nil
 }
 
 // MVCCDeleteRange deletes the range of key/value pairs specified by
-// start and end keys. Specify max=0 for unbounded deletes.
+// start and end keys, writing a deletion tombstone for each live key.
+// Specify max=0 for unbounded deletes. Keys are deleted as they're
+// iterated rather than first being read in their entirety, so the
+// deletes should be written to a batch in order to be applied
+// atomically.
 func MVCCDeleteRange(engine Engine, ms *MVCCStats, key, endKey proto.Key, max int64, timestamp proto.Timestamp, txn *proto.Transaction) (int64, error) {
 	// In order to detect the potential write intent by another
 	// concurrent transaction with a newer timestamp, we need
 	// to use the max timestamp for scan.
-	kvs, err := MVCCScan(engine, key, endKey, max, proto.MaxTimestamp, true, txn)
-	if err != nil {
-		return 0, err
-	}
-
 	num := int64(0)
-	for _, kv := range kvs {
-		err = MVCCDelete(engine, ms, kv.Key, timestamp, txn)
-		if err != nil {
-			return num, err
+	err := MVCCIterate(engine, key, endKey, max, proto.MaxTimestamp, true, txn, func(kv proto.KeyValue) (bool, error) {
+		if err := MVCCDelete(engine, ms, kv.Key, timestamp, txn); err != nil {
+			return true, err
 		}
 		num++
-	}
-	return num, nil
+		return max != 0 && num == max, nil
+	})
+	return num, err
 }
 
 // MVCCScan scans the key range specified by start key through end key
@@ -919,7 +967,7 @@ func MVCCDeleteRange(engine Engine, ms *MVCCStats, key, endKey proto.Key, max in
 func MVCCScan(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
 	consistent bool, txn *proto.Transaction) ([]proto.KeyValue, error) {
 	res := []proto.KeyValue{}
-	if err := MVCCIterate(engine, key, endKey, timestamp, consistent, txn, func(kv proto.KeyValue) (bool, error) {
+	if err := MVCCIterate(engine, key, endKey, max, timestamp, consistent, txn, func(kv proto.KeyValue) (bool, error) {
 		res = append(res, kv)
 		if max != 0 && max == int64(len(res)) {
 			return true, nil
@@ -931,11 +979,68 @@ func MVCCScan(engine Engine, key, endKey proto.Key, max int64, timestamp proto.T
 	return res, nil
 }
 
+// MVCCScanWithLimits scans the key range specified by start key
+// through end key like MVCCScan, returning up to max results whose
+// encoded sizes total no more than maxBytes. Specify max=0 or
+// maxBytes=0 to leave either unbounded. The first result is always
+// returned, even if it alone exceeds maxBytes, so that a scan
+// resumed from it makes progress. If the scan stops at a limit, the
+// key following the last result returned is returned as the key at
+// which to resume the scan; otherwise the resume key is nil.
+func MVCCScanWithLimits(engine Engine, key, endKey proto.Key, max, maxBytes int64, timestamp proto.Timestamp,
+	consistent bool, txn *proto.Transaction) ([]proto.KeyValue, proto.Key, error) {
+	res := []proto.KeyValue{}
+	resumeKey, err := MVCCIterateWithLimits(engine, key, endKey, max, maxBytes, timestamp, consistent, txn, func(kv proto.KeyValue) error {
+		res = append(res, kv)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, resumeKey, nil
+}
+
+// MVCCIterateWithLimits iterates over the key range specified by
+// start and end keys like MVCCIterate, invoking f() for up to max
+// key/value pairs whose encoded sizes total no more than maxBytes,
+// subject to the same rules as MVCCScanWithLimits. It returns the key
+// at which to resume the iteration if it stopped at a limit. No
+// key/value pairs are read past max; reaching maxBytes is detected
+// by reading the pair which would exceed it.
+func MVCCIterateWithLimits(engine Engine, key, endKey proto.Key, max, maxBytes int64, timestamp proto.Timestamp,
+	consistent bool, txn *proto.Transaction, f func(proto.KeyValue) error) (proto.Key, error) {
+	var resumeKey, lastKey proto.Key
+	var count, resBytes int64
+	if err := MVCCIterate(engine, key, endKey, max, timestamp, consistent, txn, func(kv proto.KeyValue) (bool, error) {
+		size := int64(kv.Size())
+		if count > 0 && maxBytes != 0 && resBytes+size > maxBytes {
+			resumeKey = lastKey.Next()
+			return true, nil
+		}
+		if err := f(kv); err != nil {
+			return true, err
+		}
+		count++
+		resBytes += size
+		lastKey = kv.Key
+		if max != 0 && count == max {
+			resumeKey = lastKey.Next()
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	return resumeKey, nil
+}
+
 // MVCCIterate iterates over the key range specified by start and end
 // keys, At each step of the iteration, f() is invoked with the
 // current key/value pair. If f returns true (done) or an error, the
-// iteration stops and the error is propagated.
-func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp,
+// iteration stops and the error is propagated. max is a hint of the
+// maximum number of key/value pairs f() will consume, or 0 if
+// unbounded; it is passed to the engine to tune its reads.
+func MVCCIterate(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
 	consistent bool, txn *proto.Transaction, f func(proto.KeyValue) (bool, error)) error {
 	if !consistent && txn != nil {
 		return util.Errorf("cannot allow inconsistent reads within a transaction")
@@ -954,7 +1059,7 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 	encKey := mvccEncodeKey(keyBuf, key)
 
 	// Get a new iterator and define our getEarlierFunc using iter.Seek.
-	iter := engine.NewIterator()
+	iter := engine.NewScanIterator(ScanHint{MaxKeys: max, EndKey: encEndKey})
 	defer iter.Close()
 	getValue := func(engine Engine, start, end proto.EncodedKey,
 		msg gogoproto.Message) (proto.EncodedKey, error) {
@@ -999,6 +1104,108 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 	}
 }
 
+// MVCCReverseScan scans the key range specified by start key through
+// end key in descending order, up to some maximum number of results.
+// Specify max=0 for unbounded scans.
+func MVCCReverseScan(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
+	consistent bool, txn *proto.Transaction) ([]proto.KeyValue, error) {
+	res := []proto.KeyValue{}
+	if err := MVCCReverseIterate(engine, key, endKey, max, timestamp, consistent, txn, func(kv proto.KeyValue) (bool, error) {
+		res = append(res, kv)
+		if max != 0 && max == int64(len(res)) {
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// MVCCReverseIterate is like MVCCIterate, but iterates over the key
+// range in descending order. The iterator is moved back from the
+// metadata key of each key to the versions of the preceding key, so
+// that no more of the range is read than by a forward iteration.
+func MVCCReverseIterate(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
+	consistent bool, txn *proto.Transaction, f func(proto.KeyValue) (bool, error)) error {
+	if !consistent && txn != nil {
+		return util.Errorf("cannot allow inconsistent reads within a transaction")
+	}
+	if len(endKey) == 0 {
+		return emptyKeyError()
+	}
+
+	buf := getBufferPool.Get().(*getBuffer)
+	defer getBufferPool.Put(buf)
+
+	// We store encEndKey and the metadata key of the current key in the
+	// same buffer to avoid memory allocations.
+	encEndKey := mvccEncodeKey(buf.key[0:0], endKey)
+	keyBuf := encEndKey[len(encEndKey):]
+	encStartKey := MVCCEncodeKey(key)
+
+	// The iterator is not bounded by the end key, as it must be able to
+	// seek to the end key and step back from there.
+	iter := engine.NewScanIterator(ScanHint{MaxKeys: max})
+	defer iter.Close()
+	getValue := func(engine Engine, start, end proto.EncodedKey,
+		msg gogoproto.Message) (proto.EncodedKey, error) {
+		iter.Seek(start)
+		if !iter.Valid() {
+			return nil, iter.Error()
+		}
+		key := iter.Key()
+		if bytes.Compare(key, end) >= 0 {
+			return nil, iter.Error()
+		}
+		return key, iter.ValueProto(msg)
+	}
+
+	// Position the iterator at the last key/value before the end key.
+	iter.Seek(encEndKey)
+	if !iter.Valid() {
+		if err := iter.Error(); err != nil {
+			return err
+		}
+		iter.SeekToLast()
+	}
+	for {
+		for iter.Valid() && bytes.Compare(iter.Key(), encEndKey) >= 0 {
+			iter.Prev()
+		}
+		if !iter.Valid() || bytes.Compare(iter.Key(), encStartKey) < 0 {
+			return iter.Error()
+		}
+		// The iterator is positioned at a version or the metadata of
+		// the next key in descending order; seek to its metadata.
+		key, _, _ := MVCCDecodeKey(iter.Key())
+		metaKey := mvccEncodeKey(keyBuf, key)
+		iter.Seek(metaKey)
+		if !iter.Valid() {
+			return iter.Error()
+		}
+		if !iter.Key().Equal(metaKey) {
+			return util.Errorf("expected an MVCC metadata key: %q", metaKey)
+		}
+		if err := iter.ValueProto(&buf.meta); err != nil {
+			return err
+		}
+		value, err := mvccGetInternal(engine, key, metaKey, timestamp, consistent, txn, getValue, buf)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			done, err := f(proto.KeyValue{Key: key, Value: *value})
+			if done || err != nil {
+				return err
+			}
+		}
+		// Move back from the metadata key to the preceding key.
+		iter.Seek(metaKey)
+		iter.Prev()
+	}
+}
+
 // MVCCResolveWriteIntent either commits or aborts (rolls back) an
 // extant write intent for a given txn according to commit parameter.
 // ResolveWriteIntent will skip write intents of other txns.
@@ -1144,61 +1351,69 @@ func MVCCResolveWriteIntent(engine Engine, ms *MVCCStats, key proto.Key, timesta
 // MVCCResolveWriteIntentRange commits or aborts (rolls back) the
 // range of write intents specified by start and end keys for a given
 // txn. ResolveWriteIntentRange will skip write intents of other
-// txns. Specify max=0 for unbounded resolves.
+// txns. Specify max=0 for unbounded resolves. Returns the number of
+// intents of txn which were resolved.
 func MVCCResolveWriteIntentRange(engine Engine, ms *MVCCStats, key, endKey proto.Key, max int64, timestamp proto.Timestamp, txn *proto.Transaction) (int64, error) {
 	if txn == nil {
 		return 0, util.Error("no txn specified")
 	}
 
-	encKey := MVCCEncodeKey(key)
-	encEndKey := MVCCEncodeKey(endKey)
-	nextKey := encKey
+	iter := engine.NewIterator()
+	defer iter.Close()
 
+	// A single iterator visits the metadata of each key in turn,
+	// seeking past its versions. Only the keys holding an intent of
+	// txn are read again to be resolved.
+	encEndKey := MVCCEncodeKey(endKey)
 	num := int64(0)
-	for {
-		kvs, err := Scan(engine, nextKey, encEndKey, 1)
-		if err != nil {
-			return num, err
-		}
-		// No more keys exists in the given range.
-		if len(kvs) == 0 {
+	for iter.Seek(MVCCEncodeKey(key)); iter.Valid(); {
+		if !iter.Key().Less(encEndKey) {
 			break
 		}
-
-		currentKey, _, isValue := MVCCDecodeKey(kvs[0].Key)
+		currentKey, _, isValue := MVCCDecodeKey(iter.Key())
 		if isValue {
-			return 0, util.Errorf("expected an MVCC metadata key: %s", kvs[0].Key)
+			return num, util.Errorf("expected an MVCC metadata key: %s", iter.Key())
 		}
-		err = MVCCResolveWriteIntent(engine, ms, currentKey, timestamp, txn)
-		if err != nil {
-			log.Warningf("failed to resolve intent for key %q: %v", currentKey, err)
-		} else {
-			num++
-			if max != 0 && max == num {
-				break
+		meta := &proto.MVCCMetadata{}
+		if err := gogoproto.Unmarshal(iter.Value(), meta); err != nil {
+			return num, util.Errorf("unable to unmarshal mvcc meta of key %q: %s", currentKey, err)
+		}
+		if meta.Txn != nil && bytes.Equal(meta.Txn.ID, txn.ID) {
+			if err := MVCCResolveWriteIntent(engine, ms, currentKey, timestamp, txn); err != nil {
+				log.Warningf("failed to resolve intent for key %q: %v", currentKey, err)
+			} else {
+				num++
+				if max != 0 && max == num {
+					break
+				}
 			}
 		}
 
 		// In order to efficiently skip the possibly long list of
 		// old versions for this key; refer to Scan for details.
-		nextKey = MVCCEncodeKey(currentKey.Next())
+		iter.Seek(MVCCEncodeKey(currentKey.Next()))
 	}
 
-	return num, nil
+	return num, iter.Error()
 }
 
 // MVCCGarbageCollect creates an iterator on the engine. In parallel
 // it iterates through the keys listed for garbage collection by the
 // keys slice. The engine iterator is seeked in turn to each listed
-// key, clearing all values with timestamps <= to expiration.
+// key, clearing all values with timestamps <= to expiration. Keys
+// with inline values are skipped.
 func MVCCGarbageCollect(engine Engine, ms *MVCCStats, keys []proto.InternalGCRequest_GCKey, timestamp proto.Timestamp) error {
 	iter := engine.NewIterator()
+	defer iter.Close()
 
 	// Iterate through specified GC keys.
 	for _, gcKey := range keys {
 		encKey := MVCCEncodeKey(gcKey.Key)
 		iter.Seek(encKey)
 		if !iter.Valid() {
+			if err := iter.Error(); err != nil {
+				return err
+			}
 			return util.Errorf("could not seek to key %q", gcKey.Key)
 		}
 		// First, check whether all values of the key are being deleted.
@@ -1206,6 +1421,11 @@ func MVCCGarbageCollect(engine Engine, ms *MVCCStats, keys []proto.InternalGCReq
 		if err := gogoproto.Unmarshal(iter.Value(), meta); err != nil {
 			return util.Errorf("unable to marshal mvcc meta: %s", err)
 		}
+		// Inline values (e.g. transaction records) have no versions to
+		// collect and are left alone.
+		if meta.IsInline() {
+			continue
+		}
 		if !gcKey.Timestamp.Less(meta.Timestamp) {
 			if !meta.Deleted {
 				return util.Errorf("request to GC non-deleted, latest value of %q", gcKey.Key)
@@ -1215,7 +1435,9 @@ func MVCCGarbageCollect(engine Engine, ms *MVCCStats, keys []proto.InternalGCReq
 			}
 			ageSeconds := timestamp.WallTime/1E9 - meta.Timestamp.WallTime/1E9
 			ms.updateStatsOnGC(gcKey.Key, int64(len(iter.Key())), int64(len(iter.Value())), meta, ageSeconds)
-			engine.Clear(iter.Key())
+			if err := engine.Clear(iter.Key()); err != nil {
+				return err
+			}
 		}
 
 		// Now, iterate through all values, GC'ing ones which have expired.
@@ -1229,7 +1451,9 @@ func MVCCGarbageCollect(engine Engine, ms *MVCCStats, keys []proto.InternalGCReq
 			if !gcKey.Timestamp.Less(ts) {
 				ageSeconds := timestamp.WallTime/1E9 - ts.WallTime/1E9
 				ms.updateStatsOnGC(gcKey.Key, mvccVersionTimestampSize, int64(len(iter.Value())), nil, ageSeconds)
-				engine.Clear(iter.Key())
+				if err := engine.Clear(iter.Key()); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -1308,7 +1532,7 @@ func MVCCFindSplitKey(engine Engine, raftID int64, key, endKey proto.Key) (proto
 	bestSplitKey := encStartKey
 	bestSplitDiff := int64(math.MaxInt64)
 
-	if err := engine.Iterate(encStartKey, encEndKey, func(kv proto.RawKeyValue) (bool, error) {
+	if err := Iterate(engine, encStartKey, encEndKey, func(kv proto.RawKeyValue) (bool, error) {
 		// Is key within a legal key range?
 		valid := isValidEncodedSplitKey(kv.Key)
 
@@ -1366,7 +1590,7 @@ func MVCCComputeStats(engine Engine, key, endKey proto.Key, nowNanos int64) (MVC
 	ms := MVCCStats{LastUpdateNanos: nowNanos}
 	first := false
 	meta := &proto.MVCCMetadata{}
-	err := engine.Iterate(encStartKey, encEndKey, func(kv proto.RawKeyValue) (bool, error) {
+	err := Iterate(engine, encStartKey, encEndKey, func(kv proto.RawKeyValue) (bool, error) {
 		_, ts, isValue := MVCCDecodeKey(kv.Key)
 		if !isValue {
 			totalBytes := int64(len(kv.Value)) + int64(len(kv.Key))
@@ -1421,6 +1645,34 @@ func MVCCComputeStats(engine Engine, key, endKey proto.Key, nowNanos int64) (MVC
 	return ms, err
 }
 
+// AgeTo advances the intent and GC bytes ages of the stats, last
+// updated at LastUpdateNanos, to nowNanos.
+func (ms *MVCCStats) AgeTo(nowNanos int64) {
+	diffSeconds := nowNanos/1E9 - ms.LastUpdateNanos/1E9
+	ms.IntentAge += ms.IntentCount * diffSeconds
+	ms.GCBytesAge += MVCCComputeGCBytesAge(ms.KeyBytes+ms.ValBytes-ms.LiveBytes, diffSeconds)
+	ms.LastUpdateNanos = nowNanos
+}
+
+// MVCCSplitStats divides ms, the stats of a range ending at endKey
+// aged to nowNanos, at splitKey. It returns the stats of the ranges
+// before and after splitKey. Only the range after splitKey is scanned;
+// the stats of the range before it are derived by subtraction, so
+// that a split needn't scan both halves. Range-local keys, including
+// those addressed by keys after splitKey, which move to the new range,
+// are never counted in MVCCStats (see updateStatsForKey), so the
+// subtraction leaves none of the new range's stats behind.
+func MVCCSplitStats(engine Engine, ms MVCCStats, splitKey, endKey proto.Key, nowNanos int64) (MVCCStats, MVCCStats, error) {
+	right, err := MVCCComputeStats(engine, splitKey, endKey, nowNanos)
+	if err != nil {
+		return MVCCStats{}, MVCCStats{}, err
+	}
+	left := ms
+	left.Subtract(right)
+	left.LastUpdateNanos = nowNanos
+	return left, right, nil
+}
+
 // MVCCEncodeKey makes an MVCC key for storing MVCC metadata or
 // for storing raw values directly. Use MVCCEncodeVersionValue for
 // storing timestamped version values.
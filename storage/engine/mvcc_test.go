@@ -199,7 +199,7 @@ func TestMVCCPutWithoutTxn(t *testing.T) {
 func TestMVCCIncrement(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
-	newVal, err := MVCCIncrement(engine, nil, testKey1, makeTS(0, 1), nil, 0)
+	newVal, err := MVCCIncrement(engine, nil, testKey1, makeTS(0, 1), nil, 0, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -214,7 +214,7 @@ func TestMVCCIncrement(t *testing.T) {
 		t.Errorf("expected increment of 0 to create key/value")
 	}
 
-	newVal, err = MVCCIncrement(engine, nil, testKey1, makeTS(0, 2), nil, 2)
+	newVal, err = MVCCIncrement(engine, nil, testKey1, makeTS(0, 2), nil, 2, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -223,6 +223,79 @@ func TestMVCCIncrement(t *testing.T) {
 	}
 }
 
+// TestMVCCIncrementOverflow verifies that increments which overflow
+// or underflow return an IncrementOverflowError without writing, and
+// that saturating increments store the int64 bounds instead.
+func TestMVCCIncrementOverflow(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	for i, test := range []struct {
+		key       proto.Key
+		initial   int64
+		inc       int64
+		saturated int64
+	}{
+		{testKey1, math.MaxInt64 - 1, 2, math.MaxInt64},
+		{testKey2, math.MinInt64 + 1, -2, math.MinInt64},
+	} {
+		if _, err := MVCCIncrement(engine, nil, test.key, makeTS(0, 1), nil, test.initial, false); err != nil {
+			t.Fatal(err)
+		}
+		_, err := MVCCIncrement(engine, nil, test.key, makeTS(0, 2), nil, test.inc, false)
+		oErr, ok := err.(*proto.IncrementOverflowError)
+		if !ok {
+			t.Fatalf("%d: expected IncrementOverflowError; got %v", i, err)
+		}
+		if !oErr.Key.Equal(test.key) || oErr.Value != test.initial || oErr.Increment != test.inc {
+			t.Errorf("%d: unexpected error %+v", i, oErr)
+		}
+		newVal, err := MVCCIncrement(engine, nil, test.key, makeTS(0, 3), nil, test.inc, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newVal != test.saturated {
+			t.Errorf("%d: expected saturated value %d; got %d", i, test.saturated, newVal)
+		}
+		val, err := MVCCGet(engine, test.key, makeTS(0, 3), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val.GetInteger() != test.saturated {
+			t.Errorf("%d: expected stored value %d; got %d", i, test.saturated, val.GetInteger())
+		}
+	}
+}
+
+// TestMVCCMerge verifies that integer values merged into a key are
+// summed by the engine's merge operator without a read of the key,
+// both when merged into the engine and into a batch.
+func TestMVCCMerge(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	defer engine.Close()
+	batch := engine.NewBatch()
+	defer batch.Close()
+
+	for _, e := range []Engine{engine, batch} {
+		key := testKey1
+		if e == batch {
+			key = testKey2
+		}
+		for _, inc := range []int64{1, 2, -5} {
+			if err := MVCCMerge(e, nil, key, proto.Value{Integer: gogoproto.Int64(inc)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		val, err := MVCCGet(e, key, makeTS(0, 1), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val == nil || val.GetInteger() != -2 {
+			t.Errorf("expected merged value of -2; got %+v", val)
+		}
+	}
+}
+
 func TestMVCCUpdateExistingKey(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -444,6 +517,88 @@ func TestMVCCGetAndDelete(t *testing.T) {
 	}
 }
 
+// TestMVCCScanDeletedKeys verifies that reads of keys whose latest
+// version is a committed deletion don't read the deletion tombstone,
+// while historical reads below the deletion still see earlier values.
+// TestMVCCGetCorruptValue verifies that a value which no longer
+// matches the checksum computed when it was written is reported by
+// reads with a ValueCorruptionError.
+func TestMVCCGetCorruptValue(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	if err := MVCCPut(engine, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	versionKey := MVCCEncodeVersionKey(testKey1, makeTS(1, 0))
+	mvccVal := &proto.MVCCValue{}
+	if ok, _, _, err := engine.GetProto(versionKey, mvccVal); !ok || err != nil {
+		t.Fatalf("unable to read value: %t, %v", ok, err)
+	}
+	if mvccVal.Value.Checksum == nil {
+		t.Fatal("expected value to be written with a checksum")
+	}
+	// Flip a bit of the value, leaving its checksum in place.
+	mvccVal.Value.Bytes[0] ^= 1
+	data, err := gogoproto.Marshal(mvccVal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Put(versionKey, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MVCCGet(engine, testKey1, makeTS(2, 0), true, nil); err == nil {
+		t.Fatal("expected corrupt value to fail the read")
+	} else if cErr, ok := err.(*proto.ValueCorruptionError); !ok {
+		t.Fatalf("expected ValueCorruptionError; got %T: %s", err, err)
+	} else if !cErr.Key.Equal(testKey1) {
+		t.Errorf("expected corruption of key %q; got %q", testKey1, cErr.Key)
+	}
+	if _, err := MVCCScan(engine, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil); err == nil {
+		t.Error("expected corrupt value to fail the scan")
+	} else if _, ok := err.(*proto.ValueCorruptionError); !ok {
+		t.Errorf("expected ValueCorruptionError; got %T: %s", err, err)
+	}
+}
+
+func TestMVCCScanDeletedKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	for _, key := range []proto.Key{testKey1, testKey2, testKey3} {
+		if err := MVCCPut(engine, nil, key, makeTS(1, 0), value1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := MVCCDeleteRange(engine, nil, testKey1, testKey3, 0, makeTS(2, 0), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the tombstones; reads which observe the deletions must
+	// be answered from the metadata alone.
+	for _, key := range []proto.Key{testKey1, testKey2} {
+		if err := engine.Put(MVCCEncodeVersionKey(key, makeTS(2, 0)), []byte("garbage")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kvs, err := MVCCScan(engine, testKey1, KeyMax, 0, makeTS(3, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || !bytes.Equal(kvs[0].Key, testKey3) {
+		t.Errorf("expected only %q to be live; got %+v", testKey3, kvs)
+	}
+	if value, err := MVCCGet(engine, testKey1, makeTS(3, 0), true, nil); value != nil || err != nil {
+		t.Errorf("expected deleted key; got %+v, %v", value, err)
+	}
+	kvs, err = MVCCScan(engine, testKey1, KeyMax, 0, makeTS(1, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 3 {
+		t.Errorf("expected 3 values before the deletion; got %+v", kvs)
+	}
+}
+
 func TestMVCCDeleteMissingKey(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := NewInMem(proto.Attributes{}, 1<<20)
@@ -615,6 +770,138 @@ func TestMVCCScan(t *testing.T) {
 	}
 }
 
+// TestMVCCReverseScan verifies that a reverse scan returns the values
+// of keys visible at the scan timestamp in descending order, skipping
+// deleted keys, both from an engine and from a batch.
+func TestMVCCReverseScan(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	defer engine.Close()
+	for _, kv := range []struct {
+		key   proto.Key
+		ts    proto.Timestamp
+		value proto.Value
+	}{
+		{testKey1, makeTS(1, 0), value1},
+		{testKey1, makeTS(2, 0), value4},
+		{testKey2, makeTS(1, 0), value2},
+		{testKey2, makeTS(3, 0), value3},
+		{testKey3, makeTS(1, 0), value3},
+		{testKey4, makeTS(1, 0), value4},
+	} {
+		if err := MVCCPut(engine, nil, kv.key, kv.ts, kv.value, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := MVCCDelete(engine, nil, testKey3, makeTS(2, 0), nil); err != nil {
+		t.Fatal(err)
+	}
+	batch := engine.NewBatch()
+	defer batch.Close()
+
+	testCases := []struct {
+		key, endKey proto.Key
+		max         int64
+		ts          proto.Timestamp
+		expected    []proto.KeyValue
+	}{
+		{KeyMin, KeyMax, 0, makeTS(1, 0), []proto.KeyValue{
+			{Key: testKey4, Value: value4},
+			{Key: testKey3, Value: value3},
+			{Key: testKey2, Value: value2},
+			{Key: testKey1, Value: value1},
+		}},
+		{KeyMin, KeyMax, 0, makeTS(3, 0), []proto.KeyValue{
+			{Key: testKey4, Value: value4},
+			{Key: testKey2, Value: value3},
+			{Key: testKey1, Value: value4},
+		}},
+		{testKey2, testKey4, 0, makeTS(1, 0), []proto.KeyValue{
+			{Key: testKey3, Value: value3},
+			{Key: testKey2, Value: value2},
+		}},
+		{KeyMin, testKey4, 2, makeTS(3, 0), []proto.KeyValue{
+			{Key: testKey2, Value: value3},
+			{Key: testKey1, Value: value4},
+		}},
+		{testKey4.Next(), KeyMax, 0, makeTS(3, 0), []proto.KeyValue{}},
+	}
+	for _, e := range []Engine{engine, batch} {
+		for i, test := range testCases {
+			kvs, err := MVCCReverseScan(e, test.key, test.endKey, test.max, test.ts, true, nil)
+			if err != nil {
+				t.Fatalf("%d: %s", i, err)
+			}
+			if len(kvs) != len(test.expected) {
+				t.Errorf("%d: expected %d key/values; got %d", i, len(test.expected), len(kvs))
+				continue
+			}
+			for j, kv := range kvs {
+				if !kv.Key.Equal(test.expected[j].Key) || !bytes.Equal(kv.Value.Bytes, test.expected[j].Value.Bytes) {
+					t.Errorf("%d: expected %q=%q at %d; got %q=%q", i, test.expected[j].Key,
+						test.expected[j].Value.Bytes, j, kv.Key, kv.Value.Bytes)
+				}
+			}
+		}
+	}
+}
+
+// TestMVCCReverseIterate verifies that MVCCReverseIterate visits the
+// keys of the span in descending order, at the requested timestamp.
+func TestMVCCReverseIterate(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	if err := MVCCPut(engine, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey2, makeTS(1, 0), value2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey2, makeTS(3, 0), value3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey3, makeTS(1, 0), value3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey4, makeTS(1, 0), value4, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		start, end proto.Key
+		ts         proto.Timestamp
+		max        int
+		expKeys    []proto.Key
+		expValues  []proto.Value
+	}{
+		{testKey1, testKey4, makeTS(2, 0), 0, []proto.Key{testKey3, testKey2, testKey1}, []proto.Value{value3, value2, value1}},
+		{testKey1, testKey4, makeTS(3, 0), 0, []proto.Key{testKey3, testKey2, testKey1}, []proto.Value{value3, value3, value1}},
+		{testKey2, KeyMax, makeTS(3, 0), 0, []proto.Key{testKey4, testKey3, testKey2}, []proto.Value{value4, value3, value3}},
+		{testKey1, KeyMax, makeTS(3, 0), 2, []proto.Key{testKey4, testKey3}, []proto.Value{value4, value3}},
+	}
+	for i, test := range testCases {
+		var keys []proto.Key
+		var values []proto.Value
+		if err := MVCCReverseIterate(engine, test.start, test.end, int64(test.max), test.ts, true, nil,
+			func(kv proto.KeyValue) (bool, error) {
+				keys = append(keys, kv.Key)
+				values = append(values, kv.Value)
+				return len(keys) == test.max, nil
+			}); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if len(keys) != len(test.expKeys) {
+			t.Fatalf("%d: expected keys %q; got %q", i, test.expKeys, keys)
+		}
+		for j := range keys {
+			if !keys[j].Equal(test.expKeys[j]) || !bytes.Equal(values[j].Bytes, test.expValues[j].Bytes) {
+				t.Errorf("%d: expected %q=%q at %d; got %q=%q", i, test.expKeys[j], test.expValues[j].Bytes,
+					j, keys[j], values[j].Bytes)
+			}
+		}
+	}
+}
+
 func TestMVCCScanMaxNum(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -634,6 +921,65 @@ func TestMVCCScanMaxNum(t *testing.T) {
 	}
 }
 
+// TestMVCCScanWithLimits verifies that scans stop at the key and byte
+// limits on a key boundary without exceeding them, and that resuming
+// from the returned key scans the remaining results.
+func TestMVCCScanWithLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	keys := []proto.Key{testKey1, testKey2, testKey3, testKey4}
+	values := []proto.Value{value1, value2, value3, value4}
+	for i, key := range keys {
+		if err := MVCCPut(engine, nil, key, makeTS(1, 0), values[i], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := MVCCScan(engine, testKey1, KeyMax, 0, makeTS(1, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvSize := int64(all[0].Size())
+
+	testCases := []struct {
+		max, maxBytes int64
+		expCount      int
+		expResume     proto.Key
+	}{
+		{0, 0, 4, nil},
+		{4, 0, 4, testKey4.Next()}, // max is reached on the last result
+		{2, 0, 2, testKey2.Next()},
+		{0, 1, 1, testKey1.Next()},            // the first result is always returned
+		{0, 2*kvSize + 1, 2, testKey2.Next()}, // stops short of exceeding the budget
+		{0, 3 * kvSize, 3, testKey3.Next()},   // stops exactly at the budget
+		{1, 3 * kvSize, 1, testKey1.Next()},   // max is reached first
+		{0, 4 * kvSize, 4, nil},               // the budget covers all results
+		{3, 2*kvSize + 1, 2, testKey2.Next()}, // the budget is reached first
+	}
+	for i, test := range testCases {
+		kvs, resumeKey, err := MVCCScanWithLimits(engine, testKey1, KeyMax, test.max, test.maxBytes, makeTS(1, 0), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != test.expCount {
+			t.Errorf("%d: expected %d results; got %d", i, test.expCount, len(kvs))
+		}
+		if !resumeKey.Equal(test.expResume) {
+			t.Errorf("%d: expected resume key %q; got %q", i, test.expResume, resumeKey)
+		}
+		if resumeKey == nil {
+			continue
+		}
+		// Resuming from the returned key scans the remaining results.
+		rest, _, err := MVCCScanWithLimits(engine, resumeKey, KeyMax, 0, 0, makeTS(1, 0), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs)+len(rest) != len(all) {
+			t.Errorf("%d: expected %d results after resuming; got %d", i, len(all)-len(kvs), len(rest))
+		}
+	}
+}
+
 func TestMVCCScanWithKeyPrefix(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -734,10 +1080,16 @@ func TestMVCCScanInconsistent(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Values are written with checksums.
+	expValue := func(key proto.Key, value proto.Value, ts *proto.Timestamp) proto.Value {
+		value.Timestamp = ts
+		value.InitChecksum(key)
+		return value
+	}
 	expKVs := []proto.KeyValue{
-		{Key: testKey1, Value: proto.Value{Bytes: value1.Bytes, Timestamp: &ts1}},
-		{Key: testKey2, Value: proto.Value{Bytes: value2.Bytes, Timestamp: &ts4}},
-		{Key: testKey4, Value: proto.Value{Bytes: value4.Bytes, Timestamp: &ts6}},
+		{Key: testKey1, Value: expValue(testKey1, value1, &ts1)},
+		{Key: testKey2, Value: expValue(testKey2, value2, &ts4)},
+		{Key: testKey4, Value: expValue(testKey4, value4, &ts6)},
 	}
 	if !reflect.DeepEqual(kvs, expKVs) {
 		t.Errorf("expected key values equal %v != %v", kvs, expKVs)
@@ -749,8 +1101,8 @@ func TestMVCCScanInconsistent(t *testing.T) {
 		t.Fatal(err)
 	}
 	expKVs = []proto.KeyValue{
-		{Key: testKey1, Value: proto.Value{Bytes: value1.Bytes, Timestamp: &ts1}},
-		{Key: testKey2, Value: proto.Value{Bytes: value1.Bytes, Timestamp: &ts3}},
+		{Key: testKey1, Value: expValue(testKey1, value1, &ts1)},
+		{Key: testKey2, Value: expValue(testKey2, value1, &ts3)},
 	}
 	if !reflect.DeepEqual(kvs, expKVs) {
 		t.Errorf("expected key values equal %v != %v", kvs, expKVs)
@@ -808,6 +1160,40 @@ func TestMVCCDeleteRange(t *testing.T) {
 	}
 }
 
+// TestMVCCDeleteRangeBatch verifies that a ranged delete written to a
+// batch deletes up to max keys, each of which remains visible in the
+// underlying engine until the batch is committed.
+func TestMVCCDeleteRangeBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	for _, key := range []proto.Key{testKey1, testKey2, testKey3, testKey4} {
+		if err := MVCCPut(engine, nil, key, makeTS(1, 0), value1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batch := engine.NewBatch()
+	num, err := MVCCDeleteRange(batch, nil, KeyMin, KeyMax, 3, makeTS(2, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num != 3 {
+		t.Errorf("expected 3 keys deleted; got %d", num)
+	}
+	if kvs, err := MVCCScan(engine, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil); err != nil || len(kvs) != 4 {
+		t.Errorf("expected all keys visible before commit; got %v, %v", kvs, err)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := MVCCScan(engine, KeyMin, KeyMax, 0, makeTS(2, 0), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || !kvs[0].Key.Equal(testKey4) {
+		t.Errorf("expected only %q to remain; got %v", testKey4, kvs)
+	}
+}
+
 func TestMVCCDeleteRangeFailed(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -844,7 +1230,7 @@ func TestMVCCDeleteRangeConcurrentTxn(t *testing.T) {
 func TestMVCCConditionalPut(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
-	err := MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &value2, nil)
+	_, err := MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &value2, false, nil)
 	if err == nil {
 		t.Fatal("expected error on key not exists")
 	}
@@ -858,7 +1244,7 @@ func TestMVCCConditionalPut(t *testing.T) {
 	}
 
 	// Verify the difference between missing value and empty value.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &valueEmpty, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &valueEmpty, false, nil)
 	if err == nil {
 		t.Fatal("expected error on key not exists")
 	}
@@ -872,13 +1258,13 @@ func TestMVCCConditionalPut(t *testing.T) {
 	}
 
 	// Do a conditional put with expectation that the value is completely missing; will succeed.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, nil, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, nil, false, nil)
 	if err != nil {
 		t.Fatalf("expected success with condition that key doesn't yet exist: %v", err)
 	}
 
 	// Another conditional put expecting value missing will fail, now that value1 is written.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, nil, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, nil, false, nil)
 	if err == nil {
 		t.Fatal("expected error on key already exists")
 	}
@@ -893,7 +1279,7 @@ func TestMVCCConditionalPut(t *testing.T) {
 	}
 
 	// Conditional put expecting wrong value2, will fail.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &value2, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &value2, false, nil)
 	if err == nil {
 		t.Fatal("expected error on key does not match")
 	}
@@ -908,12 +1294,12 @@ func TestMVCCConditionalPut(t *testing.T) {
 	}
 
 	// Move to a empty value. Will succeed.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), valueEmpty, &value1, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), valueEmpty, &value1, false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	// Now move to value2 from expected empty value.
-	err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value2, &valueEmpty, nil)
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value2, &valueEmpty, false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -925,6 +1311,48 @@ func TestMVCCConditionalPut(t *testing.T) {
 	}
 }
 
+// TestMVCCConditionalPutSwap verifies that a conditional put may be
+// allowed on a missing key, that it returns the value it replaced,
+// and that a failed condition returns the actual value to swap
+// against.
+func TestMVCCConditionalPutSwap(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	prev, err := MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 1), value1, &value2, true, nil)
+	if err != nil {
+		t.Fatalf("expected success on missing key: %v", err)
+	}
+	if prev != nil {
+		t.Fatalf("expected no previous value; got %v", prev)
+	}
+
+	// Allowing a missing key still requires an existing value to match.
+	_, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 2), value3, &value2, true, nil)
+	cErr, ok := err.(*proto.ConditionFailedError)
+	if !ok {
+		t.Fatalf("expected ConditionFailedError; got %v", err)
+	}
+	if !bytes.Equal(cErr.ActualValue.Bytes, value1.Bytes) {
+		t.Fatalf("expected actual value %q; got %q", value1.Bytes, cErr.ActualValue.Bytes)
+	}
+
+	// Retry the swap against the actual value.
+	prev, err = MVCCConditionalPut(engine, nil, testKey1, makeTS(0, 2), value3, cErr.ActualValue, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || !bytes.Equal(prev.Bytes, value1.Bytes) {
+		t.Fatalf("expected previous value %q; got %v", value1.Bytes, prev)
+	}
+	value, err := MVCCGet(engine, testKey1, makeTS(0, 2), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value.Bytes, value3.Bytes) {
+		t.Fatalf("expected value %q; got %q", value3.Bytes, value.Bytes)
+	}
+}
+
 func TestMVCCResolveTxn(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -1901,6 +2329,60 @@ func TestMVCCGarbageCollect(t *testing.T) {
 	verifyStats("verification", ms, &expMS, t)
 }
 
+// TestMVCCSplitStats verifies that dividing the aged stats of a key
+// range at a split key yields the stats computed for each half, and
+// that range-local keys addressed by either half don't skew them.
+func TestMVCCSplitStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		key := proto.Key(k)
+		for _, ts := range []proto.Timestamp{makeTS(1E9, 0), makeTS(2E9, 0)} {
+			if err := MVCCPut(engine, nil, key, ts, value1, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i%2 == 0 {
+			if err := MVCCDelete(engine, nil, key, makeTS(3E9, 0), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	txn := *txn1
+	txn.Timestamp = makeTS(4E9, 0)
+	if err := MVCCPut(engine, nil, proto.Key("d"), txn.Timestamp, value2, &txn); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := MVCCComputeStats(engine, KeyMin, KeyMax, int64(4E9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "d"} {
+		if err := MVCCPut(engine, &ms, RangeDescriptorKey(proto.Key(k)), txn.Timestamp, value1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nowNanos := int64(5E9)
+	ms.AgeTo(nowNanos)
+	splitKey := proto.Key("c")
+	left, right, err := MVCCSplitStats(engine, ms, splitKey, KeyMax, nowNanos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expLeft, err := MVCCComputeStats(engine, KeyMin, splitKey, nowNanos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expRight, err := MVCCComputeStats(engine, splitKey, KeyMax, nowNanos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyStats("left", &left, &expLeft, t)
+	verifyStats("right", &right, &expRight, t)
+}
+
 // TestMVCCGarbageCollectNonDeleted verifies that the first value for
 // a key cannot be GC'd if it's not deleted.
 func TestMVCCGarbageCollectNonDeleted(t *testing.T) {
@@ -1926,6 +2408,30 @@ func TestMVCCGarbageCollectNonDeleted(t *testing.T) {
 	}
 }
 
+// TestMVCCGarbageCollectInline verifies that inline values are
+// skipped by garbage collection.
+func TestMVCCGarbageCollectInline(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	key := proto.Key("a")
+	if err := MVCCPut(engine, nil, key, proto.ZeroTimestamp, proto.Value{Bytes: []byte("value")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	keys := []proto.InternalGCRequest_GCKey{
+		{Key: key, Timestamp: makeTS(1E9, 0)},
+	}
+	if err := MVCCGarbageCollect(engine, nil, keys, makeTS(2E9, 0)); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := Scan(engine, MVCCEncodeKey(KeyMin), MVCCEncodeKey(KeyMax), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 {
+		t.Errorf("expected inline value to remain; got %+v", kvs)
+	}
+}
+
 // TestMVCCGarbageCollectIntent verifies that an intent cannot be GC'd.
 func TestMVCCGarbageCollectIntent(t *testing.T) {
 	defer leaktest.AfterTest(t)
@@ -0,0 +1,181 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// cleanShutdownFile is the name of the marker file written to a
+	// RocksDB data directory when the engine is closed cleanly. It's
+	// removed when the engine is opened.
+	cleanShutdownFile = "COCKROACH_CLEAN_SHUTDOWN"
+	// runningFile is the name of the marker file written to a RocksDB
+	// data directory when the engine is opened and removed when it's
+	// closed cleanly, so its presence at startup indicates that the
+	// previous process crashed or was killed. If neither marker is
+	// present, as when the directory was last written by a version
+	// which didn't leave them, the previous shutdown is unknown.
+	runningFile = "COCKROACH_RUNNING"
+)
+
+// A ShutdownState describes how an engine was previously closed.
+type ShutdownState int
+
+const (
+	// ShutdownUnknown indicates the engine left no record of how it
+	// was closed.
+	ShutdownUnknown ShutdownState = iota
+	// ShutdownClean indicates the engine was closed cleanly, or is new.
+	ShutdownClean
+	// ShutdownUnclean indicates the process crashed or was killed
+	// while the engine was open.
+	ShutdownUnclean
+)
+
+// String formats a shutdown state.
+func (s ShutdownState) String() string {
+	switch s {
+	case ShutdownClean:
+		return "clean"
+	case ShutdownUnclean:
+		return "unclean"
+	}
+	return "unknown"
+}
+
+// A Durability is the policy governing when writes to an engine are
+// synced to disk.
+type Durability int
+
+const (
+	// DurabilityBuffered acknowledges writes once they're appended to
+	// the write-ahead log in the OS buffer cache. Writes survive a
+	// process crash but may be lost if the machine fails.
+	DurabilityBuffered Durability = iota
+	// DurabilitySync syncs the write-ahead log to disk before each
+	// write is acknowledged.
+	DurabilitySync
+)
+
+// String formats a durability mode.
+func (d Durability) String() string {
+	if d == DurabilitySync {
+		return "sync"
+	}
+	return "buffered"
+}
+
+// A ReplayReport describes the write-ahead log replayed when an
+// engine was opened.
+type ReplayReport struct {
+	WALFiles   int           // Number of write-ahead log files replayed
+	WALBytes   int64         // Total size of the write-ahead log files
+	Shutdown   ShutdownState // How the engine was previously closed
+	Durability Durability    // Durability mode configured for the engine
+}
+
+// PossibleDataLoss returns true if acknowledged writes may have been
+// lost: the engine is known to have been closed uncleanly, so its
+// write-ahead log was replayed after a crash, and writes weren't
+// synced before being acknowledged. Only a failure of the machine
+// (rather than of the process) can actually lose buffered writes,
+// which the engine can't distinguish.
+func (rr ReplayReport) PossibleDataLoss() bool {
+	return rr.Shutdown == ShutdownUnclean && rr.WALBytes > 0 && rr.Durability != DurabilitySync
+}
+
+// String formats a replay report.
+func (rr ReplayReport) String() string {
+	return fmt.Sprintf("replayed %d WAL file(s) (%d bytes) after %s shutdown with %s durability",
+		rr.WALFiles, rr.WALBytes, rr.Shutdown, rr.Durability)
+}
+
+// inspectWAL examines the RocksDB data directory before it's opened
+// and returns a report of the write-ahead log about to be replayed.
+func inspectWAL(dir string, durability Durability) (ReplayReport, error) {
+	rr := ReplayReport{Durability: durability}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// A new engine; there's nothing to replay.
+			rr.Shutdown = ShutdownClean
+			return rr, nil
+		}
+		return rr, err
+	}
+	if len(infos) == 0 {
+		rr.Shutdown = ShutdownClean
+	}
+	for _, info := range infos {
+		switch {
+		case info.Name() == runningFile:
+			// Takes precedence over a stale clean shutdown marker.
+			rr.Shutdown = ShutdownUnclean
+		case info.Name() == cleanShutdownFile:
+			if rr.Shutdown != ShutdownUnclean {
+				rr.Shutdown = ShutdownClean
+			}
+		case !info.IsDir() && strings.HasSuffix(info.Name(), ".log"):
+			rr.WALFiles++
+			rr.WALBytes += info.Size()
+		}
+	}
+	return rr, nil
+}
+
+// markRunning replaces the clean shutdown marker in the RocksDB data
+// directory with the running marker, so that a crash before the next
+// clean close is detected.
+func markRunning(dir string) error {
+	if err := createMarker(dir, runningFile); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, cleanShutdownFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// markCleanShutdown replaces the running marker in the RocksDB data
+// directory with the clean shutdown marker.
+func markCleanShutdown(dir string) error {
+	if err := createMarker(dir, cleanShutdownFile); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, runningFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// createMarker creates and syncs the named empty marker file in dir.
+func createMarker(dir, name string) error {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -24,9 +24,9 @@ package engine
 // #include "db.h"
 import "C"
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"unsafe"
@@ -44,6 +44,91 @@ type RocksDB struct {
 	attrs     proto.Attributes // Attributes for this engine
 	dir       string           // The data directory
 	cacheSize int64            // Memory to use to cache values.
+
+	durability Durability     // When writes are synced to disk
+	replay     ReplayReport   // Write-ahead log replayed on open
+	maxSize    int64          // If non-zero, limits the reported capacity
+	opts       RocksDBOptions // Tuning of the RocksDB instance
+}
+
+const (
+	defaultWriteBufferSize          = 64 << 20 // 64 MB
+	defaultMaxBackgroundCompactions = 1
+)
+
+// compactionThreadsOnce sizes the process-wide pool of compaction
+// threads.
+var compactionThreadsOnce sync.Once
+
+// SetCompactionThreads sets the number of threads in the pool which
+// runs the compactions of every RocksDB instance in the process. The
+// pool is process-wide, so it's sized only once: by the first call,
+// or else by the first instance opened, from its
+// MaxBackgroundCompactions. Later calls have no effect.
+func SetCompactionThreads(threads int) {
+	compactionThreadsOnce.Do(func() {
+		C.DBSetCompactionThreads(C.int(threads))
+	})
+}
+
+// Compression is the algorithm with which RocksDB compresses the
+// blocks of its tables.
+type Compression int
+
+const (
+	// CompressionSnappy compresses blocks with Snappy. It's the default.
+	CompressionSnappy Compression = iota
+	// CompressionNone leaves blocks uncompressed.
+	CompressionNone
+	// CompressionZlib compresses blocks with zlib.
+	CompressionZlib
+	// CompressionLZ4 compresses blocks with LZ4.
+	CompressionLZ4
+)
+
+var compressionNames = map[Compression]string{
+	CompressionSnappy: "snappy",
+	CompressionNone:   "none",
+	CompressionZlib:   "zlib",
+	CompressionLZ4:    "lz4",
+}
+
+// String returns the name of the compression algorithm.
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// ParseCompression returns the compression algorithm with the given
+// name: one of "snappy", "none", "zlib" or "lz4".
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressionNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, util.Errorf("unknown compression algorithm %q", name)
+}
+
+// RocksDBOptions tunes a RocksDB instance for the memory and disks of
+// its machine. Zero values select the defaults.
+type RocksDBOptions struct {
+	// WriteBufferSize is the size in bytes of a memtable before it's
+	// flushed to disk. Defaults to 64 MB.
+	WriteBufferSize int64
+	// MaxBackgroundCompactions is the maximum number of compactions
+	// run concurrently. Compactions run on a pool of threads shared by
+	// every instance in the process; see SetCompactionThreads.
+	// Defaults to 1.
+	MaxBackgroundCompactions int
+	// Compression is the algorithm with which blocks are compressed.
+	Compression Compression
+	// BloomFilterBitsPerKey is the number of bits per key of the bloom
+	// filter of each table, which saves reads of tables not containing
+	// a key. Zero disables the bloom filters.
+	BloomFilterBitsPerKey int
 }
 
 // NewRocksDB allocates and returns a new RocksDB object.
@@ -66,6 +151,31 @@ func newMemRocksDB(attrs proto.Attributes, cacheSize int64) *RocksDB {
 	}
 }
 
+// SetDurability sets the policy governing when writes are synced to
+// disk. It must be called before the engine is opened.
+func (r *RocksDB) SetDurability(d Durability) {
+	r.durability = d
+}
+
+// SetMaxSize limits the capacity reported by the engine to maxSize
+// bytes, so that a store may be allotted only part of a disk. Zero
+// imposes no limit.
+func (r *RocksDB) SetMaxSize(maxSize int64) {
+	r.maxSize = maxSize
+}
+
+// SetOptions sets the tuning of the engine. It must be called before
+// the engine is opened.
+func (r *RocksDB) SetOptions(opts RocksDBOptions) {
+	r.opts = opts
+}
+
+// ReplayReport returns a report of the write-ahead log replayed when
+// the engine was opened. In-memory engines report a clean shutdown.
+func (r *RocksDB) ReplayReport() ReplayReport {
+	return r.replay
+}
+
 // String formatter.
 func (r *RocksDB) String() string {
 	return fmt.Sprintf("%s=%s", r.attrs.Attrs, r.dir)
@@ -85,16 +195,50 @@ func (r *RocksDB) Open() error {
 	}
 
 	log.Infof("opening rocksdb instance at %q", r.dir)
+	r.replay = ReplayReport{Shutdown: ShutdownClean, Durability: r.durability}
+	if r.dir != "" {
+		var err error
+		if r.replay, err = inspectWAL(r.dir, r.durability); err != nil {
+			return util.Errorf("could not inspect rocksdb write-ahead log: %s", err)
+		}
+	}
+	writeBufferSize := r.opts.WriteBufferSize
+	if writeBufferSize == 0 {
+		writeBufferSize = defaultWriteBufferSize
+	}
+	maxBackgroundCompactions := r.opts.MaxBackgroundCompactions
+	if maxBackgroundCompactions == 0 {
+		maxBackgroundCompactions = defaultMaxBackgroundCompactions
+	}
+	SetCompactionThreads(maxBackgroundCompactions)
 	status := C.DBOpen(&r.rdb, goToCSlice([]byte(r.dir)),
 		C.DBOptions{
-			cache_size:      C.int64_t(r.cacheSize),
-			allow_os_buffer: C.bool(true),
-			logging_enabled: C.bool(log.V(1)),
+			cache_size:                 C.int64_t(r.cacheSize),
+			allow_os_buffer:            C.bool(true),
+			logging_enabled:            C.bool(log.V(1)),
+			sync_wal:                   C.bool(r.durability == DurabilitySync),
+			write_buffer_size:          C.int64_t(writeBufferSize),
+			max_background_compactions: C.int(maxBackgroundCompactions),
+			compression:                C.int(r.opts.Compression),
+			bloom_bits_per_key:         C.int(r.opts.BloomFilterBitsPerKey),
 		})
 	err := statusToError(status)
 	if err != nil {
 		return util.Errorf("could not open rocksdb instance: %s", err)
 	}
+	if r.dir != "" {
+		if err := markRunning(r.dir); err != nil {
+			C.DBClose(r.rdb)
+			r.rdb = nil
+			return util.Errorf("could not mark rocksdb instance as running: %s", err)
+		}
+	}
+	if r.replay.PossibleDataLoss() {
+		log.Warningf("rocksdb instance at %q %s; writes acknowledged shortly before the "+
+			"crash may have been lost if the machine failed", r.dir, r.replay)
+	} else if r.replay.WALFiles > 0 {
+		log.Infof("rocksdb instance at %q %s", r.dir, r.replay)
+	}
 
 	atomic.AddInt32(&r.refcount, 1)
 	return nil
@@ -109,6 +253,11 @@ func (r *RocksDB) Close() {
 	if r.rdb != nil {
 		C.DBClose(r.rdb)
 		r.rdb = nil
+		if r.dir != "" {
+			if err := markCleanShutdown(r.dir); err != nil {
+				log.Warningf("unable to mark clean shutdown of rocksdb instance at %q: %s", r.dir, err)
+			}
+		}
 	}
 }
 
@@ -217,34 +366,6 @@ func (r *RocksDB) Clear(key proto.EncodedKey) error {
 	return statusToError(C.DBDelete(r.rdb, goToCSlice(key)))
 }
 
-// Iterate iterates from start to end keys, invoking f on each
-// key/value pair. See engine.Iterate for details.
-func (r *RocksDB) Iterate(start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error)) error {
-	return r.iterateInternal(start, end, f, nil)
-}
-
-func (r *RocksDB) iterateInternal(start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error),
-	snapshotHandle *C.DBSnapshot) error {
-	if bytes.Compare(start, end) >= 0 {
-		return nil
-	}
-	it := newRocksDBIterator(r.rdb, snapshotHandle)
-	defer it.Close()
-
-	it.Seek(start)
-	for ; it.Valid(); it.Next() {
-		k := it.Key()
-		if !it.Key().Less(end) {
-			break
-		}
-		if done, err := f(proto.RawKeyValue{Key: k, Value: it.Value()}); done || err != nil {
-			return err
-		}
-	}
-	// Check for any errors during iteration.
-	return it.Error()
-}
-
 // WriteBatch applies the puts, merges and deletes atomically via
 // the RocksDB write batch facility. The list must only contain
 // elements of type Batch{Put,Merge,Delete}.
@@ -277,7 +398,9 @@ func (r *RocksDB) WriteBatch(cmds []interface{}) error {
 }
 
 // Capacity queries the underlying file system for disk capacity
-// information.
+// information. If the engine has a maximum size, the capacity is
+// limited to it and the available space to the difference between it
+// and the approximate size of the engine's data.
 func (r *RocksDB) Capacity() (StoreCapacity, error) {
 	var fs syscall.Statfs_t
 	var capacity StoreCapacity
@@ -290,6 +413,19 @@ func (r *RocksDB) Capacity() (StoreCapacity, error) {
 	}
 	capacity.Capacity = int64(fs.Bsize) * int64(fs.Blocks)
 	capacity.Available = int64(fs.Bsize) * int64(fs.Bavail)
+	if r.maxSize > 0 && r.maxSize < capacity.Capacity {
+		used, err := r.ApproximateSize(proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax))
+		if err != nil {
+			return capacity, err
+		}
+		capacity.Capacity = r.maxSize
+		if avail := r.maxSize - int64(used); avail < capacity.Available {
+			capacity.Available = avail
+		}
+		if capacity.Available < 0 {
+			capacity.Available = 0
+		}
+	}
 	return capacity, nil
 }
 
@@ -338,6 +474,19 @@ func (r *RocksDB) Flush() error {
 	return statusToError(C.DBFlush(r.rdb))
 }
 
+// Checkpoint creates a consistent copy of the engine's on-disk state
+// in dir, which must not exist, hard-linking the engine's tables where
+// possible. The checkpoint may be opened as a RocksDB engine.
+func (r *RocksDB) Checkpoint(dir string) error {
+	if r.dir == "" {
+		return util.Errorf("cannot checkpoint an in-memory engine")
+	}
+	if err := statusToError(C.DBCheckpoint(r.rdb, goToCSlice([]byte(dir)))); err != nil {
+		return util.Errorf("could not checkpoint rocksdb instance at %q to %q: %s", r.dir, dir, err)
+	}
+	return nil
+}
+
 // goToCSlice converts a go byte slice to a DBSlice. Note that this is
 // potentially dangerous as the DBSlice holds a reference to the go
 // byte slice memory that the Go GC does not know about. This method
@@ -415,7 +564,24 @@ func goMerge(existing, update []byte) ([]byte, error) {
 
 // NewIterator returns an iterator over this rocksdb engine.
 func (r *RocksDB) NewIterator() Iterator {
-	return newRocksDBIterator(r.rdb, nil)
+	return newRocksDBIterator(r.rdb, nil, nil)
+}
+
+// NewScanIterator returns an iterator over this rocksdb engine tuned
+// according to the supplied scan hint.
+func (r *RocksDB) NewScanIterator(hint ScanHint) Iterator {
+	return newRocksDBIterator(r.rdb, nil, &hint)
+}
+
+// CacheStats returns the block cache statistics accumulated since
+// the engine was opened.
+func (r *RocksDB) CacheStats() CacheStats {
+	var stats C.DBCacheStats
+	C.DBGetCacheStats(r.rdb, &stats)
+	return CacheStats{
+		Hits:   int64(stats.hits),
+		Misses: int64(stats.misses),
+	}
 }
 
 // NewSnapshot creates a snapshot handle from engine and returns a
@@ -476,13 +642,6 @@ func (r *rocksDBSnapshot) GetProto(key proto.EncodedKey, msg gogoproto.Message)
 	return r.parent.getProtoInternal(key, msg, r.handle)
 }
 
-// Iterate iterates over the keys between start inclusive and end
-// exclusive, invoking f() on each key/value pair using the snapshot
-// handle.
-func (r *rocksDBSnapshot) Iterate(start, end proto.EncodedKey, f func(proto.RawKeyValue) (bool, error)) error {
-	return r.parent.iterateInternal(start, end, f, r.handle)
-}
-
 // Clear is illegal for snapshot and returns an error.
 func (r *rocksDBSnapshot) Clear(key proto.EncodedKey) error {
 	return util.Errorf("cannot Clear from a snapshot")
@@ -503,6 +662,11 @@ func (r *rocksDBSnapshot) Capacity() (StoreCapacity, error) {
 	return r.parent.Capacity()
 }
 
+// CacheStats returns the block cache statistics of the parent engine.
+func (r *rocksDBSnapshot) CacheStats() CacheStats {
+	return r.parent.CacheStats()
+}
+
 // SetGCTimeouts is a noop for a snapshot.
 func (r *rocksDBSnapshot) SetGCTimeouts(minTxnTS, minRCacheTS int64) {
 }
@@ -513,15 +677,31 @@ func (r *rocksDBSnapshot) ApproximateSize(start, end proto.EncodedKey) (uint64,
 	return r.parent.ApproximateSize(start, end)
 }
 
+// CompactRange is a noop for a snapshot.
+func (r *rocksDBSnapshot) CompactRange(start, end proto.EncodedKey) {
+}
+
 // Flush is a no-op for snapshots.
 func (r *rocksDBSnapshot) Flush() error {
 	return nil
 }
 
+// Checkpoint is illegal for snapshot and returns an error.
+func (r *rocksDBSnapshot) Checkpoint(dir string) error {
+	return util.Errorf("cannot Checkpoint a snapshot")
+}
+
 // NewIterator returns a new instance of an Iterator over the
 // engine using the snapshot handle.
 func (r *rocksDBSnapshot) NewIterator() Iterator {
-	return newRocksDBIterator(r.parent.rdb, r.handle)
+	return newRocksDBIterator(r.parent.rdb, r.handle, nil)
+}
+
+// NewScanIterator returns a new instance of an Iterator over the
+// engine using the snapshot handle, tuned according to the supplied
+// scan hint.
+func (r *rocksDBSnapshot) NewScanIterator(hint ScanHint) Iterator {
+	return newRocksDBIterator(r.parent.rdb, r.handle, &hint)
 }
 
 // NewSnapshot is illegal for snapshot and returns nil.
@@ -545,15 +725,19 @@ type rocksDBIterator struct {
 
 // newRocksDBIterator returns a new iterator over the supplied RocksDB
 // instance. If snapshotHandle is not nil, uses the indicated snapshot.
+// If hint is not nil, the iterator is tuned for the described scan.
 // The caller must call rocksDBIterator.Close() when finished with the
 // iterator to free up resources.
-func newRocksDBIterator(rdb *C.DBEngine, snapshotHandle *C.DBSnapshot) *rocksDBIterator {
-	// In order to prevent content displacement, caching is disabled
-	// when performing scans. Any options set within the shared read
-	// options field that should be carried over needs to be set here
-	// as well.
+func newRocksDBIterator(rdb *C.DBEngine, snapshotHandle *C.DBSnapshot, hint *ScanHint) *rocksDBIterator {
+	opts := C.DBIterOptions{fill_cache: C.bool(true)}
+	if hint != nil {
+		// In order to prevent content displacement, caching is disabled
+		// when performing large scans.
+		opts.fill_cache = C.bool(hint.FillCache())
+		opts.upper_bound = goToCSlice(hint.EndKey)
+	}
 	return &rocksDBIterator{
-		iter: C.DBNewIter(rdb, snapshotHandle),
+		iter: C.DBNewIter(rdb, snapshotHandle, opts),
 	}
 }
 
@@ -572,6 +756,10 @@ func (r *rocksDBIterator) Seek(key []byte) {
 	}
 }
 
+func (r *rocksDBIterator) SeekToLast() {
+	C.DBIterSeekToLast(r.iter)
+}
+
 func (r *rocksDBIterator) Valid() bool {
 	return C.DBIterValid(r.iter) == 1
 }
@@ -580,6 +768,10 @@ func (r *rocksDBIterator) Next() {
 	C.DBIterNext(r.iter)
 }
 
+func (r *rocksDBIterator) Prev() {
+	C.DBIterPrev(r.iter)
+}
+
 func (r *rocksDBIterator) Key() proto.EncodedKey {
 	// The data returned by rocksdb_iter_{key,value} is not meant to be
 	// freed by the client. It is a direct reference to the data managed
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -134,6 +135,210 @@ func TestRocksDBCompaction(t *testing.T) {
 	}
 }
 
+// TestRocksDBScanHint verifies that large scans bypass the block
+// cache while small scans fill it, and that scan iterators stop at
+// the hinted end key.
+func TestRocksDBScanHint(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb := newMemRocksDB(proto.Attributes{}, testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+
+	for i := 0; i < 100; i++ {
+		key := proto.EncodedKey(fmt.Sprintf("key-%03d", i))
+		if err := rocksdb.Put(key, []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Flush so that reads are served from sstables through the block cache.
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(hint ScanHint) int {
+		iter := rocksdb.NewScanIterator(hint)
+		defer iter.Close()
+		count := 0
+		for iter.Seek(nil); iter.Valid(); iter.Next() {
+			count++
+		}
+		if err := iter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// Two large scans in succession must both miss the cache.
+	before := rocksdb.CacheStats()
+	scan(ScanHint{})
+	scan(ScanHint{MaxKeys: LargeScanThreshold + 1})
+	if after := rocksdb.CacheStats(); after.Hits != before.Hits || after.Misses == before.Misses {
+		t.Errorf("expected large scans to bypass the cache; before %+v, after %+v", before, after)
+	}
+
+	// The second of two small scans must be served from the cache.
+	scan(ScanHint{MaxKeys: 10})
+	before = rocksdb.CacheStats()
+	scan(ScanHint{MaxKeys: 10})
+	if after := rocksdb.CacheStats(); after.Hits == before.Hits {
+		t.Errorf("expected small scan to hit the cache; before %+v, after %+v", before, after)
+	}
+
+	if count := scan(ScanHint{EndKey: proto.EncodedKey("key-050")}); count != 50 {
+		t.Errorf("expected iteration to stop at end key after 50 keys; got %d", count)
+	}
+}
+
+// TestRocksDBOptions verifies that an engine opened with each
+// compression algorithm and tuned options reads back its writes after
+// a flush, and that compression algorithms are parsed by name.
+func TestRocksDBOptions(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	for c := range compressionNames {
+		loc := util.CreateTempDirectory()
+		rocksdb := NewRocksDB(proto.Attributes{}, loc, testCacheSize)
+		rocksdb.SetOptions(RocksDBOptions{
+			WriteBufferSize:          1 << 20,
+			MaxBackgroundCompactions: 2,
+			Compression:              c,
+			BloomFilterBitsPerKey:    10,
+		})
+		if err := rocksdb.Open(); err != nil {
+			t.Fatalf("could not open rocksdb instance with %s compression: %v", c, err)
+		}
+		key := MVCCEncodeKey(proto.Key("a"))
+		if err := rocksdb.Put(key, []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+		if err := rocksdb.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if val, err := rocksdb.Get(key); err != nil || string(val) != "value" {
+			t.Errorf("%s: expected to read \"value\"; got %q, %v", c, val, err)
+		}
+		rocksdb.Close()
+		if err := os.RemoveAll(loc); err != nil {
+			t.Errorf("could not remove %s: %v", loc, err)
+		}
+
+		if parsed, err := ParseCompression(c.String()); err != nil || parsed != c {
+			t.Errorf("expected to parse %s; got %s, %v", c, parsed, err)
+		}
+	}
+	if _, err := ParseCompression("bzip2"); err == nil {
+		t.Error("expected unknown compression algorithm to fail to parse")
+	}
+}
+
+// TestRocksDBCheckpoint verifies that a checkpoint holds the writes
+// made to an engine before the checkpoint, and not those made after,
+// and that in-memory engines can't be checkpointed.
+func TestRocksDBCheckpoint(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	loc := util.CreateTempDirectory()
+	defer func() {
+		if err := os.RemoveAll(loc); err != nil {
+			t.Errorf("could not remove %s: %v", loc, err)
+		}
+	}()
+	rocksdb := NewRocksDB(proto.Attributes{}, filepath.Join(loc, "db"), testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatalf("could not open rocksdb instance: %v", err)
+	}
+	keyA, keyB := MVCCEncodeKey(proto.Key("a")), MVCCEncodeKey(proto.Key("b"))
+	if err := rocksdb.Put(keyA, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	checkpointDir := filepath.Join(loc, "checkpoint")
+	if err := rocksdb.Checkpoint(checkpointDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Put(keyB, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Checkpoint(checkpointDir); err == nil {
+		t.Error("expected checkpoint to existing directory to fail")
+	}
+	rocksdb.Close()
+
+	checkpoint := NewRocksDB(proto.Attributes{}, checkpointDir, testCacheSize)
+	if err := checkpoint.Open(); err != nil {
+		t.Fatalf("could not open checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+	if val, err := checkpoint.Get(keyA); err != nil || string(val) != "value" {
+		t.Errorf("expected to read \"value\" from checkpoint; got %q, %v", val, err)
+	}
+	if val, err := checkpoint.Get(keyB); err != nil || val != nil {
+		t.Errorf("expected write after checkpoint to be absent; got %q, %v", val, err)
+	}
+
+	inMem := NewInMem(proto.Attributes{}, testCacheSize)
+	defer inMem.Close()
+	if err := inMem.Checkpoint(filepath.Join(loc, "in-mem")); err == nil {
+		t.Error("expected checkpoint of in-memory engine to fail")
+	}
+}
+
+// TestRocksDBReplayReport verifies that reopening a RocksDB engine
+// reports the write-ahead log replayed and whether acknowledged writes
+// may have been lost after an unclean shutdown, and that a previous
+// shutdown which left no markers is reported as unknown.
+func TestRocksDBReplayReport(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	loc := util.CreateTempDirectory()
+	defer func() {
+		if err := os.RemoveAll(loc); err != nil {
+			t.Errorf("could not remove %s: %v", loc, err)
+		}
+	}()
+
+	// reopen opens the engine at loc with the given durability, writes
+	// a key and closes it, returning the replay report.
+	reopen := func(d Durability) ReplayReport {
+		rocksdb := NewRocksDB(proto.Attributes{}, loc, testCacheSize)
+		rocksdb.SetDurability(d)
+		if err := rocksdb.Open(); err != nil {
+			t.Fatalf("could not create new rocksdb db instance at %s: %v", loc, err)
+		}
+		defer rocksdb.Close()
+		if err := rocksdb.Put(MVCCEncodeKey(proto.Key("a")), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+		return rocksdb.ReplayReport()
+	}
+	// crash leaves the markers as they were while the engine was open.
+	crash := func() {
+		if err := os.Rename(filepath.Join(loc, cleanShutdownFile), filepath.Join(loc, runningFile)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rr := reopen(DurabilityBuffered); rr.Shutdown != ShutdownClean || rr.WALFiles != 0 || rr.PossibleDataLoss() {
+		t.Errorf("expected new engine to report a clean shutdown with no WAL; got %s", rr)
+	}
+	if rr := reopen(DurabilityBuffered); rr.Shutdown != ShutdownClean || rr.PossibleDataLoss() {
+		t.Errorf("expected clean shutdown without data loss; got %s", rr)
+	}
+	crash()
+	if rr := reopen(DurabilityBuffered); rr.Shutdown != ShutdownUnclean || rr.WALBytes == 0 || !rr.PossibleDataLoss() {
+		t.Errorf("expected unclean shutdown with possible data loss; got %s", rr)
+	}
+	crash()
+	if rr := reopen(DurabilitySync); rr.Shutdown != ShutdownUnclean || rr.PossibleDataLoss() {
+		t.Errorf("expected unclean shutdown without data loss under sync durability; got %s", rr)
+	}
+	// An engine last closed by a version which left no markers.
+	if err := os.Remove(filepath.Join(loc, cleanShutdownFile)); err != nil {
+		t.Fatal(err)
+	}
+	if rr := reopen(DurabilityBuffered); rr.Shutdown != ShutdownUnknown || rr.PossibleDataLoss() {
+		t.Errorf("expected unknown shutdown without data loss; got %s", rr)
+	}
+}
+
 // setupMVCCData writes up to numVersions values at each of numKeys
 // keys. The number of versions written for each key is chosen
 // randomly according to a uniform distribution. Each successive
@@ -301,6 +506,98 @@ func BenchmarkMVCCScan100Versions1000Rows(b *testing.B) {
 	runMVCCScan(1000, 100, b)
 }
 
+// setupMVCCDeletedScanData creates a rocksdb database with numKeys
+// keys, all but one in every liveInterval of which have been deleted,
+// as happens after a large DeleteRange. As with setupMVCCScanData,
+// the database is persisted between runs in the current directory as
+// "mvcc_scan_deleted_<keys>_<liveInterval>".
+func setupMVCCDeletedScanData(numKeys, liveInterval int, b *testing.B) *RocksDB {
+	loc := fmt.Sprintf("mvcc_scan_deleted_%d_%d", numKeys, liveInterval)
+
+	exists := true
+	if _, err := os.Stat(loc); os.IsNotExist(err) {
+		exists = false
+	}
+
+	log.Infof("creating mvcc data: %s", loc)
+	const cacheSize = 8 << 30 // 8 GB
+	rocksdb := NewRocksDB(proto.Attributes{Attrs: []string{"ssd"}}, loc, cacheSize)
+	if err := rocksdb.Open(); err != nil {
+		b.Fatalf("could not create new rocksdb db instance at %s: %v", loc, err)
+	}
+
+	if exists {
+		return rocksdb
+	}
+
+	rng, _ := util.NewPseudoRand()
+	batch := rocksdb.NewBatch()
+	for i := 0; i < numKeys; i++ {
+		key := proto.Key(encoding.EncodeUvarint([]byte("key-"), uint64(i)))
+		value := proto.Value{Bytes: util.RandBytes(rng, 1024)}
+		value.InitChecksum(key)
+		if err := MVCCPut(batch, nil, key, makeTS(5, 0), value, nil); err != nil {
+			b.Fatal(err)
+		}
+		if i%liveInterval != 0 {
+			if err := MVCCDelete(batch, nil, key, makeTS(10, 0), nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	rocksdb.CompactRange(nil, nil)
+
+	return rocksdb
+}
+
+// runMVCCScanDeleted performs b.N MVCCScans for numRows live keys
+// over data in which the live keys are separated by runs of deleted
+// keys, measuring the cost of skipping the deletions.
+func runMVCCScanDeleted(numRows, liveInterval int, b *testing.B) {
+	const numKeys = 100000
+
+	rocksdb := setupMVCCDeletedScanData(numKeys, liveInterval, b)
+	defer rocksdb.Close()
+
+	prewarmCache(rocksdb)
+
+	b.SetBytes(int64(numRows * 1024))
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		keyBuf := append(make([]byte, 0, 64), []byte("key-")...)
+		for pb.Next() {
+			// Choose a random key to start scan.
+			keyIdx := rand.Int31n(int32(numKeys - numRows*liveInterval))
+			startKey := proto.Key(encoding.EncodeUvarint(keyBuf[0:4], uint64(keyIdx)))
+			kvs, err := MVCCScan(rocksdb, startKey, KeyMax, int64(numRows), makeTS(15, 0), true, nil)
+			if err != nil {
+				b.Fatalf("failed scan: %s", err)
+			}
+			if len(kvs) != numRows {
+				b.Fatalf("failed to scan: %d != %d", len(kvs), numRows)
+			}
+		}
+	})
+
+	b.StopTimer()
+}
+
+func BenchmarkMVCCScanDeleted10Rows10Interval(b *testing.B) {
+	runMVCCScanDeleted(10, 10, b)
+}
+
+func BenchmarkMVCCScanDeleted10Rows1000Interval(b *testing.B) {
+	runMVCCScanDeleted(10, 1000, b)
+}
+
+func BenchmarkMVCCScanDeleted100Rows100Interval(b *testing.B) {
+	runMVCCScanDeleted(100, 100, b)
+}
+
 // runMVCCGet first creates test data (and resets the benchmarking
 // timer). It then performs b.N MVCCGets.
 func runMVCCGet(numVersions int, b *testing.B) {
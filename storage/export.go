@@ -0,0 +1,201 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// An ExportSink stores the files written by InternalExport, such as
+// the files of a backup. Names are chosen by the caller of
+// InternalExport and are unique within the sink.
+type ExportSink interface {
+	// Create returns a writer for a new file with the given name. The
+	// file is complete once the writer has been closed without error;
+	// a file whose export failed must be aborted instead.
+	Create(name string) (ExportFile, error)
+	// Open returns a reader for the file with the given name.
+	Open(name string) (io.ReadCloser, error)
+}
+
+// An ExportFile is a file being written to an ExportSink.
+type ExportFile interface {
+	io.Writer
+	// Close completes the file. If it fails, the file is discarded.
+	Close() error
+	// Abort discards the file.
+	Abort()
+}
+
+// A LocalExportSink is an ExportSink which stores files in a
+// directory of the local file system, typically a mount of shared
+// storage.
+type LocalExportSink struct {
+	dir string
+}
+
+// NewLocalExportSink returns an ExportSink which stores files in dir.
+func NewLocalExportSink(dir string) *LocalExportSink {
+	return &LocalExportSink{dir: dir}
+}
+
+// path returns the path of the named file, which may not refer to a
+// file outside of the sink's directory.
+func (s *LocalExportSink) path(name string) (string, error) {
+	clean := filepath.Clean(name)
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", util.Errorf("invalid export file name %q", name)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// Create implements the ExportSink interface. The file is written
+// under a temporary name and renamed once closed, so that a file
+// with the given name is always complete.
+func (s *LocalExportSink) Create(name string) (ExportFile, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, util.Errorf("export file %q already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &localExportFile{File: f, path: path}, nil
+}
+
+// Open implements the ExportSink interface.
+func (s *LocalExportSink) Open(name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// A localExportFile is a file being written by a LocalExportSink.
+type localExportFile struct {
+	*os.File
+	path string
+}
+
+// Close syncs and closes the file and moves it to its final path.
+// On failure, the temporary file is removed.
+func (f *localExportFile) Close() error {
+	err := f.File.Sync()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.File.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.File.Name())
+	}
+	return err
+}
+
+// Abort closes and removes the temporary file.
+func (f *localExportFile) Abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+// A countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// An exportWriter writes key/value pairs in the format of export
+// files: each pair is an encoded proto.KeyValue prefixed by its
+// length as a uvarint.
+type exportWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func newExportWriter(w io.Writer) *exportWriter {
+	return &exportWriter{w: bufio.NewWriter(w)}
+}
+
+// add appends kv to the export file.
+func (ew *exportWriter) add(kv *proto.KeyValue) error {
+	b, err := gogoproto.Marshal(kv)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(ew.buf[:], uint64(len(b)))
+	if _, err := ew.w.Write(ew.buf[:n]); err != nil {
+		return err
+	}
+	_, err = ew.w.Write(b)
+	return err
+}
+
+// flush writes any buffered data to the underlying writer.
+func (ew *exportWriter) flush() error {
+	return ew.w.Flush()
+}
+
+// ReadExport invokes f with each of the key/value pairs, in key
+// order, of the export file read from r. If f returns an error, the
+// iteration stops and the error is returned.
+func ReadExport(r io.Reader, f func(proto.KeyValue) error) error {
+	br := bufio.NewReader(r)
+	var b []byte
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if uint64(cap(b)) < n {
+			b = make([]byte, n)
+		}
+		b = b[:n]
+		if _, err := io.ReadFull(br, b); err != nil {
+			return util.Errorf("truncated export file: %s", err)
+		}
+		var kv proto.KeyValue
+		if err := gogoproto.Unmarshal(b, &kv); err != nil {
+			return err
+		}
+		if err := f(kv); err != nil {
+			return err
+		}
+	}
+}
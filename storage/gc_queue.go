@@ -18,6 +18,7 @@
 package storage
 
 import (
+	"bytes"
 	"math"
 	"sync"
 	"time"
@@ -33,6 +34,9 @@ import (
 const (
 	// gcQueueMaxSize is the max size of the gc queue.
 	gcQueueMaxSize = 100
+	// gcQueueMaxConcurrency is the max number of ranges processed at once
+	// by the gc queue.
+	gcQueueMaxConcurrency = 1
 	// gcQueueTimerDuration is the duration between GCs of queued ranges.
 	gcQueueTimerDuration = 1 * time.Second
 	// gcByteCountNormalization is the count of GC'able bytes which
@@ -44,8 +48,23 @@ const (
 	// intentAgeThreshold is the threshold after which an extant intent
 	// will be resolved.
 	intentAgeThreshold = 2 * time.Hour // 2 hour
+	// abortedTxnAgeThreshold is the threshold after which the record of
+	// an aborted transaction is garbage collected. It exceeds
+	// intentAgeThreshold so that extant intents of the transaction are
+	// resolved before its record is removed.
+	abortedTxnAgeThreshold = 2 * intentAgeThreshold
+	// responseCacheGCInterval is the time since a range's last GC scan
+	// after which it is queued to garbage collect expired response
+	// cache entries, regardless of other GC'able data.
+	responseCacheGCInterval = GCResponseCacheExpiration
 )
 
+// gcKeysPerRequest is the maximum number of keys garbage collected by
+// a single InternalGC command, limiting the size of its Raft command
+// and the time for which it blocks other commands on the range. Tests
+// may lower it.
+var gcKeysPerRequest = 1000
+
 // gcQueue manages a queue of ranges slated to be scanned in their
 // entirety using the MVCC versions iterator. The gc queue manages the
 // following tasks:
@@ -54,6 +73,11 @@ const (
 //    as implemented going forward).
 //  - Resolve extant write intents and determine oldest non-resolvable
 //    intent.
+//  - GC of aborted transaction records which have outlived any of
+//    their intents.
+//  - GC of response cache entries older than the maximum client
+//    retry window (GCResponseCacheExpiration), both persisted and
+//    inflight.
 //
 // The shouldQueue function combines the need for both tasks into a
 // single priority. If any task is overdue, shouldQueue returns true.
@@ -64,14 +88,15 @@ type gcQueue struct {
 // newGCQueue returns a new instance of gcQueue.
 func newGCQueue() *gcQueue {
 	gcq := &gcQueue{}
-	gcq.baseQueue = newBaseQueue("gc", gcq, gcQueueMaxSize)
+	gcq.baseQueue = newBaseQueue("gc", gcq, gcQueueMaxSize, gcQueueMaxConcurrency)
 	return gcq
 }
 
 // shouldQueue determines whether a range should be queued for garbage
 // collection, and if so, at what priority. Returns true for shouldQ
 // in the event that the cumulative ages of GC'able bytes or extant
-// intents exceed thresholds.
+// intents exceed thresholds, or if the range hasn't been scanned for
+// expired response cache entries in responseCacheGCInterval.
 func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
 	// Only queue for GC if this replica is leader.
 	if !rng.IsLeader() {
@@ -84,8 +109,13 @@ func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool,
 		return
 	}
 
-	// GC score is the total GC'able bytes age normalized by 1 MB * the range's TTL in seconds.
-	gcScore := float64(rng.stats.GetGCBytesAge(now.WallTime)) / float64(policy.TTLSeconds) / float64(gcByteCountNormalization)
+	// GC score is the total GC'able bytes age normalized by 1 MB * the
+	// range's TTL in seconds. A non-positive TTL means values are never
+	// GC'd.
+	var gcScore float64
+	if policy.TTLSeconds > 0 {
+		gcScore = float64(rng.stats.GetGCBytesAge(now.WallTime)) / float64(policy.TTLSeconds) / float64(gcByteCountNormalization)
+	}
 
 	// Intent score. This computes the average age of outstanding intents
 	// and normalizes.
@@ -98,14 +128,31 @@ func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool,
 	if intentScore > 1 {
 		priority += intentScore
 	}
+
+	// Response cache score. This is the time elapsed since the last
+	// scan normalized by the response cache GC interval.
+	gcMeta, err := rng.GetGCMetadata()
+	if err != nil {
+		log.Errorf("GC metadata: %s", err)
+		return
+	}
+	rcacheScore := float64(now.WallTime-gcMeta.LastScanNanos) / float64(responseCacheGCInterval.Nanoseconds())
+	if rcacheScore > 1 {
+		priority += rcacheScore
+	}
 	shouldQ = priority > 0
 	return
 }
 
 // process iterates through all keys in a range, calling the garbage
 // collector for each key and associated set of values. GC'd keys are
-// batched into InternalGC calls. Extant intents are resolved if
-// intents are older than intentAgeThreshold.
+// batched into InternalGC calls of at most gcKeysPerRequest keys.
+// Extant intents are resolved if
+// intents are older than intentAgeThreshold. Response cache entries
+// older than GCResponseCacheExpiration are GC'd, and any commands
+// left inflight in the response cache since then are cleared. The
+// records of Raft proposals made before the current lease holder's
+// tenure are GC'd as well.
 func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	if !rng.IsLeader() {
 		log.Infof("not leader of range %s; skipping GC", rng)
@@ -129,13 +176,33 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	// Compute intent expiration (intent age at which we attempt to resolve).
 	intentExp := now
 	intentExp.WallTime -= intentAgeThreshold.Nanoseconds()
+	// Compute expiration of aborted transaction records.
+	txnExp := now
+	txnExp.WallTime -= abortedTxnAgeThreshold.Nanoseconds()
+	// Compute expiration of response cache entries.
+	rcacheExp := now.WallTime - GCResponseCacheExpiration.Nanoseconds()
 
-	gcArgs := &proto.InternalGCRequest{
-		RequestHeader: proto.RequestHeader{
-			Key:       rng.Desc().StartKey,
-			Timestamp: now,
-			RaftID:    rng.Desc().RaftID,
-		},
+	newGCArgs := func() *proto.InternalGCRequest {
+		return &proto.InternalGCRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       rng.Desc().StartKey,
+				Timestamp: now,
+				RaftID:    rng.Desc().RaftID,
+			},
+		}
+	}
+	gcArgs := newGCArgs()
+	// sendGC sends the keys accumulated in gcArgs for GC through the
+	// range and begins a new request. Only the final request carries
+	// the GC metadata, which includes the oldest intent and is known
+	// only once the scan is complete.
+	sendGC := func(final bool) error {
+		if final {
+			gcArgs.GCMeta = *gcMeta
+		}
+		err := rng.AddCmd(proto.InternalGC, gcArgs, &proto.InternalGCResponse{}, true)
+		gcArgs = newGCArgs()
+		return err
 	}
 	var mu sync.Mutex
 	var oldestIntentNanos int64 = math.MaxInt64
@@ -158,6 +225,36 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	// resolution and values after the MVCC metadata, and possible
 	// intent, are sent for garbage collection.
 	processKeysAndValues := func() {
+		// A single inline value may be the record of a transaction which
+		// has long since been aborted.
+		if len(keys) == 1 && isTransactionKey(expBaseKey) {
+			if isAbortedTxnExpired(vals[0], txnExp) {
+				gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{Key: expBaseKey, Timestamp: txnExp})
+			}
+			return
+		}
+		// Or it may be a response cache entry outside the client retry
+		// window. The command ID's wall time is the time at which the
+		// client first sent the command.
+		if len(keys) == 1 {
+			if cmdID, err := rng.respCache.decodeResponseCacheKey(keys[0]); err == nil {
+				if cmdID.WallTime < rcacheExp {
+					gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{
+						Key:       expBaseKey,
+						Timestamp: proto.Timestamp{WallTime: rcacheExp},
+					})
+				}
+				return
+			}
+			// Or it may be the record of a Raft proposal made under an
+			// earlier lease, which is no longer needed; see isReproposal.
+			if proposalID, err := decodeAppliedProposalKey(keys[0]); err == nil {
+				if rng.isStaleProposal(proposalID) {
+					gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{Key: expBaseKey})
+				}
+				return
+			}
+		}
 		// If there's more than a single value for the key, possibly send for GC.
 		if len(keys) > 1 {
 			meta := &proto.MVCCMetadata{}
@@ -181,9 +278,6 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 				}
 				// See if any values may be GC'd.
 				if gcTS := gc.Filter(keys[startIdx:], vals[startIdx:]); !gcTS.Equal(proto.ZeroTimestamp) {
-					// TODO(spencer): need to split the requests up into
-					// multiple requests in the event that more than X keys
-					// are added to the request.
 					gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{Key: expBaseKey, Timestamp: gcTS})
 				}
 			}
@@ -196,6 +290,11 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 		if !isValue {
 			// Moving to the next key (& values).
 			processKeysAndValues()
+			if len(gcArgs.Keys) >= gcKeysPerRequest {
+				if err := sendGC(false); err != nil {
+					return err
+				}
+			}
 			expBaseKey = baseKey
 			keys = []proto.EncodedKey{iter.Key()}
 			vals = [][]byte{iter.Value()}
@@ -218,9 +317,15 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	wg.Wait()
 	gcMeta.OldestIntentNanos = gogoproto.Int64(oldestIntentNanos)
 
-	// Send GC request through range.
-	gcArgs.GCMeta = *gcMeta
-	if err := rng.AddCmd(proto.InternalGC, gcArgs, &proto.InternalGCResponse{}, true); err != nil {
+	// Clear commands left inflight in the response cache by clients
+	// which have since abandoned them, other than those still awaiting
+	// application.
+	if n := rng.respCache.ClearExpiredInflight(rcacheExp, rng.pendingClientCmds()); n > 0 {
+		log.Infof("cleared %d expired inflight commands from range %s response cache", n, rng)
+	}
+
+	// Send the last GC request through range.
+	if err := sendGC(true); err != nil {
 		return err
 	}
 
@@ -327,3 +432,56 @@ func (gcq *gcQueue) lookupGCPolicy(rng *Range) (proto.GCPolicy, error) {
 	}
 	return *gc, nil
 }
+
+// isTransactionKey returns true if the key is a range-local
+// transaction record key.
+func isTransactionKey(key proto.Key) bool {
+	if !bytes.HasPrefix(key, engine.KeyLocalRangeKeyPrefix) {
+		return false
+	}
+	_, suffix, _ := engine.DecodeRangeKey(key)
+	return suffix.Equal(engine.KeyLocalTransactionSuffix)
+}
+
+// isAbortedTxnExpired unmarshals the supplied MVCC metadata holding an
+// inline transaction record and returns true if the transaction has
+// been aborted and was last active before the expiration timestamp.
+func isAbortedTxnExpired(metaBytes []byte, expiration proto.Timestamp) bool {
+	meta := &proto.MVCCMetadata{}
+	if err := gogoproto.Unmarshal(metaBytes, meta); err != nil || !meta.IsInline() {
+		return false
+	}
+	txn := &proto.Transaction{}
+	if err := gogoproto.Unmarshal(meta.Value.Bytes, txn); err != nil {
+		log.Errorf("unable to unmarshal transaction record: %s", err)
+		return false
+	}
+	return abortedTxnExpired(txn, expiration)
+}
+
+// abortedTxnExpired returns true if the transaction has been aborted
+// and was last active before the expiration timestamp.
+func abortedTxnExpired(txn *proto.Transaction, expiration proto.Timestamp) bool {
+	if txn.Status != proto.ABORTED {
+		return false
+	}
+	lastActive := txn.Timestamp
+	if txn.LastHeartbeat != nil {
+		lastActive.Forward(*txn.LastHeartbeat)
+	}
+	return lastActive.Less(expiration)
+}
+
+// gcTxnRecord removes the inline record of a transaction which was
+// aborted and last active before the GC key's timestamp. MVCC garbage
+// collection skips inline values, so the record is re-read and its
+// expiration verified before it's deleted; records of transactions
+// which are not aborted or have since been heartbeat are kept.
+func gcTxnRecord(batch engine.Engine, ms *engine.MVCCStats, gcKey proto.InternalGCRequest_GCKey) error {
+	txn := &proto.Transaction{}
+	ok, err := engine.MVCCGetProto(batch, gcKey.Key, proto.ZeroTimestamp, true, nil, txn)
+	if err != nil || !ok || !abortedTxnExpired(txn, gcKey.Timestamp) {
+		return err
+	}
+	return engine.MVCCDelete(batch, ms, gcKey.Key, proto.ZeroTimestamp, nil)
+}
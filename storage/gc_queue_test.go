@@ -19,6 +19,7 @@ package storage
 
 import (
 	"math"
+	"reflect"
 	"testing"
 	"time"
 
@@ -47,11 +48,9 @@ func TestGCQueueShouldQueue(t *testing.T) {
 	tc.Start(t)
 	defer tc.Stop()
 
-	// Put an empty GC metadata; all that's read from it is last scan nanos.
+	// GC metadata is written for each test case; all that's read from
+	// it is last scan nanos.
 	key := engine.RangeGCMetadataKey(tc.rng.Desc().RaftID)
-	if err := engine.MVCCPutProto(tc.rng.rm.Engine(), nil, key, proto.ZeroTimestamp, nil, &proto.GCMetadata{}); err != nil {
-		t.Fatal(err)
-	}
 
 	iaN := intentAgeNormalization.Nanoseconds()
 	ia := iaN / 1E9
@@ -103,6 +102,12 @@ func TestGCQueueShouldQueue(t *testing.T) {
 			GCBytesAge:  test.gcBytesAge,
 		}
 		tc.rng.stats.SetMVCCStats(tc.rng.rm.Engine(), stats)
+		// Mark the range as just scanned so that the response cache
+		// doesn't contribute to the priority.
+		gcMeta := &proto.GCMetadata{LastScanNanos: test.now.WallTime}
+		if err := engine.MVCCPutProto(tc.rng.rm.Engine(), nil, key, proto.ZeroTimestamp, nil, gcMeta); err != nil {
+			t.Fatal(err)
+		}
 
 		shouldQ, priority := gcQ.shouldQueue(test.now, tc.rng)
 		if shouldQ != test.shouldQ {
@@ -279,6 +284,266 @@ func TestGCQueueProcess(t *testing.T) {
 	}
 }
 
+// TestGCQueueTransactionRecords verifies that the GC queue removes
+// the records of aborted transactions once they are older than
+// abortedTxnAgeThreshold and leaves all other records in place.
+func TestGCQueueTransactionRecords(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	const now int64 = 48 * 60 * 60 * 1E9 // 2d past the epoch
+	tc.manualClock.Set(now)
+	oldTS := makeTS(now-abortedTxnAgeThreshold.Nanoseconds()-1, 0)
+	newTS := makeTS(now-abortedTxnAgeThreshold.Nanoseconds()+1, 0)
+
+	testCases := []struct {
+		key       proto.Key
+		status    proto.TransactionStatus
+		ts        proto.Timestamp
+		heartbeat *proto.Timestamp
+		expGC     bool
+	}{
+		{proto.Key("a"), proto.ABORTED, oldTS, nil, true},
+		{proto.Key("b"), proto.ABORTED, newTS, nil, false},
+		{proto.Key("c"), proto.ABORTED, oldTS, &newTS, false},
+		{proto.Key("d"), proto.PENDING, oldTS, nil, false},
+		{proto.Key("e"), proto.COMMITTED, oldTS, nil, false},
+	}
+	var txnKeys []proto.Key
+	for _, test := range testCases {
+		txn := newTransaction("test", test.key, 1, proto.SERIALIZABLE, tc.clock)
+		txn.Status = test.status
+		txn.Timestamp = test.ts
+		txn.LastHeartbeat = test.heartbeat
+		key := engine.TransactionKey(txn.Key, txn.ID)
+		if err := engine.MVCCPutProto(tc.engine, nil, key, proto.ZeroTimestamp, nil, txn); err != nil {
+			t.Fatal(err)
+		}
+		txnKeys = append(txnKeys, key)
+	}
+
+	gcQ := newGCQueue()
+	if err := gcQ.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range testCases {
+		ok, err := engine.MVCCGetProto(tc.engine, txnKeys[i], proto.ZeroTimestamp, true, nil, &proto.Transaction{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok == test.expGC {
+			t.Errorf("%d: expected GC=%t; record found=%t", i, test.expGC, ok)
+		}
+	}
+}
+
+// TestInternalGCTransactionRecords verifies that InternalGC removes
+// only transaction records which it finds aborted and expired, no
+// matter which keys the request lists.
+func TestInternalGCTransactionRecords(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	oldTS := makeTS(1, 0)
+	gcTS := makeTS(2, 0)
+	statuses := []proto.TransactionStatus{proto.ABORTED, proto.PENDING, proto.COMMITTED}
+	gcArgs := &proto.InternalGCRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       engine.KeyMin,
+			EndKey:    engine.KeyMax,
+			Timestamp: gcTS,
+			RaftID:    1,
+			Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+		},
+	}
+	var txnKeys []proto.Key
+	for _, status := range statuses {
+		txn := newTransaction("test", proto.Key("a"), 1, proto.SERIALIZABLE, tc.clock)
+		txn.Status = status
+		txn.Timestamp = oldTS
+		key := engine.TransactionKey(txn.Key, txn.ID)
+		if err := engine.MVCCPutProto(tc.engine, nil, key, proto.ZeroTimestamp, nil, txn); err != nil {
+			t.Fatal(err)
+		}
+		txnKeys = append(txnKeys, key)
+		gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{Key: key, Timestamp: gcTS})
+	}
+	if err := tc.rng.AddCmd(proto.InternalGC, gcArgs, &proto.InternalGCResponse{}, true); err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range statuses {
+		ok, err := engine.MVCCGetProto(tc.engine, txnKeys[i], proto.ZeroTimestamp, true, nil, &proto.Transaction{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expGC := status == proto.ABORTED; ok == expGC {
+			t.Errorf("%d: expected GC=%t of %s transaction; record found=%t", i, expGC, status, ok)
+		}
+	}
+}
+
+// TestInternalGCMetadata verifies that InternalGC stores the GC
+// metadata it carries, and leaves it intact when it carries none, as
+// for all but the last request of a GC scan.
+func TestInternalGCMetadata(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	gcArgs := func(gcMeta proto.GCMetadata) *proto.InternalGCRequest {
+		return &proto.InternalGCRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       engine.KeyMin,
+				Timestamp: tc.clock.Now(),
+				RaftID:    1,
+				Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+			},
+			GCMeta: gcMeta,
+		}
+	}
+	expGCMeta := proto.NewGCMetadata(5)
+	for i, gcMeta := range []proto.GCMetadata{*expGCMeta, {}} {
+		if err := tc.rng.AddCmd(proto.InternalGC, gcArgs(gcMeta), &proto.InternalGCResponse{}, true); err != nil {
+			t.Fatal(err)
+		}
+		gcMeta, err := tc.rng.GetGCMetadata()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gcMeta, expGCMeta) {
+			t.Errorf("%d: expected GC metadata %+v; got %+v", i, expGCMeta, gcMeta)
+		}
+	}
+}
+
+// TestGCQueueBatches verifies that the GC queue garbage collects the
+// keys of a range in multiple requests once there are more than
+// gcKeysPerRequest of them.
+func TestGCQueueBatches(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	defer func(n int) { gcKeysPerRequest = n }(gcKeysPerRequest)
+	gcKeysPerRequest = 2
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	const now int64 = 48 * 60 * 60 * 1E9 // 2d past the epoch
+	tc.manualClock.Set(now)
+	oldTS := makeTS(now-abortedTxnAgeThreshold.Nanoseconds()-1, 0)
+
+	var txnKeys []proto.Key
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		txn := newTransaction("test", proto.Key(k), 1, proto.SERIALIZABLE, tc.clock)
+		txn.Status = proto.ABORTED
+		txn.Timestamp = oldTS
+		key := engine.TransactionKey(txn.Key, txn.ID)
+		if err := engine.MVCCPutProto(tc.engine, nil, key, proto.ZeroTimestamp, nil, txn); err != nil {
+			t.Fatal(err)
+		}
+		txnKeys = append(txnKeys, key)
+	}
+
+	gcQ := newGCQueue()
+	if err := gcQ.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range txnKeys {
+		ok, err := engine.MVCCGetProto(tc.engine, key, proto.ZeroTimestamp, true, nil, &proto.Transaction{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Errorf("%d: expected record of aborted transaction to be GC'd", i)
+		}
+	}
+}
+
+// TestGCQueueResponseCache verifies that the GC queue removes
+// response cache entries and inflight commands older than
+// GCResponseCacheExpiration and leaves newer ones in place, along with
+// old inflight commands which are still awaiting application.
+func TestGCQueueResponseCache(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	const now int64 = 48 * 60 * 60 * 1E9 // 2d past the epoch
+	tc.manualClock.Set(now)
+	oldCmdID := proto.ClientCmdID{WallTime: now - GCResponseCacheExpiration.Nanoseconds() - 1, Random: 1}
+	newCmdID := proto.ClientCmdID{WallTime: now - GCResponseCacheExpiration.Nanoseconds() + 1, Random: 2}
+	for _, cmdID := range []proto.ClientCmdID{oldCmdID, newCmdID} {
+		if err := tc.rng.respCache.PutResponse(cmdID, &proto.PutResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leave a command inflight for each command ID, as if abandoned by
+	// its client.
+	inflightOld := proto.ClientCmdID{WallTime: oldCmdID.WallTime, Random: 3}
+	inflightNew := proto.ClientCmdID{WallTime: newCmdID.WallTime, Random: 4}
+	inflightPending := proto.ClientCmdID{WallTime: oldCmdID.WallTime, Random: 5}
+	for _, cmdID := range []proto.ClientCmdID{inflightOld, inflightNew, inflightPending} {
+		if ok, err := tc.rng.respCache.GetResponse(cmdID, &proto.PutResponse{}); ok || err != nil {
+			t.Fatalf("unexpected response cache hit for %s: %t, %v", &cmdID, ok, err)
+		}
+	}
+	// The last is still awaiting application, as if its proposal were
+	// stuck in Raft.
+	pendingKey := tc.rng.newProposalID()
+	tc.rng.Lock()
+	tc.rng.pendingCmds[pendingKey] = &pendingCmd{cmdID: inflightPending, done: make(chan error, 1)}
+	tc.rng.Unlock()
+	defer func() {
+		tc.rng.Lock()
+		delete(tc.rng.pendingCmds, pendingKey)
+		tc.rng.Unlock()
+	}()
+
+	gcQ := newGCQueue()
+	if err := gcQ.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		cmdID proto.ClientCmdID
+		expGC bool
+	}{
+		{oldCmdID, true},
+		{newCmdID, false},
+	}
+	for i, test := range testCases {
+		key := engine.ResponseCacheKey(tc.rng.Desc().RaftID, &test.cmdID)
+		ok, err := engine.MVCCGetProto(tc.engine, key, proto.ZeroTimestamp, true, nil, &proto.ReadWriteCmdResponse{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok == test.expGC {
+			t.Errorf("%d: expected GC=%t; entry found=%t", i, test.expGC, ok)
+		}
+	}
+
+	tc.rng.respCache.Lock()
+	_, oldInflight := tc.rng.respCache.inflight[makeCmdIDKey(inflightOld)]
+	_, newInflight := tc.rng.respCache.inflight[makeCmdIDKey(inflightNew)]
+	_, pendingInflight := tc.rng.respCache.inflight[makeCmdIDKey(inflightPending)]
+	tc.rng.respCache.Unlock()
+	if oldInflight {
+		t.Errorf("expected expired inflight command to be cleared")
+	}
+	if !newInflight {
+		t.Errorf("expected recent inflight command to remain")
+	}
+	if !pendingInflight {
+		t.Errorf("expected expired command awaiting application to remain")
+	}
+}
+
 // TestGCQueueLookupGCPolicy verifies the hierarchical lookup of GC
 // policy in the event that the longest matching key prefix does not
 // have a zone configured.
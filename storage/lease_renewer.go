@@ -0,0 +1,157 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// defaultLeaseRenewalInterval is the interval at which queued leader
+// lease extensions are proposed.
+const defaultLeaseRenewalInterval = defaultLeaderLeaseDuration / 10
+
+// A leaseRenewer schedules the re-proposal of leader leases held by a
+// store's ranges. The leases of most ranges are epoch-based: they're
+// extended wholesale by the heartbeat of the node's liveness record,
+// one write per node rather than one Raft command per range, and are
+// only re-proposed every closedTimestampRefreshInterval to advance
+// their closed timestamps. The expiration-based leases of ranges in the
+// system keyspace, which hold the liveness records, and of ranges on
+// nodes which aren't live, are re-proposed as they fall due.
+//
+// Rather than each range proposing its lease independently, ranges
+// queue themselves and the renewer proposes all queued leases
+// in a single pass at each interval. Leases proposed in the same pass
+// share the same start time, so they fall due together, and a range
+// is proposed at most once per pass however often it's queued. Ranges
+// which see no traffic are never queued (re-proposals are requested on
+// access), so quiescent ranges cause no lease traffic; their
+// expiration-based leases simply lapse.
+type leaseRenewer struct {
+	interval time.Duration
+	now      func() int64 // Physical clock
+
+	renewals int64 // Extensions proposed; updated atomically
+	passes   int64 // Passes which proposed extensions; updated atomically
+	failures int64 // Extensions which failed to commit; updated atomically
+
+	mu      sync.Mutex
+	pending map[int64]*Range // Ranges awaiting extension, by Raft ID
+}
+
+// newLeaseRenewer returns a lease renewer which proposes queued
+// extensions at the given interval.
+func newLeaseRenewer(interval time.Duration, now func() int64) *leaseRenewer {
+	return &leaseRenewer{
+		interval: interval,
+		now:      now,
+		pending:  map[int64]*Range{},
+	}
+}
+
+// add queues an extension of the supplied range's leader lease.
+func (lr *leaseRenewer) add(r *Range) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.pending[r.Desc().RaftID] = r
+}
+
+// start proposes queued extensions at each interval until the
+// stopper is stopped.
+func (lr *leaseRenewer) start(stopper *util.Stopper) {
+	stopper.RunWorker(func() {
+		ticker := util.NewTicker(lr.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				lr.renew(stopper)
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// renew proposes the lease of each queued range which still holds its
+// leader lease, one Raft command per range, and waits for the
+// proposals to commit in the background.
+func (lr *leaseRenewer) renew(stopper *util.Stopper) {
+	lr.mu.Lock()
+	pending := lr.pending
+	lr.pending = map[int64]*Range{}
+	lr.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	wallTime := lr.now()
+	var errChs []<-chan error
+	var rngs []*Range
+	for _, r := range pending {
+		l := r.getLease()
+		// An unhealthy store lets its leases expire so that replicas on
+		// healthy stores take them over.
+		if !r.HasLeaderLease() || r.rm.Unhealthy() != "" {
+			atomic.StoreInt32(&r.extending, 0)
+			continue
+		}
+		errChs = append(errChs, r.rm.ProposeRaftCommand(r.newLeaderLeaseCmd(l.Term, wallTime)))
+		rngs = append(rngs, r)
+	}
+	if len(errChs) == 0 {
+		return
+	}
+	atomic.AddInt64(&lr.renewals, int64(len(errChs)))
+	atomic.AddInt64(&lr.passes, 1)
+
+	stopper.RunWorker(func() {
+		for i, errCh := range errChs {
+			select {
+			case err := <-errCh:
+				if err != nil {
+					atomic.AddInt64(&lr.failures, 1)
+					log.Warningf("failed to extend leader lease for %s: %s", rngs[i], err)
+				}
+				atomic.StoreInt32(&rngs[i].extending, 0)
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// LeaseRenewalStats holds cumulative counts of the leader lease
+// extensions proposed by a store.
+type LeaseRenewalStats struct {
+	Renewals int64 // Extensions proposed
+	Passes   int64 // Passes in which extensions were proposed
+	Failures int64 // Extensions which failed to commit
+}
+
+// stats returns the renewer's cumulative counts.
+func (lr *leaseRenewer) stats() LeaseRenewalStats {
+	return LeaseRenewalStats{
+		Renewals: atomic.LoadInt64(&lr.renewals),
+		Passes:   atomic.LoadInt64(&lr.passes),
+		Failures: atomic.LoadInt64(&lr.failures),
+	}
+}
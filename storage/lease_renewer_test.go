@@ -0,0 +1,67 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestLeaseRenewerExtendsLeases verifies that accessing a range whose
+// leader lease is nearing expiration queues an extension which the
+// store's lease renewer proposes, and that the renewal is counted in
+// the store's stats.
+func TestLeaseRenewerExtendsLeases(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	// Acquire the lease on the first range.
+	gArgs, gReply := getArgs([]byte("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiration := rng.getLease().Expiration
+	if s := store.Stats().LeaseRenewals; s.Renewals != 0 {
+		t.Fatalf("expected no lease renewals; got %+v", s)
+	}
+
+	// Move the clock past the lease's half-life; the next access queues
+	// an extension.
+	manual.Set(expiration - int64(defaultLeaderLeaseDuration)/4)
+	gArgs, gReply = getArgs([]byte("a"), 1, store.StoreID())
+	gArgs.Timestamp = store.Clock().Now()
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.IsTrueWithin(func() bool {
+		return rng.getLease().Expiration > expiration
+	}, 1*time.Second); err != nil {
+		t.Fatalf("leader lease was not extended: %s", err)
+	}
+	s := store.Stats().LeaseRenewals
+	if s.Renewals != 1 || s.Passes != 1 || s.Failures != 0 {
+		t.Errorf("expected a single renewal in a single pass; got %+v", s)
+	}
+}
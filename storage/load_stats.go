@@ -0,0 +1,115 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+const (
+	// loadStatsWindow is the period over which a range's request rate
+	// is measured for load-based splitting.
+	loadStatsWindow = 10 * time.Second
+	// loadStatsSampleSize is the number of request keys sampled in
+	// each window to choose a key at which to split a hot range.
+	loadStatsSampleSize = 20
+)
+
+// rangeLoadStats measures the request rate of a range over
+// successive windows and keeps a uniform sample of the keys requested
+// in each, from which a split key balancing the load is chosen.
+type rangeLoadStats struct {
+	sync.Mutex
+	windowStart int64       // Wall time in nanoseconds the current window began
+	count       int64       // Requests in the current window
+	samples     []proto.Key // Sample of keys requested in the current window
+	qps         float64     // Request rate over the last complete window
+	lastSamples []proto.Key // Sample of keys requested in the last complete window
+}
+
+// record counts a request for key at the specified wall time. If the
+// request completes a window, returns the request rate over that
+// window and true.
+func (ls *rangeLoadStats) record(key proto.Key, nowNanos int64) (float64, bool) {
+	ls.Lock()
+	defer ls.Unlock()
+	var qps float64
+	completed := false
+	if ls.windowStart == 0 {
+		ls.windowStart = nowNanos
+	} else if elapsed := time.Duration(nowNanos - ls.windowStart); elapsed >= loadStatsWindow {
+		qps = float64(ls.count) / elapsed.Seconds()
+		completed = true
+		ls.qps, ls.lastSamples = qps, ls.samples
+		ls.windowStart, ls.count, ls.samples = nowNanos, 0, nil
+	}
+
+	// Reservoir sampling keeps each key requested during the window
+	// in the sample with equal probability.
+	ls.count++
+	if len(ls.samples) < loadStatsSampleSize {
+		ls.samples = append(ls.samples, key)
+	} else if i := rand.Int63n(ls.count); i < loadStatsSampleSize {
+		ls.samples[i] = key
+	}
+	return qps, completed
+}
+
+// QPS returns the request rate over the last complete window, or zero
+// if the range has gone without requests for longer than a window
+// since.
+func (ls *rangeLoadStats) QPS(nowNanos int64) float64 {
+	ls.Lock()
+	defer ls.Unlock()
+	if time.Duration(nowNanos-ls.windowStart) >= 2*loadStatsWindow {
+		return 0
+	}
+	return ls.qps
+}
+
+// splitKey returns the median of the keys sampled over the last
+// complete window which satisfy valid, or nil if there are none.
+// Splitting at the median divides the sampled requests evenly
+// between the two resulting ranges.
+func (ls *rangeLoadStats) splitKey(valid func(proto.Key) bool) proto.Key {
+	ls.Lock()
+	defer ls.Unlock()
+	var keys proto.KeySlice
+	for _, key := range ls.lastSamples {
+		if valid(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Sort(keys)
+	return keys[len(keys)/2]
+}
+
+// reset discards all measurements, as when the range's key span has
+// changed.
+func (ls *rangeLoadStats) reset() {
+	ls.Lock()
+	defer ls.Unlock()
+	ls.windowStart, ls.count, ls.samples = 0, 0, nil
+	ls.qps, ls.lastSamples = 0, nil
+}
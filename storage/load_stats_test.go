@@ -0,0 +1,76 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestRangeLoadStats verifies the measurement of request rates over
+// windows and the choice of split key from the sampled keys.
+func TestRangeLoadStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var ls rangeLoadStats
+	start := int64(time.Hour)
+	step := int64(loadStatsWindow) / loadStatsSampleSize
+
+	// Request each of loadStatsSampleSize keys once over the window.
+	for i := 0; i < loadStatsSampleSize; i++ {
+		key := proto.Key(fmt.Sprintf("k%02d", i))
+		if _, ok := ls.record(key, start+int64(i)*step); ok {
+			t.Fatalf("%d: unexpected window completion", i)
+		}
+	}
+	if qps := ls.QPS(start); qps != 0 {
+		t.Errorf("expected no rate before the first window completes; got %f", qps)
+	}
+	end := start + int64(loadStatsWindow)
+	qps, ok := ls.record(proto.Key("k00"), end)
+	expQPS := float64(loadStatsSampleSize) / loadStatsWindow.Seconds()
+	if !ok || math.Abs(qps-expQPS) > 0.00001 {
+		t.Errorf("expected window to complete with %f qps; got %f, %t", expQPS, qps, ok)
+	}
+	if qps := ls.QPS(end); math.Abs(qps-expQPS) > 0.00001 {
+		t.Errorf("expected %f qps; got %f", expQPS, qps)
+	}
+	if qps := ls.QPS(end + 2*int64(loadStatsWindow)); qps != 0 {
+		t.Errorf("expected rate of idle range to lapse; got %f", qps)
+	}
+
+	// All keys were sampled; the split key is their median.
+	all := func(proto.Key) bool { return true }
+	if key := ls.splitKey(all); !key.Equal(proto.Key("k10")) {
+		t.Errorf("expected split key k10; got %q", key)
+	}
+	below := func(key proto.Key) bool { return key.Less(proto.Key("k05")) }
+	if key := ls.splitKey(below); !key.Equal(proto.Key("k02")) {
+		t.Errorf("expected split key k02; got %q", key)
+	}
+	if key := ls.splitKey(func(proto.Key) bool { return false }); key != nil {
+		t.Errorf("expected no split key; got %q", key)
+	}
+
+	ls.reset()
+	if qps := ls.QPS(end); qps != 0 || ls.splitKey(all) != nil {
+		t.Errorf("expected reset to discard measurements; got %f qps", qps)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// DefaultNodeLivenessThreshold is the default duration for which a
+// heartbeat of a node's liveness record keeps the node live. Nodes
+// heartbeat at twice this frequency.
+const DefaultNodeLivenessThreshold = 10 * time.Second
+
+// DefaultTimeUntilNodeDead is the default duration for which a node's
+// liveness record must have been expired before its replicas are
+// replaced. It is much longer than the liveness threshold so that a
+// brief pause or a restart doesn't trigger up-replication.
+const DefaultTimeUntilNodeDead = 5 * time.Minute
+
+// A LivenessRecord is a node's liveness record. A node is live until
+// the expiration of its most recent heartbeat. The epoch is
+// incremented by other nodes once the record has expired, which
+// invalidates the epoch-based leader leases the node held under the
+// previous epoch; the node adopts the new epoch when it next
+// heartbeats. Records written before epochs were introduced have
+// epoch zero.
+type LivenessRecord struct {
+	NodeID     proto.NodeID `json:"node_id"`
+	Epoch      int64        `json:"epoch"`
+	Expiration int64        `json:"expiration"` // Wall time in nanoseconds
+}
+
+// IsLive returns whether the record shows the node to be live at the
+// specified wall time.
+func (lr LivenessRecord) IsLive(nowNanos int64) bool {
+	return nowNanos < lr.Expiration
+}
+
+// NodeLiveness heartbeats the local node's liveness record and tracks
+// the liveness of all nodes. Records are written to the system
+// keyspace under engine.KeyNodeLivenessPrefix, which is authoritative,
+// and gossiped so that other nodes learn of them without reading the
+// KV store.
+//
+// The methods which query liveness may be called on a nil
+// NodeLiveness, in which case no node is considered dead.
+type NodeLiveness struct {
+	db        *client.KV
+	gossip    *gossip.Gossip
+	clock     *hlc.Clock
+	threshold time.Duration
+
+	mu      sync.Mutex
+	records map[proto.NodeID]LivenessRecord // Most recent record learned per node
+}
+
+// NewNodeLiveness returns a new NodeLiveness whose heartbeats keep a
+// node live for the specified threshold.
+func NewNodeLiveness(db *client.KV, g *gossip.Gossip, clock *hlc.Clock, threshold time.Duration) *NodeLiveness {
+	nl := &NodeLiveness{
+		db:        db,
+		gossip:    g,
+		clock:     clock,
+		threshold: threshold,
+		records:   map[proto.NodeID]LivenessRecord{},
+	}
+	if g != nil {
+		g.RegisterCallback(gossip.MakePrefixPattern(gossip.KeyNodeLivenessPrefix), nl.livenessGossipUpdate)
+	}
+	return nl
+}
+
+// Start loads the liveness records persisted in the KV store and then
+// heartbeats the liveness record of the specified node until the
+// stopper is stopped. Loading the persisted records lets a restarted
+// node recognize nodes which died while it was down.
+func (nl *NodeLiveness) Start(nodeID proto.NodeID, stopper *util.Stopper) {
+	stopper.RunWorker(func() {
+		if stopper.StartTask() {
+			if _, err := nl.ScanRecords(); err != nil {
+				log.Warningf("unable to load node liveness records: %s", err)
+			}
+			stopper.FinishTask()
+		}
+
+		ticker := util.NewTicker(nl.threshold / 2)
+		defer ticker.Stop()
+		for {
+			if stopper.StartTask() {
+				if err := nl.Heartbeat(nodeID); err != nil {
+					log.Warningf("unable to heartbeat liveness of node %d: %s", nodeID, err)
+				}
+				stopper.FinishTask()
+			}
+			select {
+			case <-ticker.C:
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// Heartbeat extends the liveness of the specified node by the
+// liveness threshold, writing its record to the KV store and
+// gossiping it. The record is written with a conditional put against
+// the last record known, so that a heartbeat never extends an epoch
+// which another node has incremented in the meantime; the node adopts
+// the incremented epoch instead.
+func (nl *NodeLiveness) Heartbeat(nodeID proto.NodeID) error {
+	prev, ok := nl.GetLiveness(nodeID)
+	if !ok {
+		var err error
+		if prev, ok, err = nl.getRecord(nodeID); err != nil {
+			return err
+		}
+	}
+	for retried := false; ; retried = true {
+		lr := LivenessRecord{
+			NodeID:     nodeID,
+			Epoch:      prev.Epoch,
+			Expiration: nl.clock.PhysicalNow() + nl.threshold.Nanoseconds(),
+		}
+		if lr.Epoch == 0 {
+			lr.Epoch = 1
+		}
+		err := nl.cput(prev, ok, lr)
+		if cErr, isCondErr := err.(*proto.ConditionFailedError); isCondErr && !retried {
+			// The record was changed under us, most likely by another node
+			// incrementing its epoch. Retry against the actual record.
+			if ok = cErr.ActualValue != nil; ok {
+				if prev, err = decodeLivenessRecord(nodeID, cErr.ActualValue); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return nl.publish(lr)
+	}
+}
+
+// IncrementEpoch increments the epoch of the specified node's liveness
+// record, invalidating the epoch-based leader leases the node holds
+// under its current epoch, and returns the new epoch. It fails if the
+// node is live. If the record's epoch already exceeds the specified
+// epoch, another node has incremented it; the record's epoch is
+// returned without incrementing it again.
+func (nl *NodeLiveness) IncrementEpoch(nodeID proto.NodeID, epoch int64) (int64, error) {
+	if nl == nil {
+		return 0, util.Errorf("cannot increment epoch of node %d: node liveness is not configured", nodeID)
+	}
+	prev, ok, err := nl.getRecord(nodeID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, util.Errorf("node %d has no liveness record", nodeID)
+	}
+	if prev.IsLive(nl.clock.PhysicalNow()) {
+		return 0, util.Errorf("cannot increment epoch of node %d: node is live", nodeID)
+	}
+	if prev.Epoch > epoch {
+		nl.update(prev)
+		return prev.Epoch, nil
+	}
+	lr := prev
+	lr.Epoch++
+	if err := nl.cput(prev, true, lr); err != nil {
+		return 0, err
+	}
+	if err := nl.publish(lr); err != nil {
+		return 0, err
+	}
+	return lr.Epoch, nil
+}
+
+// cput writes lr to the KV store provided the stored record is still
+// prev, or is missing if exists is false.
+func (nl *NodeLiveness) cput(prev LivenessRecord, exists bool, lr LivenessRecord) error {
+	args := &proto.ConditionalPutRequest{
+		RequestHeader: proto.RequestHeader{Key: engine.NodeLivenessKey(lr.NodeID)},
+		Value:         livenessValue(lr),
+	}
+	if exists {
+		expValue := livenessValue(prev)
+		args.ExpValue = &expValue
+	}
+	return nl.db.Call(proto.ConditionalPut, args, &proto.ConditionalPutResponse{})
+}
+
+// publish records lr, which has just been written to the KV store,
+// and gossips it. Records are gossiped without a TTL so that the last
+// record of a dead node remains available to show that it's dead.
+func (nl *NodeLiveness) publish(lr LivenessRecord) error {
+	nl.set(lr)
+	if nl.gossip != nil {
+		return nl.gossip.AddInfo(gossip.MakeNodeLivenessKey(lr.NodeID), lr, 0*time.Second)
+	}
+	return nil
+}
+
+// getRecord reads the liveness record of the specified node from the
+// KV store, returning false if the node has none.
+func (nl *NodeLiveness) getRecord(nodeID proto.NodeID) (LivenessRecord, bool, error) {
+	reply := &proto.GetResponse{}
+	if err := nl.db.Call(proto.Get, proto.GetArgs(engine.NodeLivenessKey(nodeID)), reply); err != nil {
+		return LivenessRecord{}, false, err
+	}
+	if reply.Value == nil {
+		return LivenessRecord{}, false, nil
+	}
+	lr, err := decodeLivenessRecord(nodeID, reply.Value)
+	return lr, err == nil, err
+}
+
+// livenessValue returns the value under which lr is stored. Records
+// of epoch zero keep the original encoding, an integer expiration;
+// others encode the epoch and expiration as varints.
+func livenessValue(lr LivenessRecord) proto.Value {
+	if lr.Epoch == 0 {
+		expiration := lr.Expiration
+		return proto.Value{Integer: &expiration}
+	}
+	return proto.Value{Bytes: encoding.EncodeVarint(encoding.EncodeVarint(nil, lr.Epoch), lr.Expiration)}
+}
+
+// decodeLivenessRecord decodes the liveness record of the specified
+// node from the value under which it's stored.
+func decodeLivenessRecord(nodeID proto.NodeID, v *proto.Value) (LivenessRecord, error) {
+	lr := LivenessRecord{NodeID: nodeID}
+	if v.Integer != nil {
+		lr.Expiration = *v.Integer
+		return lr, nil
+	}
+	if len(v.Bytes) == 0 {
+		return LivenessRecord{}, util.Errorf("liveness record of node %d has no expiration", nodeID)
+	}
+	b, epoch := encoding.DecodeVarint(v.Bytes)
+	_, lr.Expiration = encoding.DecodeVarint(b)
+	lr.Epoch = epoch
+	return lr, nil
+}
+
+// livenessGossipUpdate is a gossip callback triggered whenever a
+// liveness record is gossiped.
+func (nl *NodeLiveness) livenessGossipUpdate(key string, contentsChanged bool) {
+	info, err := nl.gossip.GetInfo(key)
+	if err != nil {
+		log.Errorf("unable to fetch %s from gossip: %s", key, err)
+		return
+	}
+	lr, ok := info.(LivenessRecord)
+	if !ok {
+		log.Errorf("gossiped info is not a LivenessRecord: %+v", info)
+		return
+	}
+	nl.update(lr)
+}
+
+// update records lr unless a more recent record of the node is
+// known. A record of a higher epoch is more recent, whatever its
+// expiration.
+func (nl *NodeLiveness) update(lr LivenessRecord) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if old, ok := nl.records[lr.NodeID]; !ok || old.Epoch < lr.Epoch ||
+		(old.Epoch == lr.Epoch && old.Expiration < lr.Expiration) {
+		nl.records[lr.NodeID] = lr
+	}
+}
+
+// set records lr, which was read from or written to the KV store, in
+// place of any record of the node known.
+func (nl *NodeLiveness) set(lr LivenessRecord) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	nl.records[lr.NodeID] = lr
+}
+
+// GetLiveness returns the most recent liveness record of the
+// specified node, if any is known.
+func (nl *NodeLiveness) GetLiveness(nodeID proto.NodeID) (LivenessRecord, bool) {
+	if nl == nil {
+		return LivenessRecord{}, false
+	}
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	lr, ok := nl.records[nodeID]
+	return lr, ok
+}
+
+// IsDead returns whether the most recent liveness record of the
+// specified node has expired. A node whose record isn't known isn't
+// considered dead, as its record may not have been gossiped yet.
+func (nl *NodeLiveness) IsDead(nodeID proto.NodeID) bool {
+	lr, ok := nl.GetLiveness(nodeID)
+	return ok && !lr.IsLive(nl.clock.PhysicalNow())
+}
+
+// IsDeadFor returns whether the most recent liveness record of the
+// specified node expired at least d ago.
+func (nl *NodeLiveness) IsDeadFor(nodeID proto.NodeID, d time.Duration) bool {
+	lr, ok := nl.GetLiveness(nodeID)
+	return ok && !lr.IsLive(nl.clock.PhysicalNow()-d.Nanoseconds())
+}
+
+// LiveNodes returns the IDs of the nodes known to be live, in
+// ascending order.
+func (nl *NodeLiveness) LiveNodes() []proto.NodeID {
+	return nl.nodes(true)
+}
+
+// DeadNodes returns the IDs of the nodes known to be dead, in
+// ascending order.
+func (nl *NodeLiveness) DeadNodes() []proto.NodeID {
+	return nl.nodes(false)
+}
+
+func (nl *NodeLiveness) nodes(live bool) []proto.NodeID {
+	if nl == nil {
+		return nil
+	}
+	nowNanos := nl.clock.PhysicalNow()
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	var nodeIDs []proto.NodeID
+	for nodeID, lr := range nl.records {
+		if lr.IsLive(nowNanos) == live {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	sort.Sort(nodeIDSlice(nodeIDs))
+	return nodeIDs
+}
+
+// ScanRecords reads the liveness records of all nodes from the KV
+// store, in ascending order of node ID. The records read are also
+// reflected in subsequent liveness queries.
+func (nl *NodeLiveness) ScanRecords() ([]LivenessRecord, error) {
+	args := proto.ScanArgs(engine.KeyNodeLivenessPrefix, engine.KeyNodeLivenessPrefix.PrefixEnd(), 0)
+	reply := &proto.ScanResponse{}
+	if err := nl.db.Call(proto.Scan, args, reply); err != nil {
+		return nil, err
+	}
+	records := make([]LivenessRecord, 0, len(reply.Rows))
+	for _, kv := range reply.Rows {
+		nodeID, err := engine.DecodeNodeLivenessKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		lr, err := decodeLivenessRecord(nodeID, &kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		nl.update(lr)
+		records = append(records, lr)
+	}
+	return records, nil
+}
+
+// nodeIDSlice implements sort.Interface.
+type nodeIDSlice []proto.NodeID
+
+func (s nodeIDSlice) Len() int           { return len(s) }
+func (s nodeIDSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s nodeIDSlice) Less(i, j int) bool { return s[i] < s[j] }
@@ -0,0 +1,145 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestNodeLivenessHeartbeat verifies that heartbeats are persisted
+// and that nodes which stop heartbeating are reported dead once
+// their records expire.
+func TestNodeLivenessHeartbeat(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+	nl := NewNodeLiveness(store.DB(), store.Gossip(), store.Clock(), time.Second)
+
+	for _, nodeID := range []proto.NodeID{2, 1} {
+		if err := nl.Heartbeat(nodeID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dead := nl.DeadNodes(); len(dead) != 0 {
+		t.Errorf("expected no dead nodes; got %v", dead)
+	}
+	if live := nl.LiveNodes(); !reflect.DeepEqual(live, []proto.NodeID{1, 2}) {
+		t.Errorf("expected nodes 1 and 2 live; got %v", live)
+	}
+
+	// Only node 1 heartbeats after node 2's record has expired.
+	manual.Set(time.Second.Nanoseconds())
+	if err := nl.Heartbeat(1); err != nil {
+		t.Fatal(err)
+	}
+	if !nl.IsDead(2) || nl.IsDead(1) {
+		t.Errorf("expected only node 2 dead; got dead nodes %v", nl.DeadNodes())
+	}
+	if nl.IsDead(3) {
+		t.Error("expected node without liveness record not to be dead")
+	}
+
+	// Node 2 isn't dead for removal purposes until its record has been
+	// expired for the specified duration.
+	if nl.IsDeadFor(2, time.Second) {
+		t.Error("expected node 2 not to be dead for a second")
+	}
+	manual.Set(2 * time.Second.Nanoseconds())
+	if !nl.IsDeadFor(2, time.Second) {
+		t.Error("expected node 2 to be dead for a second")
+	}
+
+	// A fresh instance learns the persisted records by scanning them.
+	records, err := NewNodeLiveness(store.DB(), nil, store.Clock(), time.Second).ScanRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expRecords := []LivenessRecord{
+		{NodeID: 1, Epoch: 1, Expiration: 2 * time.Second.Nanoseconds()},
+		{NodeID: 2, Epoch: 1, Expiration: time.Second.Nanoseconds()},
+	}
+	if !reflect.DeepEqual(records, expRecords) {
+		t.Errorf("expected records %+v; got %+v", expRecords, records)
+	}
+}
+
+// TestNodeLivenessIncrementEpoch verifies that a node's epoch is only
+// incremented once its record has expired, that concurrent increments
+// of the same epoch increment it once, and that the node adopts the
+// incremented epoch when it next heartbeats.
+func TestNodeLivenessIncrementEpoch(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+	nl := NewNodeLiveness(store.DB(), nil, store.Clock(), time.Second)
+	other := NewNodeLiveness(store.DB(), nil, store.Clock(), time.Second)
+
+	if err := nl.Heartbeat(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.IncrementEpoch(1, 1); err == nil {
+		t.Fatal("expected increment of a live node's epoch to fail")
+	}
+
+	manual.Set(time.Second.Nanoseconds())
+	for i := 0; i < 2; i++ {
+		epoch, err := other.IncrementEpoch(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if epoch != 2 {
+			t.Errorf("%d: expected epoch 2; got %d", i, epoch)
+		}
+	}
+
+	// nl still knows node 1 at epoch 1; its heartbeat fails the
+	// conditional put and is retried at the incremented epoch.
+	if err := nl.Heartbeat(1); err != nil {
+		t.Fatal(err)
+	}
+	lr, ok := nl.GetLiveness(1)
+	if !ok || lr.Epoch != 2 || !lr.IsLive(manual.UnixNano()) {
+		t.Errorf("expected node 1 live at epoch 2; got %+v", lr)
+	}
+	records, err := other.ScanRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0] != lr {
+		t.Errorf("expected persisted record %+v; got %+v", lr, records)
+	}
+}
+
+// TestNodeLivenessNil verifies that a nil NodeLiveness reports no
+// node dead, as when liveness tracking isn't configured.
+func TestNodeLivenessNil(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var nl *NodeLiveness
+	if nl.IsDead(1) {
+		t.Error("expected nil liveness to report node live")
+	}
+	if _, ok := nl.GetLiveness(1); ok {
+		t.Error("expected nil liveness to have no records")
+	}
+	if len(nl.LiveNodes()) != 0 || len(nl.DeadNodes()) != 0 {
+		t.Error("expected nil liveness to list no nodes")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2014 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+//
+// Author: Spencer Kimball (spencer.kimball@gmail.com)
+
+package storage
+
+import (
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// An Authorizer decides whether a user may invoke a command. It is
+// consulted for every command, both at the gateway and again at the
+// store executing the command, and allows integration of external
+// policy systems (e.g. LDAP group membership).
+type Authorizer interface {
+	// Authorize returns nil if the requesting user (header.User) may
+	// invoke method on the key span [header.Key, header.EndKey).
+	// Otherwise it returns an error, usually a *proto.PermissionError.
+	Authorize(method string, header *proto.RequestHeader) error
+}
+
+// AuthorizerFunc is an adapter which allows an ordinary function to be
+// used as an Authorizer.
+type AuthorizerFunc func(method string, header *proto.RequestHeader) error
+
+// Authorize invokes f(method, header).
+func (f AuthorizerFunc) Authorize(method string, header *proto.RequestHeader) error {
+	return f(method, header)
+}
+
+// NewPermConfigAuthorizer returns the default Authorizer, which grants
+// permissions according to the permission configs available via the
+// supplied gossip instance. See VerifyPermissions.
+func NewPermConfigAuthorizer(g *gossip.Gossip) Authorizer {
+	return AuthorizerFunc(func(method string, header *proto.RequestHeader) error {
+		return VerifyPermissions(g, method, header)
+	})
+}
+
+// VerifyPermissions verifies that the requesting user (header.User)
+// has permission to read/write (capabilities depend on method
+// name). In the event that multiple permission configs apply to the
+// key range implicated by the command, the lowest common denominator
+// for permission. For example, if a scan crosses two permission
+// configs, both configs must allow read permissions or the entire
+// scan will fail. A *proto.PermissionError is returned if the user
+// lacks the required permissions.
+func VerifyPermissions(g *gossip.Gossip, method string, header *proto.RequestHeader) error {
+	// The root user can always proceed.
+	if header.User == UserRoot {
+		return nil
+	}
+	// Check for admin methods; only the root user may invoke them.
+	if proto.NeedAdminPerm(method) {
+		return &proto.PermissionError{User: header.User, Method: method, Key: header.Key, EndKey: header.EndKey}
+	}
+	// Get permissions map from gossip.
+	if g == nil {
+		return util.Errorf("perm configs not available; cannot execute %s", method)
+	}
+	configMap, err := g.GetInfo(gossip.KeyConfigPermission)
+	if err != nil {
+		return util.Errorf("permissions not available via gossip")
+	}
+	if configMap == nil {
+		return util.Errorf("perm configs not available; cannot execute %s", method)
+	}
+	permMap := configMap.(PrefixConfigMap)
+	headerEnd := header.EndKey
+	if headerEnd == nil {
+		headerEnd = header.Key
+	}
+	// Visit PermConfig(s) which apply to the method's key range.
+	//   - For each perm config which the range covers, verify read or writes
+	//     are allowed as method requires.
+	//   - Verify the permissions hierarchically; that is, if permissions aren't
+	//     granted at the longest prefix, try next longest, then next, etc., up
+	//     to and including the default prefix.
+	//
+	// TODO(spencer): it might make sense to visit prefixes from the
+	//   shortest to longest instead for performance. Keep an eye on profiling
+	//   for this code path as permission sets grow large.
+	return permMap.VisitPrefixes(header.Key, headerEnd,
+		func(start, end proto.Key, config interface{}) (bool, error) {
+			hasPerm := false
+			permMap.VisitPrefixesHierarchically(start, func(start, end proto.Key, config interface{}) (bool, error) {
+				perm := config.(*proto.PermConfig)
+				if proto.NeedReadPerm(method) && !perm.CanRead(header.User) {
+					return false, nil
+				}
+				if proto.NeedWritePerm(method) && !perm.CanWrite(header.User) {
+					return false, nil
+				}
+				// Return done = true, as permissions have been granted by this config.
+				hasPerm = true
+				return true, nil
+			})
+			if !hasPerm {
+				return false, &proto.PermissionError{User: header.User, Method: method, Key: start, EndKey: end}
+			}
+			return false, nil
+		})
+}
@@ -28,6 +28,11 @@ import (
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
+// queuePausedRecheckInterval is the interval at which a paused queue
+// checks whether it may resume processing. It's a variable so that
+// tests may shorten it.
+var queuePausedRecheckInterval = 1 * time.Minute
+
 // A rangeItem holds a range and its priority for use with a priority queue.
 type rangeItem struct {
 	value    *Range
@@ -93,13 +98,21 @@ type queueImpl interface {
 // baseQueue is not thread safe and is intended for usage only from
 // the scanner's goroutine.
 type baseQueue struct {
-	name       string
-	impl       queueImpl
-	maxSize    int                  // Maximum number of ranges to queue
-	incoming   chan *Range          // Channel for ranges to be queued
-	sync.Mutex                      // Mutex protects priorityQ and ranges
-	priorityQ  priorityQueue        // The priority queue
-	ranges     map[int64]*rangeItem // Map from RaftID to rangeItem (for updating priority)
+	name           string
+	impl           queueImpl
+	maxSize        int                  // Maximum number of ranges to queue
+	maxConcurrency int                  // Maximum number of ranges to process at once
+	incoming       chan *Range          // Channel for ranges to be queued
+	sync.Mutex                          // Mutex protects priorityQ, ranges and processing
+	priorityQ      priorityQueue        // The priority queue
+	ranges         map[int64]*rangeItem // Map from RaftID to rangeItem (for updating priority)
+	processing     map[int64]bool       // RaftIDs of ranges being processed; true if re-added
+	// paused, if not nil, is consulted before each range is processed;
+	// while it returns true, processing is deferred.
+	paused func(time.Time) bool
+	// removed, if not nil, is invoked with the mutex locked for each
+	// range dropped from the queue without being processed.
+	removed func(*Range)
 }
 
 // newBaseQueue returns a new instance of baseQueue with the
@@ -108,13 +121,17 @@ type baseQueue struct {
 // maxSize doesn't prevent new ranges from being added, it just
 // limits the total size. Higher priority ranges can still be
 // added; their addition simply removes the lowest priority range.
-func newBaseQueue(name string, impl queueImpl, maxSize int) *baseQueue {
+// Up to maxConcurrency ranges are processed at once, in order of
+// priority.
+func newBaseQueue(name string, impl queueImpl, maxSize, maxConcurrency int) *baseQueue {
 	return &baseQueue{
-		name:     name,
-		impl:     impl,
-		maxSize:  maxSize,
-		incoming: make(chan *Range, 10),
-		ranges:   map[int64]*rangeItem{},
+		name:           name,
+		impl:           impl,
+		maxSize:        maxSize,
+		maxConcurrency: maxConcurrency,
+		incoming:       make(chan *Range, 10),
+		ranges:         map[int64]*rangeItem{},
+		processing:     map[int64]bool{},
 	}
 }
 
@@ -134,10 +151,16 @@ func (bq *baseQueue) Start(clock *hlc.Clock, stopper *util.Stopper) {
 // MaybeAdd adds the specified range if bq.shouldQ specifies it should
 // be queued. Ranges are added to the queue using the priority
 // returned by bq.shouldQ. If the queue is too full, an already-queued
-// range with the lowest priority may be dropped.
+// range with the lowest priority may be dropped. Ranges which are
+// currently being processed are reconsidered once processing
+// completes.
 func (bq *baseQueue) MaybeAdd(rng *Range, now proto.Timestamp) {
 	bq.Lock()
 	defer bq.Unlock()
+	if _, ok := bq.processing[rng.Desc().RaftID]; ok {
+		bq.processing[rng.Desc().RaftID] = true
+		return
+	}
 	should, priority := bq.impl.shouldQueue(now, rng)
 	item, ok := bq.ranges[rng.Desc().RaftID]
 	if !should {
@@ -161,8 +184,14 @@ func (bq *baseQueue) MaybeAdd(rng *Range, now proto.Timestamp) {
 	if pqLen := bq.priorityQ.Len(); pqLen > bq.maxSize {
 		bq.remove(pqLen - 1)
 	}
-	// Signal the processLoop that a range has been added.
-	bq.incoming <- rng
+	// Signal the processLoop that a range has been added. The send must
+	// not block: MaybeAdd holds the mutex, which processOne needs before
+	// it can free a concurrency slot for processLoop. A full channel
+	// already guarantees processLoop will look at the queue.
+	select {
+	case bq.incoming <- rng:
+	default:
+	}
 }
 
 // MaybeRemove removes the specified range from the queue if enqueued.
@@ -175,53 +204,86 @@ func (bq *baseQueue) MaybeRemove(rng *Range) {
 	}
 }
 
+// isPaused returns whether processing is paused at time t.
+func (bq *baseQueue) isPaused(t time.Time) bool {
+	return bq.paused != nil && bq.paused(t)
+}
+
 // process processes the entries in the queue until the provided
-// stopper signals exit.
+// stopper signals exit. Each range is processed in its own goroutine,
+// with at most maxConcurrency ranges being processed at once.
 //
 // TODO(spencer): current load should factor into range processing timer.
 func (bq *baseQueue) processLoop(clock *hlc.Clock, stopper *util.Stopper) {
+	sem := make(chan struct{}, bq.maxConcurrency)
 	stopper.RunWorker(func() {
 		// nextTime is set arbitrarily far into the future so that we don't
 		// unecessarily check for a range to dequeue if the timer function
 		// returns a short duration but the priority queue is empty.
 		emptyQueue := true
-		nextTime := time.Now().Add(24 * time.Hour)
+		nextTime := util.Now().Add(24 * time.Hour)
 
 		for {
+			timer := util.NewTimer(nextTime.Sub(util.Now()))
 			select {
 			// Incoming ranges set the next time to process in the event that
 			// there were previously no ranges in the queue.
 			case <-bq.incoming:
+				timer.Stop()
 				if emptyQueue {
 					emptyQueue = false
-					nextTime = time.Now().Add(bq.impl.timer())
+					nextTime = util.Now().Add(bq.impl.timer())
 				}
 			// Process ranges as the timer expires.
-			case <-time.After(nextTime.Sub(time.Now())):
+			case <-timer.C:
+				// Defer processing while the queue is paused.
+				if bq.isPaused(util.Now()) {
+					nextTime = util.Now().Add(queuePausedRecheckInterval)
+					continue
+				}
+				// Wait for one of the in-flight ranges to finish processing
+				// if the queue is at its concurrency limit.
+				select {
+				case sem <- struct{}{}:
+				case <-stopper.ShouldStop():
+					continue
+				}
 				if !stopper.StartTask() {
+					<-sem
 					continue
 				}
-				start := time.Now()
+				start := util.Now()
 				nextTime = start.Add(bq.impl.timer())
 				bq.Lock()
 				rng := bq.pop()
+				if rng != nil {
+					bq.processing[rng.Desc().RaftID] = false
+				}
 				bq.Unlock()
 				if rng != nil {
-					log.Infof("processing range %s from %s queue...", rng, bq.name)
-					if err := bq.impl.process(clock.Now(), rng); err != nil {
-						log.Errorf("failure processing range %s from %s queue: %s", rng, bq.name, err)
-					}
-					log.Infof("processed range %s from %s queue in %s", rng, bq.name, time.Now().Sub(start))
+					go func() {
+						bq.processOne(clock, rng, start)
+						<-sem
+						stopper.FinishTask()
+					}()
+				} else {
+					<-sem
+					stopper.FinishTask()
 				}
 				if bq.Length() == 0 {
 					emptyQueue = true
-					nextTime = time.Now().Add(24 * time.Hour)
+					nextTime = util.Now().Add(24 * time.Hour)
 				}
-				stopper.FinishTask()
 
 			// Exit on stopper.
 			case <-stopper.ShouldStop():
+				timer.Stop()
 				bq.Lock()
+				if bq.removed != nil {
+					for _, item := range bq.ranges {
+						bq.removed(item.value)
+					}
+				}
 				bq.ranges = map[int64]*rangeItem{}
 				bq.priorityQ = nil
 				bq.Unlock()
@@ -231,6 +293,24 @@ func (bq *baseQueue) processLoop(clock *hlc.Clock, stopper *util.Stopper) {
 	})
 }
 
+// processOne processes a range popped from the queue and removes it
+// from the set of ranges being processed. If the range was added
+// again while being processed, it's reconsidered for the queue.
+func (bq *baseQueue) processOne(clock *hlc.Clock, rng *Range, start time.Time) {
+	log.Infof("processing range %s from %s queue...", rng, bq.name)
+	if err := bq.impl.process(clock.Now(), rng); err != nil {
+		log.Errorf("failure processing range %s from %s queue: %s", rng, bq.name, err)
+	}
+	log.Infof("processed range %s from %s queue in %s", rng, bq.name, util.Now().Sub(start))
+	bq.Lock()
+	readd := bq.processing[rng.Desc().RaftID]
+	delete(bq.processing, rng.Desc().RaftID)
+	bq.Unlock()
+	if readd {
+		bq.MaybeAdd(rng, clock.Now())
+	}
+}
+
 // pop dequeues the highest priority range in the queue. Returns the
 // range if not empty; otherwise, returns nil. Expects mutex to be
 // locked.
@@ -248,4 +328,7 @@ func (bq *baseQueue) pop() *Range {
 func (bq *baseQueue) remove(index int) {
 	item := heap.Remove(&bq.priorityQ, index).(*rangeItem)
 	delete(bq.ranges, item.value.Desc().RaftID)
+	if bq.removed != nil {
+		bq.removed(item.value)
+	}
 }
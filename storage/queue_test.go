@@ -29,9 +29,11 @@ import (
 	"github.com/cockroachdb/cockroach/util/leaktest"
 )
 
-// testQueueImpl implements queueImpl with a closure for shouldQueue.
+// testQueueImpl implements queueImpl with a closure for shouldQueue
+// and an optional closure invoked by process.
 type testQueueImpl struct {
 	shouldQueueFn func(proto.Timestamp, *Range) (bool, float64)
+	processFn     func(*Range)
 	processed     int32
 	duration      time.Duration
 }
@@ -41,6 +43,9 @@ func (tq *testQueueImpl) shouldQueue(now proto.Timestamp, r *Range) (bool, float
 }
 
 func (tq *testQueueImpl) process(now proto.Timestamp, r *Range) error {
+	if tq.processFn != nil {
+		tq.processFn(r)
+	}
 	atomic.AddInt32(&tq.processed, 1)
 	return nil
 }
@@ -111,7 +116,7 @@ func TestBaseQueueAddUpdateAndRemove(t *testing.T) {
 			return shouldAddMap[r], priorityMap[r]
 		},
 	}
-	bq := newBaseQueue("test", testQueue, 2)
+	bq := newBaseQueue("test", testQueue, 2, 1)
 	bq.MaybeAdd(r1, proto.ZeroTimestamp)
 	bq.MaybeAdd(r2, proto.ZeroTimestamp)
 	if bq.Length() != 2 {
@@ -188,7 +193,7 @@ func TestBaseQueueProcess(t *testing.T) {
 		},
 		duration: 5 * time.Millisecond,
 	}
-	bq := newBaseQueue("test", testQueue, 2)
+	bq := newBaseQueue("test", testQueue, 2, 1)
 	stopper := util.NewStopper()
 	mc := hlc.NewManualClock(0)
 	clock := hlc.NewClock(mc.UnixNano)
@@ -228,7 +233,7 @@ func TestBaseQueueAddRemove(t *testing.T) {
 			return
 		},
 	}
-	bq := newBaseQueue("test", testQueue, 2)
+	bq := newBaseQueue("test", testQueue, 2, 1)
 	stopper := util.NewStopper()
 	mc := hlc.NewManualClock(0)
 	clock := hlc.NewClock(mc.UnixNano)
@@ -243,3 +248,143 @@ func TestBaseQueueAddRemove(t *testing.T) {
 		t.Errorf("expected processed count of 0; got %d", pc)
 	}
 }
+
+// TestBaseQueueConcurrency verifies that no more than the maximum
+// number of ranges are processed at once and that a range added while
+// being processed is processed again afterwards.
+func TestBaseQueueConcurrency(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var ranges []*Range
+	for i := 1; i <= 3; i++ {
+		r := &Range{}
+		r.SetDesc(&proto.RangeDescriptor{RaftID: int64(i)})
+		ranges = append(ranges, r)
+	}
+	var inflight, maxInflight int32
+	unblock := make(chan struct{})
+	testQueue := &testQueueImpl{
+		shouldQueueFn: func(now proto.Timestamp, r *Range) (shouldQueue bool, priority float64) {
+			return true, float64(r.Desc().RaftID)
+		},
+		processFn: func(r *Range) {
+			n := atomic.AddInt32(&inflight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInflight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+					break
+				}
+			}
+			<-unblock
+			atomic.AddInt32(&inflight, -1)
+		},
+	}
+	bq := newBaseQueue("test", testQueue, 10, 2)
+	stopper := util.NewStopper()
+	mc := hlc.NewManualClock(0)
+	clock := hlc.NewClock(mc.UnixNano)
+	bq.Start(clock, stopper)
+	defer stopper.Stop()
+
+	for _, r := range ranges {
+		bq.MaybeAdd(r, proto.ZeroTimestamp)
+	}
+	if err := util.IsTrueWithin(func() bool {
+		return atomic.LoadInt32(&inflight) == 2
+	}, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// The two highest priority ranges are being processed; re-add one.
+	bq.MaybeAdd(ranges[2], proto.ZeroTimestamp)
+	if l := bq.Length(); l != 1 {
+		t.Errorf("expected only the unprocessed range to be queued; got length %d", l)
+	}
+	close(unblock)
+	if err := util.IsTrueWithin(func() bool {
+		return atomic.LoadInt32(&testQueue.processed) == 4
+	}, 100*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	if max := atomic.LoadInt32(&maxInflight); max != 2 {
+		t.Errorf("expected at most 2 ranges processed at once; got %d", max)
+	}
+}
+
+// TestBaseQueuePaused verifies that ranges are not processed while the
+// queue is paused and are processed once it resumes.
+func TestBaseQueuePaused(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	defer func(interval time.Duration) { queuePausedRecheckInterval = interval }(queuePausedRecheckInterval)
+	queuePausedRecheckInterval = time.Millisecond
+
+	r := &Range{}
+	r.SetDesc(&proto.RangeDescriptor{RaftID: 1})
+	testQueue := &testQueueImpl{
+		shouldQueueFn: func(now proto.Timestamp, r *Range) (shouldQueue bool, priority float64) {
+			return true, 1.0
+		},
+	}
+	bq := newBaseQueue("test", testQueue, 2, 1)
+	var paused int32 = 1
+	bq.paused = func(time.Time) bool { return atomic.LoadInt32(&paused) == 1 }
+	stopper := util.NewStopper()
+	mc := hlc.NewManualClock(0)
+	clock := hlc.NewClock(mc.UnixNano)
+	bq.Start(clock, stopper)
+	defer stopper.Stop()
+
+	bq.MaybeAdd(r, proto.ZeroTimestamp)
+	time.Sleep(10 * time.Millisecond)
+	if pc := atomic.LoadInt32(&testQueue.processed); pc != 0 {
+		t.Errorf("expected no ranges processed while paused; got %d", pc)
+	}
+	atomic.StoreInt32(&paused, 0)
+	if err := util.IsTrueWithin(func() bool {
+		return atomic.LoadInt32(&testQueue.processed) == 1
+	}, 100*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestBaseQueueAddWhileSaturated verifies that adding more ranges than
+// the incoming channel buffers doesn't block while the queue is at its
+// concurrency limit.
+func TestBaseQueueAddWhileSaturated(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	unblock := make(chan struct{})
+	testQueue := &testQueueImpl{
+		shouldQueueFn: func(now proto.Timestamp, r *Range) (shouldQueue bool, priority float64) {
+			return true, float64(r.Desc().RaftID)
+		},
+		processFn: func(r *Range) {
+			<-unblock
+		},
+	}
+	const count = 30
+	bq := newBaseQueue("test", testQueue, count, 1)
+	stopper := util.NewStopper()
+	mc := hlc.NewManualClock(0)
+	clock := hlc.NewClock(mc.UnixNano)
+	bq.Start(clock, stopper)
+	defer stopper.Stop()
+
+	added := make(chan struct{})
+	go func() {
+		for i := 1; i <= count; i++ {
+			r := &Range{}
+			r.SetDesc(&proto.RangeDescriptor{RaftID: int64(i)})
+			bq.MaybeAdd(r, proto.ZeroTimestamp)
+		}
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("adding ranges blocked while the queue was saturated")
+	}
+	close(unblock)
+	if err := util.IsTrueWithin(func() bool {
+		return atomic.LoadInt32(&testQueue.processed) == count
+	}, time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// raftLogQueueMaxSize is the max size of the raft log queue.
+	raftLogQueueMaxSize = 100
+	// raftLogQueueMaxConcurrency is the max number of ranges processed at once
+	// by the raft log queue.
+	raftLogQueueMaxConcurrency = 1
+	// raftLogQueueTimerDuration is the duration between truncations of
+	// queued ranges.
+	raftLogQueueTimerDuration = 0 * time.Second // zero duration to process truncations greedily.
+	// raftLogMinTruncation is the minimum number of entries which must
+	// be discardable before a range's log is truncated.
+	raftLogMinTruncation = 100
+	// raftLogMaxEntries is the number of entries beyond which the log
+	// is truncated regardless of lagging followers. Followers which
+	// need the discarded entries are caught up via snapshot.
+	raftLogMaxEntries = 10000
+)
+
+// followerMatchFn returns the highest log index acknowledged by each
+// follower of the range with the given Raft ID.
+type followerMatchFn func(raftID int64) map[multiraft.NodeID]uint64
+
+// raftLogQueue manages a queue of ranges slated to have their raft
+// logs truncated. A range's log is truncated through the lowest index
+// applied locally and acknowledged by all followers. If the log grows
+// beyond raftLogMaxEntries, it is truncated through the local applied
+// index regardless, and lagging followers will receive a snapshot.
+type raftLogQueue struct {
+	*baseQueue
+	followerMatch followerMatchFn
+}
+
+// newRaftLogQueue returns a new instance of raftLogQueue.
+func newRaftLogQueue(followerMatch followerMatchFn) *raftLogQueue {
+	rlq := &raftLogQueue{followerMatch: followerMatch}
+	rlq.baseQueue = newBaseQueue("raftlog", rlq, raftLogQueueMaxSize, raftLogQueueMaxConcurrency)
+	return rlq
+}
+
+// getTruncatableIndexes returns the range's first log index and the
+// index of the first entry which must be kept after truncation.
+func (rlq *raftLogQueue) getTruncatableIndexes(rng *Range) (firstIndex, truncateIndex uint64, err error) {
+	firstIndex, err = rng.FirstIndex()
+	if err != nil {
+		return 0, 0, err
+	}
+	applied := atomic.LoadUint64(&rng.appliedIndex)
+	if applied < firstIndex {
+		return firstIndex, firstIndex, nil
+	}
+	truncateIndex = applied
+	if applied-firstIndex >= raftLogMaxEntries {
+		return firstIndex, truncateIndex, nil
+	}
+
+	// Keep all entries not yet acknowledged by every follower.
+	match := rlq.followerMatch(rng.Desc().RaftID)
+	for _, replica := range rng.Desc().Replicas {
+		nodeID := MakeRaftNodeID(replica.NodeID, replica.StoreID)
+		if nodeID == rng.rm.RaftNodeID() {
+			continue
+		}
+		if index := match[nodeID] + 1; index < truncateIndex {
+			truncateIndex = index
+		}
+	}
+	if truncateIndex < firstIndex {
+		truncateIndex = firstIndex
+	}
+	return firstIndex, truncateIndex, nil
+}
+
+// shouldQueue determines whether a range should be queued for
+// truncating. This is true if the range holds the leader lease and at
+// least raftLogMinTruncation entries can be discarded.
+func (rlq *raftLogQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
+	if !rng.HasLeaderLease() {
+		return
+	}
+	firstIndex, truncateIndex, err := rlq.getTruncatableIndexes(rng)
+	if err != nil {
+		log.Warning(err)
+		return
+	}
+	if n := truncateIndex - firstIndex; n >= raftLogMinTruncation {
+		return true, float64(n)
+	}
+	return
+}
+
+// process truncates the raft log of the range through the truncatable
+// index.
+func (rlq *raftLogQueue) process(now proto.Timestamp, rng *Range) error {
+	if !rng.HasLeaderLease() {
+		log.Infof("not leader of range %s; skipping log truncation", rng)
+		return nil
+	}
+	firstIndex, truncateIndex, err := rlq.getTruncatableIndexes(rng)
+	if err != nil {
+		return err
+	}
+	if truncateIndex <= firstIndex {
+		return nil
+	}
+	log.V(1).Infof("truncating raft log of range %s from %d to %d", rng, firstIndex, truncateIndex)
+	args := &proto.InternalTruncateLogRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       rng.Desc().StartKey,
+			Timestamp: now,
+			RaftID:    rng.Desc().RaftID,
+		},
+		Index: truncateIndex,
+	}
+	return rng.AddCmd(proto.InternalTruncateLog, args, &proto.InternalTruncateLogResponse{}, true)
+}
+
+// timer returns the duration between truncations of queued ranges.
+func (rlq *raftLogQueue) timer() time.Duration {
+	return raftLogQueueTimerDuration
+}
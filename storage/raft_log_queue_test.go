@@ -0,0 +1,83 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestRaftLogQueueTruncation verifies that the raft log queue only
+// discards entries which have been acknowledged by all followers.
+func TestRaftLogQueueTruncation(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	for i := 0; i < 2*raftLogMinTruncation; i++ {
+		args, resp := incrementArgs([]byte("a"), 1, 1, tc.store.StoreID())
+		if err := tc.rng.AddCmd(proto.Increment, args, resp, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	applied := atomic.LoadUint64(&tc.rng.appliedIndex)
+
+	// Add a follower which has acknowledged only the first entries.
+	follower := proto.Replica{NodeID: 2, StoreID: 2}
+	desc := *tc.rng.Desc()
+	desc.Replicas = append(append([]proto.Replica(nil), desc.Replicas...), follower)
+	tc.rng.SetDesc(&desc)
+	firstIndex, err := tc.rng.FirstIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := firstIndex + raftLogMinTruncation/2
+	rlq := newRaftLogQueue(func(raftID int64) map[multiraft.NodeID]uint64 {
+		return map[multiraft.NodeID]uint64{
+			MakeRaftNodeID(follower.NodeID, follower.StoreID): match,
+		}
+	})
+
+	// The lagging follower prevents truncation.
+	if shouldQ, _ := rlq.shouldQueue(tc.clock.Now(), tc.rng); shouldQ {
+		t.Errorf("expected lagging follower to prevent truncation")
+	}
+	if _, truncateIndex, err := rlq.getTruncatableIndexes(tc.rng); err != nil {
+		t.Fatal(err)
+	} else if truncateIndex != match+1 {
+		t.Errorf("expected truncate index %d; got %d", match+1, truncateIndex)
+	}
+
+	// Once the follower catches up, the log is truncated through the
+	// applied index.
+	match = applied
+	if shouldQ, _ := rlq.shouldQueue(tc.clock.Now(), tc.rng); !shouldQ {
+		t.Fatalf("expected range to be queued for log truncation")
+	}
+	if err := rlq.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+	if firstIndex, err = tc.rng.FirstIndex(); err != nil {
+		t.Fatal(err)
+	} else if firstIndex != applied {
+		t.Errorf("expected first index %d; got %d", applied, firstIndex)
+	}
+}
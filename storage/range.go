@@ -22,8 +22,10 @@ package storage
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"math/rand"
 	"reflect"
 	"sync"
@@ -55,6 +57,7 @@ func init() {
 	gob.Register(proto.RangeDescriptor{})
 	gob.Register(proto.Transaction{})
 	gob.Register(&NodeDescriptor{})
+	gob.Register(LivenessRecord{})
 }
 
 var (
@@ -94,6 +97,35 @@ const (
 	defaultLeaderLeaseDuration = time.Second
 )
 
+const (
+	// backpressureRangeSizeMultiplier is the multiple of its max size
+	// beyond which writes which add data to a range are backpressured
+	// until it splits.
+	backpressureRangeSizeMultiplier = 2
+	// backpressureMaxWait is the maximum time for which the store
+	// delays a backpressured write before failing it.
+	backpressureMaxWait = 10 * time.Second
+	// backpressurePausedDelay is the delay applied to each write which
+	// adds data to an oversized range while splits are paused.
+	backpressurePausedDelay = 100 * time.Millisecond
+)
+
+// closedTimestampLag is how far behind the current time the holder of
+// a leader lease closes timestamps when extending its lease. Writes at
+// or below the closed timestamp are pushed above it, so the lag is
+// long enough to leave most writes unaffected. Followers serve reads
+// at or below the closed timestamp.
+const closedTimestampLag = 2 * time.Second
+
+// closedTimestampRefreshInterval is how often the holder of an
+// epoch-based leader lease re-proposes its lease to advance the closed
+// timestamp. Epoch-based leases are extended by the heartbeats of the
+// holder's node liveness record rather than by re-proposing them, so
+// without re-proposals the closed timestamp would never advance. The
+// interval trades the Raft traffic of re-proposals against how far
+// behind the present follower reads may be served.
+const closedTimestampRefreshInterval = 10 * time.Second
+
 // configDescriptor describes administrative configuration maps
 // affecting ranges of the key-value map by key prefix.
 type configDescriptor struct {
@@ -126,6 +158,38 @@ var tsCacheMethods = map[string]struct{}{
 	proto.EnqueueMessage:        {},
 	proto.InternalResolveIntent: {},
 	proto.InternalMerge:         {},
+	proto.InternalExport:        {},
+	proto.InternalImport:        {},
+}
+
+// backpressureMethods specifies the set of methods which add data to
+// a range and are backpressured while the range is oversized.
+// Deletions, which may shrink the range, and the commands which split
+// it are exempt.
+var backpressureMethods = map[string]struct{}{
+	proto.Put:            {},
+	proto.ConditionalPut: {},
+	proto.Increment:      {},
+	proto.EnqueueUpdate:  {},
+	proto.EnqueueMessage: {},
+	proto.InternalMerge:  {},
+}
+
+// closedTimestampExemptMethods specifies the set of write methods
+// which may be applied at or below the closed timestamp. They write no
+// new versioned values: they finalize intents, which reads at the
+// closed timestamp already encounter as intents, update transaction
+// records or maintain the range's own state. Every other write method
+// is rejected at or below the closed timestamp.
+var closedTimestampExemptMethods = map[string]struct{}{
+	proto.EndTransaction:         {},
+	proto.InternalHeartbeatTxn:   {},
+	proto.InternalPushTxn:        {},
+	proto.InternalResolveIntent:  {},
+	proto.InternalGC:             {},
+	proto.InternalTruncateLog:    {},
+	proto.InternalLeaderLease:    {},
+	proto.InternalRecomputeStats: {},
 }
 
 // UsesTimestampCache returns true if the method affects or is
@@ -140,7 +204,9 @@ func UsesTimestampCache(method string) bool {
 // executed and the result returned via the done channel.
 type pendingCmd struct {
 	Reply proto.Response
-	done  chan error // Used to signal waiting RPC handler
+	cmdID proto.ClientCmdID // The client command ID; zero for lease requests
+	done  chan error        // Used to signal waiting RPC handler
+	trace *cmdTrace         // Records the command's application; may be nil
 }
 
 // A RangeManager is an interface satisfied by Store through which ranges
@@ -156,6 +222,14 @@ type RangeManager interface {
 	Allocator() *allocator
 	Gossip() *gossip.Gossip
 	SplitQueue() *splitQueue
+	ClockMonitor() *clockMonitor
+	Compactor() *compactor
+	LeaseRenewer() *leaseRenewer
+	Liveness() *NodeLiveness
+	RangeAdmission() *rangeAdmission
+	Draining() bool
+	Unhealthy() string
+	Exports() ExportSink
 
 	// Range manipulation methods.
 	AddRange(rng *Range) error
@@ -163,8 +237,10 @@ type RangeManager interface {
 	MergeRange(subsumingRng *Range, updatedEndKey proto.Key, subsumedRaftID int64) (*Range, error)
 	NewRangeDescriptor(start, end proto.Key, replicas []proto.Replica) (*proto.RangeDescriptor, error)
 	NewSnapshot() engine.Engine
+	ProcessRangeDescriptorUpdate(rng *Range) error
 	ProposeRaftCommand(cmdIDKey, proto.InternalRaftCommand) <-chan error
 	RemoveRange(rng *Range) error
+	SendPreemptiveSnapshot(pri snapshotPriority, to multiraft.NodeID, generate func() (*multiraft.RaftMessageRequest, error)) error
 	SplitRange(origRng, newRng *Range) error
 }
 
@@ -179,6 +255,8 @@ type Range struct {
 	rm       RangeManager   // Makes some store methods available
 	stats    *rangeStats    // Range statistics
 	maxBytes int64          // Max bytes before split.
+	// Request rate and sampled keys for load-based splitting.
+	loadStats rangeLoadStats
 	// Held while a split, merge, or replica change is underway.
 	metaLock sync.Mutex
 	// Last index persisted to the raft log (not necessarily committed).
@@ -187,6 +265,10 @@ type Range struct {
 	// Last index applied to the state machine. Updated atomically.
 	appliedIndex uint64
 	lease        unsafe.Pointer // Information for leader lease
+	invalidLease unsafe.Pointer // Lease this replica stopped serving under; node-local
+	leaseMu      sync.Mutex     // Serializes on-demand leader lease acquisition
+	extending    int32          // Non-zero while a lease extension is in flight
+	transfer     unsafe.Pointer // Outstanding *leaseTransfer, if any
 	stopper      *util.Stopper
 
 	sync.RWMutex                 // Protects the following fields (and Desc)
@@ -224,9 +306,28 @@ func NewRange(desc *proto.RangeDescriptor, rm RangeManager) (*Range, error) {
 		return nil, err
 	}
 
+	lease := &proto.Lease{}
+	ok, err := engine.MVCCGetProto(rm.Engine(), engine.RangeLeaderLeaseKey(desc.RaftID),
+		proto.ZeroTimestamp, true, nil, lease)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		r.setLease(lease)
+	}
+
 	return r, nil
 }
 
+// shouldStop returns a channel which is closed when the range's
+// stopper is stopping; nil if the range hasn't been started.
+func (r *Range) shouldStop() <-chan struct{} {
+	if r.stopper == nil {
+		return nil
+	}
+	return r.stopper.ShouldStop()
+}
+
 // String returns a string representation of the range.
 func (r *Range) String() string {
 	return fmt.Sprintf("range=%d (%s-%s)", r.Desc().RaftID, r.Desc().StartKey, r.Desc().EndKey)
@@ -251,13 +352,39 @@ func (r *Range) start(stopper *util.Stopper) {
 
 // Destroy cleans up all data associated with this range.
 func (r *Range) Destroy() error {
-	var deletes []interface{}
+	return r.destroy(nil)
+}
+
+// destroyWithTombstone clears the range's data and atomically writes
+// a tombstone recording the range's next replica ID. Replicas with
+// lower IDs, including this one, may not be recreated on the store by
+// stale Raft messages; a newer replica added later may.
+func (r *Range) destroyWithTombstone() error {
+	minID := int64(nextReplicaID(r.Desc()))
+	return r.destroy(&proto.Value{Integer: &minID})
+}
+
+// destroy clears the range's data, writing the tombstone, if not
+// nil, in the same batch.
+func (r *Range) destroy(tombstone *proto.Value) error {
+	batch := r.rm.Engine().NewBatch()
 	iter := newRangeDataIterator(r, r.rm.Engine())
 	defer iter.Close()
+	for _, kr := range iter.ranges {
+		r.rm.Compactor().suggestOnCommit(batch, kr.start, kr.end)
+	}
 	for ; iter.Valid(); iter.Next() {
-		deletes = append(deletes, engine.BatchDelete{RawKeyValue: proto.RawKeyValue{Key: iter.Key()}})
+		if err := batch.Clear(iter.Key()); err != nil {
+			return err
+		}
 	}
-	return r.rm.Engine().WriteBatch(deletes)
+	if tombstone != nil {
+		if err := engine.MVCCPut(batch, nil, engine.RangeTombstoneKey(r.Desc().RaftID),
+			proto.ZeroTimestamp, *tombstone, nil); err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
 }
 
 // GetMaxBytes atomically gets the range maximum byte limit.
@@ -286,19 +413,338 @@ func (r *Range) setLease(l *proto.Lease) {
 	atomic.StorePointer(&r.lease, unsafe.Pointer(l))
 }
 
+// installLeaderLease sets the supplied lease as the range's current
+// leader lease. If the lease changes hands, the timestamp cache's low
+// water mark is forwarded to the start of the new lease. The previous
+// holder served reads locally only at timestamps before its lease
+// expired, which precedes the start of the new lease, so this ensures
+// the new holder cannot accept writes beneath those reads.
+func (r *Range) installLeaderLease(l *proto.Lease) {
+	if prev := r.getLease(); prev == nil || prev.RaftNodeID != l.RaftNodeID {
+		r.Lock()
+		r.tsCache.SetLowWater(proto.Timestamp{WallTime: l.Expiration - l.Duration})
+		r.Unlock()
+		// Our proposals must not precede our tenure, which may have
+		// been started by another replica's clock on a transfer.
+		if l.RaftNodeID == uint64(r.rm.RaftNodeID()) {
+			if _, err := r.rm.Clock().Update(proto.Timestamp{WallTime: l.TenureStart}); err != nil {
+				log.Warningf("unable to forward clock to start of lease %s: %s", l, err)
+			}
+		}
+	}
+	// A lease held by this replica supersedes any transfer we made.
+	if l.RaftNodeID == uint64(r.rm.RaftNodeID()) {
+		atomic.StorePointer(&r.transfer, nil)
+	}
+	r.setLease(l)
+}
+
 func (r *Range) getLease() *proto.Lease {
 	return (*proto.Lease)(atomic.LoadPointer(&r.lease))
 }
 
+// invalidateLeaderLease stops this replica from serving commands under
+// the leader lease it currently holds, if any, until it has acquired a
+// new one. The lease itself is left in place: it is replicated state
+// which commands read when they are applied, so only this replica's
+// own use of it is affected.
+func (r *Range) invalidateLeaderLease() {
+	if l := r.getLease(); l != nil && l.RaftNodeID == uint64(r.rm.RaftNodeID()) {
+		atomic.StorePointer(&r.invalidLease, unsafe.Pointer(l))
+	}
+}
+
+// ownsLease returns whether the supplied lease is held by this replica
+// and has not been invalidated.
+func (r *Range) ownsLease(l *proto.Lease) bool {
+	return l != nil && l.RaftNodeID == uint64(r.rm.RaftNodeID()) &&
+		unsafe.Pointer(l) != atomic.LoadPointer(&r.invalidLease)
+}
+
+// LeaderLease returns a copy of the leader lease most recently applied
+// by this replica, or nil if none has been.
+func (r *Range) LeaderLease() *proto.Lease {
+	l := r.getLease()
+	if l == nil {
+		return nil
+	}
+	lease := *l
+	return &lease
+}
+
+// HasLeaderLease returns true if this range replica holds an
+// unexpired leader lease according to the local clock. The lease is
+// considered expired MaxOffset before its expiration, as the clock of
+// the replica acquiring the next lease may be ahead of the local
+// clock by up to that much.
+func (r *Range) HasLeaderLease() bool {
+	l := r.getLease()
+	return r.ownsLease(l) &&
+		r.rm.Clock().PhysicalNow() < r.leaseExpiration(l)-r.rm.Clock().MaxOffset().Nanoseconds()
+}
+
+// leaseExpiration returns the wall time at which the supplied lease
+// expires. An epoch-based lease expires along with its holder's node
+// liveness record, provided the record still carries the lease's
+// epoch. If the epoch has since been incremented, or the record isn't
+// known, the lease is treated as expired; taking it over still
+// requires incrementing the holder's epoch, which fails while the
+// holder is live.
+func (r *Range) leaseExpiration(l *proto.Lease) int64 {
+	if l.Epoch == 0 {
+		return l.Expiration
+	}
+	nodeID, _ := DecodeRaftNodeID(multiraft.NodeID(l.RaftNodeID))
+	if lr, ok := r.rm.Liveness().GetLiveness(nodeID); ok && lr.Epoch == l.Epoch {
+		return lr.Expiration
+	}
+	return 0
+}
+
+// livenessEpoch returns the epoch of our node's liveness record if
+// this replica may acquire an epoch-based leader lease, or zero for an
+// expiration-based lease. Ranges which begin in the system keyspace,
+// where the liveness records themselves are stored, always use
+// expiration-based leases, as do replicas whose node isn't known to be
+// live.
+func (r *Range) livenessEpoch() int64 {
+	if bytes.Compare(r.Desc().StartKey, engine.KeySystemMax) < 0 {
+		return 0
+	}
+	nodeID, _ := DecodeRaftNodeID(r.rm.RaftNodeID())
+	lr, ok := r.rm.Liveness().GetLiveness(nodeID)
+	if !ok || !lr.IsLive(r.rm.Clock().PhysicalNow()) {
+		return 0
+	}
+	return lr.Epoch
+}
+
+// leaseHolder returns the replica holding the supplied lease, or an
+// empty replica if the holder is not part of the range descriptor.
+func (r *Range) leaseHolder(l *proto.Lease) proto.Replica {
+	_, storeID := DecodeRaftNodeID(multiraft.NodeID(l.RaftNodeID))
+	if _, replica := r.Desc().FindReplica(storeID); replica != nil {
+		return *replica
+	}
+	return proto.Replica{}
+}
+
+// A leaseTransfer records a leader lease transfer initiated by this
+// replica.
+type leaseTransfer struct {
+	target     proto.Replica // Replica receiving the lease
+	start      int64         // Wall time at which the target's lease begins
+	expiration int64         // Expiration of the transferred lease
+}
+
+// checkLeaseTransfer consults the lease transfer initiated by this
+// replica, if one is outstanding. Until the transferred lease would
+// have expired, reads at timestamps before the start of the target's
+// lease continue to be served here: the target forwards its timestamp
+// cache to the start of its lease, so no write can slip in beneath
+// them. All other commands are redirected to the target. Returns
+// false if no transfer is outstanding.
+func (r *Range) checkLeaseTransfer(readOnly bool, timestamp proto.Timestamp) (bool, error) {
+	t := (*leaseTransfer)(atomic.LoadPointer(&r.transfer))
+	if t == nil {
+		return false, nil
+	}
+	if r.rm.Clock().PhysicalNow() >= t.expiration {
+		atomic.CompareAndSwapPointer(&r.transfer, unsafe.Pointer(t), nil)
+		return false, nil
+	}
+	if readOnly && timestamp.WallTime < t.start {
+		return true, nil
+	}
+	return true, &proto.NotLeaderError{Leader: t.target}
+}
+
+// redirectOnOrAcquireLeaderLease verifies that this replica holds the
+// leader lease. If another replica holds an unexpired lease, a
+// NotLeaderError naming the holder is returned so the client can
+// redirect, as it is by a draining store in any case. Otherwise, the
+// lease is requested via Raft and this method blocks until the request
+// has been applied. Leases nearing expiration are extended
+// asynchronously. While leases are suspended following a clock jump, a
+// ClockJumpError is returned.
+func (r *Range) redirectOnOrAcquireLeaderLease() error {
+	if err := r.rm.ClockMonitor().checkLeases(); err != nil {
+		return err
+	}
+	if r.HasLeaderLease() {
+		r.maybeExtendLeaderLease()
+		return nil
+	}
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	// Check again, as a concurrent command may have acquired the lease
+	// while we were waiting.
+	if r.HasLeaderLease() {
+		return nil
+	}
+	term, err := r.redirectOrLeaseTerm()
+	if err != nil {
+		return err
+	}
+	if err := r.acquireLeaderLease(term); err != nil {
+		return err
+	}
+	if !r.HasLeaderLease() {
+		return r.leaseRedirect()
+	}
+	return nil
+}
+
+// redirectOrLeaseTerm returns a NotLeaderError if this replica must
+// not request the leader lease: another replica holds an unexpired
+// lease, which the error names, or this store is draining or
+// unhealthy, and so hands its leases off rather than acquiring them.
+// Otherwise, it returns the term of the current lease, if any, to be
+// extended or replaced by the request. Expects leaseMu to be held.
+func (r *Range) redirectOrLeaseTerm() (uint64, error) {
+	var term uint64
+	if l := r.getLease(); l != nil {
+		if l.RaftNodeID != uint64(r.rm.RaftNodeID()) && r.rm.Clock().PhysicalNow() < r.leaseExpiration(l) {
+			return 0, &proto.NotLeaderError{Leader: r.leaseHolder(l)}
+		}
+		term = l.Term
+	}
+	if r.rm.Draining() || r.rm.Unhealthy() != "" {
+		return 0, &proto.NotLeaderError{}
+	}
+	return term, nil
+}
+
+// leaseRedirect returns a NotLeaderError naming the holder of the
+// current lease, if any.
+func (r *Range) leaseRedirect() error {
+	if l := r.getLease(); l != nil {
+		return &proto.NotLeaderError{Leader: r.leaseHolder(l)}
+	}
+	return &proto.NotLeaderError{}
+}
+
+// verifyLeaseCoversRead returns nil if the leader lease held by this
+// replica extends beyond the supplied read timestamp. If it does not,
+// the lease is extended synchronously and checked again, unless the
+// read is redirected for the same reasons as other commands are by
+// redirectOnOrAcquireLeaderLease. Reads are served without consulting
+// Raft, so only timestamps covered by the lease are safe: a subsequent
+// lease holder begins its lease after this one expires and forwards
+// its timestamp cache to the lease start, moving any conflicting
+// writes above reads served here.
+func (r *Range) verifyLeaseCoversRead(timestamp proto.Timestamp) error {
+	if ok, err := r.checkLeaseTransfer(true, timestamp); ok {
+		return err
+	}
+	covers := func() bool {
+		l := r.getLease()
+		return r.ownsLease(l) && timestamp.WallTime < r.leaseExpiration(l)
+	}
+	if covers() {
+		return nil
+	}
+	if err := r.rm.ClockMonitor().checkLeases(); err != nil {
+		return err
+	}
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	if covers() {
+		return nil
+	}
+	term, err := r.redirectOrLeaseTerm()
+	if err != nil {
+		return err
+	}
+	if err := r.acquireLeaderLease(term); err != nil {
+		return err
+	}
+	if !covers() {
+		if !r.ownsLease(r.getLease()) {
+			return r.leaseRedirect()
+		}
+		return util.Errorf("read timestamp %s is not covered by the leader lease of %s", timestamp, r)
+	}
+	return nil
+}
+
+// maybeExtendLeaderLease queues an extension of the leader lease held
+// by this replica with the store's lease renewer once less than half
+// of its duration remains. An epoch-based lease needs no extension, as
+// it lasts as long as our node's liveness; it's queued for
+// re-proposal only once its closed timestamp is older than
+// closedTimestampRefreshInterval. At most one extension is queued or
+// in flight at any time.
+func (r *Range) maybeExtendLeaderLease() {
+	l := r.getLease()
+	if l == nil {
+		return
+	}
+	now := r.rm.Clock().PhysicalNow()
+	if l.Epoch != 0 {
+		// The lease was proposed Duration before its nominal expiration.
+		if now-(l.Expiration-l.Duration) < closedTimestampRefreshInterval.Nanoseconds() {
+			return
+		}
+	} else if l.Expiration-now > l.Duration/2 {
+		return
+	}
+	// Don't extend a lease which is being transferred.
+	if atomic.LoadPointer(&r.transfer) != nil {
+		return
+	}
+	if r.stopper == nil || !atomic.CompareAndSwapInt32(&r.extending, 0, 1) {
+		return
+	}
+	r.rm.LeaseRenewer().add(r)
+}
+
+// closedTimestamp returns the timestamp at or below which the range
+// accepts no more writes, as of the last leader lease applied by this
+// replica.
+func (r *Range) closedTimestamp() proto.Timestamp {
+	if l := r.getLease(); l != nil {
+		return l.ClosedTimestamp
+	}
+	return proto.ZeroTimestamp
+}
+
+// isClosedTimestampWrite returns whether the method is a write which
+// must not be applied at or below the closed timestamp.
+func isClosedTimestampWrite(method string) bool {
+	if _, ok := closedTimestampExemptMethods[method]; ok {
+		return false
+	}
+	return proto.IsReadWrite(method)
+}
+
+// isFollowerRead returns whether the command is a consistent read at
+// or below the closed timestamp, which any replica may serve without
+// holding the leader lease: every write at or below it has been
+// applied here, and later writes are rejected (see executeCmd).
+func (r *Range) isFollowerRead(method string, header *proto.RequestHeader) bool {
+	if !proto.IsReadOnly(method) || header.ReadConsistency != proto.CONSISTENT {
+		return false
+	}
+	return !r.closedTimestamp().Less(header.Timestamp)
+}
+
 // canServiceCmd returns an error in the event that the range replica
 // cannot service the command as specified. This is of the case in
-// the event that the replica is not the leader.
+// the event that the replica does not hold the leader lease and the
+// command is either a write or a consistent read above the closed
+// timestamp.
 func (r *Range) canServiceCmd(method string, args proto.Request) error {
 	header := args.Header()
-	if !r.IsLeader() {
-		if !proto.IsReadOnly(method) || header.ReadConsistency == proto.CONSISTENT {
-			// TODO(spencer): when we happen to know the leader, fill it in here via replica.
-			return &proto.NotLeaderError{}
+	if r.isFollowerRead(method, header) {
+		// Served by this replica regardless of the leader lease.
+	} else if !proto.IsReadOnly(method) || header.ReadConsistency == proto.CONSISTENT {
+		if ok, err := r.checkLeaseTransfer(proto.IsReadOnly(method), header.Timestamp); ok {
+			if err != nil {
+				return err
+			}
+		} else if err := r.redirectOnOrAcquireLeaderLease(); err != nil {
+			return err
 		}
 	}
 	if proto.IsReadOnly(method) {
@@ -394,6 +840,12 @@ func (r *Range) SetLastVerificationTimestamp(timestamp proto.Timestamp) error {
 // command queue. If wait is false, read-write commands are added to
 // Raft without waiting for their completion.
 func (r *Range) AddCmd(method string, args proto.Request, reply proto.Response, wait bool) error {
+	return r.addCmd(method, args, reply, wait, nil)
+}
+
+// addCmd is AddCmd, recording the spans of the command's execution in
+// the supplied trace, which may be nil.
+func (r *Range) addCmd(method string, args proto.Request, reply proto.Response, wait bool, trace *cmdTrace) error {
 	if err := r.canServiceCmd(method, args); err != nil {
 		reply.Header().SetGoError(err)
 		return err
@@ -402,10 +854,16 @@ func (r *Range) AddCmd(method string, args proto.Request, reply proto.Response,
 	// Differentiate between read-only and read-write.
 	if proto.IsAdmin(method) {
 		return r.addAdminCmd(method, args, reply)
-	} else if proto.IsReadOnly(method) {
-		return r.addReadOnlyCmd(method, args, reply)
 	}
-	return r.addReadWriteCmd(method, args, reply, wait)
+	if err := r.checkBackpressure(method); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
+	r.recordLoad(args.Header().Key)
+	if proto.IsReadOnly(method) {
+		return r.addReadOnlyCmd(method, args, reply, trace)
+	}
+	return r.addReadWriteCmd(method, args, reply, wait, trace)
 }
 
 // beginCmd waits for any overlapping, already-executing commands via
@@ -414,14 +872,25 @@ func (r *Range) AddCmd(method string, args proto.Request, reply proto.Response,
 // there are any overlapping commands already in the queue. Returns
 // the command queue insertion key, to be supplied to subsequent
 // invocation of cmdQ.Remove().
-func (r *Range) beginCmd(start, end proto.Key, readOnly bool) interface{} {
+//
+// Writes are refused while a leader lease transfer is outstanding. The
+// transfer is checked while holding the same lock under which
+// TransferLeaderLease starts it and collects the commands it waits
+// for, so every write is either waited for or redirected.
+func (r *Range) beginCmd(start, end proto.Key, readOnly bool) (interface{}, error) {
 	r.Lock()
+	if !readOnly {
+		if ok, err := r.checkLeaseTransfer(false, proto.ZeroTimestamp); ok {
+			r.Unlock()
+			return nil, err
+		}
+	}
 	var wg sync.WaitGroup
 	r.cmdQ.GetWait(start, end, readOnly, &wg)
 	cmdKey := r.cmdQ.Add(start, end, readOnly)
 	r.Unlock()
 	wg.Wait()
-	return cmdKey
+	return cmdKey, nil
 }
 
 // addAdminCmd executes the command directly. There is no interaction
@@ -433,6 +902,10 @@ func (r *Range) addAdminCmd(method string, args proto.Request, reply proto.Respo
 		r.AdminSplit(args.(*proto.AdminSplitRequest), reply.(*proto.AdminSplitResponse))
 	case proto.AdminMerge:
 		r.AdminMerge(args.(*proto.AdminMergeRequest), reply.(*proto.AdminMergeResponse))
+	case proto.AdminTransferLease:
+		r.AdminTransferLease(args.(*proto.AdminTransferLeaseRequest), reply.(*proto.AdminTransferLeaseResponse))
+	case proto.InternalChangeReplicas:
+		r.InternalChangeReplicas(args.(*proto.InternalChangeReplicasRequest), reply.(*proto.InternalChangeReplicasResponse))
 	default:
 		return util.Errorf("unrecognized admin command type: %s", method)
 	}
@@ -442,17 +915,19 @@ func (r *Range) addAdminCmd(method string, args proto.Request, reply proto.Respo
 // addReadOnlyCmd updates the read timestamp cache and waits for any
 // overlapping writes currently processing through Raft ahead of us to
 // clear via the read queue.
-func (r *Range) addReadOnlyCmd(method string, args proto.Request, reply proto.Response) error {
+func (r *Range) addReadOnlyCmd(method string, args proto.Request, reply proto.Response, trace *cmdTrace) error {
 	header := args.Header()
 
 	// If read-consistency is set to INCONSISTENT, run directly.
 	if header.ReadConsistency == proto.INCONSISTENT {
-		return r.executeCmd(0, method, args, reply)
+		return r.executeCmd(0, "", method, args, reply, trace)
 	}
 
 	// Add the read to the command queue to gate subsequent
 	// overlapping, commands until this command completes.
-	cmdKey := r.beginCmd(header.Key, header.EndKey, true)
+	endSpan := trace.span(spanCmdQueue)
+	cmdKey, _ := r.beginCmd(header.Key, header.EndKey, true)
+	endSpan()
 
 	// It's possible that arbitrary delays (e.g. major GC, VM
 	// de-prioritization, etc.) could cause the execution of this read
@@ -470,9 +945,25 @@ func (r *Range) addReadOnlyCmd(method string, args proto.Request, reply proto.Re
 	// for the active leader and leadership changes force the
 	// read-timestamp-cache to reset its low water mark.
 	if err := r.canServiceCmd(method, args); err != nil {
+		r.Lock()
+		r.cmdQ.Remove(cmdKey)
+		r.Unlock()
 		return err
 	}
-	err := r.executeCmd(0, method, args, reply)
+	// The read is served locally, without a Raft proposal, so it must
+	// fall within the leader lease. Reads beyond the lease could be
+	// invalidated by writes accepted by a subsequent lease holder.
+	// Reads at or below the closed timestamp can't be invalidated.
+	if r.isFollowerRead(method, header) {
+		// No lease required.
+	} else if err := r.verifyLeaseCoversRead(header.Timestamp); err != nil {
+		r.Lock()
+		r.cmdQ.Remove(cmdKey)
+		r.Unlock()
+		reply.Header().SetGoError(err)
+		return err
+	}
+	err := r.executeCmd(0, "", method, args, reply, trace)
 
 	// Only update the timestamp cache if the command succeeded.
 	r.Lock()
@@ -485,19 +976,19 @@ func (r *Range) addReadOnlyCmd(method string, args proto.Request, reply proto.Re
 	return err
 }
 
-// getCmdID will create a ClientCmdId if it's empty in Request, otherwise
-// just return it.
-func (r *Range) getCmdID(args proto.Request) (cmdID proto.ClientCmdID) {
-	if !args.Header().CmdID.IsEmpty() {
-		cmdID = args.Header().CmdID
-	} else {
-		cmdID = proto.ClientCmdID{
-			WallTime: r.rm.Clock().PhysicalNow(),
-			Random:   rand.Int63(),
-		}
-	}
-
-	return
+// newProposalID returns a new ID for a Raft proposal. Proposals are
+// identified by IDs of their own rather than by the client command
+// IDs of their commands: a client may retry a command, whereas the
+// copies of a single proposal committed more than once must be
+// applied once (see processRaftCommand). The wall time of the ID is
+// taken from the hybrid clock, which never runs behind the start of a
+// lease we hold, so that the proposal isn't mistaken for one made
+// under an earlier lease (see isStaleProposal).
+func (r *Range) newProposalID() cmdIDKey {
+	return makeCmdIDKey(proto.ClientCmdID{
+		WallTime: r.rm.Clock().Now().WallTime,
+		Random:   rand.Int63(),
+	})
 }
 
 // addReadWriteCmd first consults the response cache to determine whether
@@ -510,7 +1001,7 @@ func (r *Range) getCmdID(args proto.Request) (cmdID proto.ClientCmdID) {
 // command is submitted to Raft. Upon completion, the write is removed
 // from the read queue and the reply is added to the response cache.
 // If wait is true, will block until the command is complete.
-func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.Response, wait bool) error {
+func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.Response, wait bool, trace *cmdTrace) error {
 	// Check the response cache in case this is a replay. This call
 	// may block if the same command is already underway.
 	header := args.Header()
@@ -531,7 +1022,14 @@ func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.R
 	// done before getting the max timestamp for the key(s), as
 	// timestamp cache is only updated after preceding commands have
 	// been run to successful completion.
-	cmdKey := r.beginCmd(header.Key, header.EndKey, false)
+	endSpan := trace.span(spanCmdQueue)
+	cmdKey, err := r.beginCmd(header.Key, header.EndKey, false)
+	endSpan()
+	if err != nil {
+		r.respCache.removeInflight(header.CmdID)
+		reply.Header().SetGoError(err)
+		return err
+	}
 
 	// Two important invariants of Cockroach: 1) encountering a more
 	// recently written value means transaction restart. 2) values must
@@ -542,9 +1040,11 @@ func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.R
 	// timestamp. When the write returns, the updated timestamp will
 	// inform the final commit timestamp.
 	if UsesTimestampCache(method) {
+		endSpan := trace.span(spanTimestampCache)
 		r.Lock()
 		rTS, wTS := r.tsCache.GetMax(header.Key, header.EndKey, txnMD5)
 		r.Unlock()
+		endSpan()
 
 		// Always push the timestamp forward if there's been a read which
 		// occurred after our txn timestamp.
@@ -567,38 +1067,40 @@ func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.R
 		}
 	}
 
-	// Create command and enqueue for Raft.
+	// Create command and enqueue for Raft. If the client may stop
+	// waiting at its deadline, the command is applied to a copy of the
+	// reply, which may still be written after we return.
 	pendingCmd := &pendingCmd{
 		Reply: reply,
+		cmdID: header.CmdID,
 		done:  make(chan error, 1),
+		trace: trace,
+	}
+	deadline := wait && header.Deadline != 0
+	if deadline {
+		pendingCmd.Reply = gogoproto.Clone(reply).(proto.Response)
 	}
 	raftCmd := proto.InternalRaftCommand{
 		RaftID: r.Desc().RaftID,
 	}
-	cmdID := r.getCmdID(args)
 	ok := raftCmd.Cmd.SetValue(args)
 	if !ok {
 		log.Fatalf("unknown command type %T", args)
 	}
-	idKey := makeCmdIDKey(cmdID)
+	idKey := r.newProposalID()
 	r.Lock()
 	r.pendingCmds[idKey] = pendingCmd
 	r.Unlock()
-	// TODO(bdarnell): In certain raft failover scenarios, proposed
-	// commands may be abandoned. We need to re-propose the command
-	// if too much time passes with no response on the done channel.
+	// Commands proposed concurrently are pipelined through Raft. Should
+	// the proposal be lost, e.g. on a change of leadership, multiraft
+	// proposes it again; copies committed more than once are applied
+	// only once (see processRaftCommand).
+	endProposal := trace.span(spanRaftProposal)
 	raftChan := r.rm.ProposeRaftCommand(idKey, raftCmd)
 
-	// Create a completion func for mandatory cleanups which we either
-	// run synchronously if we're waiting or in a goroutine otherwise.
-	completionFunc := func() error {
-		// First wait for raft to commit or abort the command.
-		var err error
-		if err = <-raftChan; err == nil {
-			// Next if the command was commited, wait for the range to apply it.
-			err = <-pendingCmd.done
-		}
-
+	// Create a func for mandatory cleanups once the command has been
+	// committed and applied, or has failed.
+	finishFunc := func(err error) error {
 		// As for reads, update timestamp cache with the timestamp
 		// of this write on success. This ensures a strictly higher
 		// timestamp for successive writes to the same key or key range.
@@ -618,6 +1120,33 @@ func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.R
 		return err
 	}
 
+	if deadline {
+		return r.awaitCmd(method, header, reply, pendingCmd, raftChan, endProposal, finishFunc)
+	}
+
+	// Create a completion func which we either run synchronously if
+	// we're waiting or in a goroutine otherwise. The wait ends once the
+	// store stops, as the command may then never be applied.
+	completionFunc := func() error {
+		// First wait for raft to commit or abort the command.
+		var err error
+		select {
+		case err = <-raftChan:
+		case <-r.shouldStop():
+			err = &proto.NotLeaderError{}
+		}
+		endProposal()
+		if err == nil {
+			// Next if the command was commited, wait for the range to apply it.
+			select {
+			case err = <-pendingCmd.done:
+			case <-r.shouldStop():
+				err = &proto.NotLeaderError{}
+			}
+		}
+		return finishFunc(err)
+	}
+
 	if wait {
 		return completionFunc()
 	}
@@ -625,6 +1154,53 @@ func (r *Range) addReadWriteCmd(method string, args proto.Request, reply proto.R
 	return nil
 }
 
+// awaitCmd waits for the completion of a read-write command until the
+// deadline of its header, copying the reply of the pending command,
+// to which the command is applied, into reply on completion. Once the
+// deadline passes, the command is left to complete in the background.
+// Its proposal is unaffected, so that it may still be applied; a
+// retry with the same ClientCmdID waits for it and returns its result
+// from the response cache.
+func (r *Range) awaitCmd(method string, header *proto.RequestHeader, reply proto.Response,
+	cmd *pendingCmd, raftChan <-chan error, endProposal func(), finishFunc func(error) error) error {
+	expired := util.NewTimer(untilDeadline(header))
+	defer expired.Stop()
+	// Only one of proposed and applied is non-nil at a time: the
+	// command is awaited first by Raft and then by the range.
+	proposed, applied := raftChan, (<-chan error)(nil)
+	for {
+		var err error
+		select {
+		case err = <-proposed:
+			endProposal()
+			if err == nil {
+				proposed, applied = nil, cmd.done
+				continue
+			}
+		case err = <-applied:
+		case <-expired.C:
+			go func() {
+				var err error
+				if proposed != nil {
+					err = <-proposed
+					endProposal()
+				}
+				if err == nil {
+					err = <-cmd.done
+				}
+				finishFunc(err)
+			}()
+			err = &DeadlineExceededError{Method: method, Deadline: header.Deadline}
+			reply.Header().SetGoError(err)
+			return err
+		}
+		err = finishFunc(err)
+		reply.Reset()
+		gogoproto.Merge(reply, cmd.Reply)
+		return err
+	}
+}
+
 func (r *Range) processRaftCommand(idKey cmdIDKey, index uint64,
 	raftCmd proto.InternalRaftCommand) error {
 	if index == 0 {
@@ -652,7 +1228,33 @@ func (r *Range) processRaftCommand(idKey cmdIDKey, index uint64,
 			log.Fatal(err)
 		}
 	}
-	err = r.executeCmd(index, method, args, reply)
+	stale := method != proto.InternalLeaderLease && r.isStaleProposal(idKey.cmdID())
+	if stale || r.isReproposal(idKey, reply) {
+		// The proposal was committed to the log before; skip it but return
+		// the result of its first application. A copy of a proposal made
+		// under an earlier lease is skipped whether or not its record has
+		// been garbage collected, redirecting the replica which made it to
+		// the current lease holder should it still be waiting.
+		if stale {
+			reply.Reset()
+			reply.Header().SetGoError(&proto.NotLeaderError{Leader: r.leaseHolder(r.getLease())})
+		}
+		if err = r.advanceAppliedIndex(r.rm.Engine(), index); err != nil {
+			log.Errorf("failed to advance applied index: %s", err)
+		}
+		if proto.IsReadWrite(method) {
+			r.respCache.removeInflight(args.Header().CmdID)
+		}
+		err = reply.Header().GoError()
+	} else {
+		var trace *cmdTrace
+		if cmd != nil {
+			trace = cmd.trace
+		}
+		endSpan := trace.span(spanApply)
+		err = r.executeCmd(index, idKey, method, args, reply, trace)
+		endSpan()
+	}
 	if cmd != nil {
 		cmd.done <- err
 	} else if err != nil {
@@ -661,11 +1263,59 @@ func (r *Range) processRaftCommand(idKey cmdIDKey, index uint64,
 	return err
 }
 
+// isReproposal returns whether the Raft proposal with the supplied ID
+// was applied before, in which case the outcome of its application is
+// read into reply. Proposals which appear lost are proposed again and
+// may be committed to the Raft log more than once. Since executeCmd
+// records the outcome of every proposal it applies, atomically with
+// its writes and whether or not it fails, every replica skips the same
+// copies, including after restarts.
+//
+// The records are kept in a keyspace of their own rather than in the
+// response cache, whose entries are garbage collected by age. A record
+// is collected only once the lease has changed hands since the
+// proposal was made, after which processRaftCommand skips any copy of
+// the proposal regardless (see isStaleProposal).
+func (r *Range) isReproposal(idKey cmdIDKey, reply proto.Response) bool {
+	proposalID := idKey.cmdID()
+	key := engine.RaftAppliedProposalKey(r.Desc().RaftID, &proposalID)
+	value, err := engine.MVCCGet(r.rm.Engine(), key, proto.ZeroTimestamp, true, nil)
+	if err == nil && value != nil {
+		reply.Reset()
+		err = gogoproto.Unmarshal(value.Bytes, reply.(gogoproto.Message))
+	}
+	if err != nil {
+		log.Fatalf("unable to read outcome of proposal %x: %s", idKey, err)
+	}
+	return value != nil
+}
+
+// isStaleProposal returns whether the Raft proposal with the supplied
+// ID was made before the tenure of the current lease holder began.
+// Only the lease holder proposes commands other than lease requests,
+// and it takes proposal IDs from its hybrid clock, so such a proposal
+// was made under an earlier lease, possibly by another replica, and
+// is a copy reproposed after the lease changed hands. Lease requests,
+// which are made by replicas without the lease, aren't subject to this.
+func (r *Range) isStaleProposal(proposalID proto.ClientCmdID) bool {
+	l := r.getLease()
+	return l != nil && proposalID.WallTime < l.TenureStart
+}
+
+// advanceAppliedIndex records the Raft log entry at index as applied
+// without applying a command, for commands which fail or are skipped.
+// The applied index is written to the supplied engine or batch.
+func (r *Range) advanceAppliedIndex(e engine.Engine, index uint64) error {
+	atomic.StoreUint64(&r.appliedIndex, index)
+	return engine.MVCCPut(e, nil, engine.RaftAppliedIndexKey(r.Desc().RaftID),
+		proto.ZeroTimestamp, proto.Value{Bytes: encoding.EncodeUint64(nil, index)}, nil)
+}
+
 // startGossip periodically gossips the cluster ID if it's the
 // first range and the raft leader.
 func (r *Range) startGossip() {
 	r.stopper.RunWorker(func() {
-		ticker := time.NewTicker(ttlClusterIDGossip / 2)
+		ticker := util.NewTicker(ttlClusterIDGossip / 2)
 		for {
 			select {
 			case <-ticker.C:
@@ -758,6 +1408,28 @@ func (r *Range) maybeUpdateGossipConfigs(key proto.Key) {
 	}
 }
 
+// checkBackpressure returns a proto.RangeTooLargeError if the method adds
+// data and the range has grown beyond backpressureRangeSizeMultiplier
+// times its max size. The range is queued for splitting in that case.
+// While splits are paused, the range can't shrink until the pause
+// window ends, so rather than being refused such writes are throttled
+// by backpressurePausedDelay.
+func (r *Range) checkBackpressure(method string) error {
+	if _, ok := backpressureMethods[method]; !ok {
+		return nil
+	}
+	maxBytes := r.GetMaxBytes()
+	if size := r.stats.GetSize(); maxBytes > 0 && size > backpressureRangeSizeMultiplier*maxBytes {
+		if r.rm.SplitQueue().isPaused(util.Now()) {
+			time.Sleep(backpressurePausedDelay)
+			return nil
+		}
+		r.maybeSplit()
+		return &proto.RangeTooLargeError{RaftID: r.Desc().RaftID, Bytes: size, MaxBytes: maxBytes}
+	}
+	return nil
+}
+
 // maybeSplit checks whether the current size of the range exceeds the
 // max size specified in the zone config. If yes, the range is added
 // to the split queue.
@@ -771,6 +1443,22 @@ func (r *Range) maybeSplit() {
 	}
 }
 
+// recordLoad counts a request for key against the range's load. When
+// a window of measurement completes with a request rate exceeding the
+// split queue's threshold, the range is added to the split queue.
+func (r *Range) recordLoad(key proto.Key) {
+	if bytes.HasPrefix(key, engine.KeyLocalPrefix) {
+		return
+	}
+	qps, ok := r.loadStats.record(key, r.rm.Clock().PhysicalNow())
+	if !ok || !r.IsLeader() {
+		return
+	}
+	if threshold := r.rm.SplitQueue().qpsThreshold; threshold > 0 && qps > threshold {
+		r.rm.SplitQueue().MaybeAdd(r, r.rm.Clock().Now())
+	}
+}
+
 // executeCmd switches over the method and multiplexes to execute the
 // appropriate storage API command.
 //
@@ -781,22 +1469,42 @@ func (r *Range) maybeSplit() {
 // errors which should be classified as a ReplicaCorruptionError--when those
 // bubble up to the point where we've just tried to execute a Raft command, the
 // Raft replica would need to stall itself.
-func (r *Range) executeCmd(index uint64, method string, args proto.Request,
-	reply proto.Response) error {
+//
+// Commands applied from the Raft log have a non-zero index and the ID
+// of their proposal. The outcome of every such command is recorded
+// under its proposal ID, whether it's applied or rejected, so that
+// copies of the proposal committed again are skipped.
+func (r *Range) executeCmd(index uint64, idKey cmdIDKey, method string, args proto.Request,
+	reply proto.Response, trace *cmdTrace) error {
 	// Verify key is contained within range here to catch any range split
 	// or merge activity.
 	header := args.Header()
 	if !r.ContainsKeyRange(header.Key, header.EndKey) {
 		err := proto.NewRangeKeyMismatchError(header.Key, header.EndKey, r.Desc())
 		reply.Header().SetGoError(err)
+		r.recordRejection(index, idKey, method, args, reply)
 		return err
 	}
 
 	// If a unittest filter was installed, check for an injected error; otherwise, continue.
 	if TestingCommandFilter != nil && TestingCommandFilter(method, args, reply) {
+		r.recordRejection(index, idKey, method, args, reply)
 		return reply.Header().GoError()
 	}
 
+	// Reject writes at or below the closed timestamp, which followers
+	// may already have served reads above. The lease holder pushes
+	// writes above the closed timestamp before closing it, so this
+	// only catches writes proposed concurrently with a lease extension.
+	// Since commands are applied in Raft log order, every replica
+	// reaches the same decision.
+	if closed := r.closedTimestamp(); isClosedTimestampWrite(method) && !closed.Less(header.Timestamp) {
+		err := &proto.WriteTooOldError{Timestamp: header.Timestamp, ExistingTimestamp: closed}
+		reply.Header().SetGoError(err)
+		r.recordRejection(index, idKey, method, args, reply)
+		return err
+	}
+
 	// Create a new batch for the command to ensure all or nothing semantics.
 	batch := r.rm.Engine().NewBatch()
 	// Create an engine.MVCCStats instance.
@@ -842,12 +1550,31 @@ func (r *Range) executeCmd(index uint64, method string, args proto.Request,
 	case proto.InternalTruncateLog:
 		r.InternalTruncateLog(batch, &ms, args.(*proto.InternalTruncateLogRequest), reply.(*proto.InternalTruncateLogResponse))
 	case proto.InternalLeaderLease:
-		r.InternalLeaderLease(args.(*proto.InternalLeaderLeaseRequest), reply.(*proto.InternalLeaderLeaseResponse))
+		r.InternalLeaderLease(batch, args.(*proto.InternalLeaderLeaseRequest), reply.(*proto.InternalLeaderLeaseResponse))
+	case proto.InternalRecomputeStats:
+		r.InternalRecomputeStats(batch, &ms, args.(*proto.InternalRecomputeStatsRequest), reply.(*proto.InternalRecomputeStatsResponse))
+	case proto.InternalExport:
+		r.InternalExport(batch, args.(*proto.InternalExportRequest), reply.(*proto.InternalExportResponse))
+	case proto.InternalImport:
+		r.InternalImport(batch, &ms, args.(*proto.InternalImportRequest), reply.(*proto.InternalImportResponse))
 	default:
-		return util.Errorf("unrecognized command %s", method)
+		err := util.Errorf("unrecognized command %s", method)
+		reply.Header().SetGoError(err)
+		r.recordRejection(index, idKey, method, args, reply)
+		return err
 	}
 
-	// On success, flush the MVCC stats to the batch and commit.
+	// Propagate the request timestamp (which may have changed).
+	reply.Header().Timestamp = header.Timestamp
+
+	// On success, flush the MVCC stats to the batch and commit. The
+	// command's result is added to the response cache in the same
+	// batch if this is a read/write method. This must be done as part
+	// of the execution of raft commands so that every replica maintains
+	// the same responses to continue request idempotence when
+	// leadership changes, and atomically with the command's writes so
+	// that a crash can't separate them.
+	committed := false
 	if err := reply.Header().GoError(); err == nil {
 		// If we are applying a raft command, update the applied index.
 		if index > 0 {
@@ -864,13 +1591,29 @@ func (r *Range) executeCmd(index uint64, method string, args proto.Request,
 
 		if proto.IsReadWrite(method) {
 			r.stats.MergeMVCCStats(batch, &ms, header.Timestamp.WallTime)
-			if err := batch.Commit(); err != nil {
-				reply.Header().SetGoError(err)
+			r.putResponse(batch, method, args, reply)
+			if index > 0 {
+				r.putProposal(batch, idKey, reply)
+			}
+			endSpan := trace.span(spanEngineWrite)
+			err := batch.Commit()
+			endSpan()
+			if err != nil {
+				// A failed commit indicates an I/O error in the engine.
+				reply.Header().SetGoError(&proto.StoreUnhealthyError{
+					StoreID: r.rm.StoreID(),
+					Reason:  fmt.Sprintf("write failed: %s", err),
+				})
 			} else {
+				committed = true
 				// After successful commit, update cached stats values.
 				r.stats.Update(ms)
 				// If the commit succeeded, potentially add range to split queue.
 				r.maybeSplit()
+				// Install a newly granted or extended leader lease.
+				if method == proto.InternalLeaderLease {
+					r.installLeaderLease(&args.(*proto.InternalLeaderLeaseRequest).Lease)
+				}
 				// Maybe update gossip configs on a put.
 				if (method == proto.Put || method == proto.ConditionalPut) && header.Key.Less(engine.KeySystemMax) {
 					r.maybeUpdateGossipConfigs(header.Key)
@@ -878,18 +1621,6 @@ func (r *Range) executeCmd(index uint64, method string, args proto.Request,
 			}
 		}
 	} else {
-		if index > 0 {
-			atomic.StoreUint64(&r.appliedIndex, index)
-			// On failure, abandon the batch we've built up, but still update the
-			// applied index so we won't retry this command on restart.
-			if err := engine.MVCCPut(r.rm.Engine(), nil, engine.RaftAppliedIndexKey(r.Desc().RaftID),
-				proto.ZeroTimestamp, proto.Value{Bytes: encoding.EncodeUint64(nil, index)}, nil); err != nil {
-				// The reply header already contains an error which is going to be more useful
-				// the caller than this one, so just log it.
-				log.Errorf("failed to advance applied index: %s", err)
-			}
-		}
-
 		if err, ok := reply.Header().GoError().(*proto.ReadWithinUncertaintyIntervalError); ok {
 			// A ReadUncertaintyIntervalError contains the timestamp of the value
 			// that provoked the conflict. However, we forward the timestamp to the
@@ -904,24 +1635,103 @@ func (r *Range) executeCmd(index uint64, method string, args proto.Request,
 		}
 	}
 
-	// Propagate the request timestamp (which may have changed).
-	reply.Header().Timestamp = args.Header().Timestamp
+	// On failure, abandon the batch we've built up, but still update
+	// the applied index so we won't retry this command on restart, and
+	// record the failed result in the response cache.
+	if !committed {
+		r.recordFailure(index, idKey, method, args, reply, true /* cacheResponse */)
+	} else if proto.IsReadWrite(method) {
+		r.respCache.removeInflight(header.CmdID)
+	}
 
 	log.V(1).Infof("executed %s command %+v: %+v", method, args, reply)
 
-	// Add this command's result to the response cache if this is a
-	// read/write method. This must be done as part of the execution of
-	// raft commands so that every replica maintains the same responses
-	// to continue request idempotence when leadership changes.
-	if proto.IsReadWrite(method) {
-		if putErr := r.respCache.PutResponse(args.Header().CmdID, reply); putErr != nil {
-			log.Errorf("unable to write result of %+v: %+v to the response cache: %s",
-				args, reply, putErr)
+	// Return the error (if any) set in the reply.
+	return reply.Header().GoError()
+}
+
+// recordRejection records the outcome of a command rejected before
+// execution. Unlike failed commands, the result isn't cached for the
+// client, whose retry of the command may succeed; see recordFailure.
+func (r *Range) recordRejection(index uint64, idKey cmdIDKey, method string, args proto.Request, reply proto.Response) {
+	r.recordFailure(index, idKey, method, args, reply, false /* !cacheResponse */)
+}
+
+// recordFailure records the outcome of a command whose writes were
+// abandoned, in a batch of its own. For a command applied from the
+// Raft log, the applied index is advanced past it and its outcome is
+// recorded under its proposal ID. If cacheResponse is true, the
+// result of a read/write command is added to the response cache.
+// Either way, any client waiting on the command's inflight entry in
+// the response cache is released.
+func (r *Range) recordFailure(index uint64, idKey cmdIDKey, method string, args proto.Request,
+	reply proto.Response, cacheResponse bool) {
+	cacheResponse = cacheResponse && proto.IsReadWrite(method)
+	if index > 0 || cacheResponse {
+		failBatch := r.rm.Engine().NewBatch()
+		if index > 0 {
+			if err := r.advanceAppliedIndex(failBatch, index); err != nil {
+				// The reply header already contains an error which is going to be more useful
+				// the caller than this one, so just log it.
+				log.Errorf("failed to advance applied index: %s", err)
+			}
+			r.putProposal(failBatch, idKey, reply)
+		}
+		if cacheResponse {
+			r.putResponse(failBatch, method, args, reply)
+		}
+		if err := failBatch.Commit(); err != nil {
+			log.Errorf("unable to commit result of failed %s command: %s", method, err)
 		}
 	}
+	if proto.IsReadWrite(method) {
+		r.respCache.removeInflight(args.Header().CmdID)
+	}
+}
 
-	// Return the error (if any) set in the reply.
-	return reply.Header().GoError()
+// onCommit calls fn once the supplied batch has been committed, for
+// side effects of a command which must not happen if the command's
+// writes are abandoned. If batch isn't an engine.Batch, its writes
+// have already been made and fn is called immediately.
+func onCommit(batch engine.Engine, fn func()) {
+	if b, ok := batch.(*engine.Batch); ok {
+		b.Defer(fn)
+		return
+	}
+	fn()
+}
+
+// putResponse adds the result of a read/write command to the response
+// cache, writing it to the supplied batch. Failures are logged; they
+// only compromise the idempotence of retries of the command.
+func (r *Range) putResponse(batch engine.Engine, method string, args proto.Request, reply proto.Response) {
+	if err := r.respCache.putResponse(batch, args.Header().CmdID, reply); err != nil {
+		log.Errorf("unable to write result of %s %+v: %+v to the response cache: %s",
+			method, args, reply, err)
+	}
+}
+
+// putProposal records the outcome of the Raft proposal with the
+// supplied ID, writing it to the supplied batch; see isReproposal.
+// Unlike putResponse, it records every outcome, including errors which
+// aren't cached for clients. Since replicas must agree on which copies
+// of a proposal they skip, a failure to record it is fatal.
+func (r *Range) putProposal(batch engine.Engine, idKey cmdIDKey, reply proto.Response) {
+	proposalID := idKey.cmdID()
+	key := engine.RaftAppliedProposalKey(r.Desc().RaftID, &proposalID)
+	data, err := gogoproto.Marshal(reply.(gogoproto.Message))
+	if err == nil {
+		err = engine.MVCCPut(batch, nil, key, proto.ZeroTimestamp, proto.Value{Bytes: data}, nil)
+	}
+	if err != nil {
+		log.Fatalf("unable to record outcome of proposal %x: %s", idKey, err)
+	}
+}
+
+// decodeAppliedProposalKey decodes the proposal ID from the key of a
+// record written by putProposal.
+func decodeAppliedProposalKey(encKey proto.EncodedKey) (proto.ClientCmdID, error) {
+	return decodeRangeIDCmdIDKey(encKey, engine.KeyLocalRaftAppliedProposalSuffix)
 }
 
 // Contains verifies the existence of a key in the key value store.
@@ -950,10 +1760,11 @@ func (r *Range) Put(batch engine.Engine, ms *engine.MVCCStats, args *proto.PutRe
 }
 
 // ConditionalPut sets the value for a specified key only if
-// the expected value matches. If not, the return value contains
-// the actual value.
+// the expected value matches, returning the replaced value. If not,
+// the error contains the actual value.
 func (r *Range) ConditionalPut(batch engine.Engine, ms *engine.MVCCStats, args *proto.ConditionalPutRequest, reply *proto.ConditionalPutResponse) {
-	err := engine.MVCCConditionalPut(batch, ms, args.Key, args.Timestamp, args.Value, args.ExpValue, args.Txn)
+	prevValue, err := engine.MVCCConditionalPut(batch, ms, args.Key, args.Timestamp, args.Value, args.ExpValue, args.AllowIfMissing, args.Txn)
+	reply.PrevValue = prevValue
 	reply.SetGoError(err)
 }
 
@@ -961,7 +1772,7 @@ func (r *Range) ConditionalPut(batch engine.Engine, ms *engine.MVCCStats, args *
 // returns the newly incremented value (encoded as varint64). If no value
 // exists for the key, zero is incremented.
 func (r *Range) Increment(batch engine.Engine, ms *engine.MVCCStats, args *proto.IncrementRequest, reply *proto.IncrementResponse) {
-	val, err := engine.MVCCIncrement(batch, ms, args.Key, args.Timestamp, args.Txn, args.Increment)
+	val, err := engine.MVCCIncrement(batch, ms, args.Key, args.Timestamp, args.Txn, args.Increment, args.Saturate)
 	reply.NewValue = val
 	reply.SetGoError(err)
 }
@@ -979,12 +1790,48 @@ func (r *Range) DeleteRange(batch engine.Engine, ms *engine.MVCCStats, args *pro
 	reply.SetGoError(err)
 }
 
+// scanDesc describes a scan to the memory watchdog. It is formatted
+// only if the scan is shed.
+type scanDesc struct {
+	r    *Range
+	args *proto.ScanRequest
+}
+
+func (d scanDesc) String() string {
+	return fmt.Sprintf("scan %q-%q on %s", d.args.Key, d.args.EndKey, d.r)
+}
+
+// snapshotDesc describes a snapshot to the memory watchdog.
+type snapshotDesc struct {
+	r *Range
+}
+
+func (d snapshotDesc) String() string {
+	return fmt.Sprintf("snapshot of %s", d.r)
+}
+
 // Scan scans the key range specified by start key through end key up
 // to some maximum number of results. The last key of the iteration is
 // returned with the reply.
 func (r *Range) Scan(batch engine.Engine, args *proto.ScanRequest, reply *proto.ScanResponse) {
-	kvs, err := engine.MVCCScan(batch, args.Key, args.EndKey, args.MaxResults, args.Timestamp, args.ReadConsistency == proto.CONSISTENT, args.Txn)
+	// Account the accumulated results with the memory watchdog, which
+	// may shed the scan under memory pressure.
+	res := util.DefaultMemoryWatchdog.Reserve(scanDesc{r: r, args: args})
+	defer res.Release()
+	kvs := []proto.KeyValue{}
+	resumeKey, err := engine.MVCCIterateWithLimits(batch, args.Key, args.EndKey, args.MaxResults, args.MaxBytes, args.Timestamp, args.ReadConsistency == proto.CONSISTENT, args.Txn,
+		func(kv proto.KeyValue) error {
+			if err := res.Grow(int64(len(kv.Key) + len(kv.Value.Bytes))); err != nil {
+				return err
+			}
+			kvs = append(kvs, kv)
+			return nil
+		})
+	if err != nil {
+		kvs = nil
+	}
 	reply.Rows = kvs
+	reply.ResumeKey = resumeKey
 	reply.SetGoError(err)
 }
 
@@ -1127,12 +1974,13 @@ func (r *Range) EnqueueMessage(batch engine.Engine, args *proto.EnqueueMessageRe
 // retrieved by generating its Range Metadata Key and dispatching it to
 // InternalRangeLookup.
 //
-// Note that the Range Metadata Key sent to InternalRangeLookup is NOT the key
-// at which the desired RangeDescriptor is stored. Instead, this method returns
-// the RangeDescriptor stored at the _lowest_ existing key which is _greater_
-// than the given key. The returned RangeDescriptor will thus contain the
-// ordinary key which was originally used to generate the Range Metadata Key
-// sent to InternalRangeLookup.
+// Note that the key sent to InternalRangeLookup is NOT the key at which the
+// desired RangeDescriptor is stored, but the key immediately following the
+// Range Metadata Key, RangeMetaKey(key).Next(). This method returns the
+// RangeDescriptor stored at the _lowest_ existing key which is _greater than
+// or equal to_ the given key. The returned RangeDescriptor will thus contain
+// the ordinary key which was originally used to generate the Range Metadata
+// Key.
 //
 // The "Range Metadata Key" for a range is built by appending the end key of
 // the range to the meta[12] prefix because the RocksDB iterator only supports
@@ -1147,6 +1995,11 @@ func (r *Range) EnqueueMessage(batch engine.Engine, args *proto.EnqueueMessageRe
 // intended to serve as a sort of caching pre-fetch, so that the requesting
 // nodes can aggressively cache RangeDescriptors which are likely to be desired
 // by their current workload.
+//
+// The meta2 records may be split across several ranges, so the scan is
+// bounded by the end of this range. If no record follows the key within this
+// range, no RangeDescriptors are returned and the caller continues the lookup
+// at the start of the following range.
 func (r *Range) InternalRangeLookup(batch engine.Engine, args *proto.InternalRangeLookupRequest, reply *proto.InternalRangeLookupResponse) {
 	if err := engine.ValidateRangeMetaKey(args.Key); err != nil {
 		reply.SetGoError(err)
@@ -1160,14 +2013,19 @@ func (r *Range) InternalRangeLookup(batch engine.Engine, args *proto.InternalRan
 		return
 	}
 
-	// We want to search for the metadata key just greater than args.Key. Scan
+	// We want to search for the first metadata key at or after args.Key. Scan
 	// for both the requested key and the keys immediately afterwards, up to
-	// MaxRanges.
+	// MaxRanges, without leaving either this range or the metadata level of
+	// the queried key.
 	metaPrefix := proto.Key(args.Key[:len(engine.KeyMeta1Prefix)])
-	nextKey := proto.Key(args.Key).Next()
+	endKey := metaPrefix.PrefixEnd()
+	truncated := false
+	if rangeEnd := r.Desc().EndKey; rangeEnd.Less(endKey) {
+		endKey, truncated = rangeEnd, true
+	}
 	// Always false, at least when called from the DistSender.
 	consistent := args.ReadConsistency != proto.INCONSISTENT
-	kvs, err := engine.MVCCScan(batch, nextKey, metaPrefix.PrefixEnd(), rangeCount, args.Timestamp, consistent, args.Txn)
+	kvs, err := engine.MVCCScan(batch, args.Key, endKey, rangeCount, args.Timestamp, consistent, args.Txn)
 	if err != nil {
 		reply.SetGoError(err)
 		return
@@ -1175,6 +2033,10 @@ func (r *Range) InternalRangeLookup(batch engine.Engine, args *proto.InternalRan
 
 	// The initial key must have the same metadata level prefix as we queried.
 	if len(kvs) == 0 {
+		if truncated {
+			// The next metadata record is stored in a subsequent range.
+			return
+		}
 		// At this point the range has been verified to contain the requested
 		// key, but no matching results were returned from the scan. This could
 		// indicate a very bad system error, but for now we will just treat it
@@ -1237,12 +2099,57 @@ func (r *Range) InternalHeartbeatTxn(batch engine.Engine, args *proto.InternalHe
 // specified in the args is persisted after GC.
 func (r *Range) InternalGC(batch engine.Engine, ms *engine.MVCCStats, args *proto.InternalGCRequest, reply *proto.InternalGCResponse) {
 	// Garbage collect the specified keys by expiration timestamps.
-	if err := engine.MVCCGarbageCollect(batch, ms, args.Keys, args.Timestamp); err != nil {
+	// Transaction records, response cache entries and the records of
+	// applied proposals are inline values, which MVCC garbage collection
+	// skips, and are removed separately once verified to have expired.
+	before := ms.KeyBytes + ms.ValBytes
+	gcKeys := make([]proto.InternalGCRequest_GCKey, 0, len(args.Keys))
+	for _, gcKey := range args.Keys {
+		var err error
+		if isTransactionKey(gcKey.Key) {
+			err = gcTxnRecord(batch, ms, gcKey)
+		} else if cmdID, rcErr := r.respCache.decodeResponseCacheKey(engine.MVCCEncodeKey(gcKey.Key)); rcErr == nil {
+			if cmdID.WallTime < gcKey.Timestamp.WallTime {
+				err = engine.MVCCDelete(batch, nil, gcKey.Key, proto.ZeroTimestamp, nil)
+			}
+		} else if proposalID, pErr := decodeAppliedProposalKey(engine.MVCCEncodeKey(gcKey.Key)); pErr == nil {
+			// Copies of a proposal made under an earlier lease are
+			// rejected regardless, so its record is no longer needed.
+			if r.isStaleProposal(proposalID) {
+				err = engine.MVCCDelete(batch, nil, gcKey.Key, proto.ZeroTimestamp, nil)
+			}
+		} else {
+			gcKeys = append(gcKeys, gcKey)
+		}
+		if err != nil {
+			reply.SetGoError(err)
+			return
+		}
+	}
+	if err := engine.MVCCGarbageCollect(batch, ms, gcKeys, args.Timestamp); err != nil {
 		reply.SetGoError(err)
 		return
 	}
+	// Suggest a compaction of the span of collected keys. Collection is
+	// what finally removes the data of spans deleted by DeleteRange, so
+	// the bytes collected are an estimate of what compaction reclaims.
+	if reclaimed := before - (ms.KeyBytes + ms.ValBytes); reclaimed > 0 && len(args.Keys) > 0 {
+		start, end := args.Keys[0].Key, args.Keys[0].Key
+		for _, gcKey := range args.Keys[1:] {
+			if gcKey.Key.Less(start) {
+				start = gcKey.Key
+			} else if end.Less(gcKey.Key) {
+				end = gcKey.Key
+			}
+		}
+		r.rm.Compactor().suggestBytesOnCommit(batch, engine.MVCCEncodeKey(start), engine.MVCCEncodeKey(end.Next()), reclaimed)
+	}
 
-	// Store the GC metadata for this range.
+	// Store the GC metadata for this range, if supplied. Only the final
+	// request of a GC scan carries it.
+	if args.GCMeta.LastScanNanos == 0 {
+		return
+	}
 	key := engine.RangeGCMetadataKey(r.Desc().RaftID)
 	err := engine.MVCCPutProto(batch, ms, key, proto.ZeroTimestamp, nil, &args.GCMeta)
 	reply.SetGoError(err)
@@ -1341,9 +2248,13 @@ func (r *Range) InternalPushTxn(batch engine.Engine, args *proto.InternalPushTxn
 	// If there's no incoming transaction, the pusher is
 	// non-transactional. We make a random priority, biased by
 	// specified args.Header().UserPriority in this case.
+	// The pusher's timestamp breaks ties between equal priorities;
+	// non-transactional pushers use the request timestamp.
 	var priority int32
+	pusherTS := args.Timestamp
 	if args.Txn != nil {
 		priority = args.Txn.Priority
+		pusherTS = args.Txn.Timestamp
 	} else {
 		priority = proto.MakePriority(args.GetUserPriority())
 	}
@@ -1363,7 +2274,7 @@ func (r *Range) InternalPushTxn(batch engine.Engine, args *proto.InternalPushTxn
 		log.V(1).Infof("pushing intent from previous epoch for txn %s", reply.PusheeTxn)
 		pusherWins = true
 	} else if reply.PusheeTxn.Priority < priority ||
-		(reply.PusheeTxn.Priority == priority && args.Txn.Timestamp.Less(reply.PusheeTxn.Timestamp)) {
+		(reply.PusheeTxn.Priority == priority && pusherTS.Less(reply.PusheeTxn.Timestamp)) {
 		// Finally, choose based on priority; if priorities are equal, order by lower txn timestamp.
 		log.V(1).Infof("pushing intent from txn with lower priority %s vs %d", reply.PusheeTxn, priority)
 		pusherWins = true
@@ -1406,7 +2317,24 @@ func (r *Range) InternalResolveIntent(batch engine.Engine, ms *engine.MVCCStats,
 		reply.SetGoError(util.Errorf("no transaction specified to InternalResolveIntent"))
 		return
 	}
-	if len(args.EndKey) == 0 || bytes.Equal(args.Key, args.EndKey) {
+	if len(args.Keys) > 0 {
+		// Resolve the listed keys which lie within the request's key
+		// range, all in the command's batch. A request split by range
+		// carries the full list to each range it reaches.
+		endKey := args.EndKey
+		if len(endKey) == 0 {
+			endKey = args.Key.Next()
+		}
+		for _, key := range args.Keys {
+			if key.Less(args.Key) || !key.Less(endKey) {
+				continue
+			}
+			if err := engine.MVCCResolveWriteIntent(batch, ms, key, args.Timestamp, args.Txn); err != nil {
+				reply.SetGoError(err)
+				return
+			}
+		}
+	} else if len(args.EndKey) == 0 || bytes.Equal(args.Key, args.EndKey) {
 		reply.SetGoError(engine.MVCCResolveWriteIntent(batch, ms, args.Key, args.Timestamp, args.Txn))
 	} else {
 		_, err := engine.MVCCResolveWriteIntentRange(batch, ms, args.Key, args.EndKey, 0, args.Timestamp, args.Txn)
@@ -1432,9 +2360,10 @@ func (r *Range) InternalTruncateLog(batch engine.Engine, ms *engine.MVCCStats, a
 		reply.SetGoError(err)
 		return
 	}
-	start := engine.RaftLogKey(r.Desc().RaftID, args.Index).Next()
-	end := engine.RaftLogKey(r.Desc().RaftID, 0)
-	err = batch.Iterate(engine.MVCCEncodeKey(start), engine.MVCCEncodeKey(end),
+	start := engine.MVCCEncodeKey(engine.RaftLogKey(r.Desc().RaftID, args.Index).Next())
+	end := engine.MVCCEncodeKey(engine.RaftLogKey(r.Desc().RaftID, 0))
+	r.rm.Compactor().suggestOnCommit(batch, start, end)
+	err = engine.Iterate(batch, start, end,
 		func(kv proto.RawKeyValue) (bool, error) {
 			err := batch.Clear(kv.Key)
 			return false, err
@@ -1452,18 +2381,184 @@ func (r *Range) InternalTruncateLog(batch engine.Engine, ms *engine.MVCCStats, a
 	reply.SetGoError(err)
 }
 
-// InternalLeaderLease evaluates and responds to a request to grant a leader lease.
-func (r *Range) InternalLeaderLease(args *proto.InternalLeaderLeaseRequest, reply *proto.InternalLeaderLeaseResponse) {
-	// TODO(tschottdorf)
-	// r.grantLeaderLease(args.Lease)
+// InternalRecomputeStats recomputes the range's MVCC stats from a scan
+// of its data and adds the difference from the persisted stats to ms,
+// correcting drift accumulated by incremental updates. The command is
+// applied through Raft, so each replica scans a consistent view of its
+// data and corrects its own stats. The correction is returned in the
+// reply.
+func (r *Range) InternalRecomputeStats(batch engine.Engine, ms *engine.MVCCStats, args *proto.InternalRecomputeStatsRequest, reply *proto.InternalRecomputeStatsResponse) {
+	desc := r.Desc()
+	nowNanos := args.Timestamp.WallTime
+	actual, err := engine.MVCCComputeStats(batch, desc.StartKey, desc.EndKey, nowNanos)
+	if err != nil {
+		reply.SetGoError(err)
+		return
+	}
+	// Compare against the persisted stats aged to nowNanos, as the ages
+	// are advanced to the command's timestamp when ms is merged.
+	delta := actual
+	delta.Subtract(r.stats.GetAgedMVCC(nowNanos))
+	delta.LastUpdateNanos = 0
+	ms.Accumulate(delta)
+
+	reply.LiveBytes = delta.LiveBytes
+	reply.KeyBytes = delta.KeyBytes
+	reply.ValBytes = delta.ValBytes
+	reply.IntentBytes = delta.IntentBytes
+	reply.LiveCount = delta.LiveCount
+	reply.KeyCount = delta.KeyCount
+	reply.ValCount = delta.ValCount
+	reply.IntentCount = delta.IntentCount
+	reply.IntentAge = delta.IntentAge
+	reply.GCBytesAge = delta.GCBytesAge
+	if delta != (engine.MVCCStats{}) {
+		log.Warningf("range %d: corrected stats drift of %+v", desc.RaftID, delta)
+	}
+}
+
+// InternalExport writes the key/value pairs in the span [args.Key,
+// args.EndKey) as of args.Timestamp to the named file in the store's
+// export sink. As a read-only command, it's served by the leader
+// and is consistent: intents in the span must be resolved and later
+// writes in the span are pushed above args.Timestamp via the
+// timestamp cache. The size and checksum of the file are returned.
+func (r *Range) InternalExport(batch engine.Engine, args *proto.InternalExportRequest, reply *proto.InternalExportResponse) {
+	sink := r.rm.Exports()
+	if sink == nil {
+		reply.SetGoError(util.Errorf("no export sink configured for store %d", r.rm.StoreID()))
+		return
+	}
+	f, err := sink.Create(args.Name)
+	if err != nil {
+		reply.SetGoError(err)
+		return
+	}
+	hash := sha256.New()
+	counter := &countingWriter{}
+	ew := newExportWriter(io.MultiWriter(f, hash, counter))
+	err = engine.MVCCIterate(batch, args.Key, args.EndKey, 0, args.Timestamp, true, args.Txn,
+		func(kv proto.KeyValue) (bool, error) {
+			reply.KeyCount++
+			return false, ew.add(&kv)
+		})
+	if err == nil {
+		err = ew.flush()
+	}
+	if err != nil {
+		f.Abort()
+		reply.SetGoError(err)
+		return
+	}
+	if err := f.Close(); err != nil {
+		reply.SetGoError(err)
+		return
+	}
+	reply.DataSize = counter.n
+	reply.Sha256 = hash.Sum(nil)
 }
 
-// requestLeaderLease sends a request to obtain or extend a leader lease for this
-// replica.
-func (r *Range) requestLeaderLease(term uint64) {
-	// Prepare a Raft command to get a leader lease for the replica
-	// of that group that lives in our store.
-	wallTime := r.rm.Clock().PhysicalNow()
+// InternalImport writes the key/value pairs of args.Rows at the
+// timestamps of their values, so that imported data keeps the history
+// it was exported with. If args.Clear is set, all versions of all keys
+// in the span [args.Key, args.EndKey) are first removed, along with
+// any intents; the span must not be in use. The number of key/value
+// pairs written is returned.
+//
+// Like other writes, an import is ordered by the timestamp of its
+// header: it's pushed above reads of the span in the timestamp cache
+// and above the closed timestamp, and is recorded in the timestamp
+// cache as a write to the whole span. Rows may therefore not be newer
+// than the import. Reads of the span at earlier timestamps which were
+// served before the import aren't repeatable, which is why the span
+// must not be in use.
+func (r *Range) InternalImport(batch engine.Engine, ms *engine.MVCCStats, args *proto.InternalImportRequest, reply *proto.InternalImportResponse) {
+	if args.Clear {
+		cleared, err := engine.MVCCComputeStats(batch, args.Key, args.EndKey, args.Timestamp.WallTime)
+		if err != nil {
+			reply.SetGoError(err)
+			return
+		}
+		cleared.LastUpdateNanos = 0
+		ms.Subtract(cleared)
+		if _, err := engine.ClearRange(batch, engine.MVCCEncodeKey(args.Key), engine.MVCCEncodeKey(args.EndKey)); err != nil {
+			reply.SetGoError(err)
+			return
+		}
+	}
+	for _, kv := range args.Rows {
+		if kv.Key.Less(args.Key) || !kv.Key.Less(args.EndKey) {
+			reply.SetGoError(util.Errorf("key %q is outside of span %q-%q", kv.Key, args.Key, args.EndKey))
+			return
+		}
+		timestamp := proto.ZeroTimestamp
+		if kv.Value.Timestamp != nil {
+			timestamp = *kv.Value.Timestamp
+		}
+		if args.Timestamp.Less(timestamp) {
+			reply.SetGoError(util.Errorf("key %q at %s is newer than the import at %s", kv.Key, timestamp, args.Timestamp))
+			return
+		}
+		if err := engine.MVCCPut(batch, ms, kv.Key, timestamp, kv.Value, nil); err != nil {
+			reply.SetGoError(err)
+			return
+		}
+		reply.KeyCount++
+	}
+}
+
+// InternalLeaderLease evaluates and responds to a request to grant a
+// leader lease. The holder of an existing lease may always extend it;
+// other replicas may only obtain the lease once the previous lease has
+// expired, unless the holder is transferring the lease to them, in
+// which case the request names the lease it replaces. An epoch-based
+// lease has no expiration which every replica agrees on, so it's only
+// taken over by a request showing that the holder's liveness epoch has
+// been incremented past the lease's. Since leases are applied in Raft
+// log order, every replica reaches the same decision. On success, the
+// lease is persisted and installed after the batch commits.
+func (r *Range) InternalLeaderLease(batch engine.Engine, args *proto.InternalLeaderLeaseRequest, reply *proto.InternalLeaderLeaseResponse) {
+	prev := r.getLease()
+	if args.PrevLease != nil && (prev == nil || !leasesEqual(prev, args.PrevLease)) {
+		reply.SetGoError(util.Errorf("lease transfer failed: lease %s has been replaced by %s", args.PrevLease, prev))
+		return
+	}
+	// The closed timestamp never regresses, even as the lease changes
+	// hands.
+	if prev != nil {
+		args.Lease.ClosedTimestamp.Forward(prev.ClosedTimestamp)
+	}
+	// A new holder's tenure begins with its lease; an extension or a
+	// renewal by the same holder continues it.
+	if prev != nil && prev.RaftNodeID == args.Lease.RaftNodeID {
+		args.Lease.TenureStart = prev.TenureStart
+	} else {
+		args.Lease.TenureStart = args.Lease.Expiration - args.Lease.Duration
+	}
+	if prev != nil && prev.RaftNodeID != args.Lease.RaftNodeID && args.PrevLease == nil {
+		if prev.Epoch != 0 {
+			if args.PrevHolderEpoch <= prev.Epoch {
+				reply.SetGoError(&proto.NotLeaderError{Leader: r.leaseHolder(prev)})
+				return
+			}
+		} else if start := args.Lease.Expiration - args.Lease.Duration; start < prev.Expiration {
+			// The new lease begins Duration before its expiration.
+			reply.SetGoError(&proto.NotLeaderError{Leader: r.leaseHolder(prev)})
+			return
+		}
+	}
+	reply.SetGoError(engine.MVCCPutProto(batch, nil, engine.RangeLeaderLeaseKey(r.Desc().RaftID),
+		proto.ZeroTimestamp, nil, &args.Lease))
+}
+
+// newLeaderLeaseCmd creates a Raft command requesting a leader lease
+// for the replica of this range which lives in our store, beginning
+// at the supplied wall time. The lease is epoch-based if
+// livenessEpoch allows it. If this replica holds the lease, the
+// command closes timestamps up to closedTimestampLag before now; the
+// timestamp cache's low water mark is first forwarded to the closed
+// timestamp so that subsequent writes are pushed above it.
+func (r *Range) newLeaderLeaseCmd(term uint64, wallTime int64) (cmdIDKey, proto.InternalRaftCommand) {
 	// TODO: get this from configuration, either as a config flag
 	// or, later, dynamically adjusted.
 	duration := int64(defaultLeaderLeaseDuration)
@@ -1475,18 +2570,202 @@ func (r *Range) requestLeaderLease(term uint64) {
 		RaftID: r.Desc().RaftID,
 	}
 	args := &proto.InternalLeaderLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       r.Desc().StartKey,
+			Timestamp: r.rm.Clock().Now(),
+			RaftID:    r.Desc().RaftID,
+		},
 		Lease: proto.Lease{
 			Expiration: wallTime + duration,
 			Duration:   duration,
 			Term:       term,
 			RaftNodeID: uint64(r.rm.RaftNodeID()),
+			Epoch:      r.livenessEpoch(),
 		},
 	}
-
+	if r.HasLeaderLease() {
+		closed := args.Timestamp
+		closed.WallTime -= closedTimestampLag.Nanoseconds()
+		closed.Logical = 0
+		r.Lock()
+		r.tsCache.SetLowWater(closed)
+		r.Unlock()
+		args.Lease.ClosedTimestamp = closed
+	}
 	cmd.Cmd.SetValue(args)
+	return idKey, cmd
+}
+
+// leasesEqual returns true if the supplied leases are identical.
+func leasesEqual(a, b *proto.Lease) bool {
+	return a.Expiration == b.Expiration && a.Duration == b.Duration &&
+		a.Term == b.Term && a.RaftNodeID == b.RaftNodeID && a.Epoch == b.Epoch
+}
+
+// acquireLeaderLease proposes a leader lease for this replica and
+// blocks until the lease command has been applied to the range, has
+// failed to commit or has taken too long; see proposeLeaderLease. An
+// epoch-based lease held by another node is first invalidated by
+// incrementing that node's liveness epoch; if the node is still live,
+// a NotLeaderError naming the holder is returned.
+func (r *Range) acquireLeaderLease(term uint64) error {
+	var prevHolderEpoch int64
+	if prev := r.getLease(); prev != nil && prev.Epoch != 0 && prev.RaftNodeID != uint64(r.rm.RaftNodeID()) {
+		nodeID, _ := DecodeRaftNodeID(multiraft.NodeID(prev.RaftNodeID))
+		epoch, err := r.rm.Liveness().IncrementEpoch(nodeID, prev.Epoch)
+		if err != nil {
+			log.Infof("%s: unable to take over leader lease of node %d: %s", r, nodeID, err)
+			return &proto.NotLeaderError{Leader: r.leaseHolder(prev)}
+		}
+		prevHolderEpoch = epoch
+	}
+	// The new lease begins now, after the epoch was incremented and so
+	// after the previous holder's liveness had expired.
+	idKey, cmd := r.newLeaderLeaseCmd(term, r.rm.Clock().PhysicalNow())
+	cmd.Cmd.GetValue().(*proto.InternalLeaderLeaseRequest).PrevHolderEpoch = prevHolderEpoch
+	return r.proposeLeaderLease(idKey, cmd)
+}
+
+// pendingClientCmds returns the client command IDs of the commands
+// proposed by this replica which have yet to be applied.
+func (r *Range) pendingClientCmds() map[cmdIDKey]struct{} {
+	r.Lock()
+	defer r.Unlock()
+	pending := make(map[cmdIDKey]struct{}, len(r.pendingCmds))
+	for _, cmd := range r.pendingCmds {
+		if !cmd.cmdID.IsEmpty() {
+			pending[makeCmdIDKey(cmd.cmdID)] = struct{}{}
+		}
+	}
+	return pending
+}
 
+// proposeLeaderLease proposes the supplied lease command and blocks
+// until it has been applied to the range or has failed to commit. The
+// wait is bounded by the duration of the proposed lease, which would
+// have expired by the time a slower proposal is applied, and by the
+// stopper; a NotLeaderError is returned when it's cut short, so that
+// the client tries another replica.
+func (r *Range) proposeLeaderLease(idKey cmdIDKey, cmd proto.InternalRaftCommand) error {
+	pendingCmd := &pendingCmd{
+		Reply: &proto.InternalLeaderLeaseResponse{},
+		done:  make(chan error, 1),
+	}
+	r.Lock()
+	r.pendingCmds[idKey] = pendingCmd
+	r.Unlock()
+	abandon := func() {
+		r.Lock()
+		delete(r.pendingCmds, idKey)
+		r.Unlock()
+	}
+	duration := time.Duration(cmd.Cmd.GetValue().(*proto.InternalLeaderLeaseRequest).Lease.Duration)
+	timeout := time.After(duration)
+	select {
+	case err := <-r.rm.ProposeRaftCommand(idKey, cmd):
+		if err != nil {
+			abandon()
+			return err
+		}
+	case <-timeout:
+		abandon()
+		log.Warningf("%s: leader lease proposal did not commit within %s", r, duration)
+		return &proto.NotLeaderError{}
+	case <-r.shouldStop():
+		abandon()
+		return &proto.NotLeaderError{}
+	}
+	select {
+	case err := <-pendingCmd.done:
+		return err
+	case <-timeout:
+		abandon()
+		log.Warningf("%s: leader lease was not applied within %s", r, duration)
+		return &proto.NotLeaderError{}
+	case <-r.shouldStop():
+		abandon()
+		return &proto.NotLeaderError{}
+	}
+}
+
+// TransferLeaderLease transfers the leader lease held by this replica
+// to the target replica. Rather than beginning once the current lease
+// expires, the target's lease begins immediately after the current
+// time. Reads at earlier timestamps continue to be served by this
+// replica while all other commands are redirected to the target (see
+// checkLeaseTransfer), so reads are unavailable only for the time it
+// takes the target to apply its lease. Blocks until the new lease has
+// been applied or the transfer has failed. A successful transfer is
+// recorded in the range event log along with the supplied reason.
+func (r *Range) TransferLeaderLease(target proto.Replica, reason string) error {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	if !r.HasLeaderLease() {
+		return util.Errorf("cannot transfer leader lease of %s: lease is not held by this replica", r)
+	}
+	if _, replica := r.Desc().FindReplica(target.StoreID); replica == nil || replica.NodeID != target.NodeID {
+		return util.Errorf("cannot transfer leader lease of %s to %+v: not a replica of the range", r, target)
+	}
+	prev := r.getLease()
+	raftNodeID := MakeRaftNodeID(target.NodeID, target.StoreID)
+	if prev.RaftNodeID == uint64(raftNodeID) {
+		return nil
+	}
+	t := &leaseTransfer{
+		target:     target,
+		start:      r.rm.Clock().Now().WallTime + 1,
+		expiration: r.leaseExpiration(prev),
+	}
+
+	// From here on, new writes are redirected. Wait for the commands
+	// which were admitted under the current lease; reads issued from
+	// here on are served only at timestamps before the new lease starts,
+	// so they don't wait behind the transfer. The transfer is started
+	// under the lock which admits commands to the command queue (see
+	// beginCmd), so no write is admitted after the commands are
+	// collected.
+	r.Lock()
+	atomic.StorePointer(&r.transfer, unsafe.Pointer(t))
+	var wg sync.WaitGroup
+	r.cmdQ.GetWait(proto.KeyMin, proto.KeyMax, false, &wg)
+	r.Unlock()
+	wg.Wait()
+
+	idKey, cmd := r.newLeaderLeaseCmd(prev.Term, t.start)
+	args := cmd.Cmd.GetValue().(*proto.InternalLeaderLeaseRequest)
+	args.Lease.RaftNodeID = uint64(raftNodeID)
+	// The target's lease can't be tied to our liveness epoch; it becomes
+	// epoch-based when the target first extends it.
+	args.Lease.Epoch = 0
+	args.PrevLease = prev
+	if err := r.proposeLeaderLease(idKey, cmd); err != nil {
+		atomic.CompareAndSwapPointer(&r.transfer, unsafe.Pointer(t), nil)
+		return err
+	}
+	event := r.newRangeEvent(proto.LEASE_TRANSFERRED, reason)
+	event.Replica = target
+	r.logRangeEvent(event)
+	return nil
+}
+
+// AdminTransferLease transfers the leader lease to the range's replica
+// on the store args.TargetStoreID. It must be executed by the holder
+// of the lease; see TransferLeaderLease.
+func (r *Range) AdminTransferLease(args *proto.AdminTransferLeaseRequest, reply *proto.AdminTransferLeaseResponse) {
+	_, replica := r.Desc().FindReplica(args.TargetStoreID)
+	if replica == nil {
+		reply.SetGoError(util.Errorf("cannot transfer leader lease of %s to store %d: not a replica of the range",
+			r, args.TargetStoreID))
+		return
+	}
+	reply.SetGoError(r.TransferLeaderLease(*replica, ""))
+}
+
+// requestLeaderLease sends a request to obtain or extend a leader lease for this
+// replica without waiting for the result.
+func (r *Range) requestLeaderLease(term uint64) {
 	// Propose the Raft command.
-	errCh := r.rm.ProposeRaftCommand(idKey, cmd)
+	errCh := r.rm.ProposeRaftCommand(r.newLeaderLeaseCmd(term, r.rm.Clock().PhysicalNow()))
 
 	// Make sure we log a potential error from Raft.
 	r.stopper.RunWorker(func() {
@@ -1530,13 +2809,16 @@ func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger) err
 		return util.Errorf("unable to copy last verification timestamp: %s", err)
 	}
 
-	// Compute stats for updated range.
+	// Divide the range's stats between the updated and new ranges.
+	// Only the new range's keys are scanned; the updated range's stats
+	// are what remains of the original range's.
 	now := r.rm.Clock().Timestamp()
-	ms, err := engine.MVCCComputeStats(r.rm.Engine(), split.UpdatedDesc.StartKey, split.UpdatedDesc.EndKey, now.WallTime)
+	updatedMS, newMS, err := engine.MVCCSplitStats(r.rm.Engine(), r.stats.GetAgedMVCC(now.WallTime),
+		split.NewDesc.StartKey, split.NewDesc.EndKey, now.WallTime)
 	if err != nil {
-		return util.Errorf("unable to compute stats for updated range after split: %s", err)
+		return util.Errorf("unable to compute stats for ranges after split: %s", err)
 	}
-	r.stats.SetMVCCStats(batch, ms)
+	r.stats.SetMVCCStats(batch, updatedMS)
 
 	// Initialize the new range's response cache by copying the original's.
 	if err = r.respCache.CopyInto(batch, split.NewDesc.RaftID); err != nil {
@@ -1550,17 +2832,20 @@ func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger) err
 	if err != nil {
 		return err
 	}
-	// Compute stats for new range.
-	ms, err = engine.MVCCComputeStats(r.rm.Engine(), split.NewDesc.StartKey, split.NewDesc.EndKey, now.WallTime)
-	if err != nil {
-		return util.Errorf("unable to compute stats for new range after split: %s", err)
-	}
-	newRng.stats.SetMVCCStats(batch, ms)
+	newRng.stats.SetMVCCStats(batch, newMS)
 
-	// Copy the timestamp cache into the new range.
+	// Derive the timestamp caches of both ranges from the parent's so
+	// that reads and writes served before the split still push later
+	// writes on either side. The parent's cache is truncated only once
+	// the split commits; until then, it still serves the whole span.
 	r.Lock()
-	r.tsCache.MergeInto(newRng.tsCache, true /* clear */)
+	r.tsCache.CopyInto(newRng.tsCache, split.NewDesc.StartKey, split.NewDesc.EndKey)
 	r.Unlock()
+	onCommit(batch, func() {
+		r.Lock()
+		r.tsCache.Truncate(split.UpdatedDesc.StartKey, split.UpdatedDesc.EndKey)
+		r.Unlock()
+	})
 
 	return r.rm.SplitRange(r, newRng)
 }
@@ -1587,32 +2872,101 @@ func (r *Range) mergeTrigger(batch engine.Engine, merge *proto.MergeTrigger) err
 		return util.Errorf("unable to copy response cache to new split range: %s", err)
 	}
 
-	// Compute stats for updated range.
+	// Combine the stats of both ranges for the updated range, rather
+	// than recomputing them.
 	now := r.rm.Clock().Timestamp()
-	ms, err := engine.MVCCComputeStats(r.rm.Engine(), merge.UpdatedDesc.StartKey,
-		merge.UpdatedDesc.EndKey, now.WallTime)
-	if err != nil {
-		return util.Errorf("unable to compute stats for the range after merge: %s", err)
-	}
+	var subsumedMS engine.MVCCStats
+	if err := engine.MVCCGetRangeStats(batch, merge.SubsumedRaftID, &subsumedMS); err != nil {
+		return util.Errorf("unable to read stats of the subsumed range: %s", err)
+	}
+	subsumedMS.AgeTo(now.WallTime)
+	ms := r.stats.GetAgedMVCC(now.WallTime)
+	ms.Accumulate(subsumedMS)
+	ms.LastUpdateNanos = now.WallTime
 	r.stats.SetMVCCStats(batch, ms)
 
 	subsumedRng, err := r.rm.MergeRange(r, merge.UpdatedDesc.EndKey, merge.SubsumedRaftID)
-	if err == nil {
-		// Merge the timestamp caches from both ranges.
-		r.Lock()
-		subsumedRng.tsCache.MergeInto(r.tsCache, false /* clear */)
-		r.Unlock()
+	if err != nil {
+		return err
 	}
-	return err
+	// Merge the timestamp caches from both ranges.
+	r.Lock()
+	subsumedRng.tsCache.MergeInto(r.tsCache, false /* clear */)
+	r.Unlock()
+
+	// Remove the subsumed range's range-local metadata and schedule a
+	// compaction of the vacated span.
+	start, end := engine.PrefixSpan(engine.RangeIDPrefix(merge.SubsumedRaftID))
+	r.rm.Compactor().suggestOnCommit(batch, start, end)
+	return engine.Iterate(batch, start, end, func(kv proto.RawKeyValue) (bool, error) {
+		return false, batch.Clear(kv.Key)
+	})
 }
 
 func (r *Range) changeReplicasTrigger(change *proto.ChangeReplicasTrigger) error {
 	copy := *r.Desc()
 	copy.Replicas = change.UpdatedReplicas
+	copy.NextReplicaID = nextReplicaID(&copy)
 	r.SetDesc(&copy)
 	return nil
 }
 
+// nextReplicaID returns the ID to assign to the next replica added to
+// the range. Descriptors written before replica IDs were assigned
+// don't record the next ID, which then follows the highest ID of the
+// range's replicas.
+func nextReplicaID(desc *proto.RangeDescriptor) int32 {
+	next := desc.NextReplicaID
+	for _, rep := range desc.Replicas {
+		if rep.ReplicaID >= next {
+			next = rep.ReplicaID + 1
+		}
+	}
+	return next
+}
+
+// sendPreemptiveSnapshot sends a snapshot of the range to the store
+// holding the supplied replica, which is not yet a member of the
+// range's Raft group. The replica is initialized from the snapshot and
+// catches up from the Raft log once the replica change commits. If
+// the change aborts, the receiving store destroys the range after
+// preemptiveSnapshotExpiration. The snapshot is generated only once
+// the store's snapshot queue admits it at priority pri. It carries the
+// ID assigned to the replica, which the descriptor doesn't yet
+// include, so that a store from which an earlier replica of the range
+// was removed admits it.
+func (r *Range) sendPreemptiveSnapshot(pri snapshotPriority, replica proto.Replica) error {
+	to := MakeRaftNodeID(replica.NodeID, replica.StoreID)
+	return r.rm.SendPreemptiveSnapshot(pri, to, func() (*multiraft.RaftMessageRequest, error) {
+		snap, err := r.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		var snapData proto.RaftSnapshotData
+		if err := gogoproto.Unmarshal(snap.Data, &snapData); err != nil {
+			return nil, err
+		}
+		snapData.ReplicaID = replica.ReplicaID
+		if snap.Data, err = gogoproto.Marshal(&snapData); err != nil {
+			return nil, err
+		}
+		hs, _, err := r.InitialState()
+		if err != nil {
+			return nil, err
+		}
+		return &multiraft.RaftMessageRequest{
+			GroupID: uint64(r.Desc().RaftID),
+			Message: raftpb.Message{
+				Type:     raftpb.MsgSnap,
+				From:     uint64(r.rm.RaftNodeID()),
+				To:       uint64(to),
+				Term:     hs.Term,
+				Snapshot: snap,
+			},
+		}, nil
+	})
+}
+
 // InitialState implements the raft.Storage interface.
 func (r *Range) InitialState() (raftpb.HardState, raftpb.ConfState, error) {
 	var hs raftpb.HardState
@@ -1820,10 +3174,20 @@ func (r *Range) Snapshot() (raftpb.Snapshot, error) {
 	} else if !ok {
 		return raftpb.Snapshot{}, util.Errorf("couldn't find range descriptor")
 	}
+	snapData.RangeDescriptor = desc
 
 	// Iterate over all the data in the range, including local-only data like
-	// the response cache.
+	// the response cache. The snapshot buffer is accounted with the memory
+	// watchdog, which may shed it under memory pressure.
+	res := util.DefaultMemoryWatchdog.Reserve(snapshotDesc{r})
+	defer res.Release()
 	for iter := newRangeDataIterator(r, snap); iter.Valid(); iter.Next() {
+		if err := res.Grow(int64(len(iter.Key()) + len(iter.Value()))); err != nil {
+			// Raft treats any other error from Snapshot as fatal; a
+			// shed snapshot is retried on a later heartbeat.
+			log.Warningf("%s: %s", r, err)
+			return raftpb.Snapshot{}, raft.ErrSnapshotTemporarilyUnavailable
+		}
 		snapData.KV = append(snapData.KV,
 			&proto.RaftSnapshotData_KeyValue{Key: iter.Key(), Value: iter.Value()})
 	}
@@ -1918,12 +3282,11 @@ func (r *Range) ApplySnapshot(snap raftpb.Snapshot) error {
 		}
 	}
 
-	// Read the updated range descriptor.
-	var desc proto.RangeDescriptor
-	if _, err := engine.MVCCGetProto(batch, engine.RangeDescriptorKey(r.Desc().StartKey),
-		r.rm.Clock().Now(), false, nil, &desc); err != nil {
-		return err
-	}
+	// The updated range descriptor is carried in the snapshot. A
+	// replica which is new to the range doesn't know its key span
+	// until now, so it can't look the descriptor up in the data.
+	desc := snapData.RangeDescriptor
+	wasInitialized := r.isInitialized()
 
 	if err := batch.Commit(); err != nil {
 		return err
@@ -1932,6 +3295,11 @@ func (r *Range) ApplySnapshot(snap raftpb.Snapshot) error {
 	// Save the descriptor and applied index to our member variables.
 	r.SetDesc(&desc)
 	atomic.StoreUint64(&r.appliedIndex, snap.Metadata.Index)
+	if !wasInitialized {
+		if err := r.rm.ProcessRangeDescriptorUpdate(r); err != nil {
+			return err
+		}
+	}
 
 	// TODO(bdarnell): extract the real last index.
 	// snap.Metadata.Index is the last applied index, but our snapshot may have given us
@@ -1955,6 +3323,14 @@ func (r *Range) SetHardState(st raftpb.HardState) error {
 // the reassigned key range is carried out seamlessly through a split trigger
 // carried out as part of the commit of that transaction.
 func (r *Range) AdminSplit(args *proto.AdminSplitRequest, reply *proto.AdminSplitResponse) {
+	// Wait for the store to admit the creation of the new range, before
+	// taking the metadata lock so that waiting doesn't hold up other
+	// changes to the range.
+	if !r.rm.RangeAdmission().admit(r.shouldStop()) {
+		reply.SetGoError(util.Errorf("split of range %d abandoned as store is stopping", r.Desc().RaftID))
+		return
+	}
+
 	// Only allow a single split per range at a time.
 	r.metaLock.Lock()
 	defer r.metaLock.Unlock()
@@ -2002,6 +3378,9 @@ func (r *Range) AdminSplit(args *proto.AdminSplitRequest, reply *proto.AdminSpli
 	log.Infof("initiating a split of range %d %s-%s at key %s", desc.RaftID,
 		proto.Key(desc.StartKey), proto.Key(desc.EndKey), splitKey)
 
+	event := r.newRangeEvent(proto.RANGE_SPLIT, args.Reason)
+	event.OtherRaftID = newDesc.RaftID
+
 	txnOpts := &client.TransactionOptions{
 		Name: fmt.Sprintf("split range %d at %s", desc.RaftID, splitKey),
 	}
@@ -2026,6 +3405,9 @@ func (r *Range) AdminSplit(args *proto.AdminSplitRequest, reply *proto.AdminSpli
 		if err := InsertRange(txn, newDesc.StartKey); err != nil {
 			return err
 		}
+		if err := prepareRangeEvent(txn, event); err != nil {
+			return err
+		}
 		// End the transaction manually, instead of letting RunTransaction
 		// loop do it, in order to provide a split trigger.
 		return txn.Call(proto.EndTransaction, &proto.EndTransactionRequest{
@@ -2119,6 +3501,9 @@ func (r *Range) AdminMerge(args *proto.AdminMergeRequest, reply *proto.AdminMerg
 		subsumedDesc.RaftID, proto.Key(subsumedDesc.StartKey), proto.Key(subsumedDesc.EndKey),
 		desc.RaftID, desc.StartKey, desc.EndKey)
 
+	event := r.newRangeEvent(proto.RANGE_MERGE, "")
+	event.OtherRaftID = subsumedDesc.RaftID
+
 	txnOpts := &client.TransactionOptions{
 		Name: fmt.Sprintf("merge range %d into %d", subsumedDesc.RaftID, desc.RaftID),
 	}
@@ -2138,6 +3523,9 @@ func (r *Range) AdminMerge(args *proto.AdminMergeRequest, reply *proto.AdminMerg
 		if err := MergeRangeAddressing(txn, desc, &updatedDesc); err != nil {
 			return err
 		}
+		if err := prepareRangeEvent(txn, event); err != nil {
+			return err
+		}
 
 		// End the transaction manually instead of letting RunTransaction
 		// loop do it, in order to provide a merge trigger.
@@ -2158,10 +3546,25 @@ func (r *Range) AdminMerge(args *proto.AdminMergeRequest, reply *proto.AdminMerg
 	}
 }
 
+// InternalChangeReplicas adds or removes the replica specified in
+// args. It must be executed by the range leader; see ChangeReplicas.
+func (r *Range) InternalChangeReplicas(args *proto.InternalChangeReplicasRequest, reply *proto.InternalChangeReplicasResponse) {
+	reply.SetGoError(r.ChangeReplicas(args.ChangeType, args.Replica, ""))
+}
+
 // ChangeReplicas adds or removes a replica of a range. The change is performed
 // in a distributed transaction and takes effect when that transaction is committed.
 // When removing a replica, only the NodeID and StoreID fields of the Replica are used.
-func (r *Range) ChangeReplicas(changeType proto.ReplicaChangeType, replica proto.Replica) error {
+// The change is recorded in the range event log along with the supplied reason.
+// An added replica's preemptive snapshot is sent at rebalance priority.
+func (r *Range) ChangeReplicas(changeType proto.ReplicaChangeType, replica proto.Replica, reason string) error {
+	return r.changeReplicas(changeType, replica, reason, rebalanceSnapshot)
+}
+
+// changeReplicas is ChangeReplicas, sending an added replica's
+// preemptive snapshot at priority pri.
+func (r *Range) changeReplicas(changeType proto.ReplicaChangeType, replica proto.Replica, reason string,
+	pri snapshotPriority) error {
 	// Only allow a single change per range at a time.
 	r.metaLock.Lock()
 	defer r.metaLock.Unlock()
@@ -2177,12 +3580,25 @@ func (r *Range) ChangeReplicas(changeType proto.ReplicaChangeType, replica proto
 			break
 		}
 	}
+	updatedDesc.NextReplicaID = nextReplicaID(desc)
 	if changeType == proto.ADD_REPLICA {
 		if found != -1 {
 			return util.Errorf("adding replica %v which is already present in range %d",
 				replica, desc.RaftID)
 		}
+		replica.ReplicaID = updatedDesc.NextReplicaID
+		updatedDesc.NextReplicaID++
 		updatedDesc.Replicas = append(updatedDesc.Replicas, replica)
+		// Send the new replica its data before it joins the Raft group.
+		// Once the change commits the replica counts towards quorum;
+		// without a preemptive snapshot it would be unable to
+		// acknowledge writes until Raft got around to sending one. The
+		// target store admits the creation of the replica as it
+		// receives the snapshot (see admittingTransport).
+		if err := r.sendPreemptiveSnapshot(pri, replica); err != nil {
+			return util.Errorf("preemptive snapshot for %v in range %d failed: %s",
+				replica, desc.RaftID, err)
+		}
 	} else if changeType == proto.REMOVE_REPLICA {
 		if found == -1 {
 			return util.Errorf("removing replica %v which is not present in range %d",
@@ -2192,6 +3608,13 @@ func (r *Range) ChangeReplicas(changeType proto.ReplicaChangeType, replica proto
 		updatedDesc.Replicas = updatedDesc.Replicas[:len(updatedDesc.Replicas)-1]
 	}
 
+	eventType := proto.REPLICA_ADDED
+	if changeType == proto.REMOVE_REPLICA {
+		eventType = proto.REPLICA_REMOVED
+	}
+	event := r.newRangeEvent(eventType, reason)
+	event.Replica = replica
+
 	txnOpts := &client.TransactionOptions{
 		Name: fmt.Sprintf("change replicas of %d", desc.RaftID),
 	}
@@ -2203,7 +3626,14 @@ func (r *Range) ChangeReplicas(changeType proto.ReplicaChangeType, replica proto
 			return err
 		}
 
-		// TODO(bdarnell): call UpdateRangeAddressing
+		// Update the addressing records so that lookups see the new
+		// replica set once the transaction commits.
+		if err := UpdateRangeAddressing(txn, &updatedDesc); err != nil {
+			return err
+		}
+		if err := prepareRangeEvent(txn, event); err != nil {
+			return err
+		}
 
 		// End the transaction manually instead of letting RunTransaction
 		// loop do it, in order to provide a commit trigger.
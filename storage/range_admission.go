@@ -0,0 +1,121 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+// A rangeAdmission limits the rate at which a store creates ranges,
+// whether by splitting or by adding replicas. Bulk operations, such as
+// imports which pre-split thousands of ranges, are thereby kept from
+// starving foreground traffic on existing ranges of the resources
+// needed to apply splits and send snapshots. Splits are admitted by
+// the store of the range leader, which splits first; replicas are
+// admitted by the store on which they're created, as it receives
+// their initial snapshot.
+type rangeAdmission struct {
+	interval time.Duration // Minimum interval between admissions; 0 for no limit
+	waiting  int32         // Number of creations awaiting admission; updated atomically
+
+	mu   sync.Mutex
+	next time.Time // Earliest time at which the next creation is admitted
+}
+
+// newRangeAdmission returns a rangeAdmission which admits range
+// creations at most once per interval.
+func newRangeAdmission(interval time.Duration) *rangeAdmission {
+	return &rangeAdmission{interval: interval}
+}
+
+// admit blocks until a range creation is admitted. Creations are
+// admitted in the order in which they arrive. Returns false if stop
+// is closed first.
+func (ra *rangeAdmission) admit(stop <-chan struct{}) bool {
+	if ra.interval <= 0 {
+		return true
+	}
+	ra.mu.Lock()
+	now := util.Now()
+	at := ra.next
+	if at.Before(now) {
+		at = now
+	}
+	ra.next = at.Add(ra.interval)
+	ra.mu.Unlock()
+
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return true
+	}
+	atomic.AddInt32(&ra.waiting, 1)
+	defer atomic.AddInt32(&ra.waiting, -1)
+	timer := util.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// pending returns the number of range creations awaiting admission.
+func (ra *rangeAdmission) pending() int {
+	return int(atomic.LoadInt32(&ra.waiting))
+}
+
+// An admittingTransport is a multiraft.Transport which admits the
+// creation of replicas on its store as the snapshots initializing them
+// are received. The store's range admission is thereby applied to the
+// replicas created on it, whichever node adds them, and the range
+// adding a replica doesn't wait for admission while holding its locks.
+type admittingTransport struct {
+	multiraft.Transport
+	store *Store
+}
+
+// Listen implements the multiraft.Transport interface.
+func (t *admittingTransport) Listen(id multiraft.NodeID, server multiraft.ServerInterface) error {
+	return t.Transport.Listen(id, &admittingServer{ServerInterface: server, store: t.store})
+}
+
+// An admittingServer is a multiraft.ServerInterface which, before
+// passing on a snapshot which initializes a replica on the store,
+// waits for the store to admit the replica's creation. Each incoming
+// message is received on its own RPC goroutine, so that waiting
+// doesn't hold up Raft.
+type admittingServer struct {
+	multiraft.ServerInterface
+	store *Store
+}
+
+// RaftMessage implements the multiraft.ServerInterface interface.
+func (s *admittingServer) RaftMessage(req *multiraft.RaftMessageRequest,
+	resp *multiraft.RaftMessageResponse) error {
+	if req.Message.Type == raftpb.MsgSnap && !s.store.hasInitializedRange(int64(req.GroupID)) {
+		if !s.store.RangeAdmission().admit(s.store.stopper.ShouldStop()) {
+			return util.Errorf("snapshot of range %d abandoned as store is stopping", req.GroupID)
+		}
+	}
+	return s.ServerInterface.RaftMessage(req, resp)
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+// TestRangeAdmissionRate verifies that range creations are admitted
+// at most once per interval, and immediately without an interval.
+func TestRangeAdmissionRate(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ra := newRangeAdmission(0)
+	for i := 0; i < 10; i++ {
+		if !ra.admit(nil) {
+			t.Fatal("expected unlimited admission")
+		}
+	}
+
+	const interval = 20 * time.Millisecond
+	ra = newRangeAdmission(interval)
+	start := util.Now()
+	for i := 0; i < 3; i++ {
+		if !ra.admit(nil) {
+			t.Fatalf("%d: expected admission", i)
+		}
+	}
+	if elapsed := util.Now().Sub(start); elapsed < 2*interval {
+		t.Errorf("expected three admissions to take at least %s; took %s", 2*interval, elapsed)
+	}
+}
+
+// TestRangeAdmissionStop verifies that creations awaiting admission
+// are counted as pending and abandoned when stopping.
+func TestRangeAdmissionStop(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ra := newRangeAdmission(time.Hour)
+	if !ra.admit(nil) {
+		t.Fatal("expected first creation to be admitted immediately")
+	}
+	stop := make(chan struct{})
+	admitted := make(chan bool)
+	go func() {
+		admitted <- ra.admit(stop)
+	}()
+	if err := util.IsTrueWithin(func() bool { return ra.pending() == 1 }, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	if <-admitted {
+		t.Error("expected creation to be abandoned on stop")
+	}
+	if n := ra.pending(); n != 0 {
+		t.Errorf("expected no pending creations; got %d", n)
+	}
+}
+
+// recordingServer is a multiraft.ServerInterface which records the
+// groups of the messages it receives.
+type recordingServer chan uint64
+
+func (rs recordingServer) RaftMessage(req *multiraft.RaftMessageRequest,
+	resp *multiraft.RaftMessageResponse) error {
+	rs <- req.GroupID
+	return nil
+}
+
+// TestAdmittingServer verifies that the snapshots which initialize
+// replicas on a store await the store's admission, while other
+// messages are passed on immediately.
+func TestAdmittingServer(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	store.rangeAdmission = newRangeAdmission(time.Hour)
+	if !store.rangeAdmission.admit(nil) {
+		t.Fatal("expected first creation to be admitted immediately")
+	}
+	received := make(recordingServer, 3)
+	server := &admittingServer{ServerInterface: received, store: store}
+	send := func(groupID uint64, msgType raftpb.MessageType) error {
+		req := &multiraft.RaftMessageRequest{GroupID: groupID, Message: raftpb.Message{Type: msgType}}
+		return server.RaftMessage(req, &multiraft.RaftMessageResponse{})
+	}
+
+	// A snapshot of a new replica waits for admission.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- send(100, raftpb.MsgSnap)
+	}()
+	if err := util.IsTrueWithin(func() bool { return store.rangeAdmission.pending() == 1 }, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// Snapshots of initialized replicas and other messages don't.
+	if err := send(1, raftpb.MsgSnap); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(100, raftpb.MsgApp); err != nil {
+		t.Fatal(err)
+	}
+	if groupID := <-received; groupID != 1 {
+		t.Errorf("expected snapshot of range 1 to be passed on first; got range %d", groupID)
+	}
+	if groupID := <-received; groupID != 100 {
+		t.Errorf("expected message of range 100; got range %d", groupID)
+	}
+
+	// The waiting snapshot is abandoned when the store stops.
+	stopper.Stop()
+	if err := <-errCh; err == nil {
+		t.Error("expected waiting snapshot to be abandoned")
+	}
+	if len(received) != 0 {
+		t.Errorf("expected abandoned snapshot not to be passed on")
+	}
+}
@@ -20,7 +20,6 @@ package storage
 import (
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage/engine"
-	"github.com/cockroachdb/cockroach/util/encoding"
 )
 
 // keyRange is a helper struct for the rangeDataIterator.
@@ -28,6 +27,40 @@ type keyRange struct {
 	start, end proto.EncodedKey
 }
 
+// makeRangeIDLocalKeyRange returns the key range holding the
+// range-local data keyed by the range's Raft ID, such as its Raft
+// state, response cache and stats.
+func makeRangeIDLocalKeyRange(d *proto.RangeDescriptor) keyRange {
+	start, end := engine.PrefixSpan(engine.RangeIDPrefix(d.RaftID))
+	return keyRange{start: start, end: end}
+}
+
+// makeRangeLocalKeyRange returns the key range holding the
+// range-local data keyed by keys within the range, such as its range
+// descriptor and transaction records.
+func makeRangeLocalKeyRange(d *proto.RangeDescriptor) keyRange {
+	return keyRange{
+		start: engine.MVCCEncodeKey(engine.RangeKeyPrefix(d.StartKey)),
+		end:   engine.MVCCEncodeKey(engine.RangeKeyPrefix(d.EndKey)),
+	}
+}
+
+// makeUserKeyRange returns the key range holding the range's user
+// data.
+func makeUserKeyRange(d *proto.RangeDescriptor) keyRange {
+	// The first range in the keyspace starts at KeyMin, which includes
+	// the node-local space. We need the original StartKey to find the
+	// range metadata, but the actual data starts at KeyLocalMax.
+	dataStartKey := d.StartKey
+	if d.StartKey.Equal(engine.KeyMin) {
+		dataStartKey = engine.KeyLocalMax
+	}
+	return keyRange{
+		start: engine.MVCCEncodeKey(dataStartKey),
+		end:   engine.MVCCEncodeKey(d.EndKey),
+	}
+}
+
 // rangeDataIterator provides a complete iteration over all key / value
 // rows in a range, including all system-local metadata and user data.
 // The ranges keyRange slice specifies the key ranges which comprise
@@ -43,32 +76,20 @@ type rangeDataIterator struct {
 
 func newRangeDataIterator(r *Range, e engine.Engine) *rangeDataIterator {
 	r.RLock()
-	startKey := r.Desc().StartKey
-	endKey := r.Desc().EndKey
+	desc := r.Desc()
 	r.RUnlock()
-	// The first range in the keyspace starts at KeyMin, which includes the node-local
-	// space. We need the original StartKey to find the range metadata, but the
-	// actual data starts at KeyLocalMax.
-	dataStartKey := startKey
-	if startKey.Equal(engine.KeyMin) {
-		dataStartKey = engine.KeyLocalMax
-	}
+	return newKeyRangeIterator(e, makeRangeIDLocalKeyRange(desc),
+		makeRangeLocalKeyRange(desc), makeUserKeyRange(desc))
+}
+
+// newKeyRangeIterator returns an iterator over the supplied key
+// ranges, which must be ordered and non-overlapping. It allows
+// callers to iterate a subset of a range's data, such as only its
+// range-local data.
+func newKeyRangeIterator(e engine.Engine, ranges ...keyRange) *rangeDataIterator {
 	ri := &rangeDataIterator{
-		ranges: []keyRange{
-			{
-				start: engine.MVCCEncodeKey(engine.MakeKey(engine.KeyLocalRangeIDPrefix, encoding.EncodeUvarint(nil, uint64(r.Desc().RaftID)))),
-				end:   engine.MVCCEncodeKey(engine.MakeKey(engine.KeyLocalRangeIDPrefix, encoding.EncodeUvarint(nil, uint64(r.Desc().RaftID+1)))),
-			},
-			{
-				start: engine.MVCCEncodeKey(engine.MakeKey(engine.KeyLocalRangeKeyPrefix, encoding.EncodeBytes(nil, startKey))),
-				end:   engine.MVCCEncodeKey(engine.MakeKey(engine.KeyLocalRangeKeyPrefix, encoding.EncodeBytes(nil, endKey))),
-			},
-			{
-				start: engine.MVCCEncodeKey(dataStartKey),
-				end:   engine.MVCCEncodeKey(endKey),
-			},
-		},
-		iter: e.NewIterator(),
+		ranges: ranges,
+		iter:   e.NewIterator(),
 	}
 	ri.iter.Seek(ri.ranges[ri.curIndex].start)
 	ri.advance()
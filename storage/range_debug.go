@@ -0,0 +1,104 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// A RangeDebugInfo is a snapshot of the in-memory state of a range
+// replica, for diagnosing ranges which fail to make progress.
+type RangeDebugInfo struct {
+	StoreID  proto.StoreID   `json:"storeID"`
+	RaftID   int64           `json:"raftID"`
+	StartKey proto.Key       `json:"startKey"`
+	EndKey   proto.Key       `json:"endKey"`
+	Replicas []proto.Replica `json:"replicas"`
+	// LastIndex is the index of the last entry in the replica's Raft
+	// log and AppliedIndex that of the last entry applied.
+	LastIndex    uint64 `json:"lastIndex"`
+	AppliedIndex uint64 `json:"appliedIndex"`
+	// PendingCmds is the number of commands proposed by the replica
+	// which have yet to be applied.
+	PendingCmds int `json:"pendingCmds"`
+	// CmdQueueLen is the number of executing commands in the command
+	// queue, which gate overlapping commands.
+	CmdQueueLen int `json:"cmdQueueLen"`
+	// Lease is the last leader lease applied by the replica, if any,
+	// and HasLeaderLease whether the replica holds it.
+	Lease          *proto.Lease `json:"lease"`
+	HasLeaderLease bool         `json:"hasLeaderLease"`
+	// TimestampCacheLowWater is the timestamp at or below which writes
+	// are pushed regardless of the keys read.
+	TimestampCacheLowWater proto.Timestamp `json:"timestampCacheLowWater"`
+}
+
+// DebugInfo returns a snapshot of the in-memory state of the range.
+func (r *Range) DebugInfo() *RangeDebugInfo {
+	desc := r.Desc()
+	info := &RangeDebugInfo{
+		StoreID:        r.rm.StoreID(),
+		RaftID:         desc.RaftID,
+		StartKey:       desc.StartKey,
+		EndKey:         desc.EndKey,
+		Replicas:       append([]proto.Replica(nil), desc.Replicas...),
+		LastIndex:      atomic.LoadUint64(&r.lastIndex),
+		AppliedIndex:   atomic.LoadUint64(&r.appliedIndex),
+		Lease:          r.LeaderLease(),
+		HasLeaderLease: r.HasLeaderLease(),
+	}
+	r.RLock()
+	info.PendingCmds = len(r.pendingCmds)
+	info.CmdQueueLen = r.cmdQ.Len()
+	info.TimestampCacheLowWater = r.tsCache.LowWater()
+	r.RUnlock()
+	return info
+}
+
+// RangeDebugInfo returns a snapshot of the in-memory state of the
+// range with the specified Raft ID, or of all of the store's ranges,
+// ordered by Raft ID, if raftID is zero.
+func (s *Store) RangeDebugInfo(raftID int64) ([]*RangeDebugInfo, error) {
+	if raftID != 0 {
+		rng, err := s.GetRange(raftID)
+		if err != nil {
+			return nil, err
+		}
+		return []*RangeDebugInfo{rng.DebugInfo()}, nil
+	}
+	s.mu.RLock()
+	rngs := make([]*Range, 0, len(s.ranges))
+	for _, rng := range s.ranges {
+		rngs = append(rngs, rng)
+	}
+	s.mu.RUnlock()
+	infos := make([]*RangeDebugInfo, len(rngs))
+	for i, rng := range rngs {
+		infos[i] = rng.DebugInfo()
+	}
+	sort.Sort(rangeDebugInfosByRaftID(infos))
+	return infos, nil
+}
+
+// rangeDebugInfosByRaftID implements sort.Interface.
+type rangeDebugInfosByRaftID []*RangeDebugInfo
+
+func (s rangeDebugInfosByRaftID) Len() int           { return len(s) }
+func (s rangeDebugInfosByRaftID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s rangeDebugInfosByRaftID) Less(i, j int) bool { return s[i].RaftID < s[j].RaftID }
@@ -0,0 +1,61 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestStoreRangeDebugInfo verifies that the debug info of a range
+// reflects its applied commands and leader lease, and that the info of
+// missing ranges can't be retrieved.
+func TestStoreRangeDebugInfo(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := store.RangeDebugInfo(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected info of one range; got %+v", infos)
+	}
+	info := infos[0]
+	if info.StoreID != store.StoreID() || info.RaftID != 1 || len(info.Replicas) != 1 {
+		t.Errorf("unexpected range identity: %+v", info)
+	}
+	if info.AppliedIndex == 0 || info.AppliedIndex > info.LastIndex {
+		t.Errorf("expected applied index in (0, %d]; got %d", info.LastIndex, info.AppliedIndex)
+	}
+	if !info.HasLeaderLease || info.Lease == nil {
+		t.Errorf("expected range to hold its leader lease: %+v", info)
+	}
+	if info.PendingCmds != 0 || info.CmdQueueLen != 0 {
+		t.Errorf("expected no pending commands: %+v", info)
+	}
+
+	if _, err := store.RangeDebugInfo(2); err == nil {
+		t.Error("expected retrieval of missing range to fail")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// newRangeEvent returns an event of the specified type on this range,
+// initiated by this replica's store at the current time.
+func (r *Range) newRangeEvent(eventType proto.RangeEventType, reason string) *proto.RangeEvent {
+	return &proto.RangeEvent{
+		Timestamp: r.rm.Clock().PhysicalNow(),
+		RaftID:    r.Desc().RaftID,
+		StoreID:   r.rm.StoreID(),
+		EventType: eventType,
+		Reason:    reason,
+	}
+}
+
+// prepareRangeEvent prepares the write of event to the range event
+// log as part of txn, so that the event is recorded if and only if
+// the transaction which carries it out commits.
+func prepareRangeEvent(txn *client.KV, event *proto.RangeEvent) error {
+	return txn.PreparePutProto(engine.RangeEventLogKey(event.Timestamp, event.RaftID), event)
+}
+
+// logRangeEvent writes event to the range event log. It's used for
+// events which aren't carried out by a transaction; as the log is
+// informational, failures are logged rather than returned.
+func (r *Range) logRangeEvent(event *proto.RangeEvent) {
+	if err := r.rm.DB().PutProto(engine.RangeEventLogKey(event.Timestamp, event.RaftID), event); err != nil {
+		log.Warningf("unable to record %s of range %d in range event log: %s", event.EventType, event.RaftID, err)
+	}
+}
+
+// ScanRangeEvents returns up to maxResults entries of the range event
+// log which occurred at or after the specified wall time, oldest
+// first. If maxResults is zero, all such entries are returned.
+func ScanRangeEvents(db *client.KV, since, maxResults int64) ([]proto.RangeEvent, error) {
+	start := engine.RangeEventLogKey(since, 0)
+	args := proto.ScanArgs(start, engine.KeyRangeEventLogPrefix.PrefixEnd(), maxResults)
+	reply := &proto.ScanResponse{}
+	if err := db.Call(proto.Scan, args, reply); err != nil {
+		return nil, err
+	}
+	events := make([]proto.RangeEvent, len(reply.Rows))
+	for i, kv := range reply.Rows {
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
@@ -19,10 +19,15 @@ package storage
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -144,6 +149,7 @@ func (tc *testContext) Start(t *testing.T) {
 			}
 		}
 		tc.store.db = client.NewKV(nil, &testSender{store: tc.store})
+		tc.store.db.User = UserRoot
 		if err := tc.store.Start(tc.stopper); err != nil {
 			t.Fatal(err)
 		}
@@ -274,6 +280,458 @@ func TestRangeCanService(t *testing.T) {
 	}
 }
 
+// TestRangeLeaderLease verifies that a range acquires the leader lease
+// on demand, redirects consistent commands while another replica holds
+// an unexpired lease and reacquires the lease once it has expired.
+func TestRangeLeaderLease(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	// A write acquires the lease for this replica.
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !tc.rng.HasLeaderLease() {
+		t.Fatal("expected range to hold leader lease")
+	}
+	lease := tc.rng.getLease()
+	leaseKey := engine.RangeLeaderLeaseKey(tc.rng.Desc().RaftID)
+	persisted := &proto.Lease{}
+	if ok, err := engine.MVCCGetProto(tc.engine, leaseKey, proto.ZeroTimestamp, true, nil, persisted); !ok || err != nil {
+		t.Fatalf("expected lease to be persisted: %t, %v", ok, err)
+	}
+	if !reflect.DeepEqual(lease, persisted) {
+		t.Errorf("expected persisted lease %+v; got %+v", lease, persisted)
+	}
+
+	// A lease request from another replica overlapping the current
+	// lease is rejected.
+	otherID := uint64(MakeRaftNodeID(2, 2))
+	lArgs := &proto.InternalLeaderLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       tc.rng.Desc().StartKey,
+			Timestamp: tc.clock.Now(),
+			RaftID:    tc.rng.Desc().RaftID,
+		},
+		Lease: proto.Lease{
+			Expiration: lease.Expiration + 1,
+			Duration:   int64(defaultLeaderLeaseDuration),
+			RaftNodeID: otherID,
+		},
+	}
+	if err := tc.rng.executeCmd(0, "", proto.InternalLeaderLease, lArgs, &proto.InternalLeaderLeaseResponse{}, nil); err == nil {
+		t.Fatal("expected overlapping lease request to be rejected")
+	}
+
+	// Install a lease held by another replica; consistent commands are
+	// redirected, while inconsistent reads are still served.
+	tc.rng.setLease(&proto.Lease{
+		Expiration: tc.manualClock.UnixNano() + int64(defaultLeaderLeaseDuration),
+		Duration:   int64(defaultLeaderLeaseDuration),
+		RaftNodeID: otherID,
+	})
+	if tc.rng.HasLeaderLease() {
+		t.Fatal("expected range not to hold leader lease")
+	}
+	gArgs, gReply := getArgs(proto.Key("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err == nil {
+		t.Fatal("expected consistent read to be redirected")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	}
+	gArgs.ReadConsistency = proto.INCONSISTENT
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatalf("expected inconsistent read to succeed: %s", err)
+	}
+
+	// Once the other lease expires, the lease is reacquired.
+	tc.manualClock.Increment(int64(2 * defaultLeaderLeaseDuration))
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !tc.rng.HasLeaderLease() {
+		t.Fatal("expected range to reacquire leader lease")
+	}
+}
+
+// TestRangeEpochLeaderLease verifies that a range outside the system
+// keyspace acquires an epoch-based leader lease, which is held for as
+// long as the node is live, and that another replica may take over the
+// lease only once it has incremented the holder's epoch.
+func TestRangeEpochLeaderLease(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	threshold := 10 * defaultLeaderLeaseDuration
+	nl := NewNodeLiveness(tc.store.DB(), nil, tc.clock, threshold)
+	tc.store.NodeLiveness = nl
+	if err := nl.Heartbeat(1); err != nil {
+		t.Fatal(err)
+	}
+	if l := tc.rng.getLease(); l == nil || l.Epoch != 0 {
+		t.Fatalf("expected an expiration-based lease on the system range; got %+v", l)
+	}
+
+	newRng := splitTestRange(tc.store, engine.KeyMin, proto.Key("a"), t)
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), newRng.Desc().RaftID, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := newRng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	lease := newRng.getLease()
+	if lease == nil || lease.Epoch != 1 {
+		t.Fatalf("expected an epoch-based lease at epoch 1; got %+v", lease)
+	}
+
+	// The lease outlives its nominal expiration while the node is live.
+	tc.manualClock.Set(lease.Expiration + int64(defaultLeaderLeaseDuration))
+	if !newRng.HasLeaderLease() {
+		t.Fatal("expected epoch-based lease to be held while the node is live")
+	}
+
+	// Another replica can't take over the lease without incrementing
+	// the holder's epoch, which fails while the holder is live.
+	lArgs := &proto.InternalLeaderLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       newRng.Desc().StartKey,
+			Timestamp: tc.clock.Now(),
+			RaftID:    newRng.Desc().RaftID,
+		},
+		Lease: proto.Lease{
+			Expiration: tc.manualClock.UnixNano() + int64(defaultLeaderLeaseDuration),
+			Duration:   int64(defaultLeaderLeaseDuration),
+			RaftNodeID: uint64(MakeRaftNodeID(2, 2)),
+		},
+		PrevHolderEpoch: lease.Epoch,
+	}
+	if err := newRng.executeCmd(0, "", proto.InternalLeaderLease, lArgs, &proto.InternalLeaderLeaseResponse{}, nil); err == nil {
+		t.Fatal("expected lease request without an incremented epoch to be rejected")
+	}
+	if _, err := nl.IncrementEpoch(1, lease.Epoch); err == nil {
+		t.Fatal("expected increment of a live node's epoch to fail")
+	}
+
+	// Once the node's liveness expires, its epoch is incremented, which
+	// invalidates the lease, and the lease may be taken over.
+	lr, _ := nl.GetLiveness(1)
+	tc.manualClock.Set(lr.Expiration)
+	epoch, err := nl.IncrementEpoch(1, lease.Epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRng.HasLeaderLease() {
+		t.Fatal("expected lease not to be held after the epoch was incremented")
+	}
+	lArgs.Timestamp = tc.clock.Now()
+	lArgs.Lease.Expiration = tc.manualClock.UnixNano() + int64(defaultLeaderLeaseDuration)
+	lArgs.PrevHolderEpoch = epoch
+	if err := newRng.executeCmd(0, "", proto.InternalLeaderLease, lArgs, &proto.InternalLeaderLeaseResponse{}, nil); err != nil {
+		t.Fatalf("expected lease request with an incremented epoch to succeed: %s", err)
+	}
+}
+
+// TestRangeInvalidateLeaderLease verifies that an invalidated leader
+// lease is no longer served under but remains the range's replicated
+// lease until this replica acquires a new one.
+func TestRangeInvalidateLeaderLease(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	lease := tc.rng.getLease()
+	tc.rng.invalidateLeaderLease()
+	if tc.rng.HasLeaderLease() {
+		t.Fatal("expected invalidated lease not to be held")
+	}
+	if l := tc.rng.getLease(); l != lease {
+		t.Fatalf("expected lease %+v to remain in place; got %+v", lease, l)
+	}
+
+	// The next write acquires a new lease.
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !tc.rng.HasLeaderLease() {
+		t.Fatal("expected range to reacquire leader lease")
+	}
+	if l := tc.rng.getLease(); l == lease {
+		t.Fatal("expected a new lease")
+	}
+}
+
+// TestRangeLeaderLeaseMaxOffset verifies that a leader lease is no
+// longer held once the local clock comes within MaxOffset of its
+// expiration.
+func TestRangeLeaderLeaseMaxOffset(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	maxOffset := 100 * time.Millisecond
+	tc.clock.SetMaxOffset(maxOffset)
+
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	lease := tc.rng.getLease()
+	tc.manualClock.Set(lease.Expiration - maxOffset.Nanoseconds() - 1)
+	if !tc.rng.HasLeaderLease() {
+		t.Fatal("expected lease to be held before the MaxOffset margin")
+	}
+	tc.manualClock.Increment(1)
+	if tc.rng.HasLeaderLease() {
+		t.Fatal("expected lease not to be held within MaxOffset of its expiration")
+	}
+}
+
+// TestRangeLeaseCoversReads verifies that reads are only served
+// locally at timestamps covered by the leader lease, that a change of
+// lease holder forwards the timestamp cache to the start of the new
+// lease and that reads are then redirected to the new holder.
+func TestRangeLeaseCoversReads(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	// A read within the lease is served.
+	gArgs, gReply := getArgs(proto.Key("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatal(err)
+	}
+	lease := tc.rng.getLease()
+	if lease == nil {
+		t.Fatal("expected range to hold leader lease")
+	}
+
+	// A read beyond the lease, even once extended, is rejected.
+	gArgs.Timestamp = proto.Timestamp{WallTime: lease.Expiration + 2*lease.Duration}
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err == nil {
+		t.Fatal("expected read beyond leader lease to fail")
+	}
+
+	// The rejected read must not have left its command in the queue;
+	// a subsequent write to the same key would block otherwise.
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Install a lease for another replica beginning after the current
+	// lease; the timestamp cache must be forwarded to its start.
+	lease = tc.rng.getLease()
+	otherLease := &proto.Lease{
+		Expiration: lease.Expiration + lease.Duration,
+		Duration:   lease.Duration,
+		RaftNodeID: uint64(MakeRaftNodeID(2, 2)),
+	}
+	tc.rng.installLeaderLease(otherLease)
+	tc.rng.Lock()
+	rTS, wTS := tc.rng.tsCache.GetMax(proto.Key("z"), nil, proto.NoTxnMD5)
+	tc.rng.Unlock()
+	if rTS.WallTime != lease.Expiration || wTS.WallTime != lease.Expiration {
+		t.Errorf("expected timestamp cache low water mark at %d; got %s, %s", lease.Expiration, rTS, wTS)
+	}
+
+	// While the other replica's lease is in effect, reads are redirected
+	// to it rather than requesting the lease here.
+	if err := tc.rng.verifyLeaseCoversRead(tc.clock.Now()); err == nil {
+		t.Fatal("expected read to be redirected")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	}
+	if l := tc.rng.getLease(); l.RaftNodeID != otherLease.RaftNodeID {
+		t.Errorf("expected lease to remain with the other replica; got %+v", l)
+	}
+}
+
+// TestRangeFollowerReads verifies that extending the leader lease
+// closes timestamps behind the current time, that a replica which
+// doesn't hold the lease serves consistent reads at or below the
+// closed timestamp, and that writes at or below it are rejected.
+func TestRangeFollowerReads(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	// Acquire the lease well after time zero, then extend it.
+	tc.manualClock.Increment(int64(2 * closedTimestampLag))
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.rng.acquireLeaderLease(tc.rng.getLease().Term); err != nil {
+		t.Fatal(err)
+	}
+	lease := tc.rng.getLease()
+	closed := lease.ClosedTimestamp
+	if expWallTime := tc.manualClock.UnixNano() - int64(closedTimestampLag); closed.WallTime != expWallTime {
+		t.Fatalf("expected closed timestamp at %d; got %s", expWallTime, closed)
+	}
+	tc.rng.Lock()
+	rTS, _ := tc.rng.tsCache.GetMax(proto.Key("z"), nil, proto.NoTxnMD5)
+	tc.rng.Unlock()
+	if rTS.Less(closed) {
+		t.Errorf("expected timestamp cache low water mark at or above %s; got %s", closed, rTS)
+	}
+
+	// Hand the lease to another replica; the closed timestamp carries
+	// over, so reads at or below it are still served here.
+	tc.rng.setLease(&proto.Lease{
+		Expiration:      tc.manualClock.UnixNano() + int64(defaultLeaderLeaseDuration),
+		Duration:        int64(defaultLeaderLeaseDuration),
+		RaftNodeID:      uint64(MakeRaftNodeID(2, 2)),
+		ClosedTimestamp: closed,
+	})
+	gArgs, gReply := getArgs(proto.Key("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = closed
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatalf("expected follower read at closed timestamp to succeed: %s", err)
+	}
+	gArgs.Timestamp = closed
+	gArgs.Timestamp.Logical++
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err == nil {
+		t.Fatal("expected read above closed timestamp to be redirected")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	}
+
+	// Writes at the closed timestamp are rejected when applied, whether
+	// or not they may be part of a transaction.
+	pArgs.Timestamp = closed
+	if err := tc.rng.executeCmd(0, "", proto.Put, pArgs, pReply, nil); err == nil {
+		t.Fatal("expected write at closed timestamp to be rejected")
+	} else if _, ok := err.(*proto.WriteTooOldError); !ok {
+		t.Fatalf("expected WriteTooOldError; got %s", err)
+	}
+	mArgs, mReply := internalMergeArgs([]byte("a"), proto.Value{Bytes: []byte("value")}, 1, tc.store.StoreID())
+	mArgs.Timestamp = closed
+	if err := tc.rng.executeCmd(0, "", proto.InternalMerge, mArgs, mReply, nil); err == nil {
+		t.Fatal("expected merge at closed timestamp to be rejected")
+	} else if _, ok := err.(*proto.WriteTooOldError); !ok {
+		t.Fatalf("expected WriteTooOldError; got %s", err)
+	}
+}
+
+// TestRangeTransferLeaderLease verifies that a lease transfer grants
+// the target a lease beginning immediately, that reads at earlier
+// timestamps continue to be served locally while other commands are
+// redirected to the target, and that a transfer naming a replaced
+// lease is rejected.
+func TestRangeTransferLeaderLease(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	// A write acquires the lease for this replica.
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	prev := tc.rng.getLease()
+
+	// Add a second replica to the range and transfer the lease to it.
+	target := proto.Replica{NodeID: 2, StoreID: 2}
+	desc := *tc.rng.Desc()
+	desc.Replicas = append(append([]proto.Replica(nil), desc.Replicas...), target)
+	tc.rng.SetDesc(&desc)
+	if err := tc.rng.TransferLeaderLease(target, "test"); err != nil {
+		t.Fatal(err)
+	}
+	lease := tc.rng.getLease()
+	if lease.RaftNodeID != uint64(MakeRaftNodeID(target.NodeID, target.StoreID)) {
+		t.Fatalf("expected lease to be held by %+v; got %+v", target, lease)
+	}
+	start := lease.Expiration - lease.Duration
+	if start >= prev.Expiration {
+		t.Errorf("expected transferred lease to begin before %d; got %d", prev.Expiration, start)
+	}
+
+	// Reads before the start of the new lease are still served here.
+	gArgs, gReply := getArgs(proto.Key("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = proto.Timestamp{WallTime: start - 1}
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatalf("expected read before transfer to succeed: %s", err)
+	}
+	// Reads at or after the start of the new lease, and writes, are
+	// redirected to the target.
+	gArgs.Timestamp = proto.Timestamp{WallTime: start}
+	err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true)
+	if nlErr, ok := err.(*proto.NotLeaderError); !ok || nlErr.Leader.StoreID != target.StoreID {
+		t.Errorf("expected read to be redirected to %+v; got %v", target, err)
+	}
+	pArgs.Timestamp = proto.Timestamp{WallTime: start - 1}
+	err = tc.rng.AddCmd(proto.Put, pArgs, pReply, true)
+	if nlErr, ok := err.(*proto.NotLeaderError); !ok || nlErr.Leader.StoreID != target.StoreID {
+		t.Errorf("expected write to be redirected to %+v; got %v", target, err)
+	}
+	// A write which passed the lease check before the transfer started
+	// isn't admitted to the command queue either.
+	if _, err := tc.rng.beginCmd(proto.Key("a"), nil, false); err == nil {
+		t.Error("expected write to be refused admission during transfer")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Errorf("expected NotLeaderError; got %s", err)
+	}
+
+	// The timestamp cache is forwarded to the start of the new lease.
+	tc.rng.Lock()
+	rTS, _ := tc.rng.tsCache.GetMax(proto.Key("z"), nil, proto.NoTxnMD5)
+	tc.rng.Unlock()
+	if rTS.WallTime != start {
+		t.Errorf("expected timestamp cache low water mark at %d; got %s", start, rTS)
+	}
+
+	// A transfer of the replaced lease is rejected.
+	lArgs := &proto.InternalLeaderLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       tc.rng.Desc().StartKey,
+			Timestamp: tc.clock.Now(),
+			RaftID:    tc.rng.Desc().RaftID,
+		},
+		Lease: proto.Lease{
+			Expiration: start + 1 + lease.Duration,
+			Duration:   lease.Duration,
+			RaftNodeID: uint64(MakeRaftNodeID(3, 3)),
+		},
+		PrevLease: prev,
+	}
+	if err := tc.rng.executeCmd(0, "", proto.InternalLeaderLease, lArgs, &proto.InternalLeaderLeaseResponse{}, nil); err == nil {
+		t.Error("expected transfer of replaced lease to be rejected")
+	}
+
+	// Once the transferred lease has expired, the lease is reacquired.
+	tc.manualClock.Increment(int64(2 * defaultLeaderLeaseDuration))
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !tc.rng.HasLeaderLease() {
+		t.Fatal("expected range to reacquire leader lease")
+	}
+}
+
 // TestRangeGossipFirstRange verifies that the first range gossips its
 // location and the cluster ID.
 func TestRangeGossipFirstRange(t *testing.T) {
@@ -357,7 +815,7 @@ func TestRangeGossipConfigWithMultipleKeyPrefixes(t *testing.T) {
 	}
 	reply := &proto.PutResponse{}
 
-	if err := tc.rng.executeCmd(0, proto.Put, req, reply); err != nil {
+	if err := tc.rng.executeCmd(0, "", proto.Put, req, reply, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -399,7 +857,7 @@ func TestRangeGossipConfigUpdates(t *testing.T) {
 	}
 	reply := &proto.PutResponse{}
 
-	if err := tc.rng.executeCmd(0, proto.Put, req, reply); err != nil {
+	if err := tc.rng.executeCmd(0, "", proto.Put, req, reply, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -423,6 +881,7 @@ func TestRangeGossipConfigUpdates(t *testing.T) {
 func getArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.GetRequest, *proto.GetResponse) {
 	args := &proto.GetRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -437,6 +896,7 @@ func getArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.GetRequest
 func putArgs(key, value []byte, raftID int64, storeID proto.StoreID) (*proto.PutRequest, *proto.PutResponse) {
 	args := &proto.PutRequest{
 		RequestHeader: proto.RequestHeader{
+			User:      UserRoot,
 			Key:       key,
 			Timestamp: proto.MinTimestamp,
 			RaftID:    raftID,
@@ -454,6 +914,7 @@ func putArgs(key, value []byte, raftID int64, storeID proto.StoreID) (*proto.Put
 func deleteArgs(key proto.Key, raftID int64, storeID proto.StoreID) (*proto.DeleteRequest, *proto.DeleteResponse) {
 	args := &proto.DeleteRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -480,6 +941,7 @@ func readOrWriteArgs(key proto.Key, read bool, raftID int64, storeID proto.Store
 func incrementArgs(key []byte, inc int64, raftID int64, storeID proto.StoreID) (*proto.IncrementRequest, *proto.IncrementResponse) {
 	args := &proto.IncrementRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -493,6 +955,7 @@ func incrementArgs(key []byte, inc int64, raftID int64, storeID proto.StoreID) (
 func scanArgs(start, end []byte, raftID int64, storeID proto.StoreID) (*proto.ScanRequest, *proto.ScanResponse) {
 	args := &proto.ScanRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     start,
 			EndKey:  end,
 			RaftID:  raftID,
@@ -509,6 +972,7 @@ func endTxnArgs(txn *proto.Transaction, commit bool, raftID int64, storeID proto
 	*proto.EndTransactionRequest, *proto.EndTransactionResponse) {
 	args := &proto.EndTransactionRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     txn.Key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -526,6 +990,7 @@ func pushTxnArgs(pusher, pushee *proto.Transaction, abort bool, raftID int64, st
 	*proto.InternalPushTxnRequest, *proto.InternalPushTxnResponse) {
 	args := &proto.InternalPushTxnRequest{
 		RequestHeader: proto.RequestHeader{
+			User:      UserRoot,
 			Key:       pushee.Key,
 			Timestamp: pusher.Timestamp,
 			RaftID:    raftID,
@@ -544,6 +1009,7 @@ func heartbeatArgs(txn *proto.Transaction, raftID int64, storeID proto.StoreID)
 	*proto.InternalHeartbeatTxnRequest, *proto.InternalHeartbeatTxnResponse) {
 	args := &proto.InternalHeartbeatTxnRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     txn.Key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -561,6 +1027,7 @@ func internalMergeArgs(key []byte, value proto.Value, raftID int64, storeID prot
 	*proto.InternalMergeRequest, *proto.InternalMergeResponse) {
 	args := &proto.InternalMergeRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			Key:     key,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
@@ -575,6 +1042,7 @@ func internalTruncateLogArgs(index uint64, raftID int64, storeID proto.StoreID)
 	*proto.InternalTruncateLogRequest, *proto.InternalTruncateLogResponse) {
 	args := &proto.InternalTruncateLogRequest{
 		RequestHeader: proto.RequestHeader{
+			User:    UserRoot,
 			RaftID:  raftID,
 			Replica: proto.Replica{StoreID: storeID},
 		},
@@ -655,6 +1123,48 @@ func TestRangeUpdateTSCache(t *testing.T) {
 	}
 }
 
+// TestRangeScanLimits verifies that a scan stops at its result and
+// byte limits and returns the key at which to resume it.
+func TestRangeScanLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	keys := []proto.Key{proto.Key("a"), proto.Key("b"), proto.Key("c")}
+	for _, key := range keys {
+		pArgs, pReply := putArgs(key, []byte("value"), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		max, maxBytes int64
+		expCount      int
+		expResume     proto.Key
+	}{
+		{0, 0, 3, nil},
+		{2, 0, 2, keys[1].Next()},
+		{0, 1, 1, keys[0].Next()},
+	}
+	for i, test := range testCases {
+		sArgs, sReply := scanArgs(keys[0], proto.Key("d"), 1, tc.store.StoreID())
+		sArgs.Timestamp = tc.clock.Now()
+		sArgs.MaxResults = test.max
+		sArgs.MaxBytes = test.maxBytes
+		if err := tc.rng.AddCmd(proto.Scan, sArgs, sReply, true); err != nil {
+			t.Fatal(err)
+		}
+		if len(sReply.Rows) != test.expCount {
+			t.Errorf("%d: expected %d rows; got %d", i, test.expCount, len(sReply.Rows))
+		}
+		if !sReply.ResumeKey.Equal(test.expResume) {
+			t.Errorf("%d: expected resume key %q; got %q", i, test.expResume, sReply.ResumeKey)
+		}
+	}
+}
+
 // TestRangeCommandQueue verifies that reads/writes must wait for
 // pending commands to complete through Raft before being executed on
 // range.
@@ -1005,6 +1515,167 @@ func TestRangeIdempotence(t *testing.T) {
 	}
 }
 
+// TestRangeReproposalAppliedOnce verifies that a command committed to
+// the Raft log a second time, as happens when it is reproposed, isn't
+// applied again but returns the result of its first application.
+func TestRangeReproposalAppliedOnce(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	// Commit the same proposal to the log twice, as multiraft would after
+	// losing track of it. The client command ID is left unset so the
+	// response cache can't be what filters out the copy.
+	args, _ := incrementArgs([]byte("a"), 1, 1, tc.store.StoreID())
+	args.Timestamp = tc.clock.Now()
+	raftCmd := proto.InternalRaftCommand{RaftID: tc.rng.Desc().RaftID}
+	raftCmd.Cmd.SetValue(args)
+	idKey := tc.rng.newProposalID()
+	for i := 0; i < 2; i++ {
+		pending := &pendingCmd{Reply: &proto.IncrementResponse{}, done: make(chan error, 1)}
+		tc.rng.Lock()
+		tc.rng.pendingCmds[idKey] = pending
+		tc.rng.Unlock()
+		if err := <-tc.store.ProposeRaftCommand(idKey, raftCmd); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-pending.done; err != nil {
+			t.Fatal(err)
+		}
+		if r := pending.Reply.(*proto.IncrementResponse); r.NewValue != 1 {
+			t.Errorf("%d: expected increment to return 1; got %d", i, r.NewValue)
+		}
+	}
+
+	gArgs, gReply := getArgs([]byte("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if v := gReply.Value.GetInteger(); v != 1 {
+		t.Errorf("expected value 1 after reproposal; got %d", v)
+	}
+}
+
+// TestRangeReproposalOfRejectedCommand verifies that a copy of a
+// proposal which was rejected at apply time isn't applied either, and
+// that it returns the original error.
+func TestRangeReproposalOfRejectedCommand(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	key := proto.Key("a")
+	args, _ := incrementArgs(key, 1, 1, tc.store.StoreID())
+	args.Timestamp = tc.clock.Now()
+	raftCmd := proto.InternalRaftCommand{RaftID: tc.rng.Desc().RaftID}
+	raftCmd.Cmd.SetValue(args)
+	idKey := tc.rng.newProposalID()
+
+	// Reject the first application of the increment.
+	TestingCommandFilter = func(method string, args proto.Request, reply proto.Response) bool {
+		if method == proto.Increment && args.Header().Key.Equal(key) {
+			reply.Header().SetGoError(util.Errorf("injected error"))
+			return true
+		}
+		return false
+	}
+	defer func() { TestingCommandFilter = nil }()
+
+	for i := 0; i < 2; i++ {
+		pending := &pendingCmd{Reply: &proto.IncrementResponse{}, done: make(chan error, 1)}
+		tc.rng.Lock()
+		tc.rng.pendingCmds[idKey] = pending
+		tc.rng.Unlock()
+		if err := <-tc.store.ProposeRaftCommand(idKey, raftCmd); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-pending.done; err == nil || !strings.Contains(err.Error(), "injected error") {
+			t.Errorf("%d: expected injected error; got %v", i, err)
+		}
+		TestingCommandFilter = nil
+	}
+
+	gArgs, gReply := getArgs(key, 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if gReply.Value != nil {
+		t.Errorf("expected no value after rejected reproposal; got %s", gReply.Value)
+	}
+}
+
+// TestRangeStaleProposal verifies that the outcome of a proposal is
+// recorded outside the response cache, and that once the lease has
+// changed hands since the proposal was made, copies of it are rejected
+// and its record is garbage collected.
+func TestRangeStaleProposal(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	key := proto.Key("a")
+	args, _ := incrementArgs(key, 1, 1, tc.store.StoreID())
+	args.Timestamp = tc.clock.Now()
+	raftCmd := proto.InternalRaftCommand{RaftID: tc.rng.Desc().RaftID}
+	raftCmd.Cmd.SetValue(args)
+	idKey := tc.rng.newProposalID()
+	propose := func() error {
+		pending := &pendingCmd{Reply: &proto.IncrementResponse{}, done: make(chan error, 1)}
+		tc.rng.Lock()
+		tc.rng.pendingCmds[idKey] = pending
+		tc.rng.Unlock()
+		if err := <-tc.store.ProposeRaftCommand(idKey, raftCmd); err != nil {
+			t.Fatal(err)
+		}
+		return <-pending.done
+	}
+	if err := propose(); err != nil {
+		t.Fatal(err)
+	}
+	proposalID := idKey.cmdID()
+	recordKey := engine.RaftAppliedProposalKey(tc.rng.Desc().RaftID, &proposalID)
+	if v, err := engine.MVCCGet(tc.engine, recordKey, proto.ZeroTimestamp, true, nil); err != nil || v == nil {
+		t.Fatalf("expected outcome of proposal to be recorded: %v, %v", v, err)
+	}
+	if v, err := engine.MVCCGet(tc.engine, engine.ResponseCacheKey(tc.rng.Desc().RaftID, &proposalID),
+		proto.ZeroTimestamp, true, nil); err != nil || v != nil {
+		t.Fatalf("expected no response cache entry for the proposal: %v, %v", v, err)
+	}
+
+	// Begin a new tenure, as if the lease had been held by another
+	// replica in the meantime.
+	tc.manualClock.Increment(1)
+	lease := *tc.rng.getLease()
+	lease.TenureStart = tc.clock.Now().WallTime
+	tc.rng.setLease(&lease)
+	if err := propose(); err == nil {
+		t.Fatal("expected copy of a proposal from an earlier tenure to be rejected")
+	} else if _, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %s", err)
+	}
+	gArgs, gReply := getArgs(key, 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if v := gReply.Value.GetInteger(); v != 1 {
+		t.Errorf("expected value 1 after stale reproposal; got %d", v)
+	}
+
+	gcQ := newGCQueue()
+	if err := gcQ.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := engine.MVCCGet(tc.engine, recordKey, proto.ZeroTimestamp, true, nil); err != nil || v != nil {
+		t.Errorf("expected record of stale proposal to be garbage collected: %v, %v", v, err)
+	}
+}
+
 // TestEndTransactionBeforeHeartbeat verifies that a transaction
 // can be committed/aborted before being heartbeat.
 func TestEndTransactionBeforeHeartbeat(t *testing.T) {
@@ -1556,6 +2227,50 @@ func TestInternalPushTxnPushTimestampAlreadyPushed(t *testing.T) {
 	}
 }
 
+// TestInternalPushTxnNonTransactionalPusher verifies that a
+// non-transactional pusher with the same priority as the pushee is
+// ordered by its request timestamp, with an older request succeeding.
+func TestInternalPushTxnNonTransactionalPusher(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	ts1 := proto.Timestamp{WallTime: 1}
+	ts2 := proto.Timestamp{WallTime: 2}
+	testCases := []struct {
+		pusherTS, pusheeTS proto.Timestamp
+		abort              bool
+		expSuccess         bool
+	}{
+		{ts1, ts2, true, true},
+		{ts1, ts1, true, false},
+		{ts2, ts1, true, false},
+		{ts2, ts1, false, false},
+	}
+
+	for i, test := range testCases {
+		key := proto.Key(fmt.Sprintf("key-%d", i))
+		pushee := newTransaction("test", key, 1, proto.SERIALIZABLE, tc.clock)
+		pushee.Priority = 1
+		pushee.Timestamp = test.pusheeTS
+
+		args, reply := pushTxnArgs(pushee, pushee, test.abort, 1, tc.store.StoreID())
+		args.Txn = nil
+		args.Timestamp = test.pusherTS
+		args.UserPriority = gogoproto.Int32(-1) // explicit priority of 1
+		err := tc.rng.AddCmd(proto.InternalPushTxn, args, reply, true)
+		if test.expSuccess != (err == nil) {
+			t.Errorf("expected success on trial %d? %t; got err %s", i, test.expSuccess, err)
+		}
+		if err != nil {
+			if _, ok := err.(*proto.TransactionPushError); !ok {
+				t.Errorf("expected txn push error: %s", err)
+			}
+		}
+	}
+}
+
 func verifyRangeStats(eng engine.Engine, raftID int64, expMS engine.MVCCStats, t *testing.T) {
 	var ms engine.MVCCStats
 	if err := engine.MVCCGetRangeStats(eng, raftID, &ms); err != nil {
@@ -1634,6 +2349,329 @@ func TestRangeStatsComputation(t *testing.T) {
 	verifyRangeStats(tc.engine, tc.rng.Desc().RaftID, expMS, t)
 }
 
+// TestInternalResolveIntentKeys verifies that a resolve intent command
+// listing keys resolves the intents on the listed keys within its key
+// range and no others.
+func TestInternalResolveIntentKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{
+		bootstrapMode: bootstrapRangeOnly,
+	}
+	tc.Start(t)
+	defer tc.Stop()
+
+	txn := &proto.Transaction{ID: []byte("txn1"), Timestamp: tc.clock.Now()}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		pArgs, pReply := putArgs([]byte(key), []byte("value"), 1, tc.store.StoreID())
+		pArgs.Timestamp = txn.Timestamp
+		pArgs.Txn = txn
+		if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rArgs := &proto.InternalResolveIntentRequest{
+		RequestHeader: proto.RequestHeader{
+			Timestamp: txn.Timestamp,
+			Key:       proto.Key("a"),
+			EndKey:    proto.Key("d"),
+			RaftID:    tc.rng.Desc().RaftID,
+			Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+			Txn:       txn,
+		},
+		Keys: []proto.Key{proto.Key("a"), proto.Key("c"), proto.Key("d")},
+	}
+	rArgs.Txn.Status = proto.COMMITTED
+	if err := tc.rng.AddCmd(proto.InternalResolveIntent, rArgs, &proto.InternalResolveIntentResponse{}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expIntent := range map[string]bool{"a": false, "b": true, "c": false, "d": true} {
+		meta := &proto.MVCCMetadata{}
+		if _, _, _, err := tc.engine.GetProto(engine.MVCCEncodeKey(proto.Key(key)), meta); err != nil {
+			t.Fatal(err)
+		}
+		if intent := meta.Txn != nil; intent != expIntent {
+			t.Errorf("key %q: expected intent %t; got %t", key, expIntent, intent)
+		}
+	}
+}
+
+// TestInternalRecomputeStats verifies that InternalRecomputeStats
+// corrects drifted range stats and reports the correction.
+func TestInternalRecomputeStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{
+		bootstrapMode: bootstrapRangeOnly,
+	}
+	tc.Start(t)
+	defer tc.Stop()
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("value1"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	expMS := engine.MVCCStats{LiveBytes: 39, KeyBytes: 15, ValBytes: 24, LiveCount: 1, KeyCount: 1, ValCount: 1}
+	verifyRangeStats(tc.engine, tc.rng.Desc().RaftID, expMS, t)
+
+	// Introduce drift.
+	drifted := expMS
+	drifted.LiveBytes += 100
+	drifted.KeyCount -= 2
+	tc.rng.stats.SetMVCCStats(tc.engine, drifted)
+
+	for i, expDelta := range []engine.MVCCStats{{LiveBytes: -100, KeyCount: 2}, {}} {
+		args := &proto.InternalRecomputeStatsRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       pArgs.Key,
+				Timestamp: tc.clock.Now(),
+				RaftID:    tc.rng.Desc().RaftID,
+				Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+			},
+		}
+		reply := &proto.InternalRecomputeStatsResponse{}
+		if err := tc.rng.AddCmd(proto.InternalRecomputeStats, args, reply, true); err != nil {
+			t.Fatal(err)
+		}
+		if reply.LiveBytes != expDelta.LiveBytes || reply.KeyCount != expDelta.KeyCount ||
+			reply.KeyBytes != 0 || reply.ValBytes != 0 || reply.LiveCount != 0 || reply.ValCount != 0 {
+			t.Errorf("%d: expected delta %+v; got %+v", i, expDelta, reply)
+		}
+		verifyRangeStats(tc.engine, tc.rng.Desc().RaftID, expMS, t)
+		if ms := tc.rng.stats.GetMVCC(); !reflect.DeepEqual(ms, expMS) {
+			t.Errorf("%d: expected cached stats %+v; got %+v", i, expMS, ms)
+		}
+	}
+}
+
+// TestInternalExport verifies that InternalExport writes the values
+// in the exported span as of the export timestamp to the export sink.
+func TestInternalExport(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	put := func(key, value string) {
+		tc.manualClock.Increment(1)
+		pArgs, pReply := putArgs([]byte(key), []byte(value), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a", "a1")
+	put("b", "b1")
+	put("c", "c1")
+	tc.manualClock.Increment(1)
+	exportTS := tc.clock.Now()
+	put("b", "b2")
+	put("bb", "bb2")
+
+	args := &proto.InternalExportRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       proto.Key("a"),
+			EndKey:    proto.Key("c"),
+			Timestamp: exportTS,
+			RaftID:    1,
+			Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+		},
+		Name: "backup/1",
+	}
+	reply := &proto.InternalExportResponse{}
+	if err := tc.rng.AddCmd(proto.InternalExport, args, reply, true); err == nil {
+		t.Fatal("expected export to fail without an export sink")
+	}
+
+	sink := NewLocalExportSink(dir)
+	tc.store.ExportSink = sink
+	reply = &proto.InternalExportResponse{}
+	if err := tc.rng.AddCmd(proto.InternalExport, args, reply, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "backup", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if reply.KeyCount != 2 || reply.DataSize != int64(len(data)) || !bytes.Equal(reply.Sha256, sum[:]) {
+		t.Errorf("unexpected reply %+v for export of %d bytes", reply, len(data))
+	}
+
+	f, err := sink.Open(args.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var kvs []string
+	if err := ReadExport(f, func(kv proto.KeyValue) error {
+		kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key, kv.Value.Bytes))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expKVs := []string{"a=a1", "b=b1"}; !reflect.DeepEqual(kvs, expKVs) {
+		t.Errorf("expected exported values %s; got %s", expKVs, kvs)
+	}
+
+	// The export must push later writes to the span above the export
+	// timestamp, or a backup could miss values written below it.
+	if rTS, _ := tc.rng.tsCache.GetMax(proto.Key("b"), nil, proto.NoTxnMD5); rTS.Less(exportTS) {
+		t.Errorf("expected read timestamp of exported span >= %s; got %s", exportTS, rTS)
+	}
+
+	// Exports may not overwrite existing files.
+	if err := tc.rng.AddCmd(proto.InternalExport, args, &proto.InternalExportResponse{}, true); err == nil {
+		t.Error("expected export to existing file to fail")
+	}
+
+	// A file whose export is aborted is removed rather than left in
+	// place, and may be created again.
+	w, err := sink.Create("backup/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	w.Abort()
+	for _, name := range []string{"2", "2.tmp"} {
+		if _, err := os.Stat(filepath.Join(dir, "backup", name)); !os.IsNotExist(err) {
+			t.Errorf("expected aborted export file %s to be removed; got %v", name, err)
+		}
+	}
+	if w, err = sink.Create("backup/2"); err != nil {
+		t.Fatal(err)
+	}
+	w.Abort()
+}
+
+// TestInternalImport verifies that InternalImport writes rows at the
+// timestamps of their values, that clearing the span removes its
+// existing data and that the range's stats account for both. Imports
+// are ordered by the timestamp cache like other writes, and rows newer
+// than the import are refused.
+func TestInternalImport(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{
+		bootstrapMode: bootstrapRangeOnly,
+	}
+	tc.Start(t)
+	defer tc.Stop()
+
+	row := func(key, value string, wallTime int64) proto.KeyValue {
+		v := proto.Value{Bytes: []byte(value), Timestamp: &proto.Timestamp{WallTime: wallTime}}
+		v.InitChecksum([]byte(key))
+		return proto.KeyValue{Key: proto.Key(key), Value: v}
+	}
+	importRows := func(clear bool, rows ...proto.KeyValue) {
+		args := &proto.InternalImportRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:       proto.Key("a"),
+				EndKey:    proto.Key("c"),
+				Timestamp: tc.clock.Now(),
+				RaftID:    tc.rng.Desc().RaftID,
+				Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+			},
+			Rows:  rows,
+			Clear: clear,
+		}
+		reply := &proto.InternalImportResponse{}
+		if err := tc.rng.AddCmd(proto.InternalImport, args, reply, true); err != nil {
+			t.Fatal(err)
+		}
+		if reply.KeyCount != int64(len(rows)) {
+			t.Errorf("expected %d keys imported; got %d", len(rows), reply.KeyCount)
+		}
+	}
+	verify := func(expKVs map[string]proto.KeyValue) {
+		for _, key := range []string{"a", "b"} {
+			value, err := engine.MVCCGet(tc.engine, proto.Key(key), tc.clock.Now(), true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp, ok := expKVs[key]
+			if !ok {
+				if value != nil {
+					t.Errorf("expected %q to be cleared; got %+v", key, value)
+				}
+				continue
+			}
+			if value == nil || !bytes.Equal(value.Bytes, exp.Value.Bytes) || !value.Timestamp.Equal(*exp.Value.Timestamp) {
+				t.Errorf("expected %q to be imported as %+v; got %+v", key, exp.Value, value)
+			}
+		}
+		actual, err := engine.MVCCComputeStats(tc.engine, engine.KeyMin, engine.KeyMax, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms := tc.rng.stats.GetMVCC()
+		if ms.LiveBytes != actual.LiveBytes || ms.KeyBytes != actual.KeyBytes || ms.ValBytes != actual.ValBytes ||
+			ms.LiveCount != actual.LiveCount || ms.KeyCount != actual.KeyCount || ms.ValCount != actual.ValCount {
+			t.Errorf("expected stats %+v; got %+v", actual, ms)
+		}
+	}
+
+	a, b := row("a", "a1", 1), row("b", "b1", 2)
+	importRows(false, a, b)
+	verify(map[string]proto.KeyValue{"a": a, "b": b})
+
+	b2 := row("b", "b2", 3)
+	importRows(true, b2)
+	verify(map[string]proto.KeyValue{"b": b2})
+
+	// An import is pushed above a later read of its span.
+	readTS := tc.clock.Now()
+	readTS.WallTime += 100
+	gArgs, gReply := getArgs([]byte("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = readTS
+	if err := tc.rng.AddCmd(proto.Get, gArgs, gReply, true); err != nil {
+		t.Fatal(err)
+	}
+	args := &proto.InternalImportRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       proto.Key("a"),
+			EndKey:    proto.Key("c"),
+			Timestamp: tc.clock.Now(),
+			RaftID:    tc.rng.Desc().RaftID,
+			Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+		},
+		Rows: []proto.KeyValue{row("a", "a2", 4)},
+	}
+	iReply := &proto.InternalImportResponse{}
+	if err := tc.rng.AddCmd(proto.InternalImport, args, iReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !readTS.Less(iReply.Timestamp) {
+		t.Errorf("expected import to be pushed above read at %s; got %s", readTS, iReply.Timestamp)
+	}
+	// A later write to the span is in turn pushed above the import.
+	pArgs, pReply := putArgs([]byte("b"), []byte("b3"), 1, tc.store.StoreID())
+	pArgs.Timestamp = readTS
+	if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !iReply.Timestamp.Less(pReply.Timestamp) {
+		t.Errorf("expected put to be pushed above import at %s; got %s", iReply.Timestamp, pReply.Timestamp)
+	}
+
+	// Rows newer than the import are refused.
+	future := tc.clock.Now()
+	future.WallTime += int64(time.Hour)
+	args.Timestamp = tc.clock.Now()
+	args.Rows = []proto.KeyValue{row("a", "a3", future.WallTime)}
+	if err := tc.rng.AddCmd(proto.InternalImport, args, &proto.InternalImportResponse{}, true); err == nil {
+		t.Error("expected import of row newer than the import to fail")
+	}
+}
+
 // TestInternalMerge verifies that the InternalMerge command is behaving as
 // expected. Merge semantics for different data types are tested more robustly
 // at the engine level; this test is intended only to show that values passed to
@@ -1737,6 +2775,59 @@ func TestInternalTruncateLog(t *testing.T) {
 	}
 }
 
+// TestInternalGCSuggestsCompaction verifies that garbage collecting
+// keys suggests a compaction of the collected span with an estimate
+// of the bytes collected.
+func TestInternalGCSuggestsCompaction(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	keys := []proto.Key{proto.Key("b"), proto.Key("a")}
+	for _, key := range keys {
+		pArgs, pReply := putArgs(key, []byte("value"), 1, tc.store.StoreID())
+		pArgs.Timestamp = makeTS(1, 0)
+		if err := tc.rng.AddCmd(proto.Put, pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+		dArgs, dReply := deleteArgs(key, 1, tc.store.StoreID())
+		dArgs.Timestamp = makeTS(2, 0)
+		if err := tc.rng.AddCmd(proto.Delete, dArgs, dReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compactor := tc.store.Compactor()
+	before := compactor.ReclaimableBytes()
+	gcArgs := &proto.InternalGCRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       keys[1],
+			EndKey:    keys[0].Next(),
+			Timestamp: makeTS(3, 0),
+			RaftID:    1,
+			Replica:   proto.Replica{StoreID: tc.store.StoreID()},
+		},
+	}
+	for _, key := range keys {
+		gcArgs.Keys = append(gcArgs.Keys, proto.InternalGCRequest_GCKey{Key: key, Timestamp: makeTS(2, 0)})
+	}
+	if err := tc.rng.AddCmd(proto.InternalGC, gcArgs, &proto.InternalGCResponse{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if reclaimable := compactor.ReclaimableBytes(); reclaimable <= before {
+		t.Errorf("expected reclaimable bytes to exceed %d; got %d", before, reclaimable)
+	}
+
+	compactor.mu.Lock()
+	defer compactor.mu.Unlock()
+	last := compactor.spans[len(compactor.spans)-1]
+	expected := compactionSpan{start: engine.MVCCEncodeKey(keys[1]), end: engine.MVCCEncodeKey(keys[0].Next())}
+	if !reflect.DeepEqual(last, expected) {
+		t.Errorf("expected suggested span %q; got %q", expected, last)
+	}
+}
+
 func TestRaftStorage(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	var tc testContext
@@ -1762,7 +2853,7 @@ func TestConditionFailedError(t *testing.T) {
 	key := []byte("k")
 	value := []byte("quack")
 	pArgs, pReply := putArgs(key, value, 1, tc.store.StoreID())
-	if err := tc.rng.executeCmd(0, proto.Put, pArgs, pReply); err != nil {
+	if err := tc.rng.executeCmd(0, "", proto.Put, pArgs, pReply, nil); err != nil {
 		t.Fatal(err)
 	}
 	args := &proto.ConditionalPutRequest{
@@ -1780,7 +2871,7 @@ func TestConditionFailedError(t *testing.T) {
 		},
 	}
 	reply := &proto.ConditionalPutResponse{}
-	err := tc.rng.executeCmd(0, proto.ConditionalPut, args, reply)
+	err := tc.rng.executeCmd(0, "", proto.ConditionalPut, args, reply, nil)
 	if cErr, ok := err.(*proto.ConditionFailedError); err == nil || !ok {
 		t.Fatalf("expected ConditionFailedError, got %T with content %+v",
 			err, err)
@@ -0,0 +1,121 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// replicaGCQueueMaxSize is the max size of the replica GC queue.
+	replicaGCQueueMaxSize = 100
+	// replicaGCQueueMaxConcurrency is the max number of ranges processed
+	// at once by the replica GC queue.
+	replicaGCQueueMaxConcurrency = 1
+	// replicaGCQueueTimerDuration is the duration between GCs of queued
+	// replicas.
+	replicaGCQueueTimerDuration = 10 * time.Second
+	// replicaGCQueueInactivityThreshold is the time since the expiration
+	// of a replica's last known leader lease after which its membership
+	// is verified against the committed range descriptor. A replica
+	// removed before it applied its own removal never learns of it
+	// locally, and stops hearing of new leases.
+	replicaGCQueueInactivityThreshold = 10 * 24 * time.Hour
+)
+
+// replicaGCQueue manages a queue of replicas which may have been
+// removed from their ranges. Each queued replica is checked against
+// the committed range descriptor; if the range no longer includes
+// this store, the replica is removed from the store and its data is
+// destroyed, leaving a tombstone so that stale Raft messages don't
+// resurrect it.
+type replicaGCQueue struct {
+	*baseQueue
+	db           *client.KV
+	isPreemptive func(raftID int64) bool
+	destroy      func(rng *Range) error
+}
+
+// newReplicaGCQueue returns a new instance of replicaGCQueue. Ranges
+// for which isPreemptive returns true are left to the store's
+// preemptive snapshot GC; replicas found to be removed are passed to
+// destroy.
+func newReplicaGCQueue(db *client.KV, isPreemptive func(int64) bool, destroy func(*Range) error) *replicaGCQueue {
+	rgcq := &replicaGCQueue{
+		db:           db,
+		isPreemptive: isPreemptive,
+		destroy:      destroy,
+	}
+	rgcq.baseQueue = newBaseQueue("replicaGC", rgcq, replicaGCQueueMaxSize, replicaGCQueueMaxConcurrency)
+	return rgcq
+}
+
+// shouldQueue determines whether a replica should be queued for GC.
+// Replicas whose local range descriptor no longer includes this store
+// are queued with high priority; replicas whose last known leader
+// lease expired more than replicaGCQueueInactivityThreshold ago are
+// queued with low priority. Uninitialized replicas and those
+// initialized by preemptive snapshots are skipped.
+func (rgcq *replicaGCQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
+	if !rng.isInitialized() || rgcq.isPreemptive(rng.Desc().RaftID) {
+		return
+	}
+	if _, rep := rng.Desc().FindReplica(rng.rm.StoreID()); rep == nil {
+		return true, 1
+	}
+	// The nominal expiration of an epoch-based lease is used too: it
+	// follows the lease's last proposal, which a replica removed from
+	// the range stops seeing, whereas the holder's liveness does not.
+	if l := rng.getLease(); l != nil &&
+		now.WallTime-l.Expiration > replicaGCQueueInactivityThreshold.Nanoseconds() {
+		return true, 0
+	}
+	return
+}
+
+// process looks up the committed descriptor of the replica's range
+// and destroys the replica if this store is no longer a member.
+func (rgcq *replicaGCQueue) process(now proto.Timestamp, rng *Range) error {
+	desc := *rng.Desc()
+	if rgcq.db != nil {
+		var committed proto.RangeDescriptor
+		ok, _, err := rgcq.db.GetProto(engine.RangeDescriptorKey(desc.StartKey), &committed)
+		if err != nil {
+			return err
+		}
+		if ok && committed.RaftID == desc.RaftID {
+			desc = committed
+		} else {
+			// The range has been merged away.
+			desc.Replicas = nil
+		}
+	}
+	if _, rep := desc.FindReplica(rng.rm.StoreID()); rep != nil {
+		return nil
+	}
+	log.Infof("destroying replica of range %s removed from store %d", rng, rng.rm.StoreID())
+	return rgcq.destroy(rng)
+}
+
+// timer returns the duration between GCs of queued replicas.
+func (rgcq *replicaGCQueue) timer() time.Duration {
+	return replicaGCQueueTimerDuration
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/coreos/etcd/raft/raftpb"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestReplicaGCQueue verifies that replicas no longer included in
+// their range descriptor are destroyed and tombstoned, that tombstoned
+// ranges can be recreated via Raft only for newer replicas, and that
+// member and preemptive replicas are left alone.
+func TestReplicaGCQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	// Without a KV client, the local range descriptor is authoritative.
+	rgcq := store.replicaGCQueue
+	rgcq.db = nil
+
+	// Remove range 1 so the test ranges don't overlap it.
+	rng1, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveRange(rng1); err != nil {
+		t.Fatal(err)
+	}
+	// Range 2 no longer includes the store, range 3 does, and range 4
+	// was initialized by a preemptive snapshot.
+	rng2 := createRange(store, 2, proto.Key("a"), proto.Key("b"))
+	rng3 := createRange(store, 3, proto.Key("c"), proto.Key("d"))
+	rng4 := createRange(store, 4, proto.Key("e"), proto.Key("f"))
+	for _, rng := range []*Range{rng2, rng3, rng4} {
+		if err := store.AddRange(rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+	desc := *rng3.Desc()
+	desc.Replicas = []proto.Replica{{NodeID: 1, StoreID: store.StoreID()}}
+	rng3.SetDesc(&desc)
+	if err := store.ProcessRangeDescriptorUpdate(rng4); err != nil {
+		t.Fatal(err)
+	}
+
+	key := proto.Key("aa")
+	if err := engine.MVCCPut(store.Engine(), nil, key, store.clock.Now(), proto.Value{Bytes: []byte("value")}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := store.clock.Now()
+	for i, rng := range []*Range{rng2, rng3, rng4} {
+		shouldQ, priority := rgcq.shouldQueue(now, rng)
+		if expShouldQ := rng == rng2; shouldQ != expShouldQ {
+			t.Errorf("%d: expected shouldQueue %t; got %t (priority %f)", i, expShouldQ, shouldQ, priority)
+		}
+		if err := rgcq.process(now, rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.GetRange(2); err == nil {
+		t.Error("expected removed replica of range 2 to be destroyed")
+	}
+	for _, raftID := range []int64{3, 4} {
+		if _, err := store.GetRange(raftID); err != nil {
+			t.Errorf("expected range %d to remain: %s", raftID, err)
+		}
+	}
+	if val, err := engine.MVCCGet(store.Engine(), key, store.clock.Now(), true, nil); err != nil || val != nil {
+		t.Errorf("expected data of range 2 to be destroyed; got %v, %v", val, err)
+	}
+	minID := store.replicaTombstone(2)
+	if minID == 0 {
+		t.Fatal("expected range 2 to be tombstoned")
+	}
+	if gs := store.GroupStorage(2); gs != nil {
+		t.Error("expected tombstoned range 2 not to be recreated")
+	}
+
+	// Only a snapshot initializing a newer replica may recreate the
+	// range.
+	snapshot := func(replicaID int32) raftpb.Message {
+		data, err := gogoproto.Marshal(&proto.RaftSnapshotData{ReplicaID: replicaID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raftpb.Message{Type: raftpb.MsgSnap, Snapshot: raftpb.Snapshot{Data: data}}
+	}
+	if store.AdmitGroup(2, raftpb.Message{Type: raftpb.MsgHeartbeat}) {
+		t.Error("expected heartbeat for removed replica to be dropped")
+	}
+	if store.AdmitGroup(2, snapshot(minID-1)) {
+		t.Error("expected snapshot for removed replica to be dropped")
+	}
+	if !store.AdmitGroup(2, snapshot(minID)) {
+		t.Error("expected snapshot for newer replica to be admitted")
+	}
+	if gs := store.GroupStorage(2); gs == nil {
+		t.Error("expected range 2 to be recreated for a newer replica")
+	}
+}
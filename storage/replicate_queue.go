@@ -18,10 +18,12 @@
 package storage
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -29,18 +31,26 @@ import (
 const (
 	// replicateQueueMaxSize is the max size of the split queue.
 	replicateQueueMaxSize = 100
+	// replicateQueueMaxConcurrency is the max number of ranges processed at once
+	// by the replicate queue.
+	replicateQueueMaxConcurrency = 1
 
 	// replicateQueueTimerDuration is the duration between replication of queued ranges.
 	replicateQueueTimerDuration = 0 * time.Second // zero duration to process replication greedily
 )
 
 // replicateQueue manages a queue of ranges to have their replicas
-// change to match the zone config.
+// change to match the zone config. Once a range's replicas match, the
+// queue moves its leader lease if the allocator finds a better placed
+// replica.
 type replicateQueue struct {
 	*baseQueue
 	gossip    *gossip.Gossip
 	allocator *allocator
 	clock     *hlc.Clock
+	liveness  *NodeLiveness // Identifies replicas on dead nodes; may be nil
+	deadAfter time.Duration // Time after liveness expires until a node is dead
+	interval  int64         // Minimum interval between replica changes in nanos; accessed atomically
 	disabled  bool
 }
 
@@ -52,7 +62,7 @@ func newReplicateQueue(gossip *gossip.Gossip, allocator *allocator,
 		allocator: allocator,
 		clock:     clock,
 	}
-	rq.baseQueue = newBaseQueue("replicate", rq, replicateQueueMaxSize)
+	rq.baseQueue = newBaseQueue("replicate", rq, replicateQueueMaxSize, replicateQueueMaxConcurrency)
 	return rq
 }
 
@@ -64,7 +74,7 @@ func (rq *replicateQueue) shouldQueue(now proto.Timestamp, rng *Range) (
 	}
 
 	// If the range spans multiple zones, ignore it until the split queue has processed it.
-	if len(computeSplitKeys(rq.gossip, rng)) > 0 {
+	if len(computeSplitKeys(rq.gossip, rng, nil)) > 0 {
 		return
 	}
 
@@ -75,50 +85,159 @@ func (rq *replicateQueue) shouldQueue(now proto.Timestamp, rng *Range) (
 		return
 	}
 
-	return rq.needsReplication(zone, rng)
+	if shouldQ, priority = rq.needsReplication(zone, rng); shouldQ {
+		return
+	}
+	return rq.leaseTarget(zone, rng) != nil, 0
 }
 
+// needsReplication returns whether the range needs a replica added,
+// because it has fewer replicas on live nodes than the zone config
+// requires, or removed, because a replica on a dead node has been
+// replaced. Replicas on stores violating the zone's constraints are
+// replaced in the same manner: a conforming replica is added, then the
+// misplaced one removed. Adding missing replicas takes priority.
 func (rq *replicateQueue) needsReplication(zone proto.ZoneConfig, rng *Range) (bool, float64) {
 	// TODO(bdarnell): handle non-empty ReplicaAttrs.
 	need := len(zone.ReplicaAttrs)
 	have := len(rng.Desc().Replicas)
-	if need > have {
-		return true, float64(need - have)
+	dead := len(rq.deadReplicas(rng))
+	if live := have - dead; need > live {
+		return true, float64(need - live)
+	}
+	if dead > 0 && have > need {
+		return true, 0
+	}
+	if len(rq.allocator.misplacedReplicas(zone.Constraints, rng.Desc().Replicas)) > 0 {
+		return true, 0
 	}
 
 	return false, 0
 }
 
+// deadReplicas returns the range's replicas on nodes whose liveness
+// expired at least deadAfter ago. Nodes which have only just missed
+// their heartbeats aren't yet considered dead, as replacing their
+// replicas is expensive and they're likely to return.
+func (rq *replicateQueue) deadReplicas(rng *Range) []proto.Replica {
+	var dead []proto.Replica
+	for _, replica := range rng.Desc().Replicas {
+		if rq.liveness.IsDeadFor(replica.NodeID, rq.deadAfter) {
+			dead = append(dead, replica)
+		}
+	}
+	return dead
+}
+
+// leaseTarget returns the replica to which the range's leader lease
+// should be transferred, or nil if this replica doesn't hold the lease
+// or the lease is well placed. Leases prefer replicas matching the
+// attributes required of the zone's first replica.
+func (rq *replicateQueue) leaseTarget(zone proto.ZoneConfig, rng *Range) *proto.Replica {
+	if !rng.HasLeaderLease() {
+		return nil
+	}
+	preferred := zone.Constraints
+	if len(zone.ReplicaAttrs) > 0 {
+		preferred = zone.RequiredAttrs(0)
+	}
+	return rq.allocator.leaseTarget(preferred, rng.Desc().Replicas, rng.rm.StoreID())
+}
+
 func (rq *replicateQueue) process(now proto.Timestamp, rng *Range) error {
 	zone, err := lookupZoneConfig(rq.gossip, rng)
 	if err != nil {
 		return err
 	}
 
-	if needs, _ := rq.needsReplication(zone, rng); !needs {
+	needs, priority := rq.needsReplication(zone, rng)
+	if !needs {
+		if target := rq.leaseTarget(zone, rng); target != nil {
+			return rq.transferLease(rng, *target, "rebalance leases")
+		}
 		// Something changed between shouldQueue and process.
 		return nil
 	}
 
-	// TODO(bdarnell): handle non-homogenous ReplicaAttrs.
-	newReplica, err := rq.allocator.allocate(zone.ReplicaAttrs[0], rng.Desc().Replicas)
-	if err != nil {
-		return err
+	desc := rng.Desc()
+	dead := rq.deadReplicas(rng)
+	misplaced := rq.allocator.misplacedReplicas(zone.Constraints, desc.Replicas)
+	switch {
+	case priority == 0 && len(dead) > 0 && len(desc.Replicas) > len(zone.ReplicaAttrs):
+		// The range has enough live replicas; remove one on a dead node.
+		log.Infof("removing replica of range %d on dead node %d", desc.RaftID, dead[0].NodeID)
+		err = rng.ChangeReplicas(proto.REMOVE_REPLICA, dead[0], "replica on dead node")
+	case priority == 0 && len(misplaced) > 0 && len(desc.Replicas) > len(zone.ReplicaAttrs):
+		// The misplaced replica has been replaced; remove it, unless it's
+		// this one, in which case the leader lease must move first.
+		var remove *proto.Replica
+		for i := range misplaced {
+			if misplaced[i].StoreID != rng.rm.StoreID() {
+				remove = &misplaced[i]
+				break
+			}
+		}
+		if remove == nil {
+			target := rq.leaseTarget(zone, rng)
+			if target == nil {
+				return util.Errorf("no replica of range %d satisfying zone constraints %s to transfer lease to",
+					desc.RaftID, zone.Constraints.SortedString())
+			}
+			return rq.transferLease(rng, *target, "replica violates zone constraints")
+		}
+		log.Infof("removing replica of range %d on store %d violating zone constraints %s",
+			desc.RaftID, remove.StoreID, zone.Constraints.SortedString())
+		err = rng.ChangeReplicas(proto.REMOVE_REPLICA, *remove, "replica violates zone constraints")
+	default:
+		// TODO(bdarnell): handle non-homogenous ReplicaAttrs.
+		reason := "range under-replicated"
+		// Replicas restoring the range's replication are sent their
+		// snapshots ahead of those replacing misplaced replicas.
+		pri := recoverySnapshot
+		if priority == 0 {
+			reason = "replacing replica violating zone constraints"
+			pri = rebalanceSnapshot
+		}
+		var newReplica *StoreDescriptor
+		if newReplica, err = rq.allocator.allocate(zone.RequiredAttrs(0), desc.Replicas); err != nil {
+			return err
+		}
+
+		err = rng.changeReplicas(proto.ADD_REPLICA,
+			proto.Replica{
+				NodeID:  newReplica.Node.NodeID,
+				StoreID: newReplica.StoreID,
+				Attrs:   newReplica.Attrs,
+			}, reason, pri)
 	}
 
-	err = rng.ChangeReplicas(proto.ADD_REPLICA,
-		proto.Replica{
-			NodeID:  newReplica.Node.NodeID,
-			StoreID: newReplica.StoreID,
-			Attrs:   newReplica.Attrs,
-		})
-
 	// Enqueue this range again to see if there are more changes to be made.
 	go rq.MaybeAdd(rng, rq.clock.Now())
 
 	return err
 }
 
+// transferLease transfers the range's leader lease to target, recording
+// the transfer with the allocator so that it's counted until the
+// target's lease count is gossiped.
+func (rq *replicateQueue) transferLease(rng *Range, target proto.Replica, reason string) error {
+	log.Infof("transferring leader lease of range %d to store %d", rng.Desc().RaftID, target.StoreID)
+	if err := rng.TransferLeaderLease(target, reason); err != nil {
+		return err
+	}
+	rq.allocator.leaseTransferred(rng.rm.StoreID(), target.StoreID)
+	return nil
+}
+
+// setInterval sets the minimum interval between replica changes.
+// Zero imposes no limit.
+func (rq *replicateQueue) setInterval(interval time.Duration) {
+	atomic.StoreInt64(&rq.interval, int64(interval))
+}
+
 func (rq *replicateQueue) timer() time.Duration {
+	if interval := time.Duration(atomic.LoadInt64(&rq.interval)); interval > replicateQueueTimerDuration {
+		return interval
+	}
 	return replicateQueueTimerDuration
 }
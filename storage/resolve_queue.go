@@ -0,0 +1,160 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// resolveQueueMaxSize is the max size of the resolve queue.
+	resolveQueueMaxSize = 1000
+	// resolveQueueMaxConcurrency is the max number of ranges whose
+	// intents are resolved at once. Resolution is cheap and readers may
+	// be waiting on it, so several ranges are resolved in parallel.
+	resolveQueueMaxConcurrency = 4
+)
+
+// A pendingIntent is an intent awaiting resolution by the resolve
+// queue. The done channel is closed once resolution has been
+// attempted.
+type pendingIntent struct {
+	key  proto.Key
+	txn  proto.Transaction
+	done chan struct{}
+}
+
+// resolveQueue resolves intents of pushed transactions in the
+// background. Reads which encounter and successfully push a
+// conflicting intent enqueue its range here instead of resolving the
+// intent themselves. Concurrent reads blocked on the same intent share
+// a single resolution, and the read path never waits on the command
+// queue or Raft to clean up after another transaction.
+type resolveQueue struct {
+	*baseQueue
+	mu sync.Mutex // Protects intents
+	// intents maps from Raft ID to the range's pending intents, keyed
+	// by intent key.
+	intents map[int64]map[string]*pendingIntent
+}
+
+// newResolveQueue returns a new instance of resolveQueue.
+func newResolveQueue() *resolveQueue {
+	rq := &resolveQueue{
+		intents: map[int64]map[string]*pendingIntent{},
+	}
+	rq.baseQueue = newBaseQueue("resolve", rq, resolveQueueMaxSize, resolveQueueMaxConcurrency)
+	rq.baseQueue.removed = rq.release
+	return rq
+}
+
+// addIntent records the intent at key written by the pushed txn and
+// enqueues the range for resolution. Returns a channel which is closed
+// once the intent has been resolved. If the intent is already pending,
+// the channel of the existing entry is returned.
+func (rq *resolveQueue) addIntent(rng *Range, key proto.Key, txn *proto.Transaction, now proto.Timestamp) <-chan struct{} {
+	rq.mu.Lock()
+	raftID := rng.Desc().RaftID
+	intents, ok := rq.intents[raftID]
+	if !ok {
+		intents = map[string]*pendingIntent{}
+		rq.intents[raftID] = intents
+	}
+	pi, ok := intents[string(key)]
+	if ok && bytes.Equal(pi.txn.ID, txn.ID) {
+		rq.mu.Unlock()
+		return pi.done
+	}
+	// Replace any entry for a different transaction; its intent must
+	// already have been resolved for the new txn to have written here.
+	// Release the waiters of the replaced entry, which process will no
+	// longer see.
+	if ok {
+		close(pi.done)
+	}
+	pi = &pendingIntent{key: key, txn: *txn, done: make(chan struct{})}
+	intents[string(key)] = pi
+	rq.mu.Unlock()
+
+	rq.MaybeAdd(rng, now)
+	return pi.done
+}
+
+// shouldQueue returns true if the range has pending intents. The
+// priority is the number of pending intents.
+func (rq *resolveQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	if n := len(rq.intents[rng.Desc().RaftID]); n > 0 {
+		return true, float64(n)
+	}
+	return false, 0
+}
+
+// process resolves all pending intents for the range. Waiters are
+// released whether or not resolution succeeds; a failed resolution
+// simply means the intent will be encountered and pushed again.
+func (rq *resolveQueue) process(now proto.Timestamp, rng *Range) error {
+	rq.mu.Lock()
+	intents := rq.intents[rng.Desc().RaftID]
+	delete(rq.intents, rng.Desc().RaftID)
+	rq.mu.Unlock()
+
+	for _, pi := range intents {
+		resolveArgs := &proto.InternalResolveIntentRequest{
+			RequestHeader: proto.RequestHeader{
+				// Use the pushee's timestamp, which might be lower than the
+				// pusher's request timestamp. No need to push the intent higher
+				// than the pushee's txn!
+				Timestamp: pi.txn.Timestamp,
+				Key:       pi.key,
+				User:      UserRoot,
+				Txn:       &pi.txn,
+			},
+		}
+		resolveReply := &proto.InternalResolveIntentResponse{}
+		if err := rng.AddCmd(proto.InternalResolveIntent, resolveArgs, resolveReply, true); err != nil {
+			log.Warningf("resolve of key %q failed: %s", pi.key, err)
+		}
+		close(pi.done)
+	}
+	return nil
+}
+
+// release drops the pending intents of a range which was dropped from
+// the queue without being processed, for instance to make room for
+// ranges with more pending intents, and releases their waiters. The
+// intents are pushed again if they're still encountered.
+func (rq *resolveQueue) release(rng *Range) {
+	rq.mu.Lock()
+	intents := rq.intents[rng.Desc().RaftID]
+	delete(rq.intents, rng.Desc().RaftID)
+	rq.mu.Unlock()
+	for _, pi := range intents {
+		close(pi.done)
+	}
+}
+
+// timer returns no delay; intents are resolved as soon as possible
+// since reads may be waiting on them.
+func (rq *resolveQueue) timer() time.Duration {
+	return 0
+}
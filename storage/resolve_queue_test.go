@@ -0,0 +1,136 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestResolveQueueResolvesIntent verifies that an intent added to the
+// resolve queue is resolved in the background, and that duplicate
+// additions of the same intent share a single resolution.
+func TestResolveQueueResolvesIntent(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	txn := newTransaction("test", key, 1, proto.SERIALIZABLE, store.clock)
+
+	// Write a value, then lay down an intent over it.
+	args, reply := putArgs(key, []byte("value1"), 1, store.StoreID())
+	args.Timestamp = store.clock.Now()
+	if err := store.ExecuteCmd(proto.Put, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	args.Timestamp = store.clock.Now()
+	args.Txn = txn
+	args.Value.Bytes = []byte("value2")
+	if err := store.ExecuteCmd(proto.Put, args, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	// Abort the txn and hand its intent to the resolve queue twice.
+	aborted := *txn
+	aborted.Status = proto.ABORTED
+	rng := store.LookupRange(key, nil)
+	done1 := store.resolveQueue.addIntent(rng, key, &aborted, store.clock.Now())
+	done2 := store.resolveQueue.addIntent(rng, key, &aborted, store.clock.Now())
+	if done1 != done2 {
+		t.Errorf("expected duplicate intent to share resolution")
+	}
+	select {
+	case <-done1:
+	case <-time.After(5 * time.Second):
+		t.Fatal("intent was not resolved")
+	}
+
+	// A non-transactional read now sees the original value.
+	gArgs, gReply := getArgs(key, 1, store.StoreID())
+	gArgs.Timestamp = store.clock.Now()
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gReply.Value.Bytes, []byte("value1")) {
+		t.Errorf("expected %q; got %q", "value1", gReply.Value.Bytes)
+	}
+	if l := store.resolveQueue.Length(); l != 0 {
+		t.Errorf("expected empty resolve queue; got %d", l)
+	}
+}
+
+// TestResolveQueueReplacedIntent verifies that replacing a pending
+// intent with the intent of another transaction releases the waiters
+// of the replaced intent.
+func TestResolveQueueReplacedIntent(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	rng := store.LookupRange(key, nil)
+	txn1 := newTransaction("test1", key, 1, proto.SERIALIZABLE, store.clock)
+	txn2 := newTransaction("test2", key, 1, proto.SERIALIZABLE, store.clock)
+
+	// The queue isn't started, so nothing is resolved in the background.
+	rq := newResolveQueue()
+	done1 := rq.addIntent(rng, key, txn1, store.clock.Now())
+	done2 := rq.addIntent(rng, key, txn2, store.clock.Now())
+	select {
+	case <-done1:
+	default:
+		t.Error("expected waiters of the replaced intent to be released")
+	}
+	select {
+	case <-done2:
+		t.Error("expected the replacing intent to remain pending")
+	default:
+	}
+}
+
+// TestResolveQueueReleasesEvictedIntents verifies that the waiters of
+// the intents of a range dropped from the queue without being
+// processed are released.
+func TestResolveQueueReleasesEvictedIntents(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	rng := store.LookupRange(key, nil)
+	txn := newTransaction("test", key, 1, proto.SERIALIZABLE, store.clock)
+
+	// The queue isn't started, so nothing is resolved in the background.
+	rq := newResolveQueue()
+	done := rq.addIntent(rng, key, txn, store.clock.Now())
+	if rq.Length() != 1 {
+		t.Fatalf("expected range to be queued; got queue length %d", rq.Length())
+	}
+	rq.MaybeRemove(rng)
+	select {
+	case <-done:
+	default:
+		t.Error("expected waiters of the evicted range's intents to be released")
+	}
+	if len(rq.intents) != 0 {
+		t.Errorf("expected evicted range's intents to be dropped; got %+v", rq.intents)
+	}
+}
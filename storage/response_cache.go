@@ -39,6 +39,13 @@ func makeCmdIDKey(cmdID proto.ClientCmdID) cmdIDKey {
 	return cmdIDKey(string(buf))
 }
 
+// cmdID decodes the command ID from which the key was made.
+func (k cmdIDKey) cmdID() proto.ClientCmdID {
+	b, wallTime := encoding.DecodeUint64([]byte(k))
+	_, random := encoding.DecodeUint64(b)
+	return proto.ClientCmdID{WallTime: int64(wallTime), Random: int64(random)}
+}
+
 // A ResponseCache provides idempotence for request retries. Each
 // request to a range specifies a ClientCmdID in the request header
 // which uniquely identifies a client command. After commands have
@@ -79,12 +86,34 @@ func (rc *ResponseCache) ClearInflight() {
 	rc.inflight = map[cmdIDKey]*sync.Cond{}
 }
 
+// ClearExpiredInflight removes pending commands from the inflight map
+// whose command IDs have wall times before minWallTime, signaling any
+// waiters. Such commands have outlived the client retry window and,
+// if abandoned without completing, would otherwise block retries
+// indefinitely. Commands in the supplied set, which are still being
+// executed, are left in place: releasing their retries could execute
+// them twice. Returns the number of commands removed.
+func (rc *ResponseCache) ClearExpiredInflight(minWallTime int64, executing map[cmdIDKey]struct{}) int {
+	rc.Lock()
+	defer rc.Unlock()
+	var count int
+	for key, cond := range rc.inflight {
+		if _, ok := executing[key]; ok {
+			continue
+		}
+		if _, wallTime := encoding.DecodeUint64([]byte(key)); int64(wallTime) < minWallTime {
+			cond.Broadcast()
+			delete(rc.inflight, key)
+			count++
+		}
+	}
+	return count
+}
+
 // ClearData removes all items stored in the persistent cache. It does not alter
 // the inflight map.
 func (rc *ResponseCache) ClearData() error {
-	p := engine.ResponseCacheKey(rc.raftID, nil) // prefix for all response cache entries with this raft ID
-	end := p.PrefixEnd()
-	_, err := engine.ClearRange(rc.engine, engine.MVCCEncodeKey(p), engine.MVCCEncodeKey(end))
+	_, err := engine.ClearPrefix(rc.engine, engine.ResponseCachePrefix(rc.raftID))
 	return err
 }
 
@@ -134,6 +163,23 @@ func (rc *ResponseCache) GetResponse(cmdID proto.ClientCmdID, reply proto.Respon
 	return false, nil
 }
 
+// lookupResponse reads the response cached for cmdID into reply and
+// returns true if found. Unlike GetResponse, it neither waits for nor
+// marks the command as inflight, so that it may be used while applying
+// Raft commands.
+func (rc *ResponseCache) lookupResponse(cmdID proto.ClientCmdID, reply proto.Response) (bool, error) {
+	if cmdID.IsEmpty() {
+		return false, nil
+	}
+	rwResp := proto.ReadWriteCmdResponse{}
+	key := engine.ResponseCacheKey(rc.raftID, &cmdID)
+	ok, err := engine.MVCCGetProto(rc.engine, key, proto.ZeroTimestamp, true, nil, &rwResp)
+	if ok && err == nil && rwResp.GetValue() != nil {
+		gogoproto.Merge(reply.(gogoproto.Message), rwResp.GetValue().(gogoproto.Message))
+	}
+	return ok, err
+}
+
 // CopyInto copies all the cached results from one response cache into
 // another. The cache will be locked while copying is in progress;
 // failures decoding individual cache entries return an error. The
@@ -143,11 +189,7 @@ func (rc *ResponseCache) CopyInto(e engine.Engine, destRaftID int64) error {
 	rc.Lock()
 	defer rc.Unlock()
 
-	prefix := engine.ResponseCacheKey(rc.raftID, nil) // response cache prefix
-	start := engine.MVCCEncodeKey(prefix)
-	end := engine.MVCCEncodeKey(prefix.PrefixEnd())
-
-	return rc.engine.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+	return engine.IteratePrefix(rc.engine, engine.ResponseCachePrefix(rc.raftID), func(kv proto.RawKeyValue) (bool, error) {
 		// Decode the key into a cmd, skipping on error. Otherwise,
 		// write it to the corresponding key in the new cache.
 		cmdID, err := rc.decodeResponseCacheKey(kv.Key)
@@ -176,11 +218,7 @@ func (rc *ResponseCache) CopyInto(e engine.Engine, destRaftID int64) error {
 // error. The copy is done directly using the engine instead of interpreting
 // values through MVCC for efficiency.
 func (rc *ResponseCache) CopyFrom(e engine.Engine, originRaftID int64) error {
-	prefix := engine.ResponseCacheKey(originRaftID, nil) // response cache prefix
-	start := engine.MVCCEncodeKey(prefix)
-	end := engine.MVCCEncodeKey(prefix.PrefixEnd())
-
-	return e.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+	return engine.IteratePrefix(e, engine.ResponseCachePrefix(originRaftID), func(kv proto.RawKeyValue) (bool, error) {
 		// Decode the key into a cmd, skipping on error. Otherwise,
 		// write it to the corresponding key in the new cache.
 		cmdID, err := rc.decodeResponseCacheKey(kv.Key)
@@ -209,26 +247,38 @@ func (rc *ResponseCache) CopyFrom(e engine.Engine, originRaftID int64) error {
 // command will be signaled to wakeup and read the command response
 // from the cache.
 func (rc *ResponseCache) PutResponse(cmdID proto.ClientCmdID, reply proto.Response) error {
+	// Write response to cache before removing the inflight entry!
+	err := rc.putResponse(rc.engine, cmdID, reply)
+	rc.removeInflight(cmdID)
+	return err
+}
+
+// putResponse writes a response for the specified cmdID to the
+// supplied engine, typically the batch holding the writes of the
+// command, so that the response is committed atomically with them.
+// The inflight entry corresponding to cmdID must be removed via
+// removeInflight once the batch has been committed.
+func (rc *ResponseCache) putResponse(e engine.Engine, cmdID proto.ClientCmdID, reply proto.Response) error {
 	// Do nothing if command ID is empty.
-	if cmdID.IsEmpty() {
+	if cmdID.IsEmpty() || !rc.shouldCacheResponse(reply) {
 		return nil
 	}
-	// Write the response value to the engine.
-	var err error
-	if rc.shouldCacheResponse(reply) {
-		key := engine.ResponseCacheKey(rc.raftID, &cmdID)
-		rwResp := &proto.ReadWriteCmdResponse{}
-		rwResp.SetValue(reply)
-		err = engine.MVCCPutProto(rc.engine, nil, key, proto.ZeroTimestamp, nil, rwResp)
-	}
+	key := engine.ResponseCacheKey(rc.raftID, &cmdID)
+	rwResp := &proto.ReadWriteCmdResponse{}
+	rwResp.SetValue(reply)
+	return engine.MVCCPutProto(e, nil, key, proto.ZeroTimestamp, nil, rwResp)
+}
 
-	// Take lock after writing response to cache!
+// removeInflight removes the inflight entry corresponding to cmdID,
+// signaling any requests waiting on the outcome of the command to
+// read its response from the cache.
+func (rc *ResponseCache) removeInflight(cmdID proto.ClientCmdID) {
+	if cmdID.IsEmpty() {
+		return
+	}
 	rc.Lock()
 	defer rc.Unlock()
-	// Even on error, we remove the entry from the inflight map.
 	rc.removeInflightLocked(cmdID)
-
-	return err
 }
 
 // shouldCacheResponse returns whether the response should be cached.
@@ -267,6 +317,13 @@ func (rc *ResponseCache) removeInflightLocked(cmdID proto.ClientCmdID) {
 }
 
 func (rc *ResponseCache) decodeResponseCacheKey(encKey proto.EncodedKey) (proto.ClientCmdID, error) {
+	return decodeRangeIDCmdIDKey(encKey, engine.KeyLocalResponseCacheSuffix)
+}
+
+// decodeRangeIDCmdIDKey decodes the command ID from a range-ID local
+// key with the supplied suffix and a command ID for detail, such as a
+// response cache key.
+func decodeRangeIDCmdIDKey(encKey proto.EncodedKey, suffix proto.Key) (proto.ClientCmdID, error) {
 	ret := proto.ClientCmdID{}
 	key, _, isValue := engine.MVCCDecodeKey(encKey)
 	if isValue {
@@ -278,12 +335,11 @@ func (rc *ResponseCache) decodeResponseCacheKey(encKey proto.EncodedKey) (proto.
 	// Cut the prefix and the Raft ID.
 	b := key[len(engine.KeyLocalRangeIDPrefix):]
 	b, _ = encoding.DecodeUvarint(b)
-	if !bytes.HasPrefix(b, engine.KeyLocalResponseCacheSuffix) {
-		return ret, util.Errorf("key %s does not contain the response cache suffix %s",
-			key, engine.KeyLocalResponseCacheSuffix)
+	if !bytes.HasPrefix(b, suffix) {
+		return ret, util.Errorf("key %s does not contain the suffix %s", key, suffix)
 	}
-	// Cut the response cache suffix.
-	b = b[len(engine.KeyLocalResponseCacheSuffix):]
+	// Cut the suffix.
+	b = b[len(suffix):]
 	// Now, decode the command ID.
 	b, wt := encoding.DecodeUvarint(b)
 	b, rd := encoding.DecodeUint64(b)
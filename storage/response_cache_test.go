@@ -71,6 +71,28 @@ func TestResponseCachePutGetClearData(t *testing.T) {
 	}
 }
 
+// TestResponseCachePutResponseBatch verifies that a response written
+// to a batch becomes visible only once the batch is committed.
+func TestResponseCachePutResponseBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rc := createTestResponseCache(t, 1)
+	cmdID := makeCmdID(1, 1)
+	val := proto.IncrementResponse{}
+	batch := rc.engine.NewBatch()
+	if err := rc.putResponse(batch, cmdID, &incR); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := rc.lookupResponse(cmdID, &val); ok || err != nil {
+		t.Errorf("expected no response before commit; got %t, %v, %+v", ok, err, val)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := rc.lookupResponse(cmdID, &val); !ok || err != nil || val.NewValue != 1 {
+		t.Errorf("unexpected failure getting response: %t, %v, %+v", ok, err, val)
+	}
+}
+
 // TestResponseCacheEmptyCmdID tests operation with empty client
 // command id. All calls should be noops.
 func TestResponseCacheEmptyCmdID(t *testing.T) {
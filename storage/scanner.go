@@ -130,11 +130,11 @@ func (rs *rangeScanner) RemoveRange(rng *Range) {
 // is paced to complete a full scan in approximately the scan interval.
 func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 	stopper.RunWorker(func() {
-		start := time.Now()
+		start := util.Now()
 		stats := &storeStats{}
 
 		for {
-			elapsed := time.Now().Sub(start)
+			elapsed := util.Now().Sub(start)
 			remainingNanos := rs.interval.Nanoseconds() - elapsed.Nanoseconds()
 			if remainingNanos < 0 {
 				remainingNanos = 0
@@ -145,8 +145,9 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 			}
 			log.V(6).Infof("next range scan iteration in %s", nextIteration)
 
+			timer := util.NewTimer(nextIteration)
 			select {
-			case <-time.After(nextIteration):
+			case <-timer.C:
 				if !stopper.StartTask() {
 					continue
 				}
@@ -161,7 +162,7 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 				} else {
 					// Otherwise, we're done with the iteration. Reset iteration and start time.
 					rs.iter.Reset()
-					start = time.Now()
+					start = util.Now()
 					// Increment iteration counter.
 					atomic.AddInt64(&rs.count, 1)
 					// Store the most recent scan results in the scanner's stats.
@@ -172,6 +173,7 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 				stopper.FinishTask()
 
 			case rng := <-rs.removed:
+				timer.Stop()
 				// Remove range from all queues as applicable.
 				for _, q := range rs.queues {
 					q.MaybeRemove(rng)
@@ -179,6 +181,7 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 				log.V(6).Infof("removed range %s", rng)
 
 			case <-stopper.ShouldStop():
+				timer.Stop()
 				// Exit the loop.
 				return
 			}
@@ -0,0 +1,151 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// defaultMaxConcurrentSnapshots is the default number of snapshots a
+// store generates and sends at once.
+const defaultMaxConcurrentSnapshots = 2
+
+// A snapshotPriority orders the snapshots waiting in a snapshotQueue.
+type snapshotPriority int
+
+const (
+	// rebalanceSnapshot is the priority of preemptive snapshots sent to
+	// replicas being added by rebalancing.
+	rebalanceSnapshot snapshotPriority = iota
+	// recoverySnapshot is the priority of snapshots Raft sends to
+	// replicas which have fallen behind, and of preemptive snapshots
+	// sent to replicas added to restore a range's replication.
+	recoverySnapshot
+	numSnapshotPriorities
+)
+
+// A snapshotQueue admits the snapshots sent by a store, so that they
+// don't compete with foreground traffic for disk, memory and network.
+// At most maxConcurrent snapshots are generated and sent at once;
+// waiting snapshots are admitted in order of priority and then of
+// arrival, so that recovery snapshots overtake rebalance snapshots.
+// Once admitted, the bandwidth used by the snapshots of each priority
+// is limited by its own throttle.
+type snapshotQueue struct {
+	maxConcurrent int // Negative for no limit
+	throttles     [numSnapshotPriorities]*snapshotThrottle
+
+	mu      sync.Mutex
+	active  int                                    // Snapshots admitted and not yet released
+	waiting [numSnapshotPriorities][]chan struct{} // Closed on admission
+}
+
+// newSnapshotQueue returns a snapshotQueue which admits maxConcurrent
+// snapshots at once. Bandwidth isn't limited until setRates is called.
+func newSnapshotQueue(maxConcurrent int) *snapshotQueue {
+	sq := &snapshotQueue{maxConcurrent: maxConcurrent}
+	for pri := range sq.throttles {
+		sq.throttles[pri] = newSnapshotThrottle(0)
+	}
+	return sq
+}
+
+// setRates limits the bandwidth of recovery and rebalance snapshots
+// to the supplied rates in bytes per second. Zero imposes no limit.
+func (sq *snapshotQueue) setRates(recoveryRate, rebalanceRate int64) {
+	sq.throttles[recoverySnapshot].setRate(recoveryRate)
+	sq.throttles[rebalanceSnapshot].setRate(rebalanceRate)
+}
+
+// limited returns whether snapshots of priority pri may be delayed.
+func (sq *snapshotQueue) limited(pri snapshotPriority) bool {
+	return sq.maxConcurrent >= 0 || sq.throttles[pri].limited()
+}
+
+// acquire blocks until a snapshot of priority pri is admitted.
+// Returns false if stop is closed first. Each successful acquire must
+// be followed by a release.
+func (sq *snapshotQueue) acquire(pri snapshotPriority, stop <-chan struct{}) bool {
+	sq.mu.Lock()
+	// Admitted snapshots are released to waiting snapshots directly, so
+	// none are waiting while there is room.
+	if sq.maxConcurrent < 0 || sq.active < sq.maxConcurrent {
+		sq.active++
+		sq.mu.Unlock()
+		return true
+	}
+	admitted := make(chan struct{})
+	sq.waiting[pri] = append(sq.waiting[pri], admitted)
+	sq.mu.Unlock()
+
+	select {
+	case <-admitted:
+		return true
+	case <-stop:
+	}
+	sq.mu.Lock()
+	for i, ch := range sq.waiting[pri] {
+		if ch == admitted {
+			sq.waiting[pri] = append(sq.waiting[pri][:i], sq.waiting[pri][i+1:]...)
+			sq.mu.Unlock()
+			return false
+		}
+	}
+	sq.mu.Unlock()
+	// The snapshot was admitted as stop was closed.
+	sq.release()
+	return false
+}
+
+// release ends an admitted snapshot, admitting the first waiting
+// snapshot of the highest priority, if any.
+func (sq *snapshotQueue) release() {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	for pri := numSnapshotPriorities - 1; pri >= 0; pri-- {
+		if len(sq.waiting[pri]) > 0 {
+			close(sq.waiting[pri][0])
+			sq.waiting[pri] = sq.waiting[pri][1:]
+			return
+		}
+	}
+	sq.active--
+}
+
+// send generates a snapshot of priority pri by calling generate once
+// the snapshot is admitted, and sends it through transport to the
+// specified node once its throttle allows. Preemptive snapshots are
+// only generated on admission, which bounds the memory they hold while
+// waiting. Snapshots Raft sends have already been generated by Raft,
+// so each waiting one holds its data.
+func (sq *snapshotQueue) send(pri snapshotPriority, to multiraft.NodeID, transport multiraft.Transport,
+	generate func() (*multiraft.RaftMessageRequest, error), stop <-chan struct{}) error {
+	if !sq.acquire(pri, stop) {
+		return util.Errorf("snapshot to node %v abandoned as store is stopping", to)
+	}
+	defer sq.release()
+	req, err := generate()
+	if err != nil {
+		return err
+	}
+	if !sq.throttles[pri].wait(int64(len(req.Message.Snapshot.Data)), stop) {
+		return util.Errorf("snapshot of group %d to node %v abandoned as store is stopping", req.GroupID, to)
+	}
+	return transport.Send(to, req)
+}
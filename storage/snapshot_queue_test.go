@@ -0,0 +1,100 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// waitForSnapshots waits until the queue holds the given number of
+// waiting snapshots of priority pri.
+func waitForSnapshots(t *testing.T, sq *snapshotQueue, pri snapshotPriority, n int) {
+	if err := util.IsTrueWithin(func() bool {
+		sq.mu.Lock()
+		defer sq.mu.Unlock()
+		return len(sq.waiting[pri]) == n
+	}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSnapshotQueuePriority verifies that the queue admits no more
+// than its limit of concurrent snapshots, and that waiting recovery
+// snapshots are admitted before rebalance snapshots which arrived
+// earlier.
+func TestSnapshotQueuePriority(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	sq := newSnapshotQueue(1)
+	if !sq.acquire(rebalanceSnapshot, nil) {
+		t.Fatal("expected first snapshot to be admitted")
+	}
+
+	admitted := make(chan snapshotPriority)
+	acquire := func(pri snapshotPriority) {
+		if sq.acquire(pri, nil) {
+			admitted <- pri
+		}
+	}
+	go acquire(rebalanceSnapshot)
+	waitForSnapshots(t, sq, rebalanceSnapshot, 1)
+	go acquire(recoverySnapshot)
+	waitForSnapshots(t, sq, recoverySnapshot, 1)
+
+	select {
+	case pri := <-admitted:
+		t.Fatalf("expected snapshots to wait; admitted priority %d", pri)
+	case <-time.After(10 * time.Millisecond):
+	}
+	for _, expected := range []snapshotPriority{recoverySnapshot, rebalanceSnapshot} {
+		sq.release()
+		if pri := <-admitted; pri != expected {
+			t.Errorf("expected priority %d to be admitted; got %d", expected, pri)
+		}
+	}
+	sq.release()
+	if sq.active != 0 {
+		t.Errorf("expected no active snapshots; got %d", sq.active)
+	}
+}
+
+// TestSnapshotQueueStop verifies that waiting snapshots are abandoned
+// when stopping, without taking the place of admitted snapshots.
+func TestSnapshotQueueStop(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	sq := newSnapshotQueue(1)
+	if !sq.acquire(recoverySnapshot, nil) {
+		t.Fatal("expected first snapshot to be admitted")
+	}
+	stop := make(chan struct{})
+	admitted := make(chan bool)
+	go func() {
+		admitted <- sq.acquire(rebalanceSnapshot, stop)
+	}()
+	waitForSnapshots(t, sq, rebalanceSnapshot, 1)
+	close(stop)
+	if <-admitted {
+		t.Error("expected waiting snapshot to be abandoned")
+	}
+	waitForSnapshots(t, sq, rebalanceSnapshot, 0)
+	sq.release()
+	if sq.active != 0 {
+		t.Errorf("expected no active snapshots; got %d", sq.active)
+	}
+}
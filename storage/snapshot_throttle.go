@@ -0,0 +1,157 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+// A snapshotThrottle limits the bandwidth a store uses to send
+// snapshots using a token bucket which holds up to a second's worth
+// of bytes. A snapshot larger than the bucket is sent once the bucket
+// is full and leaves it in debt, so that the average rate is held to
+// the limit. Snapshots are sent in the order in which they arrive.
+// The rate may be changed at any time, as the cluster settings from
+// which it's taken are updated.
+type snapshotThrottle struct {
+	mu     sync.Mutex
+	rate   int64     // Bytes per second; 0 for no limit
+	tokens float64   // Bytes which may be sent; negative when in debt
+	last   time.Time // Time at which tokens was last refilled
+}
+
+// newSnapshotThrottle returns a snapshotThrottle which limits
+// snapshots to rate bytes per second.
+func newSnapshotThrottle(rate int64) *snapshotThrottle {
+	return &snapshotThrottle{rate: rate, tokens: float64(rate), last: util.Now()}
+}
+
+// setRate changes the limit to rate bytes per second. A throttle which
+// wasn't limited starts with a full bucket.
+func (st *snapshotThrottle) setRate(rate int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.rate <= 0 {
+		st.tokens = float64(rate)
+		st.last = util.Now()
+	}
+	st.rate = rate
+}
+
+// limited returns whether the throttle limits bandwidth.
+func (st *snapshotThrottle) limited() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.rate > 0
+}
+
+// wait blocks until a snapshot of size bytes may be sent. Returns
+// false if stop is closed first.
+func (st *snapshotThrottle) wait(size int64, stop <-chan struct{}) bool {
+	st.mu.Lock()
+	if st.rate <= 0 {
+		st.mu.Unlock()
+		return true
+	}
+	now := util.Now()
+	burst := float64(st.rate)
+	st.tokens += now.Sub(st.last).Seconds() * float64(st.rate)
+	if st.tokens > burst {
+		st.tokens = burst
+	}
+	st.last = now
+	// A snapshot may be sent once the bucket holds its size or is full.
+	need := float64(size)
+	if need > burst {
+		need = burst
+	}
+	var wait time.Duration
+	if st.tokens < need {
+		wait = time.Duration((need - st.tokens) / float64(st.rate) * float64(time.Second))
+	}
+	st.tokens -= float64(size)
+	st.mu.Unlock()
+
+	if wait <= 0 {
+		return true
+	}
+	timer := util.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// maxPendingRaftSnapshots is the number of snapshots sent by Raft
+// which a store holds while they await admission and transmission.
+const maxPendingRaftSnapshots = 8
+
+// A throttledTransport is a multiraft.Transport which passes the
+// snapshots which Raft sends to replicas which have fallen behind
+// through the store's snapshot queue at recovery priority. Such
+// snapshots are sent asynchronously once admitted, so that the Raft
+// loop isn't blocked; other messages are sent immediately. At most
+// maxPendingRaftSnapshots are pending at once; further snapshots fail
+// to send, so that Raft retries them later, rather than holding their
+// data and a goroutine each.
+type throttledTransport struct {
+	multiraft.Transport
+	snaps   *snapshotQueue
+	stopper *util.Stopper
+	pending chan struct{} // Semaphore bounding pending snapshots
+}
+
+// newThrottledTransport returns a throttledTransport which sends
+// through transport.
+func newThrottledTransport(transport multiraft.Transport, snaps *snapshotQueue,
+	stopper *util.Stopper) *throttledTransport {
+	return &throttledTransport{
+		Transport: transport,
+		snaps:     snaps,
+		stopper:   stopper,
+		pending:   make(chan struct{}, maxPendingRaftSnapshots),
+	}
+}
+
+// Send implements the multiraft.Transport interface.
+func (t *throttledTransport) Send(id multiraft.NodeID, req *multiraft.RaftMessageRequest) error {
+	if req.Message.Type != raftpb.MsgSnap || !t.snaps.limited(recoverySnapshot) {
+		return t.Transport.Send(id, req)
+	}
+	select {
+	case t.pending <- struct{}{}:
+	default:
+		return util.Errorf("too many pending snapshots to send snapshot of group %d to node %v",
+			req.GroupID, id)
+	}
+	t.stopper.RunWorker(func() {
+		defer func() { <-t.pending }()
+		generate := func() (*multiraft.RaftMessageRequest, error) { return req, nil }
+		if err := t.snaps.send(recoverySnapshot, id, t.Transport, generate, t.stopper.ShouldStop()); err != nil {
+			log.Warningf("failed to send snapshot of group %d to node %v: %s", req.GroupID, id, err)
+		}
+	})
+	return nil
+}
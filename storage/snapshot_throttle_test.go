@@ -0,0 +1,128 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+// TestSnapshotThrottleRate verifies that snapshots are sent
+// immediately while the token bucket holds their size and are
+// otherwise delayed until it's refilled at the limited rate.
+func TestSnapshotThrottleRate(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	st := newSnapshotThrottle(0)
+	for i := 0; i < 10; i++ {
+		if !st.wait(1<<30, nil) {
+			t.Fatal("expected unlimited bandwidth")
+		}
+	}
+
+	const rate = 10000 // Bytes per second
+	st = newSnapshotThrottle(rate)
+	start := util.Now()
+	// The bucket starts full; a snapshot larger than it is admitted
+	// immediately but leaves it in debt.
+	if !st.wait(2*rate, nil) {
+		t.Fatal("expected first snapshot to be admitted")
+	}
+	if elapsed := util.Now().Sub(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected first snapshot to be admitted immediately; took %s", elapsed)
+	}
+	// Repaying the debt and accumulating 1000 bytes takes 1.1s.
+	if !st.wait(1000, nil) {
+		t.Fatal("expected second snapshot to be admitted")
+	}
+	if elapsed := util.Now().Sub(start); elapsed < time.Second {
+		t.Errorf("expected second snapshot to wait at least 1s; waited %s", elapsed)
+	}
+}
+
+// TestSnapshotThrottleStop verifies that snapshots awaiting admission
+// are abandoned when stopping.
+func TestSnapshotThrottleStop(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	st := newSnapshotThrottle(1)
+	stop := make(chan struct{})
+	admitted := make(chan bool)
+	go func() {
+		admitted <- st.wait(1<<20, stop)
+	}()
+	go func() {
+		admitted <- st.wait(1<<20, stop)
+	}()
+	// One snapshot is admitted from the full bucket; the other waits
+	// far longer than the test.
+	if !<-admitted {
+		t.Fatal("expected a snapshot to be admitted")
+	}
+	close(stop)
+	if <-admitted {
+		t.Error("expected a snapshot to be abandoned")
+	}
+}
+
+// TestSnapshotThrottleSetRate verifies that the rate of a throttle
+// may be changed, as the cluster settings are updated.
+func TestSnapshotThrottleSetRate(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	st := newSnapshotThrottle(0)
+	if st.limited() {
+		t.Fatal("expected unlimited bandwidth")
+	}
+	st.setRate(1)
+	if !st.limited() {
+		t.Fatal("expected limited bandwidth")
+	}
+	// The bucket starts full; the next snapshot would wait for hours.
+	if !st.wait(1, nil) {
+		t.Fatal("expected first snapshot to be admitted")
+	}
+	stop := make(chan struct{})
+	close(stop)
+	if st.wait(1<<20, stop) {
+		t.Error("expected snapshot to wait for the limited rate")
+	}
+	st.setRate(0)
+	if !st.wait(1<<30, nil) {
+		t.Error("expected unlimited bandwidth once the limit is removed")
+	}
+}
+
+// TestThrottledTransportPending verifies that snapshots sent by Raft
+// fail to send once the limit of pending snapshots is reached.
+func TestThrottledTransportPending(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	// No snapshots are admitted, so that all remain pending.
+	tt := newThrottledTransport(nil, newSnapshotQueue(0), stopper)
+	req := &multiraft.RaftMessageRequest{GroupID: 1, Message: raftpb.Message{Type: raftpb.MsgSnap}}
+	for i := 0; i < maxPendingRaftSnapshots; i++ {
+		if err := tt.Send(2, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tt.Send(2, req); err == nil {
+		t.Error("expected snapshot beyond the pending limit to fail")
+	}
+}
@@ -18,12 +18,15 @@
 package storage
 
 import (
+	"fmt"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -31,16 +34,38 @@ import (
 const (
 	// splitQueueMaxSize is the max size of the split queue.
 	splitQueueMaxSize = 100
+	// splitQueueMaxConcurrency is the max number of ranges processed at once
+	// by the split queue.
+	splitQueueMaxConcurrency = 1
 	// splitQueueTimerDuration is the duration between splits of queued ranges.
 	splitQueueTimerDuration = 0 * time.Second // zero duration to process splits greedily.
 )
 
-// splitQueue manages a queue of ranges slated to be split due to size
-// or along intersecting accounting or zone config boundaries.
+// SystemSplitKeys are static split keys which keep the range
+// addressing records, each of the config maps and the remaining system
+// data in ranges of their own, apart from user data. This keeps
+// system ranges small and their load independent of user traffic.
+var SystemSplitKeys = []proto.Key{
+	engine.KeyMetaMax,
+	engine.KeyConfigAccountingPrefix,
+	engine.KeyConfigAccountingPrefix.PrefixEnd(),
+	engine.KeyConfigPermissionPrefix,
+	engine.KeyConfigPermissionPrefix.PrefixEnd(),
+	engine.KeyConfigZonePrefix,
+	engine.KeyConfigZonePrefix.PrefixEnd(),
+	engine.KeySystemMax,
+}
+
+// splitQueue manages a queue of ranges slated to be split due to
+// size, request load or along intersecting accounting or zone config
+// boundaries.
 type splitQueue struct {
 	*baseQueue
-	db     *client.KV
-	gossip *gossip.Gossip
+	db           *client.KV
+	gossip       *gossip.Gossip
+	interval     int64       // Minimum interval between splits in nanos; accessed atomically
+	qpsThreshold float64     // Request rate above which ranges split; 0 disables
+	staticKeys   []proto.Key // Keys at which ranges are always split
 	// Some tests in this package disable the split queue.
 	disabled bool
 }
@@ -51,22 +76,24 @@ func newSplitQueue(db *client.KV, gossip *gossip.Gossip) *splitQueue {
 		db:     db,
 		gossip: gossip,
 	}
-	sq.baseQueue = newBaseQueue("split", sq, splitQueueMaxSize)
+	sq.baseQueue = newBaseQueue("split", sq, splitQueueMaxSize, splitQueueMaxConcurrency)
 	return sq
 }
 
 // shouldQueue determines whether a range should be queued for
 // splitting. This is true if the range is intersected by any
-// accounting or zone config prefix or if the range's size in
-// bytes exceeds the limit for the zone.
+// accounting or zone config prefix or static split key, if the range's size in bytes
+// exceeds the limit for the zone or if the range's request rate
+// exceeds the queue's threshold.
 func (sq *splitQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
 	// Only queue for Split if this replica is leader.
 	if !rng.IsLeader() || sq.disabled {
 		return
 	}
 
-	// Set priority to 1 in the event the range is split by acct or zone configs.
-	if len(computeSplitKeys(sq.gossip, rng)) > 0 {
+	// Set priority to 1 in the event the range is split by acct or zone
+	// configs or static split keys.
+	if len(computeSplitKeys(sq.gossip, rng, sq.staticKeys)) > 0 {
 		priority = 1
 		shouldQ = true
 	}
@@ -82,6 +109,15 @@ func (sq *splitQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool
 		priority += ratio
 		shouldQ = true
 	}
+
+	// Add priority based on the request rate of the range compared to
+	// the threshold for load-based splits.
+	if sq.qpsThreshold > 0 {
+		if ratio := rng.loadStats.QPS(now.WallTime) / sq.qpsThreshold; ratio > 1 {
+			priority += ratio
+			shouldQ = true
+		}
+	}
 	return
 }
 
@@ -91,14 +127,16 @@ func (sq *splitQueue) process(now proto.Timestamp, rng *Range) error {
 		log.Infof("not leader of range %s; skipping split", rng)
 		return nil
 	}
-	// First handle case of splitting due to accounting and zone config maps.
-	splitKeys := computeSplitKeys(sq.gossip, rng)
+	// First handle case of splitting due to accounting and zone config
+	// maps and static split keys.
+	splitKeys := computeSplitKeys(sq.gossip, rng, sq.staticKeys)
 	if len(splitKeys) > 0 {
 		log.Infof("splitting range %q-%q at keys %v", rng.Desc().StartKey, rng.Desc().EndKey, splitKeys)
 		for _, splitKey := range splitKeys {
 			req := &proto.AdminSplitRequest{
 				RequestHeader: proto.RequestHeader{Key: splitKey},
 				SplitKey:      splitKey,
+				Reason:        "accounting or zone config boundary or static split key",
 			}
 			if err := sq.db.Call(proto.AdminSplit, req, &proto.AdminSplitResponse{}); err != nil {
 				return util.Errorf("unable to split at key %q: %s", splitKey, err)
@@ -106,6 +144,26 @@ func (sq *splitQueue) process(now proto.Timestamp, rng *Range) error {
 		}
 		return nil
 	}
+	// Next handle case of splitting due to load.
+	if qps := rng.loadStats.QPS(now.WallTime); sq.qpsThreshold > 0 && qps > sq.qpsThreshold {
+		desc := rng.Desc()
+		splitKey := rng.loadStats.splitKey(func(key proto.Key) bool {
+			return engine.IsValidSplitKey(key) && rng.ContainsKey(key) && !key.Equal(desc.StartKey)
+		})
+		if splitKey != nil {
+			log.Infof("splitting range %q-%q at key %q to divide load of %.1f qps",
+				desc.StartKey, desc.EndKey, splitKey, qps)
+			if err := rng.AddCmd(proto.AdminSplit, &proto.AdminSplitRequest{
+				RequestHeader: proto.RequestHeader{Key: splitKey},
+				SplitKey:      splitKey,
+				Reason:        fmt.Sprintf("load of %.1f qps", qps),
+			}, &proto.AdminSplitResponse{}, true); err != nil {
+				return util.Errorf("unable to split at key %q: %s", splitKey, err)
+			}
+			rng.loadStats.reset()
+			return nil
+		}
+	}
 	// Next handle case of splitting due to size.
 	zone, err := lookupZoneConfig(sq.gossip, rng)
 	if err != nil {
@@ -114,23 +172,40 @@ func (sq *splitQueue) process(now proto.Timestamp, rng *Range) error {
 	if float64(rng.stats.GetSize())/float64(zone.RangeMaxBytes) > 1 {
 		rng.AddCmd(proto.AdminSplit, &proto.AdminSplitRequest{
 			RequestHeader: proto.RequestHeader{Key: rng.Desc().StartKey},
+			Reason:        fmt.Sprintf("size of %d bytes", rng.stats.GetSize()),
 		}, &proto.AdminSplitResponse{}, true)
 	}
 	return nil
 }
 
+// setInterval sets the minimum interval between splits. Zero imposes
+// no limit.
+func (sq *splitQueue) setInterval(interval time.Duration) {
+	atomic.StoreInt64(&sq.interval, int64(interval))
+}
+
 // timer returns interval between processing successive queued splits.
 func (sq *splitQueue) timer() time.Duration {
+	if interval := time.Duration(atomic.LoadInt64(&sq.interval)); interval > splitQueueTimerDuration {
+		return interval
+	}
 	return splitQueueTimerDuration
 }
 
 // computeSplitKeys returns an array of keys at which the supplied
 // range should be split, as computed by intersecting the range with
-// accounting and zone config map boundaries.
-func computeSplitKeys(g *gossip.Gossip, rng *Range) []proto.Key {
+// accounting and zone config map boundaries and the supplied static
+// split keys.
+func computeSplitKeys(g *gossip.Gossip, rng *Range, staticKeys []proto.Key) []proto.Key {
+	splitKeys := proto.KeySlice{}
+	for _, key := range staticKeys {
+		if rng.Desc().StartKey.Less(key) && key.Less(rng.Desc().EndKey) {
+			splitKeys = append(splitKeys, key)
+		}
+	}
+
 	// Now split the range into pieces by intersecting it with the
 	// boundaries of the config map.
-	splitKeys := proto.KeySlice{}
 	for _, configKey := range []string{gossip.KeyConfigAccounting, gossip.KeyConfigZone} {
 		info, err := g.GetInfo(configKey)
 		if err != nil {
@@ -152,7 +227,7 @@ func computeSplitKeys(g *gossip.Gossip, rng *Range) []proto.Key {
 	}
 
 	// Sort and unique the combined split keys from intersections with
-	// both the accounting and zone config maps.
+	// both the accounting and zone config maps and the static keys.
 	sort.Sort(splitKeys)
 	var unique []proto.Key
 	for i, key := range splitKeys {
@@ -19,6 +19,7 @@ package storage
 
 import (
 	"math"
+	"reflect"
 	"testing"
 	"time"
 
@@ -102,7 +103,95 @@ func TestSplitQueueShouldQueue(t *testing.T) {
 	}
 }
 
+// TestSplitQueueShouldQueueLoad verifies that ranges whose request
+// rate exceeds the threshold are queued with priority proportional
+// to their load.
+func TestSplitQueueShouldQueueLoad(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{RangeMaxBytes: 64 << 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	splitQ := newSplitQueue(nil, tc.gossip)
+	splitQ.qpsThreshold = 100
+	now := tc.clock.Now()
+
+	testCases := []struct {
+		qps      float64
+		shouldQ  bool
+		priority float64
+	}{
+		{0, false, 0},
+		{50, false, 0},
+		{100, false, 0},
+		{300, true, 3},
+	}
+	for i, test := range testCases {
+		tc.rng.loadStats.Lock()
+		tc.rng.loadStats.windowStart = now.WallTime
+		tc.rng.loadStats.qps = test.qps
+		tc.rng.loadStats.Unlock()
+		shouldQ, priority := splitQ.shouldQueue(now, tc.rng)
+		if shouldQ != test.shouldQ {
+			t.Errorf("%d: should queue expected %t; got %t", i, test.shouldQ, shouldQ)
+		}
+		if math.Abs(priority-test.priority) > 0.00001 {
+			t.Errorf("%d: priority expected %f; got %f", i, test.priority, priority)
+		}
+	}
+}
+
 ////
 // NOTE: tests which actually verify processing of the split queue are
 // in client_split_test.go, which is in a different test package in
 // order to allow for distributed transactions with a proper client.
+
+// TestComputeSplitKeysStatic verifies that ranges are split at the
+// static split keys which fall strictly within them.
+func TestComputeSplitKeysStatic(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	configMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{RangeMaxBytes: 64 << 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{gossip.KeyConfigAccounting, gossip.KeyConfigZone} {
+		if err := tc.gossip.AddInfo(key, configMap, 0*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		start, end proto.Key
+		expKeys    []proto.Key
+	}{
+		{proto.KeyMin, proto.KeyMax, SystemSplitKeys},
+		{engine.KeyConfigZonePrefix, proto.KeyMax, []proto.Key{engine.KeyConfigZonePrefix.PrefixEnd(), engine.KeySystemMax}},
+		{engine.KeyConfigZonePrefix, engine.KeyConfigZonePrefix.PrefixEnd(), nil},
+		{engine.KeySystemMax, proto.KeyMax, nil},
+	}
+	for i, test := range testCases {
+		desc := *tc.rng.Desc()
+		desc.StartKey = test.start
+		desc.EndKey = test.end
+		tc.rng.SetDesc(&desc)
+		if keys := computeSplitKeys(tc.gossip, tc.rng, SystemSplitKeys); !reflect.DeepEqual(keys, test.expKeys) {
+			t.Errorf("%d: expected split keys %s; got %s", i, test.expKeys, keys)
+		}
+	}
+}
@@ -59,6 +59,16 @@ func (rs *rangeStats) GetMVCC() engine.MVCCStats {
 	return rs.MVCCStats
 }
 
+// GetAgedMVCC returns a copy of the underlying MVCCStats with the
+// intent and GC bytes ages advanced to nowNanos.
+func (rs *rangeStats) GetAgedMVCC(nowNanos int64) engine.MVCCStats {
+	rs.Lock()
+	defer rs.Unlock()
+	ms := rs.MVCCStats
+	ms.AgeTo(nowNanos)
+	return ms
+}
+
 // GetSize returns the range size as the sum of the key and value
 // bytes. This includes all non-live keys and all versioned values.
 func (rs *rangeStats) GetSize() int64 {
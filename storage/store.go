@@ -53,6 +53,12 @@ const (
 	defaultScanInterval = 10 * time.Minute
 	// ttlCapacityGossip is time-to-live for capacity-related info.
 	ttlCapacityGossip = 2 * time.Minute
+	// gcTimeoutsInterval is the interval at which the GC timeouts used
+	// by engine compactions are advanced.
+	gcTimeoutsInterval = 1 * time.Minute
+	// preemptiveSnapshotGCInterval is the interval at which ranges
+	// initialized by preemptive snapshots are checked for expiration.
+	preemptiveSnapshotGCInterval = 10 * time.Second
 )
 
 var (
@@ -65,6 +71,12 @@ var (
 		MaxAttempts: 0, // retry indefinitely
 	}
 
+	// preemptiveSnapshotExpiration is how long a range initialized by
+	// a preemptive snapshot may wait to be added to its Raft group
+	// before the store considers the replica change aborted and
+	// destroys the range's data.
+	preemptiveSnapshotExpiration = 1 * time.Minute
+
 	scanInterval = flag.Duration("scan_interval", defaultScanInterval, "specify "+
 		"--scan_interval to adjust the target for the duration of a single scan "+
 		"through a store's ranges. The scan is slowed as necessary to approximately"+
@@ -199,6 +211,7 @@ type StoreDescriptor struct {
 	Attrs    proto.Attributes // store specific attributes (e.g. ssd, hdd, mem)
 	Node     NodeDescriptor
 	Capacity engine.StoreCapacity
+	Stats    StoreStats
 }
 
 // CombinedAttrs returns the full list of attributes for the store,
@@ -253,6 +266,14 @@ func (si *storeRangeIterator) Reset() {
 	si.index = 0
 }
 
+// An IntentLimiter limits the intents which each transaction may
+// write, such as the TxnCoordSender of the store's node.
+type IntentLimiter interface {
+	// SetIntentLimits sets the maximum number of intents and total
+	// intent bytes per transaction. Zero imposes no limit.
+	SetIntentLimits(maxIntents, maxIntentBytes int64)
+}
+
 // StoreConfig contains various parameters of a Store.
 type StoreConfig struct {
 	// RaftTickInterval is the resolution of the Raft timer; other raft timeouts
@@ -267,6 +288,75 @@ type StoreConfig struct {
 	// higher than RaftHeartbeatIntervalTicks. The raft paper recommends a value of 150ms
 	// for local networks.
 	RaftElectionTimeoutTicks int
+
+	// RaftQuiesceTicks is the number of ticks without traffic after
+	// which a range's raft group is quiesced: it stops being ticked and
+	// heartbeated until the next request for the range wakes it. A
+	// negative value disables quiescing.
+	RaftQuiesceTicks int
+
+	// RaftReproposalTicks is the number of ticks a proposed command may
+	// go uncommitted before it's proposed again, in case its proposal
+	// was lost. Commands committed more than once are applied once. A
+	// negative value disables reproposal.
+	RaftReproposalTicks int
+
+	// ClockJumpThreshold is the divergence between the advance of the
+	// wall clock and elapsed time beyond which the store considers the
+	// clock to have jumped and suspends leader leases. A negative value
+	// disables clock jump detection.
+	ClockJumpThreshold time.Duration
+
+	// SplitQPS is the request rate above which the split queue splits
+	// a range to spread its load. Zero disables load-based splits.
+	SplitQPS float64
+
+	// StaticSplitKeys are keys at which the split queue always splits
+	// ranges, such as SystemSplitKeys.
+	StaticSplitKeys []proto.Key
+
+	// RangeCreationInterval is the minimum interval between the
+	// creation of ranges by the store, whether by splitting ranges or
+	// by adding replicas to them. Zero imposes no limit.
+	RangeCreationInterval time.Duration
+
+	// MaxConcurrentSnapshots is the number of snapshots the store
+	// generates and sends at once. Waiting recovery snapshots are
+	// admitted before rebalance snapshots. A negative value imposes no
+	// limit.
+	MaxConcurrentSnapshots int
+
+	// MaxPendingProposals is the number of write commands awaiting Raft
+	// commit and application at which the store is overloaded and sheds
+	// client commands. A negative value imposes no limit.
+	MaxPendingProposals int64
+
+	// Authorizer, if set, decides whether each command may be executed.
+	// Defaults to an Authorizer which consults the permission configs.
+	Authorizer Authorizer
+
+	// IntentLimiter, if set, receives the limits on the intents written
+	// by each transaction held by the default zone config.
+	IntentLimiter IntentLimiter
+
+	// ExportSink, if set, stores the files written by InternalExport.
+	// Exports fail on stores without an export sink.
+	ExportSink ExportSink
+
+	// NodeLiveness, if set, tracks the liveness of the cluster's nodes.
+	// Replicas aren't allocated on dead nodes, and the replicate queue
+	// replaces replicas on dead nodes.
+	NodeLiveness *NodeLiveness
+
+	// TimeUntilNodeDead is the duration for which a node's liveness
+	// record must have been expired before the replicate queue replaces
+	// its replicas.
+	TimeUntilNodeDead time.Duration
+
+	// SlowCmdThreshold is the execution time beyond which a command is
+	// logged, whether or not its request belongs to a trace. A negative
+	// value logs only traced requests.
+	SlowCmdThreshold time.Duration
 }
 
 // setDefaults initializes unset fields in StoreConfig to values
@@ -281,6 +371,27 @@ func (c *StoreConfig) setDefaults() {
 	if c.RaftElectionTimeoutTicks == 0 {
 		c.RaftElectionTimeoutTicks = 15
 	}
+	if c.RaftQuiesceTicks == 0 {
+		c.RaftQuiesceTicks = 1000
+	}
+	if c.RaftReproposalTicks == 0 {
+		c.RaftReproposalTicks = 2 * c.RaftElectionTimeoutTicks
+	}
+	if c.ClockJumpThreshold == 0 {
+		c.ClockJumpThreshold = defaultClockJumpThreshold
+	}
+	if c.MaxPendingProposals == 0 {
+		c.MaxPendingProposals = defaultMaxPendingProposals
+	}
+	if c.MaxConcurrentSnapshots == 0 {
+		c.MaxConcurrentSnapshots = defaultMaxConcurrentSnapshots
+	}
+	if c.SlowCmdThreshold == 0 {
+		c.SlowCmdThreshold = defaultSlowCmdThreshold
+	}
+	if c.TimeUntilNodeDead == 0 {
+		c.TimeUntilNodeDead = DefaultTimeUntilNodeDead
+	}
 }
 
 // TestStoreConfig is a StoreConfig for use in tests which uses very short timeouts.
@@ -288,6 +399,9 @@ var TestStoreConfig = StoreConfig{
 	RaftTickInterval:           time.Millisecond,
 	RaftHeartbeatIntervalTicks: 1,
 	RaftElectionTimeoutTicks:   5,
+	RaftQuiesceTicks:           -1,
+	// Tests move manual clocks arbitrarily.
+	ClockJumpThreshold: -1,
 }
 
 // A Store maintains a map of ranges by start key. A Store corresponds
@@ -309,15 +423,31 @@ type Store struct {
 	splitQueue     *splitQueue         // Range splitting queue
 	verifyQueue    *verifyQueue        // Checksum verification queue
 	replicateQueue *replicateQueue     // Replication queue
+	resolveQueue   *resolveQueue       // Background intent resolution queue
+	raftLogQueue   *raftLogQueue       // Raft log truncation queue
+	replicaGCQueue *replicaGCQueue     // Removed replica GC queue
 	scanner        *rangeScanner       // Range scanner
+	clockMonitor   *clockMonitor       // Detects wall clock jumps
+	compactor      *compactor          // Compacts vacated key spans
+	leaseRenewer   *leaseRenewer       // Schedules leader lease extensions
+	healthMonitor  *healthMonitor      // Measures disk and write health
+	rangeAdmission *rangeAdmission     // Limits the rate of range creation
+	snapshots      *snapshotQueue      // Admits outgoing snapshots
+	cmdAdmission   *cmdAdmission       // Sheds commands while overloaded
 	multiraft      *multiraft.MultiRaft
 	started        int32
+	draining       int32 // Non-zero once the store is draining; updated atomically
 	stopper        *util.Stopper
 	status         *proto.StoreStatus
+	writes         *writeRate // Rate of write commands
 
 	mu          sync.RWMutex     // Protects variables below...
 	ranges      map[int64]*Range // Map of ranges by Raft ID
 	rangesByKey RangeSlice       // Sorted slice of ranges by StartKey
+	preemptive  map[int64]int64  // Raft IDs of preemptive ranges to wall time initialized
+
+	pauseMu      sync.Mutex   // Protects pauseWindows
+	pauseWindows []TimeWindow // Daily windows pausing data movement
 }
 
 var _ multiraft.Storage = &Store{}
@@ -338,16 +468,40 @@ func NewStore(clock *hlc.Clock, eng engine.Engine, db *client.KV, gossip *gossip
 		gossip:      gossip,
 		transport:   transport,
 		ranges:      map[int64]*Range{},
+		preemptive:  map[int64]int64{},
 		status:      &proto.StoreStatus{},
+		writes:      newWriteRate(clock.PhysicalNow()),
+	}
+	if s.Authorizer == nil {
+		s.Authorizer = NewPermConfigAuthorizer(gossip)
 	}
+	s.allocator.liveness = config.NodeLiveness
 
 	// Add range scanner and configure with queues.
 	s.scanner = newRangeScanner(defaultScanInterval, newStoreRangeIterator(s))
 	s.gcQueue = newGCQueue()
 	s.splitQueue = newSplitQueue(db, gossip)
+	s.splitQueue.qpsThreshold = config.SplitQPS
+	s.splitQueue.staticKeys = config.StaticSplitKeys
+	s.splitQueue.paused = s.dataMovementPaused
 	s.verifyQueue = newVerifyQueue(s.scanner.Stats)
 	s.replicateQueue = newReplicateQueue(gossip, s.allocator, clock)
-	s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.verifyQueue, s.replicateQueue)
+	s.replicateQueue.liveness = config.NodeLiveness
+	s.replicateQueue.deadAfter = config.TimeUntilNodeDead
+	s.replicateQueue.paused = s.dataMovementPaused
+	s.raftLogQueue = newRaftLogQueue(s.followerMatchIndexes)
+	s.replicaGCQueue = newReplicaGCQueue(db, s.isPreemptive, s.destroyReplica)
+	s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.verifyQueue, s.replicateQueue, s.raftLogQueue,
+		s.replicaGCQueue)
+	s.resolveQueue = newResolveQueue()
+	s.clockMonitor = newClockMonitor(clock, config.ClockJumpThreshold, s.invalidateLeaderLeases)
+	s.compactor = newCompactor(eng, defaultCompactionInterval, defaultCompactionThreshold)
+	s.leaseRenewer = newLeaseRenewer(defaultLeaseRenewalInterval, clock.PhysicalNow)
+	s.healthMonitor = newHealthMonitor(eng, defaultHealthCheckInterval)
+	s.healthMonitor.onUnhealthy = s.shedLeases
+	s.rangeAdmission = newRangeAdmission(config.RangeCreationInterval)
+	s.snapshots = newSnapshotQueue(config.MaxConcurrentSnapshots)
+	s.cmdAdmission = newCmdAdmission(s.StoreID, config.MaxPendingProposals, s.healthMonitor.getSeverity)
 
 	return s
 }
@@ -391,25 +545,32 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	}
 	s.raftIDAlloc = idAlloc
 
-	// GCTimeouts method is called each time an engine compaction is
-	// underway. It sets minimum timeouts for transaction records and
-	// response cache entries.
+	// Set the GC timeouts consulted by engine compactions; they're
+	// advanced periodically once the store is started.
 	now := s.clock.Now()
-	minTxnTS := int64(0) // disable GC of transactions until we know minimum write intent age
-	minRCacheTS := now.WallTime - GCResponseCacheExpiration.Nanoseconds()
-	s.engine.SetGCTimeouts(minTxnTS, minRCacheTS)
+	s.setGCTimeouts(now)
 
 	// Iterator over all range-local key-based data.
 	start := engine.RangeDescriptorKey(engine.KeyMin)
 	end := engine.RangeDescriptorKey(engine.KeyMax)
 
+	quiesceTicks := s.RaftQuiesceTicks
+	if quiesceTicks < 0 {
+		quiesceTicks = 0
+	}
+	reproposalTicks := s.RaftReproposalTicks
+	if reproposalTicks < 0 {
+		reproposalTicks = 0
+	}
 	if s.multiraft, err = multiraft.NewMultiRaft(s.RaftNodeID(), &multiraft.Config{
-		Transport:              s.transport,
+		Transport:              &admittingTransport{Transport: newThrottledTransport(s.transport, s.snapshots, s.stopper), store: s},
 		Storage:                s,
 		StateMachine:           s,
 		TickInterval:           s.RaftTickInterval,
 		ElectionTimeoutTicks:   s.RaftElectionTimeoutTicks,
 		HeartbeatIntervalTicks: s.RaftHeartbeatIntervalTicks,
+		QuiesceTicks:           quiesceTicks,
+		ReproposalTicks:        reproposalTicks,
 		EntryFormatter:         raftEntryFormatter,
 	}); err != nil {
 		return err
@@ -419,7 +580,7 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	// (consistent=false). Uncommitted intents which have been abandoned
 	// due to a split crashing halfway will simply be resolved on the
 	// next split attempt. They can otherwise be ignored.
-	if err := engine.MVCCIterate(s.engine, start, end, now, false, nil, func(kv proto.KeyValue) (bool, error) {
+	if err := engine.MVCCIterate(s.engine, start, end, 0, now, false, nil, func(kv proto.KeyValue) (bool, error) {
 		// Only consider range metadata entries; ignore others.
 		_, suffix, _ := engine.DecodeRangeKey(kv.Key)
 		if !suffix.Equal(engine.KeyLocalRangeDescriptorSuffix) {
@@ -461,6 +622,33 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	// Start the scanner.
 	s.scanner.Start(s.clock, s.stopper)
 
+	// Start the resolve queue, which is fed directly by the read path
+	// rather than by the scanner.
+	s.resolveQueue.Start(s.clock, s.stopper)
+
+	// Start compacting spans vacated by merges and log truncation.
+	s.compactor.start(s.stopper)
+
+	// Start monitoring the wall clock for jumps.
+	s.clockMonitor.start(s.stopper)
+
+	// Start proposing batched leader lease extensions.
+	s.leaseRenewer.start(s.stopper)
+
+	// Start probing the engine for disk saturation and write stalls.
+	s.healthMonitor.start(s.stopper)
+
+	// Start rolling up usage by accounting config prefix.
+	s.startAcctRollups()
+
+	// Advance the GC timeouts so that response cache entries written
+	// after startup also expire during compactions.
+	s.startGCTimeouts()
+
+	// Destroy ranges initialized by preemptive snapshots whose replica
+	// changes were aborted.
+	s.startPreemptiveSnapshotGC()
+
 	// Register callbacks for any changes to accounting and zone
 	// configurations; we split ranges along prefix boundaries.
 	// Gossip is only ever nil for unittests.
@@ -504,9 +692,11 @@ func (s *Store) configGossipUpdate(key string, contentsChanged bool) {
 	}
 	s.maybeSplitRangesByConfigs(configMap)
 
-	// If the zone configs changed, run through ranges and set max bytes.
+	// If the zone configs changed, run through ranges and set max bytes,
+	// and apply the cluster settings of the default zone config.
 	if key == gossip.KeyConfigZone {
 		s.setRangesMaxBytes(configMap)
+		s.applyClusterSettings(configMap[0].Config.(*proto.ZoneConfig))
 	}
 }
 
@@ -575,6 +765,25 @@ func (s *Store) setRangesMaxBytes(zoneMap PrefixConfigMap) {
 	}
 }
 
+// applyClusterSettings applies the cluster settings held by the
+// default zone config to the store.
+func (s *Store) applyClusterSettings(zone *proto.ZoneConfig) {
+	s.snapshots.setRates(zone.RecoverySnapshotRate, zone.RebalanceSnapshotRate)
+	s.splitQueue.setInterval(time.Duration(zone.SplitIntervalNanos))
+	s.replicateQueue.setInterval(time.Duration(zone.RebalanceIntervalNanos))
+	if s.IntentLimiter != nil {
+		s.IntentLimiter.SetIntentLimits(zone.MaxTxnIntents, zone.MaxTxnIntentBytes)
+	}
+	windows, err := ParseTimeWindows(zone.PauseWindows)
+	if err != nil {
+		log.Warningf("%s: ignoring pause windows of default zone config: %s", s, err)
+		return
+	}
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	s.pauseWindows = windows
+}
+
 // Bootstrap writes a new store ident to the underlying engine. To
 // ensure that no crufty data already exists in the engine, it scans
 // the engine contents before writing the new store ident. The engine
@@ -618,6 +827,15 @@ func (s *Store) GetRange(raftID int64) (*Range, error) {
 	return nil, proto.NewRangeNotFoundError(raftID)
 }
 
+// hasInitializedRange returns whether the store holds an initialized
+// replica of the range.
+func (s *Store) hasInitializedRange(raftID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rng, ok := s.ranges[raftID]
+	return ok && rng.isInitialized()
+}
+
 // LookupRange looks up a range via binary search over the sorted
 // "rangesByKey" RangeSlice. Returns nil if no range is found for
 // specified key range. Note that the specified keys are transformed
@@ -652,10 +870,12 @@ func (s *Store) BootstrapRange() error {
 		EndKey:   engine.KeyMax,
 		Replicas: []proto.Replica{
 			{
-				NodeID:  1,
-				StoreID: 1,
+				NodeID:    1,
+				StoreID:   1,
+				ReplicaID: 1,
 			},
 		},
+		NextReplicaID: 2,
 	}
 	batch := s.engine.NewBatch()
 	ms := &engine.MVCCStats{}
@@ -756,9 +976,72 @@ func (s *Store) Allocator() *allocator { return s.allocator }
 // Gossip accessor.
 func (s *Store) Gossip() *gossip.Gossip { return s.gossip }
 
+// dataMovementPaused returns whether background data movement is
+// paused at time t by one of the pause windows of the default zone
+// config.
+func (s *Store) dataMovementPaused(t time.Time) bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return inTimeWindows(s.pauseWindows, t)
+}
+
 // SplitQueue accessor.
 func (s *Store) SplitQueue() *splitQueue { return s.splitQueue }
 
+// ClockMonitor accessor.
+func (s *Store) ClockMonitor() *clockMonitor { return s.clockMonitor }
+
+// followerMatchIndexes returns the highest log index acknowledged by
+// each follower of the range with the given Raft ID.
+func (s *Store) followerMatchIndexes(raftID int64) map[multiraft.NodeID]uint64 {
+	return s.multiraft.FollowerMatchIndexes(uint64(raftID))
+}
+
+// Compactor accessor.
+func (s *Store) Compactor() *compactor { return s.compactor }
+
+// LeaseRenewer accessor.
+func (s *Store) LeaseRenewer() *leaseRenewer { return s.leaseRenewer }
+
+// Liveness returns the store's node liveness, or nil if none is
+// configured.
+func (s *Store) Liveness() *NodeLiveness { return s.NodeLiveness }
+
+// RangeAdmission accessor.
+func (s *Store) RangeAdmission() *rangeAdmission { return s.rangeAdmission }
+
+// Exports returns the store's export sink, or nil if none is
+// configured.
+func (s *Store) Exports() ExportSink { return s.ExportSink }
+
+// ThrottleSeverity returns the severity in [0, 1] with which the
+// store is throttled due to a full disk or stalled writes; zero
+// indicates a healthy store.
+func (s *Store) ThrottleSeverity() float64 { return s.healthMonitor.getSeverity() }
+
+// Unhealthy returns the reason the store's engine is unhealthy, or
+// the empty string if it's healthy.
+func (s *Store) Unhealthy() string { return s.healthMonitor.getUnhealthy() }
+
+// ReclaimableBytes returns the estimated number of bytes held by key
+// spans which have been vacated but not yet compacted.
+func (s *Store) ReclaimableBytes() int64 { return s.compactor.ReclaimableBytes() }
+
+// ClockJumpEvents returns a channel on which the store reports wall
+// clock jumps. Events are dropped if the channel is not drained.
+func (s *Store) ClockJumpEvents() <-chan *ClockJumpEvent { return s.clockMonitor.events }
+
+// invalidateLeaderLeases stops this store's ranges from serving
+// commands under the leader leases they hold so that the leases are
+// reacquired under new clock readings.
+func (s *Store) invalidateLeaderLeases() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rng := range s.ranges {
+		rng.invalidateLeaderLease()
+	}
+}
+
 // NewRangeDescriptor creates a new descriptor based on start and end
 // keys and the supplied proto.Replicas slice. It allocates new Raft
 // and range IDs to fill out the supplied replicas.
@@ -769,6 +1052,7 @@ func (s *Store) NewRangeDescriptor(start, end proto.Key, replicas []proto.Replic
 		EndKey:   end,
 		Replicas: append([]proto.Replica(nil), replicas...),
 	}
+	desc.NextReplicaID = nextReplicaID(desc)
 	return desc, nil
 }
 
@@ -821,8 +1105,6 @@ func (s *Store) MergeRange(subsumingRng *Range, updatedEndKey proto.Key, subsume
 		return nil, util.Errorf("cannot remove range %s", err)
 	}
 
-	// TODO(bram): The removed range needs to have all of its metadata removed.
-
 	// Update the end key of the subsuming range.
 	copy := *subsumingRng.Desc()
 	copy.EndKey = updatedEndKey
@@ -867,6 +1149,184 @@ func (s *Store) addRangeInternal(rng *Range, resort bool) error {
 	return nil
 }
 
+// ProcessRangeDescriptorUpdate is called when the key span of a range
+// becomes known other than through a split or merge, as happens when
+// a replica which is new to the range is initialized from a snapshot.
+// The rangesByKey slice is re-sorted to reflect the range's position.
+// If the descriptor doesn't yet include this store, the snapshot was
+// sent preemptively ahead of a replica change and the range is
+// tracked until the change commits or expires.
+func (s *Store) ProcessRangeDescriptorUpdate(rng *Range) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ranges[rng.Desc().RaftID]; !ok {
+		return util.Errorf("range %d not found in store %d", rng.Desc().RaftID, s.StoreID())
+	}
+	sort.Sort(s.rangesByKey)
+	if _, rep := rng.Desc().FindReplica(s.StoreID()); rep == nil {
+		s.preemptive[rng.Desc().RaftID] = s.clock.PhysicalNow()
+	}
+	return nil
+}
+
+// SendPreemptiveSnapshot sends the snapshot returned by generate
+// directly to the specified node, bypassing the local Raft group. The
+// snapshot is generated and sent once admitted by the store's snapshot
+// queue at the supplied priority, and is subject to that priority's
+// rate limit.
+func (s *Store) SendPreemptiveSnapshot(pri snapshotPriority, to multiraft.NodeID,
+	generate func() (*multiraft.RaftMessageRequest, error)) error {
+	return s.snapshots.send(pri, to, s.transport, generate, s.stopper.ShouldStop())
+}
+
+// startPreemptiveSnapshotGC starts a worker which periodically
+// destroys expired preemptive ranges.
+func (s *Store) startPreemptiveSnapshotGC() {
+	s.stopper.RunWorker(func() {
+		ticker := util.NewTicker(preemptiveSnapshotGCInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.gcPreemptiveSnapshots()
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// gcPreemptiveSnapshots visits ranges initialized by preemptive
+// snapshots. Ranges whose descriptor has since come to include this
+// store are members of their Raft group and are no longer tracked.
+// Ranges which have waited longer than preemptiveSnapshotExpiration
+// are checked against the committed range descriptor; if it doesn't
+// include this store either, the replica change was aborted and the
+// range is removed and its data destroyed.
+func (s *Store) gcPreemptiveSnapshots() {
+	now := s.clock.PhysicalNow()
+	var expired []*Range
+	s.mu.Lock()
+	for raftID, initialized := range s.preemptive {
+		rng, ok := s.ranges[raftID]
+		if !ok {
+			delete(s.preemptive, raftID)
+			continue
+		}
+		if _, rep := rng.Desc().FindReplica(s.StoreID()); rep != nil {
+			delete(s.preemptive, raftID)
+			continue
+		}
+		if now-initialized > preemptiveSnapshotExpiration.Nanoseconds() {
+			expired = append(expired, rng)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, rng := range expired {
+		raftID := rng.Desc().RaftID
+		// Consult the committed range descriptor. This read pushes any
+		// replica change transaction still holding an intent on it.
+		var desc proto.RangeDescriptor
+		if s.db != nil {
+			ok, _, err := s.db.GetProto(engine.RangeDescriptorKey(rng.Desc().StartKey), &desc)
+			if err != nil {
+				log.Warningf("unable to look up descriptor of preemptive range %d: %s", raftID, err)
+				continue
+			}
+			if ok && desc.RaftID == raftID {
+				if _, rep := desc.FindReplica(s.StoreID()); rep != nil {
+					// The change committed; the range awaits the trigger.
+					continue
+				}
+			}
+		}
+		log.Infof("destroying range %d initialized by an aborted preemptive snapshot", raftID)
+		if err := s.RemoveRange(rng); err != nil {
+			log.Errorf("unable to remove preemptive range %d: %s", raftID, err)
+			continue
+		}
+		s.scanner.RemoveRange(rng)
+		if err := rng.Destroy(); err != nil {
+			log.Errorf("unable to destroy data of preemptive range %d: %s", raftID, err)
+		}
+		s.mu.Lock()
+		delete(s.preemptive, raftID)
+		s.mu.Unlock()
+	}
+}
+
+// isPreemptive returns true if the range with the given Raft ID was
+// initialized by a preemptive snapshot and is awaiting the commit of
+// its replica change.
+func (s *Store) isPreemptive(raftID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.preemptive[raftID]
+	return ok
+}
+
+// destroyReplica removes a replica which is no longer a member of its
+// range from the store and destroys its data, leaving a tombstone.
+func (s *Store) destroyReplica(rng *Range) error {
+	if err := s.RemoveRange(rng); err != nil {
+		return err
+	}
+	s.scanner.RemoveRange(rng)
+	return rng.destroyWithTombstone()
+}
+
+// replicaTombstone returns the lowest replica ID of the range with
+// the given Raft ID which may be created on the store, or zero if no
+// replica of the range was removed from the store. Replicas with lower
+// IDs were removed; stale Raft messages addressed to them are dropped.
+func (s *Store) replicaTombstone(raftID int64) int32 {
+	val, err := engine.MVCCGet(s.engine, engine.RangeTombstoneKey(raftID), proto.ZeroTimestamp, true, nil)
+	if err != nil {
+		log.Warningf("unable to read tombstone of range %d: %s", raftID, err)
+		return 0
+	}
+	if val == nil {
+		return 0
+	}
+	return int32(val.GetInteger())
+}
+
+// AdmitGroup implements the multiraft.GroupAdmitter interface. A
+// message for a range whose replica was removed from the store is
+// admitted only if it's a snapshot initializing a newer replica, in
+// which case the tombstone is cleared. Newer replicas are always
+// initialized by a snapshot, preemptive or otherwise, before they
+// receive any other message.
+func (s *Store) AdmitGroup(groupID uint64, msg raftpb.Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	minID := s.replicaTombstone(int64(groupID))
+	if minID == 0 {
+		return true
+	}
+	if msg.Type != raftpb.MsgSnap {
+		return false
+	}
+	var snapData proto.RaftSnapshotData
+	if err := gogoproto.Unmarshal(msg.Snapshot.Data, &snapData); err != nil {
+		log.Warningf("%s: unable to decode snapshot of range %d: %s", s, groupID, err)
+		return false
+	}
+	replicaID := snapData.ReplicaID
+	if _, rep := snapData.RangeDescriptor.FindReplica(s.StoreID()); rep != nil {
+		replicaID = rep.ReplicaID
+	}
+	if replicaID < minID {
+		return false
+	}
+	if err := engine.MVCCDelete(s.engine, nil, engine.RangeTombstoneKey(int64(groupID)), proto.ZeroTimestamp, nil); err != nil {
+		log.Warningf("%s: unable to clear tombstone of range %d: %s", s, groupID, err)
+		return false
+	}
+	return true
+}
+
 // RemoveRange removes the range from the store's range map and from
 // the sorted rangesByKey slice.
 func (s *Store) RemoveRange(rng *Range) error {
@@ -907,7 +1367,7 @@ func (s *Store) Capacity() (engine.StoreCapacity, error) {
 }
 
 // Descriptor returns a StoreDescriptor including current store
-// capacity information.
+// capacity information and usage statistics.
 func (s *Store) Descriptor(nodeDesc *NodeDescriptor) (*StoreDescriptor, error) {
 	capacity, err := s.Capacity()
 	if err != nil {
@@ -919,19 +1379,87 @@ func (s *Store) Descriptor(nodeDesc *NodeDescriptor) (*StoreDescriptor, error) {
 		Attrs:    s.Attrs(),
 		Node:     *nodeDesc,
 		Capacity: capacity,
+		Stats:    s.Stats(),
 	}, nil
 }
 
+// Stats returns usage statistics aggregated over the store's ranges
+// and the rate of write commands executed by the store.
+func (s *Store) Stats() StoreStats {
+	s.mu.RLock()
+	stats := StoreStats{RangeCount: len(s.ranges)}
+	for _, rng := range s.ranges {
+		ms := rng.stats.GetMVCC()
+		stats.LiveBytes += ms.LiveBytes
+		stats.KeyBytes += ms.KeyBytes
+		stats.ValBytes += ms.ValBytes
+		if rng.HasLeaderLease() {
+			stats.LeaseCount++
+		}
+		if rng.IsLeader() && s.isUnderReplicated(rng) {
+			stats.UnderReplicatedRanges++
+		}
+	}
+	s.mu.RUnlock()
+	stats.WritesPerSecond = s.writes.perSecond(s.clock.PhysicalNow())
+	stats.LeaseRenewals = s.leaseRenewer.stats()
+	stats.ThrottleSeverity = s.healthMonitor.getSeverity()
+	stats.Unhealthy = s.healthMonitor.getUnhealthy()
+	stats.PendingRangeCreations = s.rangeAdmission.pending()
+	stats.ShedCommands = s.cmdAdmission.shedCount()
+	stats.BlockCacheHitRate = s.engine.CacheStats().HitRate()
+	return stats
+}
+
+// isUnderReplicated returns whether the range has fewer replicas than
+// required by its zone config.
+func (s *Store) isUnderReplicated(rng *Range) bool {
+	zone, err := lookupZoneConfig(s.gossip, rng)
+	if err != nil {
+		return false
+	}
+	return len(rng.Desc().Replicas) < len(zone.ReplicaAttrs)
+}
+
 // ExecuteCmd fetches a range based on the header's replica, assembles
 // method, args & reply into a Raft Cmd struct and executes the
 // command using the fetched range.
 func (s *Store) ExecuteCmd(method string, args proto.Request, reply proto.Response) error {
 	// If the request has a zero timestamp, initialize to this node's clock.
 	header := args.Header()
+	start := util.Now()
+	trace := newCmdTrace(method, header)
+	defer s.maybeLogCmd(method, start, trace, reply)
 	if err := verifyKeys(header.Key, header.EndKey); err != nil {
 		reply.Header().SetGoError(err)
 		return err
 	}
+	// Verify permissions here as well as at the gateway; the store
+	// must not rely on its callers for access control.
+	if err := s.Authorizer.Authorize(method, header); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
+	// Skip commands whose client no longer awaits the response.
+	if err := checkDeadline(method, header); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
+	// Redirect client writes while the engine is unhealthy, rather than
+	// letting them fail as they're applied.
+	if err := s.checkHealth(method, header); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
+	// Shed client commands while the store is overloaded.
+	if err := s.cmdAdmission.admit(method, header, s.stopper.ShouldStop()); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
+	if !proto.IsReadOnly(method) {
+		s.cmdAdmission.begin()
+		defer s.cmdAdmission.finish()
+	}
 	if header.Timestamp.Equal(proto.ZeroTimestamp) {
 		// Update the incoming timestamp if unset.
 		header.Timestamp = s.clock.Now()
@@ -950,10 +1478,17 @@ func (s *Store) ExecuteCmd(method string, args proto.Request, reply proto.Respon
 	// Backoff and retry loop for handling errors.
 	retryOpts := s.RetryOpts
 	retryOpts.Tag = fmt.Sprintf("store: %s", method)
+	var backpressureStart time.Time
 	err := util.RetryWithBackoff(retryOpts, func() (util.RetryStatus, error) {
 		// Add the command to the range for execution; exit retry loop on success.
 		reply.Reset()
 
+		// Stop retrying once the deadline has passed.
+		if err := checkDeadline(method, header); err != nil {
+			reply.Header().SetGoError(err)
+			return util.RetryBreak, err
+		}
+
 		// Get range and add command to the range for execution.
 		rng, err := s.GetRange(header.RaftID)
 		if err != nil {
@@ -961,7 +1496,10 @@ func (s *Store) ExecuteCmd(method string, args proto.Request, reply proto.Respon
 			return util.RetryBreak, err
 		}
 
-		if err = rng.AddCmd(method, args, reply, true); err == nil {
+		if err = rng.addCmd(method, args, reply, true, trace); err == nil {
+			if !proto.IsReadOnly(method) {
+				s.writes.record()
+			}
 			return util.RetryBreak, nil
 		}
 
@@ -972,6 +1510,18 @@ func (s *Store) ExecuteCmd(method string, args proto.Request, reply proto.Respon
 		err = s.maybeResolveWriteIntentError(rng, method, args, reply)
 
 		switch t := err.(type) {
+		case *ClockJumpError:
+			// Back off until leader leases are reinstated.
+			return util.RetryContinue, nil
+		case *proto.RangeTooLargeError:
+			// Delay the write while the range splits, failing it if the
+			// range remains oversized for too long.
+			if backpressureStart.IsZero() {
+				backpressureStart = util.Now()
+			} else if util.Now().Sub(backpressureStart) > backpressureMaxWait {
+				return util.RetryBreak, nil
+			}
+			return util.RetryContinue, nil
 		case *proto.WriteTooOldError:
 			// Update request timestamp and retry immediately.
 			header.Timestamp = t.ExistingTimestamp
@@ -1007,7 +1557,8 @@ func (s *Store) ExecuteCmd(method string, args proto.Request, reply proto.Respon
 // is a writeIntentError, it tries to push the conflicting
 // transaction: either move its timestamp forward on a read/write
 // conflict, or abort it on a write/write conflict. If the push
-// succeeds, we immediately issue a resolve intent command and set the
+// succeeds, we immediately issue a resolve intent command (or, for
+// reads, enqueue the intent with the resolve queue) and set the
 // error's Resolved flag to true so the client retries the command
 // immediately. If the push fails, we set the error's Resolved flag to
 // false so that the client backs off before reissuing the command.
@@ -1052,6 +1603,21 @@ func (s *Store) maybeResolveWriteIntentError(rng *Range, method string, args pro
 	}
 	wiErr.Resolved = true // success!
 
+	// For read-only commands, hand the intent off to the resolve queue
+	// to be resolved in the background. This keeps the read path out
+	// of the command queue and Raft, and lets concurrent readers of the
+	// same intent share a single resolution. The read doesn't wait for
+	// it: unless the intent has already been resolved, it backs off
+	// and retries.
+	if !proto.IsReadWrite(method) {
+		select {
+		case <-s.resolveQueue.addIntent(rng, wiErr.Key, pushReply.PusheeTxn, s.clock.Now()):
+		default:
+			wiErr.Resolved = false
+		}
+		return wiErr
+	}
+
 	// We pushed the transaction successfully, so resolve the intent.
 	resolveArgs := &proto.InternalResolveIntentRequest{
 		RequestHeader: proto.RequestHeader{
@@ -1108,6 +1674,32 @@ func (s *Store) ProposeRaftCommand(idKey cmdIDKey, cmd proto.InternalRaftCommand
 	return s.multiraft.SubmitCommand(uint64(cmd.RaftID), string(idKey), data)
 }
 
+// setGCTimeouts sets minimum timeouts for transaction records and
+// response cache entries, which are consulted each time an engine
+// compaction is underway.
+func (s *Store) setGCTimeouts(now proto.Timestamp) {
+	minTxnTS := int64(0) // disable GC of transactions until we know minimum write intent age
+	minRCacheTS := now.WallTime - GCResponseCacheExpiration.Nanoseconds()
+	s.engine.SetGCTimeouts(minTxnTS, minRCacheTS)
+}
+
+// startGCTimeouts starts a worker which advances the GC timeouts
+// every gcTimeoutsInterval.
+func (s *Store) startGCTimeouts() {
+	s.stopper.RunWorker(func() {
+		ticker := util.NewTicker(gcTimeoutsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.setGCTimeouts(s.clock.Now())
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
 // processRaft processes read/write commands that have been committed
 // by the raft consensus algorithm, dispatching them to the
 // appropriate range. This method starts a goroutine to process Raft
@@ -1175,7 +1767,7 @@ func (s *Store) processRaft() {
 						// TODO(tschottdorf): Fatalf if we think we have a leader
 						// lease for that group, during which we're not supposed to
 						// get a message like that.
-						// if r.HaveLeaderLease() {
+						// if r.HasLeaderLease() {
 						//   log.Fatalf("have leader lease, but other node requests it")
 						// }
 						continue
@@ -1216,12 +1808,22 @@ func (s *Store) processRaft() {
 	})
 }
 
-// GroupStorage implements the multiraft.Storage interface.
+// GroupStorage implements the multiraft.Storage interface. Returns
+// nil if the store's replica of the range was recently removed, or if
+// the range is new to the store and the store is unhealthy.
 func (s *Store) GroupStorage(groupID uint64) multiraft.WriteableGroupStorage {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	r, ok := s.ranges[int64(groupID)]
 	if !ok {
+		if s.replicaTombstone(int64(groupID)) != 0 {
+			return nil
+		}
+		// An unhealthy store doesn't accept new replicas.
+		if reason := s.healthMonitor.getUnhealthy(); reason != "" {
+			log.Warningf("%s: refusing replica of range %d: store is unhealthy: %s", s, groupID, reason)
+			return nil
+		}
 		var err error
 		r, err = NewRange(&proto.RangeDescriptor{
 			RaftID: int64(groupID),
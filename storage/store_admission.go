@@ -0,0 +1,139 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+const (
+	// defaultMaxPendingProposals is the default number of write
+	// commands awaiting Raft commit and application at which a store
+	// is overloaded.
+	defaultMaxPendingProposals = 1000
+	// overloadSeverity is the throttle severity of the store's engine
+	// at or above which the store is overloaded, as when the LSM
+	// stalls writes pending compactions.
+	overloadSeverity = 0.5
+	// admissionMaxWait is the maximum time for which a command of
+	// raised priority waits for an overloaded store to recover before
+	// it is shed.
+	admissionMaxWait = 1 * time.Second
+	// admissionPollInterval is the interval at which waiting commands
+	// check whether the store has recovered.
+	admissionPollInterval = 10 * time.Millisecond
+)
+
+// A cmdAdmission decides whether a store accepts commands, so that an
+// overloaded store sheds load before its latency collapses rather than
+// queueing work without bound. The store is overloaded while the
+// number of write commands in flight reaches its limit or while its
+// engine is throttled severely. While overloaded, the store:
+//
+//   - admits internal and admin commands, which are needed to resolve
+//     intents, renew leases and move load off the store;
+//   - sheds commands of default or lower user priority immediately;
+//   - queues commands of raised user priority for up to
+//     admissionMaxWait, shedding them if the overload persists.
+type cmdAdmission struct {
+	storeID      func() proto.StoreID
+	maxProposals int64          // Write commands in flight at which the store is overloaded; <= 0 for no limit
+	severity     func() float64 // Engine throttle severity
+
+	proposals int64 // Write commands in flight; updated atomically
+	shed      int64 // Commands shed since the store started; updated atomically
+}
+
+// newCmdAdmission returns a cmdAdmission for the store with the
+// supplied ID, which may not be known until the store starts.
+func newCmdAdmission(storeID func() proto.StoreID, maxProposals int64, severity func() float64) *cmdAdmission {
+	return &cmdAdmission{storeID: storeID, maxProposals: maxProposals, severity: severity}
+}
+
+// overload returns the reason the store is overloaded, or the empty
+// string if it isn't.
+func (ca *cmdAdmission) overload() string {
+	if n := atomic.LoadInt64(&ca.proposals); ca.maxProposals > 0 && n >= ca.maxProposals {
+		return fmt.Sprintf("%d write commands in flight", n)
+	}
+	if s := ca.severity(); s >= overloadSeverity {
+		return fmt.Sprintf("engine throttled with severity %.2f", s)
+	}
+	return ""
+}
+
+// admit returns nil if the command may be executed, waiting while the
+// store is overloaded if the command's priority allows it. Otherwise,
+// returns a StoreOverloadedError.
+func (ca *cmdAdmission) admit(method string, header *proto.RequestHeader, stop <-chan struct{}) error {
+	if proto.IsInternal(method) || proto.IsAdmin(method) {
+		return nil
+	}
+	reason := ca.overload()
+	if reason == "" {
+		return nil
+	}
+	if header.GetUserPriority() > 1 {
+		deadline := util.Now().Add(admissionMaxWait)
+		for reason != "" && util.Now().Before(deadline) {
+			timer := util.NewTimer(admissionPollInterval)
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return ca.shedErr(reason)
+			}
+			reason = ca.overload()
+		}
+		if reason == "" {
+			return nil
+		}
+	}
+	return ca.shedErr(reason)
+}
+
+// shedErr counts a shed command and returns the error with which it
+// is shed.
+func (ca *cmdAdmission) shedErr(reason string) error {
+	atomic.AddInt64(&ca.shed, 1)
+	return &proto.StoreOverloadedError{StoreID: ca.storeID(), Reason: reason}
+}
+
+// begin records the start of a write command; the caller must invoke
+// finish once the command has completed.
+func (ca *cmdAdmission) begin() {
+	atomic.AddInt64(&ca.proposals, 1)
+}
+
+// finish records the completion of a write command.
+func (ca *cmdAdmission) finish() {
+	atomic.AddInt64(&ca.proposals, -1)
+}
+
+// inFlight returns the number of write commands in flight.
+func (ca *cmdAdmission) inFlight() int64 {
+	return atomic.LoadInt64(&ca.proposals)
+}
+
+// shedCount returns the number of commands shed.
+func (ca *cmdAdmission) shedCount() int64 {
+	return atomic.LoadInt64(&ca.shed)
+}
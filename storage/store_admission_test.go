@@ -0,0 +1,90 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+func newTestCmdAdmission(maxProposals int64, severity float64) *cmdAdmission {
+	return newCmdAdmission(func() proto.StoreID { return 1 }, maxProposals,
+		func() float64 { return severity })
+}
+
+// TestCmdAdmissionProposals verifies that client commands are shed
+// while the number of write commands in flight is at the limit, that
+// internal commands are admitted regardless and that commands of
+// raised priority wait for the overload to clear.
+func TestCmdAdmissionProposals(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ca := newTestCmdAdmission(2, 0)
+	header := &proto.RequestHeader{}
+	for i := 0; i < 2; i++ {
+		if err := ca.admit(proto.Put, header, nil); err != nil {
+			t.Fatalf("%d: expected admission; got %s", i, err)
+		}
+		ca.begin()
+	}
+
+	err := ca.admit(proto.Put, header, nil)
+	if _, ok := err.(*proto.StoreOverloadedError); !ok {
+		t.Fatalf("expected StoreOverloadedError; got %v", err)
+	}
+	if !err.(*proto.StoreOverloadedError).CanRetry() {
+		t.Error("expected StoreOverloadedError to be retryable")
+	}
+	if err := ca.admit(proto.InternalResolveIntent, header, nil); err != nil {
+		t.Errorf("expected internal command to be admitted; got %s", err)
+	}
+
+	header.UserPriority = gogoproto.Int32(10)
+	go func() {
+		time.Sleep(admissionPollInterval)
+		ca.finish()
+	}()
+	if err := ca.admit(proto.Put, header, nil); err != nil {
+		t.Errorf("expected raised priority command to wait for admission; got %s", err)
+	}
+	if n := ca.shedCount(); n != 1 {
+		t.Errorf("expected 1 shed command; got %d", n)
+	}
+}
+
+// TestCmdAdmissionSeverity verifies that commands are shed while the
+// engine is throttled severely, and that a waiting command is shed
+// when stopping.
+func TestCmdAdmissionSeverity(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	if err := newTestCmdAdmission(0, overloadSeverity/2).admit(proto.Put, &proto.RequestHeader{}, nil); err != nil {
+		t.Errorf("expected mildly throttled store to admit command; got %s", err)
+	}
+
+	ca := newTestCmdAdmission(0, overloadSeverity)
+	if _, ok := ca.admit(proto.Get, &proto.RequestHeader{}, nil).(*proto.StoreOverloadedError); !ok {
+		t.Error("expected severely throttled store to shed command")
+	}
+	stop := make(chan struct{})
+	close(stop)
+	header := &proto.RequestHeader{UserPriority: gogoproto.Int32(10)}
+	if _, ok := ca.admit(proto.Put, header, stop).(*proto.StoreOverloadedError); !ok {
+		t.Error("expected waiting command to be shed when stopping")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// drainMaxWait is the maximum time for which a draining store
+	// waits for its write commands in flight to be applied.
+	drainMaxWait = 5 * time.Second
+	// drainPollInterval is the interval at which a draining store
+	// checks for write commands in flight.
+	drainPollInterval = 10 * time.Millisecond
+)
+
+// Drain prepares the store to stop while its node is still running.
+// The store stops acquiring leader leases and transfers those it holds
+// to other replicas, so that its ranges remain available without
+// waiting for the leases to expire. Each transfer redirects new
+// commands to the target and waits for the commands admitted under the
+// lease before proposing the target's lease. Finally, the store waits
+// for its remaining write commands to be applied, so that the store
+// stops in a clean state. Drain implements util.Drainer.
+func (s *Store) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+	transferred := s.transferLeases("store draining")
+
+	deadline := util.Now().Add(drainMaxWait)
+	for s.cmdAdmission.inFlight() > 0 && util.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+	if n := s.cmdAdmission.inFlight(); n > 0 {
+		log.Warningf("%s: stopping with %d write commands in flight", s, n)
+	}
+	log.Infof("%s: drained; transferred %d leader leases", s, transferred)
+}
+
+// transferLeases transfers the leader leases held by the store's
+// replicas to replicas on other stores, returning the number of leases
+// transferred.
+func (s *Store) transferLeases(reason string) int {
+	s.mu.RLock()
+	ranges := make([]*Range, 0, len(s.ranges))
+	for _, rng := range s.ranges {
+		ranges = append(ranges, rng)
+	}
+	s.mu.RUnlock()
+
+	var transferred int
+	for _, rng := range ranges {
+		if !rng.HasLeaderLease() {
+			continue
+		}
+		target := s.drainTarget(rng)
+		if target == nil {
+			continue
+		}
+		if err := rng.TransferLeaderLease(*target, reason); err != nil {
+			log.Warningf("%s: unable to transfer leader lease of %s to %+v: %s", s, rng, *target, err)
+			continue
+		}
+		transferred++
+	}
+	return transferred
+}
+
+// Draining returns true if the store is draining, in which case its
+// replicas don't acquire leader leases.
+func (s *Store) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// drainTarget returns a replica of the range on another store to
+// which its leader lease may be transferred, or nil if there is none.
+// Replicas on nodes known to be dead are skipped.
+func (s *Store) drainTarget(rng *Range) *proto.Replica {
+	for _, replica := range rng.Desc().Replicas {
+		if replica.StoreID == s.StoreID() || s.NodeLiveness.IsDead(replica.NodeID) {
+			continue
+		}
+		target := replica
+		return &target
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// defaultHealthCheckInterval is the interval at which a store's
+	// health monitor probes the engine.
+	defaultHealthCheckInterval = 1 * time.Second
+	// lowDiskThreshold is the fraction of available disk capacity below
+	// which a store begins to throttle; throttling is at full severity
+	// once the disk is full.
+	lowDiskThreshold = 0.05
+	// slowWriteThreshold is the probe write latency above which a store
+	// begins to throttle, as when the LSM stalls writes pending
+	// compactions.
+	slowWriteThreshold = 100 * time.Millisecond
+	// stalledWriteThreshold is the probe write latency at which a store
+	// throttles at full severity and is unhealthy.
+	stalledWriteThreshold = 1 * time.Second
+	// fullDiskThreshold is the fraction of available disk capacity at
+	// or below which a store is unhealthy. Some capacity is held back
+	// so that the engine can still compact and the store's leases and
+	// replicas can be moved away.
+	fullDiskThreshold = 0.01
+	// unhealthyProbeCount is the number of consecutive failed probes
+	// after which a store is unhealthy, so that a single slow write
+	// doesn't shed all of the store's leases.
+	unhealthyProbeCount = 3
+	// healthyRecoveryWindow is the time for which the probes of an
+	// unhealthy store must succeed before it is healthy again, so that
+	// a flapping store doesn't repeatedly take on and shed leases.
+	healthyRecoveryWindow = 10 * time.Second
+)
+
+// throttleSeverity returns the severity in [0, 1] with which a store
+// should throttle, given the fraction of its disk capacity available
+// and the latency of its most recent probe write. Zero indicates a
+// healthy store; the severity is the worse of the two measures.
+func throttleSeverity(availFrac float64, writeLatency time.Duration) float64 {
+	var disk, write float64
+	if availFrac < lowDiskThreshold {
+		disk = (lowDiskThreshold - math.Max(availFrac, 0)) / lowDiskThreshold
+	}
+	if writeLatency > slowWriteThreshold {
+		write = math.Min(1, float64(writeLatency-slowWriteThreshold)/
+			float64(stalledWriteThreshold-slowWriteThreshold))
+	}
+	return math.Max(disk, write)
+}
+
+// unhealthyReason returns the reason a store is unhealthy, given the
+// fraction of its disk capacity available and the latency or error of
+// its most recent probe write, or the empty string if it's healthy.
+func unhealthyReason(availFrac float64, writeLatency time.Duration, writeErr error) string {
+	switch {
+	case writeErr != nil:
+		return fmt.Sprintf("probe write failed: %s", writeErr)
+	case availFrac <= fullDiskThreshold:
+		return fmt.Sprintf("disk is full with %.1f%% available", availFrac*100)
+	case writeLatency >= stalledWriteThreshold:
+		return fmt.Sprintf("probe write stalled for %s", writeLatency)
+	}
+	return ""
+}
+
+// A healthMonitor periodically measures the available disk capacity
+// of a store's engine and the latency of a small synchronous write to
+// it, and maintains the resulting throttle severity. The severity is
+// gossiped with the store's stats and aggregated by the node to push
+// back on new client requests when all of its stores are unhealthy.
+// The monitor also decides whether the store is unhealthy, in which
+// case the store hands off its leader leases, redirects client writes,
+// refuses new replicas and is avoided by allocators throughout the
+// cluster. A store becomes unhealthy only after unhealthyProbeCount
+// consecutive failed probes, and healthy again only once its probes
+// have succeeded for healthyRecoveryWindow.
+type healthMonitor struct {
+	eng         engine.Engine
+	interval    time.Duration
+	onUnhealthy func() // Called when the store becomes unhealthy; may be nil
+
+	mu           sync.Mutex
+	severity     float64
+	unhealthy    string    // Reason the store is unhealthy; empty if healthy
+	failedProbes int       // Number of consecutive failed probes
+	healthySince time.Time // Time of the first of consecutive successful probes
+}
+
+// newHealthMonitor returns a health monitor for the given engine.
+func newHealthMonitor(eng engine.Engine, interval time.Duration) *healthMonitor {
+	return &healthMonitor{
+		eng:      eng,
+		interval: interval,
+	}
+}
+
+// start runs the health monitor until the stopper is stopped.
+func (hm *healthMonitor) start(stopper *util.Stopper) {
+	stopper.RunWorker(func() {
+		ticker := util.NewTicker(hm.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				hm.check()
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// check probes the engine and updates the throttle severity and
+// health. A failure to write the probe is treated as a stalled engine.
+func (hm *healthMonitor) check() {
+	availFrac := 1.0
+	if capacity, err := hm.eng.Capacity(); err != nil {
+		log.Warningf("unable to determine capacity of %s: %s", hm.eng, err)
+	} else if capacity.Capacity > 0 {
+		availFrac = capacity.PercentAvail()
+	}
+	start := time.Now()
+	latency := stalledWriteThreshold
+	key := engine.MVCCEncodeKey(engine.StoreHealthProbeKey())
+	err := hm.eng.Put(key, []byte(start.String()))
+	if err != nil {
+		log.Warningf("health probe write to %s failed: %s", hm.eng, err)
+	} else {
+		latency = time.Since(start)
+	}
+	hm.observe(time.Now(), throttleSeverity(availFrac, latency), unhealthyReason(availFrac, latency, err))
+}
+
+// observe records the outcome of a probe made at the supplied time,
+// given the resulting throttle severity and the reason the probe
+// found the store unhealthy, if any. The severity takes effect
+// immediately, whereas the store's health changes only as described
+// for healthMonitor.
+func (hm *healthMonitor) observe(now time.Time, severity float64, reason string) {
+	hm.mu.Lock()
+	unhealthy := hm.unhealthy
+	if reason != "" {
+		hm.failedProbes++
+		hm.healthySince = time.Time{}
+		if hm.failedProbes >= unhealthyProbeCount {
+			unhealthy = reason
+		}
+	} else {
+		hm.failedProbes = 0
+		if hm.healthySince.IsZero() {
+			hm.healthySince = now
+		}
+		if now.Sub(hm.healthySince) >= healthyRecoveryWindow {
+			unhealthy = ""
+		}
+	}
+	hm.mu.Unlock()
+	hm.setHealth(severity, unhealthy)
+}
+
+// setHealth sets the throttle severity and the reason the store is
+// unhealthy, logging transitions between healthy, throttled and
+// unhealthy. On becoming unhealthy, onUnhealthy is called
+// synchronously once the new health is visible.
+func (hm *healthMonitor) setHealth(severity float64, unhealthy string) {
+	hm.mu.Lock()
+	becameUnhealthy := unhealthy != "" && hm.unhealthy == ""
+	if (severity > 0) != (hm.severity > 0) {
+		if severity > 0 {
+			log.Warningf("%s is throttling with severity %.2f", hm.eng, severity)
+		} else {
+			log.Infof("%s is no longer throttling", hm.eng)
+		}
+	}
+	if (unhealthy != "") != (hm.unhealthy != "") {
+		if unhealthy != "" {
+			log.Errorf("%s is unhealthy: %s", hm.eng, unhealthy)
+		} else {
+			log.Infof("%s is healthy again", hm.eng)
+		}
+	}
+	hm.severity = severity
+	hm.unhealthy = unhealthy
+	hm.mu.Unlock()
+
+	if becameUnhealthy && hm.onUnhealthy != nil {
+		hm.onUnhealthy()
+	}
+}
+
+// getSeverity returns the most recently measured throttle severity.
+func (hm *healthMonitor) getSeverity() float64 {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	return hm.severity
+}
+
+// getUnhealthy returns the reason the store was most recently found
+// to be unhealthy, or the empty string if it's healthy.
+func (hm *healthMonitor) getUnhealthy() string {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	return hm.unhealthy
+}
+
+// checkHealth returns an error if the store's engine is unhealthy and
+// the command is a client write. The client is redirected with a
+// NotLeaderError to a replica of the range on another store, which
+// takes over the leader lease once the unhealthy store has handed it
+// off or let it expire. If the range has no other replica, a
+// StoreUnhealthyError is returned. Internal and admin commands are
+// executed regardless, as they're needed to resolve intents and to
+// move leases and replicas off the store.
+func (s *Store) checkHealth(method string, header *proto.RequestHeader) error {
+	if proto.IsReadOnly(method) || proto.IsInternal(method) || proto.IsAdmin(method) {
+		return nil
+	}
+	reason := s.healthMonitor.getUnhealthy()
+	if reason == "" {
+		return nil
+	}
+	if rng, err := s.GetRange(header.RaftID); err == nil {
+		if target := s.drainTarget(rng); target != nil {
+			return &proto.NotLeaderError{Leader: *target}
+		}
+	}
+	return &proto.StoreUnhealthyError{StoreID: s.StoreID(), Reason: reason}
+}
+
+// shedLeases transfers the leader leases of the store's replicas to
+// replicas on other stores once the store has become unhealthy.
+func (s *Store) shedLeases() {
+	if n := s.transferLeases("store unhealthy"); n > 0 {
+		log.Warningf("%s: unhealthy; transferred %d leader leases", s, n)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestThrottleSeverity verifies the throttle severity computed from
+// available disk capacity and probe write latency.
+func TestThrottleSeverity(t *testing.T) {
+	testCases := []struct {
+		availFrac float64
+		latency   time.Duration
+		expected  float64
+	}{
+		{1, 0, 0},
+		{lowDiskThreshold, slowWriteThreshold, 0},
+		{lowDiskThreshold / 2, 0, 0.5},
+		{0, 0, 1},
+		{-0.1, 0, 1},
+		{1, (slowWriteThreshold + stalledWriteThreshold) / 2, 0.5},
+		{1, stalledWriteThreshold, 1},
+		{1, 2 * stalledWriteThreshold, 1},
+		// The worse of the two measures is used.
+		{lowDiskThreshold / 2, stalledWriteThreshold, 1},
+		{0, (slowWriteThreshold + stalledWriteThreshold) / 2, 1},
+	}
+	for i, test := range testCases {
+		if s := throttleSeverity(test.availFrac, test.latency); s != test.expected {
+			t.Errorf("%d: expected severity %f; got %f", i, test.expected, s)
+		}
+	}
+}
+
+// TestHealthMonitorCheck verifies that a health check writes the
+// probe key and that a store's severity is reported in its stats.
+func TestHealthMonitorCheck(t *testing.T) {
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	hm := newHealthMonitor(eng, time.Hour)
+	hm.check()
+	val, err := eng.Get(engine.MVCCEncodeKey(engine.StoreHealthProbeKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(val) == 0 {
+		t.Errorf("expected health probe key to be written")
+	}
+	hm.setHealth(0.5, "")
+	if s := hm.getSeverity(); s != 0.5 {
+		t.Errorf("expected severity 0.5; got %f", s)
+	}
+
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.healthMonitor.setHealth(0.25, "")
+	if s := store.Stats().ThrottleSeverity; s != 0.25 {
+		t.Errorf("expected store stats to report severity 0.25; got %f", s)
+	}
+}
+
+// TestUnhealthyReason verifies that a store is unhealthy once its disk
+// is full, its probe write stalls or the probe write fails.
+func TestUnhealthyReason(t *testing.T) {
+	testCases := []struct {
+		availFrac float64
+		latency   time.Duration
+		err       error
+		unhealthy bool
+	}{
+		{1, 0, nil, false},
+		{lowDiskThreshold, slowWriteThreshold, nil, false},
+		{fullDiskThreshold, 0, nil, true},
+		{0, 0, nil, true},
+		{1, stalledWriteThreshold, nil, true},
+		{1, 0, util.Errorf("I/O error"), true},
+	}
+	for i, test := range testCases {
+		if reason := unhealthyReason(test.availFrac, test.latency, test.err); (reason != "") != test.unhealthy {
+			t.Errorf("%d: expected unhealthy=%t; got %q", i, test.unhealthy, reason)
+		}
+	}
+}
+
+// TestHealthMonitorHysteresis verifies that a store becomes unhealthy
+// only after consecutive failed probes, and healthy again only once
+// its probes have succeeded for the recovery window.
+func TestHealthMonitorHysteresis(t *testing.T) {
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	hm := newHealthMonitor(eng, time.Hour)
+	now := time.Unix(0, 0)
+
+	for i := 0; i < unhealthyProbeCount; i++ {
+		if reason := hm.getUnhealthy(); reason != "" {
+			t.Fatalf("%d: expected store to be healthy before %d failed probes; got %q",
+				i, unhealthyProbeCount, reason)
+		}
+		hm.observe(now, 1, "probe write stalled")
+		if i == 0 {
+			// A successful probe resets the count of failed probes.
+			hm.observe(now, 0, "")
+			hm.observe(now, 1, "probe write stalled")
+		}
+	}
+	if reason := hm.getUnhealthy(); reason == "" {
+		t.Fatalf("expected store to be unhealthy after %d failed probes", unhealthyProbeCount)
+	}
+	if s := hm.getSeverity(); s != 1 {
+		t.Errorf("expected severity 1; got %f", s)
+	}
+
+	// The store recovers once its probes have succeeded for the
+	// recovery window; a failed probe restarts the window.
+	hm.observe(now, 0, "")
+	if s := hm.getSeverity(); s != 0 {
+		t.Errorf("expected severity to take effect immediately; got %f", s)
+	}
+	hm.observe(now.Add(healthyRecoveryWindow/2), 1, "probe write stalled")
+	hm.observe(now.Add(healthyRecoveryWindow), 0, "")
+	hm.observe(now.Add(2*healthyRecoveryWindow-1), 0, "")
+	if reason := hm.getUnhealthy(); reason == "" {
+		t.Fatal("expected store to remain unhealthy within the recovery window")
+	}
+	hm.observe(now.Add(2*healthyRecoveryWindow), 0, "")
+	if reason := hm.getUnhealthy(); reason != "" {
+		t.Errorf("expected store to be healthy after the recovery window; got %q", reason)
+	}
+}
+
+// TestStoreUnhealthy verifies that an unhealthy store fails client
+// writes with a StoreUnhealthyError while serving reads, refuses new
+// replicas and reports its health in its stats.
+func TestStoreUnhealthy(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.healthMonitor.setHealth(1, "disk is full")
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	err := store.ExecuteCmd(proto.Put, pArgs, pReply)
+	if _, ok := err.(*proto.StoreUnhealthyError); !ok {
+		t.Fatalf("expected StoreUnhealthyError; got %v", err)
+	}
+	gArgs, gReply := getArgs([]byte("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatalf("expected read to succeed; got %s", err)
+	}
+	if gs := store.GroupStorage(100); gs != nil {
+		t.Error("expected unhealthy store to refuse a new replica")
+	}
+	if reason := store.Stats().Unhealthy; reason != "disk is full" {
+		t.Errorf("expected store stats to report unhealthy store; got %q", reason)
+	}
+
+	store.healthMonitor.setHealth(0, "")
+	pArgs, pReply = putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatalf("expected write to succeed once healthy; got %s", err)
+	}
+}
+
+// TestStoreUnhealthyTransfersLeases verifies that a store which
+// becomes unhealthy hands its leader leases to replicas on other
+// stores and redirects client writes to them.
+func TestStoreUnhealthyTransfersLeases(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	// A write acquires the lease for the store's replica.
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a second replica to the range, which receives the lease.
+	target := proto.Replica{NodeID: 2, StoreID: 2}
+	desc := *rng.Desc()
+	desc.Replicas = append(append([]proto.Replica(nil), desc.Replicas...), target)
+	rng.SetDesc(&desc)
+
+	store.healthMonitor.setHealth(1, "disk is full")
+	lease := rng.getLease()
+	if lease.RaftNodeID != uint64(MakeRaftNodeID(target.NodeID, target.StoreID)) {
+		t.Fatalf("expected lease to be held by %+v; got %+v", target, lease)
+	}
+
+	pArgs, pReply = putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	err = store.ExecuteCmd(proto.Put, pArgs, pReply)
+	if nlErr, ok := err.(*proto.NotLeaderError); !ok {
+		t.Fatalf("expected NotLeaderError; got %v", err)
+	} else if nlErr.Leader.StoreID != target.StoreID {
+		t.Errorf("expected redirect to %+v; got %+v", target, nlErr.Leader)
+	}
+}
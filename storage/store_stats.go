@@ -0,0 +1,104 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writeRateMinInterval is the minimum interval over which the write
+// rate is measured. Measurements requested more frequently return the
+// previously measured rate.
+const writeRateMinInterval = 1 * time.Second
+
+// StoreStats holds usage statistics aggregated over the ranges of a
+// store. They're gossiped as part of the store descriptor for use by
+// the allocator and rebalancer and reported by the admin UI.
+type StoreStats struct {
+	RangeCount int
+	// LeaseCount counts the ranges whose leader lease is held by the
+	// store. The allocator moves leases away from stores holding more
+	// than their share.
+	LeaseCount      int
+	LiveBytes       int64
+	KeyBytes        int64
+	ValBytes        int64
+	WritesPerSecond float64
+	LeaseRenewals   LeaseRenewalStats
+	// ThrottleSeverity is in [0, 1]; non-zero if the store's disk is
+	// nearly full or its writes are stalling.
+	ThrottleSeverity float64
+	// Unhealthy is the reason the store's engine is unhealthy, or empty
+	// if it's healthy. Allocators don't place replicas or leases on
+	// unhealthy stores.
+	Unhealthy string
+	// UnderReplicatedRanges counts the ranges led by the store which
+	// have fewer replicas than their zones require. During a bulk
+	// import it tracks the progress of up-replication.
+	UnderReplicatedRanges int
+	// PendingRangeCreations counts the splits and replica additions
+	// awaiting admission by the store; see RangeCreationInterval.
+	PendingRangeCreations int
+	// ShedCommands counts the client commands shed by the store while
+	// overloaded; see MaxPendingProposals.
+	ShedCommands int64
+	// BlockCacheHitRate is the fraction of the engine's block reads
+	// served from its block cache since the store was opened.
+	BlockCacheHitRate float64
+}
+
+// A writeRate measures the rate of write commands executed by a
+// store. It must be allocated with newWriteRate: count is accessed
+// atomically and so must remain the first field, which keeps it 64-bit
+// aligned on 32-bit platforms.
+type writeRate struct {
+	count int64 // Updated atomically; must be first
+
+	sync.Mutex // Protects the fields below
+	lastCount  int64
+	lastNanos  int64
+	rate       float64
+}
+
+// newWriteRate returns a writeRate whose first measurement begins at
+// nowNanos.
+func newWriteRate(nowNanos int64) *writeRate {
+	return &writeRate{lastNanos: nowNanos}
+}
+
+// record counts a write command.
+func (wr *writeRate) record() {
+	atomic.AddInt64(&wr.count, 1)
+}
+
+// perSecond returns the rate of writes per second since the previous
+// measurement, provided at least writeRateMinInterval has elapsed;
+// otherwise, the previously measured rate is returned.
+func (wr *writeRate) perSecond(nowNanos int64) float64 {
+	wr.Lock()
+	defer wr.Unlock()
+	elapsed := nowNanos - wr.lastNanos
+	if elapsed < writeRateMinInterval.Nanoseconds() {
+		return wr.rate
+	}
+	count := atomic.LoadInt64(&wr.count)
+	wr.rate = float64(count-wr.lastCount) / (float64(elapsed) / 1e9)
+	wr.lastCount = count
+	wr.lastNanos = nowNanos
+	return wr.rate
+}
@@ -104,6 +104,7 @@ func createTestStore(t *testing.T) (*Store, *hlc.ManualClock, *util.Stopper) {
 		t.Fatal(err)
 	}
 	store.db = client.NewKV(nil, &testSender{store: store})
+	store.db.User = UserRoot
 	if err := store.BootstrapRange(); err != nil {
 		t.Fatal(err)
 	}
@@ -402,6 +403,354 @@ func TestStoreExecuteCmd(t *testing.T) {
 	}
 }
 
+// TestStoreDrain verifies that a draining store transfers its leader
+// leases to other replicas and no longer acquires leases itself.
+func TestStoreDrain(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	// A write acquires the lease for the store's replica.
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rng.HasLeaderLease() {
+		t.Fatal("expected store to hold the leader lease")
+	}
+
+	// Add a second replica to the range, which receives the lease.
+	target := proto.Replica{NodeID: 2, StoreID: 2}
+	desc := *rng.Desc()
+	desc.Replicas = append(append([]proto.Replica(nil), desc.Replicas...), target)
+	rng.SetDesc(&desc)
+
+	store.Drain()
+	if !store.Draining() {
+		t.Fatal("expected store to be draining")
+	}
+	lease := rng.getLease()
+	if lease.RaftNodeID != uint64(MakeRaftNodeID(target.NodeID, target.StoreID)) {
+		t.Fatalf("expected lease to be held by %+v; got %+v", target, lease)
+	}
+	if rng.HasLeaderLease() {
+		t.Error("expected draining store not to hold the leader lease")
+	}
+}
+
+// testIntentLimiter records the intent limits set on it.
+type testIntentLimiter struct {
+	maxIntents, maxIntentBytes int64
+}
+
+func (l *testIntentLimiter) SetIntentLimits(maxIntents, maxIntentBytes int64) {
+	l.maxIntents, l.maxIntentBytes = maxIntents, maxIntentBytes
+}
+
+// TestStoreApplyClusterSettings verifies that the data movement
+// settings of the default zone config are applied to the store's
+// queues, that the intent limits are passed to the store's intent
+// limiter, and that invalid pause windows are ignored.
+func TestStoreApplyClusterSettings(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	// The store isn't started, so that configs gossiped by its ranges
+	// don't apply the settings concurrently.
+	limiter := &testIntentLimiter{}
+	store := &Store{
+		StoreConfig:    StoreConfig{IntentLimiter: limiter},
+		splitQueue:     newSplitQueue(nil, nil),
+		replicateQueue: newReplicateQueue(nil, nil, nil),
+		snapshots:      newSnapshotQueue(1),
+	}
+
+	store.applyClusterSettings(&proto.ZoneConfig{
+		SplitIntervalNanos:     int64(time.Hour),
+		RebalanceIntervalNanos: int64(2 * time.Hour),
+		PauseWindows:           "12:00-13:00",
+		MaxTxnIntents:          100,
+		MaxTxnIntentBytes:      1 << 20,
+	})
+	if limiter.maxIntents != 100 || limiter.maxIntentBytes != 1<<20 {
+		t.Errorf("expected intent limits of 100 and 1MiB; got %d and %d", limiter.maxIntents, limiter.maxIntentBytes)
+	}
+	if timer := store.splitQueue.timer(); timer != time.Hour {
+		t.Errorf("expected split queue timer of 1h; got %s", timer)
+	}
+	if timer := store.replicateQueue.timer(); timer != 2*time.Hour {
+		t.Errorf("expected replicate queue timer of 2h; got %s", timer)
+	}
+	noon := time.Date(2015, 6, 1, 12, 30, 0, 0, time.UTC)
+	if !store.dataMovementPaused(noon) {
+		t.Error("expected data movement to be paused at 12:30")
+	}
+	if store.dataMovementPaused(noon.Add(time.Hour)) {
+		t.Error("expected data movement not to be paused at 13:30")
+	}
+
+	store.applyClusterSettings(&proto.ZoneConfig{PauseWindows: "noon"})
+	if timer := store.splitQueue.timer(); timer != splitQueueTimerDuration {
+		t.Errorf("expected default split queue timer; got %s", timer)
+	}
+	if !store.dataMovementPaused(noon) {
+		t.Error("expected invalid pause windows to be ignored")
+	}
+}
+
+// TestStoreBackpressureOversizedRange verifies that writes which add
+// data to a range grown far beyond its max size fail with a
+// proto.RangeTooLargeError once backpressure gives up, while deletions
+// proceed, and that such writes are throttled rather than refused
+// while splits are paused.
+func TestStoreBackpressureOversizedRange(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	setTestRetryOptions(store)
+
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng.SetMaxBytes(1 << 10)
+	rng.stats.SetMVCCStats(store.Engine(), engine.MVCCStats{KeyBytes: 3 << 10})
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	err = store.ExecuteCmd(proto.Put, pArgs, pReply)
+	if tlErr, ok := err.(*proto.RangeTooLargeError); !ok {
+		t.Fatalf("expected RangeTooLargeError; got %v", err)
+	} else if tlErr.Bytes != 3<<10 || tlErr.MaxBytes != 1<<10 {
+		t.Errorf("unexpected error %+v", tlErr)
+	}
+
+	dArgs, dReply := deleteArgs(proto.Key("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Delete, dArgs, dReply); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pause data movement for the whole day.
+	store.pauseMu.Lock()
+	store.pauseWindows = []TimeWindow{{Start: 0, End: 24 * time.Hour}}
+	store.pauseMu.Unlock()
+	start := time.Now()
+	pArgs, pReply = putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < backpressurePausedDelay {
+		t.Errorf("expected write to be delayed by at least %s; took %s", backpressurePausedDelay, elapsed)
+	}
+	store.pauseMu.Lock()
+	store.pauseWindows = nil
+	store.pauseMu.Unlock()
+
+	// Once the range is no longer oversized, writes succeed again.
+	rng.SetMaxBytes(64 << 20)
+	pArgs, pReply = putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStoreVerifyPermissions verifies that the store checks the
+// gossiped permission configs before executing a command, returning
+// a PermissionError if the user lacks the required permission.
+func TestStoreVerifyPermissions(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	// Grant user "foo" read permission on prefix "/db1"; writing the
+	// config causes it to be re-gossiped.
+	perm := &proto.PermConfig{
+		Read:  []string{"foo"},
+		Write: []string{UserRoot},
+	}
+	data, err := gogoproto.Marshal(perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := engine.MakeKey(engine.KeyConfigPermissionPrefix, proto.Key("/db1"))
+	pArgs, pReply := putArgs(key, data, 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		method string
+		key    proto.Key
+		expErr bool
+	}{
+		{proto.Get, proto.Key("/db1/a"), false},
+		{proto.Put, proto.Key("/db1/a"), true},
+		{proto.Get, proto.Key("a"), true},
+	}
+	for i, test := range testCases {
+		_, args, reply := readOrWriteArgs(test.key, test.method == proto.Get, 1, store.StoreID())
+		args.Header().User = "foo"
+		err := store.ExecuteCmd(test.method, args, reply)
+		if !test.expErr {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		if _, ok := err.(*proto.PermissionError); !ok {
+			t.Errorf("%d: expected permission error; got %v", i, err)
+		}
+	}
+}
+
+// TestStorePreemptiveSnapshotGC verifies that ranges initialized by
+// preemptive snapshots are destroyed if their replica change doesn't
+// commit within preemptiveSnapshotExpiration, and are retained once
+// the range descriptor includes the store.
+func TestStorePreemptiveSnapshotGC(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+	// Without a KV client, expired ranges are destroyed without
+	// consulting the committed range descriptor.
+	store.db = nil
+
+	// Remove range 1 so the test ranges don't overlap it.
+	rng1, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveRange(rng1); err != nil {
+		t.Fatal(err)
+	}
+	// Neither range's descriptor includes the store.
+	rng2 := createRange(store, 2, proto.Key("a"), proto.Key("b"))
+	rng3 := createRange(store, 3, proto.Key("c"), proto.Key("d"))
+	for _, rng := range []*Range{rng2, rng3} {
+		if err := store.AddRange(rng); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ProcessRangeDescriptorUpdate(rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(store.preemptive) != 2 {
+		t.Fatalf("expected 2 preemptive ranges; got %d", len(store.preemptive))
+	}
+
+	// Range 3's replica change commits.
+	desc := *rng3.Desc()
+	desc.Replicas = []proto.Replica{{NodeID: 1, StoreID: store.StoreID()}}
+	rng3.SetDesc(&desc)
+
+	// Nothing is destroyed before expiration.
+	store.gcPreemptiveSnapshots()
+	if _, err := store.GetRange(2); err != nil {
+		t.Errorf("expected range 2 to remain before expiration: %s", err)
+	}
+
+	manual.Set(preemptiveSnapshotExpiration.Nanoseconds() + 1)
+	store.gcPreemptiveSnapshots()
+	if _, err := store.GetRange(2); err == nil {
+		t.Error("expected expired preemptive range 2 to be removed")
+	}
+	if _, err := store.GetRange(3); err != nil {
+		t.Errorf("expected member range 3 to remain: %s", err)
+	}
+	if len(store.preemptive) != 0 {
+		t.Errorf("expected no preemptive ranges; got %v", store.preemptive)
+	}
+}
+
+// TestStoreAuthorizer verifies that a registered Authorizer is
+// consulted with the user, method and key span of every command and
+// that its errors are returned to the caller.
+func TestStoreAuthorizer(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	type authCall struct {
+		method, user string
+		key          proto.Key
+	}
+	var calls []authCall
+	denied := &proto.PermissionError{User: "foo", Method: proto.Put}
+	store.Authorizer = AuthorizerFunc(func(method string, header *proto.RequestHeader) error {
+		calls = append(calls, authCall{method, header.User, header.Key})
+		if method == proto.Put && header.User == "foo" {
+			return denied
+		}
+		return nil
+	})
+
+	gArgs, gReply := getArgs(proto.Key("a"), 1, store.StoreID())
+	gArgs.User = "foo"
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	pArgs, pReply := putArgs(proto.Key("a"), []byte("value"), 1, store.StoreID())
+	pArgs.User = "foo"
+	if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != denied {
+		t.Errorf("expected authorizer error; got %v", err)
+	}
+	expCalls := []authCall{
+		{proto.Get, "foo", proto.Key("a")},
+		{proto.Put, "foo", proto.Key("a")},
+	}
+	if !reflect.DeepEqual(calls, expCalls) {
+		t.Errorf("expected authorizer calls %+v; got %+v", expCalls, calls)
+	}
+}
+
+// TestStoreStats verifies that store stats aggregate range stats and
+// measure the rate of write commands.
+func TestStoreStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	const numWrites = 10
+	for i := 0; i < numWrites; i++ {
+		pArgs, pReply := putArgs([]byte(fmt.Sprintf("a%d", i)), []byte("value"), 1, store.StoreID())
+		if err := store.ExecuteCmd(proto.Put, pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gArgs, gReply := getArgs([]byte("a0"), 1, store.StoreID())
+	if err := store.ExecuteCmd(proto.Get, gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+
+	manual.Set(2 * time.Second.Nanoseconds())
+	stats := store.Stats()
+	if stats.RangeCount != 1 {
+		t.Errorf("expected 1 range; got %d", stats.RangeCount)
+	}
+	if stats.LiveBytes <= 0 || stats.KeyBytes <= 0 || stats.ValBytes <= 0 {
+		t.Errorf("expected positive live, key and value bytes; got %+v", stats)
+	}
+	if expRate := float64(numWrites) / 2; stats.WritesPerSecond != expRate {
+		t.Errorf("expected %f writes per second; got %f", expRate, stats.WritesPerSecond)
+	}
+	// Within the minimum interval, the previous rate is returned.
+	if rate := store.Stats().WritesPerSecond; rate != stats.WritesPerSecond {
+		t.Errorf("expected unchanged write rate %f; got %f", stats.WritesPerSecond, rate)
+	}
+
+	desc, err := store.Descriptor(&NodeDescriptor{NodeID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Stats.RangeCount != 1 {
+		t.Errorf("expected store descriptor to include stats; got %+v", desc.Stats)
+	}
+	if hitRate := desc.Stats.BlockCacheHitRate; hitRate < 0 || hitRate > 1 {
+		t.Errorf("expected block cache hit rate in [0, 1]; got %f", hitRate)
+	}
+}
+
 // TestStoreVerifyKeys checks that key length is enforced and
 // that end keys must sort >= start.
 func TestStoreVerifyKeys(t *testing.T) {
@@ -0,0 +1,79 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// A TimeWindow is a daily window of time, specified as offsets from
+// midnight UTC. A window whose End precedes its Start wraps past
+// midnight.
+type TimeWindow struct {
+	Start, End time.Duration
+}
+
+// Contains returns whether the time of day of t falls within the
+// window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// ParseTimeWindows parses a comma-separated list of daily time
+// windows, each specified as "HH:MM-HH:MM" in UTC. For example,
+// "22:00-02:00,12:00-13:00" specifies the two hours either side of
+// midnight and the hour after noon.
+func ParseTimeWindows(s string) ([]TimeWindow, error) {
+	var windows []TimeWindow
+	for _, spec := range strings.Split(s, ",") {
+		if len(spec) == 0 {
+			continue
+		}
+		bounds := strings.Split(spec, "-")
+		if len(bounds) != 2 {
+			return nil, util.Errorf("unable to parse time window %q; expected HH:MM-HH:MM", spec)
+		}
+		var w TimeWindow
+		for i, dst := range []*time.Duration{&w.Start, &w.End} {
+			t, err := time.Parse("15:04", strings.TrimSpace(bounds[i]))
+			if err != nil {
+				return nil, util.Errorf("unable to parse time window %q: %s", spec, err)
+			}
+			*dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// inTimeWindows returns whether t falls within any of windows.
+func inTimeWindows(windows []TimeWindow, t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
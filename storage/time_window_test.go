@@ -0,0 +1,85 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestParseTimeWindows verifies parsing of time window lists.
+func TestParseTimeWindows(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	testCases := []struct {
+		s      string
+		expErr bool
+		exp    []TimeWindow
+	}{
+		{"", false, nil},
+		{"12:00-13:30", false, []TimeWindow{{12 * time.Hour, 13*time.Hour + 30*time.Minute}}},
+		{"22:00-02:00, 08:15-09:00", false, []TimeWindow{
+			{22 * time.Hour, 2 * time.Hour},
+			{8*time.Hour + 15*time.Minute, 9 * time.Hour},
+		}},
+		{"12:00", true, nil},
+		{"12:00-25:00", true, nil},
+		{"noon-13:00", true, nil},
+	}
+	for i, test := range testCases {
+		windows, err := ParseTimeWindows(test.s)
+		if (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(windows, test.exp) {
+			t.Errorf("%d: expected %v; got %v", i, test.exp, windows)
+		}
+	}
+}
+
+// TestTimeWindowContains verifies that windows contain the times of
+// day within them, including windows which wrap past midnight.
+func TestTimeWindowContains(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	at := func(hour, min int) time.Time {
+		return time.Date(2015, 3, 1, hour, min, 0, 0, time.UTC)
+	}
+	day := TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour}
+	night := TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour}
+	testCases := []struct {
+		w   TimeWindow
+		t   time.Time
+		exp bool
+	}{
+		{day, at(8, 59), false},
+		{day, at(9, 0), true},
+		{day, at(16, 59), true},
+		{day, at(17, 0), false},
+		{night, at(21, 59), false},
+		{night, at(23, 0), true},
+		{night, at(1, 59), true},
+		{night, at(2, 0), false},
+		{night, at(12, 0), false},
+	}
+	for i, test := range testCases {
+		if c := test.w.Contains(test.t); c != test.exp {
+			t.Errorf("%d: expected %v to contain %s: %t; got %t", i, test.w, test.t, test.exp, c)
+		}
+	}
+}
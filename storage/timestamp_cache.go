@@ -34,6 +34,11 @@ const (
 	// than minCacheWindow will necessarily have to advance their commit
 	// timestamp.
 	MinTSCacheWindow = 10 * time.Second
+
+	// MaxTSCacheEntries bounds the number of entries held in the cache.
+	// Once exceeded, the oldest entries are evicted even if they are
+	// still within MinTSCacheWindow, forwarding the low water mark.
+	MaxTSCacheEntries = 64 << 10
 )
 
 // A TimestampCache maintains an interval tree FIFO cache of keys or
@@ -48,9 +53,16 @@ const (
 // recently evicted entry's timestamp. This value always ratchets
 // with monotonic increases. The low water mark is initialized to
 // the current system time plus the maximum clock offset.
+//
+// Entries are evicted in insertion order once they fall outside of
+// MinTSCacheWindow or once the cache holds more than maxEntries.
+// Because eviction forwards the low water mark, bounding the cache's
+// size doesn't compromise correctness; it only makes the cache
+// coarser, causing more writes to have their timestamps pushed.
 type TimestampCache struct {
 	cache            *util.IntervalCache
 	lowWater, latest proto.Timestamp
+	maxEntries       int
 }
 
 // A cacheEntry combines the timestamp with an optional MD5 of the
@@ -65,7 +77,8 @@ type cacheEntry struct {
 // hybrid clock.
 func NewTimestampCache(clock *hlc.Clock) *TimestampCache {
 	tc := &TimestampCache{
-		cache: util.NewIntervalCache(util.CacheConfig{Policy: util.CacheFIFO}),
+		cache:      util.NewIntervalCache(util.CacheConfig{Policy: util.CacheFIFO}),
+		maxEntries: MaxTSCacheEntries,
 	}
 	tc.Clear(clock)
 	tc.cache.CacheConfig.ShouldEvict = tc.shouldEvict
@@ -81,6 +94,24 @@ func (tc *TimestampCache) Clear(clock *hlc.Clock) {
 	tc.latest = tc.lowWater
 }
 
+// SetLowWater raises the low water mark to the supplied timestamp if
+// it is greater than the current low water mark. Entries at or below
+// the new low water mark are left in place; they no longer affect the
+// results of GetMax.
+func (tc *TimestampCache) SetLowWater(lowWater proto.Timestamp) {
+	if tc.lowWater.Less(lowWater) {
+		tc.lowWater = lowWater
+	}
+	if tc.latest.Less(tc.lowWater) {
+		tc.latest = tc.lowWater
+	}
+}
+
+// LowWater returns the low water mark of the cache.
+func (tc *TimestampCache) LowWater() proto.Timestamp {
+	return tc.lowWater
+}
+
 // Add the specified timestamp to the cache as covering the range of
 // keys from start to end. If end is nil, the range covers the start
 // key only. txnMD5 is empty for no transaction. readOnly specifies
@@ -160,7 +191,7 @@ func (tc *TimestampCache) MergeInto(dest *TimestampCache, clear bool) {
 			dest.lowWater = tc.lowWater
 		}
 		if dest.latest.Less(tc.latest) {
-			tc.latest = dest.latest
+			dest.latest = tc.latest
 		}
 	}
 	tc.cache.Do(func(k, v interface{}) {
@@ -168,17 +199,71 @@ func (tc *TimestampCache) MergeInto(dest *TimestampCache, clear bool) {
 	})
 }
 
+// CopyInto clears the dest timestamp cache and copies into it the
+// values of lowWater and latest and the entries overlapping the
+// interval from start to end, clipped to the interval. This derives
+// the timestamp cache of a range split off from this one, so that
+// reads and writes on the parent remain visible to the new range
+// without retaining entries for keys it doesn't contain.
+func (tc *TimestampCache) CopyInto(dest *TimestampCache, start, end proto.Key) {
+	dest.cache.Clear()
+	dest.lowWater = tc.lowWater
+	dest.latest = tc.latest
+	for _, o := range tc.cache.GetOverlaps(start, end) {
+		oStart, oEnd := clipInterval(o.Key, start, end)
+		dest.cache.Add(dest.cache.NewKey(oStart, oEnd), o.Value)
+	}
+}
+
+// Truncate removes entries which don't overlap the interval from
+// start to end and clips those which extend beyond it. This discards
+// the entries of keys which have been split off into another range.
+func (tc *TimestampCache) Truncate(start, end proto.Key) {
+	var clipped []util.Overlap
+	tc.cache.Do(func(k, v interface{}) {
+		key := k.(*util.IntervalKey)
+		if key.Start().Compare(start) < 0 || key.End().Compare(end) > 0 {
+			clipped = append(clipped, util.Overlap{Key: key, Value: v})
+		}
+	})
+	for _, o := range clipped {
+		tc.cache.Del(o.Key)
+		if o.Key.End().Compare(start) > 0 && o.Key.Start().Compare(end) < 0 {
+			oStart, oEnd := clipInterval(o.Key, start, end)
+			tc.cache.Add(tc.cache.NewKey(oStart, oEnd), o.Value)
+		}
+	}
+}
+
+// clipInterval returns the start and end of key clipped to the
+// interval from start to end.
+func clipInterval(key *util.IntervalKey, start, end proto.Key) (proto.Key, proto.Key) {
+	kStart, kEnd := key.Start().(proto.Key), key.End().(proto.Key)
+	if kStart.Less(start) {
+		kStart = start
+	}
+	if end.Less(kEnd) {
+		kEnd = end
+	}
+	return kStart, kEnd
+}
+
 // shouldEvict returns true if the cache entry's timestamp is no
-// longer within the MinTSCacheWindow.
+// longer within the MinTSCacheWindow or if the cache has grown beyond
+// maxEntries.
 func (tc *TimestampCache) shouldEvict(size int, key, value interface{}) bool {
 	ce := value.(cacheEntry)
 	// Compute the edge of the cache window.
 	edge := tc.latest
 	edge.WallTime -= MinTSCacheWindow.Nanoseconds()
 	// We evict and update the low water mark if the proposed evictee's
-	// timestamp is <= than the edge of the window.
-	if !edge.Less(ce.timestamp) {
-		tc.lowWater = ce.timestamp
+	// timestamp is <= than the edge of the window, or if the cache is
+	// full. Entries are evicted in insertion order, which need not be
+	// timestamp order, so the low water mark is only ever forwarded.
+	if !edge.Less(ce.timestamp) || size > tc.maxEntries {
+		if tc.lowWater.Less(ce.timestamp) {
+			tc.lowWater = ce.timestamp
+		}
 		return true
 	}
 	return false
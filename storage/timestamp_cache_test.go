@@ -126,6 +126,45 @@ func TestTimestampCacheEviction(t *testing.T) {
 	}
 }
 
+// TestTimestampCacheMaxEntries verifies that the cache is bounded in
+// size, evicting its oldest entries even within MinTSCacheWindow, and
+// that the low water mark only ratchets forward as entries are
+// evicted out of timestamp order.
+func TestTimestampCacheMaxEntries(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	clock.SetMaxOffset(maxClockOffset)
+	tc := NewTimestampCache(clock)
+	tc.maxEntries = 2
+
+	manual.Set(maxClockOffset.Nanoseconds() + 1)
+	aTS := clock.Now()
+	bTS := aTS.Add(10, 0)
+	tc.Add(proto.Key("b"), nil, bTS, proto.NoTxnMD5, true)
+	tc.Add(proto.Key("a"), nil, aTS, proto.NoTxnMD5, true)
+	if l := tc.cache.Len(); l != 2 {
+		t.Fatalf("expected 2 entries; got %d", l)
+	}
+
+	// Adding a third entry evicts "b", which was added first.
+	cTS := aTS.Add(5, 0)
+	tc.Add(proto.Key("c"), nil, cTS, proto.NoTxnMD5, true)
+	if l := tc.cache.Len(); l != 2 {
+		t.Fatalf("expected 2 entries; got %d", l)
+	}
+	if rTS, _ := tc.GetMax(proto.Key("b"), nil, proto.NoTxnMD5); !rTS.Equal(bTS) {
+		t.Errorf("expected low water mark %s for evicted \"b\"; got %s", bTS, rTS)
+	}
+
+	// Evicting "a", whose timestamp precedes the low water mark, must
+	// not move the low water mark backwards.
+	tc.Add(proto.Key("d"), nil, aTS.Add(20, 0), proto.NoTxnMD5, true)
+	if rTS, _ := tc.GetMax(proto.Key("a"), nil, proto.NoTxnMD5); !rTS.Equal(bTS) {
+		t.Errorf("expected low water mark %s for evicted \"a\"; got %s", bTS, rTS)
+	}
+}
+
 func TestTimestampCacheMergeInto(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	manual := hlc.NewManualClock(0)
@@ -187,6 +226,57 @@ func TestTimestampCacheMergeInto(t *testing.T) {
 	}
 }
 
+// TestTimestampCacheSplit verifies that on a split, the timestamp
+// caches of both ranges are derived from the parent's, retaining only
+// entries for their own keys.
+func TestTimestampCacheSplit(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	parent := NewTimestampCache(clock)
+	child := NewTimestampCache(clock)
+
+	adTS := clock.Now()
+	parent.Add(proto.Key("a"), proto.Key("d"), adTS, proto.NoTxnMD5, true)
+	bTS := clock.Now()
+	parent.Add(proto.Key("b"), nil, bTS, proto.NoTxnMD5, false)
+	eTS := clock.Now()
+	parent.Add(proto.Key("e"), nil, eTS, proto.NoTxnMD5, true)
+
+	// Split at "c": the child holds "c"-"d" and "e".
+	splitKey := proto.Key("c")
+	parent.CopyInto(child, splitKey, proto.KeyMax)
+	parent.Truncate(proto.KeyMin, splitKey)
+
+	if child.cache.Len() != 2 {
+		t.Errorf("expected 2 entries in child; got %d", child.cache.Len())
+	}
+	if parent.cache.Len() != 2 {
+		t.Errorf("expected 2 entries in parent; got %d", parent.cache.Len())
+	}
+	if !child.lowWater.Equal(parent.lowWater) || !child.latest.Equal(parent.latest) {
+		t.Errorf("expected child low water and latest to match parent")
+	}
+
+	testCases := []struct {
+		tc         *TimestampCache
+		key        proto.Key
+		expR, expW proto.Timestamp
+	}{
+		{parent, proto.Key("a"), adTS, parent.lowWater},
+		{parent, proto.Key("b"), adTS, bTS},
+		{child, proto.Key("c"), adTS, child.lowWater},
+		{child, proto.Key("e"), eTS, child.lowWater},
+		{child, proto.Key("b"), child.lowWater, child.lowWater},
+	}
+	for i, test := range testCases {
+		rTS, wTS := test.tc.GetMax(test.key, nil, proto.NoTxnMD5)
+		if !rTS.Equal(test.expR) || !wTS.Equal(test.expW) {
+			t.Errorf("%d: expected %s, %s for key %q; got %s, %s", i, test.expR, test.expW, test.key, rTS, wTS)
+		}
+	}
+}
+
 // TestTimestampCacheLayeredIntervals verifies the maximum timestamp
 // is chosen if previous entries have ranges which are layered over
 // each other.
@@ -264,6 +354,33 @@ func TestTimestampCacheClear(t *testing.T) {
 	}
 }
 
+// TestTimestampCacheSetLowWater verifies that the low water mark is
+// only ever forwarded and masks entries beneath it.
+func TestTimestampCacheSetLowWater(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	clock.SetMaxOffset(maxClockOffset)
+	tc := NewTimestampCache(clock)
+
+	manual.Set(maxClockOffset.Nanoseconds() + 1)
+	ts := clock.Now()
+	tc.Add(proto.Key("a"), nil, ts, proto.NoTxnMD5, true)
+
+	// Forward the low water mark beyond the cached read.
+	lowWater := ts.Add(10, 0)
+	tc.SetLowWater(lowWater)
+	if rTS, _ := tc.GetMax(proto.Key("a"), nil, proto.NoTxnMD5); !rTS.Equal(lowWater) {
+		t.Errorf("expected low water mark %s for \"a\"; got %s", lowWater, rTS)
+	}
+
+	// An earlier low water mark is ignored.
+	tc.SetLowWater(ts)
+	if rTS, _ := tc.GetMax(proto.Key("b"), nil, proto.NoTxnMD5); !rTS.Equal(lowWater) {
+		t.Errorf("expected low water mark %s for \"b\"; got %s", lowWater, rTS)
+	}
+}
+
 // TestTimestampCacheReplacements verifies that a newer entry
 // in the timestamp cache which completely "covers" an older
 // entry will replace it.
@@ -0,0 +1,137 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// defaultSlowCmdThreshold is the default execution time beyond which
+// a command's trace is logged.
+const defaultSlowCmdThreshold = time.Second
+
+// The names of the spans recorded for the execution of a command.
+const (
+	spanCmdQueue       = "command queue"   // Waiting for overlapping commands
+	spanTimestampCache = "timestamp cache" // Consulting the timestamp cache
+	spanRaftProposal   = "raft proposal"   // Proposing until committed
+	spanApply          = "apply"           // Applying the committed command
+	spanEngineWrite    = "engine write"    // Committing the command's batch
+)
+
+// A traceSpan records a step in the execution of a command.
+type traceSpan struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+}
+
+// A cmdTrace records the spans of a command's execution by a store,
+// so that the time taken by a slow command can be decomposed. Only
+// the commands of traced requests, those whose header carries a trace
+// ID, are given a trace; the gateway assigns trace IDs to a sample of
+// requests. Spans may be recorded concurrently, as a command is
+// applied by the Raft processing goroutine. The methods of a nil
+// cmdTrace are no-ops, so that untraced commands needn't be
+// special-cased.
+type cmdTrace struct {
+	method  string
+	traceID int64
+	start   time.Time
+
+	mu    sync.Mutex
+	spans []traceSpan
+}
+
+// newCmdTrace returns a trace of the execution of a command which
+// begins now, or nil if the command's request isn't traced.
+func newCmdTrace(method string, header *proto.RequestHeader) *cmdTrace {
+	if header.TraceID == 0 {
+		return nil
+	}
+	return &cmdTrace{
+		method:  method,
+		traceID: header.TraceID,
+		start:   util.Now(),
+	}
+}
+
+// endNoSpan is returned by the span method of a nil trace, so that
+// untraced commands don't allocate a closure per span.
+func endNoSpan() {}
+
+// span begins a span with the given name, returning a func which ends
+// it.
+func (t *cmdTrace) span(name string) func() {
+	if t == nil {
+		return endNoSpan
+	}
+	start := util.Now()
+	return func() {
+		duration := util.Now().Sub(start)
+		t.mu.Lock()
+		t.spans = append(t.spans, traceSpan{name: name, start: start, duration: duration})
+		t.mu.Unlock()
+	}
+}
+
+// elapsed returns the time since the trace began.
+func (t *cmdTrace) elapsed() time.Duration {
+	return util.Now().Sub(t.start)
+}
+
+// String formats the trace with the offset of each span from the
+// beginning of the trace.
+func (t *cmdTrace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "trace %d: %s %s", t.traceID, t.method, t.elapsed())
+	for _, s := range t.spans {
+		fmt.Fprintf(&buf, "; %s %s at +%s", s.name, s.duration, s.start.Sub(t.start))
+	}
+	return buf.String()
+}
+
+// maybeLogCmd logs the execution of a command which began at start,
+// along with the error in its reply, if its request is traced or its
+// execution took at least the store's SlowCmdThreshold. The spans of
+// traced commands are included; untraced slow commands are logged
+// with their duration only.
+func (s *Store) maybeLogCmd(method string, start time.Time, t *cmdTrace, reply proto.Response) {
+	var desc string
+	if t != nil {
+		desc = t.String()
+	} else {
+		elapsed := util.Now().Sub(start)
+		if s.SlowCmdThreshold < 0 || elapsed < s.SlowCmdThreshold {
+			return
+		}
+		desc = fmt.Sprintf("slow command: %s %s", method, elapsed)
+	}
+	if err := reply.Header().GoError(); err != nil {
+		log.Infof("store %d: %s; failed: %s", s.StoreID(), desc, err)
+		return
+	}
+	log.Infof("store %d: %s", s.StoreID(), desc)
+}
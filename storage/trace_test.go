@@ -0,0 +1,80 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// traceSpanNames returns the set of the names of the trace's spans.
+func traceSpanNames(trace *cmdTrace) map[string]bool {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	names := map[string]bool{}
+	for _, s := range trace.spans {
+		names[s.name] = true
+	}
+	return names
+}
+
+// TestCmdTrace verifies that the steps of the execution of reads and
+// writes are recorded in their traces.
+func TestCmdTrace(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("aaa"), 1, store.StoreID())
+	pArgs.Timestamp = store.clock.Now()
+	pArgs.TraceID = 1
+	trace := newCmdTrace(proto.Put, &pArgs.RequestHeader)
+	if err := rng.addCmd(proto.Put, pArgs, pReply, true, trace); err != nil {
+		t.Fatal(err)
+	}
+	names := traceSpanNames(trace)
+	for _, name := range []string{spanCmdQueue, spanTimestampCache, spanRaftProposal, spanApply, spanEngineWrite} {
+		if !names[name] {
+			t.Errorf("expected write trace to contain span %q; got %s", name, trace)
+		}
+	}
+
+	gArgs, gReply := getArgs([]byte("a"), 1, store.StoreID())
+	gArgs.Timestamp = store.clock.Now()
+	gArgs.TraceID = 2
+	trace = newCmdTrace(proto.Get, &gArgs.RequestHeader)
+	if err := rng.addCmd(proto.Get, gArgs, gReply, true, trace); err != nil {
+		t.Fatal(err)
+	}
+	if names := traceSpanNames(trace); len(names) != 1 || !names[spanCmdQueue] {
+		t.Errorf("expected read trace to contain only span %q; got %s", spanCmdQueue, trace)
+	}
+
+	// Untraced requests aren't given a trace, and spans of nil traces
+	// are ignored.
+	gArgs.TraceID = 0
+	nilTrace := newCmdTrace(proto.Get, &gArgs.RequestHeader)
+	if nilTrace != nil {
+		t.Errorf("expected no trace for an untraced request; got %s", nilTrace)
+	}
+	nilTrace.span(spanApply)()
+}
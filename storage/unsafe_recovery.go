@@ -0,0 +1,111 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// UnsafeRecoverRange forcibly rewrites the descriptor of the range
+// with the specified Raft ID held in eng so that its replica set
+// consists of only the surviving stores, and returns the rewritten
+// descriptor. It is the last resort for a range which has permanently
+// lost its quorum.
+//
+// THIS IS UNSAFE. It bypasses consensus: writes committed by the lost
+// quorum but not yet applied by the survivors are lost, and the
+// survivors may disagree about the state of the range. It must only be
+// run while the store's node is stopped, and it must be run with the
+// same survivors on the stores of each of them. As the Raft
+// configuration of a range is derived from its descriptor, the
+// survivors form the range's quorum once restarted. The range's
+// addressing records aren't updated; they're corrected on the range's
+// next replica change.
+//
+// An uncommitted intent on the descriptor, as left by a replica change
+// or split which could not complete, is aborted.
+func UnsafeRecoverRange(eng engine.Engine, raftID int64, survivors []proto.StoreID, now proto.Timestamp) (*proto.RangeDescriptor, error) {
+	if len(survivors) == 0 {
+		return nil, util.Errorf("no surviving stores specified for range %d", raftID)
+	}
+	var ident proto.StoreIdent
+	ok, err := engine.MVCCGetProto(eng, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &ident)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, &NotBootstrappedError{}
+	}
+
+	// Find the range's descriptor, including uncommitted versions.
+	var desc *proto.RangeDescriptor
+	var descKey proto.Key
+	start := engine.RangeDescriptorKey(engine.KeyMin)
+	end := engine.RangeDescriptorKey(engine.KeyMax)
+	if err := engine.MVCCIterate(eng, start, end, 0, now, false, nil, func(kv proto.KeyValue) (bool, error) {
+		_, suffix, _ := engine.DecodeRangeKey(kv.Key)
+		if !suffix.Equal(engine.KeyLocalRangeDescriptorSuffix) {
+			return false, nil
+		}
+		d := &proto.RangeDescriptor{}
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, d); err != nil {
+			return false, err
+		}
+		if d.RaftID != raftID {
+			return false, nil
+		}
+		desc, descKey = d, kv.Key
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, util.Errorf("range %d not found on store %d", raftID, ident.StoreID)
+	}
+
+	newDesc := *desc
+	newDesc.Replicas = nil
+	for _, storeID := range survivors {
+		_, replica := desc.FindReplica(storeID)
+		if replica == nil {
+			return nil, util.Errorf("store %d isn't a replica of range %d: %+v", storeID, raftID, desc.Replicas)
+		}
+		newDesc.Replicas = append(newDesc.Replicas, *replica)
+	}
+	if _, replica := newDesc.FindReplica(ident.StoreID); replica == nil {
+		return nil, util.Errorf("store %d isn't a surviving replica of range %d", ident.StoreID, raftID)
+	}
+
+	ms := &engine.MVCCStats{}
+	if _, err := engine.MVCCGet(eng, descKey, now, true, nil); err != nil {
+		wiErr, ok := err.(*proto.WriteIntentError)
+		if !ok {
+			return nil, err
+		}
+		txn := gogoproto.Clone(&wiErr.Txn).(*proto.Transaction)
+		txn.Status = proto.ABORTED
+		if err := engine.MVCCResolveWriteIntent(eng, ms, descKey, now, txn); err != nil {
+			return nil, err
+		}
+	}
+	if err := engine.MVCCPutProto(eng, ms, descKey, now, nil, &newDesc); err != nil {
+		return nil, err
+	}
+	ms.MergeStats(eng, raftID)
+	return &newDesc, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestUnsafeRecoverRange verifies that the descriptor of a range is
+// rewritten to hold only the surviving replicas, aborting an intent
+// left on it, and that invalid replica sets are refused.
+func TestUnsafeRecoverRange(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	ident := proto.StoreIdent{ClusterID: "cluster", NodeID: 1, StoreID: 1}
+	if err := engine.MVCCPutProto(eng, nil, engine.StoreIdentKey(), proto.ZeroTimestamp, nil, &ident); err != nil {
+		t.Fatal(err)
+	}
+	desc := &proto.RangeDescriptor{
+		RaftID:   1,
+		StartKey: engine.KeyMin,
+		EndKey:   engine.KeyMax,
+		Replicas: createReplicaSets([]proto.StoreID{1, 2, 3}),
+	}
+	descKey := engine.RangeDescriptorKey(desc.StartKey)
+	if err := engine.MVCCPutProto(eng, nil, descKey, makeTS(1, 0), nil, desc); err != nil {
+		t.Fatal(err)
+	}
+	// Leave the intent of a replica change which couldn't complete.
+	txn := proto.NewTransaction("test", descKey, 1, proto.SERIALIZABLE, makeTS(2, 0), 0)
+	pending := *desc
+	pending.Replicas = createReplicaSets([]proto.StoreID{1, 2, 3, 4})
+	if err := engine.MVCCPutProto(eng, nil, descKey, makeTS(2, 0), txn, &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	now := makeTS(3, 0)
+	for _, survivors := range [][]proto.StoreID{
+		{},     // no survivors
+		{1, 4}, // store 4 isn't a replica
+		{2, 3}, // local store isn't a survivor
+	} {
+		if _, err := UnsafeRecoverRange(eng, 1, survivors, now); err == nil {
+			t.Errorf("expected recovery with survivors %v to fail", survivors)
+		}
+	}
+	if _, err := UnsafeRecoverRange(eng, 2, []proto.StoreID{1}, now); err == nil {
+		t.Error("expected recovery of missing range to fail")
+	}
+
+	newDesc, err := UnsafeRecoverRange(eng, 1, []proto.StoreID{1, 3}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expReplicas := []proto.Replica{desc.Replicas[0], desc.Replicas[2]}
+	if !reflect.DeepEqual(newDesc.Replicas, expReplicas) {
+		t.Errorf("expected replicas %+v; got %+v", expReplicas, newDesc.Replicas)
+	}
+	readDesc := &proto.RangeDescriptor{}
+	if ok, err := engine.MVCCGetProto(eng, descKey, now, true, nil, readDesc); err != nil || !ok {
+		t.Fatalf("unable to read rewritten descriptor: %t, %v", ok, err)
+	}
+	if !reflect.DeepEqual(readDesc, newDesc) {
+		t.Errorf("expected descriptor %+v; got %+v", newDesc, readDesc)
+	}
+}
@@ -27,6 +27,9 @@ import (
 const (
 	// verifyQueueMaxSize is the max size of the verification queue.
 	verifyQueueMaxSize = 100
+	// verifyQueueMaxConcurrency is the max number of ranges processed at once
+	// by the verification queue.
+	verifyQueueMaxConcurrency = 1
 	// verificationInterval is the target duration for verifying on-disk
 	// checksums via full scan.
 	verificationInterval = 60 * 24 * time.Hour // 60 days
@@ -47,7 +50,7 @@ type verifyQueue struct {
 // newVerifyQueue returns a new instance of verifyQueue.
 func newVerifyQueue(stats storeStatsFn) *verifyQueue {
 	vq := &verifyQueue{stats: stats}
-	vq.baseQueue = newBaseQueue("verify", vq, verifyQueueMaxSize)
+	vq.baseQueue = newBaseQueue("verify", vq, verifyQueueMaxSize, verifyQueueMaxConcurrency)
 	return vq
 }
 
@@ -34,9 +34,11 @@ func NewDB(kv *client.KV) *DB {
 	}
 }
 
-// storeData attempts to store the supplied time series data on the server.
-// Data will be sampled at the supplied resolution.
-func (db *DB) storeData(r Resolution, data proto.TimeSeriesData) error {
+// StoreData stores the supplied time series data, downsampled to the
+// sample duration of the supplied resolution. Samples are merged into
+// any existing samples for the same series, source and time, so that
+// data may be stored in any order and by any number of writers.
+func (db *DB) StoreData(r Resolution, data proto.TimeSeriesData) error {
 	internalData, err := data.ToInternal(r.KeyDuration(), r.SampleDuration())
 	if err != nil {
 		return err
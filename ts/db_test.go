@@ -109,7 +109,9 @@ func (tm *testModel) Start() {
 
 	// Initialize and bootstrap a store which uses the engine.
 	sender := kv.NewLocalSender()
-	tm.store = storage.NewStore(tm.clock, tm.engine, client.NewKV(nil, sender), gossip, tm.transport, storage.TestStoreConfig)
+	storeDB := client.NewKV(nil, sender)
+	storeDB.User = storage.UserRoot
+	tm.store = storage.NewStore(tm.clock, tm.engine, storeDB, gossip, tm.transport, storage.TestStoreConfig)
 	if err := tm.store.Bootstrap(proto.StoreIdent{NodeID: 1, StoreID: 1}, tm.stopper); err != nil {
 		tm.t.Fatal(err)
 	}
@@ -124,7 +126,9 @@ func (tm *testModel) Start() {
 	tm.initConfigs()
 
 	// Initialize the DB instance.
-	tm.db = NewDB(client.NewKV(nil, sender))
+	db := client.NewKV(nil, sender)
+	db.User = storage.UserRoot
+	tm.db = NewDB(db)
 }
 
 // Stop stops the system under test.
@@ -223,7 +227,7 @@ func (tm *testModel) assertKeyCount(expected int) {
 // in both the model and the system under test.
 func (tm *testModel) storeTimeSeriesData(r Resolution, data proto.TimeSeriesData) {
 	// Store data in the system under test.
-	if err := tm.db.storeData(r, data); err != nil {
+	if err := tm.db.StoreData(r, data); err != nil {
 		tm.t.Fatalf("error storing time series data: %s", err.Error())
 	}
 
@@ -331,3 +335,78 @@ func TestStoreTimeSeries(t *testing.T) {
 	tm.assertKeyCount(5)
 	tm.assertModelCorrect()
 }
+
+// TestQueryTimeSeries verifies that queries downsample the stored data
+// of the queried sources into intervals and aggregate the measurements
+// in each interval.
+func TestQueryTimeSeries(t *testing.T) {
+	tm := newTestModel(t)
+	tm.Start()
+	defer tm.Stop()
+
+	const (
+		t0     = int64(1428710400000000000) // Aligned to the hour
+		second = int64(1000000000)
+		hour   = 3600 * second
+	)
+	tm.storeTimeSeriesData(Resolution10s, proto.TimeSeriesData{
+		Name:   "test.query",
+		Source: "a",
+		Datapoints: []*proto.TimeSeriesDatapoint{
+			intDatapoint(t0, 10),
+			intDatapoint(t0+5*second, 20),
+			intDatapoint(t0+10*second, 30),
+			intDatapoint(t0+hour, 40),
+		},
+	})
+	tm.storeTimeSeriesData(Resolution10s, proto.TimeSeriesData{
+		Name:   "test.query",
+		Source: "b",
+		Datapoints: []*proto.TimeSeriesDatapoint{
+			intDatapoint(t0, 100),
+		},
+	})
+	tm.assertModelCorrect()
+
+	testCases := []struct {
+		query      Query
+		start, end int64
+		expected   []*proto.TimeSeriesDatapoint
+	}{
+		// Samples from all sources are averaged.
+		{Query{Name: "test.query"}, t0, t0 + 2*hour, []*proto.TimeSeriesDatapoint{
+			floatDatapoint(t0, 130.0/3),
+			floatDatapoint(t0+10*second, 30),
+			floatDatapoint(t0+hour, 40),
+		}},
+		// Samples are downsampled into minutes from a single source.
+		{Query{Name: "test.query", Sources: []string{"a"}, Aggregator: AggregatorMax, Interval: 60 * second},
+			t0, t0 + 2*hour, []*proto.TimeSeriesDatapoint{
+				floatDatapoint(t0, 30),
+				floatDatapoint(t0+hour, 40),
+			}},
+		// Samples outside of the queried time range are omitted.
+		{Query{Name: "test.query", Aggregator: AggregatorSum}, t0 + 10*second, t0 + hour,
+			[]*proto.TimeSeriesDatapoint{
+				floatDatapoint(t0+10*second, 30),
+			}},
+		{Query{Name: "test.query", Aggregator: AggregatorMin}, t0, t0 + 10*second,
+			[]*proto.TimeSeriesDatapoint{
+				floatDatapoint(t0, 10),
+			}},
+		{Query{Name: "test.other"}, t0, t0 + 2*hour, []*proto.TimeSeriesDatapoint{}},
+	}
+	for i, test := range testCases {
+		datapoints, err := tm.db.Query(test.query, Resolution10s, test.start, test.end)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if !reflect.DeepEqual(datapoints, test.expected) {
+			t.Errorf("%d: expected %v; got %v", i, test.expected, datapoints)
+		}
+	}
+
+	if _, err := tm.db.Query(Query{Name: "test.query", Interval: 15 * second}, Resolution10s, t0, t0+hour); err == nil {
+		t.Error("expected query with interval not a multiple of the sample duration to fail")
+	}
+}
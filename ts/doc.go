@@ -132,6 +132,14 @@ keys will appear in the data store in the order shown above.
 (Note that the keys will NOT be exactly as pictured above; they will be encoded
 in a way that is more efficient, but is not readily human readable.)
 
+Queries
+
+Data is stored with DB.StoreData and read with DB.Query, which scans the keys
+of the queried series over a span of time at a single resolution. The samples
+of all queried sources are downsampled into intervals of the requested length
+(a multiple of the sample duration) and the measurements in each interval are
+aggregated into a single datapoint, e.g. their average or maximum.
+
 TODO(mrtracy):
 The ts package is a work in progress, and will initially only service queries
 for Cockroach's own internally generated time series data.
@@ -0,0 +1,201 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package ts
+
+import (
+	"math"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// An Aggregator combines the measurements within an interval of a
+// query into a single datapoint.
+type Aggregator int
+
+const (
+	// AggregatorAvg returns the average of the measurements.
+	AggregatorAvg Aggregator = iota
+	// AggregatorSum returns the sum of the measurements.
+	AggregatorSum
+	// AggregatorMax returns the largest measurement.
+	AggregatorMax
+	// AggregatorMin returns the smallest measurement.
+	AggregatorMin
+)
+
+// A Query selects the data of a series to be returned by DB.Query.
+type Query struct {
+	Name string
+	// Sources restricts the query to data gathered from the listed
+	// sources. Data from all sources is queried if empty.
+	Sources []string
+	// Aggregator combines the measurements from all queried sources
+	// within each interval.
+	Aggregator Aggregator
+	// Interval is the duration in nanoseconds of the intervals into
+	// which the data is downsampled; it must be a multiple of the
+	// sample duration of the queried resolution. Zero selects the
+	// sample duration.
+	Interval int64
+}
+
+// interval accumulates the measurements within an interval of a
+// query.
+type interval struct {
+	count    uint32
+	sum      float64
+	max, min float64
+}
+
+// add adds the measurements of a sample to the interval.
+func (i *interval) add(s *proto.InternalTimeSeriesSample) {
+	if s.IntCount > 0 {
+		sum := float64(s.GetIntSum())
+		max, min := sum, sum
+		if s.IntMax != nil {
+			max, min = float64(s.GetIntMax()), float64(s.GetIntMin())
+		}
+		i.addStats(s.IntCount, sum, max, min)
+	}
+	if s.FloatCount > 0 {
+		sum := float64(s.GetFloatSum())
+		max, min := sum, sum
+		if s.FloatMax != nil {
+			max, min = float64(s.GetFloatMax()), float64(s.GetFloatMin())
+		}
+		i.addStats(s.FloatCount, sum, max, min)
+	}
+}
+
+func (i *interval) addStats(count uint32, sum, max, min float64) {
+	if i.count == 0 {
+		i.max, i.min = max, min
+	} else {
+		i.max, i.min = math.Max(i.max, max), math.Min(i.min, min)
+	}
+	i.count += count
+	i.sum += sum
+}
+
+// value returns the aggregate of the interval's measurements.
+func (i *interval) value(agg Aggregator) float64 {
+	switch agg {
+	case AggregatorSum:
+		return i.sum
+	case AggregatorMax:
+		return i.max
+	case AggregatorMin:
+		return i.min
+	}
+	return i.sum / float64(i.count)
+}
+
+// Query returns the data of the queried series which was stored at
+// resolution r and was measured at or after startNanos and before
+// endNanos. The data is downsampled into intervals of the query's
+// duration, each of which is returned as a single datapoint holding
+// the aggregate of the measurements in the interval from all queried
+// sources. Datapoints are timestamped with the start of their
+// interval and returned in order; intervals without measurements are
+// omitted.
+func (db *DB) Query(q Query, r Resolution, startNanos, endNanos int64) ([]*proto.TimeSeriesDatapoint, error) {
+	sampleDuration := r.SampleDuration()
+	if q.Interval == 0 {
+		q.Interval = sampleDuration
+	}
+	if q.Interval < 0 || q.Interval%sampleDuration != 0 {
+		return nil, util.Errorf("query interval %d isn't a multiple of sample duration %d", q.Interval, sampleDuration)
+	}
+	if endNanos <= startNanos {
+		return nil, nil
+	}
+	var sources map[string]struct{}
+	if len(q.Sources) > 0 {
+		sources = make(map[string]struct{}, len(q.Sources))
+		for _, source := range q.Sources {
+			sources[source] = struct{}{}
+		}
+	}
+
+	// Scan the keys of all time slots spanned by the query. The data of
+	// all sources in a time slot is stored contiguously.
+	startKey := MakeDataKey(q.Name, "", r, startNanos)
+	endKey := MakeDataKey(q.Name, "", r, endNanos-1+r.KeyDuration())
+	reply := &proto.ScanResponse{}
+	if err := db.kv.Call(proto.Scan, proto.ScanArgs(startKey, endKey, 0), reply); err != nil {
+		return nil, err
+	}
+
+	intervals := map[int64]*interval{}
+	for _, row := range reply.Rows {
+		if sources != nil {
+			if _, source, _, _ := DecodeDataKey(row.Key); !containsSource(sources, source) {
+				continue
+			}
+		}
+		data, err := proto.InternalTimeSeriesDataFromValue(&row.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range data.Samples {
+			ts := data.StartTimestampNanos + int64(sample.Offset)*data.SampleDurationNanos
+			if ts < startNanos || ts >= endNanos {
+				continue
+			}
+			start := ts - ts%q.Interval
+			if ts%q.Interval < 0 {
+				start -= q.Interval
+			}
+			i, ok := intervals[start]
+			if !ok {
+				i = &interval{}
+				intervals[start] = i
+			}
+			i.add(sample)
+		}
+	}
+
+	starts := make([]int64, 0, len(intervals))
+	for start, i := range intervals {
+		if i.count > 0 {
+			starts = append(starts, start)
+		}
+	}
+	sort.Sort(int64Slice(starts))
+	datapoints := make([]*proto.TimeSeriesDatapoint, len(starts))
+	for j, start := range starts {
+		datapoints[j] = &proto.TimeSeriesDatapoint{
+			TimestampNanos: start,
+			FloatValue:     gogoproto.Float32(float32(intervals[start].value(q.Aggregator))),
+		}
+	}
+	return datapoints, nil
+}
+
+func containsSource(sources map[string]struct{}, source string) bool {
+	_, ok := sources[source]
+	return ok
+}
+
+// int64Slice implements sort.Interface.
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
@@ -399,6 +399,17 @@ func EncodeBytes(b []byte, data []byte) []byte {
 	return append(b, escape1, escapedTerm)
 }
 
+// EncodeBytesDecreasing encodes the []byte value so that it sorts in
+// reverse order, from largest to smallest. The value is encoded as by
+// EncodeBytes, and the encoding, which is never a prefix of another,
+// is inverted.
+func EncodeBytesDecreasing(b []byte, data []byte) []byte {
+	n := len(b)
+	b = EncodeBytes(b, data)
+	onesComplement(b, n, len(b))
+	return b
+}
+
 // DecodeBytes decodes a []byte value from the input buffer which was
 // encoded using EncodeBytes. The remainder of the input buffer and
 // the decoded []byte are returned.
@@ -445,3 +456,92 @@ func DecodeBytes(b []byte) ([]byte, []byte) {
 		b = b[i+2:]
 	}
 }
+
+// DecodeBytesDecreasing decodes a []byte value from the input buffer
+// which was encoded using EncodeBytesDecreasing. The remainder of the
+// input buffer and the decoded []byte are returned.
+func DecodeBytesDecreasing(b []byte) ([]byte, []byte) {
+	// Find the end of the inverted encoding: the inverted terminator,
+	// skipping a leading inverted escape of \xff and inverted escapes
+	// of \x00.
+	i := 0
+	if len(b) > 0 && b[0] == ^byte(escape2) {
+		i = 2
+	}
+	for {
+		j := bytes.IndexByte(b[i:], ^byte(escape1))
+		if j == -1 || i+j+1 >= len(b) {
+			panic("did not find terminator")
+		}
+		i += j + 2
+		if b[i-1] == ^byte(escapedTerm) {
+			break
+		}
+	}
+	enc := append([]byte(nil), b[:i]...)
+	onesComplement(enc, 0, len(enc))
+	_, r := DecodeBytes(enc)
+	return b[i:], r
+}
+
+const (
+	encodedNull              = 0x00
+	encodedNotNull           = 0x01
+	encodedNullDecreasing    = ^byte(encodedNull)
+	encodedNotNullDecreasing = ^byte(encodedNotNull)
+)
+
+// EncodeNull appends the marker of a NULL value, for use in composite
+// keys whose values may be NULL. It sorts before the marker appended
+// by EncodeNotNull, which precedes the encoding of each non-NULL
+// value.
+func EncodeNull(b []byte) []byte {
+	return append(b, encodedNull)
+}
+
+// EncodeNotNull appends the marker which precedes the encoding of a
+// non-NULL value.
+func EncodeNotNull(b []byte) []byte {
+	return append(b, encodedNotNull)
+}
+
+// DecodeIfNull decodes a marker appended by EncodeNull or
+// EncodeNotNull from the input buffer. The remainder of the input
+// buffer and whether the value is NULL are returned.
+func DecodeIfNull(b []byte) ([]byte, bool) {
+	return decodeNullMarker(b, encodedNull, encodedNotNull)
+}
+
+// EncodeNullDecreasing appends the marker of a NULL value in a value
+// sorted in reverse order, so that NULLs sort after the non-NULL
+// values.
+func EncodeNullDecreasing(b []byte) []byte {
+	return append(b, encodedNullDecreasing)
+}
+
+// EncodeNotNullDecreasing appends the marker which precedes the
+// encoding of a non-NULL value sorted in reverse order.
+func EncodeNotNullDecreasing(b []byte) []byte {
+	return append(b, encodedNotNullDecreasing)
+}
+
+// DecodeIfNullDecreasing decodes a marker appended by
+// EncodeNullDecreasing or EncodeNotNullDecreasing from the input
+// buffer. The remainder of the input buffer and whether the value is
+// NULL are returned.
+func DecodeIfNullDecreasing(b []byte) ([]byte, bool) {
+	return decodeNullMarker(b, encodedNullDecreasing, encodedNotNullDecreasing)
+}
+
+func decodeNullMarker(b []byte, null, notNull byte) ([]byte, bool) {
+	if len(b) == 0 {
+		panic("insufficient bytes to decode null marker")
+	}
+	switch b[0] {
+	case null:
+		return b[1:], true
+	case notNull:
+		return b[1:], false
+	}
+	panic(fmt.Sprintf("invalid null marker %#x", b[0]))
+}
@@ -403,6 +403,67 @@ func TestEncodeDecodeBytes(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeBytesDecreasing(t *testing.T) {
+	// The values are listed in decreasing order.
+	values := [][]byte{
+		{0xff, 0xff, 'b'},
+		{0xff, 'b'},
+		{0xff, 0x01},
+		{0xff, 0, 'a'},
+		[]byte("hello"),
+		{'b', 0xff},
+		{'b', 0, 0, 'a'},
+		{'b', 0},
+		{'b'},
+		{'a'},
+		{0, 0xff, 'a'},
+		{0, 1, 'a'},
+		{0},
+		{},
+	}
+	var last []byte
+	for i, v := range values {
+		enc := EncodeBytesDecreasing(nil, v)
+		if i > 0 && bytes.Compare(last, enc) >= 0 {
+			t.Errorf("%v: expected [% x] to be less than [% x]", v, last, enc)
+		}
+		last = enc
+		remainder, dec := DecodeBytesDecreasing(append(enc, []byte("remainder")...))
+		if !bytes.Equal(v, dec) {
+			t.Errorf("unexpected decoding mismatch for %v. got %v", v, dec)
+		}
+		if string(remainder) != "remainder" {
+			t.Errorf("unexpected remaining bytes: %v", remainder)
+		}
+	}
+}
+
+func TestEncodeDecodeNull(t *testing.T) {
+	testCases := []struct {
+		encode func([]byte) []byte
+		decode func([]byte) ([]byte, bool)
+		null   bool
+	}{
+		{EncodeNull, DecodeIfNull, true},
+		{EncodeNotNull, DecodeIfNull, false},
+		{EncodeNullDecreasing, DecodeIfNullDecreasing, true},
+		{EncodeNotNullDecreasing, DecodeIfNullDecreasing, false},
+	}
+	for i, c := range testCases {
+		remainder, null := c.decode(append(c.encode(nil), "remainder"...))
+		if null != c.null || string(remainder) != "remainder" {
+			t.Errorf("%d: expected null=%t; got %t with remainder %q", i, c.null, null, remainder)
+		}
+	}
+	// NULL sorts before non-NULL values, and after them in decreasing order.
+	if bytes.Compare(EncodeNull(nil), EncodeVarint(EncodeNotNull(nil), math.MinInt64)) >= 0 {
+		t.Error("expected NULL to sort before non-NULL values")
+	}
+	if bytes.Compare(EncodeNullDecreasing(nil), EncodeVarintDecreasing(EncodeNotNullDecreasing(nil), math.MinInt64)) <= 0 {
+		t.Error("expected NULL to sort after non-NULL values in decreasing order")
+	}
+}
+
 func TestDecodeInvalidBytes(t *testing.T) {
 	testCases := []struct {
 		value []byte
@@ -35,6 +35,7 @@ const (
 	orderedEncodingNegativeInfinity = 0x07
 	orderedEncodingZero             = 0x15
 	orderedEncodingInfinity         = 0x23
+	orderedEncodingNaNDecreasing    = 0x24
 	orderedEncodingTerminator       = 0x00
 )
 
@@ -206,6 +207,17 @@ func EncodeNumericFloat(b []byte, f float64) []byte {
 	return nil
 }
 
+// EncodeNumericFloatDecreasing returns the resulting byte slice with
+// the encoded float64 value in decreasing order appended to b. The
+// order is the exact reverse of the increasing order, so NaN, which
+// sorts first in the increasing order, sorts last.
+func EncodeNumericFloatDecreasing(b []byte, f float64) []byte {
+	if math.IsNaN(f) {
+		return append(b, orderedEncodingNaNDecreasing)
+	}
+	return EncodeNumericFloat(b, -f)
+}
+
 // DecodeNumericFloat returns the remaining byte slice after decoding and the decoded
 // float64 from buf.
 func DecodeNumericFloat(buf []byte) ([]byte, float64) {
@@ -249,6 +261,16 @@ func DecodeNumericFloat(buf []byte) ([]byte, float64) {
 	}
 }
 
+// DecodeNumericFloatDecreasing returns the remaining byte slice after
+// decoding and the decoded float64 in decreasing order from buf.
+func DecodeNumericFloatDecreasing(buf []byte) ([]byte, float64) {
+	if buf[0] == orderedEncodingNaNDecreasing {
+		return buf[1:], math.NaN()
+	}
+	b, f := DecodeNumericFloat(buf)
+	return b, -f
+}
+
 // floatMandE computes and returns the mantissa M and exponent E for f.
 //
 // The mantissa is a base-100 representation of the value. The exponent E
@@ -291,3 +291,23 @@ func BenchmarkDecodeNumericFloat(b *testing.B) {
 		_, _ = DecodeNumericFloat(vals[i%len(vals)])
 	}
 }
+
+func TestEncodeNumericFloatDecreasing(t *testing.T) {
+	values := []float64{
+		math.Inf(1), math.MaxFloat64, 1e308, 9999.5, 100, 1, 0.123,
+		math.SmallestNonzeroFloat64, 0, -0.00123, -1, -9999, -math.MaxFloat64,
+		math.Inf(-1), math.NaN(),
+	}
+	var last []byte
+	for i, v := range values {
+		enc := EncodeNumericFloatDecreasing(nil, v)
+		if i > 0 && bytes.Compare(last, enc) >= 0 {
+			t.Errorf("%v: expected [% x] to be less than [% x]", v, last, enc)
+		}
+		last = enc
+		rem, dec := DecodeNumericFloatDecreasing(append(enc, "remainder"...))
+		if (dec != v && !(math.IsNaN(dec) && math.IsNaN(v))) || string(rem) != "remainder" {
+			t.Errorf("%v: unexpected decoding %v with remainder %q", v, dec, rem)
+		}
+	}
+}
@@ -63,6 +63,7 @@ type Clock struct {
 // is manually controlled. ManualClock is thread safe.
 type ManualClock struct {
 	nanos int64
+	vc    *util.VirtualClock // If not nil, holds the timestamp instead
 }
 
 // NewManualClock returns a new instance, initialized with
@@ -71,18 +72,36 @@ func NewManualClock(nanos int64) *ManualClock {
 	return &ManualClock{nanos: nanos}
 }
 
+// NewVirtualManualClock returns a manual clock backed by the supplied
+// virtual clock. Setting or incrementing the manual clock advances
+// the virtual clock, firing any timers and tickers which come due.
+func NewVirtualManualClock(vc *util.VirtualClock) *ManualClock {
+	return &ManualClock{vc: vc}
+}
+
 // UnixNano returns the underlying manual clock's timestamp.
 func (m *ManualClock) UnixNano() int64 {
+	if m.vc != nil {
+		return m.vc.UnixNano()
+	}
 	return atomic.LoadInt64(&m.nanos)
 }
 
 // Increment atomically increments the manual clock's timestamp.
 func (m *ManualClock) Increment(incr int64) {
+	if m.vc != nil {
+		m.vc.Advance(time.Duration(incr))
+		return
+	}
 	atomic.AddInt64(&m.nanos, incr)
 }
 
 // Set atomically sets the manual clock's timestamp.
 func (m *ManualClock) Set(nanos int64) {
+	if m.vc != nil {
+		m.vc.Set(nanos)
+		return
+	}
 	atomic.StoreInt64(&m.nanos, nanos)
 }
 
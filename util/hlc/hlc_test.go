@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
@@ -202,3 +203,25 @@ func ExampleManualClock() {
 		log.Fatalf("manual clock error")
 	}
 }
+
+// TestVirtualManualClock verifies that a manual clock backed by a
+// virtual clock advances the virtual time, firing its timers.
+func TestVirtualManualClock(t *testing.T) {
+	vc := util.NewVirtualClock(0)
+	m := NewVirtualManualClock(vc)
+	ch := vc.After(10)
+	m.Increment(5)
+	if vc.UnixNano() != 5 || m.UnixNano() != 5 {
+		t.Errorf("expected time 5; got %d, %d", vc.UnixNano(), m.UnixNano())
+	}
+	m.Set(10)
+	select {
+	case <-ch:
+	default:
+		t.Error("expected timer to fire")
+	}
+	vc.Advance(5)
+	if m.UnixNano() != 15 {
+		t.Errorf("expected time 15; got %d", m.UnixNano())
+	}
+}
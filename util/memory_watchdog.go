@@ -0,0 +1,194 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// DefaultMemoryWatchdog is the process-wide memory watchdog. It is
+// disabled until a budget is set.
+var DefaultMemoryWatchdog = NewMemoryWatchdog(0)
+
+// A MemoryShedError indicates that an operation was cancelled by the
+// memory watchdog to keep the heap within its budget. The operation
+// may be retried.
+type MemoryShedError struct {
+	Desc  fmt.Stringer // Description of the shed operation
+	Bytes int64        // Bytes held by the operation when shed
+}
+
+// Error formats error.
+func (e *MemoryShedError) Error() string {
+	return fmt.Sprintf("%s shed by memory watchdog after accumulating %d bytes", e.Desc, e.Bytes)
+}
+
+// CanRetry implements the Retryable interface.
+func (e *MemoryShedError) CanRetry() bool {
+	return true
+}
+
+// A MemoryReservation tracks the memory accumulated by an in-flight
+// operation, such as a scan or snapshot, which the watchdog may shed.
+type MemoryReservation struct {
+	desc  fmt.Stringer
+	bytes int64           // Updated atomically
+	shed  int32           // Non-zero once shed; updated atomically
+	w     *MemoryWatchdog // Nil if not registered with the watchdog
+}
+
+// Grow adds n bytes to the reservation. Returns a MemoryShedError if
+// the watchdog has shed the operation, in which case the caller must
+// abandon it and release any memory it holds.
+func (r *MemoryReservation) Grow(n int64) error {
+	bytes := atomic.AddInt64(&r.bytes, n)
+	if atomic.LoadInt32(&r.shed) != 0 {
+		return &MemoryShedError{Desc: r.desc, Bytes: bytes}
+	}
+	return nil
+}
+
+// Release unregisters the reservation from the watchdog.
+func (r *MemoryReservation) Release() {
+	if r.w == nil {
+		return
+	}
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	delete(r.w.reservations, r)
+}
+
+// A MemoryWatchdog monitors the size of the Go heap against a budget.
+// When the budget is exceeded, the largest in-flight reservations are
+// shed until their combined size covers the excess, so that memory is
+// returned before the operating system runs out of it.
+type MemoryWatchdog struct {
+	budget   int64        // Heap budget in bytes; zero disables; updated atomically
+	heapSize func() int64 // Returns the current heap size
+
+	mu           sync.Mutex
+	reservations map[*MemoryReservation]struct{}
+}
+
+// NewMemoryWatchdog returns a watchdog with the specified heap budget
+// in bytes. A budget of zero disables shedding.
+func NewMemoryWatchdog(budget int64) *MemoryWatchdog {
+	return &MemoryWatchdog{
+		budget:       budget,
+		heapSize:     heapAlloc,
+		reservations: map[*MemoryReservation]struct{}{},
+	}
+}
+
+// heapAlloc returns the number of bytes of allocated heap objects.
+func heapAlloc() int64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapAlloc)
+}
+
+// SetBudget sets the heap budget in bytes. A budget of zero disables
+// shedding.
+func (w *MemoryWatchdog) SetBudget(budget int64) {
+	atomic.StoreInt64(&w.budget, budget)
+}
+
+// Reserve registers a new in-flight operation with the watchdog. The
+// description is only formatted if the operation is shed, so callers
+// on hot paths need not build a string up front. The returned
+// reservation must be released on completion. While the watchdog is
+// disabled, reservations aren't registered, sparing operations the
+// watchdog's lock; they're never shed, even once a budget is set.
+func (w *MemoryWatchdog) Reserve(desc fmt.Stringer) *MemoryReservation {
+	r := &MemoryReservation{desc: desc}
+	if atomic.LoadInt64(&w.budget) <= 0 {
+		return r
+	}
+	r.w = w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reservations[r] = struct{}{}
+	return r
+}
+
+// Start runs a worker which checks the heap size at the specified
+// interval until the stopper is stopped.
+func (w *MemoryWatchdog) Start(interval time.Duration, stopper *Stopper) {
+	stopper.RunWorker(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// check sheds the largest reservations if the heap exceeds the budget.
+func (w *MemoryWatchdog) check() {
+	budget := atomic.LoadInt64(&w.budget)
+	if budget <= 0 {
+		return
+	}
+	heap := w.heapSize()
+	excess := heap - budget
+	if excess <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var candidates memoryReservations
+	for r := range w.reservations {
+		if atomic.LoadInt32(&r.shed) == 0 {
+			candidates = append(candidates, r)
+		}
+	}
+	sort.Sort(candidates)
+	for _, r := range candidates {
+		if excess <= 0 {
+			break
+		}
+		bytes := atomic.LoadInt64(&r.bytes)
+		atomic.StoreInt32(&r.shed, 1)
+		excess -= bytes
+		log.Warningf("heap size %d exceeds budget %d; shedding %s holding %d bytes",
+			heap, budget, r.desc, bytes)
+	}
+	if excess > 0 {
+		log.Warningf("heap size %d exceeds budget %d; no further operations to shed", heap, budget)
+	}
+}
+
+// memoryReservations sorts reservations by decreasing size.
+type memoryReservations []*MemoryReservation
+
+func (mr memoryReservations) Len() int      { return len(mr) }
+func (mr memoryReservations) Swap(i, j int) { mr[i], mr[j] = mr[j], mr[i] }
+func (mr memoryReservations) Less(i, j int) bool {
+	return atomic.LoadInt64(&mr[i].bytes) > atomic.LoadInt64(&mr[j].bytes)
+}
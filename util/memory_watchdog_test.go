@@ -0,0 +1,94 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import "testing"
+
+// reservationDesc is a static reservation description.
+type reservationDesc string
+
+func (d reservationDesc) String() string { return string(d) }
+
+// TestMemoryWatchdogShedsLargest verifies that the watchdog sheds the
+// largest reservations until the excess heap is covered.
+func TestMemoryWatchdogShedsLargest(t *testing.T) {
+	heap := int64(100)
+	w := NewMemoryWatchdog(150)
+	w.heapSize = func() int64 { return heap }
+
+	small, medium, large := w.Reserve(reservationDesc("small")), w.Reserve(reservationDesc("medium")), w.Reserve(reservationDesc("large"))
+	defer small.Release()
+	defer medium.Release()
+	defer large.Release()
+	for r, n := range map[*MemoryReservation]int64{small: 10, medium: 30, large: 50} {
+		if err := r.Grow(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Within budget; nothing is shed.
+	w.check()
+	for _, r := range []*MemoryReservation{small, medium, large} {
+		if err := r.Grow(0); err != nil {
+			t.Errorf("unexpected shed of %s: %s", r.desc, err)
+		}
+	}
+
+	// 60 bytes over budget requires shedding the two largest.
+	heap = 210
+	w.check()
+	if err := small.Grow(1); err != nil {
+		t.Errorf("unexpected shed of small reservation: %s", err)
+	}
+	for _, r := range []*MemoryReservation{medium, large} {
+		err := r.Grow(1)
+		if _, ok := err.(*MemoryShedError); !ok {
+			t.Errorf("expected %s to be shed; got %v", r.desc, err)
+		} else if !err.(Retryable).CanRetry() {
+			t.Errorf("expected shed error to be retryable")
+		}
+	}
+}
+
+// TestMemoryWatchdogDisabled verifies that a zero budget disables
+// shedding, that reservations made while disabled aren't tracked and
+// that released reservations are no longer tracked.
+func TestMemoryWatchdogDisabled(t *testing.T) {
+	w := NewMemoryWatchdog(0)
+	w.heapSize = func() int64 { return 1 << 40 }
+	r := w.Reserve(reservationDesc("scan"))
+	if len(w.reservations) != 0 {
+		t.Errorf("expected reservation not to be tracked while disabled; got %d", len(w.reservations))
+	}
+	if err := r.Grow(1 << 30); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	if err := r.Grow(0); err != nil {
+		t.Errorf("unexpected shed with disabled watchdog: %s", err)
+	}
+	r.Release()
+
+	w.SetBudget(1)
+	r = w.Reserve(reservationDesc("scan"))
+	if len(w.reservations) != 1 {
+		t.Errorf("expected reservation to be tracked; got %d", len(w.reservations))
+	}
+	r.Release()
+	if len(w.reservations) != 0 {
+		t.Errorf("expected no reservations after release; got %d", len(w.reservations))
+	}
+}
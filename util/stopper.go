@@ -28,31 +28,43 @@ type Closer interface {
 	Close()
 }
 
+// Drainer is an interface for objects to attach to the stopper to
+// be drained before the stopper refuses tasks and stops its workers.
+type Drainer interface {
+	Drain()
+}
+
 // A Stopper provides a channel-based mechanism to stop an arbitrary
 // array of workers. Each worker is registered with the stopper via
 // the AddWorker() method. The system further tracks each task which
 // is outstanding by calling StartTask() when a task is started and
 // FinishTask() when completed.
 //
-// Stopping occurs in two phases: the first is the request to stop,
-// which moves the stopper into a draining phase. While draining,
-// calls to StartTask() return false, meaning the system is draining
-// and new tasks should not be accepted. When all outstanding tasks
-// have been completed via calls to FinishTask(), the stopper closes
-// its stopper channel, which signals all live workers that it's safe
-// to shut down. Once shutdown, each worker invokes SetStopped(). When
-// all workers have shutdown, the stopper is complete.
+// Stopping occurs in three phases. First, the objects implementing
+// the Drainer interface which were added via AddDrainer() are
+// drained, in the order they were added, while the system is still
+// fully running; this allows them to hand off their work, e.g. to
+// other nodes. Next, the stopper moves into a draining phase. While
+// draining, calls to StartTask() return false, meaning the system is
+// draining and new tasks should not be accepted. When all outstanding
+// tasks have been completed via calls to FinishTask(), the stopper
+// closes its stopper channel, which signals all live workers that
+// it's safe to shut down. Once shutdown, each worker invokes
+// SetStopped(). When all workers have shutdown, the stopper is
+// complete.
 //
 // An arbitrary list of objects implementing the Closer interface may
 // be added to the stopper via AddCloser(), to be closed after the
-// stopper has stopped.
+// stopper has stopped, in the order they were added.
 type Stopper struct {
 	stopper  chan struct{}  // Closed when stopping
 	stopped  chan struct{}  // Closed when stopped completely
+	drained  chan struct{}  // Closed when tasks start being refused
 	draining int32          // Uses atomic operations instead of mu.
 	drain    sync.WaitGroup // Incremented for outstanding tasks
 	stop     sync.WaitGroup // Incremented for outstanding workers
-	mu       sync.Mutex     // Protects the slice of Closers
+	mu       sync.Mutex     // Protects the slices of Drainers and Closers
+	drainers []Drainer
 	closers  []Closer
 }
 
@@ -61,6 +73,7 @@ func NewStopper() *Stopper {
 	return &Stopper{
 		stopper: make(chan struct{}),
 		stopped: make(chan struct{}),
+		drained: make(chan struct{}),
 	}
 }
 
@@ -79,6 +92,14 @@ func (s *Stopper) AddWorker() {
 	s.stop.Add(1)
 }
 
+// AddDrainer adds an object to drain when the stopper is asked to
+// stop, before tasks are refused and workers are stopped.
+func (s *Stopper) AddDrainer(d Drainer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainers = append(s.drainers, d)
+}
+
 // AddCloser adds an object to close after the stopper has been stopped.
 func (s *Stopper) AddCloser(c Closer) {
 	s.mu.Lock()
@@ -110,10 +131,18 @@ func (s *Stopper) FinishTask() {
 	s.drain.Done()
 }
 
-// Stop signals all live workers to stop and then waits for each to
-// confirm it has stopped (workers do this by calling SetStopped()).
+// Stop drains the drainers, signals all live workers to stop and then
+// waits for each to confirm it has stopped (workers do this by calling
+// SetStopped()). Finally, the closers are closed.
 func (s *Stopper) Stop() {
+	s.mu.Lock()
+	drainers := append([]Drainer(nil), s.drainers...)
+	s.mu.Unlock()
+	for _, d := range drainers {
+		d.Drain()
+	}
 	atomic.StoreInt32(&s.draining, 1)
+	close(s.drained)
 	s.drain.Wait()
 	close(s.stopper)
 	s.stop.Wait()
@@ -135,6 +164,18 @@ func (s *Stopper) ShouldStop() <-chan struct{} {
 	return s.stopper
 }
 
+// ShouldDrain returns a channel which will be closed once Stop() has
+// begun refusing new tasks. Outstanding tasks which wait on work that
+// may never complete, e.g. commands awaiting Raft, should give up
+// waiting when it's closed, as the stopper waits for them to finish
+// before stopping its workers.
+func (s *Stopper) ShouldDrain() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	return s.drained
+}
+
 // IsStopped returns a channel which will be closed after Stop() has
 // been invoked to full completion, meaning all workers have completed
 // and all closers have been closed.
@@ -126,6 +126,12 @@ func TestStopperStartFinishTasks(t *testing.T) {
 	}
 	go s.Stop()
 
+	select {
+	case <-s.ShouldDrain():
+		// Expected.
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("expected stopper to signal draining")
+	}
 	select {
 	case <-s.ShouldStop():
 		t.Fatal("expected stopper to be draining")
@@ -179,3 +185,37 @@ func TestStopperClosers(t *testing.T) {
 		t.Errorf("expected true & true; got %t & %t", tc1, tc2)
 	}
 }
+
+type testDrainer struct {
+	s       *Stopper
+	drained bool
+}
+
+func (td *testDrainer) Drain() {
+	td.drained = true
+	// The system must still be running while drainers are drained.
+	if !td.s.StartTask() {
+		panic("expected stopper to accept tasks while draining drainers")
+	}
+	td.s.FinishTask()
+	select {
+	case <-td.s.ShouldStop():
+		panic("expected workers to run while draining drainers")
+	default:
+	}
+}
+
+func TestStopperDrainers(t *testing.T) {
+	s := NewStopper()
+	td := &testDrainer{s: s}
+	s.AddDrainer(td)
+	var tc testCloser
+	s.AddCloser(&tc)
+	s.Stop()
+	if !td.drained || !bool(tc) {
+		t.Errorf("expected drained & closed; got %t & %t", td.drained, tc)
+	}
+	if s.StartTask() {
+		t.Error("expected StartTask to fail after stop")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Ticker holds a channel which delivers ticks at intervals, like
+// time.Ticker, but may be driven by a VirtualClock.
+type Ticker struct {
+	C    <-chan time.Time // The channel on which ticks are delivered
+	stop func()
+}
+
+// Stop turns off the ticker. No more ticks will be sent.
+func (t *Ticker) Stop() {
+	t.stop()
+}
+
+// A Timer holds a channel which delivers a single time, like
+// time.Timer, but may be driven by a VirtualClock.
+type Timer struct {
+	C    <-chan time.Time // The channel on which the time is delivered
+	stop func()
+}
+
+// Stop prevents the timer from firing if it hasn't already, releasing
+// its resources.
+func (t *Timer) Stop() {
+	t.stop()
+}
+
+// Now returns the current time; it's the virtual time if a virtual
+// clock is installed.
+func Now() time.Time {
+	if vc := getVirtualClock(); vc != nil {
+		return vc.Now()
+	}
+	return time.Now()
+}
+
+// NewTicker returns a new Ticker delivering ticks at the specified
+// interval, driven by the virtual clock if one is installed.
+func NewTicker(d time.Duration) *Ticker {
+	if vc := getVirtualClock(); vc != nil {
+		return vc.NewTicker(d)
+	}
+	t := time.NewTicker(d)
+	return &Ticker{C: t.C, stop: t.Stop}
+}
+
+// NewTimer returns a new Timer which sends the current time on its
+// channel once the duration has elapsed, driven by the virtual clock
+// if one is installed.
+func NewTimer(d time.Duration) *Timer {
+	if vc := getVirtualClock(); vc != nil {
+		return vc.NewTimer(d)
+	}
+	t := time.NewTimer(d)
+	return &Timer{C: t.C, stop: func() { t.Stop() }}
+}
+
+// After waits for the duration to elapse and then sends the current
+// time on the returned channel, driven by the virtual clock if one is
+// installed. As with time.After, the timer isn't released until it
+// fires; callers which may abandon the channel before then should use
+// NewTimer and stop it.
+func After(d time.Duration) <-chan time.Time {
+	if vc := getVirtualClock(); vc != nil {
+		return vc.After(d)
+	}
+	return time.After(d)
+}
+
+// A VirtualClock is a manually advanced clock which drives tickers
+// and timers. Long-running workers create their timers with the
+// package-level NewTicker and After functions, which are driven by
+// a VirtualClock installed with SetVirtualClock in builds with the
+// virtualclock tag. Tests may then run hours of timing-dependent
+// behavior in milliseconds, deterministically.
+//
+// As with the time package, ticks which aren't received before the
+// next tick is due are dropped, so advancing the clock by many
+// intervals delivers a single tick.
+type VirtualClock struct {
+	mu     sync.Mutex
+	nanos  int64
+	timers []*virtualTimer
+}
+
+// virtualTimer is a one-shot timer or, if period is non-zero, a
+// ticker scheduled on a VirtualClock.
+type virtualTimer struct {
+	deadline int64
+	period   int64
+	ch       chan time.Time
+}
+
+// NewVirtualClock returns a new virtual clock set to the specified
+// unix epoch nanoseconds.
+func NewVirtualClock(nanos int64) *VirtualClock {
+	return &VirtualClock{nanos: nanos}
+}
+
+// UnixNano returns the virtual time in unix epoch nanoseconds. It may
+// be supplied as the physical clock of a hybrid logical clock.
+func (vc *VirtualClock) UnixNano() int64 {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.nanos
+}
+
+// Now returns the virtual time.
+func (vc *VirtualClock) Now() time.Time {
+	return time.Unix(0, vc.UnixNano())
+}
+
+// Advance moves the virtual time forward by d, firing timers and
+// tickers which come due in order of their deadlines.
+func (vc *VirtualClock) Advance(d time.Duration) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.setLocked(vc.nanos + d.Nanoseconds())
+}
+
+// Set sets the virtual time. Timers and tickers which come due are
+// fired in order of their deadlines; setting the time backwards fires
+// nothing.
+func (vc *VirtualClock) Set(nanos int64) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.setLocked(nanos)
+}
+
+func (vc *VirtualClock) setLocked(nanos int64) {
+	if nanos <= vc.nanos {
+		vc.nanos = nanos
+		return
+	}
+	sort.Sort(virtualTimersByDeadline(vc.timers))
+	var remaining []*virtualTimer
+	for _, t := range vc.timers {
+		if t.deadline > nanos {
+			remaining = append(remaining, t)
+			continue
+		}
+		// Deliver the tick unless the previous one is still pending.
+		select {
+		case t.ch <- time.Unix(0, t.deadline):
+		default:
+		}
+		if t.period > 0 {
+			t.deadline += ((nanos-t.deadline)/t.period + 1) * t.period
+			remaining = append(remaining, t)
+		}
+	}
+	vc.timers = remaining
+	vc.nanos = nanos
+}
+
+// NewTicker returns a ticker delivering ticks every d of virtual time.
+func (vc *VirtualClock) NewTicker(d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := vc.schedule(d, d)
+	return &Ticker{C: t.ch, stop: func() { vc.unschedule(t) }}
+}
+
+// NewTimer returns a timer which sends the virtual time on its channel
+// once d of virtual time has elapsed.
+func (vc *VirtualClock) NewTimer(d time.Duration) *Timer {
+	t := vc.schedule(d, 0)
+	return &Timer{C: t.ch, stop: func() { vc.unschedule(t) }}
+}
+
+// After returns a channel on which the virtual time is sent once d of
+// virtual time has elapsed.
+func (vc *VirtualClock) After(d time.Duration) <-chan time.Time {
+	return vc.schedule(d, 0).ch
+}
+
+// pending returns the number of timers and tickers yet to fire.
+func (vc *VirtualClock) pending() int {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return len(vc.timers)
+}
+
+func (vc *VirtualClock) schedule(d, period time.Duration) *virtualTimer {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	t := &virtualTimer{
+		deadline: vc.nanos + d.Nanoseconds(),
+		period:   period.Nanoseconds(),
+		ch:       make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.ch <- time.Unix(0, vc.nanos)
+		return t
+	}
+	vc.timers = append(vc.timers, t)
+	return t
+}
+
+func (vc *VirtualClock) unschedule(t *virtualTimer) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	for i, o := range vc.timers {
+		if o == t {
+			vc.timers = append(vc.timers[:i], vc.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// virtualTimersByDeadline implements sort.Interface.
+type virtualTimersByDeadline []*virtualTimer
+
+func (vt virtualTimersByDeadline) Len() int           { return len(vt) }
+func (vt virtualTimersByDeadline) Swap(i, j int)      { vt[i], vt[j] = vt[j], vt[i] }
+func (vt virtualTimersByDeadline) Less(i, j int) bool { return vt[i].deadline < vt[j].deadline }
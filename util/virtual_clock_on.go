@@ -0,0 +1,44 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+// +build virtualclock
+
+package util
+
+import "sync"
+
+// VirtualClockEnabled is true in builds with the virtualclock tag,
+// which allow a VirtualClock to be installed.
+const VirtualClockEnabled = true
+
+var virtualClock struct {
+	sync.Mutex
+	vc *VirtualClock
+}
+
+// SetVirtualClock installs a virtual clock driving the package-level
+// timing functions. Supplying nil restores real time. Timers created
+// before the call are unaffected.
+func SetVirtualClock(vc *VirtualClock) {
+	virtualClock.Lock()
+	defer virtualClock.Unlock()
+	virtualClock.vc = vc
+}
+
+func getVirtualClock() *VirtualClock {
+	virtualClock.Lock()
+	defer virtualClock.Unlock()
+	return virtualClock.vc
+}
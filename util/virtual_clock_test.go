@@ -0,0 +1,135 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// expectTick verifies whether a value is pending on the channel.
+func expectTick(t *testing.T, ch <-chan time.Time, expected bool) {
+	select {
+	case <-ch:
+		if !expected {
+			t.Error("unexpected tick")
+		}
+	default:
+		if expected {
+			t.Error("expected tick")
+		}
+	}
+}
+
+// TestVirtualClockAfter verifies that timers fire only once the
+// virtual time reaches their deadline.
+func TestVirtualClockAfter(t *testing.T) {
+	vc := NewVirtualClock(0)
+	ch := vc.After(10 * time.Second)
+	vc.Advance(9 * time.Second)
+	expectTick(t, ch, false)
+	vc.Advance(time.Second)
+	select {
+	case now := <-ch:
+		if now.UnixNano() != (10 * time.Second).Nanoseconds() {
+			t.Errorf("expected tick at 10s; got %s", now)
+		}
+	default:
+		t.Error("expected timer to fire")
+	}
+	vc.Advance(time.Hour)
+	expectTick(t, ch, false)
+
+	// Non-positive durations fire immediately.
+	expectTick(t, vc.After(0), true)
+}
+
+// TestVirtualClockTimerStop verifies that fired and stopped timers are
+// removed from the clock.
+func TestVirtualClockTimerStop(t *testing.T) {
+	vc := NewVirtualClock(0)
+	fired := vc.NewTimer(time.Second)
+	stopped := vc.NewTimer(time.Second)
+	if n := vc.pending(); n != 2 {
+		t.Fatalf("expected 2 pending timers; got %d", n)
+	}
+	stopped.Stop()
+	if n := vc.pending(); n != 1 {
+		t.Errorf("expected stopped timer to be removed; %d pending", n)
+	}
+	vc.Advance(time.Second)
+	expectTick(t, fired.C, true)
+	expectTick(t, stopped.C, false)
+	if n := vc.pending(); n != 0 {
+		t.Errorf("expected fired timer to be removed; %d pending", n)
+	}
+	// Stopping a fired timer is a no-op.
+	fired.Stop()
+}
+
+// TestVirtualClockTicker verifies periodic ticks, dropping of missed
+// ticks and stopping.
+func TestVirtualClockTicker(t *testing.T) {
+	vc := NewVirtualClock(0)
+	ticker := vc.NewTicker(time.Second)
+	for i := 0; i < 3; i++ {
+		vc.Advance(time.Second)
+		expectTick(t, ticker.C, true)
+	}
+	// Advancing over many intervals delivers a single tick.
+	vc.Advance(time.Hour)
+	expectTick(t, ticker.C, true)
+	expectTick(t, ticker.C, false)
+	vc.Advance(time.Second)
+	expectTick(t, ticker.C, true)
+
+	ticker.Stop()
+	vc.Advance(time.Second)
+	expectTick(t, ticker.C, false)
+}
+
+// TestVirtualClockSetBackwards verifies that setting the time
+// backwards fires nothing and defers pending deadlines.
+func TestVirtualClockSetBackwards(t *testing.T) {
+	vc := NewVirtualClock(5)
+	ch := vc.After(10)
+	vc.Set(0)
+	if vc.UnixNano() != 0 {
+		t.Errorf("expected time 0; got %d", vc.UnixNano())
+	}
+	vc.Set(14)
+	expectTick(t, ch, false)
+	vc.Set(15)
+	expectTick(t, ch, true)
+}
+
+// TestVirtualClockDisabled verifies the package-level functions use
+// real time when no virtual clock is installed.
+func TestVirtualClockDisabled(t *testing.T) {
+	if VirtualClockEnabled {
+		SetVirtualClock(nil)
+	}
+	if d := time.Since(Now()); d < 0 || d > time.Minute {
+		t.Errorf("expected Now() to report real time; off by %s", d)
+	}
+	ticker := NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C:
+	case <-time.After(5 * time.Second):
+		t.Error("ticker failed to fire")
+	}
+}